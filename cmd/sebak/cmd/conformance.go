@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"boscoin.io/sebak/lib"
+
+	"boscoin.io/sebak/cmd/sebak/common"
+)
+
+var (
+	conformanceCmd *cobra.Command
+
+	flagConformanceNode    string
+	flagConformanceTimeout string = sebak.DefaultConformanceTimeout.String()
+	flagConformanceOutput  string
+)
+
+func init() {
+	conformanceCmd = &cobra.Command{
+		Use:   "conformance",
+		Short: "Run the conformance test suite against the running node",
+		Run: func(c *cobra.Command, args []string) {
+			if u, err := url.Parse(flagConformanceNode); err != nil || len(u.Scheme) < 1 || len(u.Host) < 1 {
+				common.PrintFlagsError(c, "--node", errors.New("must be the URL of node, like 'https://localhost:12345'"))
+			}
+
+			timeout, err := time.ParseDuration(flagConformanceTimeout)
+			if err != nil || timeout <= 0 {
+				common.PrintFlagsError(c, "--timeout", errors.New("must be positive duration like '10s'"))
+			}
+
+			client, err := sebak.NewConformanceClient(flagConformanceNode, timeout)
+			if err != nil {
+				common.PrintFlagsError(c, "--node", err)
+			}
+
+			report := sebak.RunConformance(client)
+			for _, result := range report.Results {
+				switch {
+				case !result.IsPassed():
+					fmt.Printf("FAIL  %-10s %-22s %s\n", result.Category, result.Name, result.Error)
+				case len(result.Skipped) > 0:
+					fmt.Printf("SKIP  %-10s %-22s %s\n", result.Category, result.Name, result.Skipped)
+				default:
+					fmt.Printf("PASS  %-10s %-22s %s\n", result.Category, result.Name, result.Elapsed)
+				}
+			}
+			fmt.Printf("\n%s: %d passed, %d failed, %d skipped\n", report.Node, report.Passed, report.Failed, report.Skipped)
+
+			if len(flagConformanceOutput) > 0 {
+				b, _ := json.MarshalIndent(report, "", "  ")
+				if err = ioutil.WriteFile(flagConformanceOutput, b, 0644); err != nil {
+					common.PrintFlagsError(c, "--output", err)
+				}
+			}
+
+			if !report.IsPassed() {
+				os.Exit(1)
+			}
+		},
+	}
+
+	conformanceCmd.Flags().StringVar(&flagConformanceNode, "node", flagConformanceNode, "URL of node with the base path of API, like 'https://localhost:12345'")
+	conformanceCmd.Flags().StringVar(&flagConformanceTimeout, "timeout", flagConformanceTimeout, "timeout of each request")
+	conformanceCmd.Flags().StringVar(&flagConformanceOutput, "output", flagConformanceOutput, "file to write the report in JSON")
+
+	conformanceCmd.MarkFlagRequired("node")
+
+	rootCmd.AddCommand(conformanceCmd)
+}
@@ -3,10 +3,12 @@ package cmd
 import (
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 
-	"github.com/google/uuid"
 	"github.com/spf13/cobra"
 	"github.com/stellar/go/keypair"
 
@@ -21,27 +23,96 @@ const (
 	initialBalance = "1,000,000,000,000.0000000"
 )
 
+type FlagGenesisAccounts []sebak.GenesisAccount
+
+func (f *FlagGenesisAccounts) Type() string {
+	return "accounts"
+}
+
+func (f *FlagGenesisAccounts) String() string {
+	return ""
+}
+
+func (f *FlagGenesisAccounts) Set(v string) error {
+	parsed := strings.SplitN(v, ",", 2)
+	if len(parsed) < 2 {
+		return errors.New("'<public address>,<balance>' must be given")
+	}
+
+	balance, err := common.ParseAmountFromString(parsed[1])
+	if err != nil {
+		return err
+	}
+
+	*f = append(*f, sebak.GenesisAccount{Address: parsed[0], Balance: balance})
+
+	return nil
+}
+
 var (
-	genesisCmd  *cobra.Command
-	flagBalance string = sebakcommon.GetENVValue("SEBAK_GENESIS_BALANCE", initialBalance)
+	genesisCmd    *cobra.Command
+	flagBalance   string = sebakcommon.GetENVValue("SEBAK_GENESIS_BALANCE", initialBalance)
+	flagBlockTime string = sebakcommon.GetENVValue("SEBAK_BLOCK_TIME", sebak.DefaultBlockTime.String())
+
+	flagMinimumBalance  string = sebakcommon.GetENVValue("SEBAK_MINIMUM_BALANCE", "0")
+	flagFeePerOperation string = sebakcommon.GetENVValue("SEBAK_FEE_PER_OPERATION", "0")
+	flagFeePerByte      string = sebakcommon.GetENVValue("SEBAK_FEE_PER_BYTE", "0")
+	flagFeePerDataEntry string = sebakcommon.GetENVValue("SEBAK_FEE_PER_DATA_ENTRY", "0")
+	flagRewardAccount   string = sebakcommon.GetENVValue("SEBAK_REWARD_ACCOUNT", "")
+	flagRewardAmount    string = sebakcommon.GetENVValue("SEBAK_REWARD_AMOUNT", "0")
+	flagRewardInterval  string = sebakcommon.GetENVValue("SEBAK_REWARD_INTERVAL", "0")
+	flagRewardSource    string = sebakcommon.GetENVValue("SEBAK_REWARD_SOURCE", "")
+
+	flagMaxOperationsPerTransaction string = sebakcommon.GetENVValue("SEBAK_MAX_OPERATIONS_PER_TRANSACTION", "0")
+	flagMaxTransactionsPerBlock     string = sebakcommon.GetENVValue("SEBAK_MAX_TRANSACTIONS_PER_BLOCK", "0")
+
+	flagGenesisFile            string = sebakcommon.GetENVValue("SEBAK_GENESIS", "")
+	flagGenesisAccounts        FlagGenesisAccounts
+	flagGenesisValidators      FlagValidators
+	flagGenesisThresholdINIT   string = strconv.Itoa(sebak.DefaultThresholdINIT)
+	flagGenesisThresholdSIGN   string = strconv.Itoa(sebak.DefaultThresholdSIGN)
+	flagGenesisThresholdACCEPT string = strconv.Itoa(sebak.DefaultThresholdACCEPT)
 )
 
 func init() {
-	var genesisCmd = &cobra.Command{
-		Use:   "genesis <public key>",
-		Short: "initialize new network",
-		Args:  cobra.ExactArgs(1),
+	genesisCmd = &cobra.Command{
+		Use:   "genesis [<public key>]",
+		Short: "initialize new network by genesis.json with --file, or by the one account",
+		Args:  cobra.MaximumNArgs(1),
 		Run: func(c *cobra.Command, args []string) {
 			var err error
-			var kp keypair.KP
-			var balance sebak.Amount
+			var genesis sebak.Genesis
 
-			if kp, err = keypair.Parse(args[0]); err != nil {
-				common.PrintFlagsError(c, "<public key>", err)
-			}
+			if len(flagGenesisFile) > 0 {
+				if len(args) > 0 {
+					common.PrintFlagsError(c, "<public key>", errors.New("<public key> can not be used with --file"))
+				}
+				genesis = readGenesis(c, flagGenesisFile)
+			} else {
+				if len(args) < 1 {
+					common.PrintFlagsError(c, "<public key>", errors.New("<public key> or --file must be given"))
+				}
+
+				var kp keypair.KP
+				if kp, err = keypair.Parse(args[0]); err != nil {
+					common.PrintFlagsError(c, "<public key>", err)
+				}
 
-			if balance, err = common.ParseAmountFromString(flagBalance); err != nil {
-				common.PrintFlagsError(c, "--balance", err)
+				var balance sebak.Amount
+				if balance, err = common.ParseAmountFromString(flagBalance); err != nil {
+					common.PrintFlagsError(c, "--balance", err)
+				}
+
+				genesis = sebak.NewGenesis(flagNetworkID)
+				genesis.Accounts = append(genesis.Accounts, sebak.GenesisAccount{Address: kp.Address(), Balance: balance})
+				genesis.Consensus.BlockTime = flagBlockTime
+				genesis.Consensus.MinimumBalance = parseFlagMinimumBalance(c)
+				genesis.Consensus.FeePerOperation, genesis.Consensus.FeePerByte, genesis.Consensus.FeePerDataEntry = parseFlagFees(c)
+				parseFlagReward(c, &genesis.Consensus)
+				parseFlagLimits(c, &genesis.Consensus)
+				if err = genesis.IsWellFormed(); err != nil {
+					common.PrintFlagsError(c, "<public key>", err)
+				}
 			}
 
 			if storageConfig, err = sebakstorage.NewConfigFromString(flagStorageConfigString); err != nil {
@@ -53,21 +124,85 @@ func init() {
 				common.PrintFlagsError(c, "--storage", fmt.Errorf("failed to initialize storage: %v", err))
 			}
 
-			// check account is exists
-			if _, err = sebak.GetBlockAccount(st, kp.Address()); err == nil {
-				common.PrintFlagsError(c, "<public key>", errors.New("account is already created"))
+			if err = genesis.Apply(st); err != nil {
+				common.PrintFlagsError(c, "--storage", fmt.Errorf("failed to apply genesis: %v", err))
 			}
 
-			checkpoint := uuid.New().String()
-			account := sebak.NewBlockAccount(kp.Address(), balance, checkpoint)
-			account.Save(st)
-
 			fmt.Println("successfully created genesis block")
 		},
 	}
 
-	/*
-	 */
+	createCmd := &cobra.Command{
+		Use:   "create <file>",
+		Short: "create new genesis.json",
+		Args:  cobra.ExactArgs(1),
+		Run: func(c *cobra.Command, args []string) {
+			genesis := sebak.NewGenesis(flagNetworkID)
+			genesis.Accounts = flagGenesisAccounts
+			genesis.Consensus.BlockTime = flagBlockTime
+			genesis.Consensus.MinimumBalance = parseFlagMinimumBalance(c)
+			genesis.Consensus.FeePerOperation, genesis.Consensus.FeePerByte, genesis.Consensus.FeePerDataEntry = parseFlagFees(c)
+			parseFlagReward(c, &genesis.Consensus)
+			parseFlagLimits(c, &genesis.Consensus)
+
+			for _, v := range flagGenesisValidators {
+				if v.Endpoint() == nil {
+					common.PrintFlagsError(c, "--validator", errors.New("endpoint of validator must be given"))
+				}
+				genesis.Validators = append(genesis.Validators, sebak.GenesisValidator{
+					Address:  v.Address(),
+					Endpoint: v.Endpoint().String(),
+					Alias:    v.Alias(),
+				})
+			}
+
+			var err error
+			thresholds := map[string]*int{
+				"--threshold-init":   &genesis.Consensus.ThresholdINIT,
+				"--threshold-sign":   &genesis.Consensus.ThresholdSIGN,
+				"--threshold-accept": &genesis.Consensus.ThresholdACCEPT,
+			}
+			for name, v := range map[string]string{
+				"--threshold-init":   flagGenesisThresholdINIT,
+				"--threshold-sign":   flagGenesisThresholdSIGN,
+				"--threshold-accept": flagGenesisThresholdACCEPT,
+			} {
+				if *thresholds[name], err = strconv.Atoi(v); err != nil {
+					common.PrintFlagsError(c, name, errors.New("must be integer between 1 and 100"))
+				}
+			}
+
+			if err = genesis.IsWellFormed(); err != nil {
+				common.PrintFlagsError(c, "<file>", err)
+			}
+
+			var b []byte
+			if b, err = genesis.Serialize(); err != nil {
+				common.PrintFlagsError(c, "<file>", err)
+			}
+			if err = ioutil.WriteFile(args[0], b, 0644); err != nil {
+				common.PrintFlagsError(c, "<file>", err)
+			}
+
+			fmt.Printf("genesis, '%s' written\n", args[0])
+		},
+	}
+
+	validateCmd := &cobra.Command{
+		Use:   "validate <file>",
+		Short: "validate genesis.json",
+		Args:  cobra.ExactArgs(1),
+		Run: func(c *cobra.Command, args []string) {
+			genesis := readGenesis(c, args[0])
+			fmt.Printf(
+				"genesis, '%s' is valid: network id '%s', %d accounts, %d validators\n",
+				args[0],
+				genesis.NetworkID,
+				len(genesis.Accounts),
+				len(genesis.Validators),
+			)
+		},
+	}
 
 	var err error
 	var currentDirectory string
@@ -80,8 +215,101 @@ func init() {
 
 	flagStorageConfigString = sebakcommon.GetENVValue("SEBAK_STORAGE", fmt.Sprintf("file://%s/db", currentDirectory))
 
+	genesisCmd.Flags().StringVar(&flagGenesisFile, "file", flagGenesisFile, "genesis.json to apply")
 	genesisCmd.Flags().StringVar(&flagBalance, "balance", flagBalance, "initial balance of genesis block")
+	genesisCmd.Flags().StringVar(&flagBlockTime, "block-time", flagBlockTime, "target interval of blocks, like '1s'")
+	genesisCmd.Flags().StringVar(&flagMinimumBalance, "minimum-balance", flagMinimumBalance, "minimum initial balance of new account; 0 is no minimum")
+	genesisCmd.Flags().StringVar(&flagFeePerOperation, "fee-per-operation", flagFeePerOperation, "minimum fee of each operation; 0 is the base fee")
+	genesisCmd.Flags().StringVar(&flagFeePerByte, "fee-per-byte", flagFeePerByte, "minimum fee of each byte of transaction; 0 is no fee for the size")
+	genesisCmd.Flags().StringVar(&flagFeePerDataEntry, "fee-per-data-entry", flagFeePerDataEntry, "minimum fee of each data entry set by manage-data; 0 is no fee")
+	genesisCmd.Flags().StringVar(&flagRewardAccount, "reward-account", flagRewardAccount, "account, which receives the reward")
+	genesisCmd.Flags().StringVar(&flagRewardAmount, "reward-amount", flagRewardAmount, "amount of reward in each interval")
+	genesisCmd.Flags().StringVar(&flagRewardInterval, "reward-interval", flagRewardInterval, "number of blocks between rewards; 0 is no reward")
+	genesisCmd.Flags().StringVar(&flagRewardSource, "reward-source", flagRewardSource, "account, which the reward is moved from; empty mints the reward")
+	genesisCmd.Flags().StringVar(&flagMaxOperationsPerTransaction, "max-operations-per-transaction", flagMaxOperationsPerTransaction, "maximum number of operations in one transaction; 0 is no limit")
+	genesisCmd.Flags().StringVar(&flagMaxTransactionsPerBlock, "max-transactions-per-block", flagMaxTransactionsPerBlock, "maximum number of transactions in one block; 0 is no limit")
 	genesisCmd.Flags().StringVar(&flagStorageConfigString, "storage", flagStorageConfigString, "storage uri")
+	genesisCmd.PersistentFlags().StringVar(&flagNetworkID, "network-id", flagNetworkID, "network id")
 
+	createCmd.Flags().Var(&flagGenesisAccounts, "account", "add account: '<public address>,<balance>'")
+	createCmd.Flags().Var(&flagGenesisValidators, "validator", "add validator: '<public address>,<endpoint url>,<alias>' or <public address>,<endpoint url>")
+	createCmd.Flags().StringVar(&flagBlockTime, "block-time", flagBlockTime, "target interval of blocks, like '1s'")
+	createCmd.Flags().StringVar(&flagMinimumBalance, "minimum-balance", flagMinimumBalance, "minimum initial balance of new account; 0 is no minimum")
+	createCmd.Flags().StringVar(&flagFeePerOperation, "fee-per-operation", flagFeePerOperation, "minimum fee of each operation; 0 is the base fee")
+	createCmd.Flags().StringVar(&flagFeePerByte, "fee-per-byte", flagFeePerByte, "minimum fee of each byte of transaction; 0 is no fee for the size")
+	createCmd.Flags().StringVar(&flagFeePerDataEntry, "fee-per-data-entry", flagFeePerDataEntry, "minimum fee of each data entry set by manage-data; 0 is no fee")
+	createCmd.Flags().StringVar(&flagRewardAccount, "reward-account", flagRewardAccount, "account, which receives the reward")
+	createCmd.Flags().StringVar(&flagRewardAmount, "reward-amount", flagRewardAmount, "amount of reward in each interval")
+	createCmd.Flags().StringVar(&flagRewardInterval, "reward-interval", flagRewardInterval, "number of blocks between rewards; 0 is no reward")
+	createCmd.Flags().StringVar(&flagRewardSource, "reward-source", flagRewardSource, "account, which the reward is moved from; empty mints the reward")
+	createCmd.Flags().StringVar(&flagMaxOperationsPerTransaction, "max-operations-per-transaction", flagMaxOperationsPerTransaction, "maximum number of operations in one transaction; 0 is no limit")
+	createCmd.Flags().StringVar(&flagMaxTransactionsPerBlock, "max-transactions-per-block", flagMaxTransactionsPerBlock, "maximum number of transactions in one block; 0 is no limit")
+	createCmd.Flags().StringVar(&flagGenesisThresholdINIT, "threshold-init", flagGenesisThresholdINIT, "percentage of validators to pass INIT")
+	createCmd.Flags().StringVar(&flagGenesisThresholdSIGN, "threshold-sign", flagGenesisThresholdSIGN, "percentage of validators to pass SIGN")
+	createCmd.Flags().StringVar(&flagGenesisThresholdACCEPT, "threshold-accept", flagGenesisThresholdACCEPT, "percentage of validators to pass ACCEPT")
+
+	genesisCmd.AddCommand(createCmd, validateCmd)
 	rootCmd.AddCommand(genesisCmd)
 }
+
+func parseFlagMinimumBalance(c *cobra.Command) (minimum sebak.Amount) {
+	var err error
+	if minimum, err = common.ParseAmountFromString(flagMinimumBalance); err != nil {
+		common.PrintFlagsError(c, "--minimum-balance", err)
+	}
+
+	return
+}
+
+func parseFlagFees(c *cobra.Command) (perOperation, perByte, perDataEntry sebak.Amount) {
+	var err error
+	if perOperation, err = common.ParseAmountFromString(flagFeePerOperation); err != nil {
+		common.PrintFlagsError(c, "--fee-per-operation", err)
+	}
+	if perByte, err = common.ParseAmountFromString(flagFeePerByte); err != nil {
+		common.PrintFlagsError(c, "--fee-per-byte", err)
+	}
+	if perDataEntry, err = common.ParseAmountFromString(flagFeePerDataEntry); err != nil {
+		common.PrintFlagsError(c, "--fee-per-data-entry", err)
+	}
+
+	return
+}
+
+func parseFlagReward(c *cobra.Command, consensus *sebak.GenesisConsensus) {
+	var err error
+	if consensus.RewardAmount, err = common.ParseAmountFromString(flagRewardAmount); err != nil {
+		common.PrintFlagsError(c, "--reward-amount", err)
+	}
+	if consensus.RewardInterval, err = strconv.ParseUint(flagRewardInterval, 10, 64); err != nil {
+		common.PrintFlagsError(c, "--reward-interval", err)
+	}
+	consensus.RewardAccount = flagRewardAccount
+	consensus.RewardSource = flagRewardSource
+}
+
+func parseFlagLimits(c *cobra.Command, consensus *sebak.GenesisConsensus) {
+	var err error
+	if consensus.MaxOperationsPerTransaction, err = strconv.ParseUint(flagMaxOperationsPerTransaction, 10, 64); err != nil {
+		common.PrintFlagsError(c, "--max-operations-per-transaction", err)
+	}
+	if consensus.MaxTransactionsPerBlock, err = strconv.ParseUint(flagMaxTransactionsPerBlock, 10, 64); err != nil {
+		common.PrintFlagsError(c, "--max-transactions-per-block", err)
+	}
+}
+
+func readGenesis(c *cobra.Command, path string) (genesis sebak.Genesis) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		common.PrintFlagsError(c, "<file>", err)
+	}
+
+	if genesis, err = sebak.NewGenesisFromJSON(b); err != nil {
+		common.PrintFlagsError(c, "<file>", fmt.Errorf("invalid genesis, '%s': %v", path, err))
+	}
+	if err = genesis.IsWellFormed(); err != nil {
+		common.PrintFlagsError(c, "<file>", fmt.Errorf("invalid genesis, '%s': %v", path, err))
+	}
+
+	return
+}
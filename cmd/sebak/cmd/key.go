@@ -22,5 +22,9 @@ func init() {
 	}
 
 	keyCmd.AddCommand(key.GenerateCmd)
+	keyCmd.AddCommand(key.NewCmd)
+	keyCmd.AddCommand(key.ShowCmd)
+	keyCmd.AddCommand(key.DeriveCmd)
+	keyCmd.AddCommand(key.CertifyCmd)
 	rootCmd.AddCommand(keyCmd)
 }
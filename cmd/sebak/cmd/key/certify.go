@@ -0,0 +1,73 @@
+package key
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/spf13/cobra"
+	"github.com/stellar/go/keypair"
+
+	"boscoin.io/sebak/cmd/sebak/common"
+	"boscoin.io/sebak/lib"
+)
+
+var (
+	CertifyCmd *cobra.Command
+
+	flagIdentitySeed string
+	flagSessionKey   string
+	flagExpiresAt    string
+	flagNetworkID    string
+)
+
+func init() {
+	CertifyCmd = &cobra.Command{
+		Use:   "certify",
+		Short: "Certify the session key of validator by the identity key",
+		Run: func(c *cobra.Command, args []string) {
+			parsedKP, err := keypair.Parse(flagIdentitySeed)
+			if err != nil {
+				common.PrintFlagsError(c, "--secret-seed", err)
+			}
+			identity, ok := parsedKP.(*keypair.Full)
+			if !ok {
+				common.PrintFlagsError(c, "--secret-seed", errors.New("must be secret seed"))
+			}
+
+			session, err := keypair.Parse(flagSessionKey)
+			if err != nil {
+				common.PrintFlagsError(c, "--session", err)
+			}
+			if session.Address() == identity.Address() {
+				common.PrintFlagsError(c, "--session", errors.New("must not be the identity key"))
+			}
+
+			expiresAt, err := strconv.ParseUint(flagExpiresAt, 10, 64)
+			if err != nil || expiresAt < 1 {
+				common.PrintFlagsError(c, "--expires-at", errors.New("must be the height of block"))
+			}
+			if len(flagNetworkID) < 1 {
+				common.PrintFlagsError(c, "--network-id", errors.New("--network-id must be given"))
+			}
+
+			certificate := sebak.NewSessionKeyCertificate(identity.Address(), session.Address(), expiresAt)
+			certificate.Sign(identity, []byte(flagNetworkID))
+
+			b, _ := json.MarshalIndent(certificate, "", "  ")
+			fmt.Fprintf(os.Stdout, "%s\n", b)
+		},
+	}
+
+	CertifyCmd.Flags().StringVar(&flagIdentitySeed, "secret-seed", flagIdentitySeed, "secret seed of the identity key of validator")
+	CertifyCmd.Flags().StringVar(&flagSessionKey, "session", flagSessionKey, "public address of the session key")
+	CertifyCmd.Flags().StringVar(&flagExpiresAt, "expires-at", flagExpiresAt, "height of block, which the certificate expires at")
+	CertifyCmd.Flags().StringVar(&flagNetworkID, "network-id", flagNetworkID, "network id")
+
+	CertifyCmd.MarkFlagRequired("secret-seed")
+	CertifyCmd.MarkFlagRequired("session")
+	CertifyCmd.MarkFlagRequired("expires-at")
+	CertifyCmd.MarkFlagRequired("network-id")
+}
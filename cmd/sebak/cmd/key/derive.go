@@ -0,0 +1,61 @@
+package key
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"boscoin.io/sebak/cmd/sebak/common"
+)
+
+var (
+	DeriveCmd *cobra.Command
+
+	flagDeriveFormat     string = FormatText
+	flagDerivePassphrase string
+	flagDeriveIndex      uint32
+	flagDeriveCount      uint32 = 1
+)
+
+func init() {
+	DeriveCmd = &cobra.Command{
+		Use:   "derive [<mnemonic>]",
+		Short: "Derive the keypairs from the BIP39 mnemonic; without <mnemonic>, it is read from stdin",
+		Args:  cobra.MaximumNArgs(1),
+		Run: func(c *cobra.Command, args []string) {
+			if err := checkFormat(flagDeriveFormat); err != nil {
+				common.PrintFlagsError(c, "--format", err)
+			}
+			if flagDeriveCount < 1 {
+				common.PrintFlagsError(c, "--count", errors.New("must be greater than 0"))
+			}
+
+			var mnemonic string
+			if len(args) > 0 {
+				mnemonic = args[0]
+			} else {
+				// the mnemonic in the arguments is kept in the history of shell
+				line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+				if err != nil && len(line) < 1 {
+					common.PrintFlagsError(c, "<mnemonic>", err)
+				}
+				mnemonic = strings.TrimSpace(line)
+			}
+
+			keys, err := deriveKeys(mnemonic, flagDerivePassphrase, flagDeriveIndex, flagDeriveCount)
+			if err != nil {
+				common.PrintFlagsError(c, "<mnemonic>", err)
+			}
+
+			printKeys(flagDeriveFormat, keys...)
+		},
+	}
+
+	DeriveCmd.Flags().StringVar(&flagDeriveFormat, "format", flagDeriveFormat, "output format, 'text' or 'json'")
+	DeriveCmd.Flags().StringVar(&flagDerivePassphrase, "passphrase", flagDerivePassphrase, "passphrase of mnemonic")
+	DeriveCmd.Flags().Uint32Var(&flagDeriveIndex, "index", flagDeriveIndex, "index of the first account, m/44'/148'/<index>'")
+	DeriveCmd.Flags().Uint32Var(&flagDeriveCount, "count", flagDeriveCount, "number of accounts to derive from --index")
+}
@@ -0,0 +1,54 @@
+package key
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/stellar/go/keypair"
+
+	"boscoin.io/sebak/cmd/sebak/common"
+	"boscoin.io/sebak/lib/common"
+)
+
+var (
+	NewCmd *cobra.Command
+
+	flagNewFormat     string = FormatText
+	flagNewMnemonic   bool
+	flagNewWords      int = 24
+	flagNewPassphrase string
+)
+
+func init() {
+	NewCmd = &cobra.Command{
+		Use:   "new",
+		Short: "Generate new keypair; with --mnemonic, from the new mnemonic for the backup",
+		Args:  cobra.NoArgs,
+		Run: func(c *cobra.Command, args []string) {
+			if err := checkFormat(flagNewFormat); err != nil {
+				common.PrintFlagsError(c, "--format", err)
+			}
+
+			if !flagNewMnemonic {
+				kp, _ := keypair.Random()
+				printKeys(flagNewFormat, NewKey(kp))
+				return
+			}
+
+			mnemonic, err := sebakcommon.NewMnemonic(flagNewWords)
+			if err != nil {
+				common.PrintFlagsError(c, "--words", err)
+			}
+			keys, err := deriveKeys(mnemonic, flagNewPassphrase, 0, 1)
+			if err != nil {
+				common.PrintFlagsError(c, "--mnemonic", err)
+			}
+			keys[0].Mnemonic = mnemonic
+
+			printKeys(flagNewFormat, keys...)
+		},
+	}
+
+	NewCmd.Flags().StringVar(&flagNewFormat, "format", flagNewFormat, "output format, 'text' or 'json'")
+	NewCmd.Flags().BoolVar(&flagNewMnemonic, "mnemonic", flagNewMnemonic, "derive the keypair from the new BIP39 mnemonic, which restores it by 'sebak key derive'")
+	NewCmd.Flags().IntVar(&flagNewWords, "words", flagNewWords, "number of words of mnemonic; 12, 15, 18, 21 or 24")
+	NewCmd.Flags().StringVar(&flagNewPassphrase, "passphrase", flagNewPassphrase, "optional passphrase of mnemonic; it is needed to restore the keypair")
+}
@@ -0,0 +1,94 @@
+package key
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/template"
+
+	"github.com/stellar/go/keypair"
+
+	"boscoin.io/sebak/lib/common"
+)
+
+const (
+	FormatText string = "text"
+	FormatJSON string = "json"
+)
+
+// Key is the output of the key commands; `Seed` is empty for the public
+// address, and `Mnemonic` and `Path` are only for the derived key.
+type Key struct {
+	Address  string `json:"address"`
+	Seed     string `json:"seed,omitempty"`
+	Mnemonic string `json:"mnemonic,omitempty"`
+	Path     string `json:"path,omitempty"`
+}
+
+func NewKey(kp keypair.KP) Key {
+	k := Key{Address: kp.Address()}
+	if full, ok := kp.(*keypair.Full); ok {
+		k.Seed = full.Seed()
+	}
+
+	return k
+}
+
+var keyTextTemplate = template.Must(template.New("").Parse(`{{ range $i, $k := . }}{{ if $i }}
+{{ end }}{{ if $k.Seed }}       Secret Seed: {{ $k.Seed }}
+{{ end }}    Public Address: {{ $k.Address }}
+{{ if $k.Mnemonic }}          Mnemonic: {{ $k.Mnemonic }}
+{{ end }}{{ if $k.Path }}   Derivation Path: {{ $k.Path }}
+{{ end }}{{ end }}`))
+
+func checkFormat(format string) error {
+	if format != FormatText && format != FormatJSON {
+		return fmt.Errorf("must be '%s' or '%s'", FormatText, FormatJSON)
+	}
+
+	return nil
+}
+
+// printKeys prints the keys; the json format is the list of keys, only if
+// more than one key is given.
+func printKeys(format string, keys ...Key) {
+	if format == FormatJSON {
+		var v interface{} = keys
+		if len(keys) == 1 {
+			v = keys[0]
+		}
+		b, _ := json.MarshalIndent(v, "", "  ")
+		fmt.Fprintf(os.Stdout, "%s\n", b)
+		return
+	}
+
+	keyTextTemplate.Execute(os.Stdout, keys)
+}
+
+// deriveKeys derives the keys from `start` to `start+count-1` from the
+// mnemonic and the optional passphrase.
+func deriveKeys(mnemonic, passphrase string, start, count uint32) (keys []Key, err error) {
+	var seed []byte
+	if seed, err = sebakcommon.MnemonicToSeed(mnemonic, passphrase); err != nil {
+		err = fmt.Errorf("invalid mnemonic: %v", err)
+		return
+	}
+
+	for index := start; index < start+count; index++ {
+		var raw [32]byte
+		if raw, err = sebakcommon.DeriveAccountKey(seed, index); err != nil {
+			return
+		}
+
+		var kp *keypair.Full
+		if kp, err = keypair.FromRawSeed(raw); err != nil {
+			return
+		}
+
+		k := NewKey(kp)
+		k.Path = sebakcommon.AccountKeyPath(index)
+		keys = append(keys, k)
+	}
+
+	return
+}
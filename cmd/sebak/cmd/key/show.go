@@ -0,0 +1,36 @@
+package key
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/stellar/go/keypair"
+
+	"boscoin.io/sebak/cmd/sebak/common"
+)
+
+var (
+	ShowCmd *cobra.Command
+
+	flagShowFormat string = FormatText
+)
+
+func init() {
+	ShowCmd = &cobra.Command{
+		Use:   "show <secret seed or public address>",
+		Short: "Show the public address of secret seed, or check the public address",
+		Args:  cobra.ExactArgs(1),
+		Run: func(c *cobra.Command, args []string) {
+			if err := checkFormat(flagShowFormat); err != nil {
+				common.PrintFlagsError(c, "--format", err)
+			}
+
+			kp, err := keypair.Parse(args[0])
+			if err != nil {
+				common.PrintFlagsError(c, "<secret seed or public address>", err)
+			}
+
+			printKeys(flagShowFormat, NewKey(kp))
+		},
+	}
+
+	ShowCmd.Flags().StringVar(&flagShowFormat, "format", flagShowFormat, "output format, 'text' or 'json'")
+}
@@ -1,11 +1,17 @@
 package cmd
 
 import (
+	"crypto/x509"
 	"errors"
 	"fmt"
+	"io/ioutil"
+	"net"
+	"net/url"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 
 	"golang.org/x/net/http2"
 
@@ -16,6 +22,7 @@ import (
 
 	"boscoin.io/sebak/lib"
 	"boscoin.io/sebak/lib/common"
+	"boscoin.io/sebak/lib/error"
 	"boscoin.io/sebak/lib/network"
 	"boscoin.io/sebak/lib/storage"
 
@@ -38,16 +45,17 @@ func (f *FlagValidators) Set(v string) error {
 	}
 
 	parsed := strings.SplitN(v, ",", 3)
-	if len(parsed) < 2 {
-		return errors.New("at least '<public address>,<endpoint url>' must be given")
-	}
-	if len(parsed) < 3 {
+	for len(parsed) < 3 {
 		parsed = append(parsed, "")
 	}
 
-	endpoint, err := sebakcommon.ParseNodeEndpoint(parsed[1])
-	if err != nil {
-		return err
+	// without the endpoint, the endpoint is found by the peer discovery
+	var endpoint *sebakcommon.Endpoint
+	if len(parsed[1]) > 0 {
+		var err error
+		if endpoint, err = sebakcommon.ParseNodeEndpoint(parsed[1]); err != nil {
+			return err
+		}
 	}
 	node, err := sebakcommon.NewValidator(parsed[0], endpoint, parsed[2])
 	if err != nil {
@@ -59,7 +67,7 @@ func (f *FlagValidators) Set(v string) error {
 		if node.Address() == n.Address() {
 			return fmt.Errorf("duplicated public address found")
 		}
-		if node.Endpoint() == n.Endpoint() {
+		if node.Endpoint() != nil && node.Endpoint() == n.Endpoint() {
 			return fmt.Errorf("duplicated endpoint found")
 		}
 	}
@@ -84,20 +92,178 @@ var (
 		"SEBAK_ENDPOINT",
 		fmt.Sprintf("%s://%s:%d", defaultNetwork, defaultHost, defaultPort),
 	)
-	flagStorageConfigString string
-	flagTLSCertFile         string = sebakcommon.GetENVValue("SEBAK_TLS_CERT", "sebak.crt")
-	flagTLSKeyFile          string = sebakcommon.GetENVValue("SEBAK_TLS_KEY", "sebak.key")
-	flagValidators          FlagValidators
+	flagStorageConfigString  string
+	flagTLSCertFile          string = sebakcommon.GetENVValue("SEBAK_TLS_CERT", "sebak.crt")
+	flagTLSKeyFile           string = sebakcommon.GetENVValue("SEBAK_TLS_KEY", "sebak.key")
+	flagTLSCAFile            string = sebakcommon.GetENVValue("SEBAK_TLS_CA", "")
+	flagTLSClientAuth        bool   = sebakcommon.GetENVValue("SEBAK_TLS_CLIENT_AUTH", "0") == "1"
+	flagTLSSelfSigned        bool   = sebakcommon.GetENVValue("SEBAK_TLS_SELF_SIGNED", "0") == "1"
+	flagValidators           FlagValidators
+	flagStartupQuorumTimeout string = sebakcommon.GetENVValue("SEBAK_STARTUP_QUORUM_TIMEOUT", "60s")
+	flagTransactionOrdering  string = sebakcommon.GetENVValue("SEBAK_TRANSACTION_ORDERING", string(sebak.DefaultTransactionOrderingPolicy))
+	flagCommitRevalidation   string = sebakcommon.GetENVValue("SEBAK_COMMIT_REVALIDATION", string(sebak.DefaultRevalidationPolicy))
+	flagProposerTimeout      string = sebakcommon.GetENVValue("SEBAK_PROPOSER_TIMEOUT", "0s")
+	flagProposerTimeoutMin   string = sebakcommon.GetENVValue("SEBAK_PROPOSER_TIMEOUT_MIN", "0s")
+	flagTransactionPoolLimit string = sebakcommon.GetENVValue(
+		"SEBAK_TRANSACTION_POOL_LIMIT",
+		strconv.Itoa(sebak.DefaultTransactionPoolMaxSize),
+	)
+	flagTransactionPoolAccountLimit string = sebakcommon.GetENVValue(
+		"SEBAK_TRANSACTION_POOL_ACCOUNT_LIMIT",
+		strconv.Itoa(sebak.DefaultTransactionPoolMaxPerAccount),
+	)
+	flagSelfTest     bool
+	flagSelfTestMode string = sebakcommon.GetENVValue("SEBAK_SELFTEST_MODE", string(sebak.SelfTestModeQuick))
+
+	flagBallotAggregation string = sebakcommon.GetENVValue("SEBAK_BALLOT_AGGREGATION", "0s")
+	flagBallotCompression bool   = sebakcommon.GetENVValue("SEBAK_BALLOT_COMPRESSION", "0") == "1"
+	flagBallotFanout      string = sebakcommon.GetENVValue("SEBAK_BALLOT_FANOUT", string(sebaknetwork.DefaultFanoutPolicy))
+	flagGossipFanout      string = sebakcommon.GetENVValue("SEBAK_GOSSIP_FANOUT", strconv.Itoa(sebak.DefaultGossipFanout))
+
+	flagPeerCircuitFailures string = sebakcommon.GetENVValue(
+		"SEBAK_PEER_CIRCUIT_FAILURES",
+		strconv.Itoa(sebaknetwork.DefaultClientPoolConfig.FailureThreshold),
+	)
+	flagPeerCircuitTimeout string = sebakcommon.GetENVValue(
+		"SEBAK_PEER_CIRCUIT_TIMEOUT",
+		sebaknetwork.DefaultClientPoolConfig.OpenTimeout.String(),
+	)
+	flagPeerBanDuration string = sebakcommon.GetENVValue(
+		"SEBAK_PEER_BAN_DURATION",
+		sebaknetwork.DefaultPeerBanConfig.BanDuration.String(),
+	)
+	flagNetworkCapabilities string = sebakcommon.GetENVValue(
+		"SEBAK_NETWORK_CAPABILITIES",
+		sebaknetwork.JoinCapabilities(sebaknetwork.Capabilities...),
+	)
+
+	flagSyncRangeSize string = sebakcommon.GetENVValue(
+		"SEBAK_SYNC_RANGE_SIZE",
+		strconv.FormatUint(sebak.DefaultBlockSyncConfig.RangeSize, 10),
+	)
+	flagSyncParallel string = sebakcommon.GetENVValue("SEBAK_SYNC_PARALLEL", strconv.Itoa(sebak.DefaultBlockSyncConfig.Parallel))
+	flagSyncTimeout  string = sebakcommon.GetENVValue("SEBAK_SYNC_TIMEOUT", sebak.DefaultBlockSyncConfig.Timeout.String())
+
+	flagConsensusQueueSize string = sebakcommon.GetENVValue(
+		"SEBAK_CONSENSUS_QUEUE_SIZE",
+		strconv.Itoa(sebaknetwork.DefaultMessageQueueConfig.ConsensusSize),
+	)
+	flagClientQueueSize string = sebakcommon.GetENVValue(
+		"SEBAK_CLIENT_QUEUE_SIZE",
+		strconv.Itoa(sebaknetwork.DefaultMessageQueueConfig.ClientSize),
+	)
+	flagSyncServeWorkers string = sebakcommon.GetENVValue(
+		"SEBAK_SYNC_SERVE_WORKERS",
+		strconv.Itoa(sebaknetwork.DefaultMessageQueueConfig.SyncWorkers),
+	)
+
+	flagUpgradeSignals string = sebakcommon.GetENVValue("SEBAK_UPGRADE_SIGNALS", "")
+
+	flagGraphQL bool = sebakcommon.GetENVValue("SEBAK_GRAPHQL", "0") == "1"
+
+	flagForwardingReceipts bool = sebakcommon.GetENVValue("SEBAK_FORWARDING_RECEIPTS", "0") == "1"
+
+	flagFaucetSecretSeed   string = sebakcommon.GetENVValue("SEBAK_FAUCET_SECRET_SEED", "")
+	flagFaucetAmount       string = sebakcommon.GetENVValue("SEBAK_FAUCET_AMOUNT", sebak.DefaultFaucetAmount.String())
+	flagFaucetAddressQuota string = sebakcommon.GetENVValue("SEBAK_FAUCET_ADDRESS_QUOTA", strconv.Itoa(sebak.DefaultFaucetAddressQuota))
+	flagFaucetIPQuota      string = sebakcommon.GetENVValue("SEBAK_FAUCET_IP_QUOTA", strconv.Itoa(sebak.DefaultFaucetIPQuota))
+	flagFaucetTokens       string = sebakcommon.GetENVValue("SEBAK_FAUCET_TOKENS", "")
+
+	flagRateLimit       string = sebakcommon.GetENVValue("SEBAK_RATE_LIMIT", "0")
+	flagAPIKeys         string = sebakcommon.GetENVValue("SEBAK_API_KEYS", "")
+	flagAPIKeyRateLimit string = sebakcommon.GetENVValue("SEBAK_API_KEY_RATE_LIMIT", "0")
+
+	flagCORSOrigins    string = sebakcommon.GetENVValue("SEBAK_CORS_ORIGINS", "")
+	flagTrustedProxies string = sebakcommon.GetENVValue("SEBAK_TRUSTED_PROXIES", "")
+	flagAPIBasePath    string = sebakcommon.GetENVValue("SEBAK_API_BASE_PATH", "")
+	flagAPICacheSize   string = sebakcommon.GetENVValue("SEBAK_API_CACHE_SIZE", strconv.Itoa(sebak.DefaultAPICacheSize))
+	flagAPIDeprecate   string = sebakcommon.GetENVValue("SEBAK_API_DEPRECATE", "")
+
+	flagReadyMaxBlocksBehind string = sebakcommon.GetENVValue(
+		"SEBAK_READY_MAX_BLOCKS_BEHIND",
+		strconv.FormatUint(sebak.DefaultReadyMaxBlocksBehind, 10),
+	)
+	flagReadyMinValidators string = sebakcommon.GetENVValue("SEBAK_READY_MIN_VALIDATORS", "0")
+
+	flagLoadMaxCPU         string = sebakcommon.GetENVValue("SEBAK_LOAD_MAX_CPU", "0")
+	flagLoadMaxMemoryMB    string = sebakcommon.GetENVValue("SEBAK_LOAD_MAX_MEMORY_MB", "0")
+	flagLoadMaxDiskLatency string = sebakcommon.GetENVValue("SEBAK_LOAD_MAX_DISK_LATENCY", "0")
+
+	flagPEXSeeds string = sebakcommon.GetENVValue("SEBAK_PEX_SEEDS", "")
+
+	flagAdvertiseEndpoint string = sebakcommon.GetENVValue("SEBAK_ADVERTISE_ENDPOINT", "")
+	flagNAT               string = sebakcommon.GetENVValue("SEBAK_NAT", string(sebaknetwork.NATNone))
+	flagProxy             string = sebakcommon.GetENVValue("SEBAK_PROXY", "")
+
+	flagAdminAddr     string = sebakcommon.GetENVValue("SEBAK_ADMIN_ADDR", "")
+	flagAdminToken    string = sebakcommon.GetENVValue("SEBAK_ADMIN_TOKEN", "")
+	flagAdminClientCA string = sebakcommon.GetENVValue("SEBAK_ADMIN_CLIENT_CA", "")
+
+	flagGRPCAddr string = sebakcommon.GetENVValue("SEBAK_GRPC_ADDR", "")
+
+	flagAddress         string = sebakcommon.GetENVValue("SEBAK_ADDRESS", "")
+	flagSigners         string = sebakcommon.GetENVValue("SEBAK_SIGNERS", "")
+	flagSignerThreshold string = sebakcommon.GetENVValue("SEBAK_SIGNER_THRESHOLD", "2")
+	flagSignerToken     string = sebakcommon.GetENVValue("SEBAK_SIGNER_TOKEN", "")
+	flagSignerCA        string = sebakcommon.GetENVValue("SEBAK_SIGNER_CA", "")
+	flagSignerTimeout   string = sebakcommon.GetENVValue("SEBAK_SIGNER_TIMEOUT", "3s")
+
+	flagSessionSeed        string = sebakcommon.GetENVValue("SEBAK_SESSION_SEED", "")
+	flagSessionCertificate string = sebakcommon.GetENVValue("SEBAK_SESSION_CERTIFICATE", "")
 )
 
 var (
 	nodeCmd *cobra.Command
 
 	kp            *keypair.Full
+	nodeAddress   string
 	nodeEndpoint  *sebakcommon.Endpoint
 	storageConfig *sebakstorage.Config
 	logLevel      logging.Lvl
 	log           logging.Logger
+
+	startupQuorumTimeout      time.Duration
+	transactionOrderingPolicy sebak.TransactionOrderingPolicy
+	revalidationPolicy        sebak.RevalidationPolicy
+	proposerTimeout           time.Duration
+	proposerTimeoutMin        time.Duration
+
+	transactionPoolLimit        int
+	transactionPoolAccountLimit int
+
+	selfTestMode sebak.SelfTestMode
+
+	ballotAggregation  time.Duration
+	ballotFanout       sebaknetwork.FanoutPolicy
+	clientPoolConfig   sebaknetwork.ClientPoolConfig
+	peerBanConfig      sebaknetwork.PeerBanConfig
+	capabilities       []sebaknetwork.Capability
+	gossipFanout       int
+	blockSyncConfig    sebak.BlockSyncConfig
+	messageQueueConfig sebaknetwork.MessageQueueConfig
+	upgradeSignals     []string
+
+	faucet *sebak.Faucet
+
+	rateLimiter *sebak.RateLimiter
+	apiConfig   sebak.APIConfig
+
+	readinessConfig sebak.ReadinessConfig
+
+	loadWatermarks sebak.LoadWatermarks
+
+	pexSeeds []*sebakcommon.Endpoint
+
+	advertiseEndpoint *sebakcommon.Endpoint
+	natConfig         sebaknetwork.NATConfig
+	proxyURL          *url.URL // nil if the nodes are connected directly
+
+	adminConfig sebak.AdminConfig
+
+	grpcConfig sebak.GRPCConfig
+
+	thresholdSigner  *sebak.ThresholdNodeSigner
+	sessionKeySigner *sebak.SessionKeyNodeSigner
 )
 
 func init() {
@@ -130,14 +296,75 @@ func init() {
 	nodeCmd.Flags().StringVar(&flagLogOutput, "log-output", flagLogOutput, "set log output file")
 	nodeCmd.Flags().BoolVar(&flagVerbose, "verbose", flagVerbose, "verbose")
 	nodeCmd.Flags().StringVar(&flagEndpointString, "endpoint", flagEndpointString, "endpoint uri to listen on ('https://0.0.0.0:12345')")
+	nodeCmd.Flags().StringVar(&flagAdvertiseEndpoint, "advertise-endpoint", flagAdvertiseEndpoint, "endpoint uri, which the other nodes connect to, like 'https://203.0.113.7:12345'; without it, --endpoint is advertised")
+	nodeCmd.Flags().StringVar(&flagNAT, "nat", flagNAT, "discover the public address behind NAT, {none, upnp, stun, stun:<host>:<port>}")
+	nodeCmd.Flags().StringVar(&flagProxy, "proxy", flagProxy, "SOCKS5 proxy of the connections to the other nodes, like 'socks5://127.0.0.1:9050'")
 	nodeCmd.Flags().StringVar(&flagStorageConfigString, "storage", flagStorageConfigString, "storage uri")
 	nodeCmd.Flags().StringVar(&flagTLSCertFile, "tls-cert", flagTLSCertFile, "tls certificate file")
 	nodeCmd.Flags().StringVar(&flagTLSKeyFile, "tls-key", flagTLSKeyFile, "tls key file")
-	nodeCmd.Flags().Var(&flagValidators, "validator", "set validator: '<public address>,<endpoint url>,<alias>' or <public address>,<endpoint url>")
+	nodeCmd.Flags().StringVar(&flagTLSCAFile, "tls-ca", flagTLSCAFile, "CA file of the tls certificates of validators; without it, the certificates of validators are not verified")
+	nodeCmd.Flags().BoolVar(&flagTLSClientAuth, "tls-client-auth", flagTLSClientAuth, "the messages from the validators need the client certificate, which is verified by --tls-ca")
+	nodeCmd.Flags().BoolVar(&flagTLSSelfSigned, "tls-self-signed", flagTLSSelfSigned, "generate the self-signed --tls-cert and --tls-key, if they do not exist; for the test network")
+	nodeCmd.Flags().StringVar(&flagStartupQuorumTimeout, "startup-quorum-timeout", flagStartupQuorumTimeout, "wait until the quorum of validators is connected before voting; 0 disables waiting")
+	nodeCmd.Flags().StringVar(&flagProposerTimeout, "proposer-timeout", flagProposerTimeout, "pass the turn of proposer to the next validator if the expected proposer does not propose in time; 0 disables view change")
+	nodeCmd.Flags().StringVar(&flagProposerTimeoutMin, "proposer-timeout-min", flagProposerTimeoutMin, "adapt the proposer timeout to the latencies of validators between this and --proposer-timeout; 0 keeps the timeout static")
+	nodeCmd.Flags().StringVar(&flagTransactionOrdering, "transaction-ordering", flagTransactionOrdering, "order of transactions in proposal, {fee, fifo, nonce}")
+	nodeCmd.Flags().StringVar(&flagCommitRevalidation, "commit-revalidation", flagCommitRevalidation, "validation of the accepted transactions at commit, {full, trust}")
+	nodeCmd.Flags().StringVar(&flagTransactionPoolLimit, "transaction-pool-limit", flagTransactionPoolLimit, "maximum number of transactions in transaction pool; 0 is unlimited")
+	nodeCmd.Flags().StringVar(&flagTransactionPoolAccountLimit, "transaction-pool-account-limit", flagTransactionPoolAccountLimit, "maximum number of transactions of one source account in transaction pool; 0 is unlimited")
+	nodeCmd.Flags().BoolVar(&flagSelfTest, "selftest", flagSelfTest, "run the full self test and exit")
+	nodeCmd.Flags().StringVar(&flagSelfTestMode, "selftest-mode", flagSelfTestMode, "self test before joining consensus, {off, quick, full}")
+	nodeCmd.Flags().StringVar(&flagBallotAggregation, "ballot-aggregation", flagBallotAggregation, "send the ballots to one validator in this window together, like '5ms'; 0 sends every ballot immediately")
+	nodeCmd.Flags().BoolVar(&flagBallotCompression, "ballot-compression", flagBallotCompression, "compress the aggregated ballots by snappy")
+	nodeCmd.Flags().StringVar(&flagBallotFanout, "ballot-fanout", flagBallotFanout, "order of sending the ballots to the validators, {unordered, latency}")
+	nodeCmd.Flags().StringVar(&flagGossipFanout, "gossip-fanout", flagGossipFanout, "number of validators, which the new transactions are announced to; 0 sends the full transactions to every validator")
+	nodeCmd.Flags().StringVar(&flagPeerCircuitFailures, "peer-circuit-failures", flagPeerCircuitFailures, "failures in a row, which open the circuit to the peer")
+	nodeCmd.Flags().StringVar(&flagPeerCircuitTimeout, "peer-circuit-timeout", flagPeerCircuitTimeout, "how long the messages are not sent to the peer of the open circuit, like '10s'")
+	nodeCmd.Flags().StringVar(&flagPeerBanDuration, "peer-ban-duration", flagPeerBanDuration, "how long the peer, which sends the malformed messages or the invalid signatures repeatedly is banned, like '24h'; 0 disables ban")
+	nodeCmd.Flags().StringVar(&flagNetworkCapabilities, "network-capabilities", flagNetworkCapabilities, "comma separated capabilities, which are negotiated with the validators, {ballot-batch, ballot-compression, tx-inventory, block-sync}")
+	nodeCmd.Flags().StringVar(&flagSyncRangeSize, "sync-range-size", flagSyncRangeSize, "number of blocks in one range request of block sync")
+	nodeCmd.Flags().StringVar(&flagSyncParallel, "sync-parallel", flagSyncParallel, "number of range requests of block sync, which are sent to the validators at once")
+	nodeCmd.Flags().StringVar(&flagSyncTimeout, "sync-timeout", flagSyncTimeout, "timeout of one range request of block sync, like '10s'; the range is requested again from the other validator")
+	nodeCmd.Flags().StringVar(&flagConsensusQueueSize, "consensus-queue-size", flagConsensusQueueSize, "number of the inbound ballots and the other consensus messages, which wait to be handled")
+	nodeCmd.Flags().StringVar(&flagClientQueueSize, "client-queue-size", flagClientQueueSize, "number of the inbound transactions, which wait to be handled; over it, the transactions are dropped")
+	nodeCmd.Flags().StringVar(&flagSyncServeWorkers, "sync-serve-workers", flagSyncServeWorkers, "number of the sync requests of the other nodes, which are served at once")
+	nodeCmd.Flags().StringVar(&flagUpgradeSignals, "upgrade-signals", flagUpgradeSignals, "comma separated protocol features, which this node is ready for, like 'fee-model.v2'")
+	nodeCmd.Flags().BoolVar(&flagGraphQL, "graphql", flagGraphQL, "enable the GraphQL API for explorer queries")
+	nodeCmd.Flags().BoolVar(&flagForwardingReceipts, "forwarding-receipts", flagForwardingReceipts, "forward the submitted transactions to the validators at once and return the signed receipts")
+	nodeCmd.Flags().StringVar(&flagFaucetSecretSeed, "faucet-secret-seed", flagFaucetSecretSeed, "secret seed of faucet account; enables the faucet API in the test network")
+	nodeCmd.Flags().StringVar(&flagFaucetAmount, "faucet-amount", flagFaucetAmount, "amount of one funding of faucet")
+	nodeCmd.Flags().StringVar(&flagFaucetAddressQuota, "faucet-address-quota", flagFaucetAddressQuota, "maximum number of fundings of one address in a day; 0 is unlimited")
+	nodeCmd.Flags().StringVar(&flagFaucetIPQuota, "faucet-ip-quota", flagFaucetIPQuota, "maximum number of fundings of one IP in a day; 0 is unlimited")
+	nodeCmd.Flags().StringVar(&flagFaucetTokens, "faucet-tokens", flagFaucetTokens, "comma separated tokens, one of which the faucet requests must have")
+	nodeCmd.Flags().StringVar(&flagRateLimit, "rate-limit", flagRateLimit, "maximum number of API requests of one client IP in a second; 0 is unlimited")
+	nodeCmd.Flags().StringVar(&flagAPIKeys, "api-keys", flagAPIKeys, "comma separated API keys, which get the quota of --api-key-rate-limit instead of the client IP")
+	nodeCmd.Flags().StringVar(&flagAPIKeyRateLimit, "api-key-rate-limit", flagAPIKeyRateLimit, "maximum number of API requests of one API key in a second; 0 is unlimited")
+	nodeCmd.Flags().StringVar(&flagCORSOrigins, "cors-origins", flagCORSOrigins, "comma separated origins, which the browsers can call the API from; '*' allows every origin")
+	nodeCmd.Flags().StringVar(&flagTrustedProxies, "trusted-proxies", flagTrustedProxies, "comma separated IPs or CIDRs of the reverse proxies, whose 'X-Forwarded-For' is trusted")
+	nodeCmd.Flags().StringVar(&flagAPIBasePath, "api-base-path", flagAPIBasePath, "path prefix of the API behind the reverse proxy, like '/sebak'")
+	nodeCmd.Flags().StringVar(&flagAPICacheSize, "api-cache-size", flagAPICacheSize, "number of the responses of the finalized blocks and operations in cache; 0 is disabled")
+	nodeCmd.Flags().StringVar(&flagAPIDeprecate, "api-deprecate", flagAPIDeprecate, "comma separated deprecated API versions with their sunset date, like 'v1=2027-06-30'")
+	nodeCmd.Flags().StringVar(&flagReadyMaxBlocksBehind, "ready-max-blocks-behind", flagReadyMaxBlocksBehind, "/readyz fails when the latest block is behind the blocks announced by the validators more than this")
+	nodeCmd.Flags().StringVar(&flagReadyMinValidators, "ready-min-validators", flagReadyMinValidators, "/readyz fails when less validators are connected; 0 is the quorum of validators")
+	nodeCmd.Flags().StringVar(&flagLoadMaxCPU, "load-max-cpu", flagLoadMaxCPU, "load average for one CPU, like 0.9, above which the node sheds the load; 0 is not watched")
+	nodeCmd.Flags().StringVar(&flagLoadMaxMemoryMB, "load-max-memory-mb", flagLoadMaxMemoryMB, "megabytes of heap in use, above which the node sheds the load; 0 is not watched")
+	nodeCmd.Flags().StringVar(&flagLoadMaxDiskLatency, "load-max-disk-latency", flagLoadMaxDiskLatency, "storage write latency, like 50ms, above which the node sheds the load; 0 is not watched")
+	nodeCmd.Flags().StringVar(&flagPEXSeeds, "pex-seeds", flagPEXSeeds, "comma separated endpoints of the nodes, which are asked for the peers with the validators")
+	nodeCmd.Flags().StringVar(&flagAdminAddr, "admin-addr", flagAdminAddr, "address of the admin listener, like '127.0.0.1:12346'; empty disables it")
+	nodeCmd.Flags().StringVar(&flagAdminToken, "admin-token", flagAdminToken, "bearer token of the admin requests")
+	nodeCmd.Flags().StringVar(&flagAdminClientCA, "admin-client-ca", flagAdminClientCA, "CA certificate file, which signs the client certificates of the admin requests")
+	nodeCmd.Flags().StringVar(&flagGRPCAddr, "grpc-addr", flagGRPCAddr, "address of the gRPC listener, like '0.0.0.0:12347'; empty disables it")
+	nodeCmd.Flags().StringVar(&flagAddress, "address", flagAddress, "public address of this node, which signs by --signers or --session-seed instead of --secret-seed")
+	nodeCmd.Flags().StringVar(&flagSigners, "signers", flagSigners, "comma separated endpoints of the signer daemons, which keep the threshold shares of the validator key")
+	nodeCmd.Flags().StringVar(&flagSignerThreshold, "signer-threshold", flagSignerThreshold, "number of signers needed to sign")
+	nodeCmd.Flags().StringVar(&flagSignerToken, "signer-token", flagSignerToken, "token of the signer daemons")
+	nodeCmd.Flags().StringVar(&flagSignerCA, "signer-ca", flagSignerCA, "CA certificate file, which signs the certificates of the signer daemons")
+	nodeCmd.Flags().StringVar(&flagSignerTimeout, "signer-timeout", flagSignerTimeout, "timeout of the request to one signer")
+	nodeCmd.Flags().StringVar(&flagSessionSeed, "session-seed", flagSessionSeed, "secret seed of the session key, which signs instead of the identity key of --address; with --signers, it signs only the messages to the other nodes")
+	nodeCmd.Flags().StringVar(&flagSessionCertificate, "session-certificate", flagSessionCertificate, "file of the certificate of the session key by 'sebak key certify'")
+	nodeCmd.Flags().Var(&flagValidators, "validator", "set validator: '<public address>,<endpoint url>,<alias>' or <public address>,<endpoint url>; without the endpoint, like '<public address>' or '<public address>,,<alias>', it is discovered by --pex-seeds and the stored peers")
 
 	nodeCmd.MarkFlagRequired("network-id")
-	nodeCmd.MarkFlagRequired("secret-seed")
-	nodeCmd.MarkFlagRequired("validator")
 
 	rootCmd.AddCommand(nodeCmd)
 }
@@ -149,6 +376,9 @@ func parseFlagsNode() {
 		common.PrintFlagsError(nodeCmd, "--network-id", errors.New("-network-id must be given"))
 	}
 
+	if flagTLSSelfSigned {
+		parseFlagsTLSSelfSigned()
+	}
 	if _, err = os.Stat(flagTLSCertFile); os.IsNotExist(err) {
 		common.PrintFlagsError(nodeCmd, "--tls-cert", err)
 	}
@@ -156,12 +386,27 @@ func parseFlagsNode() {
 		common.PrintFlagsError(nodeCmd, "--tls-key", err)
 	}
 
-	var parsedKP keypair.KP
-	parsedKP, err = keypair.Parse(flagKPSecretSeed)
-	if err != nil {
-		common.PrintFlagsError(nodeCmd, "--secret-seed", err)
+	if len(flagSigners) > 0 {
+		parseFlagsSigners()
+
+		// the messages to the other nodes are signed by the session key
+		if len(flagSessionSeed) < 1 {
+			common.PrintFlagsError(nodeCmd, "--session-seed", errors.New("must be given with --signers"))
+		}
+		parseFlagsSessionKey()
+	} else if len(flagSessionSeed) > 0 {
+		parseFlagsSessionKey()
 	} else {
-		kp = parsedKP.(*keypair.Full)
+		var parsedKP keypair.KP
+		parsedKP, err = keypair.Parse(flagKPSecretSeed)
+		if err != nil {
+			common.PrintFlagsError(nodeCmd, "--secret-seed", err)
+		} else if full, ok := parsedKP.(*keypair.Full); !ok {
+			common.PrintFlagsError(nodeCmd, "--secret-seed", errors.New("must be secret seed"))
+		} else {
+			kp = full
+			nodeAddress = kp.Address()
+		}
 	}
 
 	if p, err := sebakcommon.ParseNodeEndpoint(flagEndpointString); err != nil {
@@ -174,12 +419,52 @@ func parseFlagsNode() {
 	queries := nodeEndpoint.Query()
 	queries.Add("TLSCertFile", flagTLSCertFile)
 	queries.Add("TLSKeyFile", flagTLSKeyFile)
+	if len(flagTLSCAFile) > 0 {
+		if _, err = sebaknetwork.LoadTLSCertPool(flagTLSCAFile); err != nil {
+			common.PrintFlagsError(nodeCmd, "--tls-ca", err)
+		}
+		queries.Add("TLSCAFile", flagTLSCAFile)
+	}
+	if flagTLSClientAuth {
+		if len(flagTLSCAFile) < 1 {
+			common.PrintFlagsError(nodeCmd, "--tls-client-auth", errors.New("--tls-ca must be given"))
+		}
+		queries.Add("TLSClientAuth", "true")
+	}
 	queries.Add("IdleTimeout", "3s")
-	queries.Add("NodeName", sebakcommon.MakeAlias(kp.Address()))
+	queries.Add("NodeName", sebakcommon.MakeAlias(nodeAddress))
 	nodeEndpoint.RawQuery = queries.Encode()
 
+	if len(flagAdvertiseEndpoint) > 0 {
+		if p, err := sebakcommon.ParseNodeEndpoint(flagAdvertiseEndpoint); err != nil {
+			common.PrintFlagsError(nodeCmd, "--advertise-endpoint", err)
+		} else if p.Scheme != nodeEndpoint.Scheme {
+			common.PrintFlagsError(nodeCmd, "--advertise-endpoint", errors.New("scheme must be same with --endpoint"))
+		} else {
+			advertiseEndpoint = p
+			flagAdvertiseEndpoint = advertiseEndpoint.String()
+		}
+	}
+	if natConfig, err = sebaknetwork.NewNATConfigFromString(flagNAT); err != nil {
+		common.PrintFlagsError(nodeCmd, "--nat", err)
+	}
+	if advertiseEndpoint != nil && natConfig.Method != sebaknetwork.NATNone {
+		common.PrintFlagsError(nodeCmd, "--nat", errors.New("must not be given with --advertise-endpoint"))
+	}
+	if len(flagProxy) > 0 {
+		if proxyURL, err = sebaknetwork.ParseProxyURL(flagProxy); err != nil {
+			common.PrintFlagsError(nodeCmd, "--proxy", err)
+		}
+		flagProxy = sebaknetwork.RedactProxyURL(proxyURL)
+
+		// the discovery does not go through the proxy
+		if natConfig.Method != sebaknetwork.NATNone {
+			common.PrintFlagsError(nodeCmd, "--nat", errors.New("must not be given with --proxy"))
+		}
+	}
+
 	for _, n := range flagValidators {
-		if n.Address() == kp.Address() {
+		if n.Address() == nodeAddress {
 			common.PrintFlagsError(nodeCmd, "--validator", fmt.Errorf("duplicated public address found"))
 		}
 		if n.Endpoint() == nodeEndpoint {
@@ -190,6 +475,143 @@ func parseFlagsNode() {
 	if storageConfig, err = sebakstorage.NewConfigFromString(flagStorageConfigString); err != nil {
 		common.PrintFlagsError(nodeCmd, "--storage", err)
 	}
+	if _, err = sebakstorage.NewSyncModeFromString(storageConfig.Query().Get("sync")); err != nil {
+		common.PrintFlagsError(nodeCmd, "--storage", err)
+	}
+
+	if startupQuorumTimeout, err = time.ParseDuration(flagStartupQuorumTimeout); err != nil || startupQuorumTimeout < 0 {
+		common.PrintFlagsError(nodeCmd, "--startup-quorum-timeout", errors.New("must be positive duration like '60s'"))
+	}
+
+	if proposerTimeout, err = time.ParseDuration(flagProposerTimeout); err != nil || proposerTimeout < 0 {
+		common.PrintFlagsError(nodeCmd, "--proposer-timeout", errors.New("must be positive duration like '5s'"))
+	}
+	if proposerTimeoutMin, err = time.ParseDuration(flagProposerTimeoutMin); err != nil || proposerTimeoutMin < 0 {
+		common.PrintFlagsError(nodeCmd, "--proposer-timeout-min", errors.New("must be positive duration like '500ms'"))
+	} else if proposerTimeoutMin > 0 && (proposerTimeout < 1 || proposerTimeoutMin > proposerTimeout) {
+		common.PrintFlagsError(nodeCmd, "--proposer-timeout-min", errors.New("must be given with --proposer-timeout, which is not less than it"))
+	}
+
+	if transactionOrderingPolicy, err = sebak.NewTransactionOrderingPolicyFromString(flagTransactionOrdering); err != nil {
+		common.PrintFlagsError(nodeCmd, "--transaction-ordering", err)
+	}
+
+	if revalidationPolicy, err = sebak.NewRevalidationPolicyFromString(flagCommitRevalidation); err != nil {
+		common.PrintFlagsError(nodeCmd, "--commit-revalidation", err)
+	}
+
+	if transactionPoolLimit, err = strconv.Atoi(flagTransactionPoolLimit); err != nil || transactionPoolLimit < 0 {
+		common.PrintFlagsError(nodeCmd, "--transaction-pool-limit", errors.New("must be positive integer"))
+	}
+	if transactionPoolAccountLimit, err = strconv.Atoi(flagTransactionPoolAccountLimit); err != nil || transactionPoolAccountLimit < 0 {
+		common.PrintFlagsError(nodeCmd, "--transaction-pool-account-limit", errors.New("must be positive integer"))
+	}
+
+	if selfTestMode, err = sebak.NewSelfTestModeFromString(flagSelfTestMode); err != nil {
+		common.PrintFlagsError(nodeCmd, "--selftest-mode", err)
+	}
+
+	if ballotAggregation, err = time.ParseDuration(flagBallotAggregation); err != nil || ballotAggregation < 0 {
+		common.PrintFlagsError(nodeCmd, "--ballot-aggregation", errors.New("must be positive duration like '5ms'"))
+	}
+	if flagBallotCompression && ballotAggregation == 0 {
+		common.PrintFlagsError(nodeCmd, "--ballot-compression", errors.New("--ballot-aggregation must be given"))
+	}
+	if ballotFanout, err = sebaknetwork.NewFanoutPolicyFromString(flagBallotFanout); err != nil {
+		common.PrintFlagsError(nodeCmd, "--ballot-fanout", err)
+	}
+	if gossipFanout, err = strconv.Atoi(flagGossipFanout); err != nil || gossipFanout < 0 {
+		common.PrintFlagsError(nodeCmd, "--gossip-fanout", errors.New("must be 0 or positive integer"))
+	}
+	if clientPoolConfig.FailureThreshold, err = strconv.Atoi(flagPeerCircuitFailures); err != nil || clientPoolConfig.FailureThreshold < 1 {
+		common.PrintFlagsError(nodeCmd, "--peer-circuit-failures", errors.New("must be positive integer"))
+	}
+	if clientPoolConfig.OpenTimeout, err = time.ParseDuration(flagPeerCircuitTimeout); err != nil || clientPoolConfig.OpenTimeout <= 0 {
+		common.PrintFlagsError(nodeCmd, "--peer-circuit-timeout", errors.New("must be positive duration like '10s'"))
+	}
+	peerBanConfig = sebaknetwork.DefaultPeerBanConfig
+	if peerBanConfig.BanDuration, err = time.ParseDuration(flagPeerBanDuration); err != nil || peerBanConfig.BanDuration < 0 {
+		common.PrintFlagsError(nodeCmd, "--peer-ban-duration", errors.New("must be duration like '24h'"))
+	}
+	if capabilities, err = sebaknetwork.ParseCapabilities(flagNetworkCapabilities); err != nil {
+		common.PrintFlagsError(nodeCmd, "--network-capabilities", err)
+	}
+	if blockSyncConfig.RangeSize, err = strconv.ParseUint(flagSyncRangeSize, 10, 64); err != nil || blockSyncConfig.RangeSize < 1 || blockSyncConfig.RangeSize > sebak.MaxSyncBlocks {
+		common.PrintFlagsError(nodeCmd, "--sync-range-size", fmt.Errorf("must be from 1 to %d", sebak.MaxSyncBlocks))
+	}
+	if blockSyncConfig.Parallel, err = strconv.Atoi(flagSyncParallel); err != nil || blockSyncConfig.Parallel < 1 {
+		common.PrintFlagsError(nodeCmd, "--sync-parallel", errors.New("must be positive integer"))
+	}
+	if blockSyncConfig.Timeout, err = time.ParseDuration(flagSyncTimeout); err != nil || blockSyncConfig.Timeout <= 0 {
+		common.PrintFlagsError(nodeCmd, "--sync-timeout", errors.New("must be positive duration like '10s'"))
+	}
+	if messageQueueConfig.ConsensusSize, err = strconv.Atoi(flagConsensusQueueSize); err != nil || messageQueueConfig.ConsensusSize < 1 {
+		common.PrintFlagsError(nodeCmd, "--consensus-queue-size", errors.New("must be positive integer"))
+	}
+	if messageQueueConfig.ClientSize, err = strconv.Atoi(flagClientQueueSize); err != nil || messageQueueConfig.ClientSize < 1 {
+		common.PrintFlagsError(nodeCmd, "--client-queue-size", errors.New("must be positive integer"))
+	}
+	if messageQueueConfig.SyncWorkers, err = strconv.Atoi(flagSyncServeWorkers); err != nil || messageQueueConfig.SyncWorkers < 1 {
+		common.PrintFlagsError(nodeCmd, "--sync-serve-workers", errors.New("must be positive integer"))
+	}
+	upgradeSignals = splitFlagList(flagUpgradeSignals)
+	if len(upgradeSignals) > sebak.MaxUpgradeSignals {
+		common.PrintFlagsError(nodeCmd, "--upgrade-signals", fmt.Errorf("too many features; the maximum is %d", sebak.MaxUpgradeSignals))
+	}
+	for _, feature := range upgradeSignals {
+		if !sebak.IsValidUpgradeFeature(feature) {
+			common.PrintFlagsError(nodeCmd, "--upgrade-signals", fmt.Errorf("invalid feature: '%s'", feature))
+		}
+	}
+
+	if len(flagFaucetSecretSeed) > 0 {
+		parseFlagsFaucet()
+	}
+	parseFlagsRateLimit()
+	parseFlagsAPI()
+
+	readinessConfig = sebak.NewDefaultReadinessConfig()
+	if readinessConfig.MaxBlocksBehind, err = strconv.ParseUint(flagReadyMaxBlocksBehind, 10, 64); err != nil {
+		common.PrintFlagsError(nodeCmd, "--ready-max-blocks-behind", errors.New("must be positive integer"))
+	}
+	if readinessConfig.MinValidators, err = strconv.Atoi(flagReadyMinValidators); err != nil || readinessConfig.MinValidators < 0 {
+		common.PrintFlagsError(nodeCmd, "--ready-min-validators", errors.New("must be positive integer"))
+	}
+
+	if loadWatermarks.CPU, err = strconv.ParseFloat(flagLoadMaxCPU, 64); err != nil || loadWatermarks.CPU < 0 {
+		common.PrintFlagsError(nodeCmd, "--load-max-cpu", errors.New("must be positive number"))
+	}
+	var maxMemoryMB uint64
+	if maxMemoryMB, err = strconv.ParseUint(flagLoadMaxMemoryMB, 10, 64); err != nil {
+		common.PrintFlagsError(nodeCmd, "--load-max-memory-mb", errors.New("must be positive integer"))
+	}
+	loadWatermarks.Memory = maxMemoryMB * 1024 * 1024
+	if loadWatermarks.DiskLatency, err = time.ParseDuration(flagLoadMaxDiskLatency); err != nil || loadWatermarks.DiskLatency < 0 {
+		common.PrintFlagsError(nodeCmd, "--load-max-disk-latency", errors.New("must be positive duration like '50ms'"))
+	}
+
+	for _, seed := range splitFlagList(flagPEXSeeds) {
+		endpoint, err := sebakcommon.ParseNodeEndpoint(seed)
+		if err != nil {
+			common.PrintFlagsError(nodeCmd, "--pex-seeds", fmt.Errorf("invalid endpoint, '%s'", seed))
+		}
+		pexSeeds = append(pexSeeds, endpoint)
+	}
+
+	if len(flagAdminAddr) > 0 {
+		parseFlagsAdmin()
+	}
+
+	if len(flagGRPCAddr) > 0 {
+		if _, _, err = net.SplitHostPort(flagGRPCAddr); err != nil {
+			common.PrintFlagsError(nodeCmd, "--grpc-addr", errors.New("must be like '0.0.0.0:12347'"))
+		}
+		grpcConfig = sebak.GRPCConfig{
+			Addr:        flagGRPCAddr,
+			TLSCertFile: flagTLSCertFile,
+			TLSKeyFile:  flagTLSKeyFile,
+		}
+	}
 
 	if logLevel, err = logging.LvlFromString(flagLogLevel); err != nil {
 		common.PrintFlagsError(nodeCmd, "--log-level", err)
@@ -213,10 +635,14 @@ func parseFlagsNode() {
 		}
 	}
 
+	// the level of all the loggers is changed together by the admin listener
+	levelHandler := sebak.NewLogLevelHandler(logLevel, logHandler)
+	adminConfig.LogHandler = levelHandler
+
 	log = logging.New("module", "main")
-	log.SetHandler(logging.LvlFilterHandler(logLevel, logHandler))
-	sebak.SetLogging(logLevel, logHandler)
-	sebaknetwork.SetLogging(logLevel, logHandler)
+	log.SetHandler(levelHandler)
+	sebak.SetLogging(logging.LvlDebug, levelHandler)
+	sebaknetwork.SetLogging(logging.LvlDebug, levelHandler)
 
 	log.Info("Starting Sebak")
 
@@ -224,18 +650,83 @@ func parseFlagsNode() {
 	parsedFlags := []interface{}{}
 	parsedFlags = append(parsedFlags, "\n\network-id", flagNetworkID)
 	parsedFlags = append(parsedFlags, "\n\tendpoint", flagEndpointString)
+	parsedFlags = append(parsedFlags, "\n\tadvertise-endpoint", flagAdvertiseEndpoint)
+	parsedFlags = append(parsedFlags, "\n\tnat", flagNAT)
+	parsedFlags = append(parsedFlags, "\n\tproxy", flagProxy)
 	parsedFlags = append(parsedFlags, "\n\tstorage", flagStorageConfigString)
 	parsedFlags = append(parsedFlags, "\n\ttls-cert", flagTLSCertFile)
 	parsedFlags = append(parsedFlags, "\n\ttls-key", flagTLSKeyFile)
+	parsedFlags = append(parsedFlags, "\n\ttls-ca", flagTLSCAFile)
+	parsedFlags = append(parsedFlags, "\n\ttls-client-auth", flagTLSClientAuth)
 	parsedFlags = append(parsedFlags, "\n\tlog-level", flagLogLevel)
 	parsedFlags = append(parsedFlags, "\n\tlog-output", flagLogOutput)
+	parsedFlags = append(parsedFlags, "\n\tstartup-quorum-timeout", flagStartupQuorumTimeout)
+	parsedFlags = append(parsedFlags, "\n\ttransaction-ordering", flagTransactionOrdering)
+	parsedFlags = append(parsedFlags, "\n\tcommit-revalidation", flagCommitRevalidation)
+	parsedFlags = append(parsedFlags, "\n\tproposer-timeout", flagProposerTimeout)
+	parsedFlags = append(parsedFlags, "\n\tproposer-timeout-min", flagProposerTimeoutMin)
+	parsedFlags = append(parsedFlags, "\n\ttransaction-pool-limit", flagTransactionPoolLimit)
+	parsedFlags = append(parsedFlags, "\n\ttransaction-pool-account-limit", flagTransactionPoolAccountLimit)
+	parsedFlags = append(parsedFlags, "\n\tselftest-mode", flagSelfTestMode)
+	parsedFlags = append(parsedFlags, "\n\tballot-aggregation", flagBallotAggregation)
+	parsedFlags = append(parsedFlags, "\n\tballot-compression", flagBallotCompression)
+	parsedFlags = append(parsedFlags, "\n\tballot-fanout", flagBallotFanout)
+	parsedFlags = append(parsedFlags, "\n\tgossip-fanout", flagGossipFanout)
+	parsedFlags = append(parsedFlags, "\n\tpeer-circuit-failures", flagPeerCircuitFailures)
+	parsedFlags = append(parsedFlags, "\n\tpeer-circuit-timeout", flagPeerCircuitTimeout)
+	parsedFlags = append(parsedFlags, "\n\tpeer-ban-duration", flagPeerBanDuration)
+	parsedFlags = append(parsedFlags, "\n\tnetwork-capabilities", flagNetworkCapabilities)
+	parsedFlags = append(parsedFlags, "\n\tsync-range-size", flagSyncRangeSize)
+	parsedFlags = append(parsedFlags, "\n\tsync-parallel", flagSyncParallel)
+	parsedFlags = append(parsedFlags, "\n\tsync-timeout", flagSyncTimeout)
+	parsedFlags = append(parsedFlags, "\n\tconsensus-queue-size", flagConsensusQueueSize)
+	parsedFlags = append(parsedFlags, "\n\tclient-queue-size", flagClientQueueSize)
+	parsedFlags = append(parsedFlags, "\n\tsync-serve-workers", flagSyncServeWorkers)
+	parsedFlags = append(parsedFlags, "\n\tupgrade-signals", flagUpgradeSignals)
+	parsedFlags = append(parsedFlags, "\n\tgraphql", flagGraphQL)
+	parsedFlags = append(parsedFlags, "\n\tforwarding-receipts", flagForwardingReceipts)
+	if faucet != nil {
+		parsedFlags = append(parsedFlags, "\n\tfaucet", faucet.Address())
+		parsedFlags = append(parsedFlags, "\n\tfaucet-amount", flagFaucetAmount)
+		parsedFlags = append(parsedFlags, "\n\tfaucet-address-quota", flagFaucetAddressQuota)
+		parsedFlags = append(parsedFlags, "\n\tfaucet-ip-quota", flagFaucetIPQuota)
+	}
+	parsedFlags = append(parsedFlags, "\n\trate-limit", flagRateLimit)
+	parsedFlags = append(parsedFlags, "\n\tapi-key-rate-limit", flagAPIKeyRateLimit)
+	parsedFlags = append(parsedFlags, "\n\tcors-origins", flagCORSOrigins)
+	parsedFlags = append(parsedFlags, "\n\ttrusted-proxies", flagTrustedProxies)
+	parsedFlags = append(parsedFlags, "\n\tapi-base-path", apiConfig.BasePath)
+	parsedFlags = append(parsedFlags, "\n\tapi-cache-size", flagAPICacheSize)
+	parsedFlags = append(parsedFlags, "\n\tapi-deprecate", flagAPIDeprecate)
+	parsedFlags = append(parsedFlags, "\n\tready-max-blocks-behind", flagReadyMaxBlocksBehind)
+	parsedFlags = append(parsedFlags, "\n\tready-min-validators", flagReadyMinValidators)
+	parsedFlags = append(parsedFlags, "\n\tload-max-cpu", flagLoadMaxCPU)
+	parsedFlags = append(parsedFlags, "\n\tload-max-memory-mb", flagLoadMaxMemoryMB)
+	parsedFlags = append(parsedFlags, "\n\tload-max-disk-latency", flagLoadMaxDiskLatency)
+	parsedFlags = append(parsedFlags, "\n\tpex-seeds", flagPEXSeeds)
+	if !adminConfig.IsEmpty() {
+		parsedFlags = append(parsedFlags, "\n\tadmin-addr", flagAdminAddr)
+		parsedFlags = append(parsedFlags, "\n\tadmin-client-ca", flagAdminClientCA)
+	}
+	if !grpcConfig.IsEmpty() {
+		parsedFlags = append(parsedFlags, "\n\tgrpc-addr", flagGRPCAddr)
+	}
+	if thresholdSigner != nil {
+		parsedFlags = append(parsedFlags, "\n\tsigners", flagSigners)
+		parsedFlags = append(parsedFlags, "\n\tsigner-threshold", flagSignerThreshold)
+		parsedFlags = append(parsedFlags, "\n\tsigner-ca", flagSignerCA)
+	}
+	if sessionKeySigner != nil {
+		parsedFlags = append(parsedFlags, "\n\tsession-key", sessionKeySigner.Certificate().B.SessionKey)
+		parsedFlags = append(parsedFlags, "\n\tsession-expires-at", sessionKeySigner.Certificate().B.ExpiresAt)
+	}
 
 	var vl []interface{}
 	for i, v := range flagValidators {
 		vl = append(vl, fmt.Sprintf("\n\tvalidator#%d", i))
 		vl = append(
 			vl,
-			fmt.Sprintf("alias=%s address=%s endpoint=%s", v.Alias(), v.Address(), v.Endpoint()),
+			fmt.Sprintf("alias=%s address=%s endpoint=%s", v.Alias(), v.Address(), validatorEndpointString(v)),
 		)
 	}
 	parsedFlags = append(parsedFlags, vl...)
@@ -247,15 +738,26 @@ func parseFlagsNode() {
 	}
 }
 
+func validatorEndpointString(v *sebakcommon.Validator) string {
+	if v.Endpoint() == nil {
+		return "<discovered>"
+	}
+
+	return v.Endpoint().String()
+}
+
 func runNode() {
-	// create current Node
-	currentNode, err := sebakcommon.NewValidator(kp.Address(), nodeEndpoint, "")
+	// create current Node; it is known to the other nodes by the advertised
+	// endpoint, but the network listens on --endpoint
+	endpoint := resolveAdvertiseEndpoint()
+	currentNode, err := sebakcommon.NewValidator(nodeAddress, endpoint, "")
 	if err != nil {
 		log.Error("failed to launch main node", "error", err)
 		return
 	}
-	currentNode.SetKeypair(kp)
-	currentNode.AddValidators(flagValidators...)
+	if kp != nil {
+		currentNode.SetKeypair(kp)
+	}
 
 	// create network
 	nt, err := sebaknetwork.NewNetwork(nodeEndpoint)
@@ -264,9 +766,69 @@ func runNode() {
 
 		os.Exit(1)
 	}
+	if h2n, ok := nt.(*sebaknetwork.HTTP2Network); ok && proxyURL != nil {
+		h2n.SetProxy(proxyURL)
+	}
+
+	st, err := sebakstorage.NewStorage(storageConfig)
+	if err != nil {
+		log.Crit("failed to initialize storage", "error", err)
+
+		os.Exit(1)
+	}
+
+	if flagSelfTest {
+		runSelfTest(st)
+	}
+
+	// the validators of flags precede the validators of genesis
+	if genesis, err := sebak.GetGenesis(st); err == nil {
+		if genesis.NetworkID != flagNetworkID {
+			log.Crit("network id does not match with genesis", "genesis", genesis.NetworkID)
+
+			os.Exit(1)
+		}
+
+		validators, err := genesis.GetValidators(nodeAddress)
+		if err != nil {
+			log.Crit("failed to load validators of genesis", "error", err)
+
+			os.Exit(1)
+		}
+		currentNode.AddValidators(validators...)
+	} else if err != sebakerror.ErrorGenesisNotApplied {
+		log.Crit("failed to load genesis", "error", err)
 
-	// TODO policy threshold can be set in cmd options
-	policy, _ := sebak.NewDefaultVotingThresholdPolicy(100, 30, 30)
+		os.Exit(1)
+	}
+	currentNode.AddValidators(flagValidators...)
+
+	if len(currentNode.GetValidators()) < 1 {
+		log.Crit("no validators; set --validator or the validators of genesis")
+
+		os.Exit(1)
+	}
+
+	networkParameters, err := sebak.GetNetworkParameters(st)
+	if err != nil {
+		log.Crit("failed to load network parameters", "error", err)
+
+		os.Exit(1)
+	}
+	log.Debug(
+		"network parameters loaded",
+		"block-time", networkParameters.BlockTime,
+		"threshold-init", networkParameters.ThresholdINIT,
+		"threshold-sign", networkParameters.ThresholdSIGN,
+		"threshold-accept", networkParameters.ThresholdACCEPT,
+	)
+
+	policy, err := networkParameters.VotingThresholdPolicy()
+	if err != nil {
+		log.Crit("invalid voting thresholds", "error", err)
+
+		os.Exit(1)
+	}
 	policy.SetValidators(len(currentNode.GetValidators()) + 1) // including 'self'
 
 	isaac, err := sebak.NewISAAC([]byte(flagNetworkID), currentNode, policy)
@@ -274,17 +836,376 @@ func runNode() {
 		log.Error("failed to launch consensus", "error", err)
 		return
 	}
+	if thresholdSigner != nil {
+		isaac.SetSigner(thresholdSigner)
+	} else if sessionKeySigner != nil {
+		isaac.SetSigner(sessionKeySigner)
+	}
+	if err := isaac.SetUpgradeSignals(upgradeSignals); err != nil {
+		log.Error("failed to set upgrade signals", "error", err)
+		return
+	}
 
-	st, err := sebakstorage.NewStorage(storageConfig)
+	nr := sebak.NewNodeRunner(flagNetworkID, currentNode, policy, nt, isaac, st)
+	nr.SetNetworkParameters(networkParameters)
+	if sessionKeySigner != nil {
+		nr.SetMessageSigner(sessionKeySigner)
+	}
+	nr.SetStartupQuorumTimeout(startupQuorumTimeout)
+	nr.SetTransactionOrderingPolicy(transactionOrderingPolicy)
+	nr.SetRevalidationPolicy(revalidationPolicy)
+	nr.SetProposerTimeout(proposerTimeout)
+	if proposerTimeoutMin > 0 {
+		if err := nr.SetAdaptiveProposerTimeout(proposerTimeoutMin); err != nil {
+			log.Error("failed to set adaptive proposer timeout", "error", err)
+			return
+		}
+	}
+	nr.TransactionPool().SetLimits(transactionPoolLimit, transactionPoolAccountLimit)
+	nr.SetSelfTestMode(selfTestMode)
+	nr.ConnectionManager().SetBallotAggregation(ballotAggregation, flagBallotCompression)
+	nr.ConnectionManager().SetFanoutPolicy(ballotFanout)
+	nr.ConnectionManager().ClientPool().SetConfig(clientPoolConfig)
+	nr.ConnectionManager().SetCapabilities(capabilities...)
+	nr.TransactionGossip().SetFanout(gossipFanout)
+	nr.PeerBans().SetConfig(peerBanConfig)
+	nr.BlockSync().SetConfig(blockSyncConfig)
+	nr.MessageQueue().SetConfig(messageQueueConfig)
+	nr.SetGraphQL(flagGraphQL)
+	nr.SetForwardingReceipts(flagForwardingReceipts)
+	nr.SetFaucet(faucet)
+	nr.SetRateLimiter(rateLimiter)
+	nr.SetAPIConfig(apiConfig)
+	nr.SetReadinessConfig(readinessConfig)
+	nr.SetLoadWatermarks(loadWatermarks)
+	nr.SetPeerExchangeSeeds(pexSeeds)
+	if err := nr.SetAdminConfig(adminConfig); err != nil {
+		log.Error("failed to set admin listener", "error", err)
+		return
+	}
+	nr.SetGRPCConfig(grpcConfig)
+	if err := nr.Start(); err != nil {
+		log.Crit("failed to start node", "error", err)
+
+		os.Exit(1)
+	}
+}
+
+func parseFlagsFaucet() {
+	var err error
+
+	config := sebak.NewDefaultFaucetConfig()
+	if config.Amount, err = common.ParseAmountFromString(flagFaucetAmount); err != nil || config.Amount < 1 {
+		common.PrintFlagsError(nodeCmd, "--faucet-amount", errors.New("must be positive amount"))
+	}
+	if config.AddressQuota, err = strconv.Atoi(flagFaucetAddressQuota); err != nil || config.AddressQuota < 0 {
+		common.PrintFlagsError(nodeCmd, "--faucet-address-quota", errors.New("must be positive integer"))
+	}
+	if config.IPQuota, err = strconv.Atoi(flagFaucetIPQuota); err != nil || config.IPQuota < 0 {
+		common.PrintFlagsError(nodeCmd, "--faucet-ip-quota", errors.New("must be positive integer"))
+	}
+	for _, token := range strings.Split(flagFaucetTokens, ",") {
+		if token = strings.TrimSpace(token); len(token) > 0 {
+			config.Tokens = append(config.Tokens, token)
+		}
+	}
+
+	parsedKP, err := keypair.Parse(flagFaucetSecretSeed)
 	if err != nil {
-		log.Crit("failed to initialize storage", "error", err)
+		common.PrintFlagsError(nodeCmd, "--faucet-secret-seed", err)
+	}
+	full, ok := parsedKP.(*keypair.Full)
+	if !ok {
+		common.PrintFlagsError(nodeCmd, "--faucet-secret-seed", errors.New("must be secret seed"))
+	}
 
+	if faucet, err = sebak.NewFaucet([]byte(flagNetworkID), full, config); err != nil {
+		common.PrintFlagsError(nodeCmd, "--faucet-secret-seed", err)
+	}
+}
+
+// parseFlagsAdmin makes the admin listener; it must have the token or the
+// client CA.
+func parseFlagsAdmin() {
+	if _, _, err := net.SplitHostPort(flagAdminAddr); err != nil {
+		common.PrintFlagsError(nodeCmd, "--admin-addr", errors.New("must be like '127.0.0.1:12346'"))
+	}
+	if len(flagAdminToken) < 1 && len(flagAdminClientCA) < 1 {
+		common.PrintFlagsError(nodeCmd, "--admin-addr", errors.New("--admin-token or --admin-client-ca must be given"))
+	}
+
+	adminConfig.Addr = flagAdminAddr
+	adminConfig.TLSCertFile = flagTLSCertFile
+	adminConfig.TLSKeyFile = flagTLSKeyFile
+	adminConfig.Token = flagAdminToken
+
+	if len(flagAdminClientCA) > 0 {
+		b, err := ioutil.ReadFile(flagAdminClientCA)
+		if err != nil {
+			common.PrintFlagsError(nodeCmd, "--admin-client-ca", err)
+		}
+		adminConfig.ClientCAs = x509.NewCertPool()
+		if !adminConfig.ClientCAs.AppendCertsFromPEM(b) {
+			common.PrintFlagsError(nodeCmd, "--admin-client-ca", errors.New("no PEM certificate found"))
+		}
+	}
+}
+
+// runSelfTest runs the full self test, prints the results and exits.
+func runSelfTest(st *sebakstorage.LevelDBBackend) {
+	results, err := sebak.RunSelfTest(st, []byte(flagNetworkID), sebak.SelfTestModeFull)
+	for _, result := range results {
+		status := "ok"
+		if !result.IsPassed() {
+			status = "FAILED: " + result.Error
+		}
+		fmt.Printf("%-20s %-12s %s\n", result.Name, result.Elapsed, status)
+	}
+
+	if err != nil {
 		os.Exit(1)
 	}
-	nr := sebak.NewNodeRunner(flagNetworkID, currentNode, policy, nt, isaac, st)
-	if err := nr.Start(); err != nil {
-		log.Crit("failed to start node", "error", err)
+	os.Exit(0)
+}
+
+// parseFlagsRateLimit makes the rate limiter of API; without the limit and the
+// API keys, the API is not limited.
+func parseFlagsRateLimit() {
+	var ipLimit, keyLimit float64
+	var err error
+	if ipLimit, err = strconv.ParseFloat(flagRateLimit, 64); err != nil || ipLimit < 0 {
+		common.PrintFlagsError(nodeCmd, "--rate-limit", errors.New("must be positive number"))
+	}
+	if keyLimit, err = strconv.ParseFloat(flagAPIKeyRateLimit, 64); err != nil || keyLimit < 0 {
+		common.PrintFlagsError(nodeCmd, "--api-key-rate-limit", errors.New("must be positive number"))
+	}
+
+	config := sebak.RateLimitConfig{
+		IP:     sebak.NewRateLimitRule(ipLimit),
+		APIKey: sebak.NewRateLimitRule(keyLimit),
+	}
+	for _, key := range strings.Split(flagAPIKeys, ",") {
+		if key = strings.TrimSpace(key); len(key) > 0 {
+			config.APIKeys = append(config.APIKeys, key)
+		}
+	}
+	if keyLimit > 0 && len(config.APIKeys) < 1 {
+		common.PrintFlagsError(nodeCmd, "--api-key-rate-limit", errors.New("--api-keys must be given"))
+	}
+
+	if ipLimit > 0 || len(config.APIKeys) > 0 {
+		rateLimiter = sebak.NewRateLimiter(config)
+	}
+}
+
+// resolveAdvertiseEndpoint returns the endpoint, which the other nodes connect
+// to; it is --advertise-endpoint or the public address discovered by --nat.
+// The port mapping of UPnP is renewed before the lease is expired.
+func resolveAdvertiseEndpoint() *sebakcommon.Endpoint {
+	if advertiseEndpoint != nil {
+		log.Info("advertise endpoint", "endpoint", advertiseEndpoint.String())
+		return advertiseEndpoint
+	}
+
+	if natConfig.Method == sebaknetwork.NATNone {
+		if ip := net.ParseIP((*url.URL)(nodeEndpoint).Hostname()); ip != nil && ip.IsUnspecified() {
+			log.Warn(
+				"the unspecified address of --endpoint is advertised; set --advertise-endpoint or --nat for the other nodes",
+				"endpoint", nodeEndpoint.String(),
+			)
+		}
+		return nodeEndpoint
+	}
+
+	port, err := strconv.Atoi((*url.URL)(nodeEndpoint).Port())
+	if err != nil {
+		log.Crit("invalid port of --endpoint", "error", err)
+
+		os.Exit(1)
+	}
+	mapping, err := natConfig.Discover(port, sebaknetwork.NATDiscoverTimeout)
+	if err != nil {
+		log.Crit("failed to discover the public address", "nat", flagNAT, "error", err)
 
 		os.Exit(1)
 	}
+
+	endpoint := &sebakcommon.Endpoint{
+		Scheme: nodeEndpoint.Scheme,
+		Host:   net.JoinHostPort(mapping.IP.String(), strconv.Itoa(mapping.Port)),
+	}
+	log.Info("public address discovered", "nat", flagNAT, "endpoint", endpoint.String())
+
+	if mapping.IsMapped() {
+		go func() {
+			for range time.Tick(sebaknetwork.UPnPLease / 2) {
+				if err := mapping.Renew(); err != nil {
+					log.Error("failed to renew the port mapping", "error", err)
+				}
+			}
+		}()
+	}
+
+	return endpoint
+}
+
+// parseFlagsTLSSelfSigned generates the self-signed certificate of the host of
+// --endpoint and --advertise-endpoint; the existing certificate is used again.
+func parseFlagsTLSSelfSigned() {
+	_, certErr := os.Stat(flagTLSCertFile)
+	_, keyErr := os.Stat(flagTLSKeyFile)
+	if certErr == nil && keyErr == nil {
+		return
+	} else if certErr == nil || keyErr == nil {
+		common.PrintFlagsError(nodeCmd, "--tls-self-signed", errors.New("only one of --tls-cert and --tls-key exists"))
+	}
+
+	hosts := []string{"localhost", "127.0.0.1"}
+	if endpoint, err := sebakcommon.ParseNodeEndpoint(flagEndpointString); err == nil {
+		if host := (*url.URL)(endpoint).Hostname(); len(host) > 0 {
+			hosts = append(hosts, host)
+		}
+	}
+	if len(flagAdvertiseEndpoint) > 0 {
+		if endpoint, err := sebakcommon.ParseNodeEndpoint(flagAdvertiseEndpoint); err == nil {
+			if host := (*url.URL)(endpoint).Hostname(); len(host) > 0 {
+				hosts = append(hosts, host)
+			}
+		}
+	}
+
+	if err := sebaknetwork.GenerateSelfSignedCertificate(flagTLSCertFile, flagTLSKeyFile, hosts...); err != nil {
+		common.PrintFlagsError(nodeCmd, "--tls-self-signed", err)
+	}
+}
+
+// parseFlagsSigners makes the signer of the node's own messages by the signer
+// daemons; the node does not have the secret seed of validator.
+func parseFlagsSigners() {
+	var err error
+
+	if len(flagKPSecretSeed) > 0 {
+		common.PrintFlagsError(nodeCmd, "--secret-seed", errors.New("must not be given with --signers"))
+	}
+	if _, err = keypair.Parse(flagAddress); err != nil {
+		common.PrintFlagsError(nodeCmd, "--address", err)
+	}
+	nodeAddress = flagAddress
+
+	var threshold int
+	if threshold, err = strconv.Atoi(flagSignerThreshold); err != nil || threshold < 2 {
+		common.PrintFlagsError(nodeCmd, "--signer-threshold", errors.New("must be integer greater than 1"))
+	}
+	var timeout time.Duration
+	if timeout, err = time.ParseDuration(flagSignerTimeout); err != nil || timeout <= 0 {
+		common.PrintFlagsError(nodeCmd, "--signer-timeout", errors.New("must be positive duration like '3s'"))
+	}
+	if len(flagSignerCA) < 1 {
+		common.PrintFlagsError(nodeCmd, "--signer-ca", errors.New("must be given with --signers"))
+	}
+	roots, err := sebaknetwork.LoadTLSCertPool(flagSignerCA)
+	if err != nil {
+		common.PrintFlagsError(nodeCmd, "--signer-ca", err)
+	}
+
+	var clients []sebak.ThresholdSignerClient
+	for _, endpoint := range strings.Split(flagSigners, ",") {
+		if endpoint = strings.TrimSpace(endpoint); len(endpoint) < 1 {
+			continue
+		}
+		parsed, err := sebakcommon.ParseNodeEndpoint(endpoint)
+		if err != nil {
+			common.PrintFlagsError(nodeCmd, "--signers", err)
+		}
+
+		client, err := sebak.NewHTTPThresholdSignerClient(parsed.String(), flagSignerToken, timeout, roots)
+		if err != nil {
+			common.PrintFlagsError(nodeCmd, "--signers", err)
+		}
+		clients = append(clients, client)
+	}
+
+	if thresholdSigner, err = sebak.NewThresholdNodeSigner(nodeAddress, []byte(flagNetworkID), threshold, clients); err != nil {
+		common.PrintFlagsError(nodeCmd, "--signers", err)
+	}
+}
+
+// parseFlagsSessionKey makes the signer of the node's own messages by the
+// session key; the identity key of validator stays offline and only certifies
+// the session key.
+func parseFlagsSessionKey() {
+	var err error
+
+	if len(flagKPSecretSeed) > 0 {
+		common.PrintFlagsError(nodeCmd, "--secret-seed", errors.New("must not be given with --session-seed"))
+	}
+	if _, err = keypair.Parse(flagAddress); err != nil {
+		common.PrintFlagsError(nodeCmd, "--address", err)
+	}
+	nodeAddress = flagAddress
+
+	var parsedKP keypair.KP
+	if parsedKP, err = keypair.Parse(flagSessionSeed); err != nil {
+		common.PrintFlagsError(nodeCmd, "--session-seed", err)
+	}
+	session, ok := parsedKP.(*keypair.Full)
+	if !ok {
+		common.PrintFlagsError(nodeCmd, "--session-seed", errors.New("must be secret seed"))
+	}
+
+	var b []byte
+	if b, err = ioutil.ReadFile(flagSessionCertificate); err != nil {
+		common.PrintFlagsError(nodeCmd, "--session-certificate", err)
+	}
+	var certificate sebak.SessionKeyCertificate
+	if certificate, err = sebak.NewSessionKeyCertificateFromJSON(b); err != nil {
+		common.PrintFlagsError(nodeCmd, "--session-certificate", err)
+	}
+	if certificate.B.Identity != nodeAddress {
+		common.PrintFlagsError(nodeCmd, "--session-certificate", errors.New("must be certified by --address"))
+	}
+
+	if sessionKeySigner, err = sebak.NewSessionKeyNodeSigner(session, certificate, []byte(flagNetworkID)); err != nil {
+		common.PrintFlagsError(nodeCmd, "--session-certificate", err)
+	}
+}
+
+func splitFlagList(v string) (list []string) {
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); len(item) > 0 {
+			list = append(list, item)
+		}
+	}
+
+	return
+}
+
+// parseFlagsAPI makes the configuration of the API for the browsers and the
+// reverse proxy.
+func parseFlagsAPI() {
+	var err error
+
+	apiConfig.CORSOrigins = splitFlagList(flagCORSOrigins)
+	for _, origin := range apiConfig.CORSOrigins {
+		if origin == "*" {
+			continue
+		}
+		if u, err := url.Parse(origin); err != nil || len(u.Scheme) < 1 || len(u.Host) < 1 || len(strings.Trim(u.Path, "/")) > 0 {
+			common.PrintFlagsError(nodeCmd, "--cors-origins", fmt.Errorf("invalid origin, '%s'; must be like 'https://explorer.example.com'", origin))
+		}
+	}
+
+	if apiConfig.TrustedProxies, err = sebak.ParseTrustedProxies(splitFlagList(flagTrustedProxies)); err != nil {
+		common.PrintFlagsError(nodeCmd, "--trusted-proxies", err)
+	}
+
+	apiConfig.BasePath = sebak.NormalizeAPIBasePath(flagAPIBasePath)
+
+	if apiConfig.CacheSize, err = strconv.Atoi(flagAPICacheSize); err != nil || apiConfig.CacheSize < 0 {
+		common.PrintFlagsError(nodeCmd, "--api-cache-size", errors.New("must be positive integer"))
+	}
+
+	if apiConfig.Deprecations, err = sebak.ParseAPIDeprecations(splitFlagList(flagAPIDeprecate)); err != nil {
+		common.PrintFlagsError(nodeCmd, "--api-deprecate", err)
+	}
 }
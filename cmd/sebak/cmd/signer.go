@@ -0,0 +1,27 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"boscoin.io/sebak/cmd/sebak/cmd/signer"
+)
+
+var (
+	signerCmd *cobra.Command
+)
+
+func init() {
+	signerCmd = &cobra.Command{
+		Use:   "signer",
+		Short: "Threshold signer of validator",
+		Run: func(c *cobra.Command, args []string) {
+			if len(args) < 1 {
+				c.Usage()
+			}
+		},
+	}
+
+	signerCmd.AddCommand(signer.SplitCmd)
+	signerCmd.AddCommand(signer.RunCmd)
+	rootCmd.AddCommand(signerCmd)
+}
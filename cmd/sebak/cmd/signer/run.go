@@ -0,0 +1,84 @@
+package signer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"boscoin.io/sebak/cmd/sebak/common"
+	"boscoin.io/sebak/lib"
+	"boscoin.io/sebak/lib/common"
+)
+
+var (
+	RunCmd *cobra.Command
+
+	flagShare       string = sebakcommon.GetENVValue("SEBAK_SIGNER_SHARE", "")
+	flagNetworkID   string = sebakcommon.GetENVValue("SEBAK_NETWORK_ID", "")
+	flagEndpoint    string = sebakcommon.GetENVValue("SEBAK_SIGNER_ENDPOINT", "https://0.0.0.0:12400")
+	flagTLSCertFile string = sebakcommon.GetENVValue("SEBAK_TLS_CERT", "sebak.crt")
+	flagTLSKeyFile  string = sebakcommon.GetENVValue("SEBAK_TLS_KEY", "sebak.key")
+	flagToken       string = sebakcommon.GetENVValue("SEBAK_SIGNER_TOKEN", "")
+)
+
+func init() {
+	RunCmd = &cobra.Command{
+		Use:   "run",
+		Short: "Run signer daemon, which signs the messages of validator with the share",
+		Run: func(c *cobra.Command, args []string) {
+			if len(flagNetworkID) < 1 {
+				common.PrintFlagsError(c, "--network-id", errors.New("--network-id must be given"))
+			}
+			if len(flagToken) < 1 {
+				common.PrintFlagsError(c, "--token", errors.New("--token must be given"))
+			}
+
+			b, err := ioutil.ReadFile(flagShare)
+			if err != nil {
+				common.PrintFlagsError(c, "--share", err)
+			}
+			var share sebak.ThresholdShare
+			if err = json.Unmarshal(b, &share); err != nil {
+				common.PrintFlagsError(c, "--share", err)
+			}
+			signer, err := sebak.NewThresholdSigner([]byte(flagNetworkID), share)
+			if err != nil {
+				common.PrintFlagsError(c, "--share", err)
+			}
+
+			endpoint, err := sebakcommon.ParseNodeEndpoint(flagEndpoint)
+			if err != nil {
+				common.PrintFlagsError(c, "--endpoint", err)
+			}
+
+			fmt.Fprintf(
+				os.Stdout,
+				"signer #%d of %d for %s listens on %s\n",
+				share.Index, share.Total, share.Validator, endpoint.String(),
+			)
+
+			server := &http.Server{
+				Addr:    endpoint.Host,
+				Handler: sebak.NewThresholdSignerHandler(signer, flagToken),
+			}
+			if err = server.ListenAndServeTLS(flagTLSCertFile, flagTLSKeyFile); err != nil {
+				fmt.Fprintf(os.Stderr, "failed to run signer: %v\n", err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	RunCmd.Flags().StringVar(&flagShare, "share", flagShare, "share file made by 'signer split'")
+	RunCmd.Flags().StringVar(&flagNetworkID, "network-id", flagNetworkID, "network id")
+	RunCmd.Flags().StringVar(&flagEndpoint, "endpoint", flagEndpoint, "endpoint uri to listen on")
+	RunCmd.Flags().StringVar(&flagTLSCertFile, "tls-cert", flagTLSCertFile, "tls certificate file")
+	RunCmd.Flags().StringVar(&flagTLSKeyFile, "tls-key", flagTLSKeyFile, "tls key file")
+	RunCmd.Flags().StringVar(&flagToken, "token", flagToken, "token, which the node must give")
+
+	RunCmd.MarkFlagRequired("share")
+}
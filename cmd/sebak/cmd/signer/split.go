@@ -0,0 +1,74 @@
+package signer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/spf13/cobra"
+	"github.com/stellar/go/keypair"
+
+	"boscoin.io/sebak/cmd/sebak/common"
+	"boscoin.io/sebak/lib"
+)
+
+var (
+	SplitCmd *cobra.Command
+
+	flagSecretSeed string
+	flagThreshold  string = "2"
+	flagTotal      string = "3"
+	flagOutput     string = "."
+)
+
+func init() {
+	SplitCmd = &cobra.Command{
+		Use:   "split",
+		Short: "Split the secret seed of validator into the shares of signers",
+		Run: func(c *cobra.Command, args []string) {
+			parsedKP, err := keypair.Parse(flagSecretSeed)
+			if err != nil {
+				common.PrintFlagsError(c, "--secret-seed", err)
+			}
+			kp, ok := parsedKP.(*keypair.Full)
+			if !ok {
+				common.PrintFlagsError(c, "--secret-seed", errors.New("must be secret seed"))
+			}
+
+			threshold, err := strconv.Atoi(flagThreshold)
+			if err != nil {
+				common.PrintFlagsError(c, "--threshold", err)
+			}
+			total, err := strconv.Atoi(flagTotal)
+			if err != nil {
+				common.PrintFlagsError(c, "--total", err)
+			}
+
+			shares, err := sebak.SplitKeypair(kp, threshold, total)
+			if err != nil {
+				common.PrintFlagsError(c, "--threshold", fmt.Errorf("must be between 2 and --total: %v", err))
+			}
+
+			for _, share := range shares {
+				b, _ := json.MarshalIndent(share, "", "  ")
+				name := filepath.Join(flagOutput, fmt.Sprintf("%s.share.%d.json", kp.Address(), share.Index))
+				if err = ioutil.WriteFile(name, b, 0600); err != nil {
+					fmt.Fprintf(os.Stderr, "failed to write share: %v\n", err)
+					os.Exit(1)
+				}
+				fmt.Fprintf(os.Stdout, "%s\n", name)
+			}
+		},
+	}
+
+	SplitCmd.Flags().StringVar(&flagSecretSeed, "secret-seed", flagSecretSeed, "secret seed of validator")
+	SplitCmd.Flags().StringVar(&flagThreshold, "threshold", flagThreshold, "number of shares needed to sign")
+	SplitCmd.Flags().StringVar(&flagTotal, "total", flagTotal, "number of shares")
+	SplitCmd.Flags().StringVar(&flagOutput, "output", flagOutput, "directory of the share files")
+
+	SplitCmd.MarkFlagRequired("secret-seed")
+}
@@ -0,0 +1,171 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"boscoin.io/sebak/lib"
+	"boscoin.io/sebak/lib/common"
+	"boscoin.io/sebak/lib/storage"
+
+	"boscoin.io/sebak/cmd/sebak/common"
+)
+
+var (
+	snapshotCmd *cobra.Command
+
+	flagSnapshotDir       string
+	flagSnapshotKeepLast  string = sebakcommon.GetENVValue("SEBAK_SNAPSHOT_KEEP_LAST", "0")
+	flagSnapshotKeepDaily string = sebakcommon.GetENVValue("SEBAK_SNAPSHOT_KEEP_DAILY", "0")
+	flagSnapshotForce     bool
+)
+
+func init() {
+	snapshotCmd = &cobra.Command{
+		Use:   "snapshot",
+		Short: "Storage snapshot management",
+		Run: func(c *cobra.Command, args []string) {
+			if len(args) < 1 {
+				c.Usage()
+			}
+		},
+	}
+
+	createCmd := &cobra.Command{
+		Use:   "create <name>",
+		Short: "create new named snapshot of storage",
+		Args:  cobra.ExactArgs(1),
+		Run: func(c *cobra.Command, args []string) {
+			policy := parseSnapshotRetentionPolicy(c)
+			st := openSnapshotStorage(c)
+			defer st.Close()
+
+			snapshot, err := sebakstorage.CreateSnapshot(st, flagSnapshotDir, args[0])
+			if err != nil {
+				common.PrintFlagsError(c, "<name>", err)
+			}
+			fmt.Printf("snapshot, '%s' created: %s\n", snapshot.Name, snapshot.Path())
+
+			removed, err := sebakstorage.ApplySnapshotRetention(flagSnapshotDir, policy)
+			for _, s := range removed {
+				fmt.Printf("snapshot, '%s' removed by retention policy\n", s.Name)
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "error: failed to apply retention policy: %v\n", err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "list snapshots",
+		Args:  cobra.NoArgs,
+		Run: func(c *cobra.Command, args []string) {
+			snapshots, err := sebakstorage.ListSnapshots(flagSnapshotDir)
+			if err != nil {
+				common.PrintFlagsError(c, "--snapshot-dir", err)
+			}
+
+			fmt.Printf("%-30s %-27s %12s %15s\n", "NAME", "CREATED", "SIZE", "AGE")
+			for _, s := range snapshots {
+				size, _ := s.Size()
+				fmt.Printf(
+					"%-30s %-27s %12d %15s\n",
+					s.Name,
+					s.Created.Format(time.RFC3339),
+					size,
+					s.Age().Truncate(time.Second),
+				)
+			}
+		},
+	}
+
+	restoreCmd := &cobra.Command{
+		Use:   "restore <name>",
+		Short: "replace storage with the snapshot; node must be stopped. the snapshot below the last irreversible block is refused without --force",
+		Args:  cobra.ExactArgs(1),
+		Run: func(c *cobra.Command, args []string) {
+			config, err := sebakstorage.NewConfigFromString(flagStorageConfigString)
+			if err != nil {
+				common.PrintFlagsError(c, "--storage", err)
+			}
+
+			var check sebakstorage.SnapshotRestoreCheck
+			if !flagSnapshotForce {
+				check = sebak.CheckSnapshotFinality
+			}
+			if err = sebakstorage.RestoreSnapshotWithCheck(flagSnapshotDir, args[0], config, check); err != nil {
+				common.PrintFlagsError(c, "<name>", err)
+			}
+			fmt.Printf("snapshot, '%s' restored to %s\n", args[0], config)
+		},
+	}
+
+	removeCmd := &cobra.Command{
+		Use:   "remove <name>",
+		Short: "remove snapshot",
+		Args:  cobra.ExactArgs(1),
+		Run: func(c *cobra.Command, args []string) {
+			if err := sebakstorage.RemoveSnapshot(flagSnapshotDir, args[0]); err != nil {
+				common.PrintFlagsError(c, "<name>", err)
+			}
+			fmt.Printf("snapshot, '%s' removed\n", args[0])
+		},
+	}
+
+	var err error
+	var currentDirectory string
+	if currentDirectory, err = os.Getwd(); err != nil {
+		common.PrintFlagsError(snapshotCmd, "--snapshot-dir", err)
+	}
+	if currentDirectory, err = filepath.Abs(currentDirectory); err != nil {
+		common.PrintFlagsError(snapshotCmd, "--snapshot-dir", err)
+	}
+
+	flagSnapshotDir = sebakcommon.GetENVValue("SEBAK_SNAPSHOT_DIR", filepath.Join(currentDirectory, "snapshots"))
+
+	snapshotCmd.PersistentFlags().StringVar(&flagStorageConfigString, "storage", flagStorageConfigString, "storage uri")
+	snapshotCmd.PersistentFlags().StringVar(&flagSnapshotDir, "snapshot-dir", flagSnapshotDir, "directory to keep snapshots")
+	createCmd.Flags().StringVar(&flagSnapshotKeepLast, "keep-last", flagSnapshotKeepLast, "keep only the latest N snapshots; 0 is unlimited")
+	restoreCmd.Flags().BoolVar(&flagSnapshotForce, "force", flagSnapshotForce, "restore the snapshot below the last irreversible block")
+	createCmd.Flags().StringVar(&flagSnapshotKeepDaily, "keep-daily", flagSnapshotKeepDaily, "keep the newest snapshot of each day for the latest N days")
+
+	snapshotCmd.AddCommand(createCmd, listCmd, restoreCmd, removeCmd)
+	rootCmd.AddCommand(snapshotCmd)
+}
+
+func parseSnapshotRetentionPolicy(c *cobra.Command) (policy sebakstorage.SnapshotRetentionPolicy) {
+	var err error
+	if policy.KeepLast, err = strconv.Atoi(flagSnapshotKeepLast); err != nil || policy.KeepLast < 0 {
+		common.PrintFlagsError(c, "--keep-last", errors.New("must be positive integer"))
+	}
+	if policy.KeepDaily, err = strconv.Atoi(flagSnapshotKeepDaily); err != nil || policy.KeepDaily < 0 {
+		common.PrintFlagsError(c, "--keep-daily", errors.New("must be positive integer"))
+	}
+
+	return
+}
+
+func openSnapshotStorage(c *cobra.Command) *sebakstorage.LevelDBBackend {
+	config, err := sebakstorage.NewConfigFromString(flagStorageConfigString)
+	if err != nil {
+		common.PrintFlagsError(c, "--storage", err)
+	}
+	if config.Scheme != "file" {
+		common.PrintFlagsError(c, "--storage", errors.New("only file storage can be snapshotted"))
+	}
+
+	st, err := sebakstorage.NewStorage(config)
+	if err != nil {
+		common.PrintFlagsError(c, "--storage", fmt.Errorf("failed to initialize storage: %v", err))
+	}
+
+	return st
+}
@@ -0,0 +1,288 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/stellar/go/keypair"
+
+	"boscoin.io/sebak/lib"
+	"boscoin.io/sebak/lib/common"
+	"boscoin.io/sebak/lib/error"
+	"boscoin.io/sebak/lib/network"
+
+	"boscoin.io/sebak/cmd/sebak/common"
+)
+
+var (
+	txCmd *cobra.Command
+
+	flagTxAppend    bool
+	flagTxThreshold string = "1"
+	flagTxSigners   string
+	flagTxOutput    string
+	flagTxIn        string
+	flagTxOut       string
+)
+
+func init() {
+	txCmd = &cobra.Command{
+		Use:   "tx",
+		Short: "Transaction signing and submission",
+		Run: func(c *cobra.Command, args []string) {
+			if len(args) < 1 {
+				c.Usage()
+			}
+		},
+	}
+
+	signCmd := &cobra.Command{
+		Use:   "sign <file> | --in <file>",
+		Short: "sign transaction into new envelope, or append signature to envelope with --append; with --in, sign the transaction itself offline",
+		Args:  cobra.MaximumNArgs(1),
+		Run: func(c *cobra.Command, args []string) {
+			kp := parseTxSecretSeed(c)
+
+			if len(flagTxIn) > 0 {
+				if len(args) > 0 {
+					common.PrintFlagsError(c, "--in", errors.New("<file> and --in can not be given together"))
+				}
+				signTxOffline(c, kp)
+				return
+			}
+			if len(args) < 1 {
+				common.PrintFlagsError(c, "<file>", errors.New("<file> or --in must be given"))
+			}
+
+			var err error
+			var envelope sebak.TransactionEnvelope
+			if flagTxAppend {
+				envelope = readTxEnvelope(c, args[0])
+			} else {
+				var b []byte
+				if b, err = ioutil.ReadFile(args[0]); err != nil {
+					common.PrintFlagsError(c, "<file>", err)
+				}
+
+				var tx sebak.Transaction
+				if tx, err = sebak.NewTransactionFromJSON(b); err != nil {
+					common.PrintFlagsError(c, "<file>", fmt.Errorf("invalid transaction: %v", err))
+				}
+
+				var threshold int
+				if threshold, err = strconv.Atoi(flagTxThreshold); err != nil {
+					common.PrintFlagsError(c, "--threshold", errors.New("must be positive integer"))
+				}
+
+				var signers []string
+				for _, s := range strings.Split(flagTxSigners, ",") {
+					if s = strings.TrimSpace(s); len(s) < 1 {
+						continue
+					}
+					if _, err = sebak.ParseAccountAddress(s); err != nil {
+						common.PrintFlagsError(c, "--signers", fmt.Errorf("'%s': %v", s, err))
+					}
+					signers = append(signers, s)
+				}
+
+				if envelope, err = sebak.NewTransactionEnvelope(tx, threshold, signers...); err == sebakerror.ErrorEnvelopeInvalidThreshold {
+					common.PrintFlagsError(c, "--threshold", err)
+				} else if err != nil {
+					common.PrintFlagsError(c, "--signers", err)
+				}
+			}
+
+			if err = envelope.Sign(kp, []byte(flagNetworkID)); err != nil {
+				common.PrintFlagsError(c, "--secret-seed", err)
+			}
+
+			output := flagTxOutput
+			if len(output) < 1 {
+				output = args[0]
+			}
+			writeTxEnvelope(c, output, envelope)
+		},
+	}
+
+	mergeCmd := &cobra.Command{
+		Use:   "merge <output> <envelope> <envelope>...",
+		Short: "merge the signatures of envelopes into one envelope",
+		Args:  cobra.MinimumNArgs(3),
+		Run: func(c *cobra.Command, args []string) {
+			envelope := readTxEnvelope(c, args[1])
+			for _, path := range args[2:] {
+				if err := envelope.Merge(readTxEnvelope(c, path)); err != nil {
+					common.PrintFlagsError(c, "<envelope>", fmt.Errorf("failed to merge '%s': %v", path, err))
+				}
+			}
+
+			writeTxEnvelope(c, args[0], envelope)
+		},
+	}
+
+	submitCmd := &cobra.Command{
+		Use:   "submit <envelope or signed transaction>",
+		Short: "submit the transaction of envelope, which satisfies the threshold, or the signed transaction",
+		Args:  cobra.ExactArgs(1),
+		Run: func(c *cobra.Command, args []string) {
+			if len(flagNetworkID) < 1 {
+				common.PrintFlagsError(c, "--network-id", errors.New("--network-id must be given"))
+			}
+
+			tx := readTxForSubmit(c, args[0])
+
+			var err error
+			var endpoint *sebakcommon.Endpoint
+			if endpoint, err = sebakcommon.NewEndpointFromString(flagEndpointString); err != nil {
+				common.PrintFlagsError(c, "--endpoint", err)
+			}
+
+			client := sebaknetwork.NewHTTP2NetworkClient(endpoint, nil)
+			if err = client.SendMessage(tx); err != nil {
+				common.PrintFlagsError(c, "--endpoint", fmt.Errorf("failed to submit transaction: %v", err))
+			}
+			fmt.Printf("transaction, '%s' submitted to %s\n", tx.GetHash(), endpoint)
+		},
+	}
+
+	txCmd.PersistentFlags().StringVar(&flagNetworkID, "network-id", flagNetworkID, "network id")
+	signCmd.Flags().StringVar(&flagKPSecretSeed, "secret-seed", flagKPSecretSeed, "secret seed of signer")
+	signCmd.Flags().StringVar(&flagKPSecretSeed, "key", flagKPSecretSeed, "secret seed of signer; same with --secret-seed")
+	signCmd.Flags().StringVar(&flagTxIn, "in", flagTxIn, "transaction file to sign offline; the signed transaction is written, not the envelope")
+	signCmd.Flags().StringVar(&flagTxOut, "out", flagTxOut, "signed transaction file to write with --in; default is stdout")
+	signCmd.Flags().BoolVar(&flagTxAppend, "append", flagTxAppend, "append signature to the existing envelope")
+	signCmd.Flags().StringVar(&flagTxThreshold, "threshold", flagTxThreshold, "number of signatures needed to submit the new envelope")
+	signCmd.Flags().StringVar(&flagTxSigners, "signers", flagTxSigners, "comma separated public addresses, which can sign the new envelope; source is always included")
+	signCmd.Flags().StringVar(&flagTxOutput, "output", flagTxOutput, "envelope file to write; default is <file>")
+	submitCmd.Flags().StringVar(&flagEndpointString, "endpoint", flagEndpointString, "endpoint uri of node")
+
+	txCmd.AddCommand(signCmd, mergeCmd, submitCmd)
+	rootCmd.AddCommand(txCmd)
+}
+
+func parseTxSecretSeed(c *cobra.Command) (kp *keypair.Full) {
+	if len(flagNetworkID) < 1 {
+		common.PrintFlagsError(c, "--network-id", errors.New("--network-id must be given"))
+	}
+
+	parsed, err := keypair.Parse(flagKPSecretSeed)
+	if err != nil {
+		common.PrintFlagsError(c, "--secret-seed", err)
+	}
+
+	var ok bool
+	if kp, ok = parsed.(*keypair.Full); !ok {
+		common.PrintFlagsError(c, "--secret-seed", errors.New("secret seed is needed, not public address"))
+	}
+
+	return
+}
+
+// signTxOffline signs the transaction of `--in` without the node, like on the
+// air-gapped machine; the key of source signs the transaction, and the other
+// key adds the co-signature to the transaction, which is signed by source.
+func signTxOffline(c *cobra.Command, kp *keypair.Full) {
+	if flagTxAppend || len(flagTxSigners) > 0 || len(flagTxOutput) > 0 {
+		common.PrintFlagsError(c, "--in", errors.New("--append, --signers and --output are for the envelope"))
+	}
+
+	b, err := ioutil.ReadFile(flagTxIn)
+	if err != nil {
+		common.PrintFlagsError(c, "--in", err)
+	}
+
+	var tx sebak.Transaction
+	if tx, err = sebak.NewTransactionFromJSON(b); err != nil {
+		common.PrintFlagsError(c, "--in", fmt.Errorf("invalid transaction: %v", err))
+	}
+
+	networkID := []byte(flagNetworkID)
+	if kp.Address() == tx.B.Source {
+		tx.Sign(kp, networkID)
+	} else {
+		if tx.H.Hash != tx.MakeHashString() || len(tx.H.Signature) < 1 {
+			common.PrintFlagsError(c, "--key", errors.New("transaction must be signed by source before the co-signature"))
+		}
+		tx.CoSign(kp, networkID)
+	}
+	if err = tx.IsWellFormed(networkID); err != nil {
+		common.PrintFlagsError(c, "--in", fmt.Errorf("signed transaction is not well-formed: %v", err))
+	}
+
+	encoded := []byte(tx.String() + "\n")
+	if len(flagTxOut) < 1 {
+		os.Stdout.Write(encoded)
+		return
+	}
+	if err = ioutil.WriteFile(flagTxOut, encoded, 0600); err != nil {
+		common.PrintFlagsError(c, "--out", err)
+	}
+
+	fmt.Printf("transaction, '%s' signed by %s: %s\n", tx.GetHash(), kp.Address(), flagTxOut)
+}
+
+// readTxForSubmit reads the envelope or the signed transaction.
+func readTxForSubmit(c *cobra.Command, path string) (tx sebak.Transaction) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		common.PrintFlagsError(c, "<envelope or signed transaction>", err)
+	}
+
+	var fields map[string]json.RawMessage
+	if err = json.Unmarshal(b, &fields); err != nil {
+		common.PrintFlagsError(c, "<envelope or signed transaction>", err)
+	}
+
+	if _, isEnvelope := fields["transaction"]; isEnvelope {
+		if tx, err = readTxEnvelope(c, path).SignedTransaction([]byte(flagNetworkID)); err != nil {
+			common.PrintFlagsError(c, "<envelope>", err)
+		}
+		return
+	}
+
+	if tx, err = sebak.NewTransactionFromJSON(b); err != nil {
+		common.PrintFlagsError(c, "<signed transaction>", fmt.Errorf("invalid transaction: %v", err))
+	}
+	if err = tx.IsWellFormed([]byte(flagNetworkID)); err != nil {
+		common.PrintFlagsError(c, "<signed transaction>", err)
+	}
+
+	return
+}
+
+func readTxEnvelope(c *cobra.Command, path string) (envelope sebak.TransactionEnvelope) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		common.PrintFlagsError(c, "<envelope>", err)
+	}
+
+	if envelope, err = sebak.NewTransactionEnvelopeFromJSON(b); err != nil {
+		common.PrintFlagsError(c, "<envelope>", fmt.Errorf("invalid envelope, '%s': %v", path, err))
+	}
+
+	return
+}
+
+func writeTxEnvelope(c *cobra.Command, path string, envelope sebak.TransactionEnvelope) {
+	b, err := envelope.Serialize()
+	if err != nil {
+		common.PrintFlagsError(c, "<envelope>", err)
+	}
+	if err = ioutil.WriteFile(path, b, 0600); err != nil {
+		common.PrintFlagsError(c, "--output", err)
+	}
+
+	fmt.Printf(
+		"envelope, '%s' written: %d of %d signatures, threshold %d\n",
+		path,
+		len(envelope.Signatures),
+		len(envelope.Signers),
+		envelope.Threshold,
+	)
+}
@@ -0,0 +1,175 @@
+package sebak
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"boscoin.io/sebak/lib/common"
+	"boscoin.io/sebak/lib/error"
+	"boscoin.io/sebak/lib/storage"
+)
+
+// AccountSigners are the additional signers of account with their weights,
+// which are set by `OperationSetSigners`. Every transaction of the account,
+// including the one, which changes or removes the signers, must be signed by
+// the signers, whose sum of weights reaches `Threshold`; the signature of
+// source counts `MasterWeight`, so the treasury account can make it's own key
+// alone not enough. The storage should support,
+//  * find by `Address`
+//  * 'asg-<AccountSigners.Address>': `AccountSigners`
+
+const AccountSignersPrefixAddress string = "asg-" // asg-<AccountSigners.Address>
+
+const (
+	// MaxAccountSigners is the maximum number of signers of account.
+	MaxAccountSigners int = 10
+	// MaxAccountSignerWeight is the maximum weight of one signer.
+	MaxAccountSignerWeight int = 255
+)
+
+// MaxTransactionCoSignatures is the maximum number of co-signatures of
+// transaction; they are the signatures of the signers of account.
+const MaxTransactionCoSignatures int = MaxAccountSigners
+
+type AccountSigner struct {
+	Address string `json:"address"`
+	Weight  int    `json:"weight"`
+}
+
+type AccountSigners struct {
+	Address      string
+	MasterWeight int
+	Signers      []AccountSigner
+	Threshold    int
+}
+
+func NewAccountSigners(address string, body OperationBodySetSigners) AccountSigners {
+	return AccountSigners{
+		Address:      address,
+		MasterWeight: body.MasterWeight,
+		Signers:      body.Signers,
+		Threshold:    body.Threshold,
+	}
+}
+
+func GetAccountSignersKey(address string) string {
+	return fmt.Sprintf("%s%s", AccountSignersPrefixAddress, address)
+}
+
+func (s AccountSigners) Save(st *sebakstorage.LevelDBBackend) (err error) {
+	key := GetAccountSignersKey(s.Address)
+	if err = UpdateStateHash(st, key, s); err != nil {
+		return
+	}
+
+	var exists bool
+	if exists, err = st.Has(key); err != nil {
+		return
+	}
+
+	if exists {
+		err = st.Set(key, s)
+	} else {
+		err = st.New(key, s)
+	}
+
+	return
+}
+
+// GetAccountSigners returns the signers of account; `found` is `false`, if
+// the account has no additional signers.
+func GetAccountSigners(st *sebakstorage.LevelDBBackend, address string) (signers AccountSigners, found bool, err error) {
+	key := GetAccountSignersKey(address)
+	if found, err = st.Has(key); err != nil || !found {
+		return
+	}
+
+	err = st.Get(key, &signers)
+
+	return
+}
+
+func RemoveAccountSigners(st *sebakstorage.LevelDBBackend, address string) (err error) {
+	key := GetAccountSignersKey(address)
+	if err = UpdateStateHash(st, key, nil); err != nil {
+		return
+	}
+
+	return st.Remove(key)
+}
+
+// SignedWeight returns the sum of weights of source and the signers, who
+// signed the transaction; the signature of source is already verified by
+// `CheckTransactionVerifySignature`.
+func (s AccountSigners) SignedWeight(tx Transaction, networkID []byte) int {
+	return s.MasterWeight + s.CoSignedWeight(tx, networkID)
+}
+
+// CoSignedWeight returns the sum of weights of the signers, who co-signed the
+// transaction, without the source.
+func (s AccountSigners) CoSignedWeight(tx Transaction, networkID []byte) (weight int) {
+	var signed []string
+	for _, sig := range tx.H.Signatures {
+		if _, found := sebakcommon.InStringArray(signed, sig.Signer); found {
+			continue
+		}
+		for _, signer := range s.Signers {
+			if signer.Address != sig.Signer {
+				continue
+			}
+			if verifyTransactionSignature(tx, networkID, sig) == nil {
+				signed = append(signed, sig.Signer)
+				weight += signer.Weight
+			}
+			break
+		}
+	}
+
+	return
+}
+
+// CheckAccountSigners checks the transaction against the signers of source
+// account; the weights of valid signatures must reach the threshold.
+func CheckAccountSigners(st *sebakstorage.LevelDBBackend, networkID []byte, tx Transaction) (err error) {
+	var signers AccountSigners
+	var found bool
+	if signers, found, err = GetAccountSigners(st, tx.B.Source); err != nil || !found {
+		return
+	}
+
+	if signers.SignedWeight(tx, networkID) < signers.Threshold {
+		err = sebakerror.ErrorSignersThresholdNotReached
+		return
+	}
+
+	return
+}
+
+// stateAccountSignersLeaf is the leaf of `AccountSigners` in the state hash.
+type stateAccountSignersLeaf struct {
+	Kind         string
+	Address      string
+	MasterWeight int
+	Signers      []AccountSigner
+	Threshold    int
+}
+
+func init() {
+	RegisterStateHashLeaf(StateHashLeaf{
+		Prefix: AccountSignersPrefixAddress,
+		Leaf: func(_ string, value []byte) ([]byte, error) {
+			var s AccountSigners
+			if err := json.Unmarshal(value, &s); err != nil {
+				return nil, err
+			}
+
+			return sebakcommon.MustJSONMarshal(stateAccountSignersLeaf{
+				Kind:         "account-signers",
+				Address:      s.Address,
+				MasterWeight: s.MasterWeight,
+				Signers:      s.Signers,
+				Threshold:    s.Threshold,
+			}), nil
+		},
+	})
+}
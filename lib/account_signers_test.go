@@ -0,0 +1,224 @@
+package sebak
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stellar/go/keypair"
+
+	"boscoin.io/sebak/lib/error"
+	"boscoin.io/sebak/lib/storage"
+)
+
+func TestOperationBodySetSignersIsWellFormed(t *testing.T) {
+	a, _ := keypair.Random()
+	b, _ := keypair.Random()
+
+	valid := []OperationBodySetSigners{
+		OperationBodySetSigners{}, // removes the signers
+		NewOperationBodySetSigners(1, 2, AccountSigner{a.Address(), 1}),
+		NewOperationBodySetSigners(0, 3, AccountSigner{a.Address(), 1}, AccountSigner{b.Address(), 2}),
+	}
+	for _, body := range valid {
+		if err := body.IsWellFormed(networkID); err != nil {
+			t.Errorf("'%v' must be well-formed: %v", body, err)
+			return
+		}
+	}
+
+	invalid := []OperationBodySetSigners{
+		NewOperationBodySetSigners(1, 1),
+		NewOperationBodySetSigners(1, 3, AccountSigner{a.Address(), 1}),
+		NewOperationBodySetSigners(1, 0, AccountSigner{a.Address(), 1}),
+		NewOperationBodySetSigners(-1, 1, AccountSigner{a.Address(), 1}),
+		NewOperationBodySetSigners(1, 1, AccountSigner{a.Address(), 0}),
+		NewOperationBodySetSigners(1, 1, AccountSigner{a.Address(), MaxAccountSignerWeight + 1}),
+		NewOperationBodySetSigners(1, 1, AccountSigner{a.Address(), 1}, AccountSigner{a.Address(), 1}),
+		NewOperationBodySetSigners(1, 1, AccountSigner{"invalid", 1}),
+	}
+	for _, body := range invalid {
+		if err := body.IsWellFormed(networkID); err == nil {
+			t.Errorf("'%v' must not be well-formed", body)
+			return
+		}
+	}
+
+	op, _ := NewOperation(OperationSetSigners, valid[2])
+	encoded, _ := op.Serialize()
+	decoded, err := NewOperationFromBytes(encoded)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if decoded.MakeHashString() != op.MakeHashString() {
+		t.Errorf("wrong operation: %v", decoded)
+		return
+	}
+
+	// the source can not be the signer of itself
+	op, _ = NewOperation(OperationSetSigners, NewOperationBodySetSigners(1, 1, AccountSigner{a.Address(), 1}))
+	tx, _ := NewTransaction(a.Address(), uuid.New().String(), op)
+	tx.Sign(a, networkID)
+	if err := tx.IsWellFormed(networkID); err != sebakerror.ErrorInvalidOperation {
+		t.Errorf("source in signers must be refused: %v", err)
+		return
+	}
+}
+
+func TestCheckAccountSigners(t *testing.T) {
+	st, _ := sebakstorage.NewTestMemoryLevelDBBackend()
+	defer st.Close()
+
+	kp, _ := keypair.Random()
+	a, _ := keypair.Random()
+	b, _ := keypair.Random()
+	other, _ := keypair.Random()
+
+	makeTransaction := func(signers ...*keypair.Full) Transaction {
+		tx, _ := NewTransaction(kp.Address(), uuid.New().String(), TestMakeOperation(int(BaseFee)))
+		tx.Sign(kp, networkID)
+		for _, s := range signers {
+			tx.CoSign(s, networkID)
+		}
+		return tx
+	}
+
+	// without signers, the signature of source is enough
+	if err := CheckAccountSigners(st, networkID, makeTransaction()); err != nil {
+		t.Error(err)
+		return
+	}
+
+	body := NewOperationBodySetSigners(1, 3, AccountSigner{a.Address(), 1}, AccountSigner{b.Address(), 2})
+	NewAccountSigners(kp.Address(), body).Save(st)
+
+	refused := [][]*keypair.Full{
+		{},
+		{a},
+		{other, a},
+	}
+	for _, signers := range refused {
+		if err := CheckAccountSigners(st, networkID, makeTransaction(signers...)); err != sebakerror.ErrorSignersThresholdNotReached {
+			t.Errorf("weights under the threshold must be refused: %v", err)
+			return
+		}
+	}
+	for _, signers := range [][]*keypair.Full{{b}, {a, b}} {
+		if err := CheckAccountSigners(st, networkID, makeTransaction(signers...)); err != nil {
+			t.Error(err)
+			return
+		}
+	}
+
+	// the forged signature does not count
+	forged := makeTransaction()
+	forged.H.Signatures = []TransactionEnvelopeSignature{{Signer: b.Address(), Signature: forged.H.Signature}}
+	if err := CheckAccountSigners(st, networkID, forged); err != sebakerror.ErrorSignersThresholdNotReached {
+		t.Errorf("forged signature must be refused: %v", err)
+		return
+	}
+
+	// removing the signers also requires the threshold
+	op, _ := NewOperation(OperationSetSigners, OperationBodySetSigners{})
+	remove, _ := NewTransaction(kp.Address(), uuid.New().String(), op)
+	remove.Sign(kp, networkID)
+	if err := CheckAccountSigners(st, networkID, remove); err != sebakerror.ErrorSignersThresholdNotReached {
+		t.Errorf("removing the signers without signatures must be refused: %v", err)
+		return
+	}
+	remove.CoSign(b, networkID)
+	if err := CheckAccountSigners(st, networkID, remove); err != nil {
+		t.Error(err)
+		return
+	}
+
+	RemoveAccountSigners(st, kp.Address())
+	if _, found, _ := GetAccountSigners(st, kp.Address()); found {
+		t.Error("signers must be removed")
+		return
+	}
+}
+
+func TestFinishOperationSetSigners(t *testing.T) {
+	st, _ := sebakstorage.NewTestMemoryLevelDBBackend()
+	defer st.Close()
+
+	kp, _ := keypair.Random()
+	a, _ := keypair.Random()
+	NewBlockAccount(kp.Address(), BaseFee.MustAdd(1), uuid.New().String()).Save(st)
+	tx := Transaction{B: TransactionBody{Source: kp.Address()}}
+
+	finish := func(t OperationType, body OperationBody) error {
+		op, _ := NewOperation(t, body)
+		return FinishOperation(st, tx, op)
+	}
+
+	// the source can not be the signer of itself, or the account is locked
+	if err := finish(OperationSetSigners, NewOperationBodySetSigners(0, 5, AccountSigner{kp.Address(), 5})); err != sebakerror.ErrorInvalidOperation {
+		t.Errorf("source in signers must be refused: %v", err)
+		return
+	}
+
+	// the limit needs the signers
+	if err := finish(OperationSetSpendingLimit, NewOperationBodySetSpendingLimit(BaseFee, 0)); err != sebakerror.ErrorSpendingLimitRequiresSigners {
+		t.Errorf("spending limit without signers must be refused: %v", err)
+		return
+	}
+	if err := finish(OperationSetSigners, NewOperationBodySetSigners(1, 1, AccountSigner{a.Address(), 1})); err != nil {
+		t.Error(err)
+		return
+	}
+	if err := finish(OperationSetSpendingLimit, NewOperationBodySetSpendingLimit(BaseFee, 0)); err != nil {
+		t.Error(err)
+		return
+	}
+	if err := finish(OperationSetSigners, OperationBodySetSigners{}); err != sebakerror.ErrorSpendingLimitRequiresSigners {
+		t.Errorf("removing the signers of the limit must be refused: %v", err)
+		return
+	}
+
+	if err := finish(OperationSetSpendingLimit, OperationBodySetSpendingLimit{}); err != nil {
+		t.Error(err)
+		return
+	}
+	if err := finish(OperationSetSigners, OperationBodySetSigners{}); err != nil {
+		t.Error(err)
+		return
+	}
+}
+
+// TestAccountSignersStateHash checks, the signers of account are the part of
+// state hash.
+func TestAccountSignersStateHash(t *testing.T) {
+	st, _ := sebakstorage.NewTestMemoryLevelDBBackend()
+	defer st.Close()
+
+	kp, _ := keypair.Random()
+	a, _ := keypair.Random()
+	NewBlockAccount(kp.Address(), BaseFee.MustAdd(1), uuid.New().String()).Save(st)
+	hash, _ := MakeStateHash(st)
+
+	NewAccountSigners(kp.Address(), NewOperationBodySetSigners(1, 1, AccountSigner{a.Address(), 1})).Save(st)
+	withSigners, _ := MakeStateHash(st)
+	if withSigners == hash {
+		t.Error("signers must change state hash")
+		return
+	}
+
+	NewAccountSigners(kp.Address(), NewOperationBodySetSigners(1, 2, AccountSigner{a.Address(), 1})).Save(st)
+	changed, _ := MakeStateHash(st)
+	if changed == withSigners {
+		t.Error("threshold of signers must change state hash")
+		return
+	}
+	if summed, _ := makeStateHashFromState(st); summed != changed {
+		t.Error("stored state hash must be same with the sum of state")
+		return
+	}
+
+	RemoveAccountSigners(st, kp.Address())
+	if removed, _ := MakeStateHash(st); removed != hash {
+		t.Error("removed signers must be subtracted from state hash")
+		return
+	}
+}
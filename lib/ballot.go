@@ -3,6 +3,7 @@ package sebak
 import (
 	"encoding/json"
 	"sort"
+	"time"
 
 	"github.com/btcsuite/btcutil/base58"
 	"boscoin.io/sebak/lib/common"
@@ -43,8 +44,6 @@ func (bd BallotData) String() string {
 	return string(encoded)
 }
 
-// TODO versioning
-
 type Ballot struct {
 	T string
 	H BallotHeader
@@ -58,12 +57,18 @@ func (b Ballot) Clone() Ballot {
 		NodeKey:    b.B.NodeKey,
 		State:      b.B.State,
 		VotingHole: b.B.VotingHole,
+		Signals:    b.B.Signals,
+		Proposed:   b.B.Proposed,
+
+		ProposerSignals: b.B.ProposerSignals,
 	}
 	return Ballot{
 		T: b.T,
 		H: BallotHeader{
-			Hash:      b.H.Hash,
-			Signature: b.H.Signature,
+			Version:     b.H.Version,
+			Hash:        b.H.Hash,
+			Signature:   b.H.Signature,
+			Certificate: b.H.Certificate,
 		},
 		B: body,
 		D: b.D,
@@ -108,6 +113,7 @@ func NewBallotFromMessage(nodeKey string, m sebakcommon.Message) (ballot Ballot,
 		NodeKey:    nodeKey,
 		State:      sebakcommon.InitialState,
 		VotingHole: VotingNOTYET,
+		Proposed:   sebakcommon.NowISO8601(),
 	}
 	data := BallotData{
 		Data: m,
@@ -115,12 +121,13 @@ func NewBallotFromMessage(nodeKey string, m sebakcommon.Message) (ballot Ballot,
 	ballot = Ballot{
 		T: "ballot",
 		H: BallotHeader{
-			Hash:      base58.Encode(body.MakeHash()),
+			Version:   sebakcommon.EncodingVersionCanonical,
 			Signature: "",
 		},
 		B: body,
 		D: data,
 	}
+	ballot.UpdateHash()
 
 	return
 }
@@ -146,6 +153,27 @@ func NewBallotFromJSON(b []byte) (ballot Ballot, err error) {
 	return
 }
 
+// MaxBallotProposedDrift is the difference between `BallotBody.Proposed` and
+// the clock of validator, which the validator allows when it votes; the
+// proposed time is the time of block, so it is checked once by the validators
+// at vote and every node uses it after consensus instead of it's own clock.
+const MaxBallotProposedDrift = time.Minute
+
+// IsProposedTimeDrifted checks `B.Proposed` is too far from `now`.
+func (b Ballot) IsProposedTimeDrifted(now time.Time) bool {
+	proposed, err := b.ProposedTime()
+	if err != nil {
+		return true
+	}
+
+	drift := now.Sub(proposed)
+	if drift < 0 {
+		drift = -drift
+	}
+
+	return drift > MaxBallotProposedDrift
+}
+
 var BallotWellFormedCheckerFuncs = []sebakcommon.CheckerFunc{
 	checkBallotEmptyNodeKey,
 	checkBallotEmptyHashMatch,
@@ -153,6 +181,8 @@ var BallotWellFormedCheckerFuncs = []sebakcommon.CheckerFunc{
 	checkBallotNoVoting,
 	checkBallotHasMessage,
 	checkBallotValidState,
+	checkBallotUpgradeSignals,
+	checkBallotProposedTime,
 }
 
 func (b Ballot) IsWellFormed(networkID []byte) (err error) {
@@ -169,15 +199,7 @@ func (b Ballot) IsWellFormed(networkID []byte) (err error) {
 }
 
 func (b Ballot) VerifySignature(networkID []byte) (err error) {
-	err = keypair.MustParse(b.B.NodeKey).Verify(
-		append(networkID, []byte(b.GetHash())...),
-		base58.Decode(b.H.Signature),
-	)
-	if err != nil {
-		return sebakerror.ErrorSignatureVerificationFailed
-	}
-
-	return
+	return VerifyNodeSignature(b.B.NodeKey, b.H.Certificate, networkID, b.GetHash(), b.H.Signature)
 }
 
 func (b Ballot) Validate(st *sebakstorage.LevelDBBackend) (err error) {
@@ -222,6 +244,7 @@ func (b *Ballot) Sign(kp *keypair.Full, networkID []byte) {
 	if kp.Address() != b.B.NodeKey {
 		b.B.NodeKey = kp.Address()
 	}
+	b.H.Certificate = nil
 
 	b.UpdateHash()
 	signature, _ := kp.Sign(append(networkID, []byte(b.GetHash())...))
@@ -231,20 +254,63 @@ func (b *Ballot) Sign(kp *keypair.Full, networkID []byte) {
 }
 
 func (b *Ballot) UpdateHash() {
-	b.H.Hash = base58.Encode(b.B.MakeHash())
+	b.H.Hash = b.MakeHashString()
 
 	return
 }
 
+// MakeHashString returns the hash of body by the encoding of `H.Version`.
+func (b Ballot) MakeHashString() string {
+	if b.H.Version == sebakcommon.EncodingVersionCanonical {
+		return base58.Encode(sebakcommon.MakeCanonicalHash("ballot", b.B))
+	}
+
+	return base58.Encode(b.B.MakeHash())
+}
+
+// ProposedTime returns `B.Proposed`, the time of block agreed by the
+// validators.
+func (b Ballot) ProposedTime() (t time.Time, err error) {
+	if t, err = time.Parse(time.RFC3339Nano, b.B.Proposed); err != nil {
+		err = sebakerror.ErrorBallotInvalidProposedTime
+	}
+
+	return
+}
+
+// IsProposedAfter checks `B.Proposed` is after the time of `block`, so the
+// times of blocks never go backwards.
+func (b Ballot) IsProposedAfter(block Block) bool {
+	if block.IsEmpty() {
+		return true
+	}
+
+	proposed, err := b.ProposedTime()
+	if err != nil {
+		return false
+	}
+	confirmed, err := time.Parse(time.RFC3339Nano, block.Confirmed)
+	if err != nil {
+		return false
+	}
+
+	return proposed.After(confirmed)
+}
+
 func (b *Ballot) Vote(v VotingHole) {
 	b.B.VotingHole = v
 
 	return
 }
 
+// BallotHeader has the certificate, when the ballot is signed by the session
+// key of validator; see `SessionKeyCertificate`. `Version` is the encoding of
+// body for the hash like `TransactionHeader.Version`.
 type BallotHeader struct {
-	Hash      string `json:"ballot_hash"`
-	Signature string `json:"signature"`
+	Version     string                 `json:"version,omitempty"`
+	Hash        string                 `json:"ballot_hash"`
+	Signature   string                 `json:"signature"`
+	Certificate *SessionKeyCertificate `json:"certificate,omitempty"`
 }
 
 type BallotBody struct {
@@ -253,12 +319,29 @@ type BallotBody struct {
 	State      sebakcommon.BallotState `json:"state"`
 	VotingHole VotingHole              `json:"voting_hole"`
 	Reason     string                  `json:"reason"`
+	Signals    []string                `json:"signals,omitempty"` // the features, which the validator is ready for; see `Upgrade`
+	Proposed   string                  `json:"proposed"`          // the time, when the first ballot of the message is made; see `MaxBallotProposedDrift`
+
+	// ProposerSignals is `Signals` of the first ballot of the message; the
+	// block keeps them, so the upgrades are counted only by the blocks.
+	ProposerSignals []string `json:"proposer_signals,omitempty"`
 }
 
 func (bb BallotBody) MakeHash() []byte {
 	return sebakcommon.MustMakeObjectHash(bb)
 }
 
+func (bb BallotBody) EncodeCanonical(e *sebakcommon.CanonicalEncoder) {
+	e.String(bb.Hash)
+	e.String(bb.NodeKey)
+	e.Uint64(uint64(bb.State))
+	e.String(string(bb.VotingHole))
+	e.String(bb.Reason)
+	e.Strings(bb.Signals)
+	e.String(bb.Proposed)
+	e.Strings(bb.ProposerSignals)
+}
+
 type BallotBoxes struct {
 	sebakcommon.SafeLock
 
@@ -1,7 +1,6 @@
 package sebak
 
 import (
-	"github.com/btcsuite/btcutil/base58"
 	"boscoin.io/sebak/lib/common"
 	"boscoin.io/sebak/lib/error"
 )
@@ -25,7 +24,10 @@ func checkBallotEmptyNodeKey(c sebakcommon.Checker, args ...interface{}) error {
 func checkBallotEmptyHashMatch(c sebakcommon.Checker, args ...interface{}) error {
 	checker := c.(*BallotChecker)
 
-	if base58.Encode(checker.Ballot.B.MakeHash()) != checker.Ballot.GetHash() {
+	if !sebakcommon.IsKnownEncodingVersion(checker.Ballot.H.Version) {
+		return sebakerror.ErrorUnknownEncodingVersion
+	}
+	if checker.Ballot.MakeHashString() != checker.Ballot.GetHash() {
 		return sebakerror.ErrorHashDoesNotMatch
 	}
 	return nil
@@ -58,6 +60,29 @@ func checkBallotValidState(c sebakcommon.Checker, args ...interface{}) error {
 	return nil
 }
 
+func checkBallotUpgradeSignals(c sebakcommon.Checker, args ...interface{}) error {
+	checker := c.(*BallotChecker)
+
+	for _, signals := range [][]string{checker.Ballot.B.Signals, checker.Ballot.B.ProposerSignals} {
+		if len(signals) > MaxUpgradeSignals {
+			return sebakerror.ErrorBallotInvalidUpgradeSignals
+		}
+		for _, feature := range signals {
+			if !IsValidUpgradeFeature(feature) {
+				return sebakerror.ErrorBallotInvalidUpgradeSignals
+			}
+		}
+	}
+	return nil
+}
+
+func checkBallotProposedTime(c sebakcommon.Checker, args ...interface{}) error {
+	checker := c.(*BallotChecker)
+
+	_, err := checker.Ballot.ProposedTime()
+	return err
+}
+
 func checkBallotHasMessage(c sebakcommon.Checker, args ...interface{}) error {
 	checker := c.(*BallotChecker)
 
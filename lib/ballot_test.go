@@ -3,6 +3,7 @@ package sebak
 import (
 	"testing"
 
+	"github.com/btcsuite/btcutil/base58"
 	"github.com/stellar/go/keypair"
 
 	"boscoin.io/sebak/lib/common"
@@ -70,6 +71,43 @@ func TestBallotSign(t *testing.T) {
 	}
 }
 
+func TestBallotCanonicalEncoding(t *testing.T) {
+	kpNode, _, ballot := makeNewBallot(sebakcommon.BallotStateINIT, VotingYES)
+	if ballot.H.Version != sebakcommon.EncodingVersionCanonical || ballot.GetHash() == base58.Encode(ballot.B.MakeHash()) {
+		t.Errorf("new ballot must be encoded canonically: %v", ballot.H)
+		return
+	}
+	if err := ballot.IsWellFormed(networkID); err != nil {
+		t.Error(err)
+		return
+	}
+
+	proposed := ballot
+	proposed.B.Proposed = sebakcommon.NowISO8601()
+	signals := ballot
+	signals.B.ProposerSignals = []string{"fee-model.v2"}
+	if proposed.MakeHashString() == ballot.MakeHashString() || signals.MakeHashString() == ballot.MakeHashString() {
+		t.Error("proposed time and signals of proposer must be encoded")
+		return
+	}
+
+	legacy := ballot
+	legacy.H.Version = sebakcommon.EncodingVersionRLP
+	legacy.Sign(kpNode, networkID)
+	if err := legacy.IsWellFormed(networkID); err != nil {
+		t.Error(err)
+		return
+	}
+
+	unknown := ballot
+	unknown.H.Version = "2"
+	unknown.Sign(kpNode, networkID)
+	if err := unknown.IsWellFormed(networkID); err != sebakerror.ErrorUnknownEncodingVersion {
+		t.Errorf("unknown encoding version must be refused: %v", err)
+		return
+	}
+}
+
 func TestBallotVote(t *testing.T) {
 	kpNode, _, ballot := makeNewBallot(sebakcommon.BallotStateINIT, VotingNOTYET)
 
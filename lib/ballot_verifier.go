@@ -0,0 +1,87 @@
+package sebak
+
+import (
+	"sync"
+
+	"boscoin.io/sebak/lib/common"
+)
+
+// MaxBallotVerifyBatch is the maximum number of ballots which are verified at
+// once.
+const MaxBallotVerifyBatch int = 100
+
+var BallotSignatureCheckerFuncs = []sebakcommon.CheckerFunc{
+	checkBallotEmptyNodeKey,
+	checkBallotEmptyHashMatch,
+	checkBallotVerifySignature,
+}
+
+// BallotSignatureVerifier verifies the signatures of ballots in parallel.
+// Signature verification is the most expensive part of handling ballots, so
+// the incoming ballots are verified together by the worker pool before they
+// are handled one by one.
+type BallotSignatureVerifier struct {
+	networkID []byte
+	workers   int
+}
+
+func NewBallotSignatureVerifier(networkID []byte, workers int) *BallotSignatureVerifier {
+	if workers < 1 {
+		workers = 1
+	}
+
+	return &BallotSignatureVerifier{
+		networkID: networkID,
+		workers:   workers,
+	}
+}
+
+func (v *BallotSignatureVerifier) Workers() int {
+	return v.workers
+}
+
+func (v *BallotSignatureVerifier) verify(ballot Ballot) (err error) {
+	checker := &BallotChecker{
+		DefaultChecker: sebakcommon.DefaultChecker{BallotSignatureCheckerFuncs},
+		Ballot:         ballot,
+		NetworkID:      v.networkID,
+	}
+	err = sebakcommon.RunChecker(checker, sebakcommon.DefaultDeferFunc)
+
+	return
+}
+
+// Verify verifies the signatures of ballots. The returned errors have the same
+// order with `ballots`; `nil` error means the ballot is verified.
+func (v *BallotSignatureVerifier) Verify(ballots []Ballot) (errs []error) {
+	errs = make([]error, len(ballots))
+	if len(ballots) == 1 {
+		errs[0] = v.verify(ballots[0])
+		return
+	}
+
+	jobs := make(chan int, len(ballots))
+	for i := range ballots {
+		jobs <- i
+	}
+	close(jobs)
+
+	workers := v.workers
+	if workers > len(ballots) {
+		workers = len(ballots)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				errs[j] = v.verify(ballots[j])
+			}
+		}()
+	}
+	wg.Wait()
+
+	return
+}
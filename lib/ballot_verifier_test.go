@@ -0,0 +1,56 @@
+package sebak
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcutil/base58"
+	"github.com/stellar/go/keypair"
+
+	"boscoin.io/sebak/lib/common"
+	"boscoin.io/sebak/lib/error"
+)
+
+func TestBallotSignatureVerifier(t *testing.T) {
+	var ballots []Ballot
+	for i := 0; i < 10; i++ {
+		_, _, ballot := makeNewBallot(sebakcommon.BallotStateINIT, VotingYES)
+		ballots = append(ballots, ballot)
+	}
+
+	// signed by the other keypair
+	kpOther, _ := keypair.Random()
+	signature, _ := kpOther.Sign(append(networkID, []byte(ballots[3].GetHash())...))
+	ballots[3].H.Signature = base58.Encode(signature)
+
+	// body is changed after signing
+	ballots[7].B.VotingHole = VotingNO
+
+	// invalid node key
+	ballots[9].B.NodeKey = "find me"
+	ballots[9].UpdateHash()
+
+	verifier := NewBallotSignatureVerifier(networkID, 4)
+	errs := verifier.Verify(ballots)
+	if len(errs) != len(ballots) {
+		t.Error("the number of results does not match with ballots")
+		return
+	}
+
+	expected := map[int]error{
+		3: sebakerror.ErrorSignatureVerificationFailed,
+		7: sebakerror.ErrorHashDoesNotMatch,
+		9: sebakerror.ErrorBadPublicAddress,
+	}
+	for i, err := range errs {
+		if err != expected[i] {
+			t.Errorf("ballot#%d: expected error, '%v', but got '%v'", i, expected[i], err)
+			return
+		}
+	}
+
+	// single ballot
+	if errs := verifier.Verify(ballots[:1]); errs[0] != nil {
+		t.Error(errs[0])
+		return
+	}
+}
@@ -1 +1,173 @@
 package sebak
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/btcsuite/btcutil/base58"
+
+	"boscoin.io/sebak/lib/common"
+	"boscoin.io/sebak/lib/error"
+	"boscoin.io/sebak/lib/storage"
+)
+
+// Block is the record of the transactions, which got consensus. Each
+// consensus makes new `Block` on top of the latest one. the storage should
+// support,
+//  * find by `Hash`
+//  * find by `Height`
+//  * get the latest block
+// `StateHash` is the hash of the account state after the transactions
+// of block are applied, so the nodes, which have the different state for the
+// same block can be found by it. `Signals` are the upgrade signals of the
+// validator, which proposed the block; see `Upgrade`.
+
+const (
+	BlockPrefixHash   string = "bk-hash-"   // bk-hash-<Block.Hash>
+	BlockPrefixHeight string = "bk-height-" // bk-height-<Block.Height>
+)
+
+type Block struct {
+	Hash          string   `json:"hash"`
+	Height        uint64   `json:"height"`
+	PrevBlockHash string   `json:"prev_block_hash"`
+	StateHash     string   `json:"state_hash"`
+	Transactions  []string `json:"transactions"`
+	Confirmed     string   `json:"confirmed"`
+	Signals       []string `json:"signals,omitempty"`
+}
+
+type blockHeader struct {
+	Height        uint64
+	PrevBlockHash string
+	StateHash     string
+	Transactions  []string
+	Confirmed     string
+	Signals       []string
+}
+
+// NewBlock makes the next block of `prev` at the current time. If `prev` is
+// empty, it will be the first block. `stateHash` is the result of
+// `MakeStateHash()` after the transactions are applied.
+func NewBlock(prev Block, stateHash string, transactions ...string) Block {
+	return NewBlockAt(prev, stateHash, sebakcommon.NowISO8601(), transactions...)
+}
+
+// NewBlockAt makes the next block of `prev` at `confirmed`; the block of
+// consensus has the proposed time of ballot, so the validators make the block
+// of the same hash.
+func NewBlockAt(prev Block, stateHash, confirmed string, transactions ...string) Block {
+	b := Block{
+		Height:        prev.Height + 1,
+		PrevBlockHash: prev.Hash,
+		StateHash:     stateHash,
+		Transactions:  transactions,
+		Confirmed:     confirmed,
+	}
+	b.Hash = b.MakeHashString()
+
+	return b
+}
+
+// WithSignals returns the block, which has the upgrade signals of it's
+// proposer.
+func (b Block) WithSignals(signals []string) Block {
+	b.Signals = signals
+	b.Hash = b.MakeHashString()
+
+	return b
+}
+
+func (b Block) IsEmpty() bool {
+	return len(b.Hash) < 1
+}
+
+func (b Block) MakeHashString() string {
+	return base58.Encode(sebakcommon.MustMakeObjectHash(blockHeader{
+		Height:        b.Height,
+		PrevBlockHash: b.PrevBlockHash,
+		StateHash:     b.StateHash,
+		Transactions:  b.Transactions,
+		Confirmed:     b.Confirmed,
+		Signals:       b.Signals,
+	}))
+}
+
+func (b Block) Serialize() (encoded []byte, err error) {
+	encoded, err = sebakcommon.EncodeJSONValue(b)
+	return
+}
+
+func (b Block) String() string {
+	encoded, _ := sebakcommon.EncodeJSONValue(b)
+	return string(encoded)
+}
+
+func GetBlockKey(hash string) string {
+	return fmt.Sprintf("%s%s", BlockPrefixHash, hash)
+}
+
+func GetBlockKeyHeight(height uint64) string {
+	return fmt.Sprintf("%s%020d", BlockPrefixHeight, height)
+}
+
+func (b Block) Save(st *sebakstorage.LevelDBBackend) (err error) {
+	var finality Finality
+	if finality, err = GetFinality(st); err != nil {
+		return
+	} else if !finality.IsEmpty() && b.Height <= finality.Height {
+		return sebakerror.ErrorBlockBelowFinality
+	}
+
+	var exists bool
+	if exists, err = st.Has(GetBlockKey(b.Hash)); err != nil {
+		return
+	} else if exists {
+		return sebakerror.ErrorBlockAlreadyExists
+	}
+
+	if exists, err = st.Has(GetBlockKeyHeight(b.Height)); err != nil {
+		return
+	} else if exists {
+		return sebakerror.ErrorBlockAlreadyExists
+	}
+
+	if err = st.New(GetBlockKey(b.Hash), b); err != nil {
+		return
+	}
+	if err = st.New(GetBlockKeyHeight(b.Height), b.Hash); err != nil {
+		return
+	}
+
+	return
+}
+
+func GetBlock(st *sebakstorage.LevelDBBackend, hash string) (b Block, err error) {
+	err = st.Get(GetBlockKey(hash), &b)
+	return
+}
+
+func GetBlockByHeight(st *sebakstorage.LevelDBBackend, height uint64) (b Block, err error) {
+	var hash string
+	if err = st.Get(GetBlockKeyHeight(height), &hash); err != nil {
+		return
+	}
+
+	return GetBlock(st, hash)
+}
+
+// GetLatestBlock returns the block which has the highest height. If no block
+// is stored, empty `Block` is returned without error.
+func GetLatestBlock(st *sebakstorage.LevelDBBackend) (b Block, err error) {
+	item, found := st.SeekLast(BlockPrefixHeight)
+	if !found {
+		return
+	}
+
+	var hash string
+	if err = json.Unmarshal(item.Value, &hash); err != nil {
+		return
+	}
+
+	return GetBlock(st, hash)
+}
@@ -46,6 +46,9 @@ func (b *BlockAccount) String() string {
 
 func (b *BlockAccount) Save(st *sebakstorage.LevelDBBackend) (err error) {
 	key := GetBlockAccountKey(b.Address)
+	if err = UpdateStateHash(st, key, b); err != nil {
+		return
+	}
 
 	var exists bool
 	exists, err = st.Has(key)
@@ -159,3 +162,30 @@ func (b *BlockAccount) Withdraw(fund Amount, checkpoint string) error {
 	}
 	return nil
 }
+
+// stateAccountLeaf is the leaf of `BlockAccount` in the state hash.
+type stateAccountLeaf struct {
+	Kind       string
+	Address    string
+	Balance    string
+	Checkpoint string
+}
+
+func init() {
+	RegisterStateHashLeaf(StateHashLeaf{
+		Prefix: BlockAccountPrefixAddress,
+		Leaf: func(_ string, value []byte) ([]byte, error) {
+			var ba BlockAccount
+			if err := json.Unmarshal(value, &ba); err != nil {
+				return nil, err
+			}
+
+			return sebakcommon.MustJSONMarshal(stateAccountLeaf{
+				Kind:       "account",
+				Address:    ba.Address,
+				Balance:    ba.Balance,
+				Checkpoint: ba.Checkpoint,
+			}), nil
+		},
+	})
+}
@@ -0,0 +1,152 @@
+package sebak
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"boscoin.io/sebak/lib/common"
+	"boscoin.io/sebak/lib/storage"
+)
+
+// BlockAccountData is the data entry of account, which is set by
+// `OperationManageData`. The entries of account are indexed by their name, so
+// they can be found by the prefix of name,
+//  * 'bad-<BlockAccountData.Address>-<BlockAccountData.Name>': `BlockAccountData`
+
+const BlockAccountDataPrefixAddress string = "bad-"
+
+type BlockAccountData struct {
+	Address string
+	Name    string
+	Value   []byte
+	Updated string // the confirmed time of the block, which set the entry
+}
+
+func NewBlockAccountData(address, name string, value []byte, updated string) *BlockAccountData {
+	return &BlockAccountData{
+		Address: address,
+		Name:    name,
+		Value:   value,
+		Updated: updated,
+	}
+}
+
+func GetBlockAccountDataKeyPrefix(address string) string {
+	return fmt.Sprintf("%s%s-", BlockAccountDataPrefixAddress, address)
+}
+
+func GetBlockAccountDataKey(address, name string) string {
+	return fmt.Sprintf("%s%s", GetBlockAccountDataKeyPrefix(address), name)
+}
+
+func (d *BlockAccountData) Save(st *sebakstorage.LevelDBBackend) (err error) {
+	key := GetBlockAccountDataKey(d.Address, d.Name)
+	if err = UpdateStateHash(st, key, d); err != nil {
+		return
+	}
+
+	var exists bool
+	if exists, err = st.Has(key); err != nil {
+		return
+	}
+
+	if exists {
+		err = st.Set(key, d)
+	} else {
+		err = st.New(key, d)
+	}
+
+	return
+}
+
+func (d *BlockAccountData) Serialize() (encoded []byte, err error) {
+	encoded, err = sebakcommon.EncodeJSONValue(d)
+	return
+}
+
+func RemoveBlockAccountData(st *sebakstorage.LevelDBBackend, address, name string) (err error) {
+	key := GetBlockAccountDataKey(address, name)
+	if err = UpdateStateHash(st, key, nil); err != nil {
+		return
+	}
+
+	return st.Remove(key)
+}
+
+func GetBlockAccountData(st *sebakstorage.LevelDBBackend, address, name string) (d *BlockAccountData, err error) {
+	err = st.Get(GetBlockAccountDataKey(address, name), &d)
+	return
+}
+
+// CountBlockAccountData returns the number of data entries of account.
+func CountBlockAccountData(st *sebakstorage.LevelDBBackend, address string) (n int, err error) {
+	iterFunc, closeFunc := st.GetIterator(GetBlockAccountDataKeyPrefix(address), false)
+	defer closeFunc()
+
+	for {
+		if _, hasNext := iterFunc(); !hasNext {
+			break
+		}
+		n++
+	}
+
+	return
+}
+
+// GetBlockAccountDataByPrefix returns the data entries of account, whose name
+// starts with `prefix`, in name order. If `from` is not empty, the entries
+// after the name, `from` are returned.
+func GetBlockAccountDataByPrefix(st *sebakstorage.LevelDBBackend, address, prefix, from string, reverse bool) (
+	func() (*BlockAccountData, bool),
+	func(),
+) {
+	var fromKey string
+	if len(from) > 0 {
+		fromKey = GetBlockAccountDataKey(address, from)
+	}
+	iterFunc, closeFunc := st.GetIteratorFrom(GetBlockAccountDataKeyPrefix(address)+prefix, fromKey, reverse)
+
+	return (func() (*BlockAccountData, bool) {
+			item, hasNext := iterFunc()
+			if !hasNext {
+				return nil, false
+			}
+
+			var d *BlockAccountData
+			if err := json.Unmarshal(item.Value, &d); err != nil {
+				return nil, false
+			}
+			return d, hasNext
+		}), (func() {
+			closeFunc()
+		})
+}
+
+// stateAccountDataLeaf is the leaf of `BlockAccountData` in the state hash;
+// `Updated` is when the entry is changed, not the entry itself, so it is not
+// included.
+type stateAccountDataLeaf struct {
+	Kind    string
+	Address string
+	Name    string
+	Value   []byte
+}
+
+func init() {
+	RegisterStateHashLeaf(StateHashLeaf{
+		Prefix: BlockAccountDataPrefixAddress,
+		Leaf: func(_ string, value []byte) ([]byte, error) {
+			var d BlockAccountData
+			if err := json.Unmarshal(value, &d); err != nil {
+				return nil, err
+			}
+
+			return sebakcommon.MustJSONMarshal(stateAccountDataLeaf{
+				Kind:    "account-data",
+				Address: d.Address,
+				Name:    d.Name,
+				Value:   d.Value,
+			}), nil
+		},
+	})
+}
@@ -0,0 +1,112 @@
+package sebak
+
+import (
+	"fmt"
+	"testing"
+
+	"boscoin.io/sebak/lib/error"
+	"boscoin.io/sebak/lib/common"
+	"boscoin.io/sebak/lib/storage"
+)
+
+func TestBlockAccountDataByPrefix(t *testing.T) {
+	st, _ := sebakstorage.NewTestMemoryLevelDBBackend()
+
+	ba := testMakeBlockAccount()
+	other := testMakeBlockAccount()
+
+	for _, name := range []string{"anchor-b", "anchor-a", "memo", "anchor-c"} {
+		if err := NewBlockAccountData(ba.Address, name, []byte(name), sebakcommon.NowISO8601()).Save(st); err != nil {
+			t.Error(err)
+			return
+		}
+	}
+	NewBlockAccountData(other.Address, "anchor-z", []byte("z"), sebakcommon.NowISO8601()).Save(st)
+
+	var names []string
+	iterFunc, closeFunc := GetBlockAccountDataByPrefix(st, ba.Address, "anchor-", "", false)
+	for {
+		d, hasNext := iterFunc()
+		if !hasNext {
+			break
+		}
+		names = append(names, d.Name)
+	}
+	closeFunc()
+
+	expected := []string{"anchor-a", "anchor-b", "anchor-c"}
+	if len(names) != len(expected) {
+		t.Errorf("wrong number of entries: %v", names)
+		return
+	}
+	for i, name := range expected {
+		if names[i] != name {
+			t.Errorf("entries must be ordered by name: %v", names)
+			return
+		}
+	}
+
+	// seek after the name
+	names = nil
+	iterFunc, closeFunc = GetBlockAccountDataByPrefix(st, ba.Address, "anchor-", "anchor-a", false)
+	for {
+		d, hasNext := iterFunc()
+		if !hasNext {
+			break
+		}
+		names = append(names, d.Name)
+	}
+	closeFunc()
+
+	if len(names) != 2 || names[0] != "anchor-b" || names[1] != "anchor-c" {
+		t.Errorf("entries must start after the name: %v", names)
+		return
+	}
+
+	// update and remove
+	if err := NewBlockAccountData(ba.Address, "memo", []byte("new"), sebakcommon.NowISO8601()).Save(st); err != nil {
+		t.Error(err)
+		return
+	}
+	if d, err := GetBlockAccountData(st, ba.Address, "memo"); err != nil || string(d.Value) != "new" {
+		t.Error("failed to update BlockAccountData")
+		return
+	}
+	if err := RemoveBlockAccountData(st, ba.Address, "memo"); err != nil {
+		t.Error(err)
+		return
+	}
+	if exists, _ := st.Has(GetBlockAccountDataKey(ba.Address, "memo")); exists {
+		t.Error("failed to remove BlockAccountData")
+		return
+	}
+}
+
+func TestManageDataMaxEntries(t *testing.T) {
+	st, _ := sebakstorage.NewTestMemoryLevelDBBackend()
+
+	ba := testMakeBlockAccount()
+	for i := 0; i < MaxAccountDataEntries; i++ {
+		NewBlockAccountData(ba.Address, fmt.Sprintf("entry-%03d", i), []byte("v"), sebakcommon.NowISO8601()).Save(st)
+	}
+	if n, _ := CountBlockAccountData(st, ba.Address); n != MaxAccountDataEntries {
+		t.Errorf("wrong number of entries: %d", n)
+		return
+	}
+
+	if err := CheckManageData(st, ba.Address, NewOperationBodyManageData("new", []byte("v"))); err != sebakerror.ErrorTooManyAccountDataEntries {
+		t.Errorf("new entry over the maximum must be refused: %v", err)
+		return
+	}
+
+	// the existing entry can be changed and removed
+	for _, body := range []OperationBodyManageData{
+		NewOperationBodyManageData("entry-000", []byte("changed")),
+		NewOperationBodyManageData("entry-000", nil),
+	} {
+		if err := CheckManageData(st, ba.Address, body); err != nil {
+			t.Error(err)
+			return
+		}
+	}
+}
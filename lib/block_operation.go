@@ -16,6 +16,7 @@ import (
 //
 //  * get list by `Source` and created order
 //  * get list by `Target` and created order
+//  * get list by the account, which is `Source` or `Target`, and confirmed order
 
 const (
 	BlockOperationPrefixHash       string = "bo-hash-"       // bo-hash-<BlockOperation.Hash>
@@ -24,6 +25,7 @@ const (
 	BlockOperationPrefixTarget     string = "bo-target-"     // bo-target-<BlockOperation.Target>-<created>
 	BlockOperationPrefixPeers      string = "bo-peers-"      // bo-target-<Address0>-<Address1>-<created>
 	BlockOperationPrefixCheckpoint string = "bo-checkpoint-" // bo-checkpoint-<Transaction.B.Checkpoint>-<created>
+	BlockOperationPrefixAccount    string = "bo-account-"    // bo-account-<address>-<BlockOperation.Confirmed>-<BlockOperation.Hash>
 )
 
 type BlockOperation struct {
@@ -34,6 +36,9 @@ type BlockOperation struct {
 	Source string
 	Target string
 	Amount Amount
+	Memo   *Memo // only of `OperationPayment`
+
+	Confirmed string // same with the `BlockTransaction` of it
 
 	// transaction will be used only for `Save` time.
 	transaction Transaction
@@ -45,6 +50,11 @@ func NewBlockOperationKey(op Operation, tx Transaction) string {
 }
 
 func NewBlockOperationFromOperation(op Operation, tx Transaction) BlockOperation {
+	var memo *Memo
+	if body, ok := op.B.(OperationBodyPayment); ok {
+		memo = body.Memo
+	}
+
 	return BlockOperation{
 		Hash:   NewBlockOperationKey(op, tx),
 		TxHash: tx.H.Hash,
@@ -53,6 +63,7 @@ func NewBlockOperationFromOperation(op Operation, tx Transaction) BlockOperation
 		Source: tx.B.Source,
 		Target: op.B.TargetAddress(),
 		Amount: op.B.GetAmount(),
+		Memo:   memo,
 
 		transaction: tx,
 	}
@@ -87,6 +98,11 @@ func (bo *BlockOperation) Save(st *sebakstorage.LevelDBBackend) (err error) {
 	if err = st.New(bo.NewBlockOperationCheckpoint(), bo.Hash); err != nil {
 		return
 	}
+	for _, address := range bo.Accounts() {
+		if err = st.New(bo.NewBlockOperationKeyAccount(address), bo.Hash); err != nil {
+			return
+		}
+	}
 
 	bo.isSaved = true
 
@@ -158,6 +174,32 @@ func (bo BlockOperation) NewBlockOperationCheckpoint() string {
 	)
 }
 
+func GetBlockOperationKeyPrefixAccount(address string) string {
+	return fmt.Sprintf("%s%s-", BlockOperationPrefixAccount, address)
+}
+
+// NewBlockOperationKeyAccount makes the key of account index; like the
+// account index of `BlockTransaction`, it is ordered by the confirmed time for
+// the pagination.
+func (bo BlockOperation) NewBlockOperationKeyAccount(address string) string {
+	return fmt.Sprintf(
+		"%s%s-%s",
+		GetBlockOperationKeyPrefixAccount(address),
+		bo.Confirmed,
+		bo.Hash,
+	)
+}
+
+// Accounts returns the source and the target of operation; the operation,
+// like `manage-data` has no target.
+func (bo BlockOperation) Accounts() []string {
+	if len(bo.Target) < 1 || bo.Target == bo.Source {
+		return []string{bo.Source}
+	}
+
+	return []string{bo.Source, bo.Target}
+}
+
 func (bo BlockOperation) NewBlockOperationPeersKey() string {
 	addresses := []string{bo.Target, bo.Source}
 	sort.Strings(addresses)
@@ -245,3 +287,15 @@ func GetBlockOperationsByCheckpoint(st *sebakstorage.LevelDBBackend, checkpoint
 
 	return LoadBlockOperationsInsideIterator(st, iterFunc, closeFunc)
 }
+
+// GetBlockOperationsByAccount returns the operations of account in confirmed
+// order; the operations after the key, `from`, which is made by
+// `NewBlockOperationKeyAccount()` are returned.
+func GetBlockOperationsByAccount(st *sebakstorage.LevelDBBackend, address, from string, reverse bool) (
+	func() (BlockOperation, bool),
+	func(),
+) {
+	iterFunc, closeFunc := st.GetIteratorFrom(GetBlockOperationKeyPrefixAccount(address), from, reverse)
+
+	return LoadBlockOperationsInsideIterator(st, iterFunc, closeFunc)
+}
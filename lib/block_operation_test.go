@@ -3,6 +3,8 @@ package sebak
 import (
 	"testing"
 
+	"github.com/stellar/go/keypair"
+
 	"boscoin.io/sebak/lib/common"
 	"boscoin.io/sebak/lib/error"
 	"boscoin.io/sebak/lib/storage"
@@ -243,3 +245,63 @@ func TestBlockOperationGetSortedByCheckpoint(t *testing.T) {
 		}
 	}
 }
+
+func TestBlockOperationsByAccount(t *testing.T) {
+	kp, _ := keypair.Random()
+	kpTarget, _ := keypair.Random()
+	st, _ := sebakstorage.NewTestMemoryLevelDBBackend()
+
+	var saved []string
+	for i := 0; i < 3; i++ {
+		tx := makeTransactionPayment(kp, kpTarget.Address(), Amount(1))
+		bt := NewBlockTransactionFromTransaction(tx, nil)
+		if err := bt.Save(st); err != nil {
+			t.Error(err)
+			return
+		}
+		saved = append(saved, NewBlockOperationKey(tx.B.Operations[0], tx))
+	}
+
+	collect := func(address, from string, reverse bool) (hashes []string) {
+		iterFunc, closeFunc := GetBlockOperationsByAccount(st, address, from, reverse)
+		defer closeFunc()
+		for {
+			bo, hasNext := iterFunc()
+			if !hasNext {
+				break
+			}
+			hashes = append(hashes, bo.Hash)
+		}
+		return
+	}
+
+	// both of source and target have the operations
+	for _, address := range []string{kp.Address(), kpTarget.Address()} {
+		hashes := collect(address, "", false)
+		if len(hashes) != len(saved) {
+			t.Errorf("fetched records insufficient: %d", len(hashes))
+			return
+		}
+		for i, hash := range saved {
+			if hashes[i] != hash {
+				t.Error("order mismatch")
+				return
+			}
+		}
+	}
+
+	bo, _ := GetBlockOperation(st, saved[1])
+	if len(bo.Confirmed) < 1 {
+		t.Error("operation must have the confirmed time of transaction")
+		return
+	}
+	from := bo.NewBlockOperationKeyAccount(kpTarget.Address())
+	if hashes := collect(kpTarget.Address(), from, false); len(hashes) != 1 || hashes[0] != saved[2] {
+		t.Errorf("wrong operations after key: %v", hashes)
+		return
+	}
+	if hashes := collect(kpTarget.Address(), from, true); len(hashes) != 1 || hashes[0] != saved[0] {
+		t.Errorf("wrong operations before key in reverse: %v", hashes)
+		return
+	}
+}
@@ -0,0 +1,447 @@
+package sebak
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"boscoin.io/sebak/lib/error"
+	"boscoin.io/sebak/lib/network"
+)
+
+// The node, which is behind the other validators fetches the missing blocks
+// by the block sync. The missing heights are split into the ranges of
+// `BlockSyncConfig.RangeSize` blocks, which are requested by
+// `POST /get-blocks` from the validators in parallel; each range is requested
+// from the validators, which announced the block of the end of range, and the
+// range, which fails or times out is requested again from the next
+// validator. The fetched blocks are checked and applied in order; each block
+// must be the next of the latest block and must have the same state hash after
+// it's transaction is applied.
+
+// MaxSyncBlocks is the maximum number of blocks in one range.
+const MaxSyncBlocks uint64 = 100
+
+type BlockSyncConfig struct {
+	RangeSize uint64        // the number of blocks in one range
+	Parallel  int           // the number of ranges, which are requested at once
+	Timeout   time.Duration // the timeout of one range request
+}
+
+var DefaultBlockSyncConfig = BlockSyncConfig{
+	RangeSize: 20,
+	Parallel:  4,
+	Timeout:   10 * time.Second,
+}
+
+// SyncBlock is the block with it's transactions for the block sync.
+type SyncBlock struct {
+	Block        Block             `json:"block"`
+	Transactions []json.RawMessage `json:"transactions"` // the ballot data of `Block.Transactions` in order
+
+	transactions []Transaction
+}
+
+// load checks the block of the height and loads it's transactions.
+func (sb *SyncBlock) load(networkID []byte, height uint64) (err error) {
+	if sb.Block.Height != height {
+		err = fmt.Errorf("block of height, %d is expected, but %d", height, sb.Block.Height)
+		return
+	}
+	if sb.Block.Hash != sb.Block.MakeHashString() {
+		err = fmt.Errorf("hash of block, %d does not match", height)
+		return
+	}
+	// the block has one transaction by consensus
+	if len(sb.Block.Transactions) != 1 || len(sb.Transactions) != len(sb.Block.Transactions) {
+		err = fmt.Errorf("block, %d must have one transaction", height)
+		return
+	}
+
+	sb.transactions = nil
+	for i, raw := range sb.Transactions {
+		var tx Transaction
+		if tx, err = NewTransactionFromJSON(raw); err != nil {
+			return
+		}
+		if tx.GetHash() != sb.Block.Transactions[i] {
+			err = fmt.Errorf("transaction, '%s' is not in block, %d", tx.GetHash(), height)
+			return
+		}
+		if err = tx.IsWellFormed(networkID); err != nil {
+			return
+		}
+		sb.transactions = append(sb.transactions, tx)
+	}
+
+	return
+}
+
+// BlockSync keeps the heights of the validators and the state of the block
+// sync; only one sync runs at once.
+type BlockSync struct {
+	sync.Mutex
+
+	config   BlockSyncConfig
+	heights  map[ /* validator */ string]uint64 // the latest height announced by the validator
+	running  bool
+	behindAt uint64 // the local height + 1, which was behind at the last check
+	synced   uint64
+	failures uint64
+}
+
+func NewBlockSync(config BlockSyncConfig) *BlockSync {
+	return &BlockSync{
+		config:  config,
+		heights: map[string]uint64{},
+	}
+}
+
+func (s *BlockSync) Config() BlockSyncConfig {
+	s.Lock()
+	defer s.Unlock()
+
+	return s.config
+}
+
+func (s *BlockSync) SetConfig(config BlockSyncConfig) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.config = config
+}
+
+// Stats returns the number of the synced blocks and the failed range
+// requests.
+func (s *BlockSync) Stats() (synced, failures uint64) {
+	s.Lock()
+	defer s.Unlock()
+
+	return s.synced, s.failures
+}
+
+func (s *BlockSync) IsRunning() bool {
+	s.Lock()
+	defer s.Unlock()
+
+	return s.running
+}
+
+func (s *BlockSync) observeHeight(validator string, height uint64) {
+	s.Lock()
+	defer s.Unlock()
+
+	if height > s.heights[validator] {
+		s.heights[validator] = height
+	}
+}
+
+// peers returns the validators, which have the block of the height; the
+// order is rotated by `offset`, so the parallel ranges are requested from the
+// different validators.
+func (s *BlockSync) peers(height uint64, offset int) (peers []string) {
+	s.Lock()
+	defer s.Unlock()
+
+	var found []string
+	for validator, h := range s.heights {
+		if h >= height {
+			found = append(found, validator)
+		}
+	}
+	if len(found) < 1 {
+		return
+	}
+	sort.Strings(found)
+
+	for i := range found {
+		peers = append(peers, found[(i+offset)%len(found)])
+	}
+
+	return
+}
+
+// stalled checks the node is behind the network at the same height as the
+// last check; the node, which is just behind by the block in consensus does
+// not sync.
+func (s *BlockSync) stalled(height, networkHeight uint64) bool {
+	s.Lock()
+	defer s.Unlock()
+
+	if networkHeight <= height {
+		s.behindAt = 0
+		return false
+	}
+	if s.behindAt == height+1 {
+		return true
+	}
+	s.behindAt = height + 1
+
+	return false
+}
+
+func (s *BlockSync) start() bool {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.running {
+		return false
+	}
+	s.running = true
+
+	return true
+}
+
+func (s *BlockSync) finish() {
+	s.Lock()
+	defer s.Unlock()
+
+	s.running = false
+	s.behindAt = 0
+}
+
+func (s *BlockSync) failed() {
+	s.Lock()
+	defer s.Unlock()
+
+	s.failures++
+}
+
+func (s *BlockSync) applied() {
+	s.Lock()
+	defer s.Unlock()
+
+	s.synced++
+}
+
+func (nr *NodeRunner) BlockSync() *BlockSync {
+	return nr.blockSync
+}
+
+// checkBlockSync starts the block sync, if the node is stalled behind the
+// network.
+func (nr *NodeRunner) checkBlockSync() {
+	latest, err := GetLatestBlock(nr.storage)
+	if err != nil {
+		return
+	}
+	if !nr.blockSync.stalled(latest.Height, nr.NetworkHeight()) {
+		return
+	}
+
+	go func() {
+		applied, err := nr.SyncBlocks()
+		if err != nil {
+			nr.log.Error("failed to sync blocks", "error", err, "applied", applied)
+			return
+		}
+		if applied > 0 {
+			nr.log.Info("blocks synced", "applied", applied)
+		}
+	}()
+}
+
+// SyncBlocks fetches and applies the blocks up to the network height; the
+// blocks, which are applied before the failure are kept, so the next sync
+// resumes from them.
+func (nr *NodeRunner) SyncBlocks() (applied uint64, err error) {
+	if !nr.blockSync.start() {
+		return
+	}
+	defer nr.blockSync.finish()
+
+	config := nr.blockSync.Config()
+	for {
+		var latest Block
+		if latest, err = GetLatestBlock(nr.storage); err != nil {
+			return
+		}
+		target := nr.NetworkHeight()
+		if target <= latest.Height {
+			return
+		}
+
+		var ranges []sebaknetwork.BlockRange
+		from := latest.Height + 1
+		for len(ranges) < config.Parallel && from <= target {
+			to := from + config.RangeSize - 1
+			if to > target {
+				to = target
+			}
+			ranges = append(ranges, sebaknetwork.BlockRange{From: from, To: to})
+			from = to + 1
+		}
+
+		blocks := make([][]SyncBlock, len(ranges))
+		errs := make([]error, len(ranges))
+
+		var wg sync.WaitGroup
+		for i, r := range ranges {
+			wg.Add(1)
+			go func(i int, r sebaknetwork.BlockRange) {
+				defer wg.Done()
+				blocks[i], errs[i] = nr.fetchBlockRange(r, i, config.Timeout)
+			}(i, r)
+		}
+		wg.Wait()
+
+		for i := range ranges {
+			if err = errs[i]; err != nil {
+				return
+			}
+			for _, sb := range blocks[i] {
+				if err = nr.applySyncBlock(sb); err != nil {
+					return
+				}
+				applied++
+			}
+		}
+	}
+}
+
+// fetchBlockRange requests the range from the validators, which have it, one
+// by one until it succeeds.
+func (nr *NodeRunner) fetchBlockRange(r sebaknetwork.BlockRange, offset int, timeout time.Duration) (blocks []SyncBlock, err error) {
+	// the validators, which do not serve the blocks are skipped
+	var peers []string
+	for _, validator := range nr.blockSync.peers(r.To, offset) {
+		if nr.connectionManager.HasCapability(validator, sebaknetwork.CapabilityBlockSync) {
+			peers = append(peers, validator)
+		}
+	}
+	if len(peers) < 1 {
+		err = sebakerror.ErrorBlockSyncNoPeer
+		return
+	}
+
+	for _, validator := range peers {
+		if blocks, err = nr.fetchBlocks(validator, r, timeout); err == nil {
+			return
+		}
+		nr.blockSync.failed()
+		nr.log.Debug(
+			"failed to fetch blocks; try the next validator",
+			"validator", validator,
+			"from", r.From,
+			"to", r.To,
+			"error", err,
+		)
+	}
+
+	return
+}
+
+func (nr *NodeRunner) fetchBlocks(validator string, r sebaknetwork.BlockRange, timeout time.Duration) (blocks []SyncBlock, err error) {
+	client := nr.connectionManager.GetConnection(validator)
+	if client == nil {
+		err = fmt.Errorf("validator, '%s' is not connected", validator)
+		return
+	}
+
+	type response struct {
+		b   []byte
+		err error
+	}
+	fetched := make(chan response, 1)
+	go func() {
+		b, err := client.GetBlocks(r.From, r.To)
+		fetched <- response{b, err}
+	}()
+
+	var b []byte
+	select {
+	case res := <-fetched:
+		if err = res.err; err != nil {
+			return
+		}
+		b = res.b
+	case <-time.After(timeout):
+		err = fmt.Errorf("timed out after %s", timeout)
+		return
+	}
+
+	if err = json.Unmarshal(b, &blocks); err != nil {
+		return
+	}
+	if uint64(len(blocks)) != r.To-r.From+1 {
+		err = fmt.Errorf("%d blocks are fetched for %d-%d", len(blocks), r.From, r.To)
+		return
+	}
+	for i := range blocks {
+		if err = blocks[i].load(nr.networkID, r.From+uint64(i)); err != nil {
+			return
+		}
+		if i > 0 && blocks[i].Block.PrevBlockHash != blocks[i-1].Block.Hash {
+			err = sebakerror.ErrorBlockNotNext
+			return
+		}
+	}
+
+	return
+}
+
+func (nr *NodeRunner) applySyncBlock(sb SyncBlock) (err error) {
+	tx := sb.transactions[0]
+
+	deferStats := nr.defersWork(DeferrableWorkChainStats)
+	if err = ApplySyncedBlock(nr.storage, nr.networkID, sb.Block, sb.Transactions[0], tx, deferStats); err != nil {
+		return
+	}
+	nr.blockSync.applied()
+
+	nr.transactionPool.Remove(tx.GetHash())
+	nr.transactionStatuses.Included(tx.GetHash(), sb.Block)
+	nr.updateUpgrades(sb.Block)
+	go nr.admitFutureTransactions(tx.B.Source)
+
+	return
+}
+
+// serveBlocks returns the blocks of the range with their transactions for the
+// block sync of the other validators; the range is cut at the latest block.
+// Like the other sync requests, it is refused while the node is busy by
+// `MessageQueue.AcquireSync()`.
+func (nr *NodeRunner) serveBlocks(from, to uint64) (b []byte, err error) {
+	if !nr.messageQueue.AcquireSync() {
+		err = sebakerror.ErrorMessageQueueFull
+		return
+	}
+	defer nr.messageQueue.ReleaseSync()
+
+	if from < 1 || to < from {
+		err = fmt.Errorf("invalid range of blocks, %d-%d", from, to)
+		return
+	}
+	if to-from+1 > MaxSyncBlocks {
+		err = fmt.Errorf("too many blocks; the maximum is %d", MaxSyncBlocks)
+		return
+	}
+
+	var latest Block
+	if latest, err = GetLatestBlock(nr.storage); err != nil {
+		return
+	}
+	if to > latest.Height {
+		to = latest.Height
+	}
+
+	blocks := []SyncBlock{}
+	for height := from; height <= to; height++ {
+		sb := SyncBlock{}
+		if sb.Block, err = GetBlockByHeight(nr.storage, height); err != nil {
+			return
+		}
+		for _, hash := range sb.Block.Transactions {
+			var bt BlockTransaction
+			if bt, err = GetBlockTransaction(nr.storage, hash); err != nil {
+				return
+			}
+			sb.Transactions = append(sb.Transactions, json.RawMessage(bt.Message))
+		}
+		blocks = append(blocks, sb)
+	}
+
+	return json.Marshal(blocks)
+}
@@ -0,0 +1,122 @@
+package sebak
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stellar/go/keypair"
+
+	"boscoin.io/sebak/lib/network"
+)
+
+func TestBlockSyncPeers(t *testing.T) {
+	s := NewBlockSync(DefaultBlockSyncConfig)
+	s.observeHeight("v0", 10)
+	s.observeHeight("v1", 5)
+	s.observeHeight("v2", 10)
+	s.observeHeight("v2", 3)
+
+	if peers := s.peers(10, 0); len(peers) != 2 || peers[0] != "v0" || peers[1] != "v2" {
+		t.Errorf("only the validators, which have the height must be found: %v", peers)
+		return
+	}
+	if peers := s.peers(10, 1); peers[0] != "v2" || peers[1] != "v0" {
+		t.Errorf("peers must be rotated by offset: %v", peers)
+		return
+	}
+	if peers := s.peers(11, 0); len(peers) != 0 {
+		t.Errorf("no validator has the height: %v", peers)
+		return
+	}
+}
+
+func TestBlockSyncStalled(t *testing.T) {
+	s := NewBlockSync(DefaultBlockSyncConfig)
+
+	if s.stalled(3, 4) {
+		t.Error("node, which is just behind must not be stalled")
+		return
+	}
+	if !s.stalled(3, 4) {
+		t.Error("node behind at the same height must be stalled")
+		return
+	}
+	if s.stalled(4, 5) {
+		t.Error("node, which makes progress must not be stalled")
+		return
+	}
+	if s.stalled(5, 5) || s.stalled(5, 6) {
+		t.Error("node, which caught up must not be stalled")
+		return
+	}
+}
+
+func TestNodeRunnerSyncBlocks(t *testing.T) {
+	defer sebaknetwork.CleanUpMemoryNetwork()
+
+	nodeRunners := createNodeRunners(2)
+	nr0, nr1 := nodeRunners[0], nodeRunners[1]
+	for _, nr := range nodeRunners {
+		nr.Network().SetContext(nr.ctx)
+	}
+	nr1.BlockSync().SetConfig(BlockSyncConfig{RangeSize: 2, Parallel: 2, Timeout: DefaultBlockSyncConfig.Timeout})
+
+	kpSource, _ := keypair.Random()
+	kpTarget, _ := keypair.Random()
+	checkpoint := uuid.New().String()
+	for _, nr := range nodeRunners {
+		NewBlockAccount(kpSource.Address(), Amount(BaseFee*1000), checkpoint).Save(nr.Storage())
+		NewBlockAccount(kpTarget.Address(), Amount(BaseFee*1000), checkpoint).Save(nr.Storage())
+	}
+
+	var blocks int
+	for ; blocks < 5; blocks++ {
+		tx := makeTransactionPayment(kpSource, kpTarget.Address(), Amount(1))
+		ballot, _ := NewBallotFromMessage(nr0.Node().Address(), tx)
+		if err := FinishTransaction(nr0.Storage(), networkID, ballot, tx, false); err != nil {
+			t.Error(err)
+			return
+		}
+	}
+	latest, _ := GetLatestBlock(nr0.Storage())
+
+	if _, err := nr0.serveBlocks(1, MaxSyncBlocks+1); err == nil {
+		t.Error("too many blocks must be refused")
+		return
+	}
+
+	// the validator, which is not connected fails and the next is requested
+	nr1.BlockSync().observeHeight("0-unknown", latest.Height)
+	nr1.BlockSync().observeHeight(nr0.Node().Address(), latest.Height)
+	nr1.observeNetworkHeight(latest.Height)
+
+	applied, err := nr1.SyncBlocks()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if applied != uint64(blocks) {
+		t.Errorf("wrong number of applied blocks: %d", applied)
+		return
+	}
+	if synced, _ := GetLatestBlock(nr1.Storage()); synced.Hash != latest.Hash {
+		t.Errorf("synced block must be same: %v %v", synced, latest)
+		return
+	}
+	if synced, failures := nr1.BlockSync().Stats(); synced != uint64(blocks) || failures < 1 {
+		t.Errorf("wrong stats: %d synced, %d failures", synced, failures)
+		return
+	}
+	source0, _ := GetBlockAccount(nr0.Storage(), kpSource.Address())
+	source1, _ := GetBlockAccount(nr1.Storage(), kpSource.Address())
+	if source0.Balance != source1.Balance {
+		t.Errorf("synced account must be same: %v %v", source0, source1)
+		return
+	}
+
+	// nothing to sync
+	if applied, err = nr1.SyncBlocks(); err != nil || applied != 0 {
+		t.Errorf("synced node must not sync: %d %v", applied, err)
+		return
+	}
+}
@@ -0,0 +1,52 @@
+package sebak
+
+import (
+	"testing"
+
+	"boscoin.io/sebak/lib/storage"
+)
+
+func TestBlockSaveAndLatest(t *testing.T) {
+	st, _ := sebakstorage.NewTestMemoryLevelDBBackend()
+	defer st.Close()
+
+	if latest, err := GetLatestBlock(st); err != nil || !latest.IsEmpty() {
+		t.Error("latest block must be empty without blocks")
+		return
+	}
+
+	var prev Block
+	for i := 0; i < 3; i++ {
+		_, tx := TestMakeTransaction(networkID, 1)
+		b := NewBlock(prev, "", tx.GetHash())
+		if err := b.Save(st); err != nil {
+			t.Error(err)
+			return
+		}
+		if b.PrevBlockHash != prev.Hash || b.Height != prev.Height+1 {
+			t.Error("block is not linked to previous block")
+			return
+		}
+		prev = b
+	}
+
+	if err := prev.Save(st); err == nil {
+		t.Error("same block must not be saved again")
+		return
+	}
+
+	latest, err := GetLatestBlock(st)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if latest.Hash != prev.Hash || latest.Height != 3 {
+		t.Error("failed to get latest block")
+		return
+	}
+
+	if b, err := GetBlockByHeight(st, 2); err != nil || b.Hash != latest.PrevBlockHash {
+		t.Error("failed to get block by height")
+		return
+	}
+}
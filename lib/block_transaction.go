@@ -15,12 +15,15 @@ import (
 //  * //get list by `Checkpoint` and created order
 //  * get list by `Source` and created order
 //  * get list by `Confirmed` order
+//  * get list by account, which is the source or the target of operations
+//  and confirmed order
 
 const (
 	BlockTransactionPrefixHash       string = "bt-hash-"       // bt-hash-<BlockTransaction.Hash>
 	BlockTransactionPrefixCheckpoint string = "bt-checkpoint-" // bt-hash-<BlockTransaction.Checkpoint>
 	BlockTransactionPrefixSource     string = "bt-source-"     // bt-hash-<BlockTransaction.Source>
 	BlockTransactionPrefixConfirmed  string = "bt-confirmed-"  // bt-hash-<BlockTransaction.Confirmed>
+	BlockTransactionPrefixAccount    string = "bt-account-"    // bt-account-<address>-<BlockTransaction.Confirmed>-<BlockTransaction.Hash>
 )
 
 // TODO(BlockTransaction): support counting
@@ -85,6 +88,37 @@ func (bt BlockTransaction) NewBlockTransactionKeyConfirmed() string {
 	)
 }
 
+// NewBlockTransactionKeyAccount makes the key of account index; unlike the
+// other indices, the key is ordered by the confirmed time, so it can be found
+// again from the transaction for the pagination.
+func (bt BlockTransaction) NewBlockTransactionKeyAccount(address string) string {
+	return fmt.Sprintf(
+		"%s%s-%s",
+		GetBlockTransactionKeyPrefixAccount(address),
+		bt.Confirmed,
+		bt.Hash,
+	)
+}
+
+// Accounts returns the accounts, which are involved in the transaction; the
+// source and the targets of operations.
+func (bt BlockTransaction) Accounts() (accounts []string) {
+	accounts = []string{bt.Source}
+	for _, op := range bt.transaction.B.Operations {
+		target := op.B.TargetAddress()
+
+		var found bool
+		for _, a := range accounts {
+			found = found || a == target
+		}
+		if !found && len(target) > 0 {
+			accounts = append(accounts, target)
+		}
+	}
+
+	return
+}
+
 func (bt *BlockTransaction) Save(st *sebakstorage.LevelDBBackend) (err error) {
 	if bt.isSaved {
 		return sebakerror.ErrorAlreadySaved
@@ -100,7 +134,10 @@ func (bt *BlockTransaction) Save(st *sebakstorage.LevelDBBackend) (err error) {
 		return sebakerror.ErrorBlockAlreadyExists
 	}
 
-	bt.Confirmed = sebakcommon.NowISO8601()
+	// the synced transaction keeps the time of it's block
+	if len(bt.Confirmed) < 1 {
+		bt.Confirmed = sebakcommon.NowISO8601()
+	}
 	if err = st.New(GetBlockTransactionKey(bt.Hash), bt); err != nil {
 		return
 	}
@@ -113,9 +150,15 @@ func (bt *BlockTransaction) Save(st *sebakstorage.LevelDBBackend) (err error) {
 	if err = st.New(bt.NewBlockTransactionKeyConfirmed(), bt.Hash); err != nil {
 		return
 	}
+	for _, address := range bt.Accounts() {
+		if err = st.New(bt.NewBlockTransactionKeyAccount(address), bt.Hash); err != nil {
+			return
+		}
+	}
 
 	for _, op := range bt.transaction.B.Operations {
 		bo := NewBlockOperationFromOperation(op, bt.transaction)
+		bo.Confirmed = bt.Confirmed
 		if err = bo.Save(st); err != nil {
 			return
 		}
@@ -144,6 +187,10 @@ func GetBlockTransactionKeyPrefixSource(source string) string {
 	return fmt.Sprintf("%s%s-", BlockTransactionPrefixSource, source)
 }
 
+func GetBlockTransactionKeyPrefixAccount(address string) string {
+	return fmt.Sprintf("%s%s-", BlockTransactionPrefixAccount, address)
+}
+
 func GetBlockTransactionKeyPrefixConfirmed(confirmed string) string {
 	return fmt.Sprintf("%s%s-", BlockTransactionPrefixConfirmed, confirmed)
 }
@@ -222,4 +269,16 @@ func GetBlockTransactionsByConfirmed(st *sebakstorage.LevelDBBackend, reverse bo
 	return LoadBlockTransactionsInsideIterator(st, iterFunc, closeFunc)
 }
 
+// GetBlockTransactionsByAccount returns the transactions of account in
+// confirmed order; the transactions after the key, `from`, which is made by
+// `NewBlockTransactionKeyAccount()` are returned.
+func GetBlockTransactionsByAccount(st *sebakstorage.LevelDBBackend, address, from string, reverse bool) (
+	func() (BlockTransaction, bool),
+	func(),
+) {
+	iterFunc, closeFunc := st.GetIteratorFrom(GetBlockTransactionKeyPrefixAccount(address), from, reverse)
+
+	return LoadBlockTransactionsInsideIterator(st, iterFunc, closeFunc)
+}
+
 var GetBlockTransactions = GetBlockTransactionsByConfirmed
@@ -74,6 +74,10 @@ func (bt *BlockTransactionHistory) Save(st *sebakstorage.LevelDBBackend) (err er
 	return nil
 }
 
+func ExistBlockTransactionHistory(st *sebakstorage.LevelDBBackend, hash string) (bool, error) {
+	return st.Has(GetBlockTransactionHistoryKey(hash))
+}
+
 func GetBlockTransactionHistory(st *sebakstorage.LevelDBBackend, hash string) (bt BlockTransactionHistory, err error) {
 	if err = st.Get(GetBlockTransactionHistoryKey(hash), &bt); err != nil {
 		return
@@ -399,3 +399,64 @@ func TestBlockTransactionGetByCheckpoint(t *testing.T) {
 		return
 	}
 }
+
+func TestBlockTransactionsByAccount(t *testing.T) {
+	kp, _ := keypair.Random()
+	kpTarget, _ := keypair.Random()
+	st, _ := sebakstorage.NewTestMemoryLevelDBBackend()
+
+	var saved []BlockTransaction
+	for i := 0; i < 3; i++ {
+		tx := makeTransactionPayment(kp, kpTarget.Address(), Amount(1))
+		bt := NewBlockTransactionFromTransaction(tx, nil)
+		if err := bt.Save(st); err != nil {
+			t.Error(err)
+			return
+		}
+		saved = append(saved, bt)
+	}
+
+	collect := func(address, from string, reverse bool) (hashes []string) {
+		iterFunc, closeFunc := GetBlockTransactionsByAccount(st, address, from, reverse)
+		defer closeFunc()
+		for {
+			bt, hasNext := iterFunc()
+			if !hasNext {
+				break
+			}
+			hashes = append(hashes, bt.Hash)
+		}
+		return
+	}
+
+	// both of source and target have the transactions
+	for _, address := range []string{kp.Address(), kpTarget.Address()} {
+		hashes := collect(address, "", false)
+		if len(hashes) != len(saved) {
+			t.Errorf("fetched records insufficient: %d", len(hashes))
+			return
+		}
+		for i, bt := range saved {
+			if hashes[i] != bt.Hash {
+				t.Error("order mismatch")
+				return
+			}
+		}
+	}
+
+	from := saved[1].NewBlockTransactionKeyAccount(kpTarget.Address())
+	if hashes := collect(kpTarget.Address(), from, false); len(hashes) != 1 || hashes[0] != saved[2].Hash {
+		t.Errorf("wrong transactions after key: %v", hashes)
+		return
+	}
+	if hashes := collect(kpTarget.Address(), from, true); len(hashes) != 1 || hashes[0] != saved[0].Hash {
+		t.Errorf("wrong transactions before key in reverse: %v", hashes)
+		return
+	}
+
+	another, _ := keypair.Random()
+	if hashes := collect(another.Address(), "", false); len(hashes) != 0 {
+		t.Errorf("not involved account must not have transactions: %v", hashes)
+		return
+	}
+}
@@ -0,0 +1,281 @@
+package sebak
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"boscoin.io/sebak/lib/storage"
+)
+
+// ChainStats is the rollup of the blocks in one period, the day or the epoch
+// of `ChainStatsEpochBlocks` blocks. The rollups are updated when the block is
+// committed, so the dashboards do not need to aggregate the transactions. The
+// storage should support,
+//  * 'cs-day-<YYYY-MM-DD>': `ChainStats` of the UTC day of `Block.Confirmed`
+//  * 'cs-epoch-<epoch>': `ChainStats` of the epoch
+//  * 'cs-active-<period key>-<address>': marker of the active account of period
+//  * 'cs-pending': the first height, which is not rolled up yet; it exists only
+//  while the rollups are deferred by the load shedding
+// The active accounts are the source and target accounts of transactions.
+
+const (
+	ChainStatsPrefixDay    string = "cs-day-"
+	ChainStatsPrefixEpoch  string = "cs-epoch-"
+	ChainStatsPrefixActive string = "cs-active-"
+	ChainStatsKeyPending   string = "cs-pending"
+)
+
+// ChainStatsCatchUpBlocks is the number of deferred blocks, which are rolled
+// up at once.
+const ChainStatsCatchUpBlocks int = 100
+
+const ChainStatsEpochBlocks uint64 = 1000
+
+const (
+	ChainStatsPeriodDay   string = "day"
+	ChainStatsPeriodEpoch string = "epoch"
+)
+
+type ChainStats struct {
+	Period           string `json:"period"` // day like '2018-08-01' or epoch like '3'
+	FirstHeight      uint64 `json:"first_height"`
+	LastHeight       uint64 `json:"last_height"`
+	TransactionCount uint64 `json:"transaction_count"`
+	OperationCount   uint64 `json:"operation_count"`
+	Volume           Amount `json:"volume"`
+	Fees             Amount `json:"fees"`
+	ActiveAccounts   uint64 `json:"active_accounts"`
+}
+
+func GetChainStatsDayKey(day string) string {
+	return fmt.Sprintf("%s%s", ChainStatsPrefixDay, day)
+}
+
+func GetChainStatsEpochKey(epoch uint64) string {
+	return fmt.Sprintf("%s%020d", ChainStatsPrefixEpoch, epoch)
+}
+
+func getChainStatsActiveKey(key, address string) string {
+	return fmt.Sprintf("%s%s-%s", ChainStatsPrefixActive, key, address)
+}
+
+// GetChainStatsEpoch returns the epoch of block height; the first epoch is 0.
+func GetChainStatsEpoch(height uint64) uint64 {
+	if height < 1 {
+		return 0
+	}
+	return (height - 1) / ChainStatsEpochBlocks
+}
+
+func getChainStatsDay(block Block) (day string, err error) {
+	var confirmed time.Time
+	if confirmed, err = time.Parse(time.RFC3339Nano, block.Confirmed); err != nil {
+		return
+	}
+	day = confirmed.UTC().Format("2006-01-02")
+
+	return
+}
+
+// UpdateChainStats adds the transactions of block to the rollups of it's day
+// and epoch.
+func UpdateChainStats(st *sebakstorage.LevelDBBackend, block Block, transactions ...Transaction) (err error) {
+	var day string
+	if day, err = getChainStatsDay(block); err != nil {
+		return
+	}
+
+	epoch := GetChainStatsEpoch(block.Height)
+
+	periods := map[string]string{
+		GetChainStatsDayKey(day):     day,
+		GetChainStatsEpochKey(epoch): fmt.Sprintf("%d", epoch),
+	}
+	for key, period := range periods {
+		if err = updateChainStats(st, key, period, block, transactions); err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+func updateChainStats(st *sebakstorage.LevelDBBackend, key, period string, block Block, transactions []Transaction) (err error) {
+	var exists bool
+	if exists, err = st.Has(key); err != nil {
+		return
+	}
+
+	var stats ChainStats
+	if exists {
+		if err = st.Get(key, &stats); err != nil {
+			return
+		}
+	} else {
+		stats = ChainStats{Period: period, FirstHeight: block.Height}
+	}
+	stats.LastHeight = block.Height
+
+	for _, tx := range transactions {
+		stats.TransactionCount++
+		stats.OperationCount += uint64(len(tx.B.Operations))
+
+		amount := tx.TotalAmount(false)
+		if stats.Volume, err = stats.Volume.Add(amount); err != nil {
+			return
+		}
+		stats.Fees += tx.TotalAmount(true) - amount
+
+		addresses := []string{tx.B.Source}
+		for _, op := range tx.B.Operations {
+			if target := op.B.TargetAddress(); len(target) > 0 {
+				addresses = append(addresses, target)
+			}
+		}
+		for _, address := range addresses {
+			activeKey := getChainStatsActiveKey(key, address)
+
+			var active bool
+			if active, err = st.Has(activeKey); err != nil {
+				return
+			} else if active {
+				continue
+			}
+			if err = st.New(activeKey, true); err != nil {
+				return
+			}
+			stats.ActiveAccounts++
+		}
+	}
+
+	if exists {
+		err = st.Set(key, stats)
+	} else {
+		err = st.New(key, stats)
+	}
+
+	return
+}
+
+// HasPendingChainStats returns true if some blocks are not rolled up yet.
+func HasPendingChainStats(st *sebakstorage.LevelDBBackend) (bool, error) {
+	return st.Has(ChainStatsKeyPending)
+}
+
+// deferChainStats marks the block to be rolled up later; the blocks after the
+// first deferred one are also deferred, so they are rolled up in order.
+func deferChainStats(st *sebakstorage.LevelDBBackend, block Block) (err error) {
+	var pending bool
+	if pending, err = HasPendingChainStats(st); err != nil || pending {
+		return
+	}
+
+	return st.New(ChainStatsKeyPending, block.Height)
+}
+
+// CatchUpChainStats rolls up the deferred blocks up to `limit` blocks in
+// order; `done` is true when all the blocks are rolled up.
+func CatchUpChainStats(st *sebakstorage.LevelDBBackend, limit int) (done bool, err error) {
+	var pending bool
+	if pending, err = HasPendingChainStats(st); err != nil {
+		return
+	} else if !pending {
+		done = true
+		return
+	}
+
+	var from uint64
+	if err = st.Get(ChainStatsKeyPending, &from); err != nil {
+		return
+	}
+
+	var latest Block
+	if latest, err = GetLatestBlock(st); err != nil {
+		return
+	}
+
+	var ts *sebakstorage.LevelDBBackend
+	if ts, err = st.OpenTransaction(); err != nil {
+		return
+	}
+
+	height := from
+	for ; height <= latest.Height && height < from+uint64(limit); height++ {
+		var block Block
+		if block, err = GetBlockByHeight(ts, height); err != nil {
+			ts.Discard()
+			return
+		}
+
+		var transactions []Transaction
+		for _, hash := range block.Transactions {
+			var bt BlockTransaction
+			if bt, err = GetBlockTransaction(ts, hash); err != nil {
+				ts.Discard()
+				return
+			}
+
+			var tx Transaction
+			if tx, err = NewTransactionFromJSON(bt.Message); err != nil {
+				ts.Discard()
+				return
+			}
+			transactions = append(transactions, tx)
+		}
+
+		if err = UpdateChainStats(ts, block, transactions...); err != nil {
+			ts.Discard()
+			return
+		}
+	}
+
+	if done = height > latest.Height; done {
+		err = ts.Remove(ChainStatsKeyPending)
+	} else {
+		err = ts.Set(ChainStatsKeyPending, height)
+	}
+	if err != nil {
+		ts.Discard()
+		return
+	}
+
+	if err = ts.Commit(); err != nil {
+		ts.Discard()
+	}
+
+	return
+}
+
+// GetChainStats returns the rollups of the period, `ChainStatsPeriodDay` or
+// `ChainStatsPeriodEpoch`, from the latest one.
+func GetChainStats(st *sebakstorage.LevelDBBackend, period string, limit int) (stats []ChainStats, err error) {
+	var prefix string
+	switch period {
+	case ChainStatsPeriodDay:
+		prefix = ChainStatsPrefixDay
+	case ChainStatsPeriodEpoch:
+		prefix = ChainStatsPrefixEpoch
+	default:
+		err = fmt.Errorf("unknown period, '%s'", period)
+		return
+	}
+
+	iterFunc, closeFunc := st.GetIterator(prefix, true)
+	defer closeFunc()
+
+	for len(stats) < limit {
+		item, hasNext := iterFunc()
+		if !hasNext {
+			break
+		}
+
+		var s ChainStats
+		if err = json.Unmarshal(item.Value, &s); err != nil {
+			return
+		}
+		stats = append(stats, s)
+	}
+
+	return
+}
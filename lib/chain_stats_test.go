@@ -0,0 +1,79 @@
+package sebak
+
+import (
+	"testing"
+
+	"boscoin.io/sebak/lib/storage"
+)
+
+func TestChainStats(t *testing.T) {
+	st, _ := sebakstorage.NewTestMemoryLevelDBBackend()
+
+	_, tx0 := TestMakeTransaction(networkID, 2)
+	_, tx1 := TestMakeTransaction(networkID, 1)
+
+	// same source again
+	tx2 := tx1
+	tx2.B.Operations = []Operation{TestMakeOperation(10)}
+
+	var blocks []Block
+	prev := Block{}
+	for _, confirmed := range []string{"2018-08-01T23:59:59.000000000+00:00", "2018-08-02T08:00:00.000000000+09:00", "2018-08-02T10:00:00.000000000+00:00"} {
+		prev = NewBlock(prev, "")
+		prev.Confirmed = confirmed
+		blocks = append(blocks, prev)
+	}
+
+	for i, tx := range []Transaction{tx0, tx1, tx2} {
+		if err := UpdateChainStats(st, blocks[i], tx); err != nil {
+			t.Error(err)
+			return
+		}
+	}
+
+	days, err := GetChainStats(st, ChainStatsPeriodDay, 10)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	// '2018-08-02T08:00:00+09:00' is '2018-08-01' in UTC
+	if len(days) != 2 || days[0].Period != "2018-08-02" || days[1].Period != "2018-08-01" {
+		t.Errorf("wrong days: %v", days)
+		return
+	}
+
+	d := days[1]
+	if d.FirstHeight != 1 || d.LastHeight != 2 || d.TransactionCount != 2 || d.OperationCount != 3 {
+		t.Errorf("wrong stats: %v", d)
+		return
+	}
+	if d.Volume != tx0.TotalAmount(false)+tx1.TotalAmount(false) {
+		t.Errorf("wrong volume: %v", d)
+		return
+	}
+	if d.Fees != Amount(BaseFee)*3 {
+		t.Errorf("wrong fees: %v", d)
+		return
+	}
+	if d.ActiveAccounts != 5 { // 2 sources and 3 targets
+		t.Errorf("wrong active accounts: %v", d)
+		return
+	}
+
+	// the source of tx1 is already active in the epoch
+	epochs, _ := GetChainStats(st, ChainStatsPeriodEpoch, 10)
+	if len(epochs) != 1 || epochs[0].TransactionCount != 3 || epochs[0].ActiveAccounts != 6 {
+		t.Errorf("wrong epochs: %v", epochs)
+		return
+	}
+
+	if limited, _ := GetChainStats(st, ChainStatsPeriodDay, 1); len(limited) != 1 || limited[0].Period != "2018-08-02" {
+		t.Error("the latest stats must be returned first")
+		return
+	}
+
+	if _, err = GetChainStats(st, "week", 1); err == nil {
+		t.Error("unknown period must be refused")
+		return
+	}
+}
@@ -0,0 +1,109 @@
+package sebakcommon
+
+import (
+	"encoding/binary"
+)
+
+// The encodings of the messages for hashing and signing; the version is in
+// the header of message, like `TransactionHeader.Version`,
+//  * `EncodingVersionRLP`: the RLP of the struct; the fields are in the order
+//  of the struct fields in Go, so the other implementations must follow the
+//  structs. The messages before the versioning have it.
+//  * `EncodingVersionCanonical`: the canonical binary encoding of
+//  `CanonicalEncoder`; the fields are written explicitly by
+//  `EncodeCanonical()` of each message.
+const (
+	EncodingVersionRLP       string = ""
+	EncodingVersionCanonical string = "1"
+)
+
+func IsKnownEncodingVersion(version string) bool {
+	return version == EncodingVersionRLP || version == EncodingVersionCanonical
+}
+
+// CanonicalEncodingPrefix is the first byte of the canonical encoding; it is
+// never the first byte of the RLP of struct, which starts from 0xc0, so the
+// encodings of the different versions are never same.
+const CanonicalEncodingPrefix byte = 0x01
+
+// CanonicalEncoder writes the canonical binary encoding. The encoding starts
+// with `CanonicalEncodingPrefix` and the kind of message, and the fields
+// follow in the order of `EncodeCanonical()`,
+//  * unsigned and signed integer: 8 bytes in big endian
+//  * bool: 1 byte, 0 or 1
+//  * string and bytes: the length in 4 bytes big endian, and the bytes
+//  * list: the number of items in 4 bytes big endian, and the items
+// The same message is always encoded to the same bytes, whatever the JSON
+// of it is, like the order of fields and the whitespaces.
+type CanonicalEncoder struct {
+	b []byte
+}
+
+type CanonicalEncodable interface {
+	EncodeCanonical(*CanonicalEncoder)
+}
+
+func NewCanonicalEncoder(kind string) *CanonicalEncoder {
+	e := &CanonicalEncoder{b: []byte{CanonicalEncodingPrefix}}
+	e.String(kind)
+
+	return e
+}
+
+func (e *CanonicalEncoder) Encoded() []byte {
+	return e.b
+}
+
+func (e *CanonicalEncoder) Uint64(v uint64) {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], v)
+	e.b = append(e.b, b[:]...)
+}
+
+func (e *CanonicalEncoder) Int64(v int64) {
+	e.Uint64(uint64(v))
+}
+
+func (e *CanonicalEncoder) Bool(v bool) {
+	if v {
+		e.b = append(e.b, 1)
+	} else {
+		e.b = append(e.b, 0)
+	}
+}
+
+// Len writes the length of string or bytes, or the number of items of list.
+func (e *CanonicalEncoder) Len(n int) {
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], uint32(n))
+	e.b = append(e.b, b[:]...)
+}
+
+func (e *CanonicalEncoder) Bytes(v []byte) {
+	e.Len(len(v))
+	e.b = append(e.b, v...)
+}
+
+func (e *CanonicalEncoder) String(v string) {
+	e.Bytes([]byte(v))
+}
+
+func (e *CanonicalEncoder) Strings(list []string) {
+	e.Len(len(list))
+	for _, v := range list {
+		e.String(v)
+	}
+}
+
+// EncodeCanonical returns the canonical encoding of `o` as `kind`.
+func EncodeCanonical(kind string, o CanonicalEncodable) []byte {
+	e := NewCanonicalEncoder(kind)
+	o.EncodeCanonical(e)
+
+	return e.Encoded()
+}
+
+// MakeCanonicalHash returns the hash of the canonical encoding of `o`.
+func MakeCanonicalHash(kind string, o CanonicalEncodable) []byte {
+	return MakeHash(EncodeCanonical(kind, o))
+}
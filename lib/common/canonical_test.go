@@ -0,0 +1,52 @@
+package sebakcommon
+
+import (
+	"bytes"
+	"testing"
+)
+
+type canonicalTestMessage struct {
+	Name   string
+	Amount uint64
+	Tags   []string
+}
+
+func (m canonicalTestMessage) EncodeCanonical(e *CanonicalEncoder) {
+	e.String(m.Name)
+	e.Uint64(m.Amount)
+	e.Strings(m.Tags)
+}
+
+func TestCanonicalEncoder(t *testing.T) {
+	encoded := EncodeCanonical("test", canonicalTestMessage{Name: "a", Amount: 1, Tags: []string{"b"}})
+	expected := []byte{
+		CanonicalEncodingPrefix,
+		0, 0, 0, 4, 't', 'e', 's', 't',
+		0, 0, 0, 1, 'a',
+		0, 0, 0, 0, 0, 0, 0, 1,
+		0, 0, 0, 1, 0, 0, 0, 1, 'b',
+	}
+	if !bytes.Equal(encoded, expected) {
+		t.Errorf("wrong encoding: %v", encoded)
+		return
+	}
+
+	// the kind is the part of encoding
+	if bytes.Equal(encoded, EncodeCanonical("other", canonicalTestMessage{Name: "a", Amount: 1, Tags: []string{"b"}})) {
+		t.Error("different kind must be encoded differently")
+		return
+	}
+
+	// the boundaries of strings are kept
+	a := EncodeCanonical("test", canonicalTestMessage{Tags: []string{"ab", "c"}})
+	b := EncodeCanonical("test", canonicalTestMessage{Tags: []string{"a", "bc"}})
+	if bytes.Equal(a, b) {
+		t.Error("different strings must be encoded differently")
+		return
+	}
+
+	if !IsKnownEncodingVersion(EncodingVersionRLP) || !IsKnownEncodingVersion(EncodingVersionCanonical) || IsKnownEncodingVersion("2") {
+		t.Error("wrong known encoding versions")
+		return
+	}
+}
@@ -7,10 +7,12 @@ import (
 	"io"
 	"net"
 	"net/http"
+	"net/url"
 	"sync"
 	"time"
 
 	"golang.org/x/net/http2"
+	"golang.org/x/net/proxy"
 )
 
 type HTTP2Client struct {
@@ -20,27 +22,50 @@ type HTTP2Client struct {
 	conn   net.Conn
 }
 
+// NewHTTP2Client makes the client, which does not verify the certificate of
+// server; the nodes use the self-signed certificates by default.
 func NewHTTP2Client(timeout, idleTimeout time.Duration, keepAlive bool) (client *HTTP2Client, err error) {
-	if keepAlive {
-		timeout, idleTimeout = 0, 0
-	}
+	return NewHTTP2ClientWithTLS(timeout, idleTimeout, keepAlive, &tls.Config{InsecureSkipVerify: true})
+}
+
+// NewHTTP2ClientWithTLS makes the client with the TLS config, like the CA of
+// server and the certificate of client.
+func NewHTTP2ClientWithTLS(timeout, idleTimeout time.Duration, keepAlive bool, tlsConfig *tls.Config) (client *HTTP2Client, err error) {
+	return NewHTTP2ClientWithProxy(timeout, idleTimeout, keepAlive, tlsConfig, nil)
+}
 
+// NewHTTP2ClientWithProxy makes the client, which connects to the server
+// through the SOCKS5 proxy, like 'socks5://127.0.0.1:9050'; with nil
+// `proxyURL`, it connects directly.
+func NewHTTP2ClientWithProxy(timeout, idleTimeout time.Duration, keepAlive bool, tlsConfig *tls.Config, proxyURL *url.URL) (client *HTTP2Client, err error) {
 	dialer := &net.Dialer{
 		Timeout:   1 * time.Second,
 		KeepAlive: 100000 * time.Second,
 		DualStack: true,
 	}
 
+	dial := dialer.DialContext
+	if proxyURL != nil {
+		var proxyDialer proxy.Dialer
+		if proxyDialer, err = proxy.FromURL(proxyURL, dialer); err != nil {
+			return
+		}
+		dial = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			if d, ok := proxyDialer.(proxy.ContextDialer); ok {
+				return d.DialContext(ctx, network, addr)
+			}
+			return proxyDialer.Dial(network, addr)
+		}
+	}
+
 	client = &HTTP2Client{}
 
 	transport := &http.Transport{
-		TLSClientConfig: &tls.Config{
-			InsecureSkipVerify: true,
-		},
+		TLSClientConfig:   tlsConfig,
 		IdleConnTimeout:   idleTimeout,
 		DisableKeepAlives: !keepAlive,
 		DialContext: func(ctx context.Context, network, addr string) (conn net.Conn, err error) {
-			conn, err = dialer.DialContext(ctx, network, addr)
+			conn, err = dial(ctx, network, addr)
 
 			client.conn = conn
 			return conn, err
@@ -63,6 +88,9 @@ func NewHTTP2Client(timeout, idleTimeout time.Duration, keepAlive bool) (client
 }
 
 func (c *HTTP2Client) Close() {
+	if transport, ok := c.client.Transport.(*http.Transport); ok {
+		transport.CloseIdleConnections()
+	}
 	if c.conn == nil {
 		return
 	}
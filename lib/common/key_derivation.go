@@ -0,0 +1,76 @@
+package sebakcommon
+
+import (
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"fmt"
+)
+
+// The keys of account are derived from the seed of mnemonic by SLIP-0010 for
+// ed25519, like SEP-0005 of stellar; the key of `index` is at
+// `m/44'/148'/<index>'`, so the same mnemonic restores the same accounts in
+// the other wallets, which follow SEP-0005. ed25519 of SLIP-0010 has only
+// the hardened derivation.
+const (
+	HardenedKeyOffset uint32 = 0x80000000
+
+	KeyDerivationPurpose  uint32 = 44
+	KeyDerivationCoinType uint32 = 148
+)
+
+var slip10ED25519Curve = []byte("ed25519 seed")
+
+// DerivedKey is the private key of ed25519 with it's chain code.
+type DerivedKey struct {
+	Key       [32]byte
+	ChainCode [32]byte
+}
+
+// NewMasterKey makes the master key from the seed of mnemonic.
+func NewMasterKey(seed []byte) (k DerivedKey) {
+	return newDerivedKey(slip10ED25519Curve, seed)
+}
+
+// Child derives the hardened child key of `index`; `HardenedKeyOffset` is
+// added to `index`, if it is not hardened.
+func (k DerivedKey) Child(index uint32) DerivedKey {
+	if index < HardenedKeyOffset {
+		index += HardenedKeyOffset
+	}
+
+	data := make([]byte, 1+32+4)
+	copy(data[1:33], k.Key[:])
+	binary.BigEndian.PutUint32(data[33:], index)
+
+	return newDerivedKey(k.ChainCode[:], data)
+}
+
+func newDerivedKey(key, data []byte) (k DerivedKey) {
+	h := hmac.New(sha512.New, key)
+	h.Write(data)
+	sum := h.Sum(nil)
+
+	copy(k.Key[:], sum[:32])
+	copy(k.ChainCode[:], sum[32:])
+
+	return
+}
+
+// DeriveAccountKey derives the private key of the account of `index` from
+// the seed of mnemonic; it is the raw seed of keypair.
+func DeriveAccountKey(seed []byte, index uint32) (key [32]byte, err error) {
+	if index >= HardenedKeyOffset {
+		err = fmt.Errorf("index must be lower than %d", HardenedKeyOffset)
+		return
+	}
+
+	key = NewMasterKey(seed).Child(KeyDerivationPurpose).Child(KeyDerivationCoinType).Child(index).Key
+
+	return
+}
+
+// AccountKeyPath is the derivation path of the account of `index`.
+func AccountKeyPath(index uint32) string {
+	return fmt.Sprintf("m/%d'/%d'/%d'", KeyDerivationPurpose, KeyDerivationCoinType, index)
+}
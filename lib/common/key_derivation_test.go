@@ -0,0 +1,36 @@
+package sebakcommon
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+// the test vector of SEP-0005; the seed of mnemonic, "illness spike retreat
+// truth genius clock brain pass fit cave bargain toe"
+func TestDeriveAccountKey(t *testing.T) {
+	seed, _ := hex.DecodeString("e4a5a632e70943ae7f07659df1332160937fad82587216a4c64315a0fb39497ee4a01f76ddab4cba68147977f3a147b6ad584c41808e8238a07f6cc4b582f186")
+
+	for index, expected := range []string{
+		"4d691bc19b44a1383b1a0a130aaca3e05c3c1a371dbe45930ef9b761f7a74691",
+		"88f296c601bafd56fd19d1856ee46670b9e2c87db0455ca792b5d8d588a353f1",
+	} {
+		key, err := DeriveAccountKey(seed, uint32(index))
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if hex.EncodeToString(key[:]) != expected {
+			t.Errorf("wrong key of index %d: %x", index, key)
+			return
+		}
+	}
+
+	if _, err := DeriveAccountKey(seed, HardenedKeyOffset); err == nil {
+		t.Error("hardened index must be refused")
+		return
+	}
+	if path := AccountKeyPath(3); path != "m/44'/148'/3'" {
+		t.Errorf("wrong path: %s", path)
+		return
+	}
+}
@@ -0,0 +1,178 @@
+package sebakcommon
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// The mnemonic is the BIP39 mnemonic of the English wordlist; every word is
+// the 11 bits of the entropy and it's checksum, the first bits of the SHA-256
+// of the entropy, one bit for each 32 bits of entropy. The seed of the
+// mnemonic is PBKDF2 of HMAC-SHA512 by 2048 iterations with the salt,
+// "mnemonic" and the passphrase, so the same words restore the same seed in
+// the other wallets. The words and the passphrase are not normalized by NFKD,
+// so they must be ASCII to be compatible.
+const (
+	MnemonicSeedIterations int = 2048
+	MnemonicSeedSize       int = 64
+)
+
+var (
+	mnemonicWordIndex map[string]int
+
+	errInvalidMnemonicWords   = errors.New("number of words must be 12, 15, 18, 21 or 24")
+	errInvalidMnemonicEntropy = errors.New("entropy must be 16, 20, 24, 28 or 32 bytes")
+	errMnemonicChecksum       = errors.New("checksum of mnemonic is incorrect")
+)
+
+func init() {
+	mnemonicWordIndex = map[string]int{}
+	for i, word := range mnemonicWordList {
+		mnemonicWordIndex[word] = i
+	}
+}
+
+// NewMnemonic makes the new mnemonic of `words` words from the random
+// entropy.
+func NewMnemonic(words int) (mnemonic string, err error) {
+	if words < 12 || words > 24 || words%3 != 0 {
+		err = errInvalidMnemonicWords
+		return
+	}
+
+	entropy := make([]byte, words/3*4)
+	if _, err = rand.Read(entropy); err != nil {
+		return
+	}
+
+	return NewMnemonicFromEntropy(entropy)
+}
+
+// NewMnemonicFromEntropy makes the mnemonic of the entropy.
+func NewMnemonicFromEntropy(entropy []byte) (mnemonic string, err error) {
+	if len(entropy) < 16 || len(entropy) > 32 || len(entropy)%4 != 0 {
+		err = errInvalidMnemonicEntropy
+		return
+	}
+
+	bits := mnemonicBits(entropy)
+
+	words := make([]string, len(bits)/11)
+	for i := range words {
+		var index int
+		for _, bit := range bits[i*11 : (i+1)*11] {
+			index = index<<1 | int(bit)
+		}
+		words[i] = mnemonicWordList[index]
+	}
+
+	mnemonic = strings.Join(words, " ")
+
+	return
+}
+
+// MnemonicToEntropy returns the entropy of the mnemonic; the words must be in
+// the wordlist and the checksum must be correct.
+func MnemonicToEntropy(mnemonic string) (entropy []byte, err error) {
+	words := strings.Fields(mnemonic)
+	if len(words) < 12 || len(words) > 24 || len(words)%3 != 0 {
+		err = errInvalidMnemonicWords
+		return
+	}
+
+	var bits []byte
+	for _, word := range words {
+		index, found := mnemonicWordIndex[word]
+		if !found {
+			err = fmt.Errorf("word, '%s' is not in the wordlist", word)
+			return
+		}
+		for i := 10; i >= 0; i-- {
+			bits = append(bits, byte(index>>uint(i)&1))
+		}
+	}
+
+	entropy = make([]byte, len(words)/3*4)
+	for i := range entropy {
+		for _, bit := range bits[i*8 : (i+1)*8] {
+			entropy[i] = entropy[i]<<1 | bit
+		}
+	}
+
+	if string(mnemonicBits(entropy)) != string(bits) {
+		entropy = nil
+		err = errMnemonicChecksum
+		return
+	}
+
+	return
+}
+
+// MnemonicToSeed makes the seed of the mnemonic with the passphrase, which
+// can be empty; the mnemonic is checked by `MnemonicToEntropy()`.
+func MnemonicToSeed(mnemonic, passphrase string) (seed []byte, err error) {
+	if _, err = MnemonicToEntropy(mnemonic); err != nil {
+		return
+	}
+
+	seed = pbkdf2SHA512(
+		[]byte(strings.Join(strings.Fields(mnemonic), " ")),
+		[]byte("mnemonic"+passphrase),
+		MnemonicSeedIterations,
+		MnemonicSeedSize,
+	)
+
+	return
+}
+
+// mnemonicBits returns the bits of the entropy and it's checksum, one bit in
+// each byte.
+func mnemonicBits(entropy []byte) (bits []byte) {
+	checksum := sha256.Sum256(entropy)
+
+	b := make([]byte, len(entropy)+1)
+	copy(b, entropy)
+	b[len(entropy)] = checksum[0]
+
+	for _, b := range b {
+		for i := 7; i >= 0; i-- {
+			bits = append(bits, b>>uint(i)&1)
+		}
+	}
+
+	return bits[:len(entropy)*8+len(entropy)/4]
+}
+
+// pbkdf2SHA512 is PBKDF2 of RFC 8018 with HMAC-SHA512.
+func pbkdf2SHA512(password, salt []byte, iterations, size int) (key []byte) {
+	prf := hmac.New(sha512.New, password)
+
+	block := make([]byte, 4)
+	for i := uint32(1); len(key) < size; i++ {
+		prf.Reset()
+		prf.Write(salt)
+		binary.BigEndian.PutUint32(block, i)
+		prf.Write(block)
+		u := prf.Sum(nil)
+
+		t := make([]byte, len(u))
+		copy(t, u)
+		for n := 1; n < iterations; n++ {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+			for j := range t {
+				t[j] ^= u[j]
+			}
+		}
+		key = append(key, t...)
+	}
+
+	return key[:size]
+}
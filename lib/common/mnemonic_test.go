@@ -0,0 +1,108 @@
+package sebakcommon
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+// the test vectors of BIP39 with the passphrase, "TREZOR"
+func TestMnemonic(t *testing.T) {
+	vectors := []struct {
+		entropy  string
+		mnemonic string
+		seed     string
+	}{
+		{
+			"00000000000000000000000000000000",
+			"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about",
+			"c55257c360c07c72029aebc1b53c05ed0362ada38ead3e3e9efa3708e53495531f09a6987599d18264c1e1c92f2cf141630c7a3c4ab7c81b2f001698e7463b04",
+		},
+		{
+			"80808080808080808080808080808080",
+			"letter advice cage absurd amount doctor acoustic avoid letter advice cage above",
+			"d71de856f81a8acc65e6fc851a38d4d7ec216fd0796d0a6827a3ad6ed5511a30fa280f12eb2e47ed2ac03b5c462a0358d18d69fe4f985ec81778c1b370b652a8",
+		},
+		{
+			"ffffffffffffffffffffffffffffffff",
+			"zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo wrong",
+			"ac27495480225222079d7be181583751e86f571027b0497b5b5d11218e0a8a13332572917f0f8e5a589620c6f15b11c61dee327651a14c34e18231052e48c069",
+		},
+		{
+			"000000000000000000000000000000000000000000000000",
+			"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon agent",
+			"035895f2f481b1b0f01fcf8c289c794660b289981a78f8106447707fdd9666ca06da5a9a565181599b79f53b844d8a71dd9f439c52a3d7b3e8a79c906ac845fa",
+		},
+		{
+			"68a79eaca2324873eacc50cb9c6eca8cc68ea5d936f98787c60c7ebc74e6ce7c",
+			"hamster diagram private dutch cause delay private meat slide toddler razor book happy fancy gospel tennis maple dilemma loan word shrug inflict delay length",
+			"64c87cde7e12ecf6704ab95bb1408bef047c22db4cc7491c4271d170a1b213d20b385bc1588d9c7b38f1b39d415665b8a9030c9ec653d75e65f847d8fc1fc440",
+		},
+	}
+
+	for _, v := range vectors {
+		entropy, _ := hex.DecodeString(v.entropy)
+		mnemonic, err := NewMnemonicFromEntropy(entropy)
+		if err != nil || mnemonic != v.mnemonic {
+			t.Errorf("wrong mnemonic of '%s': %s, %v", v.entropy, mnemonic, err)
+			return
+		}
+
+		decoded, err := MnemonicToEntropy(mnemonic)
+		if err != nil || hex.EncodeToString(decoded) != v.entropy {
+			t.Errorf("wrong entropy of '%s': %x, %v", mnemonic, decoded, err)
+			return
+		}
+
+		seed, err := MnemonicToSeed(mnemonic, "TREZOR")
+		if err != nil || hex.EncodeToString(seed) != v.seed {
+			t.Errorf("wrong seed of '%s': %x, %v", mnemonic, seed, err)
+			return
+		}
+	}
+
+	// the mnemonic of SEP-0005 in `TestDeriveAccountKey`
+	seed, err := MnemonicToSeed("illness spike  retreat truth genius clock brain pass fit cave bargain toe\n", "")
+	if err != nil || hex.EncodeToString(seed) != "e4a5a632e70943ae7f07659df1332160937fad82587216a4c64315a0fb39497ee4a01f76ddab4cba68147977f3a147b6ad584c41808e8238a07f6cc4b582f186" {
+		t.Errorf("wrong seed: %x, %v", seed, err)
+		return
+	}
+}
+
+func TestMnemonicInvalid(t *testing.T) {
+	for _, mnemonic := range []string{
+		"",
+		"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon",   // checksum
+		"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon",           // 11 words
+		"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon sebakcoin", // not in wordlist
+		"zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo wrong",
+	} {
+		if _, err := MnemonicToSeed(mnemonic, ""); err == nil {
+			t.Errorf("invalid mnemonic must be refused: '%s'", mnemonic)
+			return
+		}
+	}
+
+	if _, err := NewMnemonicFromEntropy(make([]byte, 15)); err == nil {
+		t.Error("invalid entropy must be refused")
+		return
+	}
+	if _, err := NewMnemonic(13); err == nil {
+		t.Error("invalid number of words must be refused")
+		return
+	}
+}
+
+func TestNewMnemonic(t *testing.T) {
+	for _, words := range []int{12, 15, 18, 21, 24} {
+		mnemonic, err := NewMnemonic(words)
+		if err != nil || len(strings.Fields(mnemonic)) != words {
+			t.Errorf("wrong mnemonic of %d words: '%s', %v", words, mnemonic, err)
+			return
+		}
+		if _, err = MnemonicToEntropy(mnemonic); err != nil {
+			t.Error(err)
+			return
+		}
+	}
+}
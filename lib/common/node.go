@@ -96,6 +96,12 @@ func (v *Validator) Endpoint() *Endpoint {
 	return v.endpoint
 }
 
+// SetEndpoint sets the endpoint of the validator, which is given only by the
+// address and is found by the peer discovery.
+func (v *Validator) SetEndpoint(endpoint *Endpoint) {
+	v.endpoint = endpoint
+}
+
 func (v *Validator) HasValidators(address string) bool {
 	_, found := v.validators[address]
 	return found
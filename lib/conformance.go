@@ -0,0 +1,598 @@
+package sebak
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/btcsuite/btcutil/base58"
+	"github.com/stellar/go/keypair"
+
+	"boscoin.io/sebak/lib/common"
+	"boscoin.io/sebak/lib/error"
+)
+
+// The conformance suite is the black-box test battery against the API of the
+// running node; the third-party deployments and the forks run it by 'sebak
+// conformance' to check they are compatible with this version. The checks
+// are in the categories,
+//  * `api`: the responses of the basic endpoints are in the documented shape
+//  * `pagination`: the pages of the cursor are consistent with one page
+//  * `errors`: the errors are `sebakerror.Problem` with the stable results
+//  * `streaming`: the streams are the Server-Sent Events
+//  * `hashing`: the hashes of node are same with the hashes made here
+// The suite only reads the node, except the invalid transaction, which must be
+// refused, so it can run against the production node.
+
+type ConformanceCategory string
+
+const (
+	ConformanceCategoryAPI        ConformanceCategory = "api"
+	ConformanceCategoryPagination ConformanceCategory = "pagination"
+	ConformanceCategoryErrors     ConformanceCategory = "errors"
+	ConformanceCategoryStreaming  ConformanceCategory = "streaming"
+	ConformanceCategoryHashing    ConformanceCategory = "hashing"
+)
+
+// DefaultConformanceTimeout is the timeout of each request of the suite.
+const DefaultConformanceTimeout time.Duration = 10 * time.Second
+
+// conformancePages is the number of pages, which the pagination checks
+// follow.
+const conformancePages int = 5
+
+type ConformanceResult struct {
+	Name     string              `json:"name"`
+	Category ConformanceCategory `json:"category"`
+	Error    string              `json:"error,omitempty"`
+	Skipped  string              `json:"skipped,omitempty"` // the reason, why the check could not run
+	Elapsed  time.Duration       `json:"elapsed"`
+}
+
+func (r ConformanceResult) IsPassed() bool {
+	return len(r.Error) < 1
+}
+
+// ConformanceReport is the result of the suite against one node.
+type ConformanceReport struct {
+	Node    string              `json:"node"`
+	Version string              `json:"version"` // the version of the suite
+	Started string              `json:"started"`
+	Passed  int                 `json:"passed"`
+	Failed  int                 `json:"failed"`
+	Skipped int                 `json:"skipped"`
+	Results []ConformanceResult `json:"results"`
+}
+
+func (r ConformanceReport) IsPassed() bool {
+	return r.Failed < 1
+}
+
+// errConformanceSkipped is returned by the check, which can not run against
+// the node, like the pagination of the node without the enough transactions.
+type errConformanceSkipped struct {
+	reason string
+}
+
+func (e errConformanceSkipped) Error() string {
+	return e.reason
+}
+
+// ConformanceClient requests to the API of node; `base` is the URL of node
+// with the base path of API, like 'https://localhost:12345/sebak'.
+type ConformanceClient struct {
+	base   string
+	client *sebakcommon.HTTP2Client
+
+	node           *NodeResponse // the node info, which the checks share
+	genesis        *BlockResponse
+	genesisAccount string
+}
+
+func NewConformanceClient(base string, timeout time.Duration) (c *ConformanceClient, err error) {
+	var client *sebakcommon.HTTP2Client
+	if client, err = sebakcommon.NewHTTP2Client(timeout, timeout, false); err != nil {
+		return
+	}
+
+	c = &ConformanceClient{base: strings.TrimRight(base, "/"), client: client}
+
+	return
+}
+
+func (c *ConformanceClient) url(path string) string {
+	return c.base + APIVersion1.Prefix() + path
+}
+
+func (c *ConformanceClient) request(method, path string, body []byte) (response *http.Response, b []byte, err error) {
+	headers := http.Header{}
+	if method == "POST" {
+		headers.Set("Content-Type", "application/json")
+		response, err = c.client.Post(c.url(path), body, headers)
+	} else {
+		response, err = c.client.Get(c.url(path), headers)
+	}
+	if err != nil {
+		return
+	}
+	defer response.Body.Close()
+
+	b, err = ioutil.ReadAll(response.Body)
+
+	return
+}
+
+// getJSON requests the JSON response of `path`, which must be 200.
+func (c *ConformanceClient) getJSON(path string, v interface{}) (err error) {
+	var response *http.Response
+	var b []byte
+	if response, b, err = c.request("GET", path, nil); err != nil {
+		return
+	}
+	if response.StatusCode != http.StatusOK {
+		err = fmt.Errorf("'%s': status must be 200, not %d", path, response.StatusCode)
+		return
+	}
+	if err = checkConformanceContentType(response, "application/json"); err != nil {
+		return
+	}
+	if err = json.Unmarshal(b, v); err != nil {
+		err = fmt.Errorf("'%s': invalid response: %v", path, err)
+		return
+	}
+
+	return
+}
+
+// expectProblem requests `path` and checks the error response is
+// `sebakerror.Problem` of `status` and `result`.
+func (c *ConformanceClient) expectProblem(method, path string, body []byte, status int, result sebakerror.ResultCode) (p sebakerror.Problem, err error) {
+	var response *http.Response
+	var b []byte
+	if response, b, err = c.request(method, path, body); err != nil {
+		return
+	}
+	if response.StatusCode != status {
+		err = fmt.Errorf("'%s %s': status must be %d, not %d", method, path, status, response.StatusCode)
+		return
+	}
+	if err = checkConformanceContentType(response, sebakerror.ProblemContentType); err != nil {
+		return
+	}
+	if err = json.Unmarshal(b, &p); err != nil {
+		err = fmt.Errorf("'%s %s': invalid problem: %v", method, path, err)
+		return
+	}
+	if p.Status != status || p.Title != http.StatusText(status) {
+		err = fmt.Errorf("'%s %s': problem must be of status %d: %v", method, path, status, p)
+		return
+	}
+	if len(result) > 0 && p.Result != result {
+		err = fmt.Errorf("'%s %s': result must be '%s', not '%s'", method, path, result, p.Result)
+		return
+	}
+
+	return
+}
+
+// Node returns the node info, which is requested once.
+func (c *ConformanceClient) Node() (node NodeResponse, err error) {
+	if c.node != nil {
+		return *c.node, nil
+	}
+
+	if err = c.getJSON(GetNodePattern, &node); err != nil {
+		return
+	}
+	c.node = &node
+
+	return
+}
+
+// Genesis returns the first block.
+func (c *ConformanceClient) Genesis() (block BlockResponse, err error) {
+	if c.genesis != nil {
+		return *c.genesis, nil
+	}
+
+	if err = c.getJSON(GetBlocksPattern+"1", &block); err != nil {
+		return
+	}
+	c.genesis = &block
+
+	return
+}
+
+// GenesisAccount returns the target of the first operation in the genesis
+// block; the target is the account, which the genesis creates, but the source
+// of genesis transaction is not saved as account.
+func (c *ConformanceClient) GenesisAccount() (address string, err error) {
+	if len(c.genesisAccount) > 0 {
+		return c.genesisAccount, nil
+	}
+
+	var genesis BlockResponse
+	if genesis, err = c.Genesis(); err != nil {
+		return
+	}
+	if len(genesis.Transactions) < 1 || len(genesis.Transactions[0].Operations) < 1 {
+		err = errConformanceSkipped{"genesis block does not have the operation"}
+		return
+	}
+
+	// the operation is found by the hash of it and the transaction
+	tx := genesis.Transactions[0]
+	var op OperationResponse
+	if err = c.getJSON(GetOperationsPattern+tx.Operations[0]+"-"+tx.Hash, &op); err != nil {
+		return
+	}
+	if len(op.Target) < 1 {
+		err = errConformanceSkipped{"genesis operation does not have the target"}
+		return
+	}
+	c.genesisAccount = op.Target
+
+	return c.genesisAccount, nil
+}
+
+func checkConformanceContentType(response *http.Response, expected string) error {
+	mediaType, _, _ := mime.ParseMediaType(response.Header.Get("Content-Type"))
+	if mediaType != expected {
+		return fmt.Errorf(
+			"'%s': 'Content-Type' must be '%s', not '%s'",
+			response.Request.URL.Path,
+			expected,
+			response.Header.Get("Content-Type"),
+		)
+	}
+
+	return nil
+}
+
+type conformanceCheck struct {
+	name     string
+	category ConformanceCategory
+	f        func(*ConformanceClient) error
+}
+
+var conformanceChecks = []conformanceCheck{
+	{"node", ConformanceCategoryAPI, conformanceNode},
+	{"openapi", ConformanceCategoryAPI, conformanceOpenAPI},
+	{"genesis-block", ConformanceCategoryAPI, conformanceGenesisBlock},
+	{"block-header-only", ConformanceCategoryAPI, conformanceBlockHeaderOnly},
+	{"account-transactions", ConformanceCategoryPagination, conformanceAccountTransactions},
+	{"limit-bounds", ConformanceCategoryPagination, conformanceLimitBounds},
+	{"not-found", ConformanceCategoryErrors, conformanceNotFound},
+	{"method-not-allowed", ConformanceCategoryErrors, conformanceMethodNotAllowed},
+	{"bad-request", ConformanceCategoryErrors, conformanceBadRequest},
+	{"invalid-transaction", ConformanceCategoryErrors, conformanceInvalidTransaction},
+	{"stream-blocks", ConformanceCategoryStreaming, conformanceStreamBlocks},
+	{"hash-vectors", ConformanceCategoryHashing, conformanceHashVectors},
+	{"block-hashes", ConformanceCategoryHashing, conformanceBlockHashes},
+}
+
+// RunConformance runs every check of the suite against the node and reports
+// the result of each check; the failed check does not stop the others.
+func RunConformance(c *ConformanceClient) (report ConformanceReport) {
+	report = ConformanceReport{
+		Node:    c.base,
+		Version: Version,
+		Started: sebakcommon.NowISO8601(),
+	}
+
+	for _, check := range conformanceChecks {
+		started := time.Now()
+		result := ConformanceResult{Name: check.name, Category: check.category}
+		if err := check.f(c); err != nil {
+			if skipped, ok := err.(errConformanceSkipped); ok {
+				result.Skipped = skipped.reason
+			} else {
+				result.Error = err.Error()
+			}
+		}
+		result.Elapsed = time.Since(started)
+
+		switch {
+		case !result.IsPassed():
+			report.Failed++
+		case len(result.Skipped) > 0:
+			report.Skipped++
+		default:
+			report.Passed++
+		}
+		report.Results = append(report.Results, result)
+	}
+
+	return
+}
+
+func conformanceNode(c *ConformanceClient) (err error) {
+	var node NodeResponse
+	if node, err = c.Node(); err != nil {
+		return
+	}
+	if _, err = keypair.Parse(node.Address); err != nil {
+		return fmt.Errorf("'address' must be the address of node: %v", err)
+	}
+	if node.Height < 1 {
+		return errors.New("'height' must be the height of the latest block")
+	}
+	if len(node.State) < 1 {
+		return errors.New("'state' must be given")
+	}
+
+	return
+}
+
+func conformanceOpenAPI(c *ConformanceClient) (err error) {
+	var doc OpenAPIDocument
+	if err = c.getJSON(GetOpenAPIPattern, &doc); err != nil {
+		return
+	}
+	if len(doc.OpenAPI) < 1 {
+		return errors.New("'openapi' must be the version of OpenAPI")
+	}
+	for _, path := range []string{GetNodePattern, PostTransactionsPattern} {
+		if _, found := doc.Paths[path]; !found {
+			return fmt.Errorf("'paths' must have '%s'", path)
+		}
+	}
+
+	return
+}
+
+func conformanceGenesisBlock(c *ConformanceClient) (err error) {
+	var genesis BlockResponse
+	if genesis, err = c.Genesis(); err != nil {
+		return
+	}
+	if genesis.Height != 1 || len(genesis.PrevBlockHash) > 0 {
+		return fmt.Errorf("genesis block must be the first block: %v", genesis.BlockHeaderResponse)
+	}
+	if len(genesis.Transactions) != genesis.TransactionCount || len(genesis.Transactions) < 1 {
+		return fmt.Errorf("genesis block must have the genesis transaction: %v", genesis.BlockHeaderResponse)
+	}
+
+	var byHash BlockResponse
+	if err = c.getJSON(GetBlocksPattern+genesis.Hash, &byHash); err != nil {
+		return
+	}
+	if byHash.Hash != genesis.Hash || byHash.Height != genesis.Height {
+		return errors.New("block by hash must be same with the block by height")
+	}
+
+	return
+}
+
+func conformanceBlockHeaderOnly(c *ConformanceClient) (err error) {
+	var response *http.Response
+	var b []byte
+	if response, b, err = c.request("GET", GetBlocksPattern+"1?headerOnly=true", nil); err != nil {
+		return
+	}
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("status must be 200, not %d", response.StatusCode)
+	}
+
+	var fields map[string]interface{}
+	if err = json.Unmarshal(b, &fields); err != nil {
+		return
+	}
+	if _, found := fields["transactions"]; found {
+		return errors.New("header must not have 'transactions'")
+	}
+	if _, found := fields["transaction_count"]; !found {
+		return errors.New("header must have 'transaction_count'")
+	}
+
+	return
+}
+
+// conformanceAccountTransactionsPath returns the path of the transactions of
+// the genesis account; the check is skipped, not failed, when the node does
+// not know the account.
+func conformanceAccountTransactionsPath(c *ConformanceClient) (path string, err error) {
+	var address string
+	if address, err = c.GenesisAccount(); err != nil {
+		return
+	}
+	path = GetAccountsPattern + address + "/" + GetAccountTransactionsSubPattern
+
+	var response *http.Response
+	if response, _, err = c.request("GET", path, nil); err != nil {
+		return
+	}
+	if response.StatusCode == http.StatusNotFound {
+		err = errConformanceSkipped{fmt.Sprintf("genesis account, '%s' is not found", address)}
+		return
+	}
+
+	return
+}
+
+// conformanceAccountTransactions checks the transactions of the genesis
+// account paged one by one are same with them in one page.
+func conformanceAccountTransactions(c *ConformanceClient) (err error) {
+	var path string
+	if path, err = conformanceAccountTransactionsPath(c); err != nil {
+		return
+	}
+
+	var all AccountTransactionsResponse
+	if err = c.getJSON(fmt.Sprintf("%s?order=asc&limit=%d", path, conformancePages), &all); err != nil {
+		return
+	}
+	if all.Order != APIOrderAsc || len(all.Transactions) > conformancePages {
+		return fmt.Errorf("page must be in 'asc' order up to the 'limit': %d transactions", len(all.Transactions))
+	}
+	if len(all.Transactions) < 2 {
+		return errConformanceSkipped{"genesis account does not have the enough transactions"}
+	}
+
+	var cursor string
+	seen := map[string]bool{}
+	for i, expected := range all.Transactions {
+		q := "?order=asc&limit=1"
+		if len(cursor) > 0 {
+			q += "&cursor=" + cursor
+		}
+
+		var page AccountTransactionsResponse
+		if err = c.getJSON(path+q, &page); err != nil {
+			return
+		}
+		if len(page.Transactions) != 1 {
+			return fmt.Errorf("page %d must have one transaction by 'limit': %d", i, len(page.Transactions))
+		}
+		hash := page.Transactions[0].Hash
+		if seen[hash] {
+			return fmt.Errorf("page %d has the transaction of the previous page, '%s'", i, hash)
+		}
+		seen[hash] = true
+		if hash != expected.Hash {
+			return fmt.Errorf("page %d must have '%s', not '%s'", i, expected.Hash, hash)
+		}
+		if page.NextCursor != hash {
+			return fmt.Errorf("'next_cursor' of page %d must be the last transaction", i)
+		}
+		cursor = page.NextCursor
+	}
+
+	return
+}
+
+func conformanceLimitBounds(c *ConformanceClient) (err error) {
+	var path string
+	if path, err = conformanceAccountTransactionsPath(c); err != nil {
+		return
+	}
+
+	for _, limit := range []string{"0", "-1", "a"} {
+		if _, err = c.expectProblem("GET", path+"?limit="+limit, nil, http.StatusBadRequest, sebakerror.ResultBadRequest); err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+func conformanceNotFound(c *ConformanceClient) (err error) {
+	var node NodeResponse
+	if node, err = c.Node(); err != nil {
+		return
+	}
+
+	path := GetBlocksPattern + strconv.FormatUint(node.Height+1000000, 10)
+	_, err = c.expectProblem("GET", path, nil, http.StatusNotFound, sebakerror.ResultNotFound)
+
+	return
+}
+
+func conformanceMethodNotAllowed(c *ConformanceClient) (err error) {
+	_, err = c.expectProblem("POST", GetNodePattern, []byte("{}"), http.StatusMethodNotAllowed, sebakerror.ResultMethodNotAllowed)
+	return
+}
+
+func conformanceBadRequest(c *ConformanceClient) (err error) {
+	var path string
+	if path, err = conformanceAccountTransactionsPath(c); err != nil {
+		return
+	}
+
+	_, err = c.expectProblem("GET", path+"?order=random", nil, http.StatusBadRequest, sebakerror.ResultBadRequest)
+
+	return
+}
+
+// conformanceInvalidTransaction checks the transaction, which is not
+// well-formed is refused with the code of `sebakerror.Error`.
+func conformanceInvalidTransaction(c *ConformanceClient) (err error) {
+	var p sebakerror.Problem
+	if p, err = c.expectProblem("POST", PostTransactionsPattern, []byte("{}"), http.StatusBadRequest, ""); err != nil {
+		return
+	}
+	if p.Code < 1 || len(p.Result) < 1 {
+		return fmt.Errorf("problem must have 'code' and 'result' of error: %v", p)
+	}
+
+	return
+}
+
+// conformanceStreamBlocks checks the headers of the stream; the events are
+// not waited, because the new block may not come in the timeout.
+func conformanceStreamBlocks(c *ConformanceClient) (err error) {
+	var response *http.Response
+	if response, err = c.client.Get(c.url(GetStreamBlocksPattern), http.Header{}); err != nil {
+		return
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("status must be 200, not %d", response.StatusCode)
+	}
+	if err = checkConformanceContentType(response, "text/event-stream"); err != nil {
+		return
+	}
+	if v := response.Header.Get("Cache-Control"); v != "no-cache" {
+		return fmt.Errorf("'Cache-Control' must be 'no-cache', not '%s'", v)
+	}
+
+	return
+}
+
+// conformanceHashVectors checks the hashing of the suite itself; if it is
+// different, the block hashes can not be checked.
+func conformanceHashVectors(_ *ConformanceClient) (err error) {
+	return selfTestHashing(nil, nil, SelfTestModeFull)
+}
+
+// conformanceBlockHashes makes the hashes of the genesis and the latest
+// blocks from their contents, and checks the latest block is linked to the
+// previous block.
+func conformanceBlockHashes(c *ConformanceClient) (err error) {
+	var node NodeResponse
+	if node, err = c.Node(); err != nil {
+		return
+	}
+
+	heights := []uint64{1}
+	if node.Height > 1 {
+		heights = append(heights, node.Height-1, node.Height)
+	}
+
+	var prev BlockResponse
+	for _, height := range heights {
+		var block BlockResponse
+		if err = c.getJSON(GetBlocksPattern+strconv.FormatUint(height, 10), &block); err != nil {
+			return
+		}
+
+		b := Block{
+			Height:        block.Height,
+			PrevBlockHash: block.PrevBlockHash,
+			StateHash:     block.StateHash,
+			Confirmed:     block.Confirmed,
+		}
+		for _, tx := range block.Transactions {
+			b.Transactions = append(b.Transactions, tx.Hash)
+		}
+		if hash := b.MakeHashString(); hash != block.Hash {
+			return fmt.Errorf("hash of block %d must be '%s', not '%s'", height, hash, block.Hash)
+		}
+		if len(base58.Decode(block.Hash)) != 32 {
+			return fmt.Errorf("hash of block %d must be 32 bytes in base58", height)
+		}
+		if prev.Height > 0 && prev.Height+1 == block.Height && prev.Hash != block.PrevBlockHash {
+			return fmt.Errorf("block %d must be linked to the previous block", height)
+		}
+		prev = block
+	}
+
+	return
+}
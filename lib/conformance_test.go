@@ -0,0 +1,87 @@
+package sebak
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stellar/go/keypair"
+
+	"boscoin.io/sebak/lib/network"
+)
+
+func TestConformance(t *testing.T) {
+	defer sebaknetwork.CleanUpMemoryNetwork()
+
+	nr := createNodeRunners(1)[0]
+
+	kp, _ := keypair.Random()
+	target := testMakeBlockAccount()
+	target.Save(nr.Storage())
+
+	var hashes []string
+	for i := 0; i < 3; i++ {
+		bt := NewBlockTransactionFromTransaction(makeTransactionPayment(kp, target.Address, Amount(1)), nil)
+		if err := bt.Save(nr.Storage()); err != nil {
+			t.Fatal(err)
+		}
+		hashes = append(hashes, bt.Hash)
+	}
+
+	genesis := NewBlock(Block{}, "", hashes[0])
+	latest := NewBlock(genesis, "", hashes[1:]...)
+	for _, b := range []Block{genesis, latest} {
+		if err := b.Save(nr.Storage()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mux := http.NewServeMux()
+	for pattern, handler := range nr.APIHandlers() {
+		mux.HandleFunc(pattern, handler)
+	}
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	c, err := NewConformanceClient(server.URL, DefaultConformanceTimeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report := RunConformance(c)
+	for _, result := range report.Results {
+		if !result.IsPassed() {
+			t.Errorf("check, '%s' failed: %s", result.Name, result.Error)
+		}
+		if len(result.Skipped) > 0 {
+			t.Errorf("check, '%s' skipped: %s", result.Name, result.Skipped)
+		}
+	}
+	if report.Passed != len(conformanceChecks) {
+		t.Errorf("every check must pass: %d of %d", report.Passed, len(conformanceChecks))
+		return
+	}
+
+	// the block, which is not of it's hash
+	tampered := latest
+	tampered.StateHash = "tampered"
+	nr.Storage().Set(GetBlockKey(latest.Hash), tampered)
+
+	c, _ = NewConformanceClient(server.URL, DefaultConformanceTimeout)
+	if err = conformanceBlockHashes(c); err == nil {
+		t.Error("block of the wrong hash must fail")
+		return
+	}
+
+	// the node, which does not know the genesis account skips the checks of
+	// the account transactions
+	nr.Storage().Remove(GetBlockAccountKey(target.Address))
+
+	c, _ = NewConformanceClient(server.URL, DefaultConformanceTimeout)
+	for _, check := range []func(*ConformanceClient) error{conformanceAccountTransactions, conformanceLimitBounds, conformanceBadRequest} {
+		if _, ok := check(c).(errConformanceSkipped); !ok {
+			t.Error("check of the unknown genesis account must be skipped")
+			return
+		}
+	}
+}
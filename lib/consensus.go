@@ -6,6 +6,7 @@ import (
 
 type Consensus interface {
 	GetNode() sebakcommon.Node
+	Signer() NodeSigner
 	HasMessage(sebakcommon.Message) bool
 	HasMessageByHash(string) bool
 	ReceiveMessage(sebakcommon.Message) (Ballot, error)
@@ -0,0 +1,417 @@
+package sebaksimulator
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"boscoin.io/sebak/lib/common"
+	"boscoin.io/sebak/lib/network"
+)
+
+var errorPartitioned = errors.New("node is partitioned")
+
+// HubStats is the number of messages, which are sent through `Hub`.
+type HubStats struct {
+	Sent    uint64 `json:"sent"`
+	Dropped uint64 `json:"dropped"`
+}
+
+// Hub connects the simulated networks of nodes. The message between nodes is
+// delayed by the latency and jitter, and it can be dropped or blocked by the
+// partition. Whether the message is dropped and how long it is delayed are
+// decided by the seed and the order of message in the link of two nodes, so
+// the same seed produces the same faults without the real network.
+type Hub struct {
+	sync.RWMutex
+
+	seed     int64
+	latency  time.Duration
+	jitter   time.Duration
+	dropRate float64
+	groups   map[ /* endpoint */ string]int
+	networks map[ /* endpoint */ string]*Network
+	links    map[ /* from endpoint + to endpoint */ string]uint64
+
+	sent    uint64
+	dropped uint64
+}
+
+func NewHub(seed int64) *Hub {
+	return &Hub{
+		seed:     seed,
+		groups:   map[string]int{},
+		networks: map[string]*Network{},
+		links:    map[string]uint64{},
+	}
+}
+
+// NewNetwork creates the network of one node, which is connected to hub.
+func (h *Hub) NewNetwork(endpoint *sebakcommon.Endpoint) *Network {
+	h.Lock()
+	defer h.Unlock()
+
+	n := &Network{
+		hub:            h,
+		endpoint:       endpoint,
+		receiveChannel: make(chan sebaknetwork.Message),
+		stop:           make(chan bool),
+	}
+	h.networks[endpoint.String()] = n
+
+	return n
+}
+
+// SetLatency sets the delay of message; every message is delayed by `latency`
+// and up to `jitter` more.
+func (h *Hub) SetLatency(latency, jitter time.Duration) {
+	h.Lock()
+	defer h.Unlock()
+
+	h.latency = latency
+	h.jitter = jitter
+}
+
+// SetDropRate sets the ratio of the messages to be dropped, between 0 and 1.
+// The handshake of `Connect` and `GetNodeInfo` is not dropped.
+func (h *Hub) SetDropRate(rate float64) {
+	h.Lock()
+	defer h.Unlock()
+
+	h.dropRate = rate
+}
+
+// Partition splits the nodes; the nodes in the different groups can not reach
+// each other. The nodes, which are not in any group are in the same group.
+func (h *Hub) Partition(groups ...[]*sebakcommon.Endpoint) {
+	h.Lock()
+	defer h.Unlock()
+
+	h.groups = map[string]int{}
+	for i, group := range groups {
+		for _, endpoint := range group {
+			h.groups[endpoint.String()] = i + 1
+		}
+	}
+}
+
+// Heal removes the partition.
+func (h *Hub) Heal() {
+	h.Partition()
+}
+
+func (h *Hub) Stats() HubStats {
+	return HubStats{
+		Sent:    atomic.LoadUint64(&h.sent),
+		Dropped: atomic.LoadUint64(&h.dropped),
+	}
+}
+
+func (h *Hub) getNetwork(endpoint *sebakcommon.Endpoint) (n *Network, err error) {
+	var ok bool
+	if n, ok = h.networks[endpoint.String()]; !ok {
+		err = fmt.Errorf("unknown endpoint, '%s'", endpoint)
+		return
+	}
+
+	return
+}
+
+// reach checks the partition between nodes; it must be called with the lock.
+func (h *Hub) reach(from, to *sebakcommon.Endpoint) (err error) {
+	if h.groups[from.String()] != h.groups[to.String()] {
+		err = errorPartitioned
+		return
+	}
+
+	return
+}
+
+// fault decides whether the next message of the link is dropped and how long
+// it is delayed.
+func (h *Hub) fault(from, to *sebakcommon.Endpoint) (drop bool, delay time.Duration) {
+	h.Lock()
+	defer h.Unlock()
+
+	link := from.String() + to.String()
+	n := h.links[link]
+	h.links[link] = n + 1
+
+	b := make([]byte, 16)
+	binary.BigEndian.PutUint64(b, uint64(h.seed))
+	binary.BigEndian.PutUint64(b[8:], n)
+	sum := sha256.Sum256(append(b, []byte(link)...))
+
+	unit := func(b []byte) float64 {
+		return float64(binary.BigEndian.Uint64(b)>>11) / float64(1<<53)
+	}
+
+	drop = unit(sum[:8]) < h.dropRate
+	delay = h.latency + time.Duration(unit(sum[8:16])*float64(h.jitter))
+
+	return
+}
+
+// send delivers the messages from `from` to `to` after the delay. If the nodes
+// are partitioned, the error is returned like the failed connection. The
+// dropped message does not return error.
+func (h *Hub) send(from, to *sebakcommon.Endpoint, messages ...sebaknetwork.Message) (err error) {
+	h.RLock()
+	var target *Network
+	if target, err = h.getNetwork(to); err == nil {
+		err = h.reach(from, to)
+	}
+	h.RUnlock()
+	if err != nil {
+		atomic.AddUint64(&h.dropped, uint64(len(messages)))
+		return
+	}
+
+	drop, delay := h.fault(from, to)
+	if drop {
+		atomic.AddUint64(&h.dropped, uint64(len(messages)))
+		return
+	}
+	atomic.AddUint64(&h.sent, uint64(len(messages)))
+
+	go func() {
+		time.Sleep(delay)
+		for _, message := range messages {
+			target.receive(message)
+		}
+	}()
+
+	return
+}
+
+func (h *Hub) nodeInfo(from, to *sebakcommon.Endpoint) (b []byte, err error) {
+	h.RLock()
+	defer h.RUnlock()
+
+	var target *Network
+	if target, err = h.getNetwork(to); err != nil {
+		return
+	}
+	if err = h.reach(from, to); err != nil {
+		return
+	}
+
+	currentNode, ok := target.Context().Value("currentNode").(sebakcommon.Serializable)
+	if !ok {
+		err = errors.New("node is not ready")
+		return
+	}
+
+	return currentNode.Serialize()
+}
+
+// transactions fetches the transactions from the node like the fetch of the
+// announced transactions; it is not delayed, but fails in the partition.
+func (h *Hub) transactions(from, to *sebakcommon.Endpoint, hashes []string) (b []byte, err error) {
+	h.RLock()
+	defer h.RUnlock()
+
+	var target *Network
+	if target, err = h.getNetwork(to); err != nil {
+		return
+	}
+	if err = h.reach(from, to); err != nil {
+		return
+	}
+
+	fetch, ok := target.Context().Value("transactionFetch").(sebaknetwork.TransactionFetchFunc)
+	if !ok {
+		err = errors.New("node is not ready")
+		return
+	}
+
+	return fetch(hashes)
+}
+
+// blocks fetches the blocks from the node for the block sync; like
+// `transactions`, it is not delayed, but fails in the partition.
+func (h *Hub) blocks(from, to *sebakcommon.Endpoint, fromHeight, toHeight uint64) (b []byte, err error) {
+	h.RLock()
+	defer h.RUnlock()
+
+	var target *Network
+	if target, err = h.getNetwork(to); err != nil {
+		return
+	}
+	if err = h.reach(from, to); err != nil {
+		return
+	}
+
+	fetch, ok := target.Context().Value("blockFetch").(sebaknetwork.BlockFetchFunc)
+	if !ok {
+		err = errors.New("node is not ready")
+		return
+	}
+
+	return fetch(fromHeight, toHeight)
+}
+
+// Network is the simulated `sebaknetwork.Network` of one node.
+type Network struct {
+	sync.RWMutex
+
+	hub            *Hub
+	ctx            context.Context
+	endpoint       *sebakcommon.Endpoint
+	receiveChannel chan sebaknetwork.Message
+	stop           chan bool
+	stopOnce       sync.Once
+}
+
+func (n *Network) Endpoint() *sebakcommon.Endpoint {
+	return n.endpoint
+}
+
+func (n *Network) Context() context.Context {
+	n.RLock()
+	defer n.RUnlock()
+
+	if n.ctx == nil {
+		return context.Background()
+	}
+
+	return n.ctx
+}
+
+func (n *Network) SetContext(ctx context.Context) {
+	n.Lock()
+	defer n.Unlock()
+
+	n.ctx = ctx
+}
+
+func (n *Network) GetClient(endpoint *sebakcommon.Endpoint) sebaknetwork.NetworkClient {
+	return &Client{hub: n.hub, from: n.endpoint, endpoint: endpoint}
+}
+
+func (n *Network) AddWatcher(f func(sebaknetwork.Network, net.Conn, http.ConnState)) {
+	return
+}
+
+// Start blocks until the network is stopped like the other networks.
+func (n *Network) Start() error {
+	<-n.stop
+
+	return nil
+}
+
+func (n *Network) Stop() {
+	n.stopOnce.Do(func() {
+		close(n.stop)
+	})
+}
+
+func (n *Network) Ready() error {
+	return nil
+}
+
+func (n *Network) IsReady() bool {
+	return true
+}
+
+func (n *Network) ReceiveChannel() chan sebaknetwork.Message {
+	return n.receiveChannel
+}
+
+func (n *Network) ReceiveMessage() <-chan sebaknetwork.Message {
+	return n.receiveChannel
+}
+
+// receive passes the message to node; after the network is stopped, the
+// message is discarded.
+func (n *Network) receive(message sebaknetwork.Message) {
+	select {
+	case <-n.stop:
+	case n.receiveChannel <- message:
+	}
+}
+
+// Client is the simulated `sebaknetwork.NetworkClient`, which sends the
+// messages through `Hub`.
+type Client struct {
+	hub      *Hub
+	from     *sebakcommon.Endpoint
+	endpoint *sebakcommon.Endpoint
+}
+
+func (c *Client) Endpoint() *sebakcommon.Endpoint {
+	return c.endpoint
+}
+
+func (c *Client) Connect(node sebakcommon.Node) ([]byte, error) {
+	return c.hub.nodeInfo(c.from, c.endpoint)
+}
+
+func (c *Client) GetNodeInfo() ([]byte, error) {
+	return c.hub.nodeInfo(c.from, c.endpoint)
+}
+
+// GetPeers is not simulated; the nodes of simulator know all the validators.
+func (c *Client) GetPeers() ([]byte, error) {
+	return nil, errors.New("peer exchange is not simulated")
+}
+
+func (c *Client) send(messageType sebaknetwork.MessageType, message sebakcommon.Serializable) (err error) {
+	var b []byte
+	if b, err = message.Serialize(); err != nil {
+		return
+	}
+
+	return c.hub.send(c.from, c.endpoint, sebaknetwork.NewMessage(messageType, b))
+}
+
+func (c *Client) SendMessage(message sebakcommon.Serializable) error {
+	return c.send(sebaknetwork.MessageFromClient, message)
+}
+
+func (c *Client) SendBallot(message sebakcommon.Serializable) error {
+	return c.send(sebaknetwork.BallotMessage, message)
+}
+
+// SendBallots sends the ballots of `sebaknetwork.BallotBatch` together; they
+// are dropped or delayed together.
+func (c *Client) SendBallots(message sebakcommon.Serializable) (err error) {
+	var b []byte
+	if b, err = message.Serialize(); err != nil {
+		return
+	}
+
+	var batch sebaknetwork.BallotBatch
+	if batch, err = sebaknetwork.NewBallotBatchFromBytes(b); err != nil {
+		return
+	}
+
+	return c.hub.send(c.from, c.endpoint, batch.Messages()...)
+}
+
+func (c *Client) SendViewChange(message sebakcommon.Serializable) error {
+	return c.send(sebaknetwork.ViewChangeMessage, message)
+}
+
+func (c *Client) SendBlockAnnouncement(message sebakcommon.Serializable) error {
+	return c.send(sebaknetwork.BlockAnnouncementMessage, message)
+}
+
+func (c *Client) SendTransactionInventory(message sebakcommon.Serializable) error {
+	return c.send(sebaknetwork.TransactionInventoryMessage, message)
+}
+
+func (c *Client) GetTransactions(hashes []string) ([]byte, error) {
+	return c.hub.transactions(c.from, c.endpoint, hashes)
+}
+
+func (c *Client) GetBlocks(from, to uint64) ([]byte, error) {
+	return c.hub.blocks(c.from, c.endpoint, from, to)
+}
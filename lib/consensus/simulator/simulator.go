@@ -0,0 +1,347 @@
+package sebaksimulator
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/stellar/go/keypair"
+
+	"boscoin.io/sebak/lib"
+	"boscoin.io/sebak/lib/common"
+	"boscoin.io/sebak/lib/storage"
+)
+
+// Config is the simulated network. `Seed` decides the keypairs of nodes and
+// the faults of `Hub`, so the run can be reproduced by it's seed.
+type Config struct {
+	NetworkID string
+	Nodes     int
+	Seed      int64
+
+	Latency  time.Duration
+	Jitter   time.Duration
+	DropRate float64
+
+	// Parameters is the network parameters of genesis; if it is empty, the
+	// default parameters are used.
+	Parameters sebak.NetworkParameters
+	// Accounts is the genesis accounts, which every node has.
+	Accounts []sebak.GenesisAccount
+}
+
+func (c Config) IsWellFormed() (err error) {
+	if len(c.NetworkID) < 1 {
+		err = errors.New("network id must be given")
+		return
+	}
+	if c.Nodes < 1 {
+		err = errors.New("at least one node must be given")
+		return
+	}
+	if c.Latency < 0 || c.Jitter < 0 {
+		err = errors.New("latency and jitter must not be negative")
+		return
+	}
+	if c.DropRate < 0 || c.DropRate > 1 {
+		err = errors.New("drop rate must be between 0 and 1")
+		return
+	}
+
+	return
+}
+
+// Simulator runs the nodes in memory with the simulated network, so the
+// consensus can be tested under the latency, partitions and message drops
+// without the real servers.
+type Simulator struct {
+	config Config
+	hub    *Hub
+	nodes  []*sebak.NodeRunner
+}
+
+func New(config Config) (s *Simulator, err error) {
+	if err = config.IsWellFormed(); err != nil {
+		return
+	}
+
+	if config.Parameters.BlockTime == 0 {
+		config.Parameters = sebak.NewDefaultNetworkParameters()
+	}
+
+	hub := NewHub(config.Seed)
+	hub.SetLatency(config.Latency, config.Jitter)
+	hub.SetDropRate(config.DropRate)
+
+	var validators []*sebakcommon.Validator
+	for i := 0; i < config.Nodes; i++ {
+		var kp *keypair.Full
+		if kp, err = keypair.FromRawSeed(sha256.Sum256([]byte(fmt.Sprintf("%d-%d", config.Seed, i)))); err != nil {
+			return
+		}
+
+		endpoint := &sebakcommon.Endpoint{Scheme: "simulator", Host: fmt.Sprintf("node%d", i)}
+
+		var v *sebakcommon.Validator
+		if v, err = sebakcommon.NewValidator(kp.Address(), endpoint, fmt.Sprintf("node%d", i)); err != nil {
+			return
+		}
+		v.SetKeypair(kp)
+		validators = append(validators, v)
+	}
+
+	for _, v := range validators {
+		for _, other := range validators {
+			if other.Address() != v.Address() {
+				v.AddValidators(other)
+			}
+		}
+	}
+
+	s = &Simulator{config: config, hub: hub}
+	for _, v := range validators {
+		var nr *sebak.NodeRunner
+		if nr, err = s.newNodeRunner(v); err != nil {
+			return
+		}
+		s.nodes = append(s.nodes, nr)
+	}
+
+	return
+}
+
+func (s *Simulator) newNodeRunner(v *sebakcommon.Validator) (nr *sebak.NodeRunner, err error) {
+	var config *sebakstorage.Config
+	if config, err = sebakstorage.NewConfigFromString("memory://"); err != nil {
+		return
+	}
+
+	var st *sebakstorage.LevelDBBackend
+	if st, err = sebakstorage.NewStorage(config); err != nil {
+		return
+	}
+
+	p := s.config.Parameters
+	if len(s.config.Accounts) > 0 {
+		genesis := sebak.NewGenesis(s.config.NetworkID)
+		genesis.Accounts = s.config.Accounts
+		genesis.Consensus = sebak.GenesisConsensus{
+			BlockTime:       p.BlockTime.String(),
+			ThresholdINIT:   p.ThresholdINIT,
+			ThresholdSIGN:   p.ThresholdSIGN,
+			ThresholdACCEPT: p.ThresholdACCEPT,
+		}
+		if err = genesis.Apply(st); err != nil {
+			return
+		}
+	}
+
+	var policy *sebak.ISAACVotingThresholdPolicy
+	if policy, err = p.VotingThresholdPolicy(); err != nil {
+		return
+	}
+	policy.SetValidators(s.config.Nodes)
+
+	var isaac *sebak.ISAAC
+	if isaac, err = sebak.NewISAAC([]byte(s.config.NetworkID), v, policy); err != nil {
+		return
+	}
+
+	network := s.hub.NewNetwork(v.Endpoint())
+	nr = sebak.NewNodeRunner(s.config.NetworkID, v, policy, network, isaac, st)
+	nr.SetNetworkParameters(p)
+
+	return
+}
+
+// Nodes returns the nodes in the order of index.
+func (s *Simulator) Nodes() []*sebak.NodeRunner {
+	return s.nodes
+}
+
+func (s *Simulator) Node(i int) *sebak.NodeRunner {
+	return s.nodes[i]
+}
+
+func (s *Simulator) Hub() *Hub {
+	return s.hub
+}
+
+// Start starts the nodes and waits until every node is connected to the other
+// nodes.
+func (s *Simulator) Start(timeout time.Duration) (err error) {
+	for _, nr := range s.nodes {
+		go nr.Start()
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		connected := true
+		for _, nr := range s.nodes {
+			if nr.ConnectionManager().CountConnected() < len(s.nodes)-1 {
+				connected = false
+				break
+			}
+		}
+		if connected {
+			return
+		}
+		if time.Now().After(deadline) {
+			err = errors.New("nodes are not connected in time")
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func (s *Simulator) Stop() {
+	for _, nr := range s.nodes {
+		nr.Stop()
+	}
+}
+
+func (s *Simulator) endpoints(nodes []int) (endpoints []*sebakcommon.Endpoint) {
+	for _, i := range nodes {
+		endpoints = append(endpoints, s.nodes[i].Node().Endpoint())
+	}
+
+	return
+}
+
+// Partition splits the nodes by their indices; see `Hub.Partition()`.
+func (s *Simulator) Partition(groups ...[]int) {
+	var endpoints [][]*sebakcommon.Endpoint
+	for _, group := range groups {
+		endpoints = append(endpoints, s.endpoints(group))
+	}
+
+	s.hub.Partition(endpoints...)
+}
+
+func (s *Simulator) Heal() {
+	s.hub.Heal()
+}
+
+// SubmitTransaction sends the transaction to the node like the client.
+func (s *Simulator) SubmitTransaction(i int, tx sebak.Transaction) error {
+	nr := s.nodes[i]
+	return nr.Network().GetClient(nr.Node().Endpoint()).SendMessage(tx)
+}
+
+// Heights returns the latest block height of each node.
+func (s *Simulator) Heights() (heights []uint64, err error) {
+	for _, nr := range s.nodes {
+		var latest sebak.Block
+		if latest, err = sebak.GetLatestBlock(nr.Storage()); err != nil {
+			return
+		}
+		heights = append(heights, latest.Height)
+	}
+
+	return
+}
+
+// WaitHeightMaxExtension limits how long `WaitHeight()` waits while the
+// nodes make progress; at most `timeout` times it.
+const WaitHeightMaxExtension = 10
+
+// WaitHeight waits until the nodes reach `height`; without `nodes`, every node
+// must reach it. `timeout` is the time without progress, the new height of
+// the nodes or the messages between them, so the busy machine, which runs
+// the consensus slowly does not fail; the whole wait is limited by
+// `WaitHeightMaxExtension`.
+func (s *Simulator) WaitHeight(height uint64, timeout time.Duration, nodes ...int) (err error) {
+	if len(nodes) < 1 {
+		for i := range s.nodes {
+			nodes = append(nodes, i)
+		}
+	}
+
+	limit := time.Now().Add(timeout * WaitHeightMaxExtension)
+	deadline := time.Now().Add(timeout)
+
+	var lastHeights []uint64
+	var lastSent uint64
+	for {
+		var heights []uint64
+		if heights, err = s.Heights(); err != nil {
+			return
+		}
+
+		reached := true
+		for _, i := range nodes {
+			if heights[i] < height {
+				reached = false
+				break
+			}
+		}
+		if reached {
+			return
+		}
+
+		if sent := s.hub.Stats().Sent; sent != lastSent || !isSameHeights(heights, lastHeights) {
+			lastSent, lastHeights = sent, heights
+			deadline = time.Now().Add(timeout)
+		}
+		if now := time.Now(); now.After(deadline) || now.After(limit) {
+			err = fmt.Errorf("nodes did not reach height %d in time: %v", height, heights)
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func isSameHeights(a, b []uint64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// CheckConsistency compares the blocks of the nodes until the lowest height of
+// them by `sebak.IsBlockConflicted()`.
+func (s *Simulator) CheckConsistency(nodes ...int) (err error) {
+	if len(nodes) < 1 {
+		for i := range s.nodes {
+			nodes = append(nodes, i)
+		}
+	}
+
+	var heights []uint64
+	if heights, err = s.Heights(); err != nil {
+		return
+	}
+
+	lowest := heights[nodes[0]]
+	for _, i := range nodes {
+		if heights[i] < lowest {
+			lowest = heights[i]
+		}
+	}
+
+	for height := uint64(1); height <= lowest; height++ {
+		var first sebak.Block
+		if first, err = sebak.GetBlockByHeight(s.nodes[nodes[0]].Storage(), height); err != nil {
+			return
+		}
+		for _, i := range nodes[1:] {
+			var block sebak.Block
+			if block, err = sebak.GetBlockByHeight(s.nodes[i].Storage(), height); err != nil {
+				return
+			}
+			if sebak.IsBlockConflicted(first, block) {
+				err = fmt.Errorf("block of height %d conflicts between node%d and node%d", height, nodes[0], i)
+				return
+			}
+		}
+	}
+
+	return
+}
@@ -0,0 +1,192 @@
+package sebaksimulator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stellar/go/keypair"
+
+	"boscoin.io/sebak/lib"
+	"boscoin.io/sebak/lib/common"
+)
+
+const networkID string = "sebak-simulator-test"
+
+func makeCreateAccount(kp *keypair.Full, checkpoint string) (tx sebak.Transaction, target *keypair.Full) {
+	target, _ = keypair.Random()
+
+	op := sebak.Operation{
+		H: sebak.OperationHeader{Type: sebak.OperationCreateAccount},
+		B: sebak.NewOperationBodyCreateAccount(target.Address(), sebak.Amount(1)),
+	}
+	tx, _ = sebak.NewTransaction(kp.Address(), checkpoint, op)
+	tx.Sign(kp, []byte(networkID))
+
+	return
+}
+
+func newSimulator(t *testing.T, nodes int, parameters sebak.NetworkParameters) (s *Simulator, kp *keypair.Full) {
+	kp, _ = keypair.Random()
+
+	var err error
+	s, err = New(Config{
+		NetworkID:  networkID,
+		Nodes:      nodes,
+		Seed:       1,
+		Latency:    2 * time.Millisecond,
+		Jitter:     3 * time.Millisecond,
+		Parameters: parameters,
+		Accounts: []sebak.GenesisAccount{
+			{Address: kp.Address(), Balance: sebak.Amount(1000000000000)},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = s.Start(5 * time.Second); err != nil {
+		s.Stop()
+		t.Fatal(err)
+	}
+
+	return
+}
+
+func TestSimulatorConfig(t *testing.T) {
+	for _, config := range []Config{
+		{Nodes: 4},
+		{NetworkID: networkID},
+		{NetworkID: networkID, Nodes: 4, Latency: -1},
+		{NetworkID: networkID, Nodes: 4, DropRate: 1.5},
+	} {
+		if _, err := New(config); err == nil {
+			t.Errorf("invalid config must be refused: %v", config)
+			return
+		}
+	}
+}
+
+func TestSimulatorCommit(t *testing.T) {
+	s, kp := newSimulator(t, 4, sebak.NetworkParameters{})
+	defer s.Stop()
+
+	tx, target := makeCreateAccount(kp, sebak.MakeGenesisCheckpoint(networkID, kp.Address()))
+	if err := s.SubmitTransaction(0, tx); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err := s.WaitHeight(1, 10*time.Second); err != nil {
+		t.Error(err)
+		return
+	}
+	if err := s.CheckConsistency(); err != nil {
+		t.Error(err)
+		return
+	}
+
+	for i, nr := range s.Nodes() {
+		account, err := sebak.GetBlockAccount(nr.Storage(), target.Address())
+		if err != nil {
+			t.Errorf("account is not created in node%d: %v", i, err)
+			return
+		}
+		if account.GetBalance() != sebak.Amount(1) {
+			t.Errorf("unexpected balance in node%d: %v", i, account.GetBalance())
+			return
+		}
+	}
+
+	if stats := s.Hub().Stats(); stats.Sent < 1 || stats.Dropped != 0 {
+		t.Errorf("unexpected stats: %v", stats)
+		return
+	}
+}
+
+// TestSimulatorPartition checks the isolated node does not commit the block,
+// which the majority commits.
+func TestSimulatorPartition(t *testing.T) {
+	parameters := sebak.NewDefaultNetworkParameters()
+	parameters.ThresholdINIT = 67
+	parameters.ThresholdSIGN = 67
+	parameters.ThresholdACCEPT = 67
+
+	s, kp := newSimulator(t, 4, parameters)
+	defer s.Stop()
+
+	s.Partition([]int{3})
+
+	tx, _ := makeCreateAccount(kp, sebak.MakeGenesisCheckpoint(networkID, kp.Address()))
+	if err := s.SubmitTransaction(0, tx); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err := s.WaitHeight(1, 10*time.Second, 0, 1, 2); err != nil {
+		t.Error(err)
+		return
+	}
+	if err := s.CheckConsistency(0, 1, 2); err != nil {
+		t.Error(err)
+		return
+	}
+
+	heights, _ := s.Heights()
+	if heights[3] != 0 {
+		t.Errorf("isolated node must not commit the block: %v", heights)
+		return
+	}
+	if stats := s.Hub().Stats(); stats.Dropped < 1 {
+		t.Errorf("messages to isolated node must be dropped: %v", stats)
+		return
+	}
+}
+
+func TestHubFault(t *testing.T) {
+	a := &sebakcommon.Endpoint{Scheme: "simulator", Host: "a"}
+	b := &sebakcommon.Endpoint{Scheme: "simulator", Host: "b"}
+
+	faults := func(seed int64) (drops []bool, delays []time.Duration) {
+		h := NewHub(seed)
+		h.SetLatency(time.Millisecond, 10*time.Millisecond)
+		h.SetDropRate(0.5)
+		for i := 0; i < 20; i++ {
+			drop, delay := h.fault(a, b)
+			drops = append(drops, drop)
+			delays = append(delays, delay)
+		}
+
+		return
+	}
+
+	drops0, delays0 := faults(1)
+	drops1, delays1 := faults(1)
+	for i := range drops0 {
+		if drops0[i] != drops1[i] || delays0[i] != delays1[i] {
+			t.Error("same seed must produce same faults")
+			return
+		}
+		if delays0[i] < time.Millisecond || delays0[i] > 11*time.Millisecond {
+			t.Errorf("delay is out of latency and jitter: %s", delays0[i])
+			return
+		}
+	}
+
+	var dropped int
+	drops2, _ := faults(2)
+	var different bool
+	for i := range drops0 {
+		if drops0[i] {
+			dropped++
+		}
+		different = different || drops0[i] != drops2[i]
+	}
+	if dropped == 0 || dropped == len(drops0) {
+		t.Errorf("half of messages must be dropped: %d", dropped)
+		return
+	}
+	if !different {
+		t.Error("different seed must produce different faults")
+		return
+	}
+}
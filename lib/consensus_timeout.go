@@ -0,0 +1,73 @@
+package sebak
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// DefaultAdaptiveTimeoutFactor is the number of the round trips of ballot in
+// the adaptive timeout; one block needs the round trips of 'INIT', 'SIGN' and
+// 'ACCEPT', and they are doubled for the margin.
+const DefaultAdaptiveTimeoutFactor int = 6
+
+// AdaptiveTimeout adapts the consensus timeout to the observed latencies of
+// the validators within `Min` and `Max`; on the fast network the timeout is
+// shortened, and on the degraded network it is stretched up to `Max`.
+type AdaptiveTimeout struct {
+	Min    time.Duration
+	Max    time.Duration
+	Factor int
+}
+
+func NewAdaptiveTimeout(min, max time.Duration) (a AdaptiveTimeout, err error) {
+	if min <= 0 {
+		err = fmt.Errorf("minimum timeout must be greater than 0")
+		return
+	}
+	if max < min {
+		err = fmt.Errorf("maximum timeout must be greater than or equal to the minimum, %s", min)
+		return
+	}
+
+	a = AdaptiveTimeout{Min: min, Max: max, Factor: DefaultAdaptiveTimeoutFactor}
+
+	return
+}
+
+// QuorumLatency returns the latency, in which the ballot reaches the quorum of
+// validators; the node itself is one of the quorum, so it is the latency of
+// the `quorum - 1`th fastest validator. If the latencies are not enough for
+// the quorum, it returns `false`.
+func QuorumLatency(latencies []time.Duration, quorum int) (time.Duration, bool) {
+	if quorum < 2 {
+		return 0, true
+	}
+	if len(latencies) < quorum-1 {
+		return 0, false
+	}
+
+	sorted := make([]time.Duration, len(latencies))
+	copy(sorted, latencies)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	return sorted[quorum-2], true
+}
+
+// Timeout returns the timeout for the latencies of validators; until the
+// latencies of the quorum are observed, it is `Max`.
+func (a AdaptiveTimeout) Timeout(latencies []time.Duration, quorum int) time.Duration {
+	latency, ok := QuorumLatency(latencies, quorum)
+	if !ok {
+		return a.Max
+	}
+
+	timeout := latency * time.Duration(a.Factor)
+	if timeout < a.Min {
+		return a.Min
+	} else if timeout > a.Max {
+		return a.Max
+	}
+
+	return timeout
+}
@@ -0,0 +1,71 @@
+package sebak
+
+import (
+	"testing"
+	"time"
+
+	"boscoin.io/sebak/lib/network"
+)
+
+func TestAdaptiveTimeout(t *testing.T) {
+	if _, err := NewAdaptiveTimeout(0, time.Second); err == nil {
+		t.Error("minimum timeout 0 must be refused")
+		return
+	}
+	if _, err := NewAdaptiveTimeout(time.Second, time.Millisecond); err == nil {
+		t.Error("maximum timeout under the minimum must be refused")
+		return
+	}
+
+	a, _ := NewAdaptiveTimeout(100*time.Millisecond, 5*time.Second)
+	latencies := []time.Duration{300 * time.Millisecond, 10 * time.Millisecond, 50 * time.Millisecond}
+
+	cases := []struct {
+		latencies []time.Duration
+		quorum    int
+		expected  time.Duration
+	}{
+		{latencies, 3, 50 * time.Millisecond * time.Duration(a.Factor)}, // fast network
+		{latencies, 2, a.Min}, // shorter than the minimum
+		{latencies, 4, 300 * time.Millisecond * time.Duration(a.Factor)}, // the slow validator is needed for quorum
+		{latencies[:1], 3, a.Max},                                     // not enough latencies
+		{[]time.Duration{2 * time.Second, 3 * time.Second}, 3, a.Max}, // degraded network
+	}
+	for _, c := range cases {
+		if timeout := a.Timeout(c.latencies, c.quorum); timeout != c.expected {
+			t.Errorf("wrong timeout for quorum %d: %s != %s", c.quorum, timeout, c.expected)
+			return
+		}
+	}
+}
+
+func TestNodeRunnerAdaptiveProposerTimeout(t *testing.T) {
+	defer sebaknetwork.CleanUpMemoryNetwork()
+
+	nodeRunners := createNodeRunners(3)
+	nr := nodeRunners[0]
+
+	if err := nr.SetAdaptiveProposerTimeout(time.Second); err == nil {
+		t.Error("adaptive timeout without proposer timeout must be refused")
+		return
+	}
+
+	nr.SetProposerTimeout(5 * time.Second)
+	if err := nr.SetAdaptiveProposerTimeout(time.Second); err != nil {
+		t.Error(err)
+		return
+	}
+
+	// the threshold of test policy is 30%, so the node itself is the quorum
+	// and does not wait the other validators
+	if timeout := nr.ProposerTimeout(); timeout != time.Second {
+		t.Errorf("timeout must be the minimum: %s", timeout)
+		return
+	}
+
+	nr.Policy().SetValidators(10)
+	if timeout := nr.ProposerTimeout(); timeout != 5*time.Second {
+		t.Errorf("timeout must be the maximum without the latencies of quorum: %s", timeout)
+		return
+	}
+}
@@ -8,3 +8,13 @@ const (
 	// transaction will fail validation.
 	BaseFee Amount = 10000
 )
+
+// NegativeCachePrefixes are the storage prefixes, which are mostly looked up
+// for the keys not existing, like the checks whether the transaction is
+// already seen; see `sebakstorage.NegativeCache`.
+var NegativeCachePrefixes = []string{
+	BlockTransactionHistoryPrefixHash,
+	BlockTransactionPrefixHash,
+	BlockTransactionPrefixCheckpoint,
+	BlockOperationPrefixHash,
+}
@@ -32,4 +32,76 @@ var (
 	ErrorBlockAccountAlreadyExists        = NewError(129, "account already exists in block")
 	ErrorAccountBalanceUnderZero          = NewError(130, "account balance will be under zero")
 	ErrorMaximumBalanceReached            = NewError(131, "monetary amount would be greater than the total supply of coins")
+	ErrorStartupQuorumTimeout             = NewError(132, "failed to reach the quorum of validators in time")
+	ErrorTransactionDoubleSpend           = NewError(133, "checkpoint of source account is already spent")
+	ErrorEmptyMessage                     = NewError(134, "empty message")
+	ErrorUnknownMessageType               = NewError(135, "unknown message type")
+	ErrorViewChangeFromUnknownValidator   = NewError(136, "view change from unknown validator")
+	ErrorEnvelopeInvalidThreshold         = NewError(137, "threshold of envelope must be between 1 and the number of signers")
+	ErrorEnvelopeUnknownSigner            = NewError(138, "signer is not in the signers of envelope")
+	ErrorEnvelopeNotMatched               = NewError(139, "envelopes are not for the same transaction")
+	ErrorEnvelopeThresholdNotSatisfied    = NewError(140, "valid signatures of envelope do not satisfy the threshold")
+	ErrorTransactionAlreadyInPool         = NewError(141, "transaction already in transaction pool")
+	ErrorTransactionPoolFull              = NewError(142, "transaction pool is full and the fee is not higher than the lowest fee in pool")
+	ErrorTransactionPoolAccountLimit      = NewError(143, "too many transactions of source account in transaction pool")
+	ErrorStateHashDoesNotMatch            = NewError(144, "account state does not match the state hash of block")
+	ErrorInvalidNodeStateTransition       = NewError(145, "invalid node state transition")
+	ErrorGenesisEmptyNetworkID            = NewError(146, "network id of genesis is empty")
+	ErrorGenesisNoAccounts                = NewError(147, "genesis must have at least one account")
+	ErrorGenesisAlreadyApplied            = NewError(148, "genesis is already applied")
+	ErrorGenesisNotApplied                = NewError(149, "genesis is not applied")
+	ErrorBlockBelowFinality               = NewError(150, "block is below the last irreversible block")
+	ErrorBlockFromUnknownValidator        = NewError(151, "block announcement from unknown validator")
+	ErrorSelfTestFailed                   = NewError(152, "self test failed")
+	ErrorFaucetNotTestNetwork             = NewError(153, "faucet is only for the test network")
+	ErrorFaucetInvalidToken               = NewError(154, "invalid faucet token")
+	ErrorFaucetQuotaExceeded              = NewError(155, "faucet quota exceeded")
+	ErrorTransactionInvalidCheckpoint     = NewError(156, "checkpoint is not the latest checkpoint of source account")
+	ErrorStreamTooManySubscribers         = NewError(157, "too many stream subscribers")
+	ErrorNodeNotReady                     = NewError(158, "node is not ready to accept transactions")
+	ErrorRateLimitExceeded                = NewError(159, "rate limit exceeded")
+	ErrorInvalidAPIKey                    = NewError(160, "invalid API key")
+	ErrorThresholdInvalidPoint            = NewError(161, "invalid point of threshold signature")
+	ErrorThresholdInvalidShare            = NewError(162, "invalid threshold share")
+	ErrorThresholdInvalidCommitment       = NewError(163, "invalid or used threshold commitment")
+	ErrorThresholdInvalidSignature        = NewError(164, "invalid partial signature")
+	ErrorThresholdNotEnoughSigners        = NewError(165, "not enough signers to reach the threshold")
+	ErrorThresholdEquivocation            = NewError(166, "signer refuses the message, which conflicts with the signed messages")
+	ErrorInvalidBlockID                   = NewError(167, "block id must be the height or the hash of block")
+	ErrorPeerExchangeExpired              = NewError(168, "peer exchange is expired or from the future")
+	ErrorPeerExchangeTooManyPeers         = NewError(169, "too many peers in peer exchange")
+	ErrorIndexInconsistent                = NewError(170, "indexes of block transaction are missing or inconsistent")
+	ErrorSpendingLimitExceeded            = NewError(171, "spending limit of source account is exceeded; co-signatures of the signers do not reach the threshold")
+	ErrorTransactionInvalidCoSignatures   = NewError(172, "co-signatures of transaction are invalid or duplicated")
+	ErrorTransactionRejected              = NewError(173, "transaction is rejected by consensus")
+	ErrorTransactionEvicted               = NewError(174, "transaction is evicted from transaction pool by the transaction of higher fee")
+	ErrorSessionKeyIdentityNotMatched     = NewError(175, "identity of session key certificate does not match the node key")
+	ErrorSessionKeyExpired                = NewError(176, "session key certificate is expired")
+	ErrorBallotInvalidUpgradeSignals      = NewError(177, "upgrade signals of ballot are invalid")
+	ErrorMessageNotSigned                 = NewError(178, "message from the node is not signed")
+	ErrorMessageNotFromValidator          = NewError(179, "message is not signed by the known validator")
+	ErrorPeerCircuitOpen                  = NewError(180, "circuit to the peer is open; the peer failed too many times in a row")
+	ErrorBlockNotNext                     = NewError(181, "block is not the next of the latest block")
+	ErrorBlockSyncNoPeer                  = NewError(182, "no validator serves the blocks to sync")
+	ErrorMessageQueueFull                 = NewError(183, "message queue is full; the node is busy with the consensus")
+	ErrorPeerBanned                       = NewError(184, "peer is banned for the malformed messages or the invalid signatures")
+	ErrorPeerGreylisted                   = NewError(185, "peer is greylisted; the sync requests are refused")
+	ErrorProtocolVersionIncompatible      = NewError(186, "protocol version of the peer is not compatible")
+	ErrorSignersThresholdNotReached       = NewError(187, "weights of signatures do not reach the threshold of account signers")
+	ErrorCreateAccountUnderfunded         = NewError(188, "amount of create-account is lower than the minimum balance of network")
+	ErrorTransactionOperationsFailed      = NewError(189, "operations of transaction fail")
+	ErrorTransactionTooManyPayments       = NewError(190, "too many payments in transaction")
+	ErrorAccountBalanceFrozen             = NewError(191, "spendable balance except the frozen balance is not enough")
+	ErrorFrozenBalanceNotUnlocked         = NewError(192, "frozen balance is not enough or not unlocked yet")
+	ErrorTooManyFrozenBalances            = NewError(193, "too many frozen balances of account")
+	ErrorTransactionInvalidTimeBounds     = NewError(194, "time bounds of transaction are invalid")
+	ErrorTransactionNotValidYet           = NewError(195, "transaction is not valid yet; before it's valid_after")
+	ErrorTransactionExpired               = NewError(196, "transaction is expired; after it's valid_until")
+	ErrorTooManyAccountDataEntries        = NewError(197, "too many data entries of account")
+	ErrorFutureTransactionExpired         = NewError(198, "checkpoint of the queued transaction is not reached in time")
+	ErrorUnknownEncodingVersion           = NewError(199, "encoding version of message is unknown")
+	ErrorTransactionTooManyOperations     = NewError(200, "too many operations in transaction")
+	ErrorBallotInvalidProposedTime        = NewError(201, "proposed time of ballot is invalid or different from the other ballots of the message")
+	ErrorThresholdSignerInsecure          = NewError(202, "signer endpoint must be https with the CA certificate of signer")
+	ErrorSpendingLimitRequiresSigners     = NewError(203, "spending limit requires the signers of source account")
 )
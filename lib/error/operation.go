@@ -0,0 +1,68 @@
+package sebakerror
+
+import (
+	"fmt"
+	"strings"
+)
+
+// OperationResult is the result of one operation of transaction by it's
+// index in the operations.
+type OperationResult struct {
+	Index  int        `json:"index"`
+	Result ResultCode `json:"result"`
+	Detail string     `json:"detail,omitempty"`
+}
+
+func (r OperationResult) IsFailed() bool {
+	return r.Result != ResultSuccess
+}
+
+// OperationsError is `ErrorTransactionOperationsFailed` with the results of
+// every operation, so the client knows which operations of the batch failed.
+type OperationsError struct {
+	Operations []OperationResult
+}
+
+// NewOperationsError makes the error from the errors of operations by their
+// index; nil is the success. If no operation failed, it returns nil.
+func NewOperationsError(errs []error) error {
+	var failed bool
+	e := &OperationsError{}
+	for i, err := range errs {
+		r := OperationResult{Index: i, Result: ResultOf(err)}
+		if err != nil {
+			failed = true
+			if c, ok := err.(*Error); ok {
+				r.Detail = c.Message
+			} else {
+				r.Detail = err.Error()
+			}
+		}
+		e.Operations = append(e.Operations, r)
+	}
+	if !failed {
+		return nil
+	}
+
+	return e
+}
+
+// Failed returns the results of the failed operations.
+func (e *OperationsError) Failed() (failed []OperationResult) {
+	for _, r := range e.Operations {
+		if r.IsFailed() {
+			failed = append(failed, r)
+		}
+	}
+
+	return
+}
+
+func (e *OperationsError) Error() string {
+	var s []string
+	for _, r := range e.Failed() {
+		s = append(s, fmt.Sprintf("%d: %s", r.Index, r.Result))
+	}
+
+	return fmt.Sprintf("%s; %s", ErrorTransactionOperationsFailed.Message, strings.Join(s, ", "))
+}
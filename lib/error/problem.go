@@ -0,0 +1,81 @@
+package sebakerror
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Problem is the error response of every HTTP handler of node, the
+// 'application/problem+json' of RFC 7807.
+//  * `Status`, `Title`: the HTTP status and it's text
+//  * `Code`: the code of `Error`; it is omitted for the errors, which are not
+//  `Error`
+//  * `Result`: the stable result code of `Results()`, which the clients
+//  should depend on
+//  * `Detail`: the message of error, which can be changed between versions
+//  * `Instance`: the request URI, which the error occurred in
+//  * `Operations`: the results of every operation of `OperationsError`
+type Problem struct {
+	Status     int               `json:"status"`
+	Title      string            `json:"title"`
+	Code       uint              `json:"code,omitempty"`
+	Result     ResultCode        `json:"result,omitempty"`
+	Detail     string            `json:"detail,omitempty"`
+	Instance   string            `json:"instance,omitempty"`
+	Operations []OperationResult `json:"operations,omitempty"`
+}
+
+const ProblemContentType string = "application/problem+json"
+
+// resultsByStatus is the result code of the errors, which are not `Error`.
+var resultsByStatus = map[int]ResultCode{
+	http.StatusBadRequest:            ResultBadRequest,
+	http.StatusUnauthorized:          ResultUnauthorized,
+	http.StatusForbidden:             ResultForbidden,
+	http.StatusNotFound:              ResultNotFound,
+	http.StatusMethodNotAllowed:      ResultMethodNotAllowed,
+	http.StatusRequestEntityTooLarge: ResultBadRequest,
+	http.StatusTooManyRequests:       ResultRateLimited,
+	http.StatusInternalServerError:   ResultInternal,
+	http.StatusServiceUnavailable:    ResultNodeNotReady,
+}
+
+func NewProblem(status int, err error) Problem {
+	p := Problem{Status: status, Title: http.StatusText(status)}
+	if e, ok := err.(*Error); ok {
+		p.Code = e.Code
+		p.Result = ResultOf(e)
+		p.Detail = e.Message
+		return p
+	}
+	if e, ok := err.(*OperationsError); ok {
+		p.Code = ErrorTransactionOperationsFailed.Code
+		p.Result = ResultTransactionOperationsFailed
+		p.Detail = e.Error()
+		p.Operations = e.Operations
+		return p
+	}
+
+	if err != nil {
+		p.Detail = err.Error()
+	}
+	if code, found := resultsByStatus[status]; found {
+		p.Result = code
+	} else if status >= http.StatusBadRequest {
+		p.Result = ResultUnknown
+	}
+
+	return p
+}
+
+// WriteProblem writes the error of the request as `Problem`.
+func WriteProblem(w http.ResponseWriter, r *http.Request, status int, err error) {
+	p := NewProblem(status, err)
+	if r != nil && r.URL != nil {
+		p.Instance = r.URL.RequestURI()
+	}
+
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(p)
+}
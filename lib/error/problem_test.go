@@ -0,0 +1,66 @@
+package sebakerror
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestProblem(t *testing.T) {
+	for status, result := range resultsByStatus {
+		if _, found := GetResult(result); !found {
+			t.Errorf("result code of status, %d is not defined: %s", status, result)
+			return
+		}
+	}
+
+	p := NewProblem(http.StatusConflict, ErrorTransactionDoubleSpend)
+	if p.Code != ErrorTransactionDoubleSpend.Code || p.Result != ResultTransactionDoubleSpend || p.Detail != ErrorTransactionDoubleSpend.Message {
+		t.Errorf("wrong problem of error: %v", p)
+		return
+	}
+
+	p = NewProblem(http.StatusNotFound, errors.New("account not found"))
+	if p.Code != 0 || p.Result != ResultNotFound || p.Title != "Not Found" {
+		t.Errorf("wrong problem of status: %v", p)
+		return
+	}
+
+	req := httptest.NewRequest("GET", "/api/v1/accounts/GABC?limit=1", nil)
+	w := httptest.NewRecorder()
+	WriteProblem(w, req, http.StatusBadRequest, errors.New("'limit' must be between 1 and 100"))
+	if w.Code != http.StatusBadRequest || w.Header().Get("Content-Type") != ProblemContentType {
+		t.Errorf("wrong response: %d %s", w.Code, w.Header().Get("Content-Type"))
+		return
+	}
+
+	var written Problem
+	if err := json.Unmarshal(w.Body.Bytes(), &written); err != nil {
+		t.Error(err)
+		return
+	}
+	if written.Instance != "/api/v1/accounts/GABC?limit=1" || written.Result != ResultBadRequest {
+		t.Errorf("wrong problem: %v", written)
+		return
+	}
+}
+
+func TestProblemOperations(t *testing.T) {
+	if err := NewOperationsError([]error{nil, nil}); err != nil {
+		t.Errorf("operations without failure must not be error: %v", err)
+		return
+	}
+
+	err := NewOperationsError([]error{nil, ErrorBlockAccountDoesNotExists})
+	p := NewProblem(http.StatusBadRequest, err)
+	if p.Code != ErrorTransactionOperationsFailed.Code || p.Result != ResultTransactionOperationsFailed || len(p.Operations) != 2 {
+		t.Errorf("wrong problem of operations: %v", p)
+		return
+	}
+	if p.Operations[0].IsFailed() || p.Operations[1].Index != 1 || p.Operations[1].Result != ResultTransactionNoAccount {
+		t.Errorf("wrong results of operations: %v", p.Operations)
+		return
+	}
+}
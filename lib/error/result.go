@@ -0,0 +1,223 @@
+package sebakerror
+
+import "sort"
+
+// ResultCode is the stable result of transaction and operation, which the
+// clients can depend on instead of the message of error. The errors of the
+// same cause share one result code; the code of transaction starts with
+// 'tx_' and the code of operation starts with 'op_'.
+type ResultCode string
+
+const (
+	ResultSuccess ResultCode = "success"
+	ResultUnknown ResultCode = "unknown"
+
+	ResultTransactionAccepted            ResultCode = "tx_accepted"
+	ResultTransactionQueued              ResultCode = "tx_queued"
+	ResultTransactionMalformed           ResultCode = "tx_malformed"
+	ResultTransactionBadSignature        ResultCode = "tx_bad_signature"
+	ResultTransactionInsufficientFee     ResultCode = "tx_insufficient_fee"
+	ResultTransactionBadCheckpoint       ResultCode = "tx_bad_checkpoint"
+	ResultTransactionDoubleSpend         ResultCode = "tx_double_spend"
+	ResultTransactionDuplicated          ResultCode = "tx_duplicated"
+	ResultTransactionNoAccount           ResultCode = "tx_no_account"
+	ResultTransactionInsufficientBalance ResultCode = "tx_insufficient_balance"
+	ResultTransactionPoolFull            ResultCode = "tx_pool_full"
+	ResultTransactionPoolAccountLimit    ResultCode = "tx_pool_account_limit"
+	ResultTransactionSpendingLimit       ResultCode = "tx_spending_limit"
+	ResultTransactionSignersThreshold    ResultCode = "tx_signers_threshold"
+	ResultTransactionOperationsFailed    ResultCode = "tx_operations_failed"
+	ResultTransactionBalanceFrozen       ResultCode = "tx_balance_frozen"
+	ResultTransactionNotValidYet         ResultCode = "tx_not_valid_yet"
+	ResultTransactionExpired             ResultCode = "tx_expired"
+	ResultTransactionRejected            ResultCode = "tx_rejected"
+	ResultTransactionEvicted             ResultCode = "tx_evicted"
+
+	ResultOperationMalformed       ResultCode = "op_malformed"
+	ResultOperationUnknownType     ResultCode = "op_unknown_type"
+	ResultOperationAccountExists   ResultCode = "op_account_exists"
+	ResultOperationBalanceOverflow ResultCode = "op_balance_overflow"
+	ResultOperationUnderfunded     ResultCode = "op_underfunded"
+	ResultOperationFrozenLocked    ResultCode = "op_frozen_locked"
+	ResultOperationTooManyFrozen   ResultCode = "op_too_many_frozen"
+	ResultOperationDataEntriesFull ResultCode = "op_data_entries_full"
+	ResultOperationNoSigners       ResultCode = "op_no_signers"
+
+	ResultNodeNotReady ResultCode = "node_not_ready"
+	ResultForbidden    ResultCode = "forbidden"
+	ResultRateLimited  ResultCode = "rate_limited"
+	ResultNotAllowed   ResultCode = "not_allowed"
+	ResultInternal     ResultCode = "internal"
+
+	// the results of the requests, which are not about the transaction
+	ResultBadRequest       ResultCode = "bad_request"
+	ResultUnauthorized     ResultCode = "unauthorized"
+	ResultNotFound         ResultCode = "not_found"
+	ResultMethodNotAllowed ResultCode = "method_not_allowed"
+)
+
+type Result struct {
+	Code ResultCode `json:"code"`
+
+	// Retryable means the same request can succeed later without any change;
+	// the other failures need the new transaction, like with the latest
+	// checkpoint, or the compensation of client.
+	Retryable   bool   `json:"retryable"`
+	Description string `json:"description"`
+}
+
+var results = map[ResultCode]Result{}
+
+func addResult(code ResultCode, retryable bool, description string) {
+	results[code] = Result{Code: code, Retryable: retryable, Description: description}
+}
+
+func init() {
+	addResult(ResultSuccess, false, "succeeded")
+	addResult(ResultUnknown, false, "the cause is unknown; the error message has the detail")
+
+	addResult(ResultTransactionAccepted, false, "transaction is accepted and will be included in the block by consensus")
+	addResult(ResultTransactionQueued, false, "checkpoint of transaction is not reached yet; it is held until the previous transactions of source account are received")
+	addResult(ResultTransactionMalformed, false, "transaction is not well-formed, like the wrong hash or no operations")
+	addResult(ResultTransactionBadSignature, false, "signature of transaction or envelope is not valid")
+	addResult(ResultTransactionInsufficientFee, false, "fee is lower than the required fee of operations")
+	addResult(ResultTransactionBadCheckpoint, false, "checkpoint is not the latest checkpoint of source account; make the transaction again with the latest checkpoint")
+	addResult(ResultTransactionDoubleSpend, false, "checkpoint of source account is already spent by the other transaction")
+	addResult(ResultTransactionDuplicated, false, "same transaction is already in the block or the transaction pool")
+	addResult(ResultTransactionNoAccount, false, "account does not exist")
+	addResult(ResultTransactionInsufficientBalance, false, "balance of source account is not enough for the amount and fee")
+	addResult(ResultTransactionPoolFull, true, "transaction pool is full; retry later or with the higher fee")
+	addResult(ResultTransactionPoolAccountLimit, true, "source account has too many transactions in the transaction pool; retry after they are confirmed")
+	addResult(ResultTransactionSpendingLimit, false, "transaction exceeds the spending limit of source account or changes it or the signers; co-sign by the signers of account up to their threshold")
+	addResult(ResultTransactionSignersThreshold, false, "weights of signatures do not reach the threshold of the signers of source account; co-sign by the signers")
+	addResult(ResultTransactionOperationsFailed, false, "one or more operations of transaction fail; the results of operations have the failed ones by their index")
+	addResult(ResultTransactionNotValidYet, true, "transaction is submitted before it's valid_after; retry after it")
+	addResult(ResultTransactionExpired, false, "transaction is expired after it's valid_until; sign the new transaction")
+	addResult(ResultTransactionBalanceFrozen, false, "balance of source account except the frozen balance is not enough for the amount, fee and freezes")
+	addResult(ResultTransactionRejected, false, "transaction is rejected by consensus; the validators voted against it")
+	addResult(ResultTransactionEvicted, true, "transaction is evicted from the full transaction pool by the higher fee; submit again later or with the higher fee")
+
+	addResult(ResultOperationMalformed, false, "operation is not valid, like the wrong address or amount")
+	addResult(ResultOperationUnknownType, false, "operation type is unknown or does not match the body")
+	addResult(ResultOperationAccountExists, false, "target account of create-account operation already exists")
+	addResult(ResultOperationBalanceOverflow, false, "balance would be greater than the total supply of coins")
+	addResult(ResultOperationUnderfunded, false, "amount of create-account operation is lower than the minimum balance of network")
+	addResult(ResultOperationFrozenLocked, false, "amount of unfreeze operation is greater than the frozen balance, which is unlocked at the next block")
+	addResult(ResultOperationDataEntriesFull, false, "source account already has the maximum number of data entries; remove the entries first")
+	addResult(ResultOperationNoSigners, false, "spending limit needs the signers of source account; set the signers before the limit, and remove the limit before the signers")
+	addResult(ResultOperationTooManyFrozen, false, "source account already has the maximum number of frozen balances; unfreeze the unlocked ones first")
+
+	addResult(ResultNodeNotReady, true, "node is not ready, like before reaching the quorum of validators")
+	addResult(ResultForbidden, false, "request is not allowed with the given token")
+	addResult(ResultRateLimited, true, "too many requests; retry later")
+	addResult(ResultNotAllowed, false, "request is not allowed in this network")
+	addResult(ResultInternal, false, "internal error of node")
+
+	addResult(ResultBadRequest, false, "request is not valid, like the wrong query or body; the error detail has the reason")
+	addResult(ResultUnauthorized, false, "request has no valid credential")
+	addResult(ResultNotFound, false, "requested resource does not exist")
+	addResult(ResultMethodNotAllowed, false, "HTTP method is not supported by the path")
+}
+
+// resultsByError maps the code of `Error` to the result code; the errors,
+// which are not here, like the errors of consensus are `ResultInternal`.
+var resultsByError = map[uint]ResultCode{
+	ErrorBlockAlreadyExists.Code:             ResultTransactionDuplicated,
+	ErrorHashDoesNotMatch.Code:               ResultTransactionMalformed,
+	ErrorSignatureVerificationFailed.Code:    ResultTransactionBadSignature,
+	ErrorBadPublicAddress.Code:               ResultOperationMalformed,
+	ErrorInvalidFee.Code:                     ResultTransactionInsufficientFee,
+	ErrorInvalidOperation.Code:               ResultOperationMalformed,
+	ErrorInvalidHash.Code:                    ResultTransactionMalformed,
+	ErrorInvalidMessage.Code:                 ResultTransactionMalformed,
+	ErrorTransactionEmptyOperations.Code:     ResultTransactionMalformed,
+	ErrorDuplicatedOperation.Code:            ResultTransactionMalformed,
+	ErrorUnknownOperationType.Code:           ResultOperationUnknownType,
+	ErrorTypeOperationBodyNotMatched.Code:    ResultOperationUnknownType,
+	ErrorBlockAccountDoesNotExists.Code:      ResultTransactionNoAccount,
+	ErrorBlockAccountAlreadyExists.Code:      ResultOperationAccountExists,
+	ErrorAccountBalanceUnderZero.Code:        ResultTransactionInsufficientBalance,
+	ErrorMaximumBalanceReached.Code:          ResultOperationBalanceOverflow,
+	ErrorTransactionDoubleSpend.Code:         ResultTransactionDoubleSpend,
+	ErrorEmptyMessage.Code:                   ResultTransactionMalformed,
+	ErrorUnknownMessageType.Code:             ResultTransactionMalformed,
+	ErrorEnvelopeInvalidThreshold.Code:       ResultTransactionBadSignature,
+	ErrorEnvelopeUnknownSigner.Code:          ResultTransactionBadSignature,
+	ErrorEnvelopeNotMatched.Code:             ResultTransactionBadSignature,
+	ErrorEnvelopeThresholdNotSatisfied.Code:  ResultTransactionBadSignature,
+	ErrorTransactionAlreadyInPool.Code:       ResultTransactionDuplicated,
+	ErrorTransactionPoolFull.Code:            ResultTransactionPoolFull,
+	ErrorTransactionPoolAccountLimit.Code:    ResultTransactionPoolAccountLimit,
+	ErrorFaucetNotTestNetwork.Code:           ResultNotAllowed,
+	ErrorFaucetInvalidToken.Code:             ResultForbidden,
+	ErrorFaucetQuotaExceeded.Code:            ResultRateLimited,
+	ErrorTransactionInvalidCheckpoint.Code:   ResultTransactionBadCheckpoint,
+	ErrorStreamTooManySubscribers.Code:       ResultRateLimited,
+	ErrorNodeNotReady.Code:                   ResultNodeNotReady,
+	ErrorRateLimitExceeded.Code:              ResultRateLimited,
+	ErrorInvalidAPIKey.Code:                  ResultForbidden,
+	ErrorStartupQuorumTimeout.Code:           ResultNodeNotReady,
+	ErrorInvalidBlockID.Code:                 ResultBadRequest,
+	ErrorSpendingLimitExceeded.Code:          ResultTransactionSpendingLimit,
+	ErrorSignersThresholdNotReached.Code:     ResultTransactionSignersThreshold,
+	ErrorCreateAccountUnderfunded.Code:       ResultOperationUnderfunded,
+	ErrorTransactionOperationsFailed.Code:    ResultTransactionOperationsFailed,
+	ErrorTransactionTooManyPayments.Code:     ResultTransactionMalformed,
+	ErrorAccountBalanceFrozen.Code:           ResultTransactionBalanceFrozen,
+	ErrorFrozenBalanceNotUnlocked.Code:       ResultOperationFrozenLocked,
+	ErrorTooManyFrozenBalances.Code:          ResultOperationTooManyFrozen,
+	ErrorTransactionInvalidTimeBounds.Code:   ResultTransactionMalformed,
+	ErrorTransactionNotValidYet.Code:         ResultTransactionNotValidYet,
+	ErrorTransactionExpired.Code:             ResultTransactionExpired,
+	ErrorTooManyAccountDataEntries.Code:      ResultOperationDataEntriesFull,
+	ErrorFutureTransactionExpired.Code:       ResultTransactionBadCheckpoint,
+	ErrorUnknownEncodingVersion.Code:         ResultTransactionMalformed,
+	ErrorTransactionTooManyOperations.Code:   ResultTransactionMalformed,
+	ErrorTransactionInvalidCoSignatures.Code: ResultTransactionBadSignature,
+	ErrorTransactionRejected.Code:            ResultTransactionRejected,
+	ErrorTransactionEvicted.Code:             ResultTransactionEvicted,
+	ErrorSpendingLimitRequiresSigners.Code:   ResultOperationNoSigners,
+}
+
+// ResultOf returns the result code of error; nil is `ResultSuccess` and the
+// error, which is not `Error` is `ResultUnknown`.
+func ResultOf(err error) ResultCode {
+	if err == nil {
+		return ResultSuccess
+	}
+
+	if _, ok := err.(*OperationsError); ok {
+		return ResultTransactionOperationsFailed
+	}
+
+	e, ok := err.(*Error)
+	if !ok {
+		return ResultUnknown
+	}
+	if code, found := resultsByError[e.Code]; found {
+		return code
+	}
+
+	return ResultInternal
+}
+
+func GetResult(code ResultCode) (result Result, found bool) {
+	result, found = results[code]
+	return
+}
+
+func IsRetryable(err error) bool {
+	return results[ResultOf(err)].Retryable
+}
+
+// Results returns all the result codes in code order.
+func Results() (list []Result) {
+	for _, result := range results {
+		list = append(list, result)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Code < list[j].Code
+	})
+
+	return
+}
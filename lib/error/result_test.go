@@ -0,0 +1,44 @@
+package sebakerror
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestResultOf(t *testing.T) {
+	for code, result := range resultsByError {
+		if _, found := GetResult(result); !found {
+			t.Errorf("result code of error, %d is not defined: %s", code, result)
+			return
+		}
+	}
+
+	cases := []struct {
+		err       error
+		expected  ResultCode
+		retryable bool
+	}{
+		{nil, ResultSuccess, false},
+		{errors.New("unknown"), ResultUnknown, false},
+		{ErrorVotingResultNotFound, ResultInternal, false},
+		{ErrorTransactionInvalidCheckpoint, ResultTransactionBadCheckpoint, false},
+		{ErrorTransactionPoolFull, ResultTransactionPoolFull, true},
+		{ErrorNodeNotReady, ResultNodeNotReady, true},
+	}
+	for _, c := range cases {
+		if result := ResultOf(c.err); result != c.expected {
+			t.Errorf("wrong result of %v: %s != %s", c.err, result, c.expected)
+			return
+		}
+		if IsRetryable(c.err) != c.retryable {
+			t.Errorf("wrong retryable of %v", c.err)
+			return
+		}
+	}
+
+	list := Results()
+	if len(list) != len(results) || list[0].Code > list[1].Code {
+		t.Error("results must be sorted by code")
+		return
+	}
+}
@@ -0,0 +1,114 @@
+package sebak
+
+import (
+	"sync"
+
+	"boscoin.io/sebak/lib/error"
+)
+
+// EventStream delivers the events of the node, like the new blocks and
+// transactions, to the subscribers. The events are sent without blocking; the
+// subscriber, which can not receive in time, is dropped by closing it's
+// channel, so the slow client does not stop the node.
+
+const (
+	StreamEventBlock       string = "block"
+	StreamEventTransaction string = "transaction"
+	StreamEventOperation   string = "operation"
+	StreamEventAccount     string = "account"
+)
+
+const (
+	DefaultMaxStreamSubscribers int = 100
+	StreamSubscriberBufferSize  int = 64
+)
+
+type StreamEvent struct {
+	ID       string
+	Type     string
+	Data     interface{}
+	Accounts []string // the accounts, which the event is related with
+}
+
+// StreamFilterFunc selects the events for the subscriber.
+type StreamFilterFunc func(StreamEvent) bool
+
+type StreamSubscriber struct {
+	Events chan StreamEvent
+	filter StreamFilterFunc
+}
+
+type EventStream struct {
+	sync.Mutex
+
+	max         int
+	subscribers map[*StreamSubscriber]struct{}
+}
+
+func NewEventStream(max int) *EventStream {
+	return &EventStream{
+		max:         max,
+		subscribers: map[*StreamSubscriber]struct{}{},
+	}
+}
+
+func (s *EventStream) Len() int {
+	s.Lock()
+	defer s.Unlock()
+
+	return len(s.subscribers)
+}
+
+func (s *EventStream) Subscribe(filter StreamFilterFunc) (sub *StreamSubscriber, err error) {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.max > 0 && len(s.subscribers) >= s.max {
+		err = sebakerror.ErrorStreamTooManySubscribers
+		return
+	}
+
+	sub = &StreamSubscriber{
+		Events: make(chan StreamEvent, StreamSubscriberBufferSize),
+		filter: filter,
+	}
+	s.subscribers[sub] = struct{}{}
+
+	return
+}
+
+func (s *EventStream) Unsubscribe(sub *StreamSubscriber) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.remove(sub)
+}
+
+func (s *EventStream) remove(sub *StreamSubscriber) {
+	if _, found := s.subscribers[sub]; !found {
+		return
+	}
+	delete(s.subscribers, sub)
+	close(sub.Events)
+}
+
+func (s *EventStream) Publish(events ...StreamEvent) {
+	s.Lock()
+	defer s.Unlock()
+
+	for sub := range s.subscribers {
+	send:
+		for _, event := range events {
+			if sub.filter != nil && !sub.filter(event) {
+				continue
+			}
+
+			select {
+			case sub.Events <- event:
+			default:
+				s.remove(sub)
+				break send
+			}
+		}
+	}
+}
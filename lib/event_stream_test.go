@@ -0,0 +1,52 @@
+package sebak
+
+import (
+	"testing"
+
+	"boscoin.io/sebak/lib/error"
+)
+
+func TestEventStream(t *testing.T) {
+	stream := NewEventStream(2)
+
+	blocks, _ := stream.Subscribe(func(event StreamEvent) bool {
+		return event.Type == StreamEventBlock
+	})
+	all, _ := stream.Subscribe(nil)
+	if _, err := stream.Subscribe(nil); err != sebakerror.ErrorStreamTooManySubscribers {
+		t.Error("subscribers over the maximum must be refused")
+		return
+	}
+
+	stream.Publish(StreamEvent{ID: "1", Type: StreamEventBlock}, StreamEvent{ID: "a", Type: StreamEventTransaction})
+	if len(blocks.Events) != 1 || len(all.Events) != 2 {
+		t.Errorf("events are not filtered: %d, %d", len(blocks.Events), len(all.Events))
+		return
+	}
+	if event := <-blocks.Events; event.ID != "1" {
+		t.Errorf("wrong event: %v", event)
+		return
+	}
+
+	// the subscriber, which does not receive, is dropped
+	for i := 0; i < StreamSubscriberBufferSize; i++ {
+		stream.Publish(StreamEvent{Type: StreamEventTransaction})
+	}
+	if stream.Len() != 1 {
+		t.Errorf("slow subscriber must be dropped: %d", stream.Len())
+		return
+	}
+	for range all.Events {
+	}
+
+	stream.Unsubscribe(all)
+	stream.Unsubscribe(blocks)
+	if stream.Len() != 0 {
+		t.Error("failed to unsubscribe")
+		return
+	}
+	if _, ok := <-blocks.Events; ok {
+		t.Error("channel of unsubscribed must be closed")
+		return
+	}
+}
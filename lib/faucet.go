@@ -0,0 +1,241 @@
+package sebak
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/stellar/go/keypair"
+
+	"boscoin.io/sebak/lib/error"
+	"boscoin.io/sebak/lib/storage"
+)
+
+// Faucet funds the requested addresses from the faucet account in the test
+// network. To prevent the abuse, the requests must have the valid token, and
+// the number of fundings of one address and one IP in a day are limited by
+// the counters in storage,
+//  * 'fc-quota-<kind>-<address or IP>-<day>': the number of fundings
+
+const FaucetPrefixQuota string = "fc-quota-" // fc-quota-<kind>-<value>-<day>
+
+const (
+	FaucetQuotaKindAddress string = "address"
+	FaucetQuotaKindIP      string = "ip"
+)
+
+const (
+	DefaultFaucetAmount       Amount = 1000 * BaseFee
+	DefaultFaucetAddressQuota int    = 1
+	DefaultFaucetIPQuota      int    = 10
+
+	// FaucetPendingTimeout is how long the last transaction of faucet is
+	// expected to be in the transaction pool; until then, the next transaction
+	// is chained from it.
+	FaucetPendingTimeout time.Duration = 10 * time.Second
+)
+
+// FaucetVerifyFunc verifies the token of request, like captcha response with
+// the IP of requester.
+type FaucetVerifyFunc func(token, ip string) error
+
+type FaucetConfig struct {
+	Amount       Amount
+	AddressQuota int // 0 is unlimited
+	IPQuota      int // 0 is unlimited
+
+	// Tokens are the allowed tokens; if `Verify` is also given, the token
+	// must pass both. Without them, the token is not checked.
+	Tokens []string
+	Verify FaucetVerifyFunc
+}
+
+func NewDefaultFaucetConfig() FaucetConfig {
+	return FaucetConfig{
+		Amount:       DefaultFaucetAmount,
+		AddressQuota: DefaultFaucetAddressQuota,
+		IPQuota:      DefaultFaucetIPQuota,
+	}
+}
+
+type Faucet struct {
+	sync.Mutex
+
+	networkID []byte
+	kp        *keypair.Full
+	config    FaucetConfig
+
+	last     Transaction
+	lastSent time.Time
+
+	now func() time.Time
+}
+
+// IsTestNetworkID checks whether the network is for test; the network id of
+// test network has 'test', like 'sebak-testnet'.
+func IsTestNetworkID(networkID []byte) bool {
+	return strings.Contains(strings.ToLower(string(networkID)), "test")
+}
+
+func NewFaucet(networkID []byte, kp *keypair.Full, config FaucetConfig) (f *Faucet, err error) {
+	if !IsTestNetworkID(networkID) {
+		err = sebakerror.ErrorFaucetNotTestNetwork
+		return
+	}
+	if config.Amount < 1 {
+		err = fmt.Errorf("faucet amount must be greater than 0")
+		return
+	}
+
+	f = &Faucet{
+		networkID: networkID,
+		kp:        kp,
+		config:    config,
+		now:       time.Now,
+	}
+
+	return
+}
+
+func (f *Faucet) Address() string {
+	return f.kp.Address()
+}
+
+func (f *Faucet) Config() FaucetConfig {
+	return f.config
+}
+
+func GetFaucetQuotaKey(kind, value string, day string) string {
+	return fmt.Sprintf("%s%s-%s-%s", FaucetPrefixQuota, kind, value, day)
+}
+
+func (f *Faucet) verifyToken(token, ip string) (err error) {
+	if len(f.config.Tokens) > 0 {
+		var found bool
+		for _, t := range f.config.Tokens {
+			found = found || t == token
+		}
+		if !found {
+			return sebakerror.ErrorFaucetInvalidToken
+		}
+	}
+
+	if f.config.Verify != nil {
+		if err = f.config.Verify(token, ip); err != nil {
+			return sebakerror.ErrorFaucetInvalidToken
+		}
+	}
+
+	return
+}
+
+func getFaucetQuota(st *sebakstorage.LevelDBBackend, key string) (count int, err error) {
+	var exists bool
+	if exists, err = st.Has(key); err != nil || !exists {
+		return
+	}
+	err = st.Get(key, &count)
+
+	return
+}
+
+func setFaucetQuota(st *sebakstorage.LevelDBBackend, key string, count int) (err error) {
+	if count == 1 {
+		return st.New(key, count)
+	}
+
+	return st.Set(key, count)
+}
+
+// nextCheckpoint returns the checkpoint of the next transaction of faucet. If
+// the last transaction is not committed yet, the next transaction is chained
+// from it, so the fundings can be sent without waiting the blocks.
+func (f *Faucet) nextCheckpoint(account *BlockAccount, tp *TransactionPool) string {
+	if len(f.last.GetHash()) < 1 {
+		return account.Checkpoint
+	}
+
+	next := f.last.NextCheckpoint()
+	if account.Checkpoint == next {
+		return next
+	}
+	if tp.Has(f.last.GetHash()) || f.now().Sub(f.lastSent) < FaucetPendingTimeout {
+		return next
+	}
+
+	// the last transaction is dropped
+	return account.Checkpoint
+}
+
+// Fund makes the signed transaction, which funds `address`; if `address` does
+// not exist, it is created. The quotas are counted when the transaction is
+// made, so the caller must send it.
+func (f *Faucet) Fund(st *sebakstorage.LevelDBBackend, tp *TransactionPool, address, ip, token string) (tx Transaction, err error) {
+	if _, err = keypair.Parse(address); err != nil {
+		return
+	}
+	if err = f.verifyToken(token, ip); err != nil {
+		return
+	}
+
+	f.Lock()
+	defer f.Unlock()
+
+	day := f.now().UTC().Format("2006-01-02")
+	quotas := []struct {
+		key   string
+		limit int
+		count int
+	}{
+		{key: GetFaucetQuotaKey(FaucetQuotaKindAddress, address, day), limit: f.config.AddressQuota},
+		{key: GetFaucetQuotaKey(FaucetQuotaKindIP, ip, day), limit: f.config.IPQuota},
+	}
+	for i, q := range quotas {
+		if quotas[i].count, err = getFaucetQuota(st, q.key); err != nil {
+			return
+		}
+		if q.limit > 0 && quotas[i].count >= q.limit {
+			err = sebakerror.ErrorFaucetQuotaExceeded
+			return
+		}
+	}
+
+	var source *BlockAccount
+	if source, err = GetBlockAccount(st, f.kp.Address()); err != nil {
+		err = sebakerror.ErrorBlockAccountDoesNotExists
+		return
+	}
+
+	var exists bool
+	if exists, err = ExistBlockAccount(st, address); err != nil {
+		return
+	}
+
+	op := Operation{
+		H: OperationHeader{Type: OperationPayment},
+		B: NewOperationBodyPayment(address, f.config.Amount),
+	}
+	if !exists {
+		op = Operation{
+			H: OperationHeader{Type: OperationCreateAccount},
+			B: NewOperationBodyCreateAccount(address, f.config.Amount),
+		}
+	}
+
+	if tx, err = NewTransaction(f.kp.Address(), f.nextCheckpoint(source, tp), op); err != nil {
+		return
+	}
+	tx.Sign(f.kp, f.networkID)
+
+	for _, q := range quotas {
+		if err = setFaucetQuota(st, q.key, q.count+1); err != nil {
+			return
+		}
+	}
+
+	f.last = tx
+	f.lastSent = f.now()
+
+	return
+}
@@ -0,0 +1,132 @@
+package sebak
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stellar/go/keypair"
+
+	"boscoin.io/sebak/lib/error"
+	"boscoin.io/sebak/lib/storage"
+)
+
+func newTestFaucet(t *testing.T, config FaucetConfig) (f *Faucet, st *sebakstorage.LevelDBBackend) {
+	st, _ = sebakstorage.NewTestMemoryLevelDBBackend()
+
+	kp, _ := keypair.Random()
+	NewBlockAccount(kp.Address(), Amount(BaseFee*1000000), "checkpoint").Save(st)
+
+	var err error
+	if f, err = NewFaucet(networkID, kp, config); err != nil {
+		t.Fatal(err)
+	}
+
+	return
+}
+
+func TestNewFaucet(t *testing.T) {
+	kp, _ := keypair.Random()
+	if _, err := NewFaucet([]byte("sebak-mainnet"), kp, NewDefaultFaucetConfig()); err != sebakerror.ErrorFaucetNotTestNetwork {
+		t.Errorf("faucet must be refused in the network, which is not for test: %v", err)
+		return
+	}
+	if _, err := NewFaucet([]byte("sebak-TestNet"), kp, FaucetConfig{}); err == nil {
+		t.Error("zero amount must be refused")
+		return
+	}
+}
+
+func TestFaucetFund(t *testing.T) {
+	config := NewDefaultFaucetConfig()
+	config.IPQuota = 2
+	config.Tokens = []string{"token0"}
+	f, st := newTestFaucet(t, config)
+	tp := NewTransactionPool()
+
+	target, _ := keypair.Random()
+	if _, err := f.Fund(st, tp, target.Address(), "127.0.0.1", "unknown"); err != sebakerror.ErrorFaucetInvalidToken {
+		t.Errorf("invalid token must be refused: %v", err)
+		return
+	}
+	if _, err := f.Fund(st, tp, "invalid-address", "127.0.0.1", "token0"); err == nil {
+		t.Error("invalid address must be refused")
+		return
+	}
+
+	tx0, err := f.Fund(st, tp, target.Address(), "127.0.0.1", "token0")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if err = tx0.IsWellFormed(networkID); err != nil {
+		t.Error(err)
+		return
+	}
+	if tx0.B.Checkpoint != "checkpoint" || tx0.B.Operations[0].H.Type != OperationCreateAccount {
+		t.Errorf("unknown address must be created: %v", tx0)
+		return
+	}
+
+	// the address quota is 1 in a day
+	if _, err = f.Fund(st, tp, target.Address(), "127.0.0.2", "token0"); err != sebakerror.ErrorFaucetQuotaExceeded {
+		t.Errorf("address quota must be exceeded: %v", err)
+		return
+	}
+
+	// the existing address is paid, and the transaction is chained from the
+	// pending transaction
+	another := testMakeBlockAccount()
+	another.Save(st)
+	tx1, err := f.Fund(st, tp, another.Address, "127.0.0.1", "token0")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if tx1.B.Operations[0].H.Type != OperationPayment || tx1.B.Checkpoint != tx0.NextCheckpoint() {
+		t.Errorf("wrong transaction for the existing address: %v", tx1)
+		return
+	}
+
+	// the IP quota is 2 in a day
+	other, _ := keypair.Random()
+	if _, err = f.Fund(st, tp, other.Address(), "127.0.0.1", "token0"); err != sebakerror.ErrorFaucetQuotaExceeded {
+		t.Errorf("IP quota must be exceeded: %v", err)
+		return
+	}
+
+	// the next day
+	f.now = func() time.Time { return time.Now().Add(24 * time.Hour) }
+	tx2, err := f.Fund(st, tp, target.Address(), "127.0.0.1", "token0")
+	if err != nil {
+		t.Errorf("quota must be reset in the next day: %v", err)
+		return
+	}
+
+	// the last transaction is dropped, so the checkpoint of account is used
+	if tx2.B.Checkpoint != "checkpoint" {
+		t.Errorf("dropped transaction must not be chained: %v", tx2.B.Checkpoint)
+		return
+	}
+}
+
+func TestFaucetVerify(t *testing.T) {
+	config := NewDefaultFaucetConfig()
+	config.Verify = func(token, ip string) error {
+		if token != "captcha-"+ip {
+			return errors.New("wrong captcha")
+		}
+		return nil
+	}
+	f, st := newTestFaucet(t, config)
+
+	target, _ := keypair.Random()
+	if _, err := f.Fund(st, NewTransactionPool(), target.Address(), "127.0.0.1", "captcha"); err != sebakerror.ErrorFaucetInvalidToken {
+		t.Errorf("failed verification must be refused: %v", err)
+		return
+	}
+	if _, err := f.Fund(st, NewTransactionPool(), target.Address(), "127.0.0.1", "captcha-127.0.0.1"); err != nil {
+		t.Error(err)
+		return
+	}
+}
@@ -0,0 +1,132 @@
+package sebak
+
+import (
+	"sort"
+
+	"boscoin.io/sebak/lib/storage"
+)
+
+// FeeEstimationBlocks is the number of the recent blocks, which the fees are
+// sampled from.
+const FeeEstimationBlocks int = 100
+
+// FeeEstimate is the fees of each operation for the new transaction,
+//  * `Minimum`: the lowest fee, which is accepted now; `FeePerOperation` of
+//  network, or above the lowest fee in pool when the pool is full
+//  * `Median`: the median fee of the transactions in the recent blocks
+//  * `Suggested`: the fee to be included soon; not lower than `Minimum` and
+//  `Median`, and with the `fee` ordering, not lower than the median fee in
+//  pool, so it is proposed before the half of the pending transactions
+// With `FeePerByte` of network, the fee of the size of transaction must be
+// also covered; see `NetworkParameters.MinimumFee()`.
+type FeeEstimate struct {
+	Minimum    Amount `json:"minimum"`
+	Median     Amount `json:"median"`
+	Suggested  Amount `json:"suggested"`
+	FeePerByte Amount `json:"fee_per_byte"`
+
+	Blocks       int    `json:"blocks"`       // the number of sampled blocks
+	Transactions int    `json:"transactions"` // the number of sampled transactions
+	PoolSize     int    `json:"pool_size"`
+	PoolMaxSize  int    `json:"pool_max_size"` // 0 is unlimited
+	Ordering     string `json:"ordering"`
+}
+
+// EstimateFees estimates the fees from the transactions of the recent
+// `blocks` blocks and the pending transactions in pool.
+func EstimateFees(st *sebakstorage.LevelDBBackend, pool *TransactionPool, policy TransactionOrderingPolicy, blocks int) (estimate FeeEstimate, err error) {
+	var parameters NetworkParameters
+	if parameters, err = GetNetworkParameters(st); err != nil {
+		return
+	}
+	minimum := parameters.FeePerOperation
+	if minimum == 0 {
+		minimum = BaseFee
+	}
+	estimate.FeePerByte = parameters.FeePerByte
+
+	var fees []Amount
+	if fees, estimate.Blocks, err = getRecentFees(st, blocks); err != nil {
+		return
+	}
+	estimate.Transactions = len(fees)
+
+	var pending []Amount
+	for _, item := range pool.Ordered(policy) {
+		pending = append(pending, item.Transaction.B.Fee)
+	}
+	estimate.PoolSize = len(pending)
+	estimate.PoolMaxSize, _ = pool.Limits()
+	estimate.Ordering = string(policy)
+
+	// when the pool is full, the transaction, whose fee is not higher than
+	// the lowest fee in pool is refused
+	estimate.Minimum = minimum
+	if estimate.PoolMaxSize > 0 && estimate.PoolSize >= estimate.PoolMaxSize {
+		lowest := pending[0]
+		for _, fee := range pending {
+			if fee < lowest {
+				lowest = fee
+			}
+		}
+		estimate.Minimum = maxAmount(estimate.Minimum, lowest+1)
+	}
+
+	estimate.Median = maxAmount(medianAmount(fees), minimum)
+	estimate.Suggested = maxAmount(estimate.Minimum, estimate.Median)
+	if policy == TransactionOrderingFee {
+		estimate.Suggested = maxAmount(estimate.Suggested, medianAmount(pending))
+	}
+
+	return
+}
+
+// getRecentFees returns the fees of the transactions in the recent `blocks`
+// blocks from the latest.
+func getRecentFees(st *sebakstorage.LevelDBBackend, blocks int) (fees []Amount, sampled int, err error) {
+	var latest Block
+	if latest, err = GetLatestBlock(st); err != nil || latest.IsEmpty() {
+		return
+	}
+
+	block := latest
+	for {
+		for _, hash := range block.Transactions {
+			var bt BlockTransaction
+			if bt, err = GetBlockTransaction(st, hash); err != nil {
+				return
+			}
+			fees = append(fees, bt.Fee)
+		}
+		sampled++
+
+		if sampled == blocks || block.Height <= 1 {
+			break
+		}
+		if block, err = GetBlockByHeight(st, block.Height-1); err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+func medianAmount(amounts []Amount) Amount {
+	if len(amounts) < 1 {
+		return 0
+	}
+
+	sorted := make([]Amount, len(amounts))
+	copy(sorted, amounts)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	return sorted[len(sorted)/2]
+}
+
+func maxAmount(a, b Amount) Amount {
+	if a > b {
+		return a
+	}
+
+	return b
+}
@@ -0,0 +1,71 @@
+package sebak
+
+import (
+	"testing"
+
+	"boscoin.io/sebak/lib/storage"
+)
+
+func testMakeTransactionWithFee(fee Amount) Transaction {
+	kp, tx := TestMakeTransaction(networkID, 1)
+	tx.B.Fee = fee
+	tx.Sign(kp, networkID)
+
+	return tx
+}
+
+func TestEstimateFees(t *testing.T) {
+	st, _ := sebakstorage.NewTestMemoryLevelDBBackend()
+	defer st.Close()
+
+	pool := NewTransactionPool()
+
+	// without blocks, `BaseFee`
+	estimate, err := EstimateFees(st, pool, TransactionOrderingFee, FeeEstimationBlocks)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if estimate.Minimum != BaseFee || estimate.Median != BaseFee || estimate.Suggested != BaseFee || estimate.Blocks != 0 {
+		t.Errorf("wrong estimate: %v", estimate)
+		return
+	}
+
+	var latest Block
+	for _, fee := range []Amount{BaseFee * 5, BaseFee, BaseFee * 3, BaseFee * 2} {
+		tx := testMakeTransactionWithFee(fee)
+		bt := NewBlockTransactionFromTransaction(tx, tx.B.MakeHash())
+		bt.Save(st)
+		latest = NewBlock(latest, "", tx.GetHash())
+		latest.Save(st)
+	}
+
+	// only the latest 3 blocks are sampled
+	estimate, _ = EstimateFees(st, pool, TransactionOrderingFee, 3)
+	if estimate.Blocks != 3 || estimate.Transactions != 3 || estimate.Median != BaseFee*2 || estimate.Suggested != BaseFee*2 {
+		t.Errorf("wrong estimate: %v", estimate)
+		return
+	}
+
+	// with the `fee` ordering, the suggested fee is above the half of pool
+	pool.SetLimits(2, 0)
+	pool.Add(testMakeTransactionWithFee(BaseFee * 4))
+	estimate, _ = EstimateFees(st, pool, TransactionOrderingFee, 3)
+	if estimate.Minimum != BaseFee || estimate.Suggested != BaseFee*4 {
+		t.Errorf("wrong estimate: %v", estimate)
+		return
+	}
+	if estimate, _ = EstimateFees(st, pool, TransactionOrderingFIFO, 3); estimate.Suggested != BaseFee*2 {
+		t.Errorf("wrong estimate: %v", estimate)
+		return
+	}
+
+	// when the pool is full, the fee must be higher than the lowest fee in
+	// pool
+	pool.Add(testMakeTransactionWithFee(BaseFee * 3))
+	estimate, _ = EstimateFees(st, pool, TransactionOrderingFIFO, 3)
+	if estimate.Minimum != BaseFee*3+1 || estimate.Suggested != BaseFee*3+1 || estimate.PoolSize != 2 || estimate.PoolMaxSize != 2 {
+		t.Errorf("wrong estimate: %v", estimate)
+		return
+	}
+}
@@ -0,0 +1,88 @@
+package sebak
+
+import (
+	"boscoin.io/sebak/lib/common"
+	"boscoin.io/sebak/lib/error"
+	"boscoin.io/sebak/lib/storage"
+)
+
+// Finality is the last irreversible block. In ISAAC, the block is made only
+// after the transaction passes ACCEPT, so every committed block is final and
+// the marker follows the latest block. The blocks below the marker can not be
+// reorganized; the new block must be higher than the marker and the snapshot
+// lower than the marker can not be restored without force. It is stored by,
+//  * 'fn-last-irreversible-block': `Finality`
+
+const FinalityKey string = "fn-last-irreversible-block"
+
+type Finality struct {
+	Hash    string `json:"hash"`
+	Height  uint64 `json:"height"`
+	Updated string `json:"updated"`
+}
+
+func NewFinality(block Block) Finality {
+	return Finality{
+		Hash:    block.Hash,
+		Height:  block.Height,
+		Updated: sebakcommon.NowISO8601(),
+	}
+}
+
+func (f Finality) IsEmpty() bool {
+	return len(f.Hash) < 1
+}
+
+// Save updates the marker; the marker can not move backward.
+func (f Finality) Save(st *sebakstorage.LevelDBBackend) (err error) {
+	var current Finality
+	if current, err = GetFinality(st); err != nil {
+		return
+	}
+
+	if current.IsEmpty() {
+		err = st.New(FinalityKey, f)
+		return
+	}
+	if f.Height < current.Height || (f.Height == current.Height && f.Hash != current.Hash) {
+		err = sebakerror.ErrorBlockBelowFinality
+		return
+	}
+
+	err = st.Set(FinalityKey, f)
+
+	return
+}
+
+// GetFinality loads the marker; if no block is final yet, empty `Finality` is
+// returned without error.
+func GetFinality(st *sebakstorage.LevelDBBackend) (f Finality, err error) {
+	var exists bool
+	if exists, err = st.Has(FinalityKey); err != nil || !exists {
+		return
+	}
+
+	err = st.Get(FinalityKey, &f)
+
+	return
+}
+
+// CheckSnapshotFinality refuses the snapshot, `snapshot` whose latest block is
+// lower than the marker of the current storage, `current`.
+func CheckSnapshotFinality(current, snapshot *sebakstorage.LevelDBBackend) (err error) {
+	var f Finality
+	if f, err = GetFinality(current); err != nil || f.IsEmpty() {
+		return
+	}
+
+	var latest Block
+	if latest, err = GetLatestBlock(snapshot); err != nil {
+		return
+	}
+	if latest.Height < f.Height {
+		err = sebakerror.ErrorBlockBelowFinality
+		return
+	}
+
+	return
+}
@@ -0,0 +1,98 @@
+package sebak
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"boscoin.io/sebak/lib/error"
+	"boscoin.io/sebak/lib/storage"
+)
+
+func TestFinality(t *testing.T) {
+	st, _ := sebakstorage.NewTestMemoryLevelDBBackend()
+
+	if f, err := GetFinality(st); err != nil || !f.IsEmpty() {
+		t.Error("empty finality must be returned")
+		return
+	}
+
+	var blocks []Block
+	var prev Block
+	for i := 0; i < 3; i++ {
+		prev = NewBlock(prev, "")
+		blocks = append(blocks, prev)
+	}
+
+	if err := NewFinality(blocks[1]).Save(st); err != nil {
+		t.Error(err)
+		return
+	}
+	if err := NewFinality(blocks[0]).Save(st); err != sebakerror.ErrorBlockBelowFinality {
+		t.Error("finality must not move backward")
+		return
+	}
+	if err := NewFinality(NewBlock(blocks[0], "other")).Save(st); err != sebakerror.ErrorBlockBelowFinality {
+		t.Error("the other block of same height must be refused")
+		return
+	}
+
+	// the blocks up to finality can not be saved
+	if err := blocks[1].Save(st); err != sebakerror.ErrorBlockBelowFinality {
+		t.Error("block below finality must be refused")
+		return
+	}
+	if err := blocks[2].Save(st); err != nil {
+		t.Error(err)
+		return
+	}
+	if err := NewFinality(blocks[2]).Save(st); err != nil {
+		t.Error(err)
+		return
+	}
+
+	f, _ := GetFinality(st)
+	if f.Hash != blocks[2].Hash || f.Height != 3 {
+		t.Errorf("wrong finality: %v", f)
+		return
+	}
+}
+
+func TestFinalitySnapshotRestore(t *testing.T) {
+	dir, _ := ioutil.TempDir("/tmp", "sebak")
+	defer sebakstorage.CleanDB(dir)
+
+	dbPath := filepath.Join(dir, "db")
+	snapshotDir := filepath.Join(dir, "snapshots")
+	st, _ := sebakstorage.NewTestFileLevelDBBackend(dbPath)
+
+	prev := NewBlock(Block{}, "")
+	prev.Save(st)
+	NewFinality(prev).Save(st)
+	sebakstorage.CreateSnapshot(st, snapshotDir, "old")
+
+	prev = NewBlock(prev, "")
+	prev.Save(st)
+	NewFinality(prev).Save(st)
+	st.Close()
+
+	config, _ := sebakstorage.NewConfigFromString(fmt.Sprintf("file://%s", dbPath))
+	if err := sebakstorage.RestoreSnapshotWithCheck(snapshotDir, "old", config, CheckSnapshotFinality); err != sebakerror.ErrorBlockBelowFinality {
+		t.Error("snapshot below finality must not be restored")
+		return
+	}
+
+	st, _ = sebakstorage.NewStorage(config)
+	if latest, _ := GetLatestBlock(st); latest.Height != 2 {
+		t.Error("refused restore must not change storage")
+		return
+	}
+	st.Close()
+
+	// without check, it is forced
+	if err := sebakstorage.RestoreSnapshot(snapshotDir, "old", config); err != nil {
+		t.Error(err)
+		return
+	}
+}
@@ -0,0 +1,195 @@
+package sebak
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/btcsuite/btcutil/base58"
+	"github.com/stellar/go/keypair"
+
+	"boscoin.io/sebak/lib/common"
+	"boscoin.io/sebak/lib/error"
+	"boscoin.io/sebak/lib/storage"
+)
+
+// BlockAnnouncement is broadcasted by validator after it commits the block, so
+// the other validators can compare it with their own block of same height.
+type BlockAnnouncement struct {
+	T string
+	H BlockAnnouncementHeader
+	B BlockAnnouncementBody
+}
+
+type BlockAnnouncementHeader struct {
+	Hash        string                 `json:"hash"`
+	Signature   string                 `json:"signature"`
+	Certificate *SessionKeyCertificate `json:"certificate,omitempty"`
+}
+
+type BlockAnnouncementBody struct {
+	NodeKey string `json:"node_key"`
+	Block   Block  `json:"block"`
+}
+
+func (bb BlockAnnouncementBody) MakeHashString() string {
+	return base58.Encode(sebakcommon.MustMakeObjectHash(bb))
+}
+
+func NewBlockAnnouncement(nodeKey string, block Block) BlockAnnouncement {
+	body := BlockAnnouncementBody{
+		NodeKey: nodeKey,
+		Block:   block,
+	}
+
+	return BlockAnnouncement{
+		T: "block-announcement",
+		H: BlockAnnouncementHeader{Hash: body.MakeHashString()},
+		B: body,
+	}
+}
+
+func NewBlockAnnouncementFromJSON(b []byte) (ba BlockAnnouncement, err error) {
+	err = json.Unmarshal(b, &ba)
+	return
+}
+
+func (ba *BlockAnnouncement) Sign(kp keypair.KP, networkID []byte) {
+	ba.H.Hash = ba.B.MakeHashString()
+	signature, _ := kp.Sign(append(networkID, []byte(ba.H.Hash)...))
+
+	ba.H.Signature = base58.Encode(signature)
+}
+
+func (ba BlockAnnouncement) IsWellFormed(networkID []byte) (err error) {
+	if ba.H.Hash != ba.B.MakeHashString() {
+		err = sebakerror.ErrorHashDoesNotMatch
+		return
+	}
+	if ba.B.Block.Hash != ba.B.Block.MakeHashString() {
+		err = sebakerror.ErrorHashDoesNotMatch
+		return
+	}
+
+	return VerifyNodeSignature(ba.B.NodeKey, ba.H.Certificate, networkID, ba.H.Hash, ba.H.Signature)
+}
+
+func (ba BlockAnnouncement) GetType() string {
+	return ba.T
+}
+
+func (ba BlockAnnouncement) GetHash() string {
+	return ba.H.Hash
+}
+
+func (ba BlockAnnouncement) Equal(m sebakcommon.Message) bool {
+	return ba.H.Hash == m.GetHash()
+}
+
+func (ba BlockAnnouncement) Serialize() (encoded []byte, err error) {
+	encoded, err = json.Marshal(ba)
+	return
+}
+
+func (ba BlockAnnouncement) String() string {
+	encoded, _ := json.MarshalIndent(ba, "", "  ")
+	return string(encoded)
+}
+
+// ForkEvidence is the block of the other validator, which conflicts with the
+// finalized block of same height. `Block.Confirmed` is the proposed time of
+// ballot, so the blocks of consensus have the same hash in every node and
+// the blocks conflict, when their hashes are different. It is stored by,
+//  * 'fk-<height>-<node key>': `ForkEvidence`
+
+const ForkEvidencePrefix string = "fk-"
+
+type ForkEvidence struct {
+	Height   uint64 `json:"height"`
+	NodeKey  string `json:"node_key"`
+	Local    Block  `json:"local"`
+	Remote   Block  `json:"remote"`
+	Detected string `json:"detected"`
+}
+
+func GetForkEvidenceKey(height uint64, nodeKey string) string {
+	return fmt.Sprintf("%s%020d-%s", ForkEvidencePrefix, height, nodeKey)
+}
+
+// IsBlockConflicted checks whether the blocks of same height are different;
+// the hash of block has the previous block, the transactions, the state and
+// the time of block.
+func IsBlockConflicted(a, b Block) bool {
+	return a.Height != b.Height || a.Hash != b.Hash
+}
+
+// Save stores the evidence; if the evidence of same height and node is already
+// stored, `saved` is `false`.
+func (f ForkEvidence) Save(st *sebakstorage.LevelDBBackend) (saved bool, err error) {
+	key := GetForkEvidenceKey(f.Height, f.NodeKey)
+
+	var exists bool
+	if exists, err = st.Has(key); err != nil || exists {
+		return
+	}
+	if err = st.New(key, f); err != nil {
+		return
+	}
+	saved = true
+
+	return
+}
+
+// GetForkEvidences returns the evidences from the highest block.
+func GetForkEvidences(st *sebakstorage.LevelDBBackend, limit int) (evidences []ForkEvidence, err error) {
+	iterFunc, closeFunc := st.GetIterator(ForkEvidencePrefix, true)
+	defer closeFunc()
+
+	for len(evidences) < limit {
+		item, hasNext := iterFunc()
+		if !hasNext {
+			break
+		}
+
+		var f ForkEvidence
+		if err = json.Unmarshal(item.Value, &f); err != nil {
+			return
+		}
+		evidences = append(evidences, f)
+	}
+
+	return
+}
+
+// CheckBlockAnnouncement compares the announced block with the finalized block
+// of same height. If they conflict, the evidence is returned. The block higher
+// than the last irreversible block is not checked.
+func CheckBlockAnnouncement(st *sebakstorage.LevelDBBackend, ba BlockAnnouncement) (evidence ForkEvidence, conflicted bool, err error) {
+	remote := ba.B.Block
+
+	var finality Finality
+	if finality, err = GetFinality(st); err != nil {
+		return
+	}
+	if finality.IsEmpty() || remote.Height > finality.Height {
+		return
+	}
+
+	var local Block
+	if local, err = GetBlockByHeight(st, remote.Height); err != nil {
+		return
+	}
+	if !IsBlockConflicted(local, remote) {
+		return
+	}
+
+	evidence = ForkEvidence{
+		Height:   remote.Height,
+		NodeKey:  ba.B.NodeKey,
+		Local:    local,
+		Remote:   remote,
+		Detected: sebakcommon.NowISO8601(),
+	}
+	conflicted = true
+
+	return
+}
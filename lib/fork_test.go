@@ -0,0 +1,110 @@
+package sebak
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stellar/go/keypair"
+
+	"boscoin.io/sebak/lib/error"
+	"boscoin.io/sebak/lib/network"
+)
+
+func TestForkDetection(t *testing.T) {
+	defer sebaknetwork.CleanUpMemoryNetwork()
+
+	nodeRunners := createNodeRunners(2)
+	nr, other := nodeRunners[0], nodeRunners[1]
+
+	local := NewBlock(Block{}, "state", "tx-a")
+	local.Save(nr.Storage())
+	NewFinality(local).Save(nr.Storage())
+
+	announce := func(block Block) BlockAnnouncement {
+		ba := NewBlockAnnouncement(other.Node().Address(), block)
+		ba.Sign(other.Node().Keypair(), networkID)
+		return ba
+	}
+
+	// the block of consensus has the same hash in every node
+	same := NewBlockAt(Block{}, "state", local.Confirmed, "tx-a")
+	if same.Hash != local.Hash {
+		t.Error("same block must have the same hash")
+		return
+	}
+	if err := nr.handleBlockAnnouncement(announce(same)); err != nil {
+		t.Error(err)
+		return
+	}
+	if nr.ForksDetected() != 0 {
+		t.Error("same block must not be fork")
+		return
+	}
+
+	// not finalized yet
+	nr.handleBlockAnnouncement(announce(NewBlock(local, "other", "tx-c")))
+	if nr.ForksDetected() != 0 {
+		t.Error("block above finality must not be checked")
+		return
+	}
+
+	conflicted := announce(NewBlock(Block{}, "other", "tx-b"))
+	if err := conflicted.IsWellFormed(networkID); err != nil {
+		t.Error(err)
+		return
+	}
+	for i := 0; i < 2; i++ {
+		if err := nr.handleBlockAnnouncement(conflicted); err != nil {
+			t.Error(err)
+			return
+		}
+	}
+	if nr.ForksDetected() != 1 {
+		t.Errorf("fork must be detected once: %d", nr.ForksDetected())
+		return
+	}
+
+	evidences, _ := GetForkEvidences(nr.Storage(), 10)
+	if len(evidences) != 1 || evidences[0].Local.Hash != local.Hash || evidences[0].Remote.Hash != conflicted.B.Block.Hash {
+		t.Errorf("wrong evidences: %v", evidences)
+		return
+	}
+
+	// unknown validator
+	kp, _ := keypair.Random()
+	unknown := NewBlockAnnouncement(kp.Address(), conflicted.B.Block)
+	unknown.Sign(kp, networkID)
+	if err := nr.handleBlockAnnouncement(unknown); err != sebakerror.ErrorBlockFromUnknownValidator {
+		t.Error("block from unknown validator must be refused")
+		return
+	}
+
+	// tampered block
+	tampered := conflicted
+	tampered.B.Block.StateHash = "tampered"
+	if err := tampered.IsWellFormed(networkID); err == nil {
+		t.Error("tampered announcement must be refused")
+		return
+	}
+
+	handler := nr.APIHandlers()[APIVersionPrefix+GetAdminForksPattern]
+	req := httptest.NewRequest("GET", APIVersionPrefix+GetAdminForksPattern, nil)
+	w := httptest.NewRecorder()
+	handler(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("failed to get forks: %d", w.Code)
+		return
+	}
+
+	var response ForksResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Error(err)
+		return
+	}
+	if response.Detected != 1 || len(response.Evidences) != 1 || response.Evidences[0].NodeKey != other.Node().Address() {
+		t.Errorf("wrong forks: %v", response)
+		return
+	}
+}
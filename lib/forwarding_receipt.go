@@ -0,0 +1,157 @@
+package sebak
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/btcsuite/btcutil/base58"
+	"github.com/stellar/go/keypair"
+
+	"boscoin.io/sebak/lib/common"
+	"boscoin.io/sebak/lib/error"
+	"boscoin.io/sebak/lib/network"
+	"boscoin.io/sebak/lib/storage"
+)
+
+// ForwardingReceipt is the proof, which the node signs, that it forwarded the
+// submitted transaction to the validators; which validators it reached and
+// when. With `SetForwardingReceipts()`, the node, which receives the
+// transaction from the API, forwards it to the connected validators at once
+// and returns the receipt to the client, so the integrators can prove the
+// submission in the dispute. The receipt is stored by,
+//  * 'fr-<transaction hash>': `ForwardingReceipt`
+
+const ForwardingReceiptPrefixHash string = "fr-"
+
+type ForwardedValidator struct {
+	Address   string `json:"address"`
+	Forwarded string `json:"forwarded"`
+}
+
+type ForwardingReceipt struct {
+	H ForwardingReceiptHeader
+	B ForwardingReceiptBody
+}
+
+type ForwardingReceiptHeader struct {
+	Hash      string `json:"hash"`
+	Signature string `json:"signature"`
+}
+
+type ForwardingReceiptBody struct {
+	Transaction string               `json:"transaction"`
+	NodeKey     string               `json:"node_key"`
+	Received    string               `json:"received"`
+	Validators  []ForwardedValidator `json:"validators"`          // the reached validators
+	Unreached   []string             `json:"unreached,omitempty"` // the validators, which the sending failed
+}
+
+func (rb ForwardingReceiptBody) MakeHashString() string {
+	return base58.Encode(sebakcommon.MustMakeObjectHash(rb))
+}
+
+func NewForwardingReceipt(nodeKey, txHash string, received time.Time, results []sebaknetwork.ForwardResult) ForwardingReceipt {
+	body := ForwardingReceiptBody{
+		Transaction: txHash,
+		NodeKey:     nodeKey,
+		Received:    received.Format(time.RFC3339Nano),
+		Validators:  []ForwardedValidator{},
+	}
+	for _, result := range results {
+		if result.Err != nil {
+			body.Unreached = append(body.Unreached, result.Address)
+			continue
+		}
+		body.Validators = append(body.Validators, ForwardedValidator{
+			Address:   result.Address,
+			Forwarded: result.Forwarded.Format(time.RFC3339Nano),
+		})
+	}
+
+	return ForwardingReceipt{
+		H: ForwardingReceiptHeader{Hash: body.MakeHashString()},
+		B: body,
+	}
+}
+
+func (fr *ForwardingReceipt) Sign(kp keypair.KP, networkID []byte) {
+	fr.H.Hash = fr.B.MakeHashString()
+	signature, _ := kp.Sign(append(networkID, []byte(fr.H.Hash)...))
+
+	fr.H.Signature = base58.Encode(signature)
+}
+
+// IsWellFormed checks the hash and the signature of the node in receipt.
+func (fr ForwardingReceipt) IsWellFormed(networkID []byte) (err error) {
+	if fr.H.Hash != fr.B.MakeHashString() {
+		err = sebakerror.ErrorHashDoesNotMatch
+		return
+	}
+
+	var kp keypair.KP
+	if kp, err = keypair.Parse(fr.B.NodeKey); err != nil {
+		err = sebakerror.ErrorBadPublicAddress
+		return
+	}
+
+	if err = kp.Verify(append(networkID, []byte(fr.H.Hash)...), base58.Decode(fr.H.Signature)); err != nil {
+		err = sebakerror.ErrorSignatureVerificationFailed
+		return
+	}
+
+	return
+}
+
+func (fr ForwardingReceipt) Serialize() (encoded []byte, err error) {
+	encoded, err = json.Marshal(fr)
+	return
+}
+
+func GetForwardingReceiptKey(txHash string) string {
+	return fmt.Sprintf("%s%s", ForwardingReceiptPrefixHash, txHash)
+}
+
+// Save stores the receipt; the receipt of the transaction, which is
+// submitted again is replaced by the latest one.
+func (fr ForwardingReceipt) Save(st *sebakstorage.LevelDBBackend) (err error) {
+	key := GetForwardingReceiptKey(fr.B.Transaction)
+
+	var exists bool
+	if exists, err = st.Has(key); err != nil {
+		return
+	}
+
+	if exists {
+		err = st.Set(key, fr)
+	} else {
+		err = st.New(key, fr)
+	}
+
+	return
+}
+
+func GetForwardingReceipt(st *sebakstorage.LevelDBBackend, txHash string) (fr ForwardingReceipt, found bool, err error) {
+	key := GetForwardingReceiptKey(txHash)
+	if found, err = st.Has(key); err != nil || !found {
+		return
+	}
+
+	err = st.Get(key, &fr)
+
+	return
+}
+
+// forwardTransaction forwards the submitted transaction to the connected
+// validators and stores the signed receipt.
+func (nr *NodeRunner) forwardTransaction(tx Transaction, received time.Time) ForwardingReceipt {
+	results := nr.connectionManager.ForwardTransaction(tx)
+
+	receipt := NewForwardingReceipt(nr.currentNode.Address(), tx.GetHash(), received, results)
+	receipt.Sign(nr.currentNode.Keypair(), nr.networkID)
+	if err := receipt.Save(nr.storage); err != nil {
+		nr.log.Error("failed to save forwarding receipt", "transaction", tx.GetHash(), "error", err)
+	}
+
+	return receipt
+}
@@ -0,0 +1,101 @@
+package sebak
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stellar/go/keypair"
+
+	"boscoin.io/sebak/lib/error"
+	"boscoin.io/sebak/lib/network"
+	"boscoin.io/sebak/lib/storage"
+)
+
+func TestForwardingReceipt(t *testing.T) {
+	st, _ := sebakstorage.NewTestMemoryLevelDBBackend()
+	defer st.Close()
+
+	kp, _ := keypair.Random()
+	reached, _ := keypair.Random()
+	unreached, _ := keypair.Random()
+
+	results := []sebaknetwork.ForwardResult{
+		{Address: reached.Address(), Forwarded: time.Now()},
+		{Address: unreached.Address(), Forwarded: time.Now(), Err: errors.New("refused")},
+	}
+	receipt := NewForwardingReceipt(kp.Address(), "tx", time.Now(), results)
+	receipt.Sign(kp, networkID)
+
+	if err := receipt.IsWellFormed(networkID); err != nil {
+		t.Error(err)
+		return
+	}
+	if len(receipt.B.Validators) != 1 || receipt.B.Validators[0].Address != reached.Address() || receipt.B.Unreached[0] != unreached.Address() {
+		t.Errorf("wrong receipt: %v", receipt.B)
+		return
+	}
+
+	// the receipt can not be changed after it is signed
+	forged := receipt
+	forged.B.Unreached = nil
+	if err := forged.IsWellFormed(networkID); err != sebakerror.ErrorHashDoesNotMatch {
+		t.Errorf("forged receipt must be refused: %v", err)
+		return
+	}
+	forged.H.Hash = forged.B.MakeHashString()
+	if err := forged.IsWellFormed(networkID); err != sebakerror.ErrorSignatureVerificationFailed {
+		t.Errorf("forged receipt must be refused: %v", err)
+		return
+	}
+
+	if err := receipt.Save(st); err != nil {
+		t.Error(err)
+		return
+	}
+	if saved, found, _ := GetForwardingReceipt(st, "tx"); !found || saved.H.Signature != receipt.H.Signature {
+		t.Errorf("wrong saved receipt: %v", saved)
+		return
+	}
+}
+
+func TestNodeRunnerForwardTransaction(t *testing.T) {
+	defer sebaknetwork.CleanUpMemoryNetwork()
+
+	nodeRunners := createNodeRunnersWithReady(2)
+	for _, nr := range nodeRunners {
+		defer nr.Stop()
+	}
+	nr := nodeRunners[0]
+
+	kp, _ := keypair.Random()
+	tx := makeTransaction(kp)
+	receipt := nr.forwardTransaction(tx, time.Now())
+	if err := receipt.IsWellFormed(networkID); err != nil {
+		t.Error(err)
+		return
+	}
+	if receipt.B.NodeKey != nr.Node().Address() || len(receipt.B.Validators) != 1 || receipt.B.Validators[0].Address != nodeRunners[1].Node().Address() {
+		t.Errorf("wrong receipt: %v", receipt.B)
+		return
+	}
+
+	w := httptest.NewRecorder()
+	nr.handleAPITransaction(w, httptest.NewRequest("GET", APIVersionPrefix+GetTransactionsPattern+tx.GetHash()+"/"+GetTransactionReceiptSubPattern, nil))
+	var saved ForwardingReceipt
+	json.Unmarshal(w.Body.Bytes(), &saved)
+	if w.Code != http.StatusOK || saved.H.Hash != receipt.H.Hash || saved.IsWellFormed(networkID) != nil {
+		t.Errorf("wrong receipt: %d %s", w.Code, w.Body.String())
+		return
+	}
+
+	w = httptest.NewRecorder()
+	nr.handleAPITransaction(w, httptest.NewRequest("GET", APIVersionPrefix+GetTransactionsPattern+"unknown/"+GetTransactionReceiptSubPattern, nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("unknown receipt must be not found: %d", w.Code)
+		return
+	}
+}
@@ -0,0 +1,222 @@
+package sebak
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"boscoin.io/sebak/lib/common"
+	"boscoin.io/sebak/lib/error"
+	"boscoin.io/sebak/lib/storage"
+)
+
+// FrozenBalances are the parts of account balance, which are locked by
+// `OperationFreeze` for the number of blocks, like the stake of membership.
+// The frozen balance is still the balance of account, but it can not be spent
+// until it is released by `OperationUnfreeze` after it's `UnlockHeight`. The
+// storage should support,
+//  * find by `Address`
+//  * 'fz-<FrozenBalances.Address>': `FrozenBalances`
+
+const FrozenBalancesPrefixAddress string = "fz-" // fz-<FrozenBalances.Address>
+
+// MaxFrozenBalances is the maximum number of frozen balances of account.
+const MaxFrozenBalances int = 10
+
+type FrozenBalance struct {
+	Amount       Amount `json:"amount"`
+	Height       uint64 `json:"height"` // height of the block, which froze it
+	UnlockHeight uint64 `json:"unlock_height"`
+}
+
+func (f FrozenBalance) IsUnlocked(height uint64) bool {
+	return height >= f.UnlockHeight
+}
+
+type FrozenBalances struct {
+	Address string
+	Frozen  []FrozenBalance
+}
+
+func GetFrozenBalancesKey(address string) string {
+	return fmt.Sprintf("%s%s", FrozenBalancesPrefixAddress, address)
+}
+
+func (fb FrozenBalances) Save(st *sebakstorage.LevelDBBackend) (err error) {
+	key := GetFrozenBalancesKey(fb.Address)
+	if err = UpdateStateHash(st, key, fb); err != nil {
+		return
+	}
+
+	var exists bool
+	if exists, err = st.Has(key); err != nil {
+		return
+	}
+
+	if exists {
+		err = st.Set(key, fb)
+	} else {
+		err = st.New(key, fb)
+	}
+
+	return
+}
+
+// GetFrozenBalances returns the frozen balances of account; `found` is
+// `false`, if nothing is frozen.
+func GetFrozenBalances(st *sebakstorage.LevelDBBackend, address string) (fb FrozenBalances, found bool, err error) {
+	key := GetFrozenBalancesKey(address)
+	if found, err = st.Has(key); err != nil || !found {
+		return
+	}
+
+	err = st.Get(key, &fb)
+
+	return
+}
+
+func RemoveFrozenBalances(st *sebakstorage.LevelDBBackend, address string) (err error) {
+	key := GetFrozenBalancesKey(address)
+	if err = UpdateStateHash(st, key, nil); err != nil {
+		return
+	}
+
+	return st.Remove(key)
+}
+
+// Total returns the sum of frozen balances, including the unlocked ones; they
+// are frozen until they are released.
+func (fb FrozenBalances) Total() (total Amount) {
+	for _, f := range fb.Frozen {
+		total += f.Amount
+	}
+
+	return
+}
+
+// Unlocked returns the sum of frozen balances, which can be released at
+// `height`.
+func (fb FrozenBalances) Unlocked(height uint64) (unlocked Amount) {
+	for _, f := range fb.Frozen {
+		if f.IsUnlocked(height) {
+			unlocked += f.Amount
+		}
+	}
+
+	return
+}
+
+// Freeze locks `amount` at `height` until `blocks` blocks pass.
+func (fb *FrozenBalances) Freeze(amount Amount, height, blocks uint64) (err error) {
+	if len(fb.Frozen) >= MaxFrozenBalances {
+		err = sebakerror.ErrorTooManyFrozenBalances
+		return
+	}
+
+	fb.Frozen = append(fb.Frozen, FrozenBalance{
+		Amount:       amount,
+		Height:       height,
+		UnlockHeight: height + blocks,
+	})
+
+	return
+}
+
+// Release releases `amount` from the unlocked balances at `height`; the older
+// one is released first and the rest of the partially released one remains
+// frozen.
+func (fb *FrozenBalances) Release(amount Amount, height uint64) (err error) {
+	if fb.Unlocked(height) < amount {
+		err = sebakerror.ErrorFrozenBalanceNotUnlocked
+		return
+	}
+
+	var frozen []FrozenBalance
+	for _, f := range fb.Frozen {
+		if amount > 0 && f.IsUnlocked(height) {
+			if f.Amount <= amount {
+				amount -= f.Amount
+				continue
+			}
+			f.Amount -= amount
+			amount = 0
+		}
+		frozen = append(frozen, f)
+	}
+	fb.Frozen = frozen
+
+	return
+}
+
+// GetFrozenAmount returns the sum of frozen balances of account.
+func GetFrozenAmount(st *sebakstorage.LevelDBBackend, address string) (amount Amount, err error) {
+	var fb FrozenBalances
+	if fb, _, err = GetFrozenBalances(st, address); err != nil {
+		return
+	}
+
+	amount = fb.Total()
+
+	return
+}
+
+// CheckFrozenBalance checks the spendable balance of source, `balance` except
+// the frozen balances covers the amount of transaction and it's freezes; the
+// unfreezes of the transaction are not counted.
+func CheckFrozenBalance(st *sebakstorage.LevelDBBackend, tx Transaction, balance Amount) (err error) {
+	var frozen Amount
+	if frozen, err = GetFrozenAmount(st, tx.B.Source); err != nil {
+		return
+	}
+
+	var required Amount
+	if required, err = frozen.Add(tx.FreezingAmount()); err != nil {
+		return
+	}
+	if required, err = required.Add(tx.TotalAmount(true)); err != nil {
+		return
+	}
+	if balance < required {
+		err = sebakerror.ErrorAccountBalanceFrozen
+		return
+	}
+
+	return
+}
+
+// getNextBlockHeight returns the height of the block, which will be made by
+// the next transaction.
+func getNextBlockHeight(st *sebakstorage.LevelDBBackend) (height uint64, err error) {
+	var latest Block
+	if latest, err = GetLatestBlock(st); err != nil {
+		return
+	}
+
+	height = latest.Height + 1
+
+	return
+}
+
+// stateFrozenBalancesLeaf is the leaf of `FrozenBalances` in the state hash.
+type stateFrozenBalancesLeaf struct {
+	Kind    string
+	Address string
+	Frozen  []FrozenBalance
+}
+
+func init() {
+	RegisterStateHashLeaf(StateHashLeaf{
+		Prefix: FrozenBalancesPrefixAddress,
+		Leaf: func(_ string, value []byte) ([]byte, error) {
+			var fb FrozenBalances
+			if err := json.Unmarshal(value, &fb); err != nil {
+				return nil, err
+			}
+
+			return sebakcommon.MustJSONMarshal(stateFrozenBalancesLeaf{
+				Kind:    "frozen-balances",
+				Address: fb.Address,
+				Frozen:  fb.Frozen,
+			}), nil
+		},
+	})
+}
@@ -0,0 +1,184 @@
+package sebak
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stellar/go/keypair"
+
+	"boscoin.io/sebak/lib/error"
+	"boscoin.io/sebak/lib/storage"
+)
+
+func TestOperationBodyFreezeIsWellFormed(t *testing.T) {
+	for _, body := range []OperationBodyFreeze{NewOperationBodyFreeze(0, 10), NewOperationBodyFreeze(BaseFee, 0)} {
+		if err := body.IsWellFormed(networkID); err == nil {
+			t.Errorf("'%v' must not be well-formed", body)
+			return
+		}
+	}
+	if err := NewOperationBodyUnfreeze(0).IsWellFormed(networkID); err == nil {
+		t.Error("unfreeze without amount must not be well-formed")
+		return
+	}
+
+	freeze, _ := NewOperation(OperationFreeze, NewOperationBodyFreeze(BaseFee, 10))
+	unfreeze, _ := NewOperation(OperationUnfreeze, NewOperationBodyUnfreeze(BaseFee))
+	for _, op := range []Operation{freeze, unfreeze} {
+		encoded, _ := op.Serialize()
+		decoded, err := NewOperationFromBytes(encoded)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if decoded.MakeHashString() != op.MakeHashString() {
+			t.Errorf("wrong operation: %v", decoded)
+			return
+		}
+	}
+}
+
+func TestFrozenBalancesRelease(t *testing.T) {
+	var fb FrozenBalances
+	fb.Freeze(Amount(100), 1, 10)
+	fb.Freeze(Amount(50), 5, 10)
+
+	if fb.Total() != Amount(150) || fb.Unlocked(10) != Amount(0) || fb.Unlocked(11) != Amount(100) {
+		t.Errorf("wrong frozen balances: %v", fb)
+		return
+	}
+	if err := fb.Release(Amount(101), 11); err != sebakerror.ErrorFrozenBalanceNotUnlocked {
+		t.Errorf("locked balance must not be released: %v", err)
+		return
+	}
+
+	// the partially released one remains
+	if err := fb.Release(Amount(120), 15); err != nil {
+		t.Error(err)
+		return
+	}
+	if len(fb.Frozen) != 1 || fb.Frozen[0].Amount != Amount(30) || fb.Frozen[0].UnlockHeight != 15 {
+		t.Errorf("wrong frozen balances: %v", fb)
+		return
+	}
+
+	for i := len(fb.Frozen); i < MaxFrozenBalances; i++ {
+		if err := fb.Freeze(Amount(1), 20, 1); err != nil {
+			t.Error(err)
+			return
+		}
+	}
+	if err := fb.Freeze(Amount(1), 20, 1); err != sebakerror.ErrorTooManyFrozenBalances {
+		t.Errorf("too many frozen balances must be refused: %v", err)
+		return
+	}
+}
+
+func TestFinishOperationFreeze(t *testing.T) {
+	st, _ := sebakstorage.NewTestMemoryLevelDBBackend()
+	defer st.Close()
+
+	kp, _ := keypair.Random()
+	target, _ := keypair.Random()
+	balance := BaseFee * 100
+	NewBlockAccount(kp.Address(), balance, uuid.New().String()).Save(st)
+
+	genesis := NewBlock(Block{}, "")
+	genesis.Save(st)
+
+	freeze, _ := NewOperation(OperationFreeze, NewOperationBodyFreeze(BaseFee*90, 2))
+	tx, _ := NewTransaction(kp.Address(), uuid.New().String(), freeze)
+	tx.Sign(kp, networkID)
+	if err := CheckFrozenBalance(st, tx, balance); err != nil {
+		t.Error(err)
+		return
+	}
+	if err := FinishOperation(st, tx, freeze); err != nil {
+		t.Error(err)
+		return
+	}
+	if frozen, _ := GetFrozenAmount(st, kp.Address()); frozen != BaseFee*90 {
+		t.Errorf("wrong frozen amount: %v", frozen)
+		return
+	}
+
+	// the payment over the spendable balance is refused
+	payment := makeTransactionPayment(kp, target.Address(), BaseFee*10)
+	if err := CheckFrozenBalance(st, payment, balance); err != sebakerror.ErrorAccountBalanceFrozen {
+		t.Errorf("frozen balance must not be spent: %v", err)
+		return
+	}
+	payment = makeTransactionPayment(kp, target.Address(), BaseFee*9)
+	if err := CheckFrozenBalance(st, payment, balance); err != nil {
+		t.Error(err)
+		return
+	}
+
+	unfreeze, _ := NewOperation(OperationUnfreeze, NewOperationBodyUnfreeze(BaseFee*90))
+	tx, _ = NewTransaction(kp.Address(), uuid.New().String(), unfreeze)
+	tx.Sign(kp, networkID)
+
+	// frozen at the height 2, so it is unlocked at 4
+	latest := NewBlock(genesis, "")
+	latest.Save(st)
+	if err := ValidateTransactionOperations(st, tx); err == nil {
+		t.Error("locked balance must not be unfrozen")
+		return
+	}
+	if err := FinishOperation(st, tx, unfreeze); err != sebakerror.ErrorFrozenBalanceNotUnlocked {
+		t.Errorf("locked balance must not be unfrozen: %v", err)
+		return
+	}
+
+	NewBlock(latest, "").Save(st)
+	if err := ValidateTransactionOperations(st, tx); err != nil {
+		t.Error(err)
+		return
+	}
+	if err := FinishOperation(st, tx, unfreeze); err != nil {
+		t.Error(err)
+		return
+	}
+	if _, found, _ := GetFrozenBalances(st, kp.Address()); found {
+		t.Error("released frozen balances must be removed")
+		return
+	}
+}
+
+// TestFrozenBalancesStateHash checks, the frozen balances are the part of
+// state hash.
+func TestFrozenBalancesStateHash(t *testing.T) {
+	st, _ := sebakstorage.NewTestMemoryLevelDBBackend()
+	defer st.Close()
+
+	kp, _ := keypair.Random()
+	NewBlockAccount(kp.Address(), BaseFee.MustAdd(BaseFee), uuid.New().String()).Save(st)
+	hash, _ := MakeStateHash(st)
+
+	fb := FrozenBalances{Address: kp.Address()}
+	fb.Freeze(BaseFee, 1, 10)
+	fb.Save(st)
+	frozen, _ := MakeStateHash(st)
+	if frozen == hash {
+		t.Error("frozen balances must change state hash")
+		return
+	}
+
+	fb.Release(BaseFee/2, 11)
+	fb.Save(st)
+	released, _ := MakeStateHash(st)
+	if released == frozen {
+		t.Error("released balance must change state hash")
+		return
+	}
+	if summed, _ := makeStateHashFromState(st); summed != released {
+		t.Error("stored state hash must be same with the sum of state")
+		return
+	}
+
+	RemoveFrozenBalances(st, kp.Address())
+	if removed, _ := MakeStateHash(st); removed != hash {
+		t.Error("removed frozen balances must be subtracted from state hash")
+		return
+	}
+}
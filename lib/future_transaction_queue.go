@@ -0,0 +1,184 @@
+package sebak
+
+import (
+	"sync"
+	"time"
+
+	"boscoin.io/sebak/lib/error"
+)
+
+const (
+	DefaultFutureTransactionQueueMaxSize       int           = 1000
+	DefaultFutureTransactionQueueMaxPerAccount int           = 10
+	DefaultFutureTransactionMaxAge             time.Duration = time.Minute * 10
+)
+
+// FutureTransactionItem is the submitted transaction held in
+// `FutureTransactionQueue` with it's raw body, so it is submitted again as
+// it was.
+type FutureTransactionItem struct {
+	Transaction Transaction
+	Body        []byte
+	Received    time.Time
+}
+
+// FutureTransactionQueue holds the submitted transactions, whose checkpoint
+// is not reached yet; it is neither the latest checkpoint of source account
+// nor the next checkpoint of the transaction in `TransactionPool`, like the
+// transactions of one client, which arrive out of order. When the checkpoint
+// of source is reached, the transaction is taken by `Take()` and submitted
+// again. The queue is bounded by `maxSize` and `maxPerAccount`, and the
+// transaction, which is not admitted in `maxAge`, is dropped. It is kept only
+// in memory.
+type FutureTransactionQueue struct {
+	sync.Mutex
+
+	items map[ /* Transaction.B.Source */ string]map[ /* Transaction.B.Checkpoint */ string]FutureTransactionItem
+	count int
+
+	maxSize       int
+	maxPerAccount int
+	maxAge        time.Duration
+}
+
+func NewFutureTransactionQueue() *FutureTransactionQueue {
+	return &FutureTransactionQueue{
+		items:         map[string]map[string]FutureTransactionItem{},
+		maxSize:       DefaultFutureTransactionQueueMaxSize,
+		maxPerAccount: DefaultFutureTransactionQueueMaxPerAccount,
+		maxAge:        DefaultFutureTransactionMaxAge,
+	}
+}
+
+func (q *FutureTransactionQueue) Len() int {
+	q.Lock()
+	defer q.Unlock()
+
+	return q.count
+}
+
+// HasSource checks the queue has the transactions of source account.
+func (q *FutureTransactionQueue) HasSource(source string) bool {
+	q.Lock()
+	defer q.Unlock()
+
+	_, found := q.items[source]
+	return found
+}
+
+// Add holds the transaction; the same transaction is added only once, and
+// the other transaction of the same checkpoint is refused as double spend.
+func (q *FutureTransactionQueue) Add(tx Transaction, body []byte, received time.Time) (err error) {
+	q.Lock()
+	defer q.Unlock()
+
+	if item, found := q.items[tx.B.Source][tx.B.Checkpoint]; found {
+		if item.Transaction.GetHash() != tx.GetHash() {
+			err = sebakerror.ErrorTransactionDoubleSpend
+		}
+		return
+	}
+	if q.maxPerAccount > 0 && len(q.items[tx.B.Source]) >= q.maxPerAccount {
+		err = sebakerror.ErrorTransactionPoolAccountLimit
+		return
+	}
+	if q.maxSize > 0 && q.count >= q.maxSize {
+		err = sebakerror.ErrorTransactionPoolFull
+		return
+	}
+
+	if _, found := q.items[tx.B.Source]; !found {
+		q.items[tx.B.Source] = map[string]FutureTransactionItem{}
+	}
+	q.items[tx.B.Source][tx.B.Checkpoint] = FutureTransactionItem{Transaction: tx, Body: body, Received: received}
+	q.count++
+
+	return
+}
+
+// Take removes and returns the transactions of source, which use one of
+// `checkpoints`.
+func (q *FutureTransactionQueue) Take(source string, checkpoints ...string) (items []FutureTransactionItem) {
+	q.Lock()
+	defer q.Unlock()
+
+	for _, checkpoint := range checkpoints {
+		item, found := q.items[source][checkpoint]
+		if !found {
+			continue
+		}
+		q.remove(source, checkpoint)
+		items = append(items, item)
+	}
+
+	return
+}
+
+// RemoveExpired removes the transactions, which are held longer than
+// `maxAge` at `now`.
+func (q *FutureTransactionQueue) RemoveExpired(now time.Time) (expired []string) {
+	q.Lock()
+	defer q.Unlock()
+
+	for source, items := range q.items {
+		for checkpoint, item := range items {
+			if now.Sub(item.Received) <= q.maxAge {
+				continue
+			}
+			q.remove(source, checkpoint)
+			expired = append(expired, item.Transaction.GetHash())
+		}
+	}
+
+	return
+}
+
+func (q *FutureTransactionQueue) remove(source, checkpoint string) {
+	delete(q.items[source], checkpoint)
+	if len(q.items[source]) < 1 {
+		delete(q.items, source)
+	}
+	q.count--
+}
+
+// queueFutureTransaction holds the transaction, whose checkpoint is not
+// reached yet, in `FutureTransactionQueue`.
+func (nr *NodeRunner) queueFutureTransaction(tx Transaction, body []byte, received time.Time) (err error) {
+	if err = nr.futureTransactions.Add(tx, body, received); err != nil {
+		return
+	}
+
+	nr.transactionStatuses.Queued(tx.GetHash())
+	nr.log.Debug("queued in future transactions", "transaction", tx.GetHash(), "checkpoint", tx.B.Checkpoint)
+
+	return
+}
+
+// admitFutureTransactions submits the queued transactions of source again,
+// whose checkpoint is reached by the latest checkpoint of account or the next
+// checkpoint of the transaction in `TransactionPool`. It is called, when the
+// transaction of source is pushed into pool or the block is applied; the
+// admitted transaction admits the next one, when it is pushed into pool.
+func (nr *NodeRunner) admitFutureTransactions(source string) {
+	if !nr.futureTransactions.HasSource(source) {
+		return
+	}
+
+	ba, err := GetBlockAccount(nr.storage, source)
+	if err != nil {
+		return
+	}
+	checkpoints := []string{ba.Checkpoint}
+	for _, tx := range nr.transactionPool.BySource(source) {
+		checkpoints = append(checkpoints, tx.NextCheckpoint())
+	}
+
+	for _, item := range nr.futureTransactions.Take(source, checkpoints...) {
+		if _, _, err = nr.submitTransaction(item.Body); err != nil {
+			nr.transactionStatuses.Rejected(item.Transaction.GetHash(), err)
+			nr.log.Debug("future transaction is not admitted", "transaction", item.Transaction.GetHash(), "error", err)
+			continue
+		}
+		nr.log.Debug("future transaction admitted", "transaction", item.Transaction.GetHash())
+	}
+}
@@ -0,0 +1,64 @@
+package sebak
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stellar/go/keypair"
+
+	"boscoin.io/sebak/lib/error"
+)
+
+func TestFutureTransactionQueue(t *testing.T) {
+	q := NewFutureTransactionQueue()
+	q.maxPerAccount = 2
+
+	kp, _ := keypair.Random()
+	target, _ := keypair.Random()
+	now := time.Now()
+
+	tx := makeTransactionPayment(kp, target.Address(), BaseFee)
+	if err := q.Add(tx, nil, now); err != nil {
+		t.Error(err)
+		return
+	}
+	// the same transaction is added only once
+	if err := q.Add(tx, nil, now); err != nil || q.Len() != 1 {
+		t.Errorf("same transaction must be added once: %v", err)
+		return
+	}
+
+	another := makeTransactionPayment(kp, target.Address(), BaseFee*2)
+	another.B.Checkpoint = tx.B.Checkpoint
+	another.H.Hash = another.B.MakeHashString()
+	if err := q.Add(another, nil, now); err != sebakerror.ErrorTransactionDoubleSpend {
+		t.Errorf("same checkpoint must be refused: %v", err)
+		return
+	}
+
+	next := makeTransactionPayment(kp, target.Address(), BaseFee)
+	if err := q.Add(next, nil, now.Add(time.Minute)); err != nil {
+		t.Error(err)
+		return
+	}
+	if err := q.Add(makeTransactionPayment(kp, target.Address(), BaseFee), nil, now); err != sebakerror.ErrorTransactionPoolAccountLimit {
+		t.Errorf("too many transactions of account must be refused: %v", err)
+		return
+	}
+
+	items := q.Take(kp.Address(), "unknown", tx.B.Checkpoint)
+	if len(items) != 1 || items[0].Transaction.GetHash() != tx.GetHash() || q.Len() != 1 {
+		t.Errorf("wrong taken transactions: %v", items)
+		return
+	}
+
+	if expired := q.RemoveExpired(now.Add(DefaultFutureTransactionMaxAge)); len(expired) != 0 {
+		t.Errorf("transaction must not be expired: %v", expired)
+		return
+	}
+	expired := q.RemoveExpired(now.Add(DefaultFutureTransactionMaxAge + time.Minute*2))
+	if len(expired) != 1 || expired[0] != next.GetHash() || q.HasSource(kp.Address()) {
+		t.Errorf("wrong expired transactions: %v", expired)
+		return
+	}
+}
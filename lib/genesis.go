@@ -0,0 +1,307 @@
+package sebak
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/btcsuite/btcutil/base58"
+	"github.com/stellar/go/keypair"
+
+	"boscoin.io/sebak/lib/common"
+	"boscoin.io/sebak/lib/error"
+	"boscoin.io/sebak/lib/storage"
+)
+
+// Genesis is the configuration of new network, which is written in
+// `genesis.json`. The applied genesis is stored by,
+//  * 'gn-genesis': `Genesis`
+
+const GenesisKey string = "gn-genesis"
+
+type GenesisAccount struct {
+	Address string `json:"address"`
+	Balance Amount `json:"balance"`
+
+	// Checkpoint is optional; by default it is derived from the network id
+	// and address, so every node gets the same genesis state.
+	Checkpoint string `json:"checkpoint,omitempty"`
+}
+
+type GenesisValidator struct {
+	Address  string `json:"address"`
+	Endpoint string `json:"endpoint"`
+	Alias    string `json:"alias,omitempty"`
+}
+
+type GenesisConsensus struct {
+	BlockTime       string `json:"block_time"` // like '100ms'
+	ThresholdINIT   int    `json:"threshold_init"`
+	ThresholdSIGN   int    `json:"threshold_sign"`
+	ThresholdACCEPT int    `json:"threshold_accept"`
+
+	UpgradeThreshold int    `json:"upgrade_threshold,omitempty"`
+	UpgradeWindow    uint64 `json:"upgrade_window,omitempty"`
+	UpgradeDelay     uint64 `json:"upgrade_delay,omitempty"`
+
+	MinimumBalance Amount `json:"minimum_balance,omitempty"`
+
+	FeePerOperation Amount `json:"fee_per_operation,omitempty"`
+	FeePerByte      Amount `json:"fee_per_byte,omitempty"`
+	FeePerDataEntry Amount `json:"fee_per_data_entry,omitempty"`
+
+	RewardAccount  string `json:"reward_account,omitempty"`
+	RewardAmount   Amount `json:"reward_amount,omitempty"`
+	RewardInterval uint64 `json:"reward_interval,omitempty"`
+	RewardSource   string `json:"reward_source,omitempty"`
+
+	MaxOperationsPerTransaction uint64 `json:"max_operations_per_transaction,omitempty"`
+	MaxTransactionsPerBlock     uint64 `json:"max_transactions_per_block,omitempty"`
+}
+
+type Genesis struct {
+	NetworkID  string             `json:"network_id"`
+	Accounts   []GenesisAccount   `json:"accounts"`
+	Validators []GenesisValidator `json:"validators"`
+	Consensus  GenesisConsensus   `json:"consensus"`
+}
+
+func NewGenesis(networkID string) Genesis {
+	p := NewDefaultNetworkParameters()
+	return Genesis{
+		NetworkID:  networkID,
+		Accounts:   []GenesisAccount{},
+		Validators: []GenesisValidator{},
+		Consensus: GenesisConsensus{
+			BlockTime:       p.BlockTime.String(),
+			ThresholdINIT:   p.ThresholdINIT,
+			ThresholdSIGN:   p.ThresholdSIGN,
+			ThresholdACCEPT: p.ThresholdACCEPT,
+
+			UpgradeThreshold: p.UpgradeThreshold,
+			UpgradeWindow:    p.UpgradeWindow,
+			UpgradeDelay:     p.UpgradeDelay,
+		},
+	}
+}
+
+func NewGenesisFromJSON(b []byte) (g Genesis, err error) {
+	if err = json.Unmarshal(b, &g); err != nil {
+		return
+	}
+
+	return
+}
+
+func (g Genesis) Serialize() (encoded []byte, err error) {
+	encoded, err = json.MarshalIndent(g, "", "  ")
+	return
+}
+
+// NetworkParameters returns the network parameters of `Consensus`.
+func (g Genesis) NetworkParameters() (p NetworkParameters, err error) {
+	if p.BlockTime, err = time.ParseDuration(g.Consensus.BlockTime); err != nil {
+		err = fmt.Errorf("invalid `block_time`: %v", err)
+		return
+	}
+	p.ThresholdINIT = g.Consensus.ThresholdINIT
+	p.ThresholdSIGN = g.Consensus.ThresholdSIGN
+	p.ThresholdACCEPT = g.Consensus.ThresholdACCEPT
+	p.UpgradeThreshold = g.Consensus.UpgradeThreshold
+	p.UpgradeWindow = g.Consensus.UpgradeWindow
+	p.UpgradeDelay = g.Consensus.UpgradeDelay
+	p.MinimumBalance = g.Consensus.MinimumBalance
+	p.FeePerOperation = g.Consensus.FeePerOperation
+	p.FeePerByte = g.Consensus.FeePerByte
+	p.FeePerDataEntry = g.Consensus.FeePerDataEntry
+	p.RewardAccount = g.Consensus.RewardAccount
+	p.RewardAmount = g.Consensus.RewardAmount
+	p.RewardInterval = g.Consensus.RewardInterval
+	p.RewardSource = g.Consensus.RewardSource
+	p.MaxOperationsPerTransaction = g.Consensus.MaxOperationsPerTransaction
+	p.MaxTransactionsPerBlock = g.Consensus.MaxTransactionsPerBlock
+
+	err = p.IsWellFormed()
+
+	return
+}
+
+func (g Genesis) IsWellFormed() (err error) {
+	if len(g.NetworkID) < 1 {
+		err = sebakerror.ErrorGenesisEmptyNetworkID
+		return
+	}
+
+	if len(g.Accounts) < 1 {
+		err = sebakerror.ErrorGenesisNoAccounts
+		return
+	}
+
+	var total Amount
+	addresses := map[string]bool{}
+	for _, a := range g.Accounts {
+		if _, err = keypair.Parse(a.Address); err != nil {
+			err = fmt.Errorf("invalid account address, '%s': %v", a.Address, err)
+			return
+		}
+		if addresses[a.Address] {
+			err = fmt.Errorf("duplicated account, '%s'", a.Address)
+			return
+		}
+		addresses[a.Address] = true
+
+		if a.Balance < 1 {
+			err = fmt.Errorf("balance of account, '%s' must be greater than zero", a.Address)
+			return
+		}
+		if a.Balance < g.Consensus.MinimumBalance {
+			err = fmt.Errorf("balance of account, '%s' must not be lower than `minimum_balance`", a.Address)
+			return
+		}
+		if total, err = total.Add(a.Balance); err != nil {
+			return
+		}
+	}
+
+	addresses = map[string]bool{}
+	endpoints := map[string]bool{}
+	for _, v := range g.Validators {
+		if _, err = keypair.Parse(v.Address); err != nil {
+			err = fmt.Errorf("invalid validator address, '%s': %v", v.Address, err)
+			return
+		}
+		if _, err = sebakcommon.ParseNodeEndpoint(v.Endpoint); err != nil {
+			err = fmt.Errorf("invalid validator endpoint, '%s': %v", v.Endpoint, err)
+			return
+		}
+		if addresses[v.Address] || endpoints[v.Endpoint] {
+			err = fmt.Errorf("duplicated validator, '%s'", v.Address)
+			return
+		}
+		addresses[v.Address] = true
+		endpoints[v.Endpoint] = true
+	}
+
+	if _, err = g.NetworkParameters(); err != nil {
+		return
+	}
+
+	return
+}
+
+// GetValidators returns the validators of genesis except `address`, usually
+// the node itself.
+func (g Genesis) GetValidators(address string) (validators []*sebakcommon.Validator, err error) {
+	for _, v := range g.Validators {
+		if v.Address == address {
+			continue
+		}
+
+		var endpoint *sebakcommon.Endpoint
+		if endpoint, err = sebakcommon.ParseNodeEndpoint(v.Endpoint); err != nil {
+			return
+		}
+
+		var validator *sebakcommon.Validator
+		if validator, err = sebakcommon.NewValidator(v.Address, endpoint, v.Alias); err != nil {
+			return
+		}
+		validators = append(validators, validator)
+	}
+
+	return
+}
+
+// MakeGenesisCheckpoint derives the default checkpoint of genesis account.
+func MakeGenesisCheckpoint(networkID, address string) string {
+	h := sha256.Sum256([]byte(networkID + address))
+	return base58.Encode(h[:])
+}
+
+// Apply creates the accounts and stores the network parameters and genesis
+// itself. It fails if any account already exists, so it can be applied only
+// to the new storage.
+func (g Genesis) Apply(st *sebakstorage.LevelDBBackend) (err error) {
+	if err = g.IsWellFormed(); err != nil {
+		return
+	}
+
+	var exists bool
+	if exists, err = st.Has(GenesisKey); err != nil {
+		return
+	} else if exists {
+		err = sebakerror.ErrorGenesisAlreadyApplied
+		return
+	}
+
+	var p NetworkParameters
+	if p, err = g.NetworkParameters(); err != nil {
+		return
+	}
+
+	var ts *sebakstorage.LevelDBBackend
+	if ts, err = st.OpenTransaction(); err != nil {
+		return
+	}
+
+	for _, a := range g.Accounts {
+		if exists, err = ExistBlockAccount(ts, a.Address); err != nil {
+			ts.Discard()
+			return
+		} else if exists {
+			ts.Discard()
+			err = sebakerror.ErrorBlockAccountAlreadyExists
+			return
+		}
+
+		checkpoint := a.Checkpoint
+		if len(checkpoint) < 1 {
+			checkpoint = MakeGenesisCheckpoint(g.NetworkID, a.Address)
+		}
+		if err = NewBlockAccount(a.Address, a.Balance, checkpoint).Save(ts); err != nil {
+			ts.Discard()
+			return
+		}
+		entry := LedgerEntry{
+			Reason:    LedgerReasonGenesis,
+			Debit:     LedgerAccountGenesis,
+			Credit:    a.Address,
+			Amount:    a.Balance,
+			Confirmed: sebakcommon.NowISO8601(),
+		}
+		if err = SaveLedgerEntries(ts, entry); err != nil {
+			ts.Discard()
+			return
+		}
+	}
+
+	if err = p.Save(ts); err != nil {
+		ts.Discard()
+		return
+	}
+	if err = ts.New(GenesisKey, g); err != nil {
+		ts.Discard()
+		return
+	}
+
+	err = ts.Commit()
+
+	return
+}
+
+// GetGenesis loads the applied genesis; if no genesis is applied,
+// `ErrorGenesisNotApplied` is returned.
+func GetGenesis(st *sebakstorage.LevelDBBackend) (g Genesis, err error) {
+	var exists bool
+	if exists, err = st.Has(GenesisKey); err != nil {
+		return
+	} else if !exists {
+		err = sebakerror.ErrorGenesisNotApplied
+		return
+	}
+
+	err = st.Get(GenesisKey, &g)
+
+	return
+}
@@ -0,0 +1,162 @@
+package sebak
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stellar/go/keypair"
+
+	"boscoin.io/sebak/lib/error"
+	"boscoin.io/sebak/lib/storage"
+)
+
+func TestGenesisIsWellFormed(t *testing.T) {
+	kp, _ := keypair.Random()
+
+	genesis := NewGenesis("")
+	if err := genesis.IsWellFormed(); err != sebakerror.ErrorGenesisEmptyNetworkID {
+		t.Error("empty network id must be refused")
+		return
+	}
+
+	genesis = NewGenesis(string(networkID))
+	if err := genesis.IsWellFormed(); err != sebakerror.ErrorGenesisNoAccounts {
+		t.Error("genesis without accounts must be refused")
+		return
+	}
+
+	genesis.Accounts = append(genesis.Accounts, GenesisAccount{Address: kp.Address(), Balance: MaximumBalance})
+	if err := genesis.IsWellFormed(); err != nil {
+		t.Error(err)
+		return
+	}
+
+	// total balance over the maximum
+	other, _ := keypair.Random()
+	genesis.Accounts = append(genesis.Accounts, GenesisAccount{Address: other.Address(), Balance: 1})
+	if err := genesis.IsWellFormed(); err != sebakerror.ErrorMaximumBalanceReached {
+		t.Error("total balance over the maximum must be refused")
+		return
+	}
+	genesis.Accounts[0].Balance = MaximumBalance - 1
+
+	genesis.Validators = append(genesis.Validators, GenesisValidator{Address: kp.Address(), Endpoint: "https://localhost:12345"})
+	genesis.Validators = append(genesis.Validators, GenesisValidator{Address: kp.Address(), Endpoint: "https://localhost:12346"})
+	if err := genesis.IsWellFormed(); err == nil {
+		t.Error("duplicated validator must be refused")
+		return
+	}
+	genesis.Validators = genesis.Validators[:1]
+
+	genesis.Consensus.MinimumBalance = MaximumBalance
+	if err := genesis.IsWellFormed(); err == nil {
+		t.Error("balance under `minimum_balance` must be refused")
+		return
+	}
+	genesis.Consensus.MinimumBalance = 0
+
+	genesis.Consensus.BlockTime = "1ms"
+	if err := genesis.IsWellFormed(); err == nil {
+		t.Error("too short `block_time` must be refused")
+		return
+	}
+	genesis.Consensus.BlockTime = "1s"
+	genesis.Consensus.ThresholdSIGN = 101
+	if err := genesis.IsWellFormed(); err == nil {
+		t.Error("threshold over 100 must be refused")
+		return
+	}
+}
+
+func TestGenesisApply(t *testing.T) {
+	kpA, _ := keypair.Random()
+	kpB, _ := keypair.Random()
+	kpValidator, _ := keypair.Random()
+
+	genesis := NewGenesis(string(networkID))
+	genesis.Accounts = []GenesisAccount{
+		{Address: kpA.Address(), Balance: 1000},
+		{Address: kpB.Address(), Balance: 2000, Checkpoint: "checkpoint-b"},
+	}
+	genesis.Validators = []GenesisValidator{
+		{Address: kpValidator.Address(), Endpoint: "https://localhost:12345", Alias: "v0"},
+	}
+	genesis.Consensus.BlockTime = "1s"
+	genesis.Consensus.ThresholdACCEPT = 66
+
+	b, err := genesis.Serialize()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if genesis, err = NewGenesisFromJSON(b); err != nil {
+		t.Error(err)
+		return
+	}
+
+	st, _ := sebakstorage.NewTestMemoryLevelDBBackend()
+	if err = genesis.Apply(st); err != nil {
+		t.Error(err)
+		return
+	}
+
+	var ba *BlockAccount
+	if ba, err = GetBlockAccount(st, kpA.Address()); err != nil {
+		t.Error(err)
+		return
+	}
+	if ba.Balance != Amount(1000).String() || ba.Checkpoint != MakeGenesisCheckpoint(string(networkID), kpA.Address()) {
+		t.Errorf("wrong genesis account: %v", ba)
+		return
+	}
+	if ba, _ = GetBlockAccount(st, kpB.Address()); ba.Checkpoint != "checkpoint-b" {
+		t.Error("given checkpoint must be used")
+		return
+	}
+
+	var p NetworkParameters
+	if p, err = GetNetworkParameters(st); err != nil {
+		t.Error(err)
+		return
+	}
+	if p.BlockTime != time.Second || p.ThresholdACCEPT != 66 {
+		t.Errorf("wrong network parameters: %v", p)
+		return
+	}
+
+	var stored Genesis
+	if stored, err = GetGenesis(st); err != nil {
+		t.Error(err)
+		return
+	}
+	validators, _ := stored.GetValidators("")
+	if len(validators) != 1 || validators[0].Alias() != "v0" {
+		t.Error("validators of genesis must be stored")
+		return
+	}
+	if validators, _ = stored.GetValidators(kpValidator.Address()); len(validators) != 0 {
+		t.Error("the given address must be excluded")
+		return
+	}
+
+	if err = genesis.Apply(st); err != sebakerror.ErrorGenesisAlreadyApplied {
+		t.Error("genesis must be applied only once")
+		return
+	}
+
+	// existing account
+	st, _ = sebakstorage.NewTestMemoryLevelDBBackend()
+	NewBlockAccount(kpB.Address(), 1, "").Save(st)
+	if err = genesis.Apply(st); err != sebakerror.ErrorBlockAccountAlreadyExists {
+		t.Error("genesis must not be applied over the existing account")
+		return
+	}
+	if exists, _ := ExistBlockAccount(st, kpA.Address()); exists {
+		t.Error("failed genesis must not leave accounts")
+		return
+	}
+	if _, err = GetGenesis(st); err != sebakerror.ErrorGenesisNotApplied {
+		t.Error("failed genesis must not be stored")
+		return
+	}
+}
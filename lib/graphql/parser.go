@@ -0,0 +1,771 @@
+package sebakgraphql
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// The parser supports the query subset of GraphQL, which the read only API
+// needs,
+//  * query operations with name and variables; mutation and subscription are
+//  refused
+//  * fields with alias, arguments and nested selection
+//  * fragment spreads, inline fragments and `@include`, `@skip` directives
+// The type system is not parsed; the schema is defined by `Schema` in Go.
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenPunctuator
+	tokenName
+	tokenInt
+	tokenFloat
+	tokenString
+)
+
+type token struct {
+	kind  tokenKind
+	value string
+	pos   int
+}
+
+func (t token) String() string {
+	if t.kind == tokenEOF {
+		return "<EOF>"
+	}
+	return fmt.Sprintf("'%s'", t.value)
+}
+
+type lexer struct {
+	source string
+	pos    int
+}
+
+func (l *lexer) error(pos int, format string, args ...interface{}) error {
+	return fmt.Errorf("syntax error at %d: %s", pos, fmt.Sprintf(format, args...))
+}
+
+func (l *lexer) skipIgnored() {
+	for l.pos < len(l.source) {
+		switch c := l.source[l.pos]; {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',':
+			l.pos++
+		case c == '#':
+			for l.pos < len(l.source) && l.source[l.pos] != '\n' {
+				l.pos++
+			}
+		case strings.HasPrefix(l.source[l.pos:], "\ufeff"):
+			l.pos += len("\ufeff")
+		default:
+			return
+		}
+	}
+}
+
+func isNameStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func (l *lexer) next() (t token, err error) {
+	l.skipIgnored()
+
+	t.pos = l.pos
+	if l.pos >= len(l.source) {
+		t.kind = tokenEOF
+		return
+	}
+
+	c := l.source[l.pos]
+	switch {
+	case strings.HasPrefix(l.source[l.pos:], "..."):
+		t.kind, t.value = tokenPunctuator, "..."
+		l.pos += 3
+	case strings.IndexByte("!$():=@[]{}|", c) >= 0:
+		t.kind, t.value = tokenPunctuator, string(c)
+		l.pos++
+	case isNameStart(c):
+		start := l.pos
+		for l.pos < len(l.source) && (isNameStart(l.source[l.pos]) || isDigit(l.source[l.pos])) {
+			l.pos++
+		}
+		t.kind, t.value = tokenName, l.source[start:l.pos]
+	case c == '-' || isDigit(c):
+		t, err = l.number()
+	case c == '"':
+		t, err = l.string()
+	default:
+		err = l.error(l.pos, "unexpected character '%c'", c)
+	}
+
+	return
+}
+
+func (l *lexer) number() (t token, err error) {
+	start := l.pos
+	t.pos, t.kind = start, tokenInt
+
+	if l.source[l.pos] == '-' {
+		l.pos++
+	}
+	digits := func() int {
+		s := l.pos
+		for l.pos < len(l.source) && isDigit(l.source[l.pos]) {
+			l.pos++
+		}
+		return l.pos - s
+	}
+	if digits() < 1 {
+		err = l.error(start, "invalid number")
+		return
+	}
+	if l.pos < len(l.source) && l.source[l.pos] == '.' {
+		l.pos++
+		t.kind = tokenFloat
+		if digits() < 1 {
+			err = l.error(start, "invalid number")
+			return
+		}
+	}
+	if l.pos < len(l.source) && (l.source[l.pos] == 'e' || l.source[l.pos] == 'E') {
+		l.pos++
+		t.kind = tokenFloat
+		if l.pos < len(l.source) && (l.source[l.pos] == '+' || l.source[l.pos] == '-') {
+			l.pos++
+		}
+		if digits() < 1 {
+			err = l.error(start, "invalid number")
+			return
+		}
+	}
+	t.value = l.source[start:l.pos]
+
+	return
+}
+
+func (l *lexer) string() (t token, err error) {
+	start := l.pos
+	t.pos, t.kind = start, tokenString
+	l.pos++ // opening quote
+
+	var b bytes.Buffer
+	for {
+		if l.pos >= len(l.source) || l.source[l.pos] == '\n' {
+			err = l.error(start, "unterminated string")
+			return
+		}
+
+		c := l.source[l.pos]
+		switch c {
+		case '"':
+			l.pos++
+			t.value = b.String()
+			return
+		case '\\':
+			if l.pos+1 >= len(l.source) {
+				err = l.error(start, "unterminated string")
+				return
+			}
+			escaped := l.source[l.pos+1]
+			l.pos += 2
+			switch escaped {
+			case '"', '\\', '/':
+				b.WriteByte(escaped)
+			case 'b':
+				b.WriteByte('\b')
+			case 'f':
+				b.WriteByte('\f')
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			case 't':
+				b.WriteByte('\t')
+			case 'u':
+				if l.pos+4 > len(l.source) {
+					err = l.error(start, "invalid unicode escape")
+					return
+				}
+				var r uint64
+				if r, err = strconv.ParseUint(l.source[l.pos:l.pos+4], 16, 32); err != nil {
+					err = l.error(start, "invalid unicode escape")
+					return
+				}
+				b.WriteRune(rune(r))
+				l.pos += 4
+			default:
+				err = l.error(start, "invalid escape '\\%c'", escaped)
+				return
+			}
+		default:
+			r, size := utf8.DecodeRuneInString(l.source[l.pos:])
+			b.WriteRune(r)
+			l.pos += size
+		}
+	}
+}
+
+// Value is the argument value in query; it is resolved with the variables.
+type Value interface {
+	Resolve(variables map[string]interface{}) (interface{}, error)
+}
+
+type literalValue struct {
+	value interface{}
+}
+
+func (v literalValue) Resolve(map[string]interface{}) (interface{}, error) {
+	return v.value, nil
+}
+
+type variableValue struct {
+	name string
+}
+
+func (v variableValue) Resolve(variables map[string]interface{}) (interface{}, error) {
+	value, ok := variables[v.name]
+	if !ok {
+		return nil, fmt.Errorf("variable '$%s' is not defined", v.name)
+	}
+	return value, nil
+}
+
+type listValue []Value
+
+func (v listValue) Resolve(variables map[string]interface{}) (interface{}, error) {
+	var list []interface{}
+	for _, item := range v {
+		resolved, err := item.Resolve(variables)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, resolved)
+	}
+	return list, nil
+}
+
+type objectValue map[string]Value
+
+func (v objectValue) Resolve(variables map[string]interface{}) (interface{}, error) {
+	object := map[string]interface{}{}
+	for name, item := range v {
+		resolved, err := item.Resolve(variables)
+		if err != nil {
+			return nil, err
+		}
+		object[name] = resolved
+	}
+	return object, nil
+}
+
+type Directive struct {
+	Name      string
+	Arguments map[string]Value
+}
+
+// Selection is the field, the fragment spread or the inline fragment.
+type Selection struct {
+	Alias        string
+	Name         string
+	Arguments    map[string]Value
+	Directives   []Directive
+	SelectionSet []*Selection
+
+	FragmentSpread string // name of the spread fragment
+	Inline         bool   // inline fragment; `SelectionSet` is it's selections
+	TypeCondition  string
+}
+
+// ResponseKey is the key of field in the response; the alias or the name.
+func (s *Selection) ResponseKey() string {
+	if len(s.Alias) > 0 {
+		return s.Alias
+	}
+	return s.Name
+}
+
+type VariableDefinition struct {
+	Name     string
+	Type     string
+	NonNull  bool
+	Default  Value
+	Position int
+}
+
+type Operation struct {
+	Type         string
+	Name         string
+	Variables    []VariableDefinition
+	SelectionSet []*Selection
+}
+
+type Fragment struct {
+	Name          string
+	TypeCondition string
+	SelectionSet  []*Selection
+}
+
+type Document struct {
+	Operations []*Operation
+	Fragments  map[string]*Fragment
+}
+
+type parser struct {
+	lexer   *lexer
+	current token
+}
+
+// Parse parses the query document.
+func Parse(source string) (document *Document, err error) {
+	p := &parser{lexer: &lexer{source: source}}
+	if err = p.advance(); err != nil {
+		return
+	}
+
+	document = &Document{Fragments: map[string]*Fragment{}}
+	for p.current.kind != tokenEOF {
+		if p.peek(tokenName, "fragment") {
+			var fragment *Fragment
+			if fragment, err = p.parseFragment(); err != nil {
+				return
+			}
+			if _, found := document.Fragments[fragment.Name]; found {
+				err = fmt.Errorf("fragment '%s' is defined more than once", fragment.Name)
+				return
+			}
+			document.Fragments[fragment.Name] = fragment
+			continue
+		}
+
+		var operation *Operation
+		if operation, err = p.parseOperation(); err != nil {
+			return
+		}
+		document.Operations = append(document.Operations, operation)
+	}
+
+	if len(document.Operations) < 1 {
+		err = fmt.Errorf("no operation is given")
+		return
+	}
+
+	return
+}
+
+func (p *parser) advance() (err error) {
+	p.current, err = p.lexer.next()
+	return
+}
+
+func (p *parser) peek(kind tokenKind, value string) bool {
+	return p.current.kind == kind && p.current.value == value
+}
+
+func (p *parser) unexpected() error {
+	return p.lexer.error(p.current.pos, "unexpected %s", p.current)
+}
+
+func (p *parser) expect(kind tokenKind, value string) (err error) {
+	if !p.peek(kind, value) {
+		err = p.lexer.error(p.current.pos, "expected '%s', found %s", value, p.current)
+		return
+	}
+	return p.advance()
+}
+
+func (p *parser) parseName() (name string, err error) {
+	if p.current.kind != tokenName {
+		err = p.lexer.error(p.current.pos, "expected name, found %s", p.current)
+		return
+	}
+	name = p.current.value
+	err = p.advance()
+	return
+}
+
+func (p *parser) parseOperation() (operation *Operation, err error) {
+	operation = &Operation{Type: "query"}
+
+	// query shorthand
+	if p.peek(tokenPunctuator, "{") {
+		operation.SelectionSet, err = p.parseSelectionSet()
+		return
+	}
+
+	if p.current.kind != tokenName {
+		err = p.unexpected()
+		return
+	}
+	switch p.current.value {
+	case "query":
+	case "mutation", "subscription":
+		err = p.lexer.error(p.current.pos, "'%s' is not supported", p.current.value)
+		return
+	default:
+		err = p.unexpected()
+		return
+	}
+	if err = p.advance(); err != nil {
+		return
+	}
+
+	if p.current.kind == tokenName {
+		if operation.Name, err = p.parseName(); err != nil {
+			return
+		}
+	}
+	if p.peek(tokenPunctuator, "(") {
+		if operation.Variables, err = p.parseVariableDefinitions(); err != nil {
+			return
+		}
+	}
+	if _, err = p.parseDirectives(); err != nil {
+		return
+	}
+
+	operation.SelectionSet, err = p.parseSelectionSet()
+
+	return
+}
+
+func (p *parser) parseVariableDefinitions() (definitions []VariableDefinition, err error) {
+	if err = p.expect(tokenPunctuator, "("); err != nil {
+		return
+	}
+
+	for !p.peek(tokenPunctuator, ")") {
+		definition := VariableDefinition{Position: p.current.pos}
+		if err = p.expect(tokenPunctuator, "$"); err != nil {
+			return
+		}
+		if definition.Name, err = p.parseName(); err != nil {
+			return
+		}
+		if err = p.expect(tokenPunctuator, ":"); err != nil {
+			return
+		}
+		if definition.Type, err = p.parseType(); err != nil {
+			return
+		}
+		definition.NonNull = strings.HasSuffix(definition.Type, "!")
+		if p.peek(tokenPunctuator, "=") {
+			if err = p.advance(); err != nil {
+				return
+			}
+			if definition.Default, err = p.parseValue(true); err != nil {
+				return
+			}
+		}
+		definitions = append(definitions, definition)
+	}
+
+	err = p.advance()
+
+	return
+}
+
+func (p *parser) parseType() (t string, err error) {
+	if p.peek(tokenPunctuator, "[") {
+		if err = p.advance(); err != nil {
+			return
+		}
+		var item string
+		if item, err = p.parseType(); err != nil {
+			return
+		}
+		if err = p.expect(tokenPunctuator, "]"); err != nil {
+			return
+		}
+		t = "[" + item + "]"
+	} else if t, err = p.parseName(); err != nil {
+		return
+	}
+
+	if p.peek(tokenPunctuator, "!") {
+		if err = p.advance(); err != nil {
+			return
+		}
+		t += "!"
+	}
+
+	return
+}
+
+func (p *parser) parseDirectives() (directives []Directive, err error) {
+	for p.peek(tokenPunctuator, "@") {
+		if err = p.advance(); err != nil {
+			return
+		}
+
+		var directive Directive
+		if directive.Name, err = p.parseName(); err != nil {
+			return
+		}
+		if p.peek(tokenPunctuator, "(") {
+			if directive.Arguments, err = p.parseArguments(); err != nil {
+				return
+			}
+		}
+		directives = append(directives, directive)
+	}
+
+	return
+}
+
+func (p *parser) parseSelectionSet() (selections []*Selection, err error) {
+	if err = p.expect(tokenPunctuator, "{"); err != nil {
+		return
+	}
+
+	for !p.peek(tokenPunctuator, "}") {
+		var selection *Selection
+		if p.peek(tokenPunctuator, "...") {
+			selection, err = p.parseFragmentSelection()
+		} else {
+			selection, err = p.parseField()
+		}
+		if err != nil {
+			return
+		}
+		selections = append(selections, selection)
+	}
+	if len(selections) < 1 {
+		err = p.lexer.error(p.current.pos, "selection set is empty")
+		return
+	}
+
+	err = p.advance()
+
+	return
+}
+
+func (p *parser) parseFragmentSelection() (selection *Selection, err error) {
+	if err = p.advance(); err != nil { // '...'
+		return
+	}
+
+	selection = &Selection{}
+	if p.current.kind == tokenName && p.current.value != "on" {
+		if selection.FragmentSpread, err = p.parseName(); err != nil {
+			return
+		}
+		selection.Directives, err = p.parseDirectives()
+		return
+	}
+
+	selection.Inline = true
+	if p.peek(tokenName, "on") {
+		if err = p.advance(); err != nil {
+			return
+		}
+		if selection.TypeCondition, err = p.parseName(); err != nil {
+			return
+		}
+	}
+	if selection.Directives, err = p.parseDirectives(); err != nil {
+		return
+	}
+	selection.SelectionSet, err = p.parseSelectionSet()
+
+	return
+}
+
+func (p *parser) parseField() (selection *Selection, err error) {
+	selection = &Selection{}
+	if selection.Name, err = p.parseName(); err != nil {
+		return
+	}
+	if p.peek(tokenPunctuator, ":") {
+		if err = p.advance(); err != nil {
+			return
+		}
+		selection.Alias = selection.Name
+		if selection.Name, err = p.parseName(); err != nil {
+			return
+		}
+	}
+	if p.peek(tokenPunctuator, "(") {
+		if selection.Arguments, err = p.parseArguments(); err != nil {
+			return
+		}
+	}
+	if selection.Directives, err = p.parseDirectives(); err != nil {
+		return
+	}
+	if p.peek(tokenPunctuator, "{") {
+		selection.SelectionSet, err = p.parseSelectionSet()
+	}
+
+	return
+}
+
+func (p *parser) parseArguments() (arguments map[string]Value, err error) {
+	if err = p.expect(tokenPunctuator, "("); err != nil {
+		return
+	}
+
+	arguments = map[string]Value{}
+	for !p.peek(tokenPunctuator, ")") {
+		var name string
+		if name, err = p.parseName(); err != nil {
+			return
+		}
+		if _, found := arguments[name]; found {
+			err = p.lexer.error(p.current.pos, "argument '%s' is given more than once", name)
+			return
+		}
+		if err = p.expect(tokenPunctuator, ":"); err != nil {
+			return
+		}
+		if arguments[name], err = p.parseValue(false); err != nil {
+			return
+		}
+	}
+	if len(arguments) < 1 {
+		err = p.lexer.error(p.current.pos, "arguments are empty")
+		return
+	}
+
+	err = p.advance()
+
+	return
+}
+
+func (p *parser) parseValue(constant bool) (value Value, err error) {
+	t := p.current
+	switch t.kind {
+	case tokenPunctuator:
+		switch t.value {
+		case "$":
+			if constant {
+				err = p.lexer.error(t.pos, "variable is not allowed")
+				return
+			}
+			if err = p.advance(); err != nil {
+				return
+			}
+			var name string
+			if name, err = p.parseName(); err != nil {
+				return
+			}
+			value = variableValue{name: name}
+			return
+		case "[":
+			return p.parseList(constant)
+		case "{":
+			return p.parseObject(constant)
+		}
+	case tokenInt:
+		var i int64
+		if i, err = strconv.ParseInt(t.value, 10, 64); err != nil {
+			err = p.lexer.error(t.pos, "invalid integer, %s", t)
+			return
+		}
+		value = literalValue{value: i}
+	case tokenFloat:
+		var f float64
+		if f, err = strconv.ParseFloat(t.value, 64); err != nil {
+			err = p.lexer.error(t.pos, "invalid float, %s", t)
+			return
+		}
+		value = literalValue{value: f}
+	case tokenString:
+		value = literalValue{value: t.value}
+	case tokenName:
+		switch t.value {
+		case "true":
+			value = literalValue{value: true}
+		case "false":
+			value = literalValue{value: false}
+		case "null":
+			value = literalValue{value: nil}
+		default: // enum value
+			value = literalValue{value: t.value}
+		}
+	}
+
+	if value == nil {
+		err = p.unexpected()
+		return
+	}
+	err = p.advance()
+
+	return
+}
+
+func (p *parser) parseList(constant bool) (value Value, err error) {
+	if err = p.advance(); err != nil { // '['
+		return
+	}
+
+	list := listValue{}
+	for !p.peek(tokenPunctuator, "]") {
+		var item Value
+		if item, err = p.parseValue(constant); err != nil {
+			return
+		}
+		list = append(list, item)
+	}
+	value = list
+	err = p.advance()
+
+	return
+}
+
+func (p *parser) parseObject(constant bool) (value Value, err error) {
+	if err = p.advance(); err != nil { // '{'
+		return
+	}
+
+	object := objectValue{}
+	for !p.peek(tokenPunctuator, "}") {
+		var name string
+		if name, err = p.parseName(); err != nil {
+			return
+		}
+		if err = p.expect(tokenPunctuator, ":"); err != nil {
+			return
+		}
+		if object[name], err = p.parseValue(constant); err != nil {
+			return
+		}
+	}
+	value = object
+	err = p.advance()
+
+	return
+}
+
+func (p *parser) parseFragment() (fragment *Fragment, err error) {
+	if err = p.advance(); err != nil { // 'fragment'
+		return
+	}
+
+	fragment = &Fragment{}
+	if fragment.Name, err = p.parseName(); err != nil {
+		return
+	}
+	if fragment.Name == "on" {
+		err = p.lexer.error(p.current.pos, "fragment can not be named 'on'")
+		return
+	}
+	if err = p.expect(tokenName, "on"); err != nil {
+		return
+	}
+	if fragment.TypeCondition, err = p.parseName(); err != nil {
+		return
+	}
+	if _, err = p.parseDirectives(); err != nil {
+		return
+	}
+	fragment.SelectionSet, err = p.parseSelectionSet()
+
+	return
+}
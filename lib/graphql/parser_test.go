@@ -0,0 +1,120 @@
+package sebakgraphql
+
+import (
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	query := `
+# explorer query
+query Account($address: String!, $first: Int = 10) {
+  account(address: $address) {
+    address
+    balance: amount
+    transactions(first: $first, after: "a\"bA") @include(if: true) {
+      ...tx
+    }
+  }
+  blocks(heights: [1, 2], filter: {min: -1.5e3, on: false, nothing: null, order: DESC}) { height }
+}
+
+fragment tx on Transaction { hash ... on Transaction { fee } }
+`
+
+	document, err := Parse(query)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if len(document.Operations) != 1 || len(document.Fragments) != 1 {
+		t.Errorf("unexpected document: %v", document)
+		return
+	}
+
+	operation := document.Operations[0]
+	if operation.Name != "Account" || len(operation.Variables) != 2 {
+		t.Errorf("unexpected operation: %v", operation)
+		return
+	}
+	if v := operation.Variables[0]; v.Name != "address" || v.Type != "String!" || !v.NonNull {
+		t.Errorf("unexpected variable: %v", v)
+		return
+	}
+	if v := operation.Variables[1]; v.Default == nil {
+		t.Errorf("default of variable is missing: %v", v)
+		return
+	}
+
+	account := operation.SelectionSet[0]
+	if account.Name != "account" || len(account.SelectionSet) != 3 {
+		t.Errorf("unexpected field: %v", account)
+		return
+	}
+	if balance := account.SelectionSet[1]; balance.Alias != "balance" || balance.Name != "amount" {
+		t.Errorf("unexpected alias: %v", balance)
+		return
+	}
+
+	transactions := account.SelectionSet[2]
+	after, _ := transactions.Arguments["after"].Resolve(nil)
+	if after != `a"bA` {
+		t.Errorf("unexpected string: %v", after)
+		return
+	}
+	if len(transactions.Directives) != 1 || transactions.SelectionSet[0].FragmentSpread != "tx" {
+		t.Errorf("unexpected field: %v", transactions)
+		return
+	}
+
+	filter, _ := operation.SelectionSet[1].Arguments["filter"].Resolve(nil)
+	expected := map[string]interface{}{"min": -1500.0, "on": false, "nothing": nil, "order": "DESC"}
+	for key, value := range expected {
+		if filter.(map[string]interface{})[key] != value {
+			t.Errorf("unexpected object value of '%s': %v", key, filter)
+			return
+		}
+	}
+
+	fragment := document.Fragments["tx"]
+	if fragment.TypeCondition != "Transaction" || !fragment.SelectionSet[1].Inline {
+		t.Errorf("unexpected fragment: %v", fragment)
+		return
+	}
+}
+
+func TestParseShorthand(t *testing.T) {
+	document, err := Parse(`{ latestBlock { height } }`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if document.Operations[0].SelectionSet[0].Name != "latestBlock" {
+		t.Errorf("unexpected document: %v", document.Operations[0])
+		return
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, query := range []string{
+		``,
+		`{`,
+		`{ }`,
+		`{ a(b: ) }`,
+		`{ a(b: 1, b: 2) }`,
+		`{ a(b: "unterminated) }`,
+		`{ a(b: "\x") }`,
+		`{ a(b: 1.) }`,
+		`mutation { a }`,
+		`subscription { a }`,
+		`query ($a: Int = $b) { a }`,
+		`fragment f on T { a }`,
+		`{ a } fragment f on T { a } fragment f on T { b }`,
+		`{ a % }`,
+	} {
+		if _, err := Parse(query); err == nil {
+			t.Errorf("invalid query must be refused: %s", query)
+			return
+		}
+	}
+}
@@ -0,0 +1,431 @@
+package sebakgraphql
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+)
+
+// MaxQueryDepth is the deepest selection of query; the nested selections of
+// the lists can multiply the storage reads, so the deeper query is refused.
+const MaxQueryDepth int = 10
+
+// Args is the arguments of field, which are resolved with the variables.
+type Args map[string]interface{}
+
+// String returns the string argument; if it is not given, `""` is returned.
+func (a Args) String(name string) (s string, err error) {
+	v, ok := a[name]
+	if !ok || v == nil {
+		return
+	}
+	if s, ok = v.(string); !ok {
+		err = fmt.Errorf("argument '%s' must be string", name)
+	}
+
+	return
+}
+
+// Int returns the integer argument; if it is not given, `defaultValue` is
+// returned. The variables from JSON are float64, so they are also accepted
+// if they are integral.
+func (a Args) Int(name string, defaultValue int) (i int, err error) {
+	v, ok := a[name]
+	if !ok || v == nil {
+		i = defaultValue
+		return
+	}
+
+	switch n := v.(type) {
+	case int64:
+		i = int(n)
+	case float64:
+		if n != float64(int64(n)) {
+			err = fmt.Errorf("argument '%s' must be integer", name)
+			return
+		}
+		i = int(n)
+	case string: // the big integers, like height can be given as string
+		var parsed int64
+		if parsed, err = strconv.ParseInt(n, 10, 64); err != nil {
+			err = fmt.Errorf("argument '%s' must be integer", name)
+			return
+		}
+		i = int(parsed)
+	default:
+		err = fmt.Errorf("argument '%s' must be integer", name)
+	}
+
+	return
+}
+
+func (a Args) Bool(name string) (b bool, err error) {
+	v, ok := a[name]
+	if !ok || v == nil {
+		return
+	}
+	if b, ok = v.(bool); !ok {
+		err = fmt.Errorf("argument '%s' must be boolean", name)
+	}
+
+	return
+}
+
+// ResolveFunc resolves the field from it's parent, `source`. The field of
+// scalar returns the value, which can be marshaled to JSON; the field of
+// object returns the source of the object fields, and the list field returns
+// the slice of them.
+type ResolveFunc func(source interface{}, args Args) (interface{}, error)
+
+type Field struct {
+	Type    *Object // nil for scalar
+	List    bool
+	Args    []string // the names of the allowed arguments
+	Resolve ResolveFunc
+}
+
+type Object struct {
+	Name   string
+	Fields map[string]*Field
+}
+
+func NewObject(name string) *Object {
+	return &Object{Name: name, Fields: map[string]*Field{}}
+}
+
+// AddField adds the field; it returns the object itself, so the fields can be
+// chained.
+func (o *Object) AddField(name string, field *Field) *Object {
+	o.Fields[name] = field
+	return o
+}
+
+type Schema struct {
+	Query *Object
+}
+
+type Request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName,omitempty"`
+	Variables     map[string]interface{} `json:"variables,omitempty"`
+}
+
+type Error struct {
+	Message string        `json:"message"`
+	Path    []interface{} `json:"path,omitempty"`
+}
+
+type Response struct {
+	Data   interface{} `json:"data"`
+	Errors []Error     `json:"errors,omitempty"`
+}
+
+// orderedMap keeps the order of fields in the selection, like GraphQL
+// requires for the response.
+type orderedMap struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+func newOrderedMap() *orderedMap {
+	return &orderedMap{values: map[string]interface{}{}}
+}
+
+func (m *orderedMap) Set(key string, value interface{}) {
+	if _, found := m.values[key]; !found {
+		m.keys = append(m.keys, key)
+	}
+	m.values[key] = value
+}
+
+func (m *orderedMap) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, key := range m.keys {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		k, _ := json.Marshal(key)
+		b.Write(k)
+		b.WriteByte(':')
+
+		v, err := json.Marshal(m.values[key])
+		if err != nil {
+			return nil, err
+		}
+		b.Write(v)
+	}
+	b.WriteByte('}')
+
+	return b.Bytes(), nil
+}
+
+type execution struct {
+	document  *Document
+	variables map[string]interface{}
+	errors    []Error
+}
+
+func (e *execution) addError(path []interface{}, err error) {
+	e.errors = append(e.errors, Error{Message: err.Error(), Path: append([]interface{}{}, path...)})
+}
+
+// Execute runs the query of request. The errors of the request itself, like
+// syntax error return the response without data; the errors of fields are
+// reported with their path and the fields are null.
+func (s *Schema) Execute(request Request) (response Response) {
+	document, err := Parse(request.Query)
+	if err != nil {
+		response.Errors = []Error{{Message: err.Error()}}
+		return
+	}
+
+	var operation *Operation
+	if operation, err = selectOperation(document, request.OperationName); err != nil {
+		response.Errors = []Error{{Message: err.Error()}}
+		return
+	}
+
+	e := &execution{document: document}
+	if e.variables, err = coerceVariables(operation, request.Variables); err != nil {
+		response.Errors = []Error{{Message: err.Error()}}
+		return
+	}
+
+	response.Data = e.executeSelectionSet(s.Query, nil, operation.SelectionSet, nil, 1)
+	response.Errors = e.errors
+
+	return
+}
+
+func selectOperation(document *Document, name string) (operation *Operation, err error) {
+	if len(name) < 1 {
+		if len(document.Operations) > 1 {
+			err = errors.New("operationName must be given for the multiple operations")
+			return
+		}
+		operation = document.Operations[0]
+		return
+	}
+
+	for _, o := range document.Operations {
+		if o.Name == name {
+			operation = o
+			return
+		}
+	}
+	err = fmt.Errorf("unknown operation, '%s'", name)
+
+	return
+}
+
+func coerceVariables(operation *Operation, given map[string]interface{}) (variables map[string]interface{}, err error) {
+	variables = map[string]interface{}{}
+	for _, definition := range operation.Variables {
+		value, ok := given[definition.Name]
+		if !ok && definition.Default != nil {
+			if value, err = definition.Default.Resolve(nil); err != nil {
+				return
+			}
+			ok = true
+		}
+		if (!ok || value == nil) && definition.NonNull {
+			err = fmt.Errorf("variable '$%s' of '%s' must be given", definition.Name, definition.Type)
+			return
+		}
+		if ok {
+			variables[definition.Name] = value
+		}
+	}
+
+	return
+}
+
+// included checks `@skip` and `@include` directives.
+func (e *execution) included(directives []Directive) (included bool, err error) {
+	for _, directive := range directives {
+		var expected bool
+		switch directive.Name {
+		case "skip":
+			expected = false
+		case "include":
+			expected = true
+		default:
+			err = fmt.Errorf("unknown directive, '@%s'", directive.Name)
+			return
+		}
+
+		value, ok := directive.Arguments["if"]
+		if !ok {
+			err = fmt.Errorf("'@%s' needs 'if' argument", directive.Name)
+			return
+		}
+
+		var resolved interface{}
+		if resolved, err = value.Resolve(e.variables); err != nil {
+			return
+		}
+		b, ok := resolved.(bool)
+		if !ok {
+			err = fmt.Errorf("'if' of '@%s' must be boolean", directive.Name)
+			return
+		}
+		if b != expected {
+			return
+		}
+	}
+	included = true
+
+	return
+}
+
+// collectFields flattens the fragments of selection set into the fields; the
+// fields of the same response key are merged.
+func (e *execution) collectFields(object *Object, selections []*Selection, fields *[]*Selection, visited map[string]bool) (err error) {
+	for _, selection := range selections {
+		var included bool
+		if included, err = e.included(selection.Directives); err != nil {
+			return
+		} else if !included {
+			continue
+		}
+
+		switch {
+		case len(selection.FragmentSpread) > 0:
+			if visited[selection.FragmentSpread] {
+				continue
+			}
+			visited[selection.FragmentSpread] = true
+
+			fragment, ok := e.document.Fragments[selection.FragmentSpread]
+			if !ok {
+				err = fmt.Errorf("unknown fragment, '%s'", selection.FragmentSpread)
+				return
+			}
+			if fragment.TypeCondition != object.Name {
+				continue
+			}
+			if err = e.collectFields(object, fragment.SelectionSet, fields, visited); err != nil {
+				return
+			}
+		case selection.Inline:
+			if len(selection.TypeCondition) > 0 && selection.TypeCondition != object.Name {
+				continue
+			}
+			if err = e.collectFields(object, selection.SelectionSet, fields, visited); err != nil {
+				return
+			}
+		default:
+			merged := false
+			for i, field := range *fields {
+				if field.ResponseKey() != selection.ResponseKey() {
+					continue
+				}
+				copied := *field
+				copied.SelectionSet = append(append([]*Selection{}, field.SelectionSet...), selection.SelectionSet...)
+				(*fields)[i] = &copied
+				merged = true
+				break
+			}
+			if !merged {
+				*fields = append(*fields, selection)
+			}
+		}
+	}
+
+	return
+}
+
+func (e *execution) executeSelectionSet(object *Object, source interface{}, selections []*Selection, path []interface{}, depth int) interface{} {
+	if depth > MaxQueryDepth {
+		e.addError(path, fmt.Errorf("query is deeper than %d", MaxQueryDepth))
+		return nil
+	}
+
+	var fields []*Selection
+	if err := e.collectFields(object, selections, &fields, map[string]bool{}); err != nil {
+		e.addError(path, err)
+		return nil
+	}
+
+	result := newOrderedMap()
+	for _, selection := range fields {
+		key := selection.ResponseKey()
+		fieldPath := append(append([]interface{}{}, path...), key)
+
+		if selection.Name == "__typename" {
+			result.Set(key, object.Name)
+			continue
+		}
+
+		result.Set(key, e.executeField(object, source, selection, fieldPath, depth))
+	}
+
+	return result
+}
+
+func (e *execution) executeField(object *Object, source interface{}, selection *Selection, path []interface{}, depth int) interface{} {
+	field, ok := object.Fields[selection.Name]
+	if !ok {
+		e.addError(path, fmt.Errorf("unknown field, '%s' of '%s'", selection.Name, object.Name))
+		return nil
+	}
+
+	args := Args{}
+	for name, value := range selection.Arguments {
+		allowed := false
+		for _, a := range field.Args {
+			allowed = allowed || a == name
+		}
+		if !allowed {
+			e.addError(path, fmt.Errorf("unknown argument, '%s' of '%s'", name, selection.Name))
+			return nil
+		}
+
+		resolved, err := value.Resolve(e.variables)
+		if err != nil {
+			e.addError(path, err)
+			return nil
+		}
+		args[name] = resolved
+	}
+
+	if field.Type == nil && len(selection.SelectionSet) > 0 {
+		e.addError(path, fmt.Errorf("scalar field, '%s' can not have selection", selection.Name))
+		return nil
+	}
+	if field.Type != nil && len(selection.SelectionSet) < 1 {
+		e.addError(path, fmt.Errorf("field, '%s' of '%s' must have selection", selection.Name, field.Type.Name))
+		return nil
+	}
+
+	resolved, err := field.Resolve(source, args)
+	if err != nil {
+		e.addError(path, err)
+		return nil
+	}
+	if resolved == nil || field.Type == nil {
+		return resolved
+	}
+
+	if !field.List {
+		return e.executeSelectionSet(field.Type, resolved, selection.SelectionSet, path, depth+1)
+	}
+
+	items := reflect.ValueOf(resolved)
+	if items.Kind() != reflect.Slice {
+		e.addError(path, fmt.Errorf("field, '%s' must be list", selection.Name))
+		return nil
+	}
+
+	list := []interface{}{}
+	for i := 0; i < items.Len(); i++ {
+		itemPath := append(append([]interface{}{}, path...), i)
+		list = append(list, e.executeSelectionSet(field.Type, items.Index(i).Interface(), selection.SelectionSet, itemPath, depth+1))
+	}
+
+	return list
+}
@@ -0,0 +1,149 @@
+package sebakgraphql
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+type testBook struct {
+	Title  string
+	Author string
+}
+
+func newTestSchema() *Schema {
+	books := []testBook{
+		{"Dune", "Herbert"},
+		{"Emma", "Austen"},
+		{"Persuasion", "Austen"},
+	}
+
+	author := NewObject("Author")
+	author.AddField("name", &Field{Resolve: func(source interface{}, args Args) (interface{}, error) {
+		return source.(string), nil
+	}})
+
+	book := NewObject("Book")
+	book.AddField("title", &Field{Resolve: func(source interface{}, args Args) (interface{}, error) {
+		return source.(testBook).Title, nil
+	}}).AddField("author", &Field{Type: author, Resolve: func(source interface{}, args Args) (interface{}, error) {
+		return source.(testBook).Author, nil
+	}}).AddField("broken", &Field{Resolve: func(source interface{}, args Args) (interface{}, error) {
+		return nil, errors.New("broken")
+	}})
+
+	query := NewObject("Query")
+	query.AddField("books", &Field{Type: book, List: true, Args: []string{"author", "first"}, Resolve: func(source interface{}, args Args) (interface{}, error) {
+		name, err := args.String("author")
+		if err != nil {
+			return nil, err
+		}
+		first, err := args.Int("first", len(books))
+		if err != nil {
+			return nil, err
+		}
+
+		var found []testBook
+		for _, b := range books {
+			if len(found) < first && (len(name) < 1 || b.Author == name) {
+				found = append(found, b)
+			}
+		}
+		return found, nil
+	}})
+	author.AddField("books", query.Fields["books"])
+
+	return &Schema{Query: query}
+}
+
+func executeTestQuery(request Request) string {
+	b, _ := json.Marshal(newTestSchema().Execute(request))
+	return string(b)
+}
+
+func TestSchemaExecute(t *testing.T) {
+	cases := []struct {
+		request  Request
+		expected string
+	}{
+		{
+			Request{Query: `{ books(first: 1) { title } }`},
+			`{"data":{"books":[{"title":"Dune"}]}}`,
+		},
+		{
+			Request{
+				Query:     `query Q($author: String!) { list: books(author: $author) { __typename t: title author { name } } }`,
+				Variables: map[string]interface{}{"author": "Austen"},
+			},
+			`{"data":{"list":[{"__typename":"Book","t":"Emma","author":{"name":"Austen"}},{"__typename":"Book","t":"Persuasion","author":{"name":"Austen"}}]}}`,
+		},
+		{ // fragments and directives; the fields of same key are merged
+			Request{
+				Query:     `query ($more: Boolean = false) { books(first: 2) { ...f title @skip(if: true) author @include(if: $more) { name } } } fragment f on Book { title ... on Book { author { name } } }`,
+				Variables: map[string]interface{}{"more": true},
+			},
+			`{"data":{"books":[{"title":"Dune","author":{"name":"Herbert"}},{"title":"Emma","author":{"name":"Austen"}}]}}`,
+		},
+		{ // the error of field is reported with it's path
+			Request{Query: `{ books(first: 1) { title broken } }`},
+			`{"data":{"books":[{"title":"Dune","broken":null}]},"errors":[{"message":"broken","path":["books",0,"broken"]}]}`,
+		},
+		{
+			Request{Query: `{ books { unknown } }`},
+			`"errors":[{"message":"unknown field, 'unknown' of 'Book'"`,
+		},
+		{
+			Request{Query: `{ books(limit: 1) { title } }`},
+			`"errors":[{"message":"unknown argument, 'limit' of 'books'"`,
+		},
+		{
+			Request{Query: `{ books }`},
+			`"errors":[{"message":"field, 'books' of 'Book' must have selection"`,
+		},
+		{
+			Request{Query: `{ books { title { a } } }`},
+			`"errors":[{"message":"scalar field, 'title' can not have selection"`,
+		},
+		{
+			Request{Query: `query ($a: String!) { books(author: $a) { title } }`},
+			`{"data":null,"errors":[{"message":"variable '$a' of 'String!' must be given"}]}`,
+		},
+		{
+			Request{Query: `{ books(first: $undefined) { title } }`},
+			`"errors":[{"message":"variable '$undefined' is not defined"`,
+		},
+		{
+			Request{Query: `query A { books { title } } query B { books { title } }`},
+			`{"data":null,"errors":[{"message":"operationName must be given for the multiple operations"}]}`,
+		},
+		{
+			Request{Query: `query A { books(first: 1) { title } } query B { books(first: 2) { title } }`, OperationName: "A"},
+			`{"data":{"books":[{"title":"Dune"}]}}`,
+		},
+		{
+			Request{Query: `{ books(first: "x") { title } }`},
+			`"errors":[{"message":"argument 'first' must be integer"`,
+		},
+	}
+
+	for _, c := range cases {
+		result := executeTestQuery(c.request)
+		if !strings.Contains(result, c.expected) {
+			t.Errorf("unexpected result of '%s':\n\t%s\n\texpected %s", c.request.Query, result, c.expected)
+			return
+		}
+	}
+}
+
+func TestSchemaExecuteMaxDepth(t *testing.T) {
+	query := "{ books(first: 1) { title } }"
+	for i := 0; i < MaxQueryDepth/2; i++ {
+		query = strings.Replace(query, "title", "author { books { title } }", 1)
+	}
+	result := executeTestQuery(Request{Query: query})
+	if !strings.Contains(result, "query is deeper than") {
+		t.Errorf("deep query must be refused: %s", result)
+		return
+	}
+}
@@ -0,0 +1,222 @@
+package sebakgrpc
+
+// The messages of 'sebak.proto'; the field numbers must be same with it.
+
+const (
+	ServiceQuery  string = "sebak.Query"
+	ServiceSubmit string = "sebak.Submit"
+	ServiceStream string = "sebak.Stream"
+)
+
+type Empty struct{}
+
+func (m *Empty) MarshalProto(e *Encoder)      {}
+func (m *Empty) UnmarshalProto(f Field) error { return nil }
+
+type GetAccountRequest struct {
+	Address string
+}
+
+func (m *GetAccountRequest) MarshalProto(e *Encoder) {
+	e.String(1, m.Address)
+}
+
+func (m *GetAccountRequest) UnmarshalProto(f Field) error {
+	if f.Number == 1 {
+		m.Address = f.String()
+	}
+	return nil
+}
+
+type Account struct {
+	Address    string
+	Balance    uint64
+	Checkpoint string
+}
+
+func (m *Account) MarshalProto(e *Encoder) {
+	e.String(1, m.Address)
+	e.Uint64(2, m.Balance)
+	e.String(3, m.Checkpoint)
+}
+
+func (m *Account) UnmarshalProto(f Field) error {
+	switch f.Number {
+	case 1:
+		m.Address = f.String()
+	case 2:
+		m.Balance = f.Uint64()
+	case 3:
+		m.Checkpoint = f.String()
+	}
+	return nil
+}
+
+type GetTransactionRequest struct {
+	Hash string
+}
+
+func (m *GetTransactionRequest) MarshalProto(e *Encoder) {
+	e.String(1, m.Hash)
+}
+
+func (m *GetTransactionRequest) UnmarshalProto(f Field) error {
+	if f.Number == 1 {
+		m.Hash = f.String()
+	}
+	return nil
+}
+
+type Transaction struct {
+	Hash       string
+	Source     string
+	Fee        uint64
+	Amount     uint64
+	Checkpoint string
+	Operations []string
+	Created    string
+	Confirmed  string
+}
+
+func (m *Transaction) MarshalProto(e *Encoder) {
+	e.String(1, m.Hash)
+	e.String(2, m.Source)
+	e.Uint64(3, m.Fee)
+	e.Uint64(4, m.Amount)
+	e.String(5, m.Checkpoint)
+	e.Strings(6, m.Operations)
+	e.String(7, m.Created)
+	e.String(8, m.Confirmed)
+}
+
+func (m *Transaction) UnmarshalProto(f Field) error {
+	switch f.Number {
+	case 1:
+		m.Hash = f.String()
+	case 2:
+		m.Source = f.String()
+	case 3:
+		m.Fee = f.Uint64()
+	case 4:
+		m.Amount = f.Uint64()
+	case 5:
+		m.Checkpoint = f.String()
+	case 6:
+		m.Operations = append(m.Operations, f.String())
+	case 7:
+		m.Created = f.String()
+	case 8:
+		m.Confirmed = f.String()
+	}
+	return nil
+}
+
+type GetBlockRequest struct {
+	Height uint64
+	Hash   string
+}
+
+func (m *GetBlockRequest) MarshalProto(e *Encoder) {
+	e.Uint64(1, m.Height)
+	e.String(2, m.Hash)
+}
+
+func (m *GetBlockRequest) UnmarshalProto(f Field) error {
+	switch f.Number {
+	case 1:
+		m.Height = f.Uint64()
+	case 2:
+		m.Hash = f.String()
+	}
+	return nil
+}
+
+type Block struct {
+	Hash          string
+	Height        uint64
+	PrevBlockHash string
+	StateHash     string
+	Transactions  []string
+	Confirmed     string
+}
+
+func (m *Block) MarshalProto(e *Encoder) {
+	e.String(1, m.Hash)
+	e.Uint64(2, m.Height)
+	e.String(3, m.PrevBlockHash)
+	e.String(4, m.StateHash)
+	e.Strings(5, m.Transactions)
+	e.String(6, m.Confirmed)
+}
+
+func (m *Block) UnmarshalProto(f Field) error {
+	switch f.Number {
+	case 1:
+		m.Hash = f.String()
+	case 2:
+		m.Height = f.Uint64()
+	case 3:
+		m.PrevBlockHash = f.String()
+	case 4:
+		m.StateHash = f.String()
+	case 5:
+		m.Transactions = append(m.Transactions, f.String())
+	case 6:
+		m.Confirmed = f.String()
+	}
+	return nil
+}
+
+type SubmitTransactionRequest struct {
+	Transaction []byte
+}
+
+func (m *SubmitTransactionRequest) MarshalProto(e *Encoder) {
+	e.RawBytes(1, m.Transaction)
+}
+
+func (m *SubmitTransactionRequest) UnmarshalProto(f Field) error {
+	if f.Number == 1 {
+		m.Transaction = append([]byte(nil), f.Data...)
+	}
+	return nil
+}
+
+type SubmitTransactionResponse struct {
+	Hash   string
+	Status string
+	Result string
+}
+
+func (m *SubmitTransactionResponse) MarshalProto(e *Encoder) {
+	e.String(1, m.Hash)
+	e.String(2, m.Status)
+	e.String(3, m.Result)
+}
+
+func (m *SubmitTransactionResponse) UnmarshalProto(f Field) error {
+	switch f.Number {
+	case 1:
+		m.Hash = f.String()
+	case 2:
+		m.Status = f.String()
+	case 3:
+		m.Result = f.String()
+	}
+	return nil
+}
+
+type StreamTransactionsRequest struct {
+	Account string
+}
+
+func (m *StreamTransactionsRequest) MarshalProto(e *Encoder) {
+	e.String(1, m.Account)
+}
+
+func (m *StreamTransactionsRequest) UnmarshalProto(f Field) error {
+	if f.Number == 1 {
+		m.Account = f.String()
+	}
+	return nil
+}
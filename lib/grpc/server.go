@@ -0,0 +1,252 @@
+package sebakgrpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Server serves the gRPC methods over HTTP/2; the request and the responses
+// are the length-prefixed messages and the status is sent in the trailers,
+// 'Grpc-Status' and 'Grpc-Message'. The compressed messages are not
+// supported, so the client must not set 'grpc-encoding'.
+
+// Code is the status code of gRPC.
+type Code uint32
+
+const (
+	OK                 Code = 0
+	Canceled           Code = 1
+	Unknown            Code = 2
+	InvalidArgument    Code = 3
+	DeadlineExceeded   Code = 4
+	NotFound           Code = 5
+	AlreadyExists      Code = 6
+	PermissionDenied   Code = 7
+	ResourceExhausted  Code = 8
+	FailedPrecondition Code = 9
+	Unimplemented      Code = 12
+	Internal           Code = 13
+	Unavailable        Code = 14
+)
+
+// ContentType is the content type of the gRPC request and response.
+const ContentType string = "application/grpc"
+
+// MaxMessageSize is the maximum size of the request message.
+const MaxMessageSize int = 4 * 1024 * 1024
+
+type Status struct {
+	Code    Code
+	Message string
+}
+
+func (s *Status) Error() string {
+	return fmt.Sprintf("grpc: code = %d desc = %s", s.Code, s.Message)
+}
+
+func Errorf(code Code, format string, a ...interface{}) *Status {
+	return &Status{Code: code, Message: fmt.Sprintf(format, a...)}
+}
+
+// StatusOf returns the status of error; nil is `OK` and the error, which is
+// not `Status` is `Unknown`.
+func StatusOf(err error) *Status {
+	if err == nil {
+		return &Status{Code: OK}
+	}
+	if s, ok := err.(*Status); ok {
+		return s
+	}
+
+	return &Status{Code: Unknown, Message: err.Error()}
+}
+
+// Method is the handler of one method; `Unary` returns the one response and
+// `Stream` sends the responses until it returns.
+type Method struct {
+	NewRequest func() Message
+	Unary      func(ctx context.Context, req Message) (Message, error)
+	Stream     func(ctx context.Context, req Message, send func(Message) error) error
+}
+
+type Server struct {
+	methods map[string]Method
+}
+
+func NewServer() *Server {
+	return &Server{methods: map[string]Method{}}
+}
+
+// MethodPath is the path of method, like '/sebak.Query/GetAccount'.
+func MethodPath(service, method string) string {
+	return "/" + service + "/" + method
+}
+
+func (s *Server) Handle(service, method string, m Method) {
+	s.methods[MethodPath(service, method)] = m
+}
+
+// Methods returns the paths of the methods in order.
+func (s *Server) Methods() (paths []string) {
+	for path := range s.methods {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	return
+}
+
+// ReadMessage reads the length-prefixed message.
+func ReadMessage(r io.Reader, m Message) error {
+	var header [5]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return Errorf(InvalidArgument, "failed to read message: %v", err)
+	}
+	if header[0] != 0 {
+		return Errorf(Unimplemented, "compressed message is not supported")
+	}
+
+	size := binary.BigEndian.Uint32(header[1:])
+	if uint64(size) > uint64(MaxMessageSize) {
+		return Errorf(ResourceExhausted, "message is larger than %d bytes", MaxMessageSize)
+	}
+
+	b := make([]byte, size)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return Errorf(InvalidArgument, "failed to read message: %v", err)
+	}
+	if err := Unmarshal(b, m); err != nil {
+		return Errorf(InvalidArgument, "%v", err)
+	}
+
+	return nil
+}
+
+// WriteMessage writes the length-prefixed message.
+func WriteMessage(w io.Writer, m Message) (err error) {
+	b := Marshal(m)
+
+	var header [5]byte
+	binary.BigEndian.PutUint32(header[1:], uint32(len(b)))
+	if _, err = w.Write(header[:]); err != nil {
+		return
+	}
+	_, err = w.Write(b)
+
+	return
+}
+
+// parseTimeout parses 'grpc-timeout', like '100m'.
+func parseTimeout(s string) (timeout time.Duration, ok bool) {
+	if len(s) < 2 {
+		return
+	}
+
+	units := map[byte]time.Duration{
+		'H': time.Hour,
+		'M': time.Minute,
+		'S': time.Second,
+		'm': time.Millisecond,
+		'u': time.Microsecond,
+		'n': time.Nanosecond,
+	}
+	unit, found := units[s[len(s)-1]]
+	if !found {
+		return
+	}
+	n, err := strconv.ParseInt(s[:len(s)-1], 10, 64)
+	if err != nil || n < 0 {
+		return
+	}
+
+	return time.Duration(n) * unit, true
+}
+
+// encodeStatusMessage percent-encodes the status message like the gRPC
+// clients expect.
+func encodeStatusMessage(s string) string {
+	var b bytes.Buffer
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c < 0x20 || c > 0x7e || c == '%' {
+			fmt.Fprintf(&b, "%%%02X", c)
+			continue
+		}
+		b.WriteByte(c)
+	}
+
+	return b.String()
+}
+
+func writeStatus(w http.ResponseWriter, err error) {
+	s := StatusOf(err)
+
+	w.Header().Set("Grpc-Status", strconv.Itoa(int(s.Code)))
+	if len(s.Message) > 0 {
+		w.Header().Set("Grpc-Message", encodeStatusMessage(s.Message))
+	}
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" || !strings.HasPrefix(r.Header.Get("Content-Type"), ContentType) {
+		w.WriteHeader(http.StatusUnsupportedMediaType)
+		return
+	}
+
+	w.Header().Set("Content-Type", ContentType)
+	w.Header().Set("Trailer", "Grpc-Status, Grpc-Message")
+	w.WriteHeader(http.StatusOK)
+
+	m, found := s.methods[r.URL.Path]
+	if !found {
+		writeStatus(w, Errorf(Unimplemented, "unknown method: %s", r.URL.Path))
+		return
+	}
+
+	ctx := r.Context()
+	if timeout, ok := parseTimeout(r.Header.Get("Grpc-Timeout")); ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	req := m.NewRequest()
+	if err := ReadMessage(r.Body, req); err != nil {
+		writeStatus(w, err)
+		return
+	}
+
+	if m.Unary != nil {
+		res, err := m.Unary(ctx, req)
+		if err == nil {
+			err = WriteMessage(w, res)
+		}
+		writeStatus(w, err)
+		return
+	}
+
+	flusher, _ := w.(http.Flusher)
+	send := func(res Message) error {
+		if err := WriteMessage(w, res); err != nil {
+			return err
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		return nil
+	}
+
+	err := m.Stream(ctx, req, send)
+	if err == nil && ctx.Err() == context.DeadlineExceeded {
+		err = Errorf(DeadlineExceeded, "deadline exceeded")
+	}
+	writeStatus(w, err)
+}
@@ -0,0 +1,83 @@
+package sebakgrpc
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func testCall(s *Server, path string, req Message) *httptest.ResponseRecorder {
+	var body bytes.Buffer
+	WriteMessage(&body, req)
+
+	r := httptest.NewRequest("POST", path, &body)
+	r.Header.Set("Content-Type", ContentType)
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+
+	return w
+}
+
+func TestServer(t *testing.T) {
+	s := NewServer()
+	s.Handle(ServiceQuery, "GetAccount", Method{
+		NewRequest: func() Message { return &GetAccountRequest{} },
+		Unary: func(ctx context.Context, req Message) (Message, error) {
+			address := req.(*GetAccountRequest).Address
+			if address != "found" {
+				return nil, Errorf(NotFound, "account %s not found", address)
+			}
+			return &Account{Address: address, Balance: 1}, nil
+		},
+	})
+	s.Handle(ServiceStream, "StreamBlocks", Method{
+		NewRequest: func() Message { return &Empty{} },
+		Stream: func(ctx context.Context, req Message, send func(Message) error) error {
+			for height := uint64(1); height <= 3; height++ {
+				if err := send(&Block{Height: height}); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	})
+
+	w := testCall(s, MethodPath(ServiceQuery, "GetAccount"), &GetAccountRequest{Address: "found"})
+	var account Account
+	if err := ReadMessage(w.Body, &account); err != nil || account.Balance != 1 || w.Result().Trailer.Get("Grpc-Status") != "0" {
+		t.Errorf("wrong response: %v %v", account, err)
+		return
+	}
+
+	w = testCall(s, MethodPath(ServiceQuery, "GetAccount"), &GetAccountRequest{Address: "100%"})
+	trailer := w.Result().Trailer
+	if w.Body.Len() > 0 || trailer.Get("Grpc-Status") != "5" || trailer.Get("Grpc-Message") != "account 100%25 not found" {
+		t.Errorf("wrong status: %v", trailer)
+		return
+	}
+
+	w = testCall(s, MethodPath(ServiceStream, "StreamBlocks"), &Empty{})
+	for height := uint64(1); height <= 3; height++ {
+		var block Block
+		if err := ReadMessage(w.Body, &block); err != nil || block.Height != height {
+			t.Errorf("wrong streamed message: %v %v", block, err)
+			return
+		}
+	}
+
+	if w = testCall(s, "/sebak.Query/Unknown", &Empty{}); w.Result().Trailer.Get("Grpc-Status") != "12" {
+		t.Error("unknown method must be unimplemented")
+		return
+	}
+
+	r := httptest.NewRequest("POST", MethodPath(ServiceQuery, "GetAccount"), nil)
+	r.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	if w.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("request without gRPC content type must be refused: %d", w.Code)
+		return
+	}
+}
@@ -0,0 +1,179 @@
+package sebakgrpc
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// The protocol buffers wire format of the messages in 'sebak.proto'. Only
+// the scalar types, which the messages use, are encoded; the varint for the
+// integers and booleans, and the length-delimited for the strings, bytes and
+// the embedded messages. The fields of the other wire types are skipped in
+// decoding, so the new fields of the newer clients do not break the node.
+// 'wire_test.go' checks the messages against 'sebak.proto' and the bytes of
+// the protobuf encoding, so they must be updated together.
+
+const (
+	wireVarint  int = 0
+	wireFixed64 int = 1
+	wireBytes   int = 2
+	wireFixed32 int = 5
+)
+
+var errMalformed = errors.New("malformed protobuf message")
+
+// Message is the protobuf message, which encodes it's own fields;
+// `UnmarshalProto` is called for every field in the encoded message.
+type Message interface {
+	MarshalProto(e *Encoder)
+	UnmarshalProto(f Field) error
+}
+
+func Marshal(m Message) []byte {
+	e := &Encoder{}
+	m.MarshalProto(e)
+
+	return e.b
+}
+
+func Unmarshal(b []byte, m Message) error {
+	return DecodeFields(b, m.UnmarshalProto)
+}
+
+// Encoder appends the fields; like proto3, the zero values are not encoded
+// except the elements of the repeated fields.
+type Encoder struct {
+	b []byte
+}
+
+func (e *Encoder) Bytes() []byte {
+	return e.b
+}
+
+func (e *Encoder) varint(v uint64) {
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(buf[:], v)
+	e.b = append(e.b, buf[:n]...)
+}
+
+func (e *Encoder) tag(field, wire int) {
+	e.varint(uint64(field)<<3 | uint64(wire))
+}
+
+func (e *Encoder) bytes(field int, b []byte) {
+	e.tag(field, wireBytes)
+	e.varint(uint64(len(b)))
+	e.b = append(e.b, b...)
+}
+
+func (e *Encoder) Uint64(field int, v uint64) {
+	if v == 0 {
+		return
+	}
+	e.tag(field, wireVarint)
+	e.varint(v)
+}
+
+func (e *Encoder) Bool(field int, v bool) {
+	if !v {
+		return
+	}
+	e.tag(field, wireVarint)
+	e.varint(1)
+}
+
+func (e *Encoder) String(field int, s string) {
+	if len(s) < 1 {
+		return
+	}
+	e.bytes(field, []byte(s))
+}
+
+func (e *Encoder) RawBytes(field int, b []byte) {
+	if len(b) < 1 {
+		return
+	}
+	e.bytes(field, b)
+}
+
+// Strings encodes the repeated string field.
+func (e *Encoder) Strings(field int, list []string) {
+	for _, s := range list {
+		e.bytes(field, []byte(s))
+	}
+}
+
+// Message encodes the embedded message; it is encoded even if it is empty,
+// so it can be the element of the repeated field.
+func (e *Encoder) Message(field int, m Message) {
+	e.bytes(field, Marshal(m))
+}
+
+// Field is the decoded field; `Varint` is the value of the varint field and
+// `Data` is the value of the other fields.
+type Field struct {
+	Number int
+	Wire   int
+	Varint uint64
+	Data   []byte
+}
+
+func (f Field) String() string {
+	return string(f.Data)
+}
+
+func (f Field) Uint64() uint64 {
+	return f.Varint
+}
+
+func (f Field) Bool() bool {
+	return f.Varint != 0
+}
+
+// DecodeFields calls `fn` for every field of the encoded message in order.
+func DecodeFields(b []byte, fn func(Field) error) error {
+	for len(b) > 0 {
+		key, n := binary.Uvarint(b)
+		if n <= 0 {
+			return errMalformed
+		}
+		b = b[n:]
+
+		f := Field{Number: int(key >> 3), Wire: int(key & 7)}
+		if f.Number < 1 {
+			return errMalformed
+		}
+
+		switch f.Wire {
+		case wireVarint:
+			if f.Varint, n = binary.Uvarint(b); n <= 0 {
+				return errMalformed
+			}
+			b = b[n:]
+		case wireFixed64, wireFixed32:
+			size := 8
+			if f.Wire == wireFixed32 {
+				size = 4
+			}
+			if len(b) < size {
+				return errMalformed
+			}
+			f.Data, b = b[:size], b[size:]
+		case wireBytes:
+			length, n := binary.Uvarint(b)
+			if n <= 0 || uint64(len(b)-n) < length {
+				return errMalformed
+			}
+			b = b[n:]
+			f.Data, b = b[:length], b[length:]
+		default:
+			return errMalformed
+		}
+
+		if err := fn(f); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,203 @@
+package sebakgrpc
+
+import (
+	"bytes"
+	"encoding/hex"
+	"io/ioutil"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestWireMarshal(t *testing.T) {
+	block := &Block{
+		Hash:         "hash",
+		Height:       300,
+		Transactions: []string{"a", "", "b"},
+		Confirmed:    "2018-01-01T00:00:00Z",
+	}
+
+	// the field 2, varint 300 is '10 ac 02'
+	b := Marshal(block)
+	if b[6] != 0x10 || b[7] != 0xac || b[8] != 0x02 {
+		t.Errorf("wrong encoding: %x", b)
+		return
+	}
+
+	var decoded Block
+	if err := Unmarshal(b, &decoded); err != nil {
+		t.Error(err)
+		return
+	}
+	if !reflect.DeepEqual(block, &decoded) {
+		t.Errorf("wrong decoded message: %v", decoded)
+		return
+	}
+
+	// the unknown fields are skipped
+	e := &Encoder{}
+	e.String(1, "address")
+	e.Uint64(10, 1)
+	e.RawBytes(11, []byte("unknown"))
+	var account Account
+	if err := Unmarshal(e.Bytes(), &account); err != nil || account.Address != "address" {
+		t.Errorf("unknown fields must be skipped: %v %v", account, err)
+		return
+	}
+
+	if err := Unmarshal([]byte{0x0a, 0x10, 'a'}, &account); err != errMalformed {
+		t.Errorf("truncated message must be refused: %v", err)
+		return
+	}
+}
+
+// TestWireGolden checks the encoding with the bytes of the protobuf wire
+// format for the same messages, which protoc makes, like
+// `protoc --encode=sebak.Block sebak.proto`; the fields are in order of the
+// field numbers and the repeated strings are not packed.
+func TestWireGolden(t *testing.T) {
+	cases := []struct {
+		message Message
+		decoded Message
+		golden  string
+	}{
+		{
+			&Block{
+				Hash:          "hash",
+				Height:        300,
+				PrevBlockHash: "prev",
+				StateHash:     "state",
+				Transactions:  []string{"a", "", "b"},
+				Confirmed:     "2018-01-01T00:00:00Z",
+			},
+			&Block{},
+			"0a046861736810ac021a0470726576220573746174652a01612a002a01623214323031382d30312d30315430303a30303a30305a",
+		},
+		{
+			&Transaction{
+				Hash:       "hash",
+				Source:     "source",
+				Fee:        10000,
+				Amount:     1<<64 - 1,
+				Checkpoint: "checkpoint",
+				Operations: []string{"op-a", "op-b"},
+				Created:    "created",
+				Confirmed:  "confirmed",
+			},
+			&Transaction{},
+			"0a04686173681206736f7572636518904e20ffffffffffffffffff012a0a636865636b706f696e7432046f702d6132046f702d623a07637265617465644209636f6e6669726d6564",
+		},
+		{&GetBlockRequest{Height: 1 << 63}, &GetBlockRequest{}, "0880808080808080808001"},
+		{&SubmitTransactionRequest{Transaction: []byte{0, 1, 0xff}}, &SubmitTransactionRequest{}, "0a030001ff"},
+		{&Empty{}, &Empty{}, ""},
+	}
+
+	for _, c := range cases {
+		golden, _ := hex.DecodeString(c.golden)
+		if b := Marshal(c.message); !bytes.Equal(b, golden) {
+			t.Errorf("%T: wrong encoding: %x", c.message, b)
+			return
+		}
+		if err := Unmarshal(golden, c.decoded); err != nil || !reflect.DeepEqual(c.message, c.decoded) {
+			t.Errorf("%T: wrong decoded message: %v %v", c.message, c.decoded, err)
+			return
+		}
+	}
+}
+
+// TestWireMatchesProto checks every field of the messages is encoded with the
+// field number and the wire type of 'sebak.proto'.
+func TestWireMatchesProto(t *testing.T) {
+	b, err := ioutil.ReadFile("sebak.proto")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type protoField struct {
+		number int
+		wire   int
+	}
+	protoMessages := map[string]map[string]protoField{}
+	fieldPattern := regexp.MustCompile(`(?:repeated )?(\w+) (\w+) = (\d+);`)
+	for _, m := range regexp.MustCompile(`(?s)message (\w+) \{(.*?)\}`).FindAllStringSubmatch(string(b), -1) {
+		fields := map[string]protoField{}
+		for _, f := range fieldPattern.FindAllStringSubmatch(m[2], -1) {
+			var name string
+			for _, part := range strings.Split(f[2], "_") {
+				name += strings.Title(part)
+			}
+			number, _ := strconv.Atoi(f[3])
+			wire := wireBytes
+			if f[1] == "uint64" || f[1] == "bool" {
+				wire = wireVarint
+			}
+			fields[name] = protoField{number: number, wire: wire}
+		}
+		protoMessages[m[1]] = fields
+	}
+
+	messages := []Message{
+		&Empty{},
+		&GetAccountRequest{},
+		&Account{},
+		&GetTransactionRequest{},
+		&Transaction{},
+		&GetBlockRequest{},
+		&Block{},
+		&SubmitTransactionRequest{},
+		&SubmitTransactionResponse{},
+		&StreamTransactionsRequest{},
+	}
+	if len(messages) != len(protoMessages) {
+		t.Errorf("messages must be same with sebak.proto: %d != %d", len(messages), len(protoMessages))
+		return
+	}
+
+	for _, m := range messages {
+		typ := reflect.TypeOf(m).Elem()
+		fields, found := protoMessages[typ.Name()]
+		if !found || len(fields) != typ.NumField() {
+			t.Errorf("'%s' must be same with sebak.proto: %v", typ.Name(), fields)
+			return
+		}
+
+		// only one field is set, so the encoded field is of it
+		for i := 0; i < typ.NumField(); i++ {
+			expected, found := fields[typ.Field(i).Name]
+			if !found {
+				t.Errorf("'%s.%s' is not in sebak.proto", typ.Name(), typ.Field(i).Name)
+				return
+			}
+
+			v := reflect.New(typ)
+			field := v.Elem().Field(i)
+			switch field.Interface().(type) {
+			case string:
+				field.SetString("x")
+			case uint64:
+				field.SetUint(1)
+			case bool:
+				field.SetBool(true)
+			case []string:
+				field.Set(reflect.ValueOf([]string{"x"}))
+			case []byte:
+				field.Set(reflect.ValueOf([]byte{1}))
+			default:
+				t.Errorf("unknown type of '%s.%s'", typ.Name(), typ.Field(i).Name)
+				return
+			}
+
+			var encoded []Field
+			DecodeFields(Marshal(v.Interface().(Message)), func(f Field) error {
+				encoded = append(encoded, f)
+				return nil
+			})
+			if len(encoded) != 1 || encoded[0].Number != expected.number || encoded[0].Wire != expected.wire {
+				t.Errorf("'%s.%s' must be field %d of wire type %d: %v", typ.Name(), typ.Field(i).Name, expected.number, expected.wire, encoded)
+				return
+			}
+		}
+	}
+}
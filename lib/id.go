@@ -0,0 +1,197 @@
+package sebak
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/btcsuite/btcutil/base58"
+	"github.com/stellar/go/strkey"
+
+	"boscoin.io/sebak/lib/error"
+	"boscoin.io/sebak/lib/storage"
+)
+
+// The identifiers are parsed and validated once, where they come in, like the
+// API routes and the JSON-RPC params; after that they are used as the keys of
+// storage without checking again.
+//  * `AccountAddress`: the public address of account, 'G...'
+//  * `TxHash`: the hash of transaction
+//  * `OpID`: the hash of operation and the hash of it's transaction,
+//  '<operation hash>-<transaction hash>'; see `NewBlockOperationKey()`
+//  * `BlockID`: the height or the hash of block
+
+// HashLength is the length of the decoded hash of the objects.
+const HashLength int = 32
+
+// IsValidHash checks the base58 encoded hash of the objects, like transaction
+// and block.
+func IsValidHash(s string) bool {
+	return len(s) > 0 && len(base58.Decode(s)) == HashLength
+}
+
+type AccountAddress string
+
+func ParseAccountAddress(s string) (address AccountAddress, err error) {
+	var b []byte
+	if b, err = strkey.Decode(strkey.VersionByteAccountID, s); err != nil || len(b) != 32 {
+		err = sebakerror.ErrorBadPublicAddress
+		return
+	}
+
+	address = AccountAddress(s)
+	return
+}
+
+func (a AccountAddress) String() string {
+	return string(a)
+}
+
+func (a AccountAddress) StorageKey() string {
+	return GetBlockAccountKey(string(a))
+}
+
+type TxHash string
+
+func ParseTxHash(s string) (hash TxHash, err error) {
+	if !IsValidHash(s) {
+		err = sebakerror.ErrorInvalidHash
+		return
+	}
+
+	hash = TxHash(s)
+	return
+}
+
+func (h TxHash) String() string {
+	return string(h)
+}
+
+func (h TxHash) StorageKey() string {
+	return GetBlockTransactionKey(string(h))
+}
+
+type OpID string
+
+func NewOpID(op Operation, tx Transaction) OpID {
+	return OpID(NewBlockOperationKey(op, tx))
+}
+
+func ParseOpID(s string) (id OpID, err error) {
+	parts := strings.Split(s, "-")
+	if len(parts) != 2 || !IsValidHash(parts[0]) || !IsValidHash(parts[1]) {
+		err = sebakerror.ErrorInvalidHash
+		return
+	}
+
+	id = OpID(s)
+	return
+}
+
+func (id OpID) String() string {
+	return string(id)
+}
+
+// OperationHash returns the hash of operation; the same operation in the
+// different transactions has the same hash.
+func (id OpID) OperationHash() string {
+	return strings.SplitN(string(id), "-", 2)[0]
+}
+
+func (id OpID) TxHash() TxHash {
+	parts := strings.SplitN(string(id), "-", 2)
+	if len(parts) != 2 {
+		return ""
+	}
+
+	return TxHash(parts[1])
+}
+
+func (id OpID) StorageKey() string {
+	return GetBlockOperationKey(string(id))
+}
+
+// BlockID finds the block by `Height` or by `Hash`; only one of them is set.
+type BlockID struct {
+	Height uint64
+	Hash   string
+}
+
+func NewBlockIDFromHeight(height uint64) BlockID {
+	return BlockID{Height: height}
+}
+
+func NewBlockIDFromHash(hash string) BlockID {
+	return BlockID{Hash: hash}
+}
+
+// ParseBlockID parses the decimal height or the hash of block; the height
+// starts from 1.
+func ParseBlockID(s string) (id BlockID, err error) {
+	if len(s) > 0 && strings.Trim(s, "0123456789") == "" {
+		var height uint64
+		if height, err = strconv.ParseUint(s, 10, 64); err != nil || height < 1 {
+			err = sebakerror.ErrorInvalidBlockID
+			return
+		}
+		id = NewBlockIDFromHeight(height)
+		return
+	}
+
+	if !IsValidHash(s) {
+		err = sebakerror.ErrorInvalidBlockID
+		return
+	}
+
+	id = NewBlockIDFromHash(s)
+	return
+}
+
+func (id BlockID) IsHeight() bool {
+	return len(id.Hash) < 1
+}
+
+func (id BlockID) String() string {
+	if id.IsHeight() {
+		return strconv.FormatUint(id.Height, 10)
+	}
+
+	return id.Hash
+}
+
+func (id BlockID) StorageKey() string {
+	if id.IsHeight() {
+		return GetBlockKeyHeight(id.Height)
+	}
+
+	return GetBlockKey(id.Hash)
+}
+
+// GetBlockByID gets the block by the height or the hash; if not found, it
+// returns the error of storage like `GetBlock()`.
+func GetBlockByID(st *sebakstorage.LevelDBBackend, id BlockID) (Block, error) {
+	if id.IsHeight() {
+		return GetBlockByHeight(st, id.Height)
+	}
+
+	return GetBlock(st, id.Hash)
+}
+
+// SplitAPIPath splits the path of request under the pattern, like
+// '/accounts/' into the ID and the sub resources; for
+// '/api/v1/accounts/GABC/data', 'GABC' and ["data"] are returned. The path
+// can be under the prefix of any `APIVersion`. The ID is empty if the path is
+// not under the pattern.
+func SplitAPIPath(path, pattern string) (id string, sub []string) {
+	for _, version := range APIVersions {
+		prefix := version.Prefix() + pattern
+		if !strings.HasPrefix(path, prefix) {
+			continue
+		}
+
+		parts := strings.Split(strings.Trim(strings.TrimPrefix(path, prefix), "/"), "/")
+		id, sub = parts[0], parts[1:]
+		return
+	}
+
+	return
+}
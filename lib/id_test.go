@@ -0,0 +1,107 @@
+package sebak
+
+import (
+	"testing"
+
+	"github.com/stellar/go/keypair"
+
+	"boscoin.io/sebak/lib/error"
+)
+
+func TestParseIDs(t *testing.T) {
+	kp, _ := keypair.Random()
+
+	if address, err := ParseAccountAddress(kp.Address()); err != nil || address.String() != kp.Address() {
+		t.Errorf("failed to parse address: %v", err)
+		return
+	}
+	for _, s := range []string{"", "unknown", kp.Seed(), kp.Address() + "A"} {
+		if _, err := ParseAccountAddress(s); err != sebakerror.ErrorBadPublicAddress {
+			t.Errorf("'%s' must not be the address", s)
+			return
+		}
+	}
+
+	_, tx := TestMakeTransaction(networkID, 1)
+	if hash, err := ParseTxHash(tx.GetHash()); err != nil || hash.StorageKey() != GetBlockTransactionKey(tx.GetHash()) {
+		t.Errorf("failed to parse transaction hash: %v", err)
+		return
+	}
+	if _, err := ParseTxHash("unknown"); err != sebakerror.ErrorInvalidHash {
+		t.Error("'unknown' must not be the hash")
+		return
+	}
+
+	opID := NewOpID(tx.B.Operations[0], tx)
+	parsed, err := ParseOpID(opID.String())
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if parsed.OperationHash() != tx.B.Operations[0].MakeHashString() || parsed.TxHash().String() != tx.GetHash() {
+		t.Errorf("wrong operation id: %s", parsed)
+		return
+	}
+	for _, s := range []string{tx.GetHash(), tx.GetHash() + "-", opID.String() + "-" + tx.GetHash()} {
+		if _, err := ParseOpID(s); err == nil {
+			t.Errorf("'%s' must not be the operation id", s)
+			return
+		}
+	}
+
+	b := NewBlock(Block{}, "", tx.GetHash())
+	cases := []struct {
+		s        string
+		expected BlockID
+	}{
+		{"1", BlockID{Height: 1}},
+		{"18446744073709551615", BlockID{Height: 18446744073709551615}},
+		{b.Hash, BlockID{Hash: b.Hash}},
+	}
+	for _, c := range cases {
+		id, err := ParseBlockID(c.s)
+		if err != nil || id != c.expected || id.String() != c.s {
+			t.Errorf("wrong block id of '%s': %v %v", c.s, id, err)
+			return
+		}
+	}
+	if id, _ := ParseBlockID("3"); id.StorageKey() != GetBlockKeyHeight(3) {
+		t.Errorf("wrong storage key: %s", id.StorageKey())
+		return
+	}
+	for _, s := range []string{"", "0", "18446744073709551616", "-1", "unknown"} {
+		if _, err := ParseBlockID(s); err != sebakerror.ErrorInvalidBlockID {
+			t.Errorf("'%s' must not be the block id", s)
+			return
+		}
+	}
+}
+
+func TestSplitAPIPath(t *testing.T) {
+	cases := []struct {
+		path    string
+		pattern string
+		id      string
+		sub     []string
+	}{
+		{APIVersionPrefix + GetAccountsPattern + "GABC/data", GetAccountsPattern, "GABC", []string{"data"}},
+		{APIVersionPrefix + GetAccountsPattern + "GABC/", GetAccountsPattern, "GABC", []string{}},
+		{APIVersionPrefix + GetOperationsPattern + "a-b", GetOperationsPattern, "a-b", []string{}},
+		{APIVersionPrefix + GetOperationsPattern, GetOperationsPattern, "", []string{}},
+		{APIVersion2.Prefix() + GetAccountsPattern + "GABC/data", GetAccountsPattern, "GABC", []string{"data"}},
+		{"/unknown/GABC", GetAccountsPattern, "", nil},
+	}
+	for _, c := range cases {
+		id, sub := SplitAPIPath(c.path, c.pattern)
+		if id != c.id || len(sub) != len(c.sub) {
+			t.Errorf("wrong split of '%s': '%s' %v", c.path, id, sub)
+			return
+		}
+		for i := range sub {
+			if sub[i] != c.sub[i] {
+				t.Errorf("wrong split of '%s': '%s' %v", c.path, id, sub)
+				return
+			}
+		}
+	}
+}
@@ -2,6 +2,7 @@ package sebak
 
 import (
 	"os"
+	"sync/atomic"
 
 	logging "github.com/inconshreveable/log15"
 )
@@ -15,3 +16,30 @@ func SetLogging(level logging.Lvl, handler logging.Handler) {
 func init() {
 	SetLogging(logging.LvlCrit, logging.StreamHandler(os.Stdout, logging.TerminalFormat()))
 }
+
+// LogLevelHandler filters the records by the level, which can be changed
+// while the node is running, like by the admin API.
+type LogLevelHandler struct {
+	level   int32
+	handler logging.Handler
+}
+
+func NewLogLevelHandler(level logging.Lvl, handler logging.Handler) *LogLevelHandler {
+	return &LogLevelHandler{level: int32(level), handler: handler}
+}
+
+func (h *LogLevelHandler) Log(r *logging.Record) error {
+	if r.Lvl > h.Level() {
+		return nil
+	}
+
+	return h.handler.Log(r)
+}
+
+func (h *LogLevelHandler) Level() logging.Lvl {
+	return logging.Lvl(atomic.LoadInt32(&h.level))
+}
+
+func (h *LogLevelHandler) SetLevel(level logging.Lvl) {
+	atomic.StoreInt32(&h.level, int32(level))
+}
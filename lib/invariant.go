@@ -0,0 +1,210 @@
+package sebak
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"runtime/pprof"
+	"strconv"
+	"strings"
+	"time"
+
+	"boscoin.io/sebak/lib/error"
+	"boscoin.io/sebak/lib/storage"
+)
+
+// The invariants of node. In the release build, the violation is logged and
+// the node continues, but the qualification build, which is built with
+// `-tags qualification` halts the node at once with the diagnostics, so the
+// release candidate soaked in testnet can not hide the violation.
+//  * `state-hash`: the account state after the block is same with the state
+//  hash of block
+//  * `index`: the committed transaction has every index of it and it's
+//  operations
+//  * `fork`: the block announced by the validator is same with the finalized
+//  block of same height
+const (
+	InvariantStateHash string = "state-hash"
+	InvariantIndex     string = "index"
+	InvariantFork      string = "fork"
+)
+
+// InvariantInjection makes the check of `Invariant` fail at the block of
+// `Height`, so the halt of qualification build can be rehearsed
+// deterministically. It is set by `SEBAK_INJECT_INVARIANT`, like
+// 'state-hash@10' only in the qualification build.
+type InvariantInjection struct {
+	Invariant string
+	Height    uint64
+}
+
+var invariantInjection InvariantInjection
+
+func ParseInvariantInjection(s string) (injection InvariantInjection, err error) {
+	parsed := strings.SplitN(s, "@", 2)
+	if len(parsed) != 2 {
+		err = fmt.Errorf("invariant injection must be '<invariant>@<height>', '%s'", s)
+		return
+	}
+
+	switch parsed[0] {
+	case InvariantStateHash, InvariantIndex:
+	default:
+		err = fmt.Errorf("unknown invariant to inject, '%s'", parsed[0])
+		return
+	}
+
+	var height uint64
+	if height, err = strconv.ParseUint(parsed[1], 10, 64); err != nil {
+		return
+	}
+
+	injection = InvariantInjection{Invariant: parsed[0], Height: height}
+
+	return
+}
+
+func (i InvariantInjection) Injects(invariant string, height uint64) bool {
+	return i.Invariant == invariant && i.Height == height
+}
+
+// CheckTransactionIndexes checks the indexes of the committed transaction and
+// it's operations, which are saved by `BlockTransaction.Save()`. The indexes,
+// whose keys end with the unique id are found by their prefix and the hash in
+// the value.
+func CheckTransactionIndexes(st *sebakstorage.LevelDBBackend, tx Transaction) (err error) {
+	var saved BlockTransaction
+	if saved, err = GetBlockTransaction(st, tx.GetHash()); err != nil {
+		return
+	}
+
+	bt := NewBlockTransactionFromTransaction(tx, nil)
+	bt.Confirmed = saved.Confirmed
+
+	keys := []string{
+		GetBlockTransactionKeyCheckpoint(bt.Checkpoint),
+	}
+	type index struct {
+		prefix string
+		hash   string
+	}
+	indexes := []index{
+		{GetBlockTransactionKeyPrefixSource(bt.Source), bt.Hash},
+		{GetBlockTransactionKeyPrefixConfirmed(bt.Confirmed), bt.Hash},
+	}
+	for _, address := range bt.Accounts() {
+		keys = append(keys, bt.NewBlockTransactionKeyAccount(address))
+	}
+	for _, op := range tx.B.Operations {
+		bo := NewBlockOperationFromOperation(op, tx)
+		bo.Confirmed = bt.Confirmed
+		keys = append(keys, GetBlockOperationKey(bo.Hash))
+		indexes = append(
+			indexes,
+			index{GetBlockOperationKeyPrefixTxHash(bo.TxHash), bo.Hash},
+			index{GetBlockOperationKeyPrefixSource(bo.Source), bo.Hash},
+			index{GetBlockOperationKeyPrefixTarget(bo.Target), bo.Hash},
+			index{GetBlockOperationKeyPrefixCheckpoint(tx.B.Checkpoint), bo.Hash},
+		)
+		for _, address := range bo.Accounts() {
+			keys = append(keys, bo.NewBlockOperationKeyAccount(address))
+		}
+	}
+
+	for _, key := range keys {
+		var exists bool
+		if exists, err = st.Has(key); err != nil {
+			return
+		}
+		if !exists {
+			err = sebakerror.ErrorIndexInconsistent
+			return
+		}
+	}
+	for _, i := range indexes {
+		if !hasIndex(st, i.prefix, i.hash) {
+			err = sebakerror.ErrorIndexInconsistent
+			return
+		}
+	}
+
+	return
+}
+
+// hasIndex returns `true` if any key of `prefix` has the hash.
+func hasIndex(st *sebakstorage.LevelDBBackend, prefix, hash string) bool {
+	iterFunc, closeFunc := st.GetIterator(prefix, false)
+	defer closeFunc()
+
+	for {
+		item, hasNext := iterFunc()
+		if !hasNext {
+			return false
+		}
+
+		var value string
+		if json.Unmarshal(item.Value, &value) == nil && value == hash {
+			return true
+		}
+	}
+}
+
+// checkInvariants checks the invariants of the block, which is just committed
+// with `tx`. It runs only in the qualification build; making the state hash
+// again costs as much as committing the block.
+func (nr *NodeRunner) checkInvariants(tx Transaction) {
+	if !QualificationBuild {
+		return
+	}
+
+	latest, err := GetLatestBlock(nr.storage)
+	if err != nil {
+		nr.log.Error("failed to get the latest block to check invariants", "error", err)
+		return
+	}
+
+	err = VerifyStateHash(nr.storage)
+	if err == nil && invariantInjection.Injects(InvariantStateHash, latest.Height) {
+		err = errors.New("injected")
+	}
+	if err != nil {
+		nr.invariantViolated(InvariantStateHash, err, "height", latest.Height, "state-hash", latest.StateHash)
+		return
+	}
+
+	err = CheckTransactionIndexes(nr.storage, tx)
+	if err == nil && invariantInjection.Injects(InvariantIndex, latest.Height) {
+		err = errors.New("injected")
+	}
+	if err != nil {
+		nr.invariantViolated(InvariantIndex, err, "height", latest.Height, "transaction", tx.GetHash())
+		return
+	}
+}
+
+func (nr *NodeRunner) invariantViolated(invariant string, err error, ctx ...interface{}) {
+	nr.log.Crit("invariant violated", append([]interface{}{"invariant", invariant, "error", err}, ctx...)...)
+
+	nr.haltOnInvariantViolation(invariant, err, ctx...)
+}
+
+// writeInvariantDiagnostics writes the state of node and the stacks of all the
+// goroutines, when the invariant is violated.
+func (nr *NodeRunner) writeInvariantDiagnostics(w io.Writer, invariant string, err error, ctx ...interface{}) {
+	fmt.Fprintf(w, "invariant violated: %s: %v\n", invariant, err)
+	for i := 0; i+1 < len(ctx); i += 2 {
+		fmt.Fprintf(w, "  %v: %v\n", ctx[i], ctx[i+1])
+	}
+	fmt.Fprintf(w, "time: %s\n", time.Now().Format(time.RFC3339Nano))
+	fmt.Fprintf(w, "node: %s\n", nr.currentNode.Address())
+	fmt.Fprintf(w, "state: %s\n", nr.state.State())
+	if latest, e := GetLatestBlock(nr.storage); e == nil {
+		fmt.Fprintf(w, "latest block: %s\n", latest)
+	}
+	fmt.Fprintf(w, "connected validators: %d\n", nr.connectionManager.CountConnected())
+	fmt.Fprintf(w, "transactions in pool: %d\n", nr.transactionPool.Len())
+	fmt.Fprintf(w, "forks detected: %d\n\n", nr.ForksDetected())
+
+	pprof.Lookup("goroutine").WriteTo(w, 2)
+}
@@ -0,0 +1,37 @@
+// +build qualification
+
+package sebak
+
+import (
+	"os"
+)
+
+// QualificationBuild is `true` in the qualification build, which halts the
+// node on the invariant violation.
+const QualificationBuild bool = true
+
+// InvariantViolationExitCode is the exit code of the qualification build,
+// which is halted by the invariant violation.
+const InvariantViolationExitCode int = 3
+
+func init() {
+	s := os.Getenv("SEBAK_INJECT_INVARIANT")
+	if len(s) < 1 {
+		return
+	}
+
+	injection, err := ParseInvariantInjection(s)
+	if err != nil {
+		panic(err)
+	}
+	invariantInjection = injection
+}
+
+// haltOnInvariantViolation writes the diagnostics to stderr and exits at
+// once; the node must not commit any block after the violation.
+func (nr *NodeRunner) haltOnInvariantViolation(invariant string, err error, ctx ...interface{}) {
+	nr.writeInvariantDiagnostics(os.Stderr, invariant, err, ctx...)
+	nr.state.Transit(NodeStateHalted)
+
+	os.Exit(InvariantViolationExitCode)
+}
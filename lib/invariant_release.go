@@ -0,0 +1,9 @@
+// +build !qualification
+
+package sebak
+
+// QualificationBuild is `true` in the qualification build, which halts the
+// node on the invariant violation.
+const QualificationBuild bool = false
+
+func (nr *NodeRunner) haltOnInvariantViolation(string, error, ...interface{}) {}
@@ -0,0 +1,70 @@
+package sebak
+
+import (
+	"encoding/json"
+	"testing"
+
+	"boscoin.io/sebak/lib/error"
+	"boscoin.io/sebak/lib/storage"
+)
+
+func TestParseInvariantInjection(t *testing.T) {
+	injection, err := ParseInvariantInjection("state-hash@10")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if !injection.Injects(InvariantStateHash, 10) || injection.Injects(InvariantStateHash, 11) || injection.Injects(InvariantIndex, 10) {
+		t.Errorf("wrong injection: %v", injection)
+		return
+	}
+
+	for _, s := range []string{"", "state-hash", "state-hash@", "unknown@10", "fork@10", "index@-1"} {
+		if _, err := ParseInvariantInjection(s); err == nil {
+			t.Errorf("'%s' must be refused", s)
+			return
+		}
+	}
+}
+
+func TestCheckTransactionIndexes(t *testing.T) {
+	st, _ := sebakstorage.NewTestMemoryLevelDBBackend()
+	defer st.Close()
+
+	_, tx := TestMakeTransaction(networkID, 2)
+	if err := CheckTransactionIndexes(st, tx); err == nil {
+		t.Error("transaction, which is not saved must be refused")
+		return
+	}
+
+	bt := NewBlockTransactionFromTransaction(tx, tx.B.MakeHash())
+	if err := bt.Save(st); err != nil {
+		t.Error(err)
+		return
+	}
+	if err := CheckTransactionIndexes(st, tx); err != nil {
+		t.Error(err)
+		return
+	}
+
+	// remove the target index of the operation
+	bo := NewBlockOperationFromOperation(tx.B.Operations[1], tx)
+	iterFunc, closeFunc := st.GetIterator(GetBlockOperationKeyPrefixTarget(bo.Target), false)
+	for {
+		item, hasNext := iterFunc()
+		if !hasNext {
+			break
+		}
+		var hash string
+		json.Unmarshal(item.Value, &hash)
+		if hash == bo.Hash {
+			st.Remove(string(item.Key))
+		}
+	}
+	closeFunc()
+
+	if err := CheckTransactionIndexes(st, tx); err != sebakerror.ErrorIndexInconsistent {
+		t.Errorf("missing index must be found: %v", err)
+		return
+	}
+}
@@ -12,7 +12,9 @@ type ISAAC struct {
 	Node                  sebakcommon.Node
 	VotingThresholdPolicy sebakcommon.VotingThresholdPolicy
 
-	Boxes *BallotBoxes
+	Boxes   *BallotBoxes
+	signer  NodeSigner
+	signals []string // the features of `Upgrade`, which the node is ready for
 }
 
 func NewISAAC(networkID []byte, node sebakcommon.Node, votingThresholdPolicy sebakcommon.VotingThresholdPolicy) (is *ISAAC, err error) {
@@ -22,6 +24,7 @@ func NewISAAC(networkID []byte, node sebakcommon.Node, votingThresholdPolicy seb
 		VotingThresholdPolicy: votingThresholdPolicy,
 		Boxes: NewBallotBoxes(),
 	}
+	is.signer = NewKeypairNodeSigner(node, networkID)
 
 	return
 }
@@ -34,6 +37,20 @@ func (is *ISAAC) GetNode() sebakcommon.Node {
 	return is.Node
 }
 
+func (is *ISAAC) Signer() NodeSigner {
+	if len(is.signals) > 0 {
+		return upgradeSignalSigner{NodeSigner: is.signer, signals: is.signals}
+	}
+
+	return is.signer
+}
+
+// SetSigner replaces the signer of the node's own ballots; it must be called
+// before the node starts.
+func (is *ISAAC) SetSigner(signer NodeSigner) {
+	is.signer = signer
+}
+
 func (is *ISAAC) HasMessage(message sebakcommon.Message) bool {
 	return is.Boxes.HasMessage(message)
 }
@@ -60,11 +77,15 @@ func (is *ISAAC) ReceiveMessage(m sebakcommon.Message) (ballot Ballot, err error
 		return
 	}
 
+	ballot.B.ProposerSignals = is.signals
+
 	// self-sign; make new `Ballot` from `Message`
 	ballot.SetState(sebakcommon.BallotStateINIT)
 	ballot.Vote(VotingYES) // The initial ballot from client will have 'VotingYES'
 	ballot.UpdateHash()
-	ballot.Sign(is.Node.Keypair(), is.networkID)
+	if err = is.Signer().SignBallot(&ballot); err != nil {
+		return
+	}
 
 	if err = ballot.IsWellFormed(is.networkID); err != nil {
 		return
@@ -112,16 +133,22 @@ func (is *ISAAC) receiveBallotStateINIT(ballot Ballot) (vs VotingStateStaging, e
 
 	if isNew {
 		var newBallot Ballot
-		newBallot, err = NewBallotFromMessage(is.Node.Keypair().Address(), ballot.Data().Message())
+		newBallot, err = NewBallotFromMessage(is.Node.Address(), ballot.Data().Message())
 		if err != nil {
 			return
 		}
+		// every validator keeps the time and the signals of the first ballot,
+		// so the block is same in every node
+		newBallot.B.Proposed = ballot.B.Proposed
+		newBallot.B.ProposerSignals = ballot.B.ProposerSignals
 
 		// self-sign
 		newBallot.SetState(sebakcommon.BallotStateINIT)
 		newBallot.Vote(VotingYES) // The BallotStateINIT ballot will have 'VotingYES'
 		newBallot.UpdateHash()
-		newBallot.Sign(is.Node.Keypair(), is.networkID)
+		if err = is.Signer().SignBallot(&newBallot); err != nil {
+			return
+		}
 
 		if err = newBallot.IsWellFormed(is.networkID); err != nil {
 			return
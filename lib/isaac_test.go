@@ -3,7 +3,9 @@ package sebak
 import (
 	"encoding/json"
 	"errors"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/btcsuite/btcutil/base58"
 	"github.com/stellar/go/keypair"
@@ -70,8 +72,14 @@ func makeISAAC(minimumValidators int) *ISAAC {
 	return is
 }
 
+// testProposedTimes keeps the proposed time of message, so the ballots of the
+// validators have the same proposed time like the ballots of network.
+var testProposedTimes sync.Map
+
 func makeBallot(kp *keypair.Full, m sebakcommon.Message, state sebakcommon.BallotState) Ballot {
 	ballot, _ := NewBallotFromMessage(kp.Address(), m)
+	proposed, _ := testProposedTimes.LoadOrStore(m.GetHash(), ballot.B.Proposed)
+	ballot.B.Proposed = proposed.(string)
 	ballot.SetState(state)
 	ballot.Vote(VotingYES)
 	ballot.Sign(kp, networkID)
@@ -497,3 +505,82 @@ func TestISAACReceiveSameBallotStates(t *testing.T) {
 		}
 	}
 }
+
+func TestISAACBallotProposedTime(t *testing.T) {
+	is := makeISAAC(5)
+	m := NewDummyMessage(sebakcommon.GenerateUUID())
+
+	kp, _ := keypair.Random()
+	ballot := makeBallot(kp, m, sebakcommon.BallotStateINIT)
+	if _, err := is.ReceiveBallot(ballot); err != nil {
+		t.Error(err)
+		return
+	}
+
+	// the ballot of current node keeps the proposed time of the first ballot
+	if vr := is.Boxes.VotingResult(ballot); vr.Proposed != ballot.B.Proposed {
+		t.Errorf("wrong proposed time: %s", vr.Proposed)
+		return
+	}
+
+	// the ballot of the other proposed time is refused
+	kpOther, _ := keypair.Random()
+	other, _ := NewBallotFromMessage(kpOther.Address(), m)
+	other.B.Proposed = time.Now().Add(time.Second).Format(time.RFC3339Nano)
+	other.SetState(sebakcommon.BallotStateINIT)
+	other.Vote(VotingYES)
+	other.Sign(kpOther, networkID)
+	if _, err := is.ReceiveBallot(other); err != sebakerror.ErrorBallotInvalidProposedTime {
+		t.Errorf("ballot of the other proposed time must be refused: %v", err)
+		return
+	}
+
+	if ballot.IsProposedTimeDrifted(time.Now()) {
+		t.Error("proposed time must not be drifted")
+		return
+	}
+	if !ballot.IsProposedTimeDrifted(time.Now().Add(MaxBallotProposedDrift + time.Second)) {
+		t.Error("proposed time must be drifted")
+		return
+	}
+
+	// the proposed time must be after the latest block
+	proposed, _ := ballot.ProposedTime()
+	if !ballot.IsProposedAfter(NewBlockAt(Block{}, "", proposed.Add(-time.Second).Format(time.RFC3339Nano))) {
+		t.Error("proposed time must be after the previous block")
+		return
+	}
+	if ballot.IsProposedAfter(NewBlockAt(Block{}, "", ballot.B.Proposed)) {
+		t.Error("proposed time must not be same with the latest block")
+		return
+	}
+}
+
+func TestISAACBallotProposerSignals(t *testing.T) {
+	is := makeISAAC(5)
+	m := NewDummyMessage(sebakcommon.GenerateUUID())
+
+	kp, _ := keypair.Random()
+	ballot := makeBallot(kp, m, sebakcommon.BallotStateINIT)
+	ballot.B.ProposerSignals = []string{"fee-model.v2"}
+	ballot.Sign(kp, networkID)
+	if _, err := is.ReceiveBallot(ballot); err != nil {
+		t.Error(err)
+		return
+	}
+
+	// the ballot of current node keeps the signals of the first ballot
+	vr := is.Boxes.VotingResult(ballot)
+	if len(vr.Signals) != 1 || vr.Signals[0] != "fee-model.v2" {
+		t.Errorf("wrong signals of proposer: %v", vr.Signals)
+		return
+	}
+
+	// the ballot of the other signals of proposer is refused
+	kpOther, _ := keypair.Random()
+	other := makeBallot(kpOther, m, sebakcommon.BallotStateINIT)
+	if _, err := is.ReceiveBallot(other); err != sebakerror.ErrorBallotInvalidUpgradeSignals {
+		t.Errorf("ballot of the other signals of proposer must be refused: %v", err)
+		return
+	}
+}
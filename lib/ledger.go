@@ -0,0 +1,203 @@
+package sebak
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"boscoin.io/sebak/lib/storage"
+)
+
+// LedgerEntry is the double-entry record of one balance change; the amount
+// leaves the `Debit` account and enters the `Credit` account, so the sum of
+// debits and the sum of credits of the chain are always same. The entries are
+// append-only and ordered by `Sequence`. The storage should support,
+//  * find by `Sequence`
+//  * get list of account, which is the debit or the credit, by `Sequence`
+// The initial balances of genesis are debited from `LedgerAccountGenesis` and
+// the fees are credited to `LedgerAccountFee`, so the balance of account is
+// the sum of it's credits minus the sum of it's debits. The minted rewards are
+// debited from `LedgerAccountInflation`.
+
+const (
+	LedgerPrefixSequence  string = "le-sequence-"     // le-sequence-<LedgerEntry.Sequence>
+	LedgerPrefixAccount   string = "le-account-"      // le-account-<address>-<LedgerEntry.Sequence>
+	LedgerLastSequenceKey string = "le-last-sequence" // the sequence of the last entry
+)
+
+// The pseudo accounts, which balance the entries of the minted coins and the
+// fees.
+const (
+	LedgerAccountGenesis string = "genesis"
+	LedgerAccountFee     string = "fee"
+)
+
+type LedgerReason string
+
+const (
+	LedgerReasonGenesis       LedgerReason = "genesis"
+	LedgerReasonCreateAccount LedgerReason = "create-account"
+	LedgerReasonPayment       LedgerReason = "payment"
+	LedgerReasonFee           LedgerReason = "fee"
+	LedgerReasonReward        LedgerReason = "reward"
+)
+
+type LedgerEntry struct {
+	Sequence  uint64       `json:"sequence"`
+	TxHash    string       `json:"tx_hash,omitempty"`
+	Reason    LedgerReason `json:"reason"`
+	Debit     string       `json:"debit"`
+	Credit    string       `json:"credit"`
+	Amount    Amount       `json:"amount"`
+	Confirmed string       `json:"confirmed"`
+}
+
+// NewLedgerEntriesFromTransaction makes the entries of the operations of
+// transaction and the fee.
+func NewLedgerEntriesFromTransaction(tx Transaction, confirmed string) (entries []LedgerEntry) {
+	for _, op := range tx.B.Operations {
+		kind, err := GetOperationKind(op.H.Type)
+		if err != nil || len(kind.LedgerReason) < 1 {
+			continue
+		}
+
+		entries = append(entries, LedgerEntry{
+			TxHash:    tx.GetHash(),
+			Reason:    kind.LedgerReason,
+			Debit:     tx.B.Source,
+			Credit:    op.B.TargetAddress(),
+			Amount:    op.B.GetAmount(),
+			Confirmed: confirmed,
+		})
+	}
+
+	if fee := tx.TotalAmount(true) - tx.TotalAmount(false); fee > 0 {
+		entries = append(entries, LedgerEntry{
+			TxHash:    tx.GetHash(),
+			Reason:    LedgerReasonFee,
+			Debit:     tx.B.Source,
+			Credit:    LedgerAccountFee,
+			Amount:    fee,
+			Confirmed: confirmed,
+		})
+	}
+
+	return
+}
+
+func GetLedgerEntryKey(sequence uint64) string {
+	return fmt.Sprintf("%s%020d", LedgerPrefixSequence, sequence)
+}
+
+func GetLedgerEntryKeyPrefixAccount(address string) string {
+	return fmt.Sprintf("%s%s-", LedgerPrefixAccount, address)
+}
+
+func GetLedgerEntryKeyAccount(address string, sequence uint64) string {
+	return fmt.Sprintf("%s%020d", GetLedgerEntryKeyPrefixAccount(address), sequence)
+}
+
+func GetLedgerLastSequence(st *sebakstorage.LevelDBBackend) (sequence uint64, err error) {
+	var exists bool
+	if exists, err = st.Has(LedgerLastSequenceKey); err != nil || !exists {
+		return
+	}
+	err = st.Get(LedgerLastSequenceKey, &sequence)
+
+	return
+}
+
+// SaveLedgerEntries appends the entries with the next sequences; it should be
+// called in the same storage transaction with the balance changes.
+func SaveLedgerEntries(st *sebakstorage.LevelDBBackend, entries ...LedgerEntry) (err error) {
+	if len(entries) < 1 {
+		return
+	}
+
+	var last uint64
+	if last, err = GetLedgerLastSequence(st); err != nil {
+		return
+	}
+	isFirst := last == 0
+
+	for _, entry := range entries {
+		last++
+		entry.Sequence = last
+
+		if err = st.New(GetLedgerEntryKey(entry.Sequence), entry); err != nil {
+			return
+		}
+		if err = st.New(GetLedgerEntryKeyAccount(entry.Debit, entry.Sequence), entry.Sequence); err != nil {
+			return
+		}
+		if err = st.New(GetLedgerEntryKeyAccount(entry.Credit, entry.Sequence), entry.Sequence); err != nil {
+			return
+		}
+	}
+
+	if isFirst {
+		return st.New(LedgerLastSequenceKey, last)
+	}
+
+	return st.Set(LedgerLastSequenceKey, last)
+}
+
+func GetLedgerEntry(st *sebakstorage.LevelDBBackend, sequence uint64) (entry LedgerEntry, err error) {
+	err = st.Get(GetLedgerEntryKey(sequence), &entry)
+	return
+}
+
+// GetLedgerEntriesByAccount returns the entries of account in sequence order;
+// the entries after the sequence, `from` are returned. With 0, it starts from
+// the first or, in reverse, the last one.
+func GetLedgerEntriesByAccount(st *sebakstorage.LevelDBBackend, address string, from uint64, reverse bool) (
+	func() (LedgerEntry, bool),
+	func(),
+) {
+	var fromKey string
+	if from > 0 {
+		fromKey = GetLedgerEntryKeyAccount(address, from)
+	}
+	iterFunc, closeFunc := st.GetIteratorFrom(GetLedgerEntryKeyPrefixAccount(address), fromKey, reverse)
+
+	return (func() (LedgerEntry, bool) {
+			item, hasNext := iterFunc()
+			if !hasNext {
+				return LedgerEntry{}, false
+			}
+
+			var sequence uint64
+			json.Unmarshal(item.Value, &sequence)
+
+			entry, err := GetLedgerEntry(st, sequence)
+			if err != nil {
+				return LedgerEntry{}, false
+			}
+
+			return entry, hasNext
+		}), (func() {
+			closeFunc()
+		})
+}
+
+// GetLedgerBalance sums the entries of account; for the real account, it
+// must be same with the balance of `BlockAccount`. The pseudo accounts have
+// the negative balance, like `LedgerAccountGenesis`.
+func GetLedgerBalance(st *sebakstorage.LevelDBBackend, address string) (balance int64, err error) {
+	iterFunc, closeFunc := GetLedgerEntriesByAccount(st, address, 0, false)
+	defer closeFunc()
+
+	for {
+		entry, hasNext := iterFunc()
+		if !hasNext {
+			break
+		}
+		if entry.Credit == address {
+			balance += int64(entry.Amount)
+		}
+		if entry.Debit == address {
+			balance -= int64(entry.Amount)
+		}
+	}
+
+	return
+}
@@ -0,0 +1,97 @@
+package sebak
+
+import (
+	"testing"
+
+	"github.com/stellar/go/keypair"
+
+	"boscoin.io/sebak/lib/storage"
+)
+
+func TestLedgerEntries(t *testing.T) {
+	kp, _ := keypair.Random()
+	kpTarget, _ := keypair.Random()
+	st, _ := sebakstorage.NewTestMemoryLevelDBBackend()
+
+	genesis := NewGenesis(string(networkID))
+	genesis.Accounts = append(genesis.Accounts, GenesisAccount{Address: kp.Address(), Balance: Amount(10000)})
+	if err := genesis.Apply(st); err != nil {
+		t.Error(err)
+		return
+	}
+
+	tx := makeTransactionPayment(kp, kpTarget.Address(), Amount(100))
+	entries := NewLedgerEntriesFromTransaction(tx, "")
+	if len(entries) != 2 {
+		t.Errorf("payment and fee entries must be made: %v", entries)
+		return
+	}
+	if entries[0].Reason != LedgerReasonPayment || entries[0].Debit != kp.Address() || entries[0].Credit != kpTarget.Address() {
+		t.Errorf("wrong payment entry: %v", entries[0])
+		return
+	}
+	if entries[1].Reason != LedgerReasonFee || entries[1].Credit != LedgerAccountFee || entries[1].Amount != BaseFee {
+		t.Errorf("wrong fee entry: %v", entries[1])
+		return
+	}
+	if err := SaveLedgerEntries(st, entries...); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if last, _ := GetLedgerLastSequence(st); last != 3 {
+		t.Errorf("wrong last sequence: %d", last)
+		return
+	}
+
+	// the debits and the credits are balanced
+	expected := map[string]int64{
+		LedgerAccountGenesis: -10000,
+		kp.Address():         10000 - 100 - int64(BaseFee),
+		kpTarget.Address():   100,
+		LedgerAccountFee:     int64(BaseFee),
+	}
+	var sum int64
+	for address, e := range expected {
+		balance, err := GetLedgerBalance(st, address)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if balance != e {
+			t.Errorf("wrong ledger balance of %s: %d != %d", address, balance, e)
+			return
+		}
+		sum += balance
+	}
+	if sum != 0 {
+		t.Errorf("the ledger is not balanced: %d", sum)
+		return
+	}
+
+	collect := func(from uint64, reverse bool) (sequences []uint64) {
+		iterFunc, closeFunc := GetLedgerEntriesByAccount(st, kp.Address(), from, reverse)
+		defer closeFunc()
+		for {
+			entry, hasNext := iterFunc()
+			if !hasNext {
+				break
+			}
+			sequences = append(sequences, entry.Sequence)
+		}
+		return
+	}
+
+	if sequences := collect(0, false); len(sequences) != 3 || sequences[0] != 1 || sequences[2] != 3 {
+		t.Errorf("wrong entries of account: %v", sequences)
+		return
+	}
+	if sequences := collect(2, false); len(sequences) != 1 || sequences[0] != 3 {
+		t.Errorf("wrong entries after sequence: %v", sequences)
+		return
+	}
+	if sequences := collect(2, true); len(sequences) != 1 || sequences[0] != 1 {
+		t.Errorf("wrong entries before sequence in reverse: %v", sequences)
+		return
+	}
+}
@@ -0,0 +1,273 @@
+package sebak
+
+import (
+	"io/ioutil"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LoadShedder keeps the consensus of node healthy under the resource pressure.
+// The resources are sampled every block time and compared with the
+// watermarks; when any of them is exceeded, the node proposes the smaller
+// blocks, fewer transactions in one block time, and defers the non-essential
+// work by `LoadSheddingSchedule` until the pressure goes away. The ballots of
+// the other validators are always handled.
+
+type LoadLevel int
+
+const (
+	LoadLevelNormal LoadLevel = iota
+	LoadLevelHigh
+	LoadLevelCritical
+)
+
+func (l LoadLevel) String() string {
+	switch l {
+	case LoadLevelHigh:
+		return "high"
+	case LoadLevelCritical:
+		return "critical"
+	default:
+		return "normal"
+	}
+}
+
+// LoadCriticalRatio is how much the watermark must be exceeded to be
+// `LoadLevelCritical`.
+const LoadCriticalRatio float64 = 1.5
+
+// The number of transactions, which are proposed in one block time under the
+// pressure.
+const (
+	DefaultHighLoadProposalLimit     int = 10
+	DefaultCriticalLoadProposalLimit int = 1
+)
+
+// LoadWatermarks are the limits of resources; 0 is not watched.
+//  * `CPU`: the load average of 1 minute for one CPU, like 0.9; it is only
+//  available on linux
+//  * `Memory`: the bytes of heap in use
+//  * `DiskLatency`: the latency to write and remove the probe key of storage
+type LoadWatermarks struct {
+	CPU         float64
+	Memory      uint64
+	DiskLatency time.Duration
+}
+
+func (w LoadWatermarks) IsEmpty() bool {
+	return w.CPU <= 0 && w.Memory < 1 && w.DiskLatency <= 0
+}
+
+type LoadSample struct {
+	CPU         float64       `json:"cpu"`
+	Memory      uint64        `json:"memory"`
+	DiskLatency time.Duration `json:"disk_latency"`
+}
+
+// DeferrableWork is the non-essential work, which can be deferred under the
+// pressure and catch up later.
+type DeferrableWork string
+
+const (
+	// DeferrableWorkChainStats is the rollups of `ChainStats`; the deferred
+	// blocks are rolled up in order after the pressure goes away.
+	DeferrableWorkChainStats DeferrableWork = "chain-stats"
+)
+
+// LoadSheddingSchedule is the priority of the deferrable works; the work is
+// deferred from the level. The lower priority work is deferred earlier.
+var LoadSheddingSchedule = map[DeferrableWork]LoadLevel{
+	DeferrableWorkChainStats: LoadLevelHigh,
+}
+
+type LoadShedder struct {
+	sync.RWMutex
+
+	watermarks     LoadWatermarks
+	proposalLimits map[LoadLevel]int
+
+	level  LoadLevel
+	sample LoadSample
+}
+
+func NewLoadShedder(watermarks LoadWatermarks) *LoadShedder {
+	return &LoadShedder{
+		watermarks: watermarks,
+		proposalLimits: map[LoadLevel]int{
+			LoadLevelHigh:     DefaultHighLoadProposalLimit,
+			LoadLevelCritical: DefaultCriticalLoadProposalLimit,
+		},
+	}
+}
+
+func (ls *LoadShedder) Watermarks() LoadWatermarks {
+	return ls.watermarks
+}
+
+// loadRatio is how much the value exceeds the watermark; 0 if it is not
+// watched.
+func loadRatio(value, watermark float64) float64 {
+	if watermark <= 0 {
+		return 0
+	}
+
+	return value / watermark
+}
+
+// Update sets the new sample and returns the level of it; `changed` is true
+// when the level is different from the previous one.
+func (ls *LoadShedder) Update(sample LoadSample) (level LoadLevel, changed bool) {
+	var max float64
+	for _, ratio := range []float64{
+		loadRatio(sample.CPU, ls.watermarks.CPU),
+		loadRatio(float64(sample.Memory), float64(ls.watermarks.Memory)),
+		loadRatio(float64(sample.DiskLatency), float64(ls.watermarks.DiskLatency)),
+	} {
+		if ratio > max {
+			max = ratio
+		}
+	}
+
+	switch {
+	case max >= LoadCriticalRatio:
+		level = LoadLevelCritical
+	case max > 1:
+		level = LoadLevelHigh
+	default:
+		level = LoadLevelNormal
+	}
+
+	ls.Lock()
+	defer ls.Unlock()
+
+	ls.sample = sample
+	changed = level != ls.level
+	ls.level = level
+
+	return
+}
+
+func (ls *LoadShedder) Level() LoadLevel {
+	ls.RLock()
+	defer ls.RUnlock()
+
+	return ls.level
+}
+
+func (ls *LoadShedder) Sample() LoadSample {
+	ls.RLock()
+	defer ls.RUnlock()
+
+	return ls.sample
+}
+
+// Defers returns true if the work must be deferred at the current level.
+func (ls *LoadShedder) Defers(work DeferrableWork) bool {
+	from, found := LoadSheddingSchedule[work]
+	if !found {
+		return false
+	}
+
+	return ls.Level() >= from
+}
+
+// ProposalLimit returns the number of transactions, which can be proposed in
+// one block time; 0 is unlimited.
+func (ls *LoadShedder) ProposalLimit() int {
+	return ls.proposalLimits[ls.Level()]
+}
+
+// readLoadAverage reads the load average of 1 minute for one CPU from
+// '/proc/loadavg'; 0 if it is not available.
+func readLoadAverage() float64 {
+	b, err := ioutil.ReadFile("/proc/loadavg")
+	if err != nil {
+		return 0
+	}
+	fields := strings.Fields(string(b))
+	if len(fields) < 1 {
+		return 0
+	}
+	load, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return 0
+	}
+
+	return load / float64(runtime.NumCPU())
+}
+
+// sampleLoad measures the resources of node; the disk latency is measured by
+// the same probe of the readiness check.
+func (nr *NodeRunner) sampleLoad() (sample LoadSample) {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+
+	sample.CPU = readLoadAverage()
+	sample.Memory = m.HeapInuse
+
+	started := time.Now()
+	if err := nr.checkStorageWritable(); err != nil {
+		nr.log.Error("failed to measure the disk latency", "error", err)
+	}
+	sample.DiskLatency = time.Since(started)
+
+	return
+}
+
+// SetLoadWatermarks enables the load shedding; it must be called before the
+// node starts.
+func (nr *NodeRunner) SetLoadWatermarks(watermarks LoadWatermarks) {
+	if watermarks.IsEmpty() {
+		nr.loadShedder = nil
+		return
+	}
+
+	nr.loadShedder = NewLoadShedder(watermarks)
+}
+
+// LoadShedder returns nil if the load shedding is disabled.
+func (nr *NodeRunner) LoadShedder() *LoadShedder {
+	return nr.loadShedder
+}
+
+// shedLoad samples the resources and, without the pressure, catches up the
+// deferred works. The works deferred before the restart are caught up even if
+// the load shedding is disabled.
+func (nr *NodeRunner) shedLoad() {
+	if nr.loadShedder != nil {
+		sample := nr.sampleLoad()
+		if level, changed := nr.loadShedder.Update(sample); changed {
+			nr.log.Warn(
+				"load level changed",
+				"level", level,
+				"cpu", sample.CPU,
+				"memory", sample.Memory,
+				"disk-latency", sample.DiskLatency,
+			)
+		}
+	}
+
+	if nr.defersWork(DeferrableWorkChainStats) {
+		return
+	}
+	if _, err := CatchUpChainStats(nr.storage, ChainStatsCatchUpBlocks); err != nil {
+		nr.log.Error("failed to catch up the chain stats", "error", err)
+	}
+}
+
+// defersWork is false if the load shedding is disabled.
+func (nr *NodeRunner) defersWork(work DeferrableWork) bool {
+	return nr.loadShedder != nil && nr.loadShedder.Defers(work)
+}
+
+// proposalLimit is 0, unlimited if the load shedding is disabled.
+func (nr *NodeRunner) proposalLimit() int {
+	if nr.loadShedder == nil {
+		return 0
+	}
+
+	return nr.loadShedder.ProposalLimit()
+}
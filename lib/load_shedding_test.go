@@ -0,0 +1,100 @@
+package sebak
+
+import (
+	"testing"
+	"time"
+
+	"boscoin.io/sebak/lib/storage"
+)
+
+func TestLoadShedder(t *testing.T) {
+	ls := NewLoadShedder(LoadWatermarks{CPU: 0.8, DiskLatency: time.Millisecond * 100})
+
+	cases := []struct {
+		sample   LoadSample
+		expected LoadLevel
+	}{
+		{LoadSample{CPU: 0.5, Memory: 1 << 40, DiskLatency: time.Millisecond}, LoadLevelNormal},
+		{LoadSample{CPU: 0.9}, LoadLevelHigh},
+		{LoadSample{CPU: 0.5, DiskLatency: time.Millisecond * 200}, LoadLevelCritical},
+	}
+	for _, c := range cases {
+		if level, _ := ls.Update(c.sample); level != c.expected || ls.Level() != c.expected {
+			t.Errorf("wrong level of %v: %s != %s", c.sample, level, c.expected)
+			return
+		}
+	}
+
+	if _, changed := ls.Update(LoadSample{DiskLatency: time.Millisecond * 300}); changed {
+		t.Error("level must not be changed")
+		return
+	}
+	if !ls.Defers(DeferrableWorkChainStats) || ls.ProposalLimit() != DefaultCriticalLoadProposalLimit {
+		t.Error("works must be deferred at critical level")
+		return
+	}
+
+	if level, changed := ls.Update(LoadSample{}); level != LoadLevelNormal || !changed {
+		t.Error("level must be changed to normal")
+		return
+	}
+	if ls.Defers(DeferrableWorkChainStats) || ls.ProposalLimit() != 0 {
+		t.Error("works must not be deferred at normal level")
+		return
+	}
+}
+
+func TestCatchUpChainStats(t *testing.T) {
+	st, _ := sebakstorage.NewTestMemoryLevelDBBackend()
+	defer st.Close()
+
+	var prev Block
+	for i := 0; i < 3; i++ {
+		_, tx := TestMakeTransaction(networkID, 1)
+		message, _ := tx.Serialize()
+		bt := NewBlockTransactionFromTransaction(tx, message)
+		if err := bt.Save(st); err != nil {
+			t.Error(err)
+			return
+		}
+
+		prev = NewBlock(prev, "", tx.GetHash())
+		if err := prev.Save(st); err != nil {
+			t.Error(err)
+			return
+		}
+		if err := deferChainStats(st, prev); err != nil {
+			t.Error(err)
+			return
+		}
+	}
+
+	if stats, _ := GetChainStats(st, ChainStatsPeriodEpoch, 1); len(stats) != 0 {
+		t.Errorf("deferred blocks must not be rolled up: %v", stats)
+		return
+	}
+
+	if done, err := CatchUpChainStats(st, 2); err != nil || done {
+		t.Errorf("blocks must be left: %v", err)
+		return
+	}
+	if stats, _ := GetChainStats(st, ChainStatsPeriodEpoch, 1); len(stats) != 1 || stats[0].TransactionCount != 2 {
+		t.Errorf("wrong stats: %v", stats)
+		return
+	}
+
+	if done, err := CatchUpChainStats(st, 2); err != nil || !done {
+		t.Errorf("all blocks must be rolled up: %v", err)
+		return
+	}
+	if pending, _ := HasPendingChainStats(st); pending {
+		t.Error("pending marker must be removed")
+		return
+	}
+
+	stats, _ := GetChainStats(st, ChainStatsPeriodEpoch, 1)
+	if len(stats) != 1 || stats[0].TransactionCount != 3 || stats[0].FirstHeight != 1 || stats[0].LastHeight != 3 {
+		t.Errorf("wrong stats: %v", stats)
+		return
+	}
+}
@@ -0,0 +1,102 @@
+package sebak
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/btcsuite/btcutil/base58"
+
+	"boscoin.io/sebak/lib/error"
+)
+
+// Memo is attached to the payment, so the receiver, like the exchange can
+// credit the deposit to it's user. The memo is one of,
+//  * `text`: the text of `MaxMemoTextSize` bytes
+//  * `hash`: the base58 encoded 32 bytes, like the hash of invoice
+//  * `id`: the unsigned 64 bit integer
+type Memo struct {
+	Type  MemoType `json:"type"`
+	Value string   `json:"value"`
+}
+
+type MemoType string
+
+const (
+	MemoText MemoType = "text"
+	MemoHash MemoType = "hash"
+	MemoID   MemoType = "id"
+)
+
+// MaxMemoTextSize is the maximum size of the `text` memo in bytes.
+const MaxMemoTextSize int = 28
+
+func NewMemo(t MemoType, value string) Memo {
+	return Memo{Type: t, Value: value}
+}
+
+func (m Memo) IsWellFormed() (err error) {
+	switch m.Type {
+	case MemoText:
+		if len(m.Value) < 1 || len(m.Value) > MaxMemoTextSize {
+			err = fmt.Errorf("invalid `text` memo: size must be between 1 and %d bytes", MaxMemoTextSize)
+			return
+		}
+	case MemoHash:
+		if len(base58.Decode(m.Value)) != 32 {
+			err = fmt.Errorf("invalid `hash` memo: must be base58 encoded 32 bytes")
+			return
+		}
+	case MemoID:
+		if _, err = strconv.ParseUint(m.Value, 10, 64); err != nil {
+			err = fmt.Errorf("invalid `id` memo: must be unsigned 64 bit integer")
+			return
+		}
+	default:
+		err = fmt.Errorf("unknown memo type: '%s'", m.Type)
+		return
+	}
+
+	return
+}
+
+// Equal checks the memo is same; the `id` memo is compared by it's number,
+// so '007' is same with '7'.
+func (m Memo) Equal(other Memo) bool {
+	if m.Type != other.Type {
+		return false
+	}
+	if m.Type == MemoID {
+		a, errA := strconv.ParseUint(m.Value, 10, 64)
+		b, errB := strconv.ParseUint(other.Value, 10, 64)
+		return errA == nil && errB == nil && a == b
+	}
+
+	return m.Value == other.Value
+}
+
+func newMemoFromInterface(v interface{}) (m *Memo, err error) {
+	if v == nil {
+		return
+	}
+
+	body, ok := v.(map[string]interface{})
+	if !ok {
+		err = sebakerror.ErrorInvalidOperation
+		return
+	}
+
+	t, ok := body["type"].(string)
+	if !ok {
+		err = sebakerror.ErrorInvalidOperation
+		return
+	}
+	value, ok := body["value"].(string)
+	if !ok {
+		err = sebakerror.ErrorInvalidOperation
+		return
+	}
+
+	m = &Memo{Type: MemoType(t), Value: value}
+
+	return
+}
@@ -0,0 +1,89 @@
+package sebak
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/btcsuite/btcutil/base58"
+	"github.com/stellar/go/keypair"
+
+	"boscoin.io/sebak/lib/common"
+)
+
+func TestMemoIsWellFormed(t *testing.T) {
+	valid := []Memo{
+		NewMemo(MemoText, "deposit"),
+		NewMemo(MemoText, strings.Repeat("a", MaxMemoTextSize)),
+		NewMemo(MemoHash, base58.Encode(sebakcommon.MakeHash([]byte("invoice")))),
+		NewMemo(MemoID, "18446744073709551615"),
+	}
+	for _, m := range valid {
+		if err := m.IsWellFormed(); err != nil {
+			t.Errorf("'%v' must be well-formed: %v", m, err)
+			return
+		}
+	}
+
+	invalid := []Memo{
+		NewMemo(MemoText, ""),
+		NewMemo(MemoText, strings.Repeat("a", MaxMemoTextSize+1)),
+		NewMemo(MemoHash, "invoice"),
+		NewMemo(MemoID, "-1"),
+		NewMemo(MemoID, "18446744073709551616"),
+		NewMemo("return", "1"),
+	}
+	for _, m := range invalid {
+		if err := m.IsWellFormed(); err == nil {
+			t.Errorf("'%v' must not be well-formed", m)
+			return
+		}
+	}
+
+	if !NewMemo(MemoID, "007").Equal(NewMemo(MemoID, "7")) {
+		t.Error("`id` memo must be compared by number")
+		return
+	}
+	if NewMemo(MemoID, "7").Equal(NewMemo(MemoText, "7")) {
+		t.Error("memo of the other type must not be same")
+		return
+	}
+}
+
+func TestOperationBodyPaymentMemo(t *testing.T) {
+	kp := keypair.Master("find me")
+
+	body := NewOperationBodyPaymentWithMemo(kp.Address(), Amount(100), NewMemo(MemoID, "12345"))
+	op, err := NewOperation(OperationPayment, body)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	// the memo changes the hash
+	plain, _ := NewOperation(OperationPayment, NewOperationBodyPayment(kp.Address(), Amount(100)))
+	if op.MakeHashString() == plain.MakeHashString() {
+		t.Error("memo must be hashed")
+		return
+	}
+
+	encoded, _ := op.Serialize()
+	decoded, err := NewOperationFromBytes(encoded)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if decoded.MakeHashString() != op.MakeHashString() {
+		t.Errorf("wrong operation: %v", decoded)
+		return
+	}
+	if m := decoded.B.(OperationBodyPayment).Memo; m == nil || *m != *body.Memo {
+		t.Errorf("wrong memo: %v", m)
+		return
+	}
+
+	body.Memo = &Memo{Type: MemoText, Value: strings.Repeat("a", MaxMemoTextSize+1)}
+	if _, err = NewOperation(OperationPayment, body); err == nil {
+		t.Error("malformed memo must be refused")
+		return
+	}
+}
@@ -0,0 +1,89 @@
+package sebak
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/btcsuite/btcutil/base58"
+
+	"boscoin.io/sebak/lib/error"
+	"boscoin.io/sebak/lib/network"
+)
+
+// MessageSigner signs the messages of the node to the other nodes, which
+// authenticate the sender by it. The signature is for the transport, so it is
+// not made by `ThresholdNodeSigner`; the node of the signer daemons signs the
+// messages by the session key.
+type MessageSigner interface {
+	SignMessage(hash string) (sebaknetwork.MessageAuth, error)
+}
+
+func (s *KeypairNodeSigner) SignMessage(hash string) (auth sebaknetwork.MessageAuth, err error) {
+	if s.node.Keypair() == nil {
+		err = errors.New("node does not have the keypair")
+		return
+	}
+
+	var signature []byte
+	if signature, err = s.node.Keypair().Sign(append(s.networkID, []byte(hash)...)); err != nil {
+		return
+	}
+
+	auth = sebaknetwork.MessageAuth{NodeKey: s.node.Address(), Signature: base58.Encode(signature)}
+
+	return
+}
+
+func (s *SessionKeyNodeSigner) SignMessage(hash string) (auth sebaknetwork.MessageAuth, err error) {
+	var certificate []byte
+	if certificate, err = s.certificate.Serialize(); err != nil {
+		return
+	}
+
+	auth = sebaknetwork.MessageAuth{
+		NodeKey:     s.certificate.B.Identity,
+		Signature:   s.sign(hash),
+		Certificate: certificate,
+	}
+
+	return
+}
+
+// SetMessageSigner sets the signer of the messages to the other nodes; by
+// default, the node signs by it's keypair.
+func (nr *NodeRunner) SetMessageSigner(signer MessageSigner) {
+	nr.messageSigner = signer
+}
+
+func (nr *NodeRunner) signMessage(hash string) (auth sebaknetwork.MessageAuth, err error) {
+	if nr.messageSigner == nil {
+		err = errors.New("message signer is not set")
+		return
+	}
+
+	return nr.messageSigner.SignMessage(hash)
+}
+
+// verifyMessage authenticates the message from the other node; it must be
+// signed by the known validator or the node itself, or by their session key,
+// which is not expired.
+func (nr *NodeRunner) verifyMessage(auth sebaknetwork.MessageAuth, hash string) (err error) {
+	if auth.NodeKey != nr.currentNode.Address() && !nr.currentNode.HasValidators(auth.NodeKey) {
+		err = sebakerror.ErrorMessageNotFromValidator
+		return
+	}
+
+	var certificate *SessionKeyCertificate
+	if len(auth.Certificate) > 0 {
+		certificate = &SessionKeyCertificate{}
+		if err = json.Unmarshal(auth.Certificate, certificate); err != nil {
+			return
+		}
+	}
+
+	if err = VerifyNodeSignature(auth.NodeKey, certificate, nr.networkID, hash, auth.Signature); err != nil {
+		return
+	}
+
+	return nr.checkSessionKeyCertificate(certificate)
+}
@@ -0,0 +1,91 @@
+package sebak
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stellar/go/keypair"
+
+	"boscoin.io/sebak/lib/common"
+	"boscoin.io/sebak/lib/error"
+	"boscoin.io/sebak/lib/network"
+)
+
+func TestNodeRunnerMessageAuth(t *testing.T) {
+	defer sebaknetwork.CleanUpMemoryNetwork()
+
+	nodeRunners := createNodeRunners(2)
+	nr0, nr1 := nodeRunners[0], nodeRunners[1]
+
+	hash := sebaknetwork.MessageAuthHash(sebaknetwork.BallotMessage, []byte("{}"))
+	auth, err := nr0.signMessage(hash)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if err = nr1.verifyMessage(auth, hash); err != nil {
+		t.Errorf("message of validator must be verified: %v", err)
+		return
+	}
+
+	other := sebaknetwork.MessageAuthHash(sebaknetwork.ViewChangeMessage, []byte("{}"))
+	if err = nr1.verifyMessage(auth, other); err != sebakerror.ErrorSignatureVerificationFailed {
+		t.Errorf("mis-signed message must be refused: %v", err)
+		return
+	}
+
+	// the node, which is not the validator
+	kp, _ := keypair.Random()
+	unknown, _ := sebakcommon.NewValidator(kp.Address(), nil, "")
+	unknown.SetKeypair(kp)
+	auth, _ = NewKeypairNodeSigner(unknown, networkID).SignMessage(hash)
+	if err = nr1.verifyMessage(auth, hash); err != sebakerror.ErrorMessageNotFromValidator {
+		t.Errorf("message of unknown node must be refused: %v", err)
+		return
+	}
+
+	// signed by the session key of validator
+	b := NewBlock(Block{}, "", hash)
+	if err = b.Save(nr1.Storage()); err != nil {
+		t.Error(err)
+		return
+	}
+	_, signer := makeSessionKeyNodeSigner(t, nr0.Node().Keypair(), 10)
+	nr0.SetMessageSigner(signer)
+	if auth, err = nr0.signMessage(hash); err != nil {
+		t.Error(err)
+		return
+	}
+	if err = nr1.verifyMessage(auth, hash); err != nil {
+		t.Errorf("message signed by the session key must be verified: %v", err)
+		return
+	}
+}
+
+func TestMemoryNetworkMessageAuth(t *testing.T) {
+	defer sebaknetwork.CleanUpMemoryNetwork()
+
+	nodeRunners := createNodeRunners(2)
+	nr0, nr1 := nodeRunners[0], nodeRunners[1]
+	for _, nr := range nodeRunners {
+		nr.Network().SetContext(nr.ctx)
+	}
+	go nr0.Network().Start()
+
+	vc := NewViewChange(nr1.Node().Address(), 1, 1)
+	vc.Sign(nr1.Node().Keypair(), networkID)
+
+	go nr1.Network().GetClient(nr0.Node().Endpoint()).SendViewChange(vc)
+	if message := <-nr0.Network().ReceiveMessage(); message.Type != sebaknetwork.ViewChangeMessage {
+		t.Errorf("signed view change must be received: %v", message)
+		return
+	}
+
+	// the network without the signer
+	mn := sebaknetwork.NewMemoryNetwork()
+	mn.SetContext(context.Background())
+	if err := mn.GetClient(nr0.Node().Endpoint()).SendViewChange(vc); err != sebakerror.ErrorMessageNotSigned {
+		t.Errorf("unsigned view change must be refused: %v", err)
+		return
+	}
+}
@@ -0,0 +1,461 @@
+package sebaknetwork
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"boscoin.io/sebak/lib/common"
+)
+
+// AdmissionController decides which inbound connections are accepted before
+// their TLS handshake, so the flood of connections can not exhaust the
+// validator. It limits,
+//  * the TLS handshakes in progress of the whole listener
+//  * the connections of one IP
+//  * the new connections of one IP by token bucket
+// The IP, which violates the limits `AdmissionStrikes` times is banned; the
+// ban of the same IP gets twice longer up to `MaxBanDuration`, and it is
+// forgiven after `MaxBanDuration` without violation. The trusted IPs, like the
+// validators are not limited.
+
+type AdmissionConfig struct {
+	MaxHandshakes       int     // 0 is unlimited
+	MaxConnectionsPerIP int     // 0 is unlimited
+	HandshakeRate       float64 // new connections of one IP in a second; 0 is unlimited
+	HandshakeBurst      int
+	HandshakeTimeout    time.Duration
+	BanDuration         time.Duration // 0 disables ban
+	MaxBanDuration      time.Duration
+}
+
+// AdmissionStrikes is the number of violations, which bans the IP.
+const AdmissionStrikes int = 3
+
+const AdmissionPruneInterval time.Duration = time.Minute
+
+func NewDefaultAdmissionConfig() AdmissionConfig {
+	return AdmissionConfig{
+		MaxHandshakes:       128,
+		MaxConnectionsPerIP: 32,
+		HandshakeRate:       5,
+		HandshakeBurst:      10,
+		HandshakeTimeout:    5 * time.Second,
+		BanDuration:         time.Minute,
+		MaxBanDuration:      time.Hour,
+	}
+}
+
+// parseAdmissionConfig parses the queries of endpoint, like
+// 'MaxConnectionsPerIP=16'; the missing ones are the defaults.
+func parseAdmissionConfig(query url.Values) (config AdmissionConfig, err error) {
+	config = NewDefaultAdmissionConfig()
+
+	ints := map[string]*int{
+		"MaxHandshakes":       &config.MaxHandshakes,
+		"MaxConnectionsPerIP": &config.MaxConnectionsPerIP,
+		"HandshakeBurst":      &config.HandshakeBurst,
+	}
+	for key, v := range ints {
+		if *v, err = strconv.Atoi(sebakcommon.GetUrlQuery(query, key, strconv.Itoa(*v))); err != nil || *v < 0 {
+			err = fmt.Errorf("invalid '%s'", key)
+			return
+		}
+	}
+
+	if config.HandshakeRate, err = strconv.ParseFloat(
+		sebakcommon.GetUrlQuery(query, "HandshakeRate", strconv.FormatFloat(config.HandshakeRate, 'f', -1, 64)),
+		64,
+	); err != nil || config.HandshakeRate < 0 {
+		err = errors.New("invalid 'HandshakeRate'")
+		return
+	}
+	if config.HandshakeRate > 0 && config.HandshakeBurst < 1 {
+		err = errors.New("'HandshakeBurst' must be given with 'HandshakeRate'")
+		return
+	}
+
+	durations := map[string]*time.Duration{
+		"HandshakeTimeout": &config.HandshakeTimeout,
+		"BanDuration":      &config.BanDuration,
+		"MaxBanDuration":   &config.MaxBanDuration,
+	}
+	for key, v := range durations {
+		if *v, err = time.ParseDuration(sebakcommon.GetUrlQuery(query, key, v.String())); err != nil || *v < 0 {
+			err = fmt.Errorf("invalid '%s'", key)
+			return
+		}
+	}
+	if config.MaxBanDuration < config.BanDuration {
+		err = errors.New("'MaxBanDuration' must not be less than 'BanDuration'")
+		return
+	}
+
+	return
+}
+
+type AdmissionRejectReason string
+
+const (
+	AdmissionRejectBanned            AdmissionRejectReason = "banned"
+	AdmissionRejectTooManyHandshakes AdmissionRejectReason = "too-many-handshakes"
+	AdmissionRejectTooManyConns      AdmissionRejectReason = "too-many-connections"
+	AdmissionRejectHandshakeRate     AdmissionRejectReason = "handshake-rate"
+	AdmissionRejectHandshakeFailed   AdmissionRejectReason = "handshake-failed"
+)
+
+type admissionPeer struct {
+	conns       int
+	tokens      float64
+	updated     time.Time
+	strikes     int
+	lastStrike  time.Time
+	bans        uint
+	bannedUntil time.Time
+}
+
+type AdmissionController struct {
+	sync.Mutex
+
+	config     AdmissionConfig
+	trusted    []*net.IPNet
+	bans       *PeerBanList // the peers banned by their messages; nil if they are not banned
+	peers      map[string]*admissionPeer
+	handshakes int
+	rejected   map[AdmissionRejectReason]uint64
+	lastPrune  time.Time
+
+	now func() time.Time
+}
+
+func NewAdmissionController(config AdmissionConfig) *AdmissionController {
+	return &AdmissionController{
+		config:    config,
+		peers:     map[string]*admissionPeer{},
+		rejected:  map[AdmissionRejectReason]uint64{},
+		lastPrune: time.Now(),
+		now:       time.Now,
+	}
+}
+
+func (a *AdmissionController) Config() AdmissionConfig {
+	return a.config
+}
+
+// Trust exempts the IPs of hosts from the limits; the host names are
+// resolved once.
+func (a *AdmissionController) Trust(hosts ...string) {
+	var nets []*net.IPNet
+	for _, host := range hosts {
+		ips, err := net.LookupIP(host)
+		if err != nil {
+			log.Warn("failed to resolve trusted host", "host", host, "error", err)
+			continue
+		}
+		for _, ip := range ips {
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+		}
+	}
+
+	a.Lock()
+	defer a.Unlock()
+
+	a.trusted = append(a.trusted, nets...)
+}
+
+// SetBanList refuses the connections of the IPs banned by `PeerBanList`.
+func (a *AdmissionController) SetBanList(bans *PeerBanList) {
+	a.Lock()
+	defer a.Unlock()
+
+	a.bans = bans
+}
+
+func (a *AdmissionController) isTrusted(ip string) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, n := range a.trusted {
+		if n.Contains(parsed) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (a *AdmissionController) reject(ip string, peer *admissionPeer, reason AdmissionRejectReason, strike bool) (bool, AdmissionRejectReason) {
+	a.rejected[reason]++
+	if strike {
+		a.strike(ip, peer)
+	}
+
+	return false, reason
+}
+
+func (a *AdmissionController) strike(ip string, peer *admissionPeer) {
+	if a.config.BanDuration <= 0 {
+		return
+	}
+
+	now := a.now()
+	if now.Sub(peer.lastStrike) > a.config.MaxBanDuration {
+		peer.strikes, peer.bans = 0, 0
+	}
+	peer.lastStrike = now
+	peer.strikes++
+	if peer.strikes < AdmissionStrikes {
+		return
+	}
+
+	peer.strikes = 0
+	peer.bans++
+	d := a.config.BanDuration * time.Duration(math.Pow(2, float64(peer.bans-1)))
+	if d > a.config.MaxBanDuration || d <= 0 {
+		d = a.config.MaxBanDuration
+	}
+	peer.bannedUntil = now.Add(d)
+	// the ban must last over the forgiving period of the strikes
+	peer.lastStrike = peer.bannedUntil
+
+	log.Warn("inbound IP banned", "ip", ip, "duration", d, "bans", peer.bans)
+}
+
+// Admit checks the new connection from the IP; if it is admitted, the
+// handshake must be finished by `HandshakeDone()` and the closed connection
+// must be released by `Release()`.
+func (a *AdmissionController) Admit(ip string) (bool, AdmissionRejectReason) {
+	a.Lock()
+	defer a.Unlock()
+
+	if a.isTrusted(ip) {
+		return true, ""
+	}
+
+	now := a.now()
+	a.prune(now)
+
+	peer, found := a.peers[ip]
+	if !found {
+		peer = &admissionPeer{tokens: float64(a.config.HandshakeBurst), updated: now}
+		a.peers[ip] = peer
+	}
+
+	if now.Before(peer.bannedUntil) || (a.bans != nil && a.bans.IsBanned(ip)) {
+		return a.reject(ip, peer, AdmissionRejectBanned, false)
+	}
+	if a.config.MaxConnectionsPerIP > 0 && peer.conns >= a.config.MaxConnectionsPerIP {
+		return a.reject(ip, peer, AdmissionRejectTooManyConns, true)
+	}
+	if a.config.HandshakeRate > 0 {
+		peer.tokens = math.Min(
+			float64(a.config.HandshakeBurst),
+			peer.tokens+now.Sub(peer.updated).Seconds()*a.config.HandshakeRate,
+		)
+		peer.updated = now
+		if peer.tokens < 1 {
+			return a.reject(ip, peer, AdmissionRejectHandshakeRate, true)
+		}
+	}
+	// the full handshakes are not the fault of one IP
+	if a.config.MaxHandshakes > 0 && a.handshakes >= a.config.MaxHandshakes {
+		return a.reject(ip, peer, AdmissionRejectTooManyHandshakes, false)
+	}
+
+	if a.config.HandshakeRate > 0 {
+		peer.tokens--
+	}
+	peer.conns++
+	a.handshakes++
+
+	return true, ""
+}
+
+// HandshakeDone finishes the handshake of the admitted connection; the failed
+// handshake, like the timeout counts one strike.
+func (a *AdmissionController) HandshakeDone(ip string, ok bool) {
+	a.Lock()
+	defer a.Unlock()
+
+	if a.isTrusted(ip) {
+		return
+	}
+
+	a.handshakes--
+	if !ok {
+		a.rejected[AdmissionRejectHandshakeFailed]++
+		if peer, found := a.peers[ip]; found {
+			a.strike(ip, peer)
+		}
+	}
+}
+
+func (a *AdmissionController) Release(ip string) {
+	a.Lock()
+	defer a.Unlock()
+
+	if peer, found := a.peers[ip]; found && peer.conns > 0 {
+		peer.conns--
+	}
+}
+
+// prune removes the IPs, which have no connection, no ban and no strike to
+// remember.
+func (a *AdmissionController) prune(now time.Time) {
+	if now.Sub(a.lastPrune) < AdmissionPruneInterval {
+		return
+	}
+	a.lastPrune = now
+
+	for ip, peer := range a.peers {
+		if peer.conns > 0 || now.Before(peer.bannedUntil) || now.Sub(peer.lastStrike) <= a.config.MaxBanDuration {
+			continue
+		}
+		if peer.tokens+now.Sub(peer.updated).Seconds()*a.config.HandshakeRate < float64(a.config.HandshakeBurst) {
+			continue
+		}
+		delete(a.peers, ip)
+	}
+}
+
+// Rejected returns the number of rejected connections by reason.
+func (a *AdmissionController) Rejected() map[AdmissionRejectReason]uint64 {
+	a.Lock()
+	defer a.Unlock()
+
+	rejected := map[AdmissionRejectReason]uint64{}
+	for reason, n := range a.rejected {
+		rejected[reason] = n
+	}
+
+	return rejected
+}
+
+// Banned returns the number of IPs banned now.
+func (a *AdmissionController) Banned() (banned int) {
+	a.Lock()
+	defer a.Unlock()
+
+	now := a.now()
+	for _, peer := range a.peers {
+		if now.Before(peer.bannedUntil) {
+			banned++
+		}
+	}
+
+	return
+}
+
+// admissionConn releases it's admission once when it is closed.
+type admissionConn struct {
+	net.Conn
+	once    sync.Once
+	release func()
+}
+
+func (c *admissionConn) Close() error {
+	c.once.Do(c.release)
+	return c.Conn.Close()
+}
+
+// admissionListener accepts the TCP connections by the `AdmissionController`
+// and returns the connections, which finished the TLS handshake; the
+// handshakes are done in their own goroutines, so the slow client does not
+// block the others.
+type admissionListener struct {
+	net.Listener
+
+	admission *AdmissionController
+	tlsConfig *tls.Config
+
+	conns     chan net.Conn
+	errs      chan error
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func newAdmissionListener(l net.Listener, admission *AdmissionController, tlsConfig *tls.Config) *admissionListener {
+	al := &admissionListener{
+		Listener:  l,
+		admission: admission,
+		tlsConfig: tlsConfig,
+		conns:     make(chan net.Conn),
+		errs:      make(chan error, 1),
+		closed:    make(chan struct{}),
+	}
+	go al.accept()
+
+	return al
+}
+
+func (l *admissionListener) accept() {
+	for {
+		conn, err := l.Listener.Accept()
+		if err != nil {
+			if ne, ok := err.(net.Error); ok && ne.Temporary() {
+				time.Sleep(5 * time.Millisecond)
+				continue
+			}
+			l.errs <- err
+			return
+		}
+
+		ip, _, _ := net.SplitHostPort(conn.RemoteAddr().String())
+		if ok, reason := l.admission.Admit(ip); !ok {
+			log.Debug("inbound connection rejected", "ip", ip, "reason", reason)
+			conn.Close()
+			continue
+		}
+
+		go l.handshake(conn, ip)
+	}
+}
+
+func (l *admissionListener) handshake(conn net.Conn, ip string) {
+	ac := &admissionConn{Conn: conn, release: func() { l.admission.Release(ip) }}
+	tlsConn := tls.Server(ac, l.tlsConfig)
+
+	if timeout := l.admission.Config().HandshakeTimeout; timeout > 0 {
+		tlsConn.SetDeadline(time.Now().Add(timeout))
+	}
+	err := tlsConn.Handshake()
+	l.admission.HandshakeDone(ip, err == nil)
+	if err != nil {
+		tlsConn.Close()
+		return
+	}
+	tlsConn.SetDeadline(time.Time{})
+
+	select {
+	case l.conns <- tlsConn:
+	case <-l.closed:
+		tlsConn.Close()
+	}
+}
+
+func (l *admissionListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case err := <-l.errs:
+		return nil, err
+	case <-l.closed:
+		return nil, errors.New("listener closed")
+	}
+}
+
+func (l *admissionListener) Close() error {
+	l.closeOnce.Do(func() { close(l.closed) })
+	return l.Listener.Close()
+}
@@ -0,0 +1,239 @@
+package sebaknetwork
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestAdmissionController(t *testing.T) {
+	a := NewAdmissionController(AdmissionConfig{
+		MaxHandshakes:       3,
+		MaxConnectionsPerIP: 2,
+		HandshakeRate:       1,
+		HandshakeBurst:      2,
+		BanDuration:         time.Minute,
+		MaxBanDuration:      3 * time.Minute,
+	})
+	now := time.Now()
+	a.now = func() time.Time { return now }
+
+	admit := func(ip string) AdmissionRejectReason {
+		_, reason := a.Admit(ip)
+		return reason
+	}
+
+	// the connections of one IP
+	for i := 0; i < 2; i++ {
+		if reason := admit("1.1.1.1"); reason != "" {
+			t.Errorf("connection must be admitted: %s", reason)
+			return
+		}
+		a.HandshakeDone("1.1.1.1", true)
+	}
+	if reason := admit("1.1.1.1"); reason != AdmissionRejectTooManyConns {
+		t.Errorf("too many connections must be rejected: %s", reason)
+		return
+	}
+	a.Release("1.1.1.1")
+
+	// the handshake rate; the burst is used
+	if reason := admit("1.1.1.1"); reason != AdmissionRejectHandshakeRate {
+		t.Errorf("fast connection must be rejected: %s", reason)
+		return
+	}
+	now = now.Add(time.Second)
+	if reason := admit("1.1.1.1"); reason != "" {
+		t.Errorf("connection must be admitted after the bucket is filled: %s", reason)
+		return
+	}
+	a.HandshakeDone("1.1.1.1", true)
+
+	// the third violation bans the IP
+	if reason := admit("1.1.1.1"); reason != AdmissionRejectTooManyConns {
+		t.Errorf("too many connections must be rejected: %s", reason)
+		return
+	}
+	if reason := admit("1.1.1.1"); reason != AdmissionRejectBanned {
+		t.Errorf("IP must be banned: %s", reason)
+		return
+	}
+	if a.Banned() != 1 {
+		t.Errorf("wrong number of banned: %d", a.Banned())
+		return
+	}
+
+	// the next ban is twice longer
+	a.Release("1.1.1.1")
+	a.Release("1.1.1.1")
+	now = now.Add(time.Minute)
+	for i := 0; i < AdmissionStrikes; i++ {
+		now = now.Add(time.Second)
+		if reason := admit("1.1.1.1"); reason != "" {
+			t.Errorf("connection must be admitted after ban: %s", reason)
+			return
+		}
+		a.HandshakeDone("1.1.1.1", false)
+		a.Release("1.1.1.1")
+	}
+	now = now.Add(time.Minute + time.Second)
+	if reason := admit("1.1.1.1"); reason != AdmissionRejectBanned {
+		t.Errorf("second ban must be longer: %s", reason)
+		return
+	}
+	now = now.Add(time.Minute)
+	if reason := admit("1.1.1.1"); reason != "" {
+		t.Errorf("ban must be expired: %s", reason)
+		return
+	}
+	a.HandshakeDone("1.1.1.1", true)
+	a.Release("1.1.1.1")
+
+	// the handshakes in progress of all IPs
+	for _, ip := range []string{"2.2.2.2", "3.3.3.3", "4.4.4.4"} {
+		if reason := admit(ip); reason != "" {
+			t.Errorf("connection must be admitted: %s", reason)
+			return
+		}
+	}
+	if reason := admit("5.5.5.5"); reason != AdmissionRejectTooManyHandshakes {
+		t.Errorf("too many handshakes must be rejected: %s", reason)
+		return
+	}
+
+	// the trusted IP is not limited
+	a.trusted = append(a.trusted, &net.IPNet{IP: net.ParseIP("6.6.6.6").To4(), Mask: net.CIDRMask(32, 32)})
+	for i := 0; i < 10; i++ {
+		if reason := admit("6.6.6.6"); reason != "" {
+			t.Errorf("trusted IP must be admitted: %s", reason)
+			return
+		}
+	}
+
+	rejected := a.Rejected()
+	if rejected[AdmissionRejectHandshakeFailed] != uint64(AdmissionStrikes) || rejected[AdmissionRejectBanned] != 2 {
+		t.Errorf("wrong rejected: %v", rejected)
+		return
+	}
+}
+
+func TestAdmissionConfigFromQuery(t *testing.T) {
+	query, _ := url.ParseQuery("MaxConnectionsPerIP=16&HandshakeRate=0.5&BanDuration=10m&MaxBanDuration=2h")
+	config, err := parseAdmissionConfig(query)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if config.MaxConnectionsPerIP != 16 || config.HandshakeRate != 0.5 || config.BanDuration != 10*time.Minute {
+		t.Errorf("wrong config: %v", config)
+		return
+	}
+	if config.MaxHandshakes != NewDefaultAdmissionConfig().MaxHandshakes {
+		t.Errorf("missing query must be the default: %v", config)
+		return
+	}
+
+	for _, q := range []string{"MaxHandshakes=-1", "HandshakeRate=fast", "BanDuration=2h"} {
+		query, _ = url.ParseQuery(q)
+		if _, err = parseAdmissionConfig(query); err == nil {
+			t.Errorf("invalid query must be refused: %s", q)
+			return
+		}
+	}
+}
+
+func makeTestTLSConfig(t *testing.T) *tls.Config {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}}}
+}
+
+func TestAdmissionListener(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	config := NewDefaultAdmissionConfig()
+	config.MaxHandshakes = 1
+	config.HandshakeTimeout = 300 * time.Millisecond
+	admission := NewAdmissionController(config)
+
+	listener := newAdmissionListener(l, admission, makeTestTLSConfig(t))
+	defer listener.Close()
+
+	accepted := make(chan net.Conn, 10)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			accepted <- conn
+		}
+	}()
+
+	dial := func() error {
+		conn, err := tls.DialWithDialer(
+			&net.Dialer{Timeout: time.Second},
+			"tcp", l.Addr().String(),
+			&tls.Config{InsecureSkipVerify: true},
+		)
+		if err == nil {
+			conn.Close()
+		}
+		return err
+	}
+
+	// the slow client holds the only handshake
+	slow, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer slow.Close()
+	time.Sleep(50 * time.Millisecond)
+
+	if err = dial(); err == nil {
+		t.Error("handshake over the limit must be rejected")
+		return
+	}
+
+	// the slow handshake is timed out
+	time.Sleep(config.HandshakeTimeout)
+	if err = dial(); err != nil {
+		t.Errorf("handshake must be admitted after timeout: %v", err)
+		return
+	}
+
+	select {
+	case <-accepted:
+	case <-time.After(time.Second):
+		t.Error("handshaked connection must be accepted")
+		return
+	}
+
+	if rejected := admission.Rejected(); rejected[AdmissionRejectTooManyHandshakes] != 1 || rejected[AdmissionRejectHandshakeFailed] != 1 {
+		t.Errorf("wrong rejected: %v", rejected)
+		return
+	}
+}
@@ -0,0 +1,89 @@
+package sebaknetwork
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/golang/snappy"
+)
+
+// BallotBatch is the ballots to one validator, which are sent in one message.
+// Every validator broadcasts the ballots to every validator, so the number of
+// messages in one round is O(n²); with the batch, the ballots of the short
+// window are sent together. The encoded batch starts with the encoding byte,
+//  * 'j': the JSON list of ballots
+//  * 's': the JSON list of ballots compressed by snappy
+type BallotBatch struct {
+	Ballots  []json.RawMessage
+	Compress bool
+}
+
+const (
+	ballotBatchEncodingJSON   byte = 'j'
+	ballotBatchEncodingSnappy byte = 's'
+)
+
+// MaxBallotBatchSize is the number of ballots to send the batch before the
+// aggregation window ends.
+const MaxBallotBatchSize int = 100
+
+func NewBallotBatch(compress bool) *BallotBatch {
+	return &BallotBatch{Compress: compress}
+}
+
+func NewBallotBatchFromBytes(b []byte) (batch BallotBatch, err error) {
+	if len(b) < 1 {
+		err = errors.New("empty ballot batch")
+		return
+	}
+
+	body := b[1:]
+	switch b[0] {
+	case ballotBatchEncodingJSON:
+	case ballotBatchEncodingSnappy:
+		if body, err = snappy.Decode(nil, body); err != nil {
+			return
+		}
+		batch.Compress = true
+	default:
+		err = errors.New("unknown encoding of ballot batch")
+		return
+	}
+
+	err = json.Unmarshal(body, &batch.Ballots)
+
+	return
+}
+
+func (b *BallotBatch) Add(ballot []byte) {
+	b.Ballots = append(b.Ballots, json.RawMessage(ballot))
+}
+
+func (b *BallotBatch) Len() int {
+	return len(b.Ballots)
+}
+
+func (b *BallotBatch) Serialize() (encoded []byte, err error) {
+	var body []byte
+	if body, err = json.Marshal(b.Ballots); err != nil {
+		return
+	}
+
+	if !b.Compress {
+		encoded = append([]byte{ballotBatchEncodingJSON}, body...)
+		return
+	}
+	encoded = append([]byte{ballotBatchEncodingSnappy}, snappy.Encode(nil, body)...)
+
+	return
+}
+
+// Messages unpacks the batch to the ballot messages, so the batch is handled
+// like the ballots, which are received one by one.
+func (b *BallotBatch) Messages() (messages []Message) {
+	for _, ballot := range b.Ballots {
+		messages = append(messages, Message{Type: BallotMessage, Data: []byte(ballot)})
+	}
+
+	return
+}
@@ -0,0 +1,96 @@
+package sebaknetwork
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"boscoin.io/sebak/lib/common"
+)
+
+func TestBallotBatchSerialize(t *testing.T) {
+	for _, compress := range []bool{false, true} {
+		batch := NewBallotBatch(compress)
+		for _, data := range []string{"a", "b", "c"} {
+			b, _ := NewDummyMessage(data).Serialize()
+			batch.Add(b)
+		}
+
+		encoded, err := batch.Serialize()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		decoded, err := NewBallotBatchFromBytes(encoded)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if decoded.Compress != compress || decoded.Len() != batch.Len() {
+			t.Errorf("unexpected batch: %v", decoded)
+			return
+		}
+
+		messages := decoded.Messages()
+		for i, message := range messages {
+			if message.Type != BallotMessage || !bytes.Equal(message.Data, batch.Ballots[i]) {
+				t.Errorf("unexpected message: %v", message)
+				return
+			}
+		}
+	}
+
+	for _, b := range [][]byte{nil, []byte("x[]"), []byte("j{")} {
+		if _, err := NewBallotBatchFromBytes(b); err == nil {
+			t.Errorf("invalid batch must be refused: '%s'", b)
+			return
+		}
+	}
+}
+
+func TestConnectionManagerBallotAggregation(t *testing.T) {
+	defer CleanUpMemoryNetwork()
+
+	_, s0, v0 := createNewMemoryNetwork()
+	_, s1, v1 := createNewMemoryNetwork()
+	go s0.Start()
+
+	c := NewConnectionManager(v1, s1, nil, map[string]*sebakcommon.Validator{v0.Address(): v0})
+	c.connected[v0.Address()] = true
+	c.SetBallotAggregation(50*time.Millisecond, true)
+
+	// `NewDummyMessage` is slow to make hash, so the messages are made before
+	// broadcasting in the window
+	var ballots []DummyMessage
+	for _, data := range []string{"a", "b", "c"} {
+		ballots = append(ballots, NewDummyMessage(data))
+	}
+	for _, ballot := range ballots {
+		c.Broadcast(ballot)
+	}
+
+	for _, ballot := range ballots {
+		select {
+		case message := <-s0.ReceiveMessage():
+			expected, _ := ballot.Serialize()
+			if message.Type != BallotMessage || !bytes.Equal(message.Data, expected) {
+				t.Errorf("unexpected message: %v", message)
+				return
+			}
+		case <-time.After(time.Second):
+			t.Error("ballots are not sent")
+			return
+		}
+	}
+
+	// the stats are updated after the batch is sent
+	deadline := time.Now().Add(time.Second)
+	for ballots, _ := c.BallotStats(); ballots < 3 && time.Now().Before(deadline); ballots, _ = c.BallotStats() {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if ballots, messages := c.BallotStats(); ballots != 3 || messages != 1 {
+		t.Errorf("ballots must be sent in one message: ballots=%d messages=%d", ballots, messages)
+		return
+	}
+}
@@ -0,0 +1,255 @@
+package sebaknetwork
+
+import (
+	"errors"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"boscoin.io/sebak/lib/common"
+)
+
+// BandwidthLimiter limits the bandwidth of each peer by IP, so one peer, like
+// the node syncing the blocks can not saturate the link of the node,
+//  * upload: the bytes of the responses to the peer
+//  * download: the bytes of the requests from the peer
+// Each direction of the peer is the token bucket of it's rate, which holds the
+// bytes of one second; the request and the response wait for the tokens
+// instead of failing. The consensus messages, like the ballots are not
+// limited, so the throttled peer can not delay the consensus.
+
+type BandwidthConfig struct {
+	Upload   int64 // bytes in a second to one peer; 0 is unlimited
+	Download int64 // bytes in a second from one peer; 0 is unlimited
+}
+
+// parseBandwidthConfig parses the queries of endpoint, like
+// 'PeerUploadRate=1048576'; the missing ones are unlimited.
+func parseBandwidthConfig(query url.Values) (config BandwidthConfig, err error) {
+	if config.Upload, err = strconv.ParseInt(
+		sebakcommon.GetUrlQuery(query, "PeerUploadRate", "0"),
+		10,
+		64,
+	); err != nil || config.Upload < 0 {
+		err = errors.New("invalid 'PeerUploadRate'")
+		return
+	}
+	if config.Download, err = strconv.ParseInt(
+		sebakcommon.GetUrlQuery(query, "PeerDownloadRate", "0"),
+		10,
+		64,
+	); err != nil || config.Download < 0 {
+		err = errors.New("invalid 'PeerDownloadRate'")
+		return
+	}
+
+	return
+}
+
+type BandwidthDirection string
+
+const (
+	BandwidthUpload   BandwidthDirection = "upload"
+	BandwidthDownload BandwidthDirection = "download"
+)
+
+// BandwidthChunk is the largest bytes, which are written to the peer at once,
+// so the large response is spread over the time instead of waiting at once.
+const BandwidthChunk int = 32 * 1024
+
+const BandwidthPruneInterval time.Duration = time.Minute
+
+type bandwidthBucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+type BandwidthLimiter struct {
+	sync.Mutex
+
+	config    BandwidthConfig
+	peers     map[BandwidthDirection]map[string]*bandwidthBucket
+	bytes     map[BandwidthDirection]uint64
+	throttled map[BandwidthDirection]time.Duration
+	lastPrune time.Time
+
+	now   func() time.Time
+	sleep func(time.Duration)
+}
+
+func NewBandwidthLimiter(config BandwidthConfig) *BandwidthLimiter {
+	return &BandwidthLimiter{
+		config: config,
+		peers: map[BandwidthDirection]map[string]*bandwidthBucket{
+			BandwidthUpload:   map[string]*bandwidthBucket{},
+			BandwidthDownload: map[string]*bandwidthBucket{},
+		},
+		bytes:     map[BandwidthDirection]uint64{},
+		throttled: map[BandwidthDirection]time.Duration{},
+		lastPrune: time.Now(),
+		now:       time.Now,
+		sleep:     time.Sleep,
+	}
+}
+
+func (l *BandwidthLimiter) Config() BandwidthConfig {
+	l.Lock()
+	defer l.Unlock()
+
+	return l.config
+}
+
+func (l *BandwidthLimiter) SetConfig(config BandwidthConfig) {
+	l.Lock()
+	defer l.Unlock()
+
+	l.config = config
+}
+
+// IsLimited checks any direction is limited.
+func (l *BandwidthLimiter) IsLimited() bool {
+	l.Lock()
+	defer l.Unlock()
+
+	return l.config.Upload > 0 || l.config.Download > 0
+}
+
+func (l *BandwidthLimiter) rate(direction BandwidthDirection) int64 {
+	if direction == BandwidthUpload {
+		return l.config.Upload
+	}
+
+	return l.config.Download
+}
+
+// reserve takes `n` bytes from the bucket of IP; it returns how long the bytes
+// must wait. The bucket can be in debt, so the next bytes wait for the
+// previous ones.
+func (l *BandwidthLimiter) reserve(ip string, direction BandwidthDirection, n int) (delay time.Duration) {
+	l.Lock()
+	defer l.Unlock()
+
+	l.bytes[direction] += uint64(n)
+
+	rate := float64(l.rate(direction))
+	if rate <= 0 {
+		return
+	}
+
+	now := l.now()
+	l.prune(now)
+
+	bucket, found := l.peers[direction][ip]
+	if !found {
+		bucket = &bandwidthBucket{tokens: rate, updated: now}
+		l.peers[direction][ip] = bucket
+	} else {
+		bucket.tokens = math.Min(rate, bucket.tokens+now.Sub(bucket.updated).Seconds()*rate)
+		bucket.updated = now
+	}
+
+	bucket.tokens -= float64(n)
+	if bucket.tokens < 0 {
+		delay = time.Duration(-bucket.tokens / rate * float64(time.Second))
+		l.throttled[direction] += delay
+	}
+
+	return
+}
+
+// Wait blocks until the `n` bytes of IP can be transferred.
+func (l *BandwidthLimiter) Wait(ip string, direction BandwidthDirection, n int) {
+	if delay := l.reserve(ip, direction, n); delay > 0 {
+		l.sleep(delay)
+	}
+}
+
+// Bytes returns the bytes transferred with the peers by direction.
+func (l *BandwidthLimiter) Bytes() map[BandwidthDirection]uint64 {
+	l.Lock()
+	defer l.Unlock()
+
+	bytes := map[BandwidthDirection]uint64{}
+	for direction, n := range l.bytes {
+		bytes[direction] = n
+	}
+
+	return bytes
+}
+
+// Throttled returns how long the transfers waited by direction.
+func (l *BandwidthLimiter) Throttled() map[BandwidthDirection]time.Duration {
+	l.Lock()
+	defer l.Unlock()
+
+	throttled := map[BandwidthDirection]time.Duration{}
+	for direction, d := range l.throttled {
+		throttled[direction] = d
+	}
+
+	return throttled
+}
+
+// prune removes the buckets, which are full again; the lock must be held.
+func (l *BandwidthLimiter) prune(now time.Time) {
+	if now.Sub(l.lastPrune) < BandwidthPruneInterval {
+		return
+	}
+	l.lastPrune = now
+
+	for direction, buckets := range l.peers {
+		rate := float64(l.rate(direction))
+		for ip, bucket := range buckets {
+			if bucket.tokens+now.Sub(bucket.updated).Seconds()*rate >= rate {
+				delete(buckets, ip)
+			}
+		}
+	}
+}
+
+type throttledReader struct {
+	io.ReadCloser
+
+	limiter *BandwidthLimiter
+	ip      string
+}
+
+func (r *throttledReader) Read(p []byte) (n int, err error) {
+	n, err = r.ReadCloser.Read(p)
+	if n > 0 {
+		r.limiter.Wait(r.ip, BandwidthDownload, n)
+	}
+
+	return
+}
+
+type throttledResponseWriter struct {
+	http.ResponseWriter
+
+	limiter *BandwidthLimiter
+	ip      string
+}
+
+func (w *throttledResponseWriter) Write(b []byte) (written int, err error) {
+	for len(b) > 0 {
+		chunk := b
+		if len(chunk) > BandwidthChunk {
+			chunk = b[:BandwidthChunk]
+		}
+		w.limiter.Wait(w.ip, BandwidthUpload, len(chunk))
+
+		var n int
+		n, err = w.ResponseWriter.Write(chunk)
+		written += n
+		if err != nil {
+			return
+		}
+		b = b[len(chunk):]
+	}
+
+	return
+}
@@ -0,0 +1,129 @@
+package sebaknetwork
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func newTestBandwidthLimiter(config BandwidthConfig) (l *BandwidthLimiter, now *time.Time, slept *time.Duration) {
+	l = NewBandwidthLimiter(config)
+
+	n := time.Now()
+	now = &n
+	slept = new(time.Duration)
+	l.now = func() time.Time { return *now }
+	l.sleep = func(d time.Duration) {
+		*slept += d
+		*now = now.Add(d)
+	}
+
+	return
+}
+
+func TestBandwidthLimiter(t *testing.T) {
+	l, now, _ := newTestBandwidthLimiter(BandwidthConfig{Upload: 1000})
+
+	// the burst of one second is not delayed
+	if delay := l.reserve("1.1.1.1", BandwidthUpload, 1000); delay != 0 {
+		t.Errorf("burst must not be delayed: %s", delay)
+		return
+	}
+	if delay := l.reserve("1.1.1.1", BandwidthUpload, 500); delay != 500*time.Millisecond {
+		t.Errorf("wrong delay over the burst: %s", delay)
+		return
+	}
+
+	// the other peer has it's own bucket
+	if delay := l.reserve("2.2.2.2", BandwidthUpload, 1000); delay != 0 {
+		t.Errorf("other peer must not be delayed: %s", delay)
+		return
+	}
+
+	// the debt is paid by the time
+	*now = now.Add(time.Second)
+	if delay := l.reserve("1.1.1.1", BandwidthUpload, 500); delay != 0 {
+		t.Errorf("bucket must be refilled: %s", delay)
+		return
+	}
+
+	// the download is unlimited
+	if delay := l.reserve("1.1.1.1", BandwidthDownload, 1000000); delay != 0 {
+		t.Errorf("unlimited direction must not be delayed: %s", delay)
+		return
+	}
+
+	if l.Throttled()[BandwidthUpload] != 500*time.Millisecond {
+		t.Errorf("wrong throttled time: %v", l.Throttled())
+		return
+	}
+	if b := l.Bytes(); b[BandwidthUpload] != 3000 || b[BandwidthDownload] != 1000000 {
+		t.Errorf("wrong bytes: %v", b)
+		return
+	}
+}
+
+func TestBandwidthLimiterPrune(t *testing.T) {
+	l, now, _ := newTestBandwidthLimiter(BandwidthConfig{Upload: 1000})
+
+	l.reserve("1.1.1.1", BandwidthUpload, 3000)
+	*now = now.Add(BandwidthPruneInterval)
+	l.reserve("2.2.2.2", BandwidthUpload, 1)
+
+	if _, found := l.peers[BandwidthUpload]["1.1.1.1"]; found {
+		t.Error("full bucket must be pruned")
+		return
+	}
+	if _, found := l.peers[BandwidthUpload]["2.2.2.2"]; !found {
+		t.Error("used bucket must not be pruned")
+		return
+	}
+}
+
+func TestBandwidthThrottled(t *testing.T) {
+	l, _, slept := newTestBandwidthLimiter(BandwidthConfig{Upload: int64(BandwidthChunk), Download: 100})
+
+	// the response is written by chunk
+	recorder := httptest.NewRecorder()
+	w := &throttledResponseWriter{ResponseWriter: recorder, limiter: l, ip: "1.1.1.1"}
+	body := bytes.Repeat([]byte("a"), 3*BandwidthChunk)
+	if n, err := w.Write(body); err != nil || n != len(body) || recorder.Body.Len() != len(body) {
+		t.Errorf("failed to write: %d, %v", n, err)
+		return
+	}
+	if *slept != 2*time.Second {
+		t.Errorf("wrong upload wait: %s", *slept)
+		return
+	}
+
+	*slept = 0
+	r := &throttledReader{ReadCloser: ioutil.NopCloser(bytes.NewReader(make([]byte, 300))), limiter: l, ip: "1.1.1.1"}
+	if b, err := ioutil.ReadAll(r); err != nil || len(b) != 300 {
+		t.Errorf("failed to read: %d, %v", len(b), err)
+		return
+	}
+	if *slept != 2*time.Second {
+		t.Errorf("wrong download wait: %s", *slept)
+		return
+	}
+}
+
+func TestBandwidthConfigFromQuery(t *testing.T) {
+	query, _ := url.ParseQuery("PeerUploadRate=1048576")
+	config, err := parseBandwidthConfig(query)
+	if err != nil || config.Upload != 1048576 || config.Download != 0 {
+		t.Errorf("wrong config: %v, %v", config, err)
+		return
+	}
+
+	for _, q := range []string{"PeerUploadRate=-1", "PeerDownloadRate=fast"} {
+		query, _ = url.ParseQuery(q)
+		if _, err = parseBandwidthConfig(query); err == nil {
+			t.Errorf("invalid query must be refused: %s", q)
+			return
+		}
+	}
+}
@@ -3,6 +3,7 @@ package sebaknetwork
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"math"
 	"net"
 	"net/http"
@@ -47,8 +48,62 @@ type NetworkClient interface {
 
 	Connect(node sebakcommon.Node) ([]byte, error)
 	GetNodeInfo() ([]byte, error)
+	GetPeers() ([]byte, error)
 	SendMessage(sebakcommon.Serializable) error
 	SendBallot(sebakcommon.Serializable) error
+	SendBallots(sebakcommon.Serializable) error
+	SendViewChange(sebakcommon.Serializable) error
+	SendBlockAnnouncement(sebakcommon.Serializable) error
+	SendTransactionInventory(sebakcommon.Serializable) error
+	GetTransactions(hashes []string) ([]byte, error)
+	GetBlocks(from, to uint64) ([]byte, error)
+}
+
+// PeerExchangeFunc makes the serialized peer exchange of node; it is set as
+// "peerExchange" of the context of network.
+type PeerExchangeFunc func() ([]byte, error)
+
+func getPeerExchange(ctx context.Context) ([]byte, error) {
+	f, ok := ctx.Value("peerExchange").(PeerExchangeFunc)
+	if !ok || f == nil {
+		return nil, errors.New("peer exchange is not available")
+	}
+
+	return f()
+}
+
+// TransactionFetchFunc returns the serialized transactions of the hashes,
+// which the node has; it is set as "transactionFetch" of the context of
+// network, so the other nodes fetch the announced transactions.
+type TransactionFetchFunc func(hashes []string) ([]byte, error)
+
+func getTransactions(ctx context.Context, hashes []string) ([]byte, error) {
+	f, ok := ctx.Value("transactionFetch").(TransactionFetchFunc)
+	if !ok || f == nil {
+		return nil, errors.New("transaction fetch is not available")
+	}
+
+	return f(hashes)
+}
+
+// BlockRange is the blocks from the height, `From` to `To`, inclusive.
+type BlockRange struct {
+	From uint64 `json:"from"`
+	To   uint64 `json:"to"`
+}
+
+// BlockFetchFunc returns the serialized blocks of the range with their
+// transactions; it is set as "blockFetch" of the context of network, so the
+// other nodes sync the blocks, which they do not have.
+type BlockFetchFunc func(from, to uint64) ([]byte, error)
+
+func getBlocks(ctx context.Context, from, to uint64) ([]byte, error) {
+	f, ok := ctx.Value("blockFetch").(BlockFetchFunc)
+	if !ok || f == nil {
+		return nil, errors.New("block fetch is not available")
+	}
+
+	return f(from, to)
 }
 
 type MessageType string
@@ -58,10 +113,16 @@ func (t MessageType) String() string {
 }
 
 const (
-	MessageFromClient  MessageType = "message"
-	ConnectMessage                 = "connect"
-	BallotMessage                  = "ballot"
-	GetNodeInfoMessage             = "get-node-info"
+	MessageFromClient           MessageType = "message"
+	ConnectMessage                          = "connect"
+	BallotMessage                           = "ballot"
+	BallotBatchMessage                      = "ballots"
+	GetNodeInfoMessage                      = "get-node-info"
+	ViewChangeMessage                       = "view-change"
+	BlockAnnouncementMessage                = "block-announcement"
+	TransactionInventoryMessage             = "tx-inventory"
+	GetTransactionsMessage                  = "get-transactions"
+	GetBlocksMessage                        = "get-blocks"
 )
 
 // TODO versioning
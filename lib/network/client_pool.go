@@ -0,0 +1,304 @@
+package sebaknetwork
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"boscoin.io/sebak/lib/common"
+	"boscoin.io/sebak/lib/error"
+)
+
+// ClientPool keeps one client for each peer, so the messages to the peer,
+// like the ballots of every broadcast, reuse the HTTP/2 connection of it
+// instead of opening the new connection. Each client tracks the health of
+// it's peer by the circuit breaker; after `FailureThreshold` failures in a
+// row, the circuit is open and the messages to the peer fail at once by
+// `ErrorPeerCircuitOpen` for `OpenTimeout`, so the dead peer does not hold
+// the senders. After `OpenTimeout`, one message is sent by the new connection
+// as the trial; the circuit is closed if it succeeds, or open again.
+type ClientPool struct {
+	sync.Mutex
+
+	network Network
+	config  ClientPoolConfig
+	clients map[ /* endpoint */ string]*PooledClient
+}
+
+type ClientPoolConfig struct {
+	FailureThreshold int
+	OpenTimeout      time.Duration
+}
+
+var DefaultClientPoolConfig = ClientPoolConfig{
+	FailureThreshold: 5,
+	OpenTimeout:      10 * time.Second,
+}
+
+func NewClientPool(network Network, config ClientPoolConfig) *ClientPool {
+	return &ClientPool{
+		network: network,
+		config:  config,
+		clients: map[string]*PooledClient{},
+	}
+}
+
+// SetConfig sets the circuit breaker of the clients; the existing clients
+// keep their state.
+func (p *ClientPool) SetConfig(config ClientPoolConfig) {
+	p.Lock()
+	defer p.Unlock()
+
+	p.config = config
+	for _, client := range p.clients {
+		client.setConfig(config)
+	}
+}
+
+// Get returns the client of the endpoint; the client is made at the first
+// call and used again after that.
+func (p *ClientPool) Get(endpoint *sebakcommon.Endpoint) *PooledClient {
+	p.Lock()
+	defer p.Unlock()
+
+	key := endpoint.String()
+	if client, ok := p.clients[key]; ok {
+		return client
+	}
+
+	client := &PooledClient{
+		endpoint: endpoint,
+		config:   p.config,
+		connect:  func() NetworkClient { return p.network.GetClient(endpoint) },
+		health:   PeerHealth{Endpoint: key, Circuit: CircuitClosed},
+	}
+	p.clients[key] = client
+
+	return client
+}
+
+// Reset closes and drops every client, so the next messages make the new
+// connections.
+func (p *ClientPool) Reset() {
+	p.Lock()
+	clients := p.clients
+	p.clients = map[string]*PooledClient{}
+	p.Unlock()
+
+	for _, client := range clients {
+		client.Close()
+	}
+}
+
+// Health returns the health of the peers by endpoint.
+func (p *ClientPool) Health() map[string]PeerHealth {
+	p.Lock()
+	defer p.Unlock()
+
+	health := map[string]PeerHealth{}
+	for key, client := range p.clients {
+		health[key] = client.Health()
+	}
+
+	return health
+}
+
+var errUnknownEndpoint = errors.New("unknown endpoint")
+
+type CircuitState string
+
+const (
+	CircuitClosed   CircuitState = "closed"
+	CircuitOpen     CircuitState = "open"
+	CircuitHalfOpen CircuitState = "half-open"
+)
+
+type PeerHealth struct {
+	Endpoint      string       `json:"endpoint"`
+	Circuit       CircuitState `json:"circuit"`
+	Failures      int          `json:"failures"` // the failures in a row
+	TotalRequests uint64       `json:"total_requests"`
+	TotalFailures uint64       `json:"total_failures"`
+	LastError     string       `json:"last_error,omitempty"`
+	LastSuccess   time.Time    `json:"last_success"`
+	Opened        time.Time    `json:"opened"`
+}
+
+// PooledClient is the `NetworkClient` of `ClientPool`.
+type PooledClient struct {
+	sync.Mutex
+
+	endpoint *sebakcommon.Endpoint
+	config   ClientPoolConfig
+	connect  func() NetworkClient
+	client   NetworkClient
+	health   PeerHealth
+	trial    bool // the trial message of half-open circuit is being sent
+}
+
+func (c *PooledClient) setConfig(config ClientPoolConfig) {
+	c.Lock()
+	defer c.Unlock()
+
+	c.config = config
+}
+
+func (c *PooledClient) Health() PeerHealth {
+	c.Lock()
+	defer c.Unlock()
+
+	return c.health
+}
+
+// Close closes the connection; the next message makes the new connection.
+func (c *PooledClient) Close() {
+	c.Lock()
+	defer c.Unlock()
+
+	c.close()
+}
+
+func (c *PooledClient) close() {
+	if closer, ok := c.client.(interface {
+		Close()
+	}); ok {
+		closer.Close()
+	}
+	c.client = nil
+}
+
+// acquire returns the client, unless the circuit is open.
+func (c *PooledClient) acquire() (client NetworkClient, err error) {
+	c.Lock()
+	defer c.Unlock()
+
+	switch c.health.Circuit {
+	case CircuitOpen:
+		if time.Since(c.health.Opened) < c.config.OpenTimeout {
+			err = sebakerror.ErrorPeerCircuitOpen
+			return
+		}
+		c.health.Circuit = CircuitHalfOpen
+		c.trial = true
+	case CircuitHalfOpen:
+		if c.trial {
+			err = sebakerror.ErrorPeerCircuitOpen
+			return
+		}
+		c.trial = true
+	}
+
+	if c.client == nil {
+		c.client = c.connect()
+	}
+	if c.client == nil {
+		c.trial = false
+		err = errUnknownEndpoint
+		return
+	}
+	c.health.TotalRequests++
+
+	return c.client, nil
+}
+
+// release records the result of the message to the health of peer.
+func (c *PooledClient) release(err error) {
+	c.Lock()
+	defer c.Unlock()
+
+	c.trial = false
+	if err == nil {
+		c.health.Circuit = CircuitClosed
+		c.health.Failures = 0
+		c.health.LastSuccess = time.Now()
+		return
+	}
+
+	c.health.Failures++
+	c.health.TotalFailures++
+	c.health.LastError = err.Error()
+	if c.health.Circuit == CircuitHalfOpen || c.health.Failures >= c.config.FailureThreshold {
+		c.health.Circuit = CircuitOpen
+		c.health.Opened = time.Now()
+		c.close() // the connection may be broken
+	}
+}
+
+func (c *PooledClient) do(f func(NetworkClient) error) (err error) {
+	var client NetworkClient
+	if client, err = c.acquire(); err != nil {
+		return
+	}
+	err = f(client)
+	c.release(err)
+
+	return
+}
+
+func (c *PooledClient) Endpoint() *sebakcommon.Endpoint {
+	return c.endpoint
+}
+
+func (c *PooledClient) Connect(node sebakcommon.Node) (body []byte, err error) {
+	err = c.do(func(client NetworkClient) (err error) {
+		body, err = client.Connect(node)
+		return
+	})
+	return
+}
+
+func (c *PooledClient) GetNodeInfo() (body []byte, err error) {
+	err = c.do(func(client NetworkClient) (err error) {
+		body, err = client.GetNodeInfo()
+		return
+	})
+	return
+}
+
+func (c *PooledClient) GetPeers() (body []byte, err error) {
+	err = c.do(func(client NetworkClient) (err error) {
+		body, err = client.GetPeers()
+		return
+	})
+	return
+}
+
+func (c *PooledClient) SendMessage(message sebakcommon.Serializable) error {
+	return c.do(func(client NetworkClient) error { return client.SendMessage(message) })
+}
+
+func (c *PooledClient) SendBallot(message sebakcommon.Serializable) error {
+	return c.do(func(client NetworkClient) error { return client.SendBallot(message) })
+}
+
+func (c *PooledClient) SendBallots(message sebakcommon.Serializable) error {
+	return c.do(func(client NetworkClient) error { return client.SendBallots(message) })
+}
+
+func (c *PooledClient) SendViewChange(message sebakcommon.Serializable) error {
+	return c.do(func(client NetworkClient) error { return client.SendViewChange(message) })
+}
+
+func (c *PooledClient) SendBlockAnnouncement(message sebakcommon.Serializable) error {
+	return c.do(func(client NetworkClient) error { return client.SendBlockAnnouncement(message) })
+}
+
+func (c *PooledClient) SendTransactionInventory(message sebakcommon.Serializable) error {
+	return c.do(func(client NetworkClient) error { return client.SendTransactionInventory(message) })
+}
+
+func (c *PooledClient) GetTransactions(hashes []string) (body []byte, err error) {
+	err = c.do(func(client NetworkClient) (err error) {
+		body, err = client.GetTransactions(hashes)
+		return
+	})
+	return
+}
+
+func (c *PooledClient) GetBlocks(from, to uint64) (body []byte, err error) {
+	err = c.do(func(client NetworkClient) (err error) {
+		body, err = client.GetBlocks(from, to)
+		return
+	})
+	return
+}
@@ -0,0 +1,127 @@
+package sebaknetwork
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"boscoin.io/sebak/lib/common"
+	"boscoin.io/sebak/lib/error"
+)
+
+type testPoolClient struct {
+	NetworkClient
+
+	err    error
+	sent   int
+	closed bool
+}
+
+func (c *testPoolClient) SendBallot(sebakcommon.Serializable) error {
+	c.sent++
+	return c.err
+}
+
+func (c *testPoolClient) Close() {
+	c.closed = true
+}
+
+type testPoolNetwork struct {
+	Network
+
+	clients []*testPoolClient
+	err     error
+}
+
+func (n *testPoolNetwork) GetClient(*sebakcommon.Endpoint) NetworkClient {
+	client := &testPoolClient{err: n.err}
+	n.clients = append(n.clients, client)
+	return client
+}
+
+func TestClientPoolReuse(t *testing.T) {
+	network := &testPoolNetwork{}
+	pool := NewClientPool(network, DefaultClientPoolConfig)
+
+	endpoint, _ := sebakcommon.NewEndpointFromString("https://localhost:12345")
+	for i := 0; i < 3; i++ {
+		if err := pool.Get(endpoint).SendBallot(nil); err != nil {
+			t.Error(err)
+			return
+		}
+	}
+	if len(network.clients) != 1 || network.clients[0].sent != 3 {
+		t.Errorf("client must be reused: %d clients", len(network.clients))
+		return
+	}
+
+	pool.Reset()
+	if !network.clients[0].closed {
+		t.Error("reset client must be closed")
+		return
+	}
+	pool.Get(endpoint).SendBallot(nil)
+	if len(network.clients) != 2 {
+		t.Errorf("new client must be made after reset: %d clients", len(network.clients))
+		return
+	}
+}
+
+func TestClientPoolCircuitBreaker(t *testing.T) {
+	network := &testPoolNetwork{err: errors.New("connection refused")}
+	pool := NewClientPool(network, ClientPoolConfig{FailureThreshold: 3, OpenTimeout: 50 * time.Millisecond})
+
+	endpoint, _ := sebakcommon.NewEndpointFromString("https://localhost:12345")
+	client := pool.Get(endpoint)
+	for i := 0; i < 3; i++ {
+		if err := client.SendBallot(nil); err != network.err {
+			t.Errorf("failure must be returned: %v", err)
+			return
+		}
+	}
+	if health := client.Health(); health.Circuit != CircuitOpen || health.Failures != 3 {
+		t.Errorf("circuit must be open: %v", health)
+		return
+	}
+	if !network.clients[0].closed {
+		t.Error("client of open circuit must be closed")
+		return
+	}
+
+	// the open circuit fails at once
+	if err := client.SendBallot(nil); err != sebakerror.ErrorPeerCircuitOpen {
+		t.Errorf("open circuit must not send: %v", err)
+		return
+	}
+	if network.clients[0].sent != 3 {
+		t.Errorf("open circuit must not send: %d", network.clients[0].sent)
+		return
+	}
+
+	// the trial after the timeout fails, so the circuit is open again
+	time.Sleep(60 * time.Millisecond)
+	if err := client.SendBallot(nil); err != network.err {
+		t.Errorf("trial must be sent: %v", err)
+		return
+	}
+	if len(network.clients) != 2 {
+		t.Errorf("trial must make the new client: %d clients", len(network.clients))
+		return
+	}
+	if health := client.Health(); health.Circuit != CircuitOpen {
+		t.Errorf("failed trial must open the circuit: %v", health)
+		return
+	}
+
+	// the peer is back
+	network.err = nil
+	time.Sleep(60 * time.Millisecond)
+	if err := client.SendBallot(nil); err != nil {
+		t.Errorf("trial must succeed: %v", err)
+		return
+	}
+	if health := client.Health(); health.Circuit != CircuitClosed || health.Failures != 0 {
+		t.Errorf("circuit must be closed: %v", health)
+		return
+	}
+}
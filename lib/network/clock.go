@@ -0,0 +1,87 @@
+package sebaknetwork
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"boscoin.io/sebak/lib/common"
+)
+
+// PeerClock is the clock offset of peer. The node info from the peer has the
+// time of the peer, so it is compared with the local time at the middle of the
+// round trip; the error of the estimation is at most half of `RTT`.
+type PeerClock struct {
+	Offset   time.Duration `json:"offset"` // peer clock - local clock
+	RTT      time.Duration `json:"rtt"`
+	Measured time.Time     `json:"measured"`
+}
+
+func EstimatePeerClock(sent, received, remote time.Time) PeerClock {
+	rtt := received.Sub(sent)
+	if rtt < 0 {
+		rtt = 0
+	}
+
+	return PeerClock{
+		Offset:   remote.Sub(sent.Add(rtt / 2)),
+		RTT:      rtt,
+		Measured: received,
+	}
+}
+
+// ClockSpread returns the distance between the fastest and the slowest clock
+// of `offsets`; the local clock, offset 0 is always included.
+func ClockSpread(offsets ...time.Duration) time.Duration {
+	var min, max time.Duration
+	for _, offset := range offsets {
+		if offset < min {
+			min = offset
+		}
+		if offset > max {
+			max = offset
+		}
+	}
+
+	return max - min
+}
+
+// serializeNodeInfo adds the current time and the handshake to the serialized
+// node, so the other node can estimate the clock offset and negotiate the
+// protocol.
+func serializeNodeInfo(ctx context.Context, node sebakcommon.Serializable) []byte {
+	o, _ := node.Serialize()
+
+	var info map[string]interface{}
+	if err := json.Unmarshal(o, &info); err != nil {
+		return o
+	}
+	info["time"] = time.Now().Format(time.RFC3339Nano)
+	info["protocol"] = getHandshake(ctx)
+
+	b, err := json.Marshal(info)
+	if err != nil {
+		return o
+	}
+
+	return b
+}
+
+// parseNodeInfoTime returns the time from the node info; the node info of the
+// old node does not have the time.
+func parseNodeInfoTime(b []byte) (t time.Time, ok bool) {
+	var info struct {
+		Time string `json:"time"`
+	}
+	if err := json.Unmarshal(b, &info); err != nil || len(info.Time) < 1 {
+		return
+	}
+
+	var err error
+	if t, err = time.Parse(time.RFC3339Nano, info.Time); err != nil {
+		return
+	}
+	ok = true
+
+	return
+}
@@ -0,0 +1,88 @@
+package sebaknetwork
+
+import (
+	"testing"
+	"time"
+
+	"boscoin.io/sebak/lib/common"
+)
+
+func TestEstimatePeerClock(t *testing.T) {
+	sent := time.Now()
+	received := sent.Add(100 * time.Millisecond)
+	remote := sent.Add(50 * time.Millisecond).Add(2 * time.Second)
+
+	clock := EstimatePeerClock(sent, received, remote)
+	if clock.Offset != 2*time.Second {
+		t.Errorf("unexpected offset: %s", clock.Offset)
+		return
+	}
+	if clock.RTT != 100*time.Millisecond || !clock.Measured.Equal(received) {
+		t.Errorf("unexpected clock: %v", clock)
+		return
+	}
+
+	// the peer clock is behind
+	clock = EstimatePeerClock(sent, received, sent.Add(-time.Second))
+	if clock.Offset != -time.Second-50*time.Millisecond {
+		t.Errorf("unexpected offset: %s", clock.Offset)
+		return
+	}
+}
+
+func TestClockSpread(t *testing.T) {
+	if spread := ClockSpread(); spread != 0 {
+		t.Errorf("unexpected spread: %s", spread)
+		return
+	}
+
+	// the local clock is included
+	if spread := ClockSpread(time.Second); spread != time.Second {
+		t.Errorf("unexpected spread: %s", spread)
+		return
+	}
+
+	if spread := ClockSpread(time.Second, -2*time.Second, 500*time.Millisecond); spread != 3*time.Second {
+		t.Errorf("unexpected spread: %s", spread)
+		return
+	}
+}
+
+func TestConnectionManagerPeerClocks(t *testing.T) {
+	defer CleanUpMemoryNetwork()
+
+	_, s0, v0 := createNewMemoryNetwork()
+	_, s1, v1 := createNewMemoryNetwork()
+
+	b := s0.GetNodeInfo()
+	if _, ok := parseNodeInfoTime(b); !ok {
+		t.Errorf("node info does not have the time: %s", b)
+		return
+	}
+	if v, err := sebakcommon.NewValidatorFromString(b); err != nil || v.Address() != v0.Address() {
+		t.Errorf("failed to load node info: %s, %v", b, err)
+		return
+	}
+
+	c := NewConnectionManager(v1, s1, nil, map[string]*sebakcommon.Validator{v0.Address(): v0})
+	if err := c.connectValidator(v0); err != nil {
+		t.Error(err)
+		return
+	}
+
+	clock, ok := c.PeerClocks()[v0.Address()]
+	if !ok {
+		t.Error("clock offset is not estimated")
+		return
+	}
+	if clock.Offset > time.Second || clock.Offset < -time.Second {
+		t.Errorf("unexpected offset of the same clock: %s", clock.Offset)
+		return
+	}
+
+	c.setPeerClock(v0, nil)
+	if _, ok := c.PeerClocks()[v0.Address()]; ok {
+		t.Error("clock offset is not removed")
+		return
+	}
+}
@@ -2,9 +2,11 @@ package sebaknetwork
 
 import (
 	"errors"
+	"math/rand"
 	"net"
 	"net/http"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	logging "github.com/inconshreveable/log15"
@@ -19,8 +21,23 @@ type ConnectionManager struct {
 	policy      sebakcommon.VotingThresholdPolicy
 
 	validators map[ /* nodd.Address() */ string]*sebakcommon.Validator
-	clients    map[ /* nodd.Address() */ string]NetworkClient
+	pool       *ClientPool
 	connected  map[ /* nodd.Address() */ string]bool
+	clocks     map[ /* nodd.Address() */ string]PeerClock
+	latencies  map[ /* nodd.Address() */ string]*PeerLatency
+	handshake  Handshake
+	handshakes map[ /* nodd.Address() */ string]Handshake // negotiated
+
+	participation *BallotParticipation
+
+	ballotWindow   time.Duration
+	ballotCompress bool
+	ballotLock     sync.Mutex
+	ballotBatches  map[ /* nodd.Address() */ string]*BallotBatch
+	fanoutPolicy   FanoutPolicy
+	fanoutPending  bool   // the batches of every region are waiting for the window
+	ballotsSent    uint64 // the number of ballots sent
+	ballotMessages uint64 // the number of messages which have the ballots
 
 	log logging.Logger
 }
@@ -38,33 +55,45 @@ func NewConnectionManager(
 		policy:     policy,
 		validators: validators,
 
-		clients:   map[string]NetworkClient{},
+		pool:      NewClientPool(network, DefaultClientPoolConfig),
 		connected: map[string]bool{},
-		log:       log.New(logging.Ctx{"node": currentNode.Alias()}),
+		clocks:    map[string]PeerClock{},
+		latencies: map[string]*PeerLatency{},
+
+		handshake:  NewHandshake(Capabilities...),
+		handshakes: map[string]Handshake{},
+
+		participation: NewBallotParticipation(),
+
+		ballotBatches: map[string]*BallotBatch{},
+		fanoutPolicy:  DefaultFanoutPolicy,
+
+		log: log.New(logging.Ctx{"node": currentNode.Alias()}),
 	}
 }
 
+// GetConnection returns the pooled client of the validator; nil if the
+// validator is unknown or it's endpoint is not discovered yet.
 func (c *ConnectionManager) GetConnection(address string) (client NetworkClient) {
 	c.Lock()
-	defer c.Unlock()
-
-	var ok bool
-	client, ok = c.clients[address]
-	if ok {
+	validator, ok := c.validators[address]
+	c.Unlock()
+	if !ok || validator.Endpoint() == nil {
 		return
 	}
 
-	var validator *sebakcommon.Validator
-	if validator, ok = c.validators[address]; !ok {
-		return
-	}
+	return c.pool.Get(validator.Endpoint())
+}
 
-	client = c.network.GetClient(validator.Endpoint())
-	if client != nil {
-		c.clients[address] = client
-	}
+// ClientPool returns the pool of the clients to the peers.
+func (c *ConnectionManager) ClientPool() *ClientPool {
+	return c.pool
+}
 
-	return
+// ResetClients drops the clients of the validators, so the next connect and
+// messages make the new connections.
+func (c *ConnectionManager) ResetClients() {
+	c.pool.Reset()
 }
 
 func (c *ConnectionManager) Start() {
@@ -128,6 +157,7 @@ func (c *ConnectionManager) connectingValidator(v *sebakcommon.Validator) {
 		err := c.connectValidator(v)
 		if err != nil {
 			c.log.Error("failed to connect", "validator", v, "error", err)
+			c.setPeerClock(v, nil)
 			continue
 		}
 
@@ -145,12 +175,18 @@ func (c *ConnectionManager) connectingValidator(v *sebakcommon.Validator) {
 
 func (c *ConnectionManager) connectValidator(v *sebakcommon.Validator) (err error) {
 	client := c.GetConnection(v.Address())
+	if client == nil {
+		err = errors.New("endpoint of validator is unknown")
+		return
+	}
 
 	var b []byte
+	sent := time.Now()
 	b, err = client.Connect(c.currentNode)
 	if err != nil {
 		return
 	}
+	received := time.Now()
 
 	// load and check validator info; addresses are same?
 	var validator *sebakcommon.Validator
@@ -163,19 +199,453 @@ func (c *ConnectionManager) connectValidator(v *sebakcommon.Validator) (err erro
 		return
 	}
 
+	var peer Handshake
+	if peer, err = parseNodeInfoHandshake(b); err != nil {
+		return
+	}
+	if err = c.setPeerHandshake(v, peer); err != nil {
+		return
+	}
+
+	if remote, ok := parseNodeInfoTime(b); ok {
+		clock := EstimatePeerClock(sent, received, remote)
+		c.setPeerClock(v, &clock)
+	}
+
 	return
 }
 
+// SetCapabilities sets the capabilities of the current node, which are
+// negotiated with the validators at the next connect.
+func (c *ConnectionManager) SetCapabilities(capabilities ...Capability) {
+	c.Lock()
+	defer c.Unlock()
+
+	c.handshake = NewHandshake(capabilities...)
+}
+
+// Handshake returns the handshake of the current node.
+func (c *ConnectionManager) Handshake() Handshake {
+	c.Lock()
+	defer c.Unlock()
+
+	return c.handshake
+}
+
+// setPeerHandshake negotiates the protocol with the handshake of validator;
+// the incompatible validator is forgotten.
+func (c *ConnectionManager) setPeerHandshake(v *sebakcommon.Validator, peer Handshake) (err error) {
+	c.Lock()
+	defer c.Unlock()
+
+	if err = c.handshake.IsCompatible(peer); err != nil {
+		delete(c.handshakes, v.Address())
+		return
+	}
+	c.handshakes[v.Address()] = c.handshake.Negotiate(peer)
+
+	return
+}
+
+// PeerHandshakes returns the negotiated handshakes of the validators.
+func (c *ConnectionManager) PeerHandshakes() map[string]Handshake {
+	c.Lock()
+	defer c.Unlock()
+
+	handshakes := map[string]Handshake{}
+	for address, handshake := range c.handshakes {
+		handshakes[address] = handshake
+	}
+
+	return handshakes
+}
+
+// HasCapability checks the capability is negotiated with the validator; before
+// the negotiation, the validator is assumed to have the capabilities of the
+// current node.
+func (c *ConnectionManager) HasCapability(address string, capability Capability) bool {
+	c.Lock()
+	defer c.Unlock()
+
+	if handshake, ok := c.handshakes[address]; ok {
+		return handshake.Has(capability)
+	}
+
+	return c.handshake.Has(capability)
+}
+
+// setPeerClock updates the clock offset of validator; with nil, the offset is
+// removed.
+func (c *ConnectionManager) setPeerClock(v *sebakcommon.Validator, clock *PeerClock) {
+	c.Lock()
+	defer c.Unlock()
+
+	if clock == nil {
+		delete(c.clocks, v.Address())
+		return
+	}
+	c.clocks[v.Address()] = *clock
+}
+
+// PeerClocks returns the clock offsets of the validators, which are estimated
+// at the last connect.
+func (c *ConnectionManager) PeerClocks() map[string]PeerClock {
+	c.Lock()
+	defer c.Unlock()
+
+	clocks := map[string]PeerClock{}
+	for address, clock := range c.clocks {
+		clocks[address] = clock
+	}
+
+	return clocks
+}
+
+// observeLatency adds the round trip time of sending the ballots to
+// validator.
+func (c *ConnectionManager) observeLatency(address string, d time.Duration) {
+	c.Lock()
+	defer c.Unlock()
+
+	latency, ok := c.latencies[address]
+	if !ok {
+		latency = &PeerLatency{}
+		c.latencies[address] = latency
+	}
+	latency.Add(d)
+}
+
+// PeerLatencies returns the latencies of the validators, which are the
+// `PeerLatencyPercentile` of the recent round trips of ballots.
+func (c *ConnectionManager) PeerLatencies() map[string]time.Duration {
+	c.Lock()
+	defer c.Unlock()
+
+	latencies := map[string]time.Duration{}
+	for address, latency := range c.latencies {
+		latencies[address] = latency.Percentile(PeerLatencyPercentile)
+	}
+
+	return latencies
+}
+
+// ObserveBallot records the ballot of the validator in the round to count the
+// missed ballots.
+func (c *ConnectionManager) ObserveBallot(round, address string) {
+	c.participation.Observe(round, address)
+}
+
+// CloseRound closes the round, which the consensus is reached for.
+func (c *ConnectionManager) CloseRound(round string) {
+	c.participation.Close(round)
+}
+
+// PeerScores returns the health of the validators; see `NewPeerScore()`.
+func (c *ConnectionManager) PeerScores() map[string]PeerScore {
+	var addresses []string
+	for _, v := range c.Validators() {
+		addresses = append(addresses, v.Address())
+	}
+	rounds, missed := c.participation.Count(addresses)
+	latencies := c.PeerLatencies()
+	health := c.pool.Health()
+
+	scores := map[string]PeerScore{}
+	for _, v := range c.Validators() {
+		var h PeerHealth
+		if v.Endpoint() != nil {
+			h = health[v.Endpoint().String()]
+		}
+		address := v.Address()
+		scores[address] = NewPeerScore(c.IsConnected(v), rounds[address], missed[address], h, latencies[address])
+	}
+
+	return scores
+}
+
+// Validators returns the validators to connect.
+func (c *ConnectionManager) Validators() []*sebakcommon.Validator {
+	var validators []*sebakcommon.Validator
+	for _, v := range c.validators {
+		validators = append(validators, v)
+	}
+
+	return validators
+}
+
 func (c *ConnectionManager) ConnectionWatcher(t Network, conn net.Conn, state http.ConnState) {
 	return
 }
 
+// SetBallotAggregation enables the aggregation of ballots; the ballots to one
+// validator in `window` are sent in one `BallotBatch`, and with `compress`,
+// the batch is compressed by snappy. If `window` is 0, every ballot is sent
+// immediately.
+func (c *ConnectionManager) SetBallotAggregation(window time.Duration, compress bool) {
+	c.ballotLock.Lock()
+	defer c.ballotLock.Unlock()
+
+	c.ballotWindow = window
+	c.ballotCompress = compress
+}
+
+// SetFanoutPolicy sets the order of sending the ballots; see `FanoutPolicy`.
+func (c *ConnectionManager) SetFanoutPolicy(policy FanoutPolicy) {
+	c.ballotLock.Lock()
+	defer c.ballotLock.Unlock()
+
+	c.fanoutPolicy = policy
+}
+
+func (c *ConnectionManager) FanoutPolicy() FanoutPolicy {
+	c.ballotLock.Lock()
+	defer c.ballotLock.Unlock()
+
+	return c.fanoutPolicy
+}
+
+// FanoutRegions returns the connected validators in the regions, which the
+// ballots are sent to in order by `FanoutLatency`.
+func (c *ConnectionManager) FanoutRegions() [][]*sebakcommon.Validator {
+	return LatencyRegions(c.AllConnected(), c.PeerLatencies())
+}
+
+// BallotStats returns the number of sent ballots and the number of messages,
+// which have them.
+func (c *ConnectionManager) BallotStats() (ballots, messages uint64) {
+	return atomic.LoadUint64(&c.ballotsSent), atomic.LoadUint64(&c.ballotMessages)
+}
+
 func (c *ConnectionManager) Broadcast(message sebakcommon.Message) {
+	c.ballotLock.Lock()
+	window := c.ballotWindow
+	policy := c.fanoutPolicy
+	c.ballotLock.Unlock()
+
+	if window > 0 {
+		c.aggregateBallot(message)
+		return
+	}
+
+	regions := [][]*sebakcommon.Validator{c.AllConnected()}
+	if policy == FanoutLatency {
+		regions = c.FanoutRegions()
+	}
+
+	for _, region := range regions {
+		for _, validator := range region {
+			go c.sendBallot(validator, message)
+		}
+	}
+}
+
+func (c *ConnectionManager) sendBallot(v *sebakcommon.Validator, message sebakcommon.Message) {
+	client := c.GetConnection(v.Address())
+	sent := time.Now()
+	if err := client.SendBallot(message); err != nil {
+		c.log.Error("failed to SendBallot", "error", err, "validator", v)
+		return
+	}
+	c.observeLatency(v.Address(), time.Since(sent))
+	atomic.AddUint64(&c.ballotsSent, 1)
+	atomic.AddUint64(&c.ballotMessages, 1)
+}
+
+// aggregateBallot adds the ballot to the batch of each validator. The batch is
+// sent when the window of the first ballot in it ends, or when it is full; by
+// `FanoutLatency`, every batch is sent together with it's region when the
+// window of the first ballot in any batch ends.
+func (c *ConnectionManager) aggregateBallot(message sebakcommon.Message) {
+	b, err := message.Serialize()
+	if err != nil {
+		c.log.Error("failed to serialize ballot", "error", err)
+		return
+	}
+
+	c.ballotLock.Lock()
+	defer c.ballotLock.Unlock()
+
+	for _, validator := range c.AllConnected() {
+		address := validator.Address()
+
+		// the validator, which does not understand the batch gets the ballot
+		// by itself
+		if !c.HasCapability(address, CapabilityBallotBatch) {
+			go c.sendBallot(validator, message)
+			continue
+		}
+
+		batch, ok := c.ballotBatches[address]
+		if !ok {
+			batch = NewBallotBatch(c.ballotCompress && c.HasCapability(address, CapabilityBallotCompression))
+			c.ballotBatches[address] = batch
+			if c.fanoutPolicy == FanoutLatency {
+				if !c.fanoutPending {
+					c.fanoutPending = true
+					time.AfterFunc(c.ballotWindow, c.flushRegions)
+				}
+			} else {
+				time.AfterFunc(c.ballotWindow, func() {
+					c.flushBallots(address, batch)
+				})
+			}
+		}
+		batch.Add(b)
+
+		if batch.Len() >= MaxBallotBatchSize {
+			delete(c.ballotBatches, address)
+			go c.sendBallots(address, batch)
+		}
+	}
+}
+
+// flushBallots sends the batch, unless it is already sent by being full.
+func (c *ConnectionManager) flushBallots(address string, batch *BallotBatch) {
+	c.ballotLock.Lock()
+	if c.ballotBatches[address] != batch {
+		c.ballotLock.Unlock()
+		return
+	}
+	delete(c.ballotBatches, address)
+	c.ballotLock.Unlock()
+
+	c.sendBallots(address, batch)
+}
+
+// flushRegions sends the batches of every validator from the slowest region.
+func (c *ConnectionManager) flushRegions() {
+	c.ballotLock.Lock()
+	batches := c.ballotBatches
+	c.ballotBatches = map[string]*BallotBatch{}
+	c.fanoutPending = false
+	c.ballotLock.Unlock()
+
+	var validators []*sebakcommon.Validator
+	for _, v := range c.Validators() {
+		if _, ok := batches[v.Address()]; ok {
+			validators = append(validators, v)
+		}
+	}
+
+	for _, region := range LatencyRegions(validators, c.PeerLatencies()) {
+		for _, v := range region {
+			go c.sendBallots(v.Address(), batches[v.Address()])
+		}
+	}
+}
+
+func (c *ConnectionManager) sendBallots(address string, batch *BallotBatch) {
+	client := c.GetConnection(address)
+	if client == nil {
+		return
+	}
+	sent := time.Now()
+	if err := client.SendBallots(batch); err != nil {
+		c.log.Error("failed to SendBallots", "error", err, "validator", address, "ballots", batch.Len())
+		return
+	}
+	c.observeLatency(address, time.Since(sent))
+	atomic.AddUint64(&c.ballotsSent, uint64(batch.Len()))
+	atomic.AddUint64(&c.ballotMessages, 1)
+}
+
+// BroadcastTransaction sends the transaction from client to the connected
+// validators.
+func (c *ConnectionManager) BroadcastTransaction(message sebakcommon.Message) {
+	for _, validator := range c.AllConnected() {
+		go func(v *sebakcommon.Validator) {
+			client := c.GetConnection(v.Address())
+			if err := client.SendMessage(message); err != nil {
+				c.log.Error("failed to SendMessage", "error", err, "validator", v)
+			}
+		}(validator)
+	}
+}
+
+// AnnounceTransactions sends the inventory of the new transactions to
+// `fanout` connected validators at random; they fetch the transactions, which
+// they do not have, and announce them again, so the transactions reach every
+// validator without sending the full transactions to all of them. The
+// validators, which do not understand the inventory get the full `message`.
+func (c *ConnectionManager) AnnounceTransactions(inventory sebakcommon.Serializable, message sebakcommon.Message, fanout int) {
+	var validators []*sebakcommon.Validator
+	for _, validator := range c.AllConnected() {
+		if c.HasCapability(validator.Address(), CapabilityTxInventory) {
+			validators = append(validators, validator)
+			continue
+		}
+		go func(v *sebakcommon.Validator) {
+			client := c.GetConnection(v.Address())
+			if err := client.SendMessage(message); err != nil {
+				c.log.Error("failed to SendMessage", "error", err, "validator", v)
+			}
+		}(validator)
+	}
+
+	for n, i := range rand.Perm(len(validators)) {
+		if n >= fanout {
+			break
+		}
+		go func(v *sebakcommon.Validator) {
+			client := c.GetConnection(v.Address())
+			if err := client.SendTransactionInventory(inventory); err != nil {
+				c.log.Error("failed to SendTransactionInventory", "error", err, "validator", v)
+			}
+		}(validators[i])
+	}
+}
+
+// ForwardResult is the result of sending the transaction to the validator by
+// `ForwardTransaction()`.
+type ForwardResult struct {
+	Address   string
+	Forwarded time.Time
+	Err       error
+}
+
+// ForwardTransaction sends the transaction to the connected validators like
+// `BroadcastTransaction()`, but waits for them, so the sender knows which
+// validators it reached.
+func (c *ConnectionManager) ForwardTransaction(message sebakcommon.Message) []ForwardResult {
+	validators := c.AllConnected()
+	results := make([]ForwardResult, len(validators))
+
+	var wg sync.WaitGroup
+	for i, validator := range validators {
+		wg.Add(1)
+		go func(i int, v *sebakcommon.Validator) {
+			defer wg.Done()
+
+			client := c.GetConnection(v.Address())
+			err := client.SendMessage(message)
+			if err != nil {
+				c.log.Error("failed to SendMessage", "error", err, "validator", v)
+			}
+			results[i] = ForwardResult{Address: v.Address(), Forwarded: time.Now(), Err: err}
+		}(i, validator)
+	}
+	wg.Wait()
+
+	return results
+}
+
+func (c *ConnectionManager) BroadcastViewChange(message sebakcommon.Message) {
+	for _, validator := range c.AllConnected() {
+		go func(v *sebakcommon.Validator) {
+			client := c.GetConnection(v.Address())
+			if err := client.SendViewChange(message); err != nil {
+				c.log.Error("failed to SendViewChange", "error", err, "validator", v)
+			}
+		}(validator)
+	}
+}
+
+func (c *ConnectionManager) BroadcastBlockAnnouncement(message sebakcommon.Message) {
 	for _, validator := range c.AllConnected() {
 		go func(v *sebakcommon.Validator) {
 			client := c.GetConnection(v.Address())
-			if err := client.SendBallot(message); err != nil {
-				c.log.Error("failed to SendBallot", "error", err, "validator", v)
+			if err := client.SendBlockAnnouncement(message); err != nil {
+				c.log.Error("failed to SendBlockAnnouncement", "error", err, "validator", v)
 			}
 		}(validator)
 	}
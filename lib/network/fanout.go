@@ -0,0 +1,82 @@
+package sebaknetwork
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"boscoin.io/sebak/lib/common"
+)
+
+// FanoutPolicy decides the order of sending the ballots to the validators,
+//  * `unordered`: the ballots are sent to every validator at once
+//  * `latency`: the validators are grouped to the regions by their latencies
+//  and the ballots are sent to the slowest region first, so the ballots to
+//  the far validators are not delayed behind the near ones. With the ballot
+//  aggregation, the batches of one region are flushed together.
+// The order only changes when the ballots leave the node; the consensus is
+// not changed.
+type FanoutPolicy string
+
+const (
+	FanoutUnordered FanoutPolicy = "unordered"
+	FanoutLatency   FanoutPolicy = "latency"
+)
+
+const DefaultFanoutPolicy = FanoutUnordered
+
+// FanoutRegionRatio is how much faster than the slowest validator of region
+// the validator must be to start the next region; the validators of 150ms and
+// 90ms are in one region, but 40ms is in the next.
+const FanoutRegionRatio float64 = 2
+
+func NewFanoutPolicyFromString(s string) (policy FanoutPolicy, err error) {
+	policy = FanoutPolicy(s)
+	switch policy {
+	case FanoutUnordered, FanoutLatency:
+	default:
+		err = fmt.Errorf("unknown fanout policy: '%s'", s)
+	}
+
+	return
+}
+
+// LatencyRegions groups the validators to the regions from the slowest. The
+// validator without latency is not measured yet, so it is regarded as the
+// slowest.
+func LatencyRegions(validators []*sebakcommon.Validator, latencies map[string]time.Duration) (regions [][]*sebakcommon.Validator) {
+	sorted := make([]*sebakcommon.Validator, len(validators))
+	copy(sorted, validators)
+
+	latencyOf := func(v *sebakcommon.Validator) (time.Duration, bool) {
+		latency, ok := latencies[v.Address()]
+		return latency, ok && latency > 0
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		a, aok := latencyOf(sorted[i])
+		b, bok := latencyOf(sorted[j])
+		if aok != bok {
+			return !aok
+		}
+		if a != b {
+			return a > b
+		}
+		return sorted[i].Address() < sorted[j].Address()
+	})
+
+	var head time.Duration
+	for _, v := range sorted {
+		latency, ok := latencyOf(v)
+		next := len(regions) < 1
+		if ok && (head == 0 || float64(latency)*FanoutRegionRatio < float64(head)) {
+			next = true
+		}
+		if next {
+			regions = append(regions, nil)
+			head = latency
+		}
+		regions[len(regions)-1] = append(regions[len(regions)-1], v)
+	}
+
+	return
+}
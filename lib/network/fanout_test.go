@@ -0,0 +1,69 @@
+package sebaknetwork
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stellar/go/keypair"
+
+	"boscoin.io/sebak/lib/common"
+)
+
+func TestLatencyRegions(t *testing.T) {
+	endpoint, _ := sebakcommon.NewEndpointFromString("https://localhost:12345")
+
+	var validators []*sebakcommon.Validator
+	for i := 0; i < 5; i++ {
+		kp, _ := keypair.Random()
+		v, _ := sebakcommon.NewValidator(kp.Address(), endpoint, "")
+		validators = append(validators, v)
+	}
+
+	// validators[4] is not measured yet
+	latencies := map[string]time.Duration{
+		validators[0].Address(): 10 * time.Millisecond,
+		validators[1].Address(): 150 * time.Millisecond,
+		validators[2].Address(): 90 * time.Millisecond,
+		validators[3].Address(): 40 * time.Millisecond,
+	}
+
+	regions := LatencyRegions(validators, latencies)
+	expected := [][]*sebakcommon.Validator{
+		{validators[4]},
+		{validators[1], validators[2]},
+		{validators[3]},
+		{validators[0]},
+	}
+	if len(regions) != len(expected) {
+		t.Errorf("wrong regions: %v", regions)
+		return
+	}
+	for i := range expected {
+		if len(regions[i]) != len(expected[i]) {
+			t.Errorf("wrong region %d: %v", i, regions[i])
+			return
+		}
+		for j := range expected[i] {
+			if regions[i][j] != expected[i][j] {
+				t.Errorf("wrong region %d: %v", i, regions[i])
+				return
+			}
+		}
+	}
+
+	if regions = LatencyRegions(nil, latencies); len(regions) != 0 {
+		t.Errorf("no validators must have no regions: %v", regions)
+		return
+	}
+}
+
+func TestNewFanoutPolicyFromString(t *testing.T) {
+	if policy, err := NewFanoutPolicyFromString("latency"); err != nil || policy != FanoutLatency {
+		t.Errorf("failed to parse policy: %v %v", policy, err)
+		return
+	}
+	if _, err := NewFanoutPolicyFromString("fastest"); err == nil {
+		t.Error("unknown policy must be refused")
+		return
+	}
+}
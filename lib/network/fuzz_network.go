@@ -0,0 +1,176 @@
+package sebaknetwork
+
+import (
+	"math/rand"
+	"time"
+)
+
+// FuzzMutation is the kind of mutation, which `FuzzMutator` applies to the
+// message.
+type FuzzMutation string
+
+const (
+	FuzzMutationNone      FuzzMutation = "none"
+	FuzzMutationFlipByte  FuzzMutation = "flip-byte"
+	FuzzMutationTruncate  FuzzMutation = "truncate"
+	FuzzMutationGarbage   FuzzMutation = "garbage"
+	FuzzMutationSwapType  FuzzMutation = "swap-type"
+	FuzzMutationDuplicate FuzzMutation = "duplicate"
+	FuzzMutationDrop      FuzzMutation = "drop"
+)
+
+var FuzzMutations = []FuzzMutation{
+	FuzzMutationNone,
+	FuzzMutationFlipByte,
+	FuzzMutationTruncate,
+	FuzzMutationGarbage,
+	FuzzMutationSwapType,
+	FuzzMutationDuplicate,
+	FuzzMutationDrop,
+}
+
+// Corrupts returns `true` if the mutated message must not be accepted by the
+// receiving node. The flipped byte can still produce the valid message, so
+// `FuzzMutationFlipByte` is not included.
+func (m FuzzMutation) Corrupts() bool {
+	switch m {
+	case FuzzMutationTruncate, FuzzMutationGarbage, FuzzMutationSwapType:
+		return true
+	default:
+		return false
+	}
+}
+
+// FuzzMutator mutates the messages randomly; the same seed always produces
+// the same mutations for the same messages, so the failure can be
+// reproduced by it's seed.
+type FuzzMutator struct {
+	rand        *rand.Rand
+	permuteRand *rand.Rand
+}
+
+func NewFuzzMutator(seed int64) *FuzzMutator {
+	return &FuzzMutator{
+		rand:        rand.New(rand.NewSource(seed)),
+		permuteRand: rand.New(rand.NewSource(seed)),
+	}
+}
+
+func (m *FuzzMutator) Mutate(message Message) (mutation FuzzMutation, mutated []Message) {
+	mutation = FuzzMutations[m.rand.Intn(len(FuzzMutations))]
+
+	data := make([]byte, len(message.Data))
+	copy(data, message.Data)
+
+	switch mutation {
+	case FuzzMutationFlipByte:
+		if len(data) > 0 {
+			data[m.rand.Intn(len(data))] ^= byte(1 + m.rand.Intn(255))
+		}
+	case FuzzMutationTruncate:
+		if len(data) > 0 {
+			data = data[:m.rand.Intn(len(data))]
+		}
+	case FuzzMutationGarbage:
+		data = make([]byte, 1+m.rand.Intn(256))
+		m.rand.Read(data)
+		data[0] = '{' // looks like JSON, but never be valid
+		data[len(data)-1] = 0
+	case FuzzMutationSwapType:
+		if message.Type == BallotMessage {
+			message.Type = MessageFromClient
+		} else {
+			message.Type = BallotMessage
+		}
+	case FuzzMutationDuplicate:
+		mutated = append(mutated, NewMessage(message.Type, data))
+	case FuzzMutationDrop:
+		return
+	}
+
+	mutated = append(mutated, NewMessage(message.Type, data))
+
+	return
+}
+
+// Permute shuffles the order of messages. It does not affect the mutations of
+// `Mutate()`.
+func (m *FuzzMutator) Permute(messages []Message) {
+	m.permuteRand.Shuffle(len(messages), func(i, j int) {
+		messages[i], messages[j] = messages[j], messages[i]
+	})
+}
+
+// FuzzNetwork sits in front of the receiving node and mutates and permutes
+// the incoming messages of the wrapped `Network`. It is only for testing the
+// robustness of nodes.
+type FuzzNetwork struct {
+	Network
+
+	mutator        *FuzzMutator
+	window         int
+	receiveChannel chan Message
+}
+
+// NewFuzzNetwork wraps the network; the incoming messages are permuted in
+// every `window` messages.
+func NewFuzzNetwork(network Network, seed int64, window int) *FuzzNetwork {
+	if window < 1 {
+		window = 1
+	}
+
+	return &FuzzNetwork{
+		Network:        network,
+		mutator:        NewFuzzMutator(seed),
+		window:         window,
+		receiveChannel: make(chan Message),
+	}
+}
+
+func (f *FuzzNetwork) Start() error {
+	go f.proxy()
+
+	return f.Network.Start()
+}
+
+func (f *FuzzNetwork) ReceiveChannel() chan Message {
+	return f.receiveChannel
+}
+
+func (f *FuzzNetwork) ReceiveMessage() <-chan Message {
+	return f.receiveChannel
+}
+
+func (f *FuzzNetwork) proxy() {
+	var buffered []Message
+
+	flush := func() {
+		f.mutator.Permute(buffered)
+		for _, message := range buffered {
+			f.receiveChannel <- message
+		}
+		buffered = nil
+	}
+
+	for {
+		select {
+		case message, ok := <-f.Network.ReceiveMessage():
+			if !ok {
+				flush()
+				close(f.receiveChannel)
+				return
+			}
+
+			_, mutated := f.mutator.Mutate(message)
+			buffered = append(buffered, mutated...)
+			if len(buffered) >= f.window {
+				flush()
+			}
+		case <-time.After(time.Millisecond * 100):
+			// the messages less than window are not kept too long
+			if len(buffered) > 0 {
+				flush()
+			}
+		}
+	}
+}
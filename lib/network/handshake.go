@@ -0,0 +1,182 @@
+package sebaknetwork
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"boscoin.io/sebak/lib/error"
+)
+
+// The nodes exchange the `Handshake` in the node info, when they connect, so
+// the network can be upgraded node by node,
+//  * the protocol version: the node refuses the peer, whose version is out of
+//  it's range from `MinProtocolVersion` to `ProtocolVersion`, and the peer
+//  refuses the node the same way
+//  * the capabilities: the optional messages, which the node understands; the
+//  messages are sent to the peer by the capabilities, which both of them have,
+//  and the other messages fall back to the ones of every version
+// The node before the handshake does not have it in it's node info; it is
+// `LegacyHandshake`.
+
+const (
+	ProtocolVersion    uint = 2
+	MinProtocolVersion uint = 1
+)
+
+type Capability string
+
+const (
+	CapabilityBallotBatch       Capability = "ballot-batch"       // '/ballots'
+	CapabilityBallotCompression Capability = "ballot-compression" // the snappy `BallotBatch`
+	CapabilityTxInventory       Capability = "tx-inventory"       // '/tx-inventory' and '/get-transactions'
+	CapabilityBlockSync         Capability = "block-sync"         // '/get-blocks'
+)
+
+// Capabilities are every capability of this version.
+var Capabilities = []Capability{
+	CapabilityBallotBatch,
+	CapabilityBallotCompression,
+	CapabilityTxInventory,
+	CapabilityBlockSync,
+}
+
+// LegacyHandshake is the handshake of the node before the handshake, which
+// has every capability of the version 1.
+var LegacyHandshake = Handshake{
+	Version:      1,
+	MinVersion:   1,
+	Capabilities: Capabilities,
+}
+
+type Handshake struct {
+	Version      uint         `json:"version"`
+	MinVersion   uint         `json:"min_version"`
+	Capabilities []Capability `json:"capabilities"`
+}
+
+func NewHandshake(capabilities ...Capability) Handshake {
+	return Handshake{
+		Version:      ProtocolVersion,
+		MinVersion:   MinProtocolVersion,
+		Capabilities: capabilities,
+	}
+}
+
+// ParseCapability checks the capability is known.
+func ParseCapability(s string) (c Capability, err error) {
+	for _, known := range Capabilities {
+		if string(known) == s {
+			c = known
+			return
+		}
+	}
+	err = fmt.Errorf("unknown capability, '%s'", s)
+
+	return
+}
+
+// ParseCapabilities parses the comma separated capabilities.
+func ParseCapabilities(s string) (capabilities []Capability, err error) {
+	capabilities = []Capability{}
+	for _, name := range strings.Split(s, ",") {
+		if name = strings.TrimSpace(name); len(name) < 1 {
+			continue
+		}
+		var c Capability
+		if c, err = ParseCapability(name); err != nil {
+			return
+		}
+		capabilities = append(capabilities, c)
+	}
+
+	return
+}
+
+// JoinCapabilities is the reverse of `ParseCapabilities`.
+func JoinCapabilities(capabilities ...Capability) string {
+	names := make([]string, len(capabilities))
+	for i, c := range capabilities {
+		names[i] = string(c)
+	}
+
+	return strings.Join(names, ",")
+}
+
+func (h Handshake) Has(c Capability) bool {
+	for _, capability := range h.Capabilities {
+		if capability == c {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsCompatible checks the versions of both nodes are in the range of each
+// other.
+func (h Handshake) IsCompatible(peer Handshake) error {
+	if peer.Version < h.MinVersion || h.Version < peer.MinVersion {
+		return sebakerror.ErrorProtocolVersionIncompatible
+	}
+
+	return nil
+}
+
+// Negotiate returns the handshake of the lower version and the capabilities,
+// which both nodes have.
+func (h Handshake) Negotiate(peer Handshake) (negotiated Handshake) {
+	negotiated = Handshake{Version: h.Version, MinVersion: h.MinVersion, Capabilities: []Capability{}}
+	if peer.Version < negotiated.Version {
+		negotiated.Version = peer.Version
+	}
+	if peer.MinVersion > negotiated.MinVersion {
+		negotiated.MinVersion = peer.MinVersion
+	}
+	for _, c := range h.Capabilities {
+		if peer.Has(c) {
+			negotiated.Capabilities = append(negotiated.Capabilities, c)
+		}
+	}
+	sort.Slice(negotiated.Capabilities, func(i, j int) bool {
+		return negotiated.Capabilities[i] < negotiated.Capabilities[j]
+	})
+
+	return
+}
+
+// HandshakeFunc returns the handshake of node; it is set as "handshake" of
+// the context of network.
+type HandshakeFunc func() Handshake
+
+// getHandshake returns the handshake by the "handshake" of context; without
+// it, the node has every capability.
+func getHandshake(ctx context.Context) Handshake {
+	if ctx != nil {
+		if f, ok := ctx.Value("handshake").(HandshakeFunc); ok && f != nil {
+			return f()
+		}
+	}
+
+	return NewHandshake(Capabilities...)
+}
+
+// parseNodeInfoHandshake returns the handshake from the node info; the node
+// info of the old node does not have it.
+func parseNodeInfoHandshake(b []byte) (h Handshake, err error) {
+	var info struct {
+		Protocol *Handshake `json:"protocol"`
+	}
+	if err = json.Unmarshal(b, &info); err != nil {
+		return
+	}
+	if info.Protocol == nil {
+		h = LegacyHandshake
+		return
+	}
+	h = *info.Protocol
+
+	return
+}
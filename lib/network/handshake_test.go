@@ -0,0 +1,131 @@
+package sebaknetwork
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"boscoin.io/sebak/lib/common"
+	"boscoin.io/sebak/lib/error"
+)
+
+func TestHandshakeNegotiate(t *testing.T) {
+	local := NewHandshake(CapabilityTxInventory, CapabilityBallotBatch, CapabilityBlockSync)
+	peer := Handshake{Version: 1, MinVersion: 1, Capabilities: []Capability{CapabilityBlockSync, CapabilityBallotBatch}}
+
+	negotiated := local.Negotiate(peer)
+	if negotiated.Version != 1 || negotiated.MinVersion != MinProtocolVersion {
+		t.Errorf("wrong negotiated version: %v", negotiated)
+		return
+	}
+	expected := []Capability{CapabilityBallotBatch, CapabilityBlockSync}
+	if !reflect.DeepEqual(negotiated.Capabilities, expected) {
+		t.Errorf("wrong negotiated capabilities: %v", negotiated.Capabilities)
+		return
+	}
+	if negotiated.Has(CapabilityTxInventory) {
+		t.Error("capability of only one node must not be negotiated")
+		return
+	}
+}
+
+func TestHandshakeIsCompatible(t *testing.T) {
+	local := NewHandshake(Capabilities...)
+
+	if err := local.IsCompatible(LegacyHandshake); err != nil {
+		t.Errorf("legacy node must be compatible: %v", err)
+		return
+	}
+
+	newer := Handshake{Version: ProtocolVersion + 2, MinVersion: ProtocolVersion + 1}
+	if err := local.IsCompatible(newer); err != sebakerror.ErrorProtocolVersionIncompatible {
+		t.Errorf("newer node, which does not support this version must be refused: %v", err)
+		return
+	}
+
+	older := Handshake{Version: MinProtocolVersion - 1, MinVersion: MinProtocolVersion - 1}
+	if err := local.IsCompatible(older); err != sebakerror.ErrorProtocolVersionIncompatible {
+		t.Errorf("older node under the min version must be refused: %v", err)
+		return
+	}
+}
+
+func TestParseCapabilities(t *testing.T) {
+	capabilities, err := ParseCapabilities(JoinCapabilities(Capabilities...))
+	if err != nil || !reflect.DeepEqual(capabilities, Capabilities) {
+		t.Errorf("failed to parse capabilities: %v, %v", capabilities, err)
+		return
+	}
+
+	if capabilities, err = ParseCapabilities(""); err != nil || len(capabilities) != 0 {
+		t.Errorf("empty capabilities must be parsed: %v, %v", capabilities, err)
+		return
+	}
+
+	if _, err = ParseCapabilities("block-sync, unknown"); err == nil {
+		t.Error("unknown capability must be refused")
+		return
+	}
+}
+
+func TestParseNodeInfoHandshake(t *testing.T) {
+	h, err := parseNodeInfoHandshake([]byte(`{"address": "GABC"}`))
+	if err != nil || !reflect.DeepEqual(h, LegacyHandshake) {
+		t.Errorf("node info without protocol must be legacy: %v, %v", h, err)
+		return
+	}
+
+	h, err = parseNodeInfoHandshake([]byte(`{"protocol": {"version": 3, "min_version": 2, "capabilities": ["block-sync"]}}`))
+	if err != nil || h.Version != 3 || h.MinVersion != 2 || !h.Has(CapabilityBlockSync) || h.Has(CapabilityBallotBatch) {
+		t.Errorf("failed to parse handshake: %v, %v", h, err)
+		return
+	}
+}
+
+func TestConnectionManagerNegotiate(t *testing.T) {
+	defer CleanUpMemoryNetwork()
+
+	_, s0, v0 := createNewMemoryNetwork()
+	_, s1, v1 := createNewMemoryNetwork()
+
+	// v0 does not sync the blocks
+	s0.SetContext(context.WithValue(
+		s0.Context(),
+		"handshake",
+		HandshakeFunc(func() Handshake { return NewHandshake(CapabilityBallotBatch, CapabilityTxInventory) }),
+	))
+
+	c := NewConnectionManager(v1, s1, nil, map[string]*sebakcommon.Validator{v0.Address(): v0})
+	if !c.HasCapability(v0.Address(), CapabilityBlockSync) {
+		t.Error("before negotiation, validator must have the local capabilities")
+		return
+	}
+	if err := c.connectValidator(v0); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if _, ok := c.PeerHandshakes()[v0.Address()]; !ok {
+		t.Error("handshake is not negotiated")
+		return
+	}
+	if !c.HasCapability(v0.Address(), CapabilityTxInventory) || c.HasCapability(v0.Address(), CapabilityBlockSync) {
+		t.Errorf("wrong negotiated capabilities: %v", c.PeerHandshakes()[v0.Address()])
+		return
+	}
+
+	// the incompatible validator is refused
+	s0.SetContext(context.WithValue(
+		s0.Context(),
+		"handshake",
+		HandshakeFunc(func() Handshake { return Handshake{Version: ProtocolVersion + 2, MinVersion: ProtocolVersion + 1} }),
+	))
+	if err := c.connectValidator(v0); err != sebakerror.ErrorProtocolVersionIncompatible {
+		t.Errorf("incompatible validator must be refused: %v", err)
+		return
+	}
+	if _, ok := c.PeerHandshakes()[v0.Address()]; ok {
+		t.Error("handshake of incompatible validator must be forgotten")
+		return
+	}
+}
@@ -2,16 +2,21 @@ package sebaknetwork
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"errors"
 	"fmt"
 	"io"
 	"net"
 	"net/http"
+	"net/url"
 	"os"
+	"strconv"
 	"time"
 
-	"github.com/gorilla/handlers"
 	"boscoin.io/sebak/lib/common"
+	"boscoin.io/sebak/lib/error"
+	"github.com/gorilla/handlers"
 
 	"golang.org/x/net/http2"
 )
@@ -26,11 +31,24 @@ type HTTP2NetworkConfig struct {
 	IdleTimeout time.Duration
 
 	TLSCertFile,
-	TLSKeyFile string
+	TLSKeyFile,
+	TLSCAFile string
+	TLSClientAuth bool
 
 	HTTP2LogOutput io.Writer
+
+	Admission AdmissionConfig
+	Bandwidth BandwidthConfig
+
+	// MaxRequestSize is the maximum size of the body of the node messages; 0
+	// is unlimited.
+	MaxRequestSize int64
 }
 
+// DefaultMaxRequestSize is enough for the `BallotBatch` of the ballots with
+// the transactions.
+const DefaultMaxRequestSize int64 = 16 * 1024 * 1024
+
 func NewHTTP2NetworkConfigFromEndpoint(endpoint *sebakcommon.Endpoint) (config HTTP2NetworkConfig, err error) {
 	query := endpoint.Query()
 
@@ -39,7 +57,8 @@ func NewHTTP2NetworkConfigFromEndpoint(endpoint *sebakcommon.Endpoint) (config H
 	var ReadHeaderTimeout time.Duration = 0
 	var WriteTimeout time.Duration = 0
 	var IdleTimeout time.Duration = 5
-	var TLSCertFile, TLSKeyFile string
+	var TLSCertFile, TLSKeyFile, TLSCAFile string
+	var TLSClientAuth bool
 	var HTTP2LogOutput io.Writer
 
 	if ReadTimeout, err = time.ParseDuration(sebakcommon.GetUrlQuery(query, "ReadTimeout", "0s")); err != nil {
@@ -88,6 +107,22 @@ func NewHTTP2NetworkConfigFromEndpoint(endpoint *sebakcommon.Endpoint) (config H
 		TLSKeyFile = v
 	}
 
+	if v := query.Get("TLSCAFile"); len(v) > 0 {
+		if _, err = LoadTLSCertPool(v); err != nil {
+			err = fmt.Errorf("invalid 'TLSCAFile': %v", err)
+			return
+		}
+		TLSCAFile = v
+	}
+
+	if TLSClientAuth, err = strconv.ParseBool(sebakcommon.GetUrlQuery(query, "TLSClientAuth", "false")); err != nil {
+		err = errors.New("invalid 'TLSClientAuth'")
+		return
+	} else if TLSClientAuth && len(TLSCAFile) < 1 {
+		err = errors.New("'TLSClientAuth' needs 'TLSCAFile'")
+		return
+	}
+
 	if v := query.Get("NodeName"); len(v) < 1 {
 		err = errors.New("`NodeName` must be given")
 		return
@@ -95,6 +130,26 @@ func NewHTTP2NetworkConfigFromEndpoint(endpoint *sebakcommon.Endpoint) (config H
 		NodeName = v
 	}
 
+	var admission AdmissionConfig
+	if admission, err = parseAdmissionConfig(query); err != nil {
+		return
+	}
+
+	var bandwidth BandwidthConfig
+	if bandwidth, err = parseBandwidthConfig(query); err != nil {
+		return
+	}
+
+	var MaxRequestSize int64
+	if MaxRequestSize, err = strconv.ParseInt(
+		sebakcommon.GetUrlQuery(query, "MaxRequestSize", strconv.FormatInt(DefaultMaxRequestSize, 10)),
+		10,
+		64,
+	); err != nil || MaxRequestSize < 0 {
+		err = errors.New("invalid 'MaxRequestSize'")
+		return
+	}
+
 	if v := query.Get("HTTP2LogOutput"); len(v) < 1 {
 		HTTP2LogOutput = os.Stdout
 	} else {
@@ -113,18 +168,24 @@ func NewHTTP2NetworkConfigFromEndpoint(endpoint *sebakcommon.Endpoint) (config H
 		IdleTimeout:       IdleTimeout,
 		TLSCertFile:       TLSCertFile,
 		TLSKeyFile:        TLSKeyFile,
+		TLSCAFile:         TLSCAFile,
+		TLSClientAuth:     TLSClientAuth,
 		HTTP2LogOutput:    HTTP2LogOutput,
+		Admission:         admission,
+		Bandwidth:         bandwidth,
+		MaxRequestSize:    MaxRequestSize,
 	}
 
 	return
 }
 
 type HTTP2Network struct {
-	ctx         context.Context
-	tlsCertFile string
-	tlsKeyFile  string
+	ctx context.Context
 
-	server *http.Server
+	server    *http.Server
+	admission *AdmissionController
+	bandwidth *BandwidthLimiter
+	proxy     *url.URL // nil if the other nodes are connected directly
 
 	receiveChannel chan Message
 
@@ -161,9 +222,9 @@ func NewHTTP2Network(config HTTP2NetworkConfig) (h2n *HTTP2Network) {
 
 	h2n = &HTTP2Network{
 		server:         server,
-		tlsCertFile:    config.TLSCertFile,
-		tlsKeyFile:     config.TLSKeyFile,
 		receiveChannel: make(chan Message),
+		admission:      NewAdmissionController(config.Admission),
+		bandwidth:      NewBandwidthLimiter(config.Bandwidth),
 	}
 
 	h2n.config = config
@@ -185,9 +246,20 @@ func (t *HTTP2Network) SetContext(ctx context.Context) {
 
 // GetClient creates new keep-alive HTTP2 client
 func (t *HTTP2Network) GetClient(endpoint *sebakcommon.Endpoint) NetworkClient {
-	rawClient, _ := sebakcommon.NewHTTP2Client(defaultTimeout, 0, true)
+	tlsConfig, err := t.config.clientTLSConfig()
+	if err != nil {
+		// the client can not connect without the certificates
+		tlsConfig = &tls.Config{RootCAs: x509.NewCertPool()}
+	}
+	var rawClient *sebakcommon.HTTP2Client
+	if t.proxy != nil {
+		rawClient, _ = sebakcommon.NewHTTP2ClientWithProxy(defaultProxyTimeout, 0, true, tlsConfig, t.proxy)
+	} else {
+		rawClient, _ = sebakcommon.NewHTTP2ClientWithTLS(defaultTimeout, 0, true, tlsConfig)
+	}
 
 	client := NewHTTP2NetworkClient(endpoint, rawClient)
+	client.SetContext(t.Context())
 
 	headers := http.Header{}
 	headers.Set("User-Agent", fmt.Sprintf("v-%s", t.config.NodeName))
@@ -215,7 +287,7 @@ func (t *HTTP2Network) setNotReadyHandler() {
 	handler := http.NewServeMux()
 	handler.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		if !t.ready {
-			http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+			sebakerror.WriteProblem(w, r, http.StatusServiceUnavailable, sebakerror.ErrorNodeNotReady)
 			return
 		}
 	})
@@ -228,18 +300,32 @@ func (t *HTTP2Network) AddHandler(ctx context.Context, pattern string, handler f
 	return nil
 }
 
+// AddHandlerFunc adds the plain handler, which does not need the context and
+// network.
+func (t *HTTP2Network) AddHandlerFunc(pattern string, handler HandlerFunc) {
+	t.handlers[pattern] = handler
+}
+
 func (t *HTTP2Network) Ready() error {
 	t.AddHandler(t.Context(), "/", Index)
 	t.AddHandler(t.Context(), "/connect", ConnectHandler)
 	t.AddHandler(t.Context(), "/message", MessageHandler)
 	t.AddHandler(t.Context(), "/ballot", BallotHandler)
+	t.AddHandler(t.Context(), "/ballots", BallotsHandler)
+	t.AddHandler(t.Context(), "/view-change", ViewChangeHandler)
+	t.AddHandler(t.Context(), "/block-announcement", BlockAnnouncementHandler)
+	t.AddHandler(t.Context(), "/peers", PeersHandler)
+	t.AddHandler(t.Context(), "/tx-inventory", TransactionInventoryHandler)
+	t.AddHandler(t.Context(), "/get-transactions", GetTransactionsHandler)
+	t.AddHandler(t.Context(), "/get-blocks", GetBlocksHandler)
 
 	handler := new(http.ServeMux)
 	for pattern, handlerFunc := range t.handlers {
 		handler.HandleFunc(pattern, handlerFunc)
 	}
 
-	t.server.Handler = handlers.CombinedLoggingHandler(t.config.HTTP2LogOutput, handler)
+	t.admission.SetBanList(peerBanList(t.Context()))
+	t.server.Handler = handlers.CombinedLoggingHandler(t.config.HTTP2LogOutput, t.refuseBanned(t.throttle(handler)))
 
 	t.ready = true
 
@@ -261,12 +347,42 @@ func (t *HTTP2Network) IsReady() bool {
 	return true
 }
 
+func (t *HTTP2Network) Admission() *AdmissionController {
+	return t.admission
+}
+
+func (t *HTTP2Network) Bandwidth() *BandwidthLimiter {
+	return t.bandwidth
+}
+
+// Start serves the TLS connections admitted by `AdmissionController`.
 func (t *HTTP2Network) Start() (err error) {
 	defer func() {
 		close(t.receiveChannel)
 	}()
 
-	return t.server.ListenAndServeTLS(t.tlsCertFile, t.tlsKeyFile)
+	var tlsConfig *tls.Config
+	if t.server.TLSConfig == nil {
+		tlsConfig = &tls.Config{}
+	} else {
+		tlsConfig = t.server.TLSConfig.Clone()
+	}
+	for _, proto := range []string{"h2", "http/1.1"} {
+		if _, found := sebakcommon.InStringArray(tlsConfig.NextProtos, proto); !found {
+			tlsConfig.NextProtos = append(tlsConfig.NextProtos, proto)
+		}
+	}
+
+	if err = t.config.serverTLSConfig(tlsConfig); err != nil {
+		return
+	}
+
+	var listener net.Listener
+	if listener, err = net.Listen("tcp", t.server.Addr); err != nil {
+		return
+	}
+
+	return t.server.Serve(newAdmissionListener(listener, t.admission, tlsConfig))
 }
 
 func (t *HTTP2Network) Stop() {
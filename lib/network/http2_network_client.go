@@ -1,6 +1,9 @@
 package sebaknetwork
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/url"
@@ -10,6 +13,7 @@ import (
 )
 
 type HTTP2NetworkClient struct {
+	ctx            context.Context
 	endpoint       *sebakcommon.Endpoint
 	client         *sebakcommon.HTTP2Client
 	defaultHeaders http.Header
@@ -36,6 +40,17 @@ func (c *HTTP2NetworkClient) Endpoint() *sebakcommon.Endpoint {
 	return c.endpoint
 }
 
+// SetContext sets the context of network, which has the "messageSigner" to
+// sign the messages to the other node.
+func (c *HTTP2NetworkClient) SetContext(ctx context.Context) {
+	c.ctx = ctx
+}
+
+// Close closes the connection to the node.
+func (c *HTTP2NetworkClient) Close() {
+	c.client.Close()
+}
+
 func (c *HTTP2NetworkClient) SetDefaultHeaders(headers http.Header) {
 	for key, values := range headers {
 		for _, v := range values {
@@ -76,17 +91,43 @@ func (c *HTTP2NetworkClient) GetNodeInfo() (body []byte, err error) {
 	return
 }
 
+func (c *HTTP2NetworkClient) GetPeers() (body []byte, err error) {
+	headers := c.DefaultHeaders()
+	headers.Set("Content-Type", "application/json")
+
+	var response *http.Response
+	response, err = c.client.Get(c.resolvePath("/peers").String(), headers)
+	if err != nil {
+		return
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		err = fmt.Errorf("failed to get peers: %s", response.Status)
+		return
+	}
+	body, err = ioutil.ReadAll(response.Body)
+	return
+}
+
 func (c *HTTP2NetworkClient) Connect(node sebakcommon.Node) (body []byte, err error) {
 	headers := c.DefaultHeaders()
 	headers.Set("Content-Type", "application/json")
 
-	n, _ := node.Serialize()
+	n := serializeNodeInfo(c.ctx, node)
+	if err = c.sign(headers, ConnectMessage, n); err != nil {
+		return
+	}
+
 	var response *http.Response
 	response, err = c.client.Post(c.resolvePath("/connect").String(), n, headers)
 	if err != nil {
 		return
 	}
 	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		err = fmt.Errorf("failed to connect: %s", response.Status)
+		return
+	}
 	body, err = ioutil.ReadAll(response.Body)
 	return
 }
@@ -100,6 +141,10 @@ func (c *HTTP2NetworkClient) SendMessage(message sebakcommon.Serializable) (err
 		return
 	}
 
+	if err = c.sign(headers, MessageFromClient, body); err != nil {
+		return
+	}
+
 	u := c.resolvePath("/message")
 
 	var response *http.Response
@@ -113,6 +158,103 @@ func (c *HTTP2NetworkClient) SendMessage(message sebakcommon.Serializable) (err
 }
 
 func (c *HTTP2NetworkClient) SendBallot(message sebakcommon.Serializable) (err error) {
+	return c.post(BallotMessage, message)
+}
+
+// SendBallots sends the `BallotBatch`; the encoded batch is not JSON.
+func (c *HTTP2NetworkClient) SendBallots(message sebakcommon.Serializable) (err error) {
+	headers := c.DefaultHeaders()
+	headers.Set("Content-Type", "application/octet-stream")
+
+	var body []byte
+	if body, err = message.Serialize(); err != nil {
+		return
+	}
+
+	if err = c.sign(headers, BallotBatchMessage, body); err != nil {
+		return
+	}
+
+	var response *http.Response
+	response, err = c.client.Post(c.resolvePath("/ballots").String(), body, headers)
+	if err != nil {
+		return
+	}
+	defer response.Body.Close()
+
+	return
+}
+
+func (c *HTTP2NetworkClient) SendViewChange(message sebakcommon.Serializable) (err error) {
+	return c.post(ViewChangeMessage, message)
+}
+
+func (c *HTTP2NetworkClient) SendBlockAnnouncement(message sebakcommon.Serializable) (err error) {
+	return c.post(BlockAnnouncementMessage, message)
+}
+
+func (c *HTTP2NetworkClient) SendTransactionInventory(message sebakcommon.Serializable) (err error) {
+	return c.post(TransactionInventoryMessage, message)
+}
+
+// GetTransactions fetches the transactions of the hashes, which the node
+// announced.
+func (c *HTTP2NetworkClient) GetTransactions(hashes []string) (body []byte, err error) {
+	headers := c.DefaultHeaders()
+	headers.Set("Content-Type", "application/json")
+
+	var b []byte
+	if b, err = json.Marshal(hashes); err != nil {
+		return
+	}
+	if err = c.sign(headers, GetTransactionsMessage, b); err != nil {
+		return
+	}
+
+	var response *http.Response
+	response, err = c.client.Post(c.resolvePath("/"+GetTransactionsMessage).String(), b, headers)
+	if err != nil {
+		return
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		err = fmt.Errorf("failed to get transactions: %s", response.Status)
+		return
+	}
+	body, err = ioutil.ReadAll(response.Body)
+	return
+}
+
+// GetBlocks fetches the blocks of the range, `from` to `to` with their
+// transactions.
+func (c *HTTP2NetworkClient) GetBlocks(from, to uint64) (body []byte, err error) {
+	headers := c.DefaultHeaders()
+	headers.Set("Content-Type", "application/json")
+
+	var b []byte
+	if b, err = json.Marshal(BlockRange{From: from, To: to}); err != nil {
+		return
+	}
+	if err = c.sign(headers, GetBlocksMessage, b); err != nil {
+		return
+	}
+
+	var response *http.Response
+	response, err = c.client.Post(c.resolvePath("/"+GetBlocksMessage).String(), b, headers)
+	if err != nil {
+		return
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		err = fmt.Errorf("failed to get blocks: %s", response.Status)
+		return
+	}
+	body, err = ioutil.ReadAll(response.Body)
+	return
+}
+
+// post sends the message to the path of it's type, like '/ballot'.
+func (c *HTTP2NetworkClient) post(mt MessageType, message sebakcommon.Serializable) (err error) {
 	headers := c.DefaultHeaders()
 	headers.Set("Content-Type", "application/json")
 
@@ -120,8 +262,11 @@ func (c *HTTP2NetworkClient) SendBallot(message sebakcommon.Serializable) (err e
 	if body, err = message.Serialize(); err != nil {
 		return
 	}
+	if err = c.sign(headers, mt, body); err != nil {
+		return
+	}
 
-	u := c.resolvePath("/ballot")
+	u := c.resolvePath("/" + mt.String())
 
 	var response *http.Response
 	response, err = c.client.Post(u.String(), body, headers)
@@ -132,3 +277,15 @@ func (c *HTTP2NetworkClient) SendBallot(message sebakcommon.Serializable) (err e
 
 	return
 }
+
+// sign puts the signature of message to the headers; the client without the
+// "messageSigner", like the client of 'sebak tx' does not sign.
+func (c *HTTP2NetworkClient) sign(headers http.Header, mt MessageType, body []byte) (err error) {
+	var auth MessageAuth
+	if auth, err = signMessage(c.ctx, mt, body); err != nil {
+		return
+	}
+	auth.SetHeader(headers)
+
+	return
+}
@@ -2,12 +2,15 @@ package sebaknetwork
 
 import (
 	"context"
-	"fmt"
+	"encoding/json"
+	"errors"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"strings"
 
 	"boscoin.io/sebak/lib/common"
+	"boscoin.io/sebak/lib/error"
 )
 
 func Index(ctx context.Context, t *HTTP2Network) HandlerFunc {
@@ -18,8 +21,7 @@ func Index(ctx context.Context, t *HTTP2Network) HandlerFunc {
 			currentNode = ctx.Value("currentNode").(sebakcommon.Serializable)
 		}
 
-		o, _ := currentNode.Serialize()
-		fmt.Fprintf(w, string(o))
+		w.Write(serializeNodeInfo(ctx, currentNode))
 	}
 }
 
@@ -32,21 +34,30 @@ func ConnectHandler(ctx context.Context, t *HTTP2Network) HandlerFunc {
 		}
 
 		if r.Method != "POST" {
-			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			sebakerror.WriteProblem(w, r, http.StatusMethodNotAllowed, nil)
 			return
 		}
 
-		body, err := ioutil.ReadAll(r.Body)
-		if err != nil {
-			http.Error(w, "Error reading request body", http.StatusInternalServerError)
+		body, ok := t.readBody(w, r)
+		if !ok || !t.authenticate(ctx, w, r, ConnectMessage, body, false) {
+			return
+		}
+
+		// the node of the incompatible protocol is refused before it is
+		// connected
+		if peer, err := parseNodeInfoHandshake(body); err != nil {
+			sebakerror.WriteProblem(w, r, http.StatusBadRequest, err)
+			return
+		} else if err = getHandshake(ctx).IsCompatible(peer); err != nil {
+			sebakerror.WriteProblem(w, r, http.StatusBadRequest, err)
+			return
 		}
 
 		// and then connect to remote
 		t.ReceiveChannel() <- Message{Type: ConnectMessage, Data: body}
 
 		// send current node info
-		o, _ := currentNode.Serialize()
-		fmt.Fprintf(w, string(o))
+		w.Write(serializeNodeInfo(ctx, currentNode))
 	}
 }
 
@@ -55,18 +66,17 @@ func MessageHandler(ctx context.Context, t *HTTP2Network) HandlerFunc {
 		defer r.Body.Close()
 
 		if r.Method != "POST" {
-			// TODO use http-problem spec
-			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			sebakerror.WriteProblem(w, r, http.StatusMethodNotAllowed, nil)
 			return
 		}
 		if ct := r.Header.Get("Content-Type"); strings.ToLower(ct) != "application/json" {
-			http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+			sebakerror.WriteProblem(w, r, http.StatusBadRequest, errors.New("'Content-Type' must be 'application/json'"))
 			return
 		}
 
-		body, err := ioutil.ReadAll(r.Body)
-		if err != nil {
-			http.Error(w, "Error reading request body", http.StatusInternalServerError)
+		body, ok := t.readBody(w, r)
+		if !ok || !t.authenticate(ctx, w, r, MessageFromClient, body, true) {
+			return
 		}
 
 		t.ReceiveChannel() <- Message{Type: MessageFromClient, Data: body}
@@ -79,20 +89,331 @@ func MessageHandler(ctx context.Context, t *HTTP2Network) HandlerFunc {
 func BallotHandler(ctx context.Context, t *HTTP2Network) HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != "POST" {
-			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			sebakerror.WriteProblem(w, r, http.StatusMethodNotAllowed, nil)
 			return
 		}
 		if ct := r.Header.Get("Content-Type"); strings.ToLower(ct) != "application/json" {
-			http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+			sebakerror.WriteProblem(w, r, http.StatusBadRequest, errors.New("'Content-Type' must be 'application/json'"))
 			return
 		}
 
-		body, err := ioutil.ReadAll(r.Body)
-		if err != nil {
-			http.Error(w, "Error reading request body", http.StatusInternalServerError)
+		body, ok := t.readBody(w, r)
+		if !ok || !t.authenticate(ctx, w, r, BallotMessage, body, false) {
+			return
 		}
 
 		t.ReceiveChannel() <- Message{Type: BallotMessage, Data: body}
 		return
 	}
 }
+
+// BallotsHandler unpacks the `BallotBatch` to the ballot messages.
+func BallotsHandler(ctx context.Context, t *HTTP2Network) HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			sebakerror.WriteProblem(w, r, http.StatusMethodNotAllowed, nil)
+			return
+		}
+		if ct := r.Header.Get("Content-Type"); strings.ToLower(ct) != "application/octet-stream" {
+			sebakerror.WriteProblem(w, r, http.StatusBadRequest, errors.New("'Content-Type' must be 'application/octet-stream'"))
+			return
+		}
+
+		body, ok := t.readBody(w, r)
+		if !ok || !t.authenticate(ctx, w, r, BallotBatchMessage, body, false) {
+			return
+		}
+
+		batch, err := NewBallotBatchFromBytes(body)
+		if err != nil {
+			t.offend(r, PeerOffenseMalformed)
+			sebakerror.WriteProblem(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		for _, message := range batch.Messages() {
+			t.ReceiveChannel() <- message
+		}
+		return
+	}
+}
+
+func ViewChangeHandler(ctx context.Context, t *HTTP2Network) HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			sebakerror.WriteProblem(w, r, http.StatusMethodNotAllowed, nil)
+			return
+		}
+		if ct := r.Header.Get("Content-Type"); strings.ToLower(ct) != "application/json" {
+			sebakerror.WriteProblem(w, r, http.StatusBadRequest, errors.New("'Content-Type' must be 'application/json'"))
+			return
+		}
+
+		body, ok := t.readBody(w, r)
+		if !ok || !t.authenticate(ctx, w, r, ViewChangeMessage, body, false) {
+			return
+		}
+
+		t.ReceiveChannel() <- Message{Type: ViewChangeMessage, Data: body}
+		return
+	}
+}
+
+func BlockAnnouncementHandler(ctx context.Context, t *HTTP2Network) HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			sebakerror.WriteProblem(w, r, http.StatusMethodNotAllowed, nil)
+			return
+		}
+		if ct := r.Header.Get("Content-Type"); strings.ToLower(ct) != "application/json" {
+			sebakerror.WriteProblem(w, r, http.StatusBadRequest, errors.New("'Content-Type' must be 'application/json'"))
+			return
+		}
+
+		body, ok := t.readBody(w, r)
+		if !ok || !t.authenticate(ctx, w, r, BlockAnnouncementMessage, body, false) {
+			return
+		}
+
+		t.ReceiveChannel() <- Message{Type: BlockAnnouncementMessage, Data: body}
+		return
+	}
+}
+
+// TransactionInventoryHandler receives the hashes of the new transactions,
+// which the other node announces.
+func TransactionInventoryHandler(ctx context.Context, t *HTTP2Network) HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			sebakerror.WriteProblem(w, r, http.StatusMethodNotAllowed, nil)
+			return
+		}
+		if ct := r.Header.Get("Content-Type"); strings.ToLower(ct) != "application/json" {
+			sebakerror.WriteProblem(w, r, http.StatusBadRequest, errors.New("'Content-Type' must be 'application/json'"))
+			return
+		}
+
+		body, ok := t.readBody(w, r)
+		if !ok || !t.authenticate(ctx, w, r, TransactionInventoryMessage, body, false) {
+			return
+		}
+
+		t.ReceiveChannel() <- Message{Type: TransactionInventoryMessage, Data: body}
+		return
+	}
+}
+
+// GetTransactionsHandler returns the transactions of the hashes, which are
+// announced by the node; the body is the JSON list of the hashes.
+func GetTransactionsHandler(ctx context.Context, t *HTTP2Network) HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			sebakerror.WriteProblem(w, r, http.StatusMethodNotAllowed, nil)
+			return
+		}
+		if ct := r.Header.Get("Content-Type"); strings.ToLower(ct) != "application/json" {
+			sebakerror.WriteProblem(w, r, http.StatusBadRequest, errors.New("'Content-Type' must be 'application/json'"))
+			return
+		}
+
+		body, ok := t.readBody(w, r)
+		if !ok || !t.authenticate(ctx, w, r, GetTransactionsMessage, body, false) {
+			return
+		}
+
+		var hashes []string
+		if err := json.Unmarshal(body, &hashes); err != nil {
+			t.offend(r, PeerOffenseMalformed)
+			sebakerror.WriteProblem(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		b, err := getTransactions(ctx, hashes)
+		if err != nil {
+			status := http.StatusBadRequest
+			if err == sebakerror.ErrorMessageQueueFull {
+				status = http.StatusServiceUnavailable
+			}
+			sebakerror.WriteProblem(w, r, status, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(b)
+	}
+}
+
+// GetBlocksHandler returns the blocks of the range with their transactions
+// for the block sync; the body is the JSON of `BlockRange`.
+func GetBlocksHandler(ctx context.Context, t *HTTP2Network) HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			sebakerror.WriteProblem(w, r, http.StatusMethodNotAllowed, nil)
+			return
+		}
+		if ct := r.Header.Get("Content-Type"); strings.ToLower(ct) != "application/json" {
+			sebakerror.WriteProblem(w, r, http.StatusBadRequest, errors.New("'Content-Type' must be 'application/json'"))
+			return
+		}
+
+		body, ok := t.readBody(w, r)
+		if !ok || !t.authenticate(ctx, w, r, GetBlocksMessage, body, false) {
+			return
+		}
+
+		var blockRange BlockRange
+		if err := json.Unmarshal(body, &blockRange); err != nil {
+			t.offend(r, PeerOffenseMalformed)
+			sebakerror.WriteProblem(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		b, err := getBlocks(ctx, blockRange.From, blockRange.To)
+		if err != nil {
+			status := http.StatusBadRequest
+			if err == sebakerror.ErrorMessageQueueFull {
+				status = http.StatusServiceUnavailable
+			}
+			sebakerror.WriteProblem(w, r, status, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(b)
+	}
+}
+
+// PeersHandler returns the peer exchange of node, which is made by the
+// "peerExchange" of context.
+func PeersHandler(ctx context.Context, t *HTTP2Network) HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
+			sebakerror.WriteProblem(w, r, http.StatusMethodNotAllowed, nil)
+			return
+		}
+
+		b, err := getPeerExchange(ctx)
+		if err != nil {
+			sebakerror.WriteProblem(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(b)
+	}
+}
+
+// authenticate verifies the signature and the client certificate of the
+// message from the other node; the message, which is not authenticated is
+// refused. The malformed header and the invalid signature are the offenses of
+// the peer.
+func (t *HTTP2Network) authenticate(ctx context.Context, w http.ResponseWriter, r *http.Request, mt MessageType, body []byte, allowEmpty bool) bool {
+	var err error
+	if !allowEmpty {
+		err = t.requireClientCertificate(r)
+	}
+
+	var auth MessageAuth
+	if err == nil {
+		if auth, err = NewMessageAuthFromHeader(r.Header); err != nil {
+			t.offend(r, PeerOffenseMalformed)
+		}
+	}
+	if err == nil {
+		err = verifyMessage(ctx, mt, auth, body, allowEmpty)
+		switch err {
+		case nil, sebakerror.ErrorMessageNotSigned, sebakerror.ErrorMessageNotFromValidator, sebakerror.ErrorSessionKeyExpired:
+		default:
+			t.offend(r, PeerOffenseInvalidSignature)
+		}
+	}
+	if err != nil {
+		sebakerror.WriteProblem(w, r, http.StatusUnauthorized, err)
+		return false
+	}
+
+	return true
+}
+
+// offend counts the offense of the peer of request by the "peerBans" of
+// context.
+func (t *HTTP2Network) offend(r *http.Request, offense PeerOffense) {
+	if bans := peerBanList(t.ctx); bans != nil {
+		bans.Offend(remoteIP(r), offense)
+	}
+}
+
+// refuseBanned refuses the requests of the banned peer, and the sync requests
+// and the peer exchange of the greylisted peer; the connections of HTTP/2
+// stay open, so the peer, which is banned after it connected is also refused
+// here.
+func (t *HTTP2Network) refuseBanned(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		bans := peerBanList(t.ctx)
+		if bans == nil {
+			handler.ServeHTTP(w, r)
+			return
+		}
+
+		ip := remoteIP(r)
+		if bans.IsBanned(ip) {
+			sebakerror.WriteProblem(w, r, http.StatusForbidden, sebakerror.ErrorPeerBanned)
+			return
+		}
+		switch r.URL.Path {
+		case "/get-transactions", "/get-blocks", "/peers":
+			if bans.IsGreylisted(ip) {
+				sebakerror.WriteProblem(w, r, http.StatusForbidden, sebakerror.ErrorPeerGreylisted)
+				return
+			}
+		}
+
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// throttle limits the bandwidth of the requests and the responses of the peer
+// by `BandwidthLimiter`; the consensus messages are not limited.
+func (t *HTTP2Network) throttle(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/connect", "/ballot", "/ballots", "/view-change", "/block-announcement":
+			handler.ServeHTTP(w, r)
+			return
+		}
+		if !t.bandwidth.IsLimited() {
+			handler.ServeHTTP(w, r)
+			return
+		}
+
+		ip := remoteIP(r)
+		r.Body = &throttledReader{ReadCloser: r.Body, limiter: t.bandwidth, ip: ip}
+		handler.ServeHTTP(&throttledResponseWriter{ResponseWriter: w, limiter: t.bandwidth, ip: ip}, r)
+	})
+}
+
+// readBody reads the body of request up to `MaxRequestSize`; the larger
+// request is refused without reading the rest of it.
+func (t *HTTP2Network) readBody(w http.ResponseWriter, r *http.Request) (body []byte, ok bool) {
+	reader := io.Reader(r.Body)
+	if t.config.MaxRequestSize > 0 {
+		reader = io.LimitReader(r.Body, t.config.MaxRequestSize+1)
+	}
+
+	body, err := ioutil.ReadAll(reader)
+	if err != nil {
+		sebakerror.WriteProblem(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	if t.config.MaxRequestSize > 0 && int64(len(body)) > t.config.MaxRequestSize {
+		sebakerror.WriteProblem(w, r, http.StatusRequestEntityTooLarge, nil)
+		return
+	}
+	if strings.ToLower(r.Header.Get("Content-Type")) == "application/json" && !json.Valid(body) {
+		t.offend(r, PeerOffenseMalformed)
+		sebakerror.WriteProblem(w, r, http.StatusBadRequest, errors.New("body is not valid JSON"))
+		return
+	}
+
+	return body, true
+}
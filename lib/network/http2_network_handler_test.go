@@ -0,0 +1,92 @@
+package sebaknetwork
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHTTP2NetworkMaxRequestSize(t *testing.T) {
+	h2n := &HTTP2Network{
+		config:         HTTP2NetworkConfig{MaxRequestSize: 10},
+		receiveChannel: make(chan Message, 1),
+	}
+	handler := ViewChangeHandler(context.Background(), h2n)
+
+	request := func(body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("POST", "/view-change", strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		handler(w, r)
+		return w
+	}
+
+	if w := request(strings.Repeat("a", 11)); w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("large request must be refused: %d", w.Code)
+		return
+	}
+	if len(h2n.receiveChannel) != 0 {
+		t.Error("large request must not be received")
+		return
+	}
+
+	request(`"` + strings.Repeat("a", 8) + `"`)
+	if message := <-h2n.receiveChannel; len(message.Data) != 10 {
+		t.Errorf("wrong message: %v", message)
+		return
+	}
+}
+
+func TestHTTP2NetworkMessageAuth(t *testing.T) {
+	h2n := &HTTP2Network{receiveChannel: make(chan Message, 1)}
+
+	ctx := context.WithValue(context.Background(), "messageVerifier", MessageVerifyFunc(func(auth MessageAuth, hash string) error {
+		if auth.NodeKey != "node" || auth.Signature != "signed-"+hash {
+			return errors.New("invalid signature")
+		}
+		return nil
+	}))
+
+	request := func(handler HandlerFunc, path, body string, auth MessageAuth) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("POST", path, strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/json")
+		auth.SetHeader(r.Header)
+		w := httptest.NewRecorder()
+		handler(w, r)
+		return w
+	}
+
+	ballotHandler := BallotHandler(ctx, h2n)
+	if w := request(ballotHandler, "/ballot", "{}", MessageAuth{}); w.Code != http.StatusUnauthorized {
+		t.Errorf("unsigned ballot must be refused: %d", w.Code)
+		return
+	}
+
+	// signed as the other type of message
+	auth := MessageAuth{NodeKey: "node", Signature: "signed-" + MessageAuthHash(ViewChangeMessage, []byte("{}"))}
+	if w := request(ballotHandler, "/ballot", "{}", auth); w.Code != http.StatusUnauthorized {
+		t.Errorf("mis-signed ballot must be refused: %d", w.Code)
+		return
+	}
+	if len(h2n.receiveChannel) != 0 {
+		t.Error("refused ballot must not be received")
+		return
+	}
+
+	auth = MessageAuth{NodeKey: "node", Signature: "signed-" + MessageAuthHash(BallotMessage, []byte("{}"))}
+	request(ballotHandler, "/ballot", "{}", auth)
+	if message := <-h2n.receiveChannel; message.Type != BallotMessage {
+		t.Errorf("signed ballot must be received: %v", message)
+		return
+	}
+
+	// the message from client is not signed
+	request(MessageHandler(ctx, h2n), "/message", "{}", MessageAuth{})
+	if message := <-h2n.receiveChannel; message.Type != MessageFromClient {
+		t.Errorf("message from client must be received: %v", message)
+		return
+	}
+}
@@ -0,0 +1,58 @@
+package sebaknetwork
+
+import (
+	"sort"
+	"time"
+)
+
+// PeerLatencySamples is the number of the recent round trips kept for each
+// peer; the old samples are overwritten, so the latency follows the current
+// network condition.
+const PeerLatencySamples int = 20
+
+// PeerLatencyPercentile is the percentile of the samples, which represents
+// the latency of peer; the high percentile covers the most of round trips,
+// not only the typical one.
+const PeerLatencyPercentile float64 = 0.9
+
+// PeerLatency keeps the recent round trip times of sending the ballots to
+// one peer.
+type PeerLatency struct {
+	samples []time.Duration
+	next    int
+}
+
+func (l *PeerLatency) Add(d time.Duration) {
+	if len(l.samples) < PeerLatencySamples {
+		l.samples = append(l.samples, d)
+		return
+	}
+
+	l.samples[l.next] = d
+	l.next = (l.next + 1) % PeerLatencySamples
+}
+
+func (l *PeerLatency) Len() int {
+	return len(l.samples)
+}
+
+// Percentile returns the latency, which `p` of the samples are shorter than
+// or equal to; 0 if there are no samples.
+func (l *PeerLatency) Percentile(p float64) time.Duration {
+	if len(l.samples) < 1 {
+		return 0
+	}
+
+	sorted := make([]time.Duration, len(l.samples))
+	copy(sorted, l.samples)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	i := int(float64(len(sorted))*p+0.5) - 1
+	if i < 0 {
+		i = 0
+	} else if i >= len(sorted) {
+		i = len(sorted) - 1
+	}
+
+	return sorted[i]
+}
@@ -0,0 +1,35 @@
+package sebaknetwork
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPeerLatency(t *testing.T) {
+	var latency PeerLatency
+	if latency.Percentile(PeerLatencyPercentile) != 0 {
+		t.Error("latency without samples must be 0")
+		return
+	}
+
+	for i := 1; i <= 10; i++ {
+		latency.Add(time.Duration(i) * time.Millisecond)
+	}
+	if p := latency.Percentile(0.9); p != 9*time.Millisecond {
+		t.Errorf("wrong percentile: %s", p)
+		return
+	}
+	if p := latency.Percentile(1); p != 10*time.Millisecond {
+		t.Errorf("wrong maximum: %s", p)
+		return
+	}
+
+	// the old samples are overwritten
+	for i := 0; i < PeerLatencySamples; i++ {
+		latency.Add(time.Second)
+	}
+	if latency.Len() != PeerLatencySamples || latency.Percentile(0) != time.Second {
+		t.Errorf("old samples must be overwritten: %d %s", latency.Len(), latency.Percentile(0))
+		return
+	}
+}
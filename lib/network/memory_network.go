@@ -52,7 +52,10 @@ func (t *MemoryNetwork) GetClient(endpoint *sebakcommon.Endpoint) NetworkClient
 		return nil
 	}
 
-	return NewMemoryNetworkClient(endpoint, n)
+	client := NewMemoryNetworkClient(endpoint, n)
+	client.ctx = t.Context()
+
+	return client
 }
 
 func (p *MemoryNetwork) AddWatcher(f func(Network, net.Conn, http.ConnState)) {
@@ -88,6 +91,11 @@ func (p *MemoryNetwork) Send(mt MessageType, b []byte) (err error) {
 	return
 }
 
+// authenticate verifies the signature of message like `HTTP2Network`.
+func (p *MemoryNetwork) authenticate(mt MessageType, auth MessageAuth, b []byte) error {
+	return verifyMessage(p.Context(), mt, auth, b, mt == MessageFromClient)
+}
+
 func (p *MemoryNetwork) ReceiveChannel() chan Message {
 	return p.receiveChannel
 }
@@ -109,8 +117,19 @@ func (p *MemoryNetwork) receiveMessage() {
 
 func (p *MemoryNetwork) GetNodeInfo() []byte {
 	currentNode := p.Context().Value("currentNode").(sebakcommon.Serializable)
-	o, _ := currentNode.Serialize()
-	return o
+	return serializeNodeInfo(p.Context(), currentNode)
+}
+
+func (p *MemoryNetwork) GetPeers() ([]byte, error) {
+	return getPeerExchange(p.Context())
+}
+
+func (p *MemoryNetwork) GetTransactions(hashes []string) ([]byte, error) {
+	return getTransactions(p.Context(), hashes)
+}
+
+func (p *MemoryNetwork) GetBlocks(from, to uint64) ([]byte, error) {
+	return getBlocks(p.Context(), from, to)
 }
 
 func CreateNewMemoryEndpoint() *sebakcommon.Endpoint {
@@ -1,10 +1,14 @@
 package sebaknetwork
 
 import (
+	"context"
+	"encoding/json"
+
 	"boscoin.io/sebak/lib/common"
 )
 
 type MemoryTransportClient struct {
+	ctx      context.Context // the context of the network, which sends
 	endpoint *sebakcommon.Endpoint
 
 	server *MemoryNetwork
@@ -31,14 +35,17 @@ func (m *MemoryTransportClient) GetNodeInfo() (b []byte, err error) {
 	return
 }
 
+func (m *MemoryTransportClient) GetPeers() (b []byte, err error) {
+	return m.server.GetPeers()
+}
+
 func (m *MemoryTransportClient) SendMessage(message sebakcommon.Serializable) (err error) {
 	var s []byte
 	if s, err = message.Serialize(); err != nil {
 		return
 	}
-	m.server.Send(MessageFromClient, s)
 
-	return
+	return m.send(MessageFromClient, s)
 }
 
 func (m *MemoryTransportClient) SendBallot(message sebakcommon.Serializable) (err error) {
@@ -46,7 +53,97 @@ func (m *MemoryTransportClient) SendBallot(message sebakcommon.Serializable) (er
 	if s, err = message.Serialize(); err != nil {
 		return
 	}
-	m.server.Send(BallotMessage, s)
+
+	return m.send(BallotMessage, s)
+}
+
+func (m *MemoryTransportClient) SendBallots(message sebakcommon.Serializable) (err error) {
+	var s []byte
+	if s, err = message.Serialize(); err != nil {
+		return
+	}
+
+	if err = m.authenticate(BallotBatchMessage, s); err != nil {
+		return
+	}
+
+	var batch BallotBatch
+	if batch, err = NewBallotBatchFromBytes(s); err != nil {
+		return
+	}
+	for _, ballot := range batch.Messages() {
+		m.server.Send(ballot.Type, ballot.Data)
+	}
 
 	return
 }
+
+func (m *MemoryTransportClient) SendViewChange(message sebakcommon.Serializable) (err error) {
+	var s []byte
+	if s, err = message.Serialize(); err != nil {
+		return
+	}
+
+	return m.send(ViewChangeMessage, s)
+}
+
+func (m *MemoryTransportClient) SendBlockAnnouncement(message sebakcommon.Serializable) (err error) {
+	var s []byte
+	if s, err = message.Serialize(); err != nil {
+		return
+	}
+
+	return m.send(BlockAnnouncementMessage, s)
+}
+
+func (m *MemoryTransportClient) SendTransactionInventory(message sebakcommon.Serializable) (err error) {
+	var s []byte
+	if s, err = message.Serialize(); err != nil {
+		return
+	}
+
+	return m.send(TransactionInventoryMessage, s)
+}
+
+func (m *MemoryTransportClient) GetTransactions(hashes []string) (b []byte, err error) {
+	var s []byte
+	if s, err = json.Marshal(hashes); err != nil {
+		return
+	}
+	if err = m.authenticate(GetTransactionsMessage, s); err != nil {
+		return
+	}
+
+	return m.server.GetTransactions(hashes)
+}
+
+func (m *MemoryTransportClient) GetBlocks(from, to uint64) (b []byte, err error) {
+	var s []byte
+	if s, err = json.Marshal(BlockRange{From: from, To: to}); err != nil {
+		return
+	}
+	if err = m.authenticate(GetBlocksMessage, s); err != nil {
+		return
+	}
+
+	return m.server.GetBlocks(from, to)
+}
+
+// authenticate signs the message like `HTTP2NetworkClient` and the server
+// refuses it when it is not authenticated.
+func (m *MemoryTransportClient) authenticate(mt MessageType, s []byte) (err error) {
+	var auth MessageAuth
+	if auth, err = signMessage(m.ctx, mt, s); err != nil {
+		return
+	}
+
+	return m.server.authenticate(mt, auth, s)
+}
+
+func (m *MemoryTransportClient) send(mt MessageType, s []byte) (err error) {
+	if err = m.authenticate(mt, s); err != nil {
+		return
+	}
+
+	return m.server.Send(mt, s)
+}
@@ -167,3 +167,36 @@ func TestMemoryNetworkConnect(t *testing.T) {
 		return
 	}
 }
+
+func TestFuzzNetwork(t *testing.T) {
+	defer CleanUpMemoryNetwork()
+
+	mn := NewMemoryNetwork()
+	fn := NewFuzzNetwork(mn, 1, 4)
+	go fn.Start()
+	defer fn.Stop()
+
+	numberOfMessages := 20
+	go func() {
+		for i := 0; i < numberOfMessages; i++ {
+			mn.Send(BallotMessage, []byte(fmt.Sprintf(`{"n":%d}`, i)))
+		}
+	}()
+
+	// the mutated messages are same with the same seed
+	mutator := NewFuzzMutator(1)
+	var expected int
+	for i := 0; i < numberOfMessages; i++ {
+		_, mutated := mutator.Mutate(NewMessage(BallotMessage, []byte(fmt.Sprintf(`{"n":%d}`, i))))
+		expected += len(mutated)
+	}
+
+	for i := 0; i < expected; i++ {
+		select {
+		case <-fn.ReceiveMessage():
+		case <-time.After(time.Second * 2):
+			t.Errorf("failed to receive mutated messages: %d/%d", i, expected)
+			return
+		}
+	}
+}
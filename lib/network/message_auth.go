@@ -0,0 +1,122 @@
+package sebaknetwork
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+
+	"github.com/btcsuite/btcutil/base58"
+
+	"boscoin.io/sebak/lib/error"
+)
+
+// The messages between the nodes are signed by the node, which sends them,
+// and the receiving node refuses the message, which is not signed or
+// mis-signed, so the ballots, the view changes and the block announcements
+// can not be injected by the attacker in the network. The signature is in the
+// headers of request,
+//  * 'SEBAK-Node-Key': the address of node
+//  * 'SEBAK-Signature': the signature of `MessageAuthHash()`
+//  * 'SEBAK-Certificate': the session key certificate in base64, when the node
+//  signs by the session key
+// '/message' is also used by the clients, so the message without the
+// signature is allowed there, but the signed message must be verified.
+const (
+	MessageAuthNodeKeyHeader     string = "SEBAK-Node-Key"
+	MessageAuthSignatureHeader   string = "SEBAK-Signature"
+	MessageAuthCertificateHeader string = "SEBAK-Certificate"
+)
+
+// MessageAuth is the signature of the message by the node, which sends it.
+type MessageAuth struct {
+	NodeKey     string
+	Signature   string
+	Certificate []byte // the serialized session key certificate
+}
+
+func (a MessageAuth) IsEmpty() bool {
+	return len(a.NodeKey) < 1 && len(a.Signature) < 1
+}
+
+func (a MessageAuth) SetHeader(headers http.Header) {
+	if a.IsEmpty() {
+		return
+	}
+
+	headers.Set(MessageAuthNodeKeyHeader, a.NodeKey)
+	headers.Set(MessageAuthSignatureHeader, a.Signature)
+	if len(a.Certificate) > 0 {
+		headers.Set(MessageAuthCertificateHeader, base64.StdEncoding.EncodeToString(a.Certificate))
+	}
+}
+
+func NewMessageAuthFromHeader(headers http.Header) (a MessageAuth, err error) {
+	a.NodeKey = headers.Get(MessageAuthNodeKeyHeader)
+	a.Signature = headers.Get(MessageAuthSignatureHeader)
+	if v := headers.Get(MessageAuthCertificateHeader); len(v) > 0 {
+		if a.Certificate, err = base64.StdEncoding.DecodeString(v); err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+// MessageAuthHash is the hash, which is signed for the message; the type of
+// message is included, so the signed message can not be sent as the other
+// type. It is not `sebakcommon.MakeHash()`, which is too slow for the every
+// message.
+func MessageAuthHash(mt MessageType, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(mt.String()))
+	h.Write([]byte{'\n'})
+	h.Write(body)
+
+	return base58.Encode(h.Sum(nil))
+}
+
+// MessageSignFunc signs the hash of message to the other nodes; it is set as
+// "messageSigner" of the context of network.
+type MessageSignFunc func(hash string) (MessageAuth, error)
+
+// MessageVerifyFunc verifies the signature of the message from the other
+// node; it is set as "messageVerifier" of the context of network.
+type MessageVerifyFunc func(auth MessageAuth, hash string) error
+
+// signMessage signs the message by the "messageSigner" of context; without
+// it, the message is sent without the signature.
+func signMessage(ctx context.Context, mt MessageType, body []byte) (auth MessageAuth, err error) {
+	if ctx == nil {
+		return
+	}
+	f, ok := ctx.Value("messageSigner").(MessageSignFunc)
+	if !ok || f == nil {
+		return
+	}
+
+	return f(MessageAuthHash(mt, body))
+}
+
+// verifyMessage verifies the message by the "messageVerifier" of context;
+// the network without it does not authenticate the messages. The message
+// without the signature is refused, unless `allowEmpty`.
+func verifyMessage(ctx context.Context, mt MessageType, auth MessageAuth, body []byte, allowEmpty bool) (err error) {
+	if ctx == nil {
+		return
+	}
+	f, ok := ctx.Value("messageVerifier").(MessageVerifyFunc)
+	if !ok || f == nil {
+		return
+	}
+
+	if auth.IsEmpty() {
+		if allowEmpty {
+			return
+		}
+		err = sebakerror.ErrorMessageNotSigned
+		return
+	}
+
+	return f(auth, MessageAuthHash(mt, body))
+}
@@ -0,0 +1,178 @@
+package sebaknetwork
+
+import (
+	"sync/atomic"
+)
+
+// The inbound messages are handled by their priority, so the flood of the
+// transactions from clients can not delay the ballots and stall the chain.
+//  * `MessagePriorityConsensus`: the ballots, the view changes, the block
+//  announcements and the connects; they are always handled first and never
+//  dropped.
+//  * `MessagePriorityClient`: the transactions from clients and the
+//  transaction inventories; they are handled only when no consensus message
+//  is waiting, and dropped when the queue is full.
+//  * `MessagePrioritySync`: the requests of the other nodes to fetch the
+//  transactions and the blocks; they are served by the limited workers and
+//  refused while the consensus messages are piled up.
+
+type MessagePriority string
+
+const (
+	MessagePriorityConsensus MessagePriority = "consensus"
+	MessagePriorityClient    MessagePriority = "client"
+	MessagePrioritySync      MessagePriority = "sync"
+)
+
+func MessagePriorityOf(t MessageType) MessagePriority {
+	switch t {
+	case MessageFromClient, TransactionInventoryMessage:
+		return MessagePriorityClient
+	case GetTransactionsMessage, GetBlocksMessage:
+		return MessagePrioritySync
+	default:
+		return MessagePriorityConsensus
+	}
+}
+
+type MessageQueueConfig struct {
+	ConsensusSize int // the buffer of the consensus messages
+	ClientSize    int // the buffer of the client messages; over it, they are dropped
+	SyncWorkers   int // the sync requests, which are served at once
+}
+
+var DefaultMessageQueueConfig = MessageQueueConfig{
+	ConsensusSize: 10000,
+	ClientSize:    1000,
+	SyncWorkers:   4,
+}
+
+// MessageQueue splits the inbound messages to the queues of their priority.
+type MessageQueue struct {
+	config MessageQueueConfig
+
+	consensus chan Message
+	client    chan Message
+	sync      chan struct{}
+
+	dropped     uint64
+	syncRefused uint64
+
+	droppedHook func(Message)
+}
+
+func NewMessageQueue(config MessageQueueConfig) *MessageQueue {
+	q := &MessageQueue{}
+	q.SetConfig(config)
+
+	return q
+}
+
+func (q *MessageQueue) Config() MessageQueueConfig {
+	return q.config
+}
+
+// SetConfig makes the queues again, so it must be called before `Run`.
+func (q *MessageQueue) SetConfig(config MessageQueueConfig) {
+	q.config = config
+	q.consensus = make(chan Message, config.ConsensusSize)
+	q.client = make(chan Message, config.ClientSize)
+	q.sync = make(chan struct{}, config.SyncWorkers)
+}
+
+// SetDroppedHook sets the hook, which is called with the client message
+// dropped by the full queue.
+func (q *MessageQueue) SetDroppedHook(hook func(Message)) {
+	q.droppedHook = hook
+}
+
+// Run pushes the messages until they are closed; after that, the queues are
+// closed.
+func (q *MessageQueue) Run(messages <-chan Message) {
+	for message := range messages {
+		q.Push(message)
+	}
+
+	close(q.consensus)
+	close(q.client)
+}
+
+// Push queues the message by it's priority; the consensus message waits for
+// the room of queue, but the client message is dropped, if the queue is full.
+func (q *MessageQueue) Push(message Message) bool {
+	if MessagePriorityOf(message.Type) != MessagePriorityClient {
+		q.consensus <- message
+		return true
+	}
+
+	select {
+	case q.client <- message:
+		return true
+	default:
+		atomic.AddUint64(&q.dropped, 1)
+		if q.droppedHook != nil {
+			q.droppedHook(message)
+		}
+		return false
+	}
+}
+
+func (q *MessageQueue) Consensus() <-chan Message {
+	return q.consensus
+}
+
+func (q *MessageQueue) Client() <-chan Message {
+	return q.client
+}
+
+// Len returns the number of the waiting messages of the priority.
+func (q *MessageQueue) Len(priority MessagePriority) int {
+	switch priority {
+	case MessagePriorityConsensus:
+		return len(q.consensus)
+	case MessagePriorityClient:
+		return len(q.client)
+	case MessagePrioritySync:
+		return len(q.sync)
+	}
+
+	return 0
+}
+
+// IsClientFull checks the new client message will be dropped.
+func (q *MessageQueue) IsClientFull() bool {
+	return len(q.client) >= cap(q.client)
+}
+
+// Dropped returns the number of the client messages dropped by the full
+// queue.
+func (q *MessageQueue) Dropped() uint64 {
+	return atomic.LoadUint64(&q.dropped)
+}
+
+// SyncRefused returns the number of the refused sync requests.
+func (q *MessageQueue) SyncRefused() uint64 {
+	return atomic.LoadUint64(&q.syncRefused)
+}
+
+// AcquireSync takes the worker to serve the sync request; it fails, if every
+// worker is busy or the consensus queue is more than half full. The worker
+// must be returned by `ReleaseSync`.
+func (q *MessageQueue) AcquireSync() bool {
+	if len(q.consensus) > cap(q.consensus)/2 {
+		atomic.AddUint64(&q.syncRefused, 1)
+		return false
+	}
+
+	select {
+	case q.sync <- struct{}{}:
+		return true
+	default:
+		atomic.AddUint64(&q.syncRefused, 1)
+		return false
+	}
+}
+
+func (q *MessageQueue) ReleaseSync() {
+	<-q.sync
+}
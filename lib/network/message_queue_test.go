@@ -0,0 +1,110 @@
+package sebaknetwork
+
+import (
+	"testing"
+)
+
+func TestMessagePriorityOf(t *testing.T) {
+	expected := map[MessageType]MessagePriority{
+		BallotMessage:               MessagePriorityConsensus,
+		ViewChangeMessage:           MessagePriorityConsensus,
+		BlockAnnouncementMessage:    MessagePriorityConsensus,
+		ConnectMessage:              MessagePriorityConsensus,
+		MessageFromClient:           MessagePriorityClient,
+		TransactionInventoryMessage: MessagePriorityClient,
+		GetTransactionsMessage:      MessagePrioritySync,
+		GetBlocksMessage:            MessagePrioritySync,
+	}
+	for mt, priority := range expected {
+		if p := MessagePriorityOf(mt); p != priority {
+			t.Errorf("wrong priority of %s: %s", mt, p)
+			return
+		}
+	}
+}
+
+func TestMessageQueueDropsClientMessages(t *testing.T) {
+	q := NewMessageQueue(MessageQueueConfig{ConsensusSize: 2, ClientSize: 1, SyncWorkers: 1})
+
+	var dropped []Message
+	q.SetDroppedHook(func(message Message) {
+		dropped = append(dropped, message)
+	})
+
+	if !q.Push(Message{Type: MessageFromClient, Data: []byte("0")}) {
+		t.Error("client message must be queued")
+		return
+	}
+	if !q.IsClientFull() {
+		t.Error("client queue must be full")
+		return
+	}
+	if q.Push(Message{Type: MessageFromClient, Data: []byte("1")}) {
+		t.Error("client message over the queue must be dropped")
+		return
+	}
+	if q.Dropped() != 1 || len(dropped) != 1 || string(dropped[0].Data) != "1" {
+		t.Errorf("dropped message must be counted and hooked: %d %v", q.Dropped(), dropped)
+		return
+	}
+
+	// the consensus messages are not dropped by the full client queue
+	if !q.Push(Message{Type: BallotMessage}) || q.Len(MessagePriorityConsensus) != 1 {
+		t.Error("consensus message must be queued")
+		return
+	}
+}
+
+func TestMessageQueueRun(t *testing.T) {
+	q := NewMessageQueue(DefaultMessageQueueConfig)
+
+	messages := make(chan Message, 3)
+	messages <- Message{Type: MessageFromClient}
+	messages <- Message{Type: BallotMessage}
+	messages <- Message{Type: ViewChangeMessage}
+	close(messages)
+	q.Run(messages)
+
+	if m := <-q.Consensus(); m.Type != BallotMessage {
+		t.Errorf("wrong consensus message: %v", m)
+		return
+	}
+	if m := <-q.Consensus(); m.Type != ViewChangeMessage {
+		t.Errorf("wrong consensus message: %v", m)
+		return
+	}
+	if m := <-q.Client(); m.Type != MessageFromClient {
+		t.Errorf("wrong client message: %v", m)
+		return
+	}
+	if _, ok := <-q.Consensus(); ok {
+		t.Error("queue must be closed after the messages are closed")
+		return
+	}
+}
+
+func TestMessageQueueSync(t *testing.T) {
+	q := NewMessageQueue(MessageQueueConfig{ConsensusSize: 2, ClientSize: 1, SyncWorkers: 1})
+
+	if !q.AcquireSync() {
+		t.Error("sync worker must be acquired")
+		return
+	}
+	if q.AcquireSync() {
+		t.Error("busy sync worker must not be acquired")
+		return
+	}
+	q.ReleaseSync()
+
+	// the consensus messages are piled up
+	q.Push(Message{Type: BallotMessage})
+	q.Push(Message{Type: BallotMessage})
+	if q.AcquireSync() {
+		t.Error("sync request must be refused while consensus queue is busy")
+		return
+	}
+	if q.SyncRefused() != 2 {
+		t.Errorf("refused sync requests must be counted: %d", q.SyncRefused())
+		return
+	}
+}
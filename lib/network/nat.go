@@ -0,0 +1,487 @@
+package sebaknetwork
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// The node behind NAT or in the container advertises the endpoint, which the
+// other nodes can reach, instead of the bind address. It is given by
+// `--advertise-endpoint` or discovered by `NATConfig`,
+//  * `NATSTUN`: the public IP is asked to the STUN server; the port of bind
+//  address must be forwarded to the node
+//  * `NATUPnP`: the public IP is asked to the internet gateway device by UPnP,
+//  and the port of bind address is mapped to the node
+
+type NATMethod string
+
+const (
+	NATNone NATMethod = "none"
+	NATSTUN NATMethod = "stun"
+	NATUPnP NATMethod = "upnp"
+)
+
+const DefaultSTUNServer string = "stun.l.google.com:19302"
+
+// NATDiscoverTimeout is the timeout of the discovery of the public address.
+const NATDiscoverTimeout time.Duration = 10 * time.Second
+
+// UPnPLease is the lease of the port mapping by UPnP; the mapping is renewed
+// before it is expired.
+const UPnPLease time.Duration = time.Hour
+
+type NATConfig struct {
+	Method     NATMethod
+	STUNServer string
+}
+
+// NewNATConfigFromString parses 'none', 'upnp', 'stun' or
+// 'stun:<host>:<port>'.
+func NewNATConfigFromString(s string) (config NATConfig, err error) {
+	method := strings.SplitN(s, ":", 2)
+	switch NATMethod(method[0]) {
+	case NATNone, NATUPnP:
+		if len(method) > 1 {
+			err = fmt.Errorf("invalid nat, '%s'", s)
+			return
+		}
+		config.Method = NATMethod(method[0])
+	case NATSTUN:
+		config.Method = NATSTUN
+		config.STUNServer = DefaultSTUNServer
+		if len(method) > 1 {
+			if _, _, err = net.SplitHostPort(method[1]); err != nil {
+				return
+			}
+			config.STUNServer = method[1]
+		}
+	default:
+		err = fmt.Errorf("unknown nat, '%s'; must be 'none', 'upnp', 'stun' or 'stun:<host>:<port>'", s)
+	}
+
+	return
+}
+
+// NATMapping is the public address of node, which is discovered.
+type NATMapping struct {
+	IP   net.IP
+	Port int
+
+	gateway *UPnPGateway // nil if the port is not mapped by UPnP
+}
+
+// Discover finds the public address of the `port` of node.
+func (c NATConfig) Discover(port int, timeout time.Duration) (mapping NATMapping, err error) {
+	mapping.Port = port
+
+	switch c.Method {
+	case NATSTUN:
+		mapping.IP, err = STUNPublicIP(c.STUNServer, timeout)
+	case NATUPnP:
+		if mapping.gateway, err = DiscoverUPnPGateway(timeout); err != nil {
+			return
+		}
+		if mapping.IP, err = mapping.gateway.ExternalIP(); err != nil {
+			return
+		}
+		err = mapping.Renew()
+	default:
+		err = errors.New("nat is not enabled")
+	}
+
+	return
+}
+
+// IsMapped checks the port is mapped by UPnP, so it must be renewed.
+func (m NATMapping) IsMapped() bool {
+	return m.gateway != nil
+}
+
+// Renew maps the port by UPnP again for `UPnPLease`.
+func (m NATMapping) Renew() error {
+	if m.gateway == nil {
+		return nil
+	}
+
+	return m.gateway.AddPortMapping("TCP", m.Port, m.Port, "sebak", UPnPLease)
+}
+
+// Close removes the port mapping of UPnP.
+func (m NATMapping) Close() error {
+	if m.gateway == nil {
+		return nil
+	}
+
+	return m.gateway.DeletePortMapping("TCP", m.Port)
+}
+
+const (
+	stunMagicCookie          uint32 = 0x2112A442
+	stunBindingRequest       uint16 = 0x0001
+	stunBindingSuccess       uint16 = 0x0101
+	stunAttrMappedAddress    uint16 = 0x0001
+	stunAttrXORMappedAddress uint16 = 0x0020
+)
+
+// STUNPublicIP asks the STUN server for the public IP of node by the binding
+// request of RFC 5389.
+func STUNPublicIP(server string, timeout time.Duration) (ip net.IP, err error) {
+	var conn net.Conn
+	if conn, err = net.DialTimeout("udp", server, timeout); err != nil {
+		return
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	request, transactionID := makeSTUNBindingRequest()
+	if _, err = conn.Write(request); err != nil {
+		return
+	}
+
+	b := make([]byte, 1500)
+	var n int
+	if n, err = conn.Read(b); err != nil {
+		return
+	}
+
+	return parseSTUNBindingResponse(b[:n], transactionID)
+}
+
+func makeSTUNBindingRequest() (request []byte, transactionID []byte) {
+	request = make([]byte, 20)
+	binary.BigEndian.PutUint16(request[0:2], stunBindingRequest)
+	binary.BigEndian.PutUint16(request[2:4], 0)
+	binary.BigEndian.PutUint32(request[4:8], stunMagicCookie)
+	rand.Read(request[8:20])
+
+	return request, request[8:20]
+}
+
+func parseSTUNBindingResponse(b []byte, transactionID []byte) (ip net.IP, err error) {
+	if len(b) < 20 {
+		err = errors.New("too short STUN response")
+		return
+	}
+	if binary.BigEndian.Uint16(b[0:2]) != stunBindingSuccess {
+		err = fmt.Errorf("STUN binding failed: 0x%04x", binary.BigEndian.Uint16(b[0:2]))
+		return
+	}
+	if binary.BigEndian.Uint32(b[4:8]) != stunMagicCookie || !bytes.Equal(b[8:20], transactionID) {
+		err = errors.New("STUN response does not match the request")
+		return
+	}
+
+	length := int(binary.BigEndian.Uint16(b[2:4]))
+	if len(b) < 20+length {
+		err = errors.New("too short STUN response")
+		return
+	}
+
+	var mapped net.IP
+	attrs := b[20 : 20+length]
+	for len(attrs) >= 4 {
+		t := binary.BigEndian.Uint16(attrs[0:2])
+		l := int(binary.BigEndian.Uint16(attrs[2:4]))
+		if len(attrs) < 4+l {
+			break
+		}
+		value := attrs[4 : 4+l]
+
+		switch t {
+		case stunAttrXORMappedAddress:
+			if ip = parseSTUNAddress(value, b[4:20]); ip != nil {
+				return
+			}
+		case stunAttrMappedAddress:
+			mapped = parseSTUNAddress(value, nil)
+		}
+
+		// the attributes are padded to 4 bytes
+		attrs = attrs[4+(l+3)/4*4:]
+	}
+
+	if mapped == nil {
+		err = errors.New("no mapped address in STUN response")
+		return
+	}
+
+	return mapped, nil
+}
+
+// parseSTUNAddress parses the address attribute; the address of
+// XOR-MAPPED-ADDRESS is xored by `xor`, the magic cookie and the transaction
+// ID.
+func parseSTUNAddress(value []byte, xor []byte) net.IP {
+	if len(value) < 4 {
+		return nil
+	}
+
+	var size int
+	switch value[1] {
+	case 0x01:
+		size = net.IPv4len
+	case 0x02:
+		size = net.IPv6len
+	default:
+		return nil
+	}
+	if len(value) < 4+size {
+		return nil
+	}
+
+	ip := make(net.IP, size)
+	copy(ip, value[4:4+size])
+	if xor != nil {
+		for i := range ip {
+			ip[i] ^= xor[i]
+		}
+	}
+
+	return ip
+}
+
+const ssdpAddress string = "239.255.255.250:1900"
+
+var upnpWANServices = []string{
+	"urn:schemas-upnp-org:service:WANIPConnection:1",
+	"urn:schemas-upnp-org:service:WANIPConnection:2",
+	"urn:schemas-upnp-org:service:WANPPPConnection:1",
+}
+
+// UPnPGateway is the WAN connection service of the internet gateway device.
+type UPnPGateway struct {
+	ControlURL  string
+	ServiceType string
+	LocalIP     net.IP // the IP of node in the local network of gateway
+
+	client *http.Client
+}
+
+// DiscoverUPnPGateway finds the internet gateway device by SSDP.
+func DiscoverUPnPGateway(timeout time.Duration) (gateway *UPnPGateway, err error) {
+	var conn net.PacketConn
+	if conn, err = net.ListenPacket("udp4", ":0"); err != nil {
+		return
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	var addr *net.UDPAddr
+	if addr, err = net.ResolveUDPAddr("udp4", ssdpAddress); err != nil {
+		return
+	}
+	search := "M-SEARCH * HTTP/1.1\r\n" +
+		"HOST: " + ssdpAddress + "\r\n" +
+		"ST: urn:schemas-upnp-org:device:InternetGatewayDevice:1\r\n" +
+		"MAN: \"ssdp:discover\"\r\n" +
+		"MX: 2\r\n\r\n"
+	if _, err = conn.WriteTo([]byte(search), addr); err != nil {
+		return
+	}
+
+	b := make([]byte, 2048)
+	for {
+		var n int
+		if n, _, err = conn.ReadFrom(b); err != nil {
+			err = fmt.Errorf("no UPnP gateway found: %v", err)
+			return
+		}
+
+		var location string
+		if location, err = parseSSDPResponse(b[:n]); err != nil {
+			continue
+		}
+		if gateway, err = NewUPnPGateway(location, timeout); err == nil {
+			return
+		}
+	}
+}
+
+func parseSSDPResponse(b []byte) (location string, err error) {
+	var response *http.Response
+	if response, err = http.ReadResponse(bufio.NewReader(bytes.NewReader(b)), nil); err != nil {
+		return
+	}
+	response.Body.Close()
+
+	if location = response.Header.Get("Location"); len(location) < 1 {
+		err = errors.New("no location in SSDP response")
+	}
+
+	return
+}
+
+type upnpService struct {
+	ServiceType string `xml:"serviceType"`
+	ControlURL  string `xml:"controlURL"`
+}
+
+type upnpDevice struct {
+	Services []upnpService `xml:"serviceList>service"`
+	Devices  []upnpDevice  `xml:"deviceList>device"`
+}
+
+func (d upnpDevice) findService(serviceTypes []string) (upnpService, bool) {
+	for _, s := range d.Services {
+		for _, t := range serviceTypes {
+			if s.ServiceType == t {
+				return s, true
+			}
+		}
+	}
+	for _, child := range d.Devices {
+		if s, found := child.findService(serviceTypes); found {
+			return s, true
+		}
+	}
+
+	return upnpService{}, false
+}
+
+// NewUPnPGateway loads the device description of the gateway at `location`
+// and finds the WAN connection service.
+func NewUPnPGateway(location string, timeout time.Duration) (gateway *UPnPGateway, err error) {
+	client := &http.Client{Timeout: timeout}
+
+	var base *url.URL
+	if base, err = url.Parse(location); err != nil {
+		return
+	}
+
+	var response *http.Response
+	if response, err = client.Get(location); err != nil {
+		return
+	}
+	defer response.Body.Close()
+
+	var description struct {
+		URLBase string     `xml:"URLBase"`
+		Device  upnpDevice `xml:"device"`
+	}
+	if err = xml.NewDecoder(response.Body).Decode(&description); err != nil {
+		return
+	}
+	if len(description.URLBase) > 0 {
+		if base, err = url.Parse(description.URLBase); err != nil {
+			return
+		}
+	}
+
+	service, found := description.Device.findService(upnpWANServices)
+	if !found {
+		err = errors.New("no WAN connection service in UPnP gateway")
+		return
+	}
+	var control *url.URL
+	if control, err = base.Parse(service.ControlURL); err != nil {
+		return
+	}
+
+	// the local IP, which the gateway reaches the node by
+	var conn net.Conn
+	if conn, err = net.DialTimeout("udp", base.Host, timeout); err != nil {
+		return
+	}
+	localIP := conn.LocalAddr().(*net.UDPAddr).IP
+	conn.Close()
+
+	gateway = &UPnPGateway{
+		ControlURL:  control.String(),
+		ServiceType: service.ServiceType,
+		LocalIP:     localIP,
+		client:      client,
+	}
+
+	return
+}
+
+func (g *UPnPGateway) soap(action, arguments string) (body []byte, err error) {
+	envelope := `<?xml version="1.0"?>` +
+		`<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/" s:encodingStyle="http://schemas.xmlsoap.org/soap/encoding/">` +
+		`<s:Body><u:` + action + ` xmlns:u="` + g.ServiceType + `">` + arguments + `</u:` + action + `></s:Body>` +
+		`</s:Envelope>`
+
+	var request *http.Request
+	if request, err = http.NewRequest("POST", g.ControlURL, strings.NewReader(envelope)); err != nil {
+		return
+	}
+	request.Header.Set("Content-Type", `text/xml; charset="utf-8"`)
+	request.Header.Set("SOAPAction", `"`+g.ServiceType+"#"+action+`"`)
+
+	var response *http.Response
+	if response, err = g.client.Do(request); err != nil {
+		return
+	}
+	defer response.Body.Close()
+
+	if body, err = ioutil.ReadAll(response.Body); err != nil {
+		return
+	}
+	if response.StatusCode != http.StatusOK {
+		err = fmt.Errorf("UPnP %s failed: %s", action, response.Status)
+	}
+
+	return
+}
+
+// ExternalIP returns the public IP of the gateway.
+func (g *UPnPGateway) ExternalIP() (ip net.IP, err error) {
+	var body []byte
+	if body, err = g.soap("GetExternalIPAddress", ""); err != nil {
+		return
+	}
+
+	var response struct {
+		IP string `xml:"Body>GetExternalIPAddressResponse>NewExternalIPAddress"`
+	}
+	if err = xml.Unmarshal(body, &response); err != nil {
+		return
+	}
+	if ip = net.ParseIP(strings.TrimSpace(response.IP)); ip == nil {
+		err = fmt.Errorf("invalid external IP of UPnP gateway, '%s'", response.IP)
+	}
+
+	return
+}
+
+// AddPortMapping maps the external port of the gateway to the internal port
+// of node.
+func (g *UPnPGateway) AddPortMapping(protocol string, externalPort, internalPort int, description string, lease time.Duration) (err error) {
+	arguments := fmt.Sprintf(
+		"<NewRemoteHost></NewRemoteHost>"+
+			"<NewExternalPort>%d</NewExternalPort>"+
+			"<NewProtocol>%s</NewProtocol>"+
+			"<NewInternalPort>%d</NewInternalPort>"+
+			"<NewInternalClient>%s</NewInternalClient>"+
+			"<NewEnabled>1</NewEnabled>"+
+			"<NewPortMappingDescription>%s</NewPortMappingDescription>"+
+			"<NewLeaseDuration>%d</NewLeaseDuration>",
+		externalPort, protocol, internalPort, g.LocalIP, description, int(lease.Seconds()),
+	)
+	_, err = g.soap("AddPortMapping", arguments)
+
+	return
+}
+
+func (g *UPnPGateway) DeletePortMapping(protocol string, externalPort int) (err error) {
+	arguments := fmt.Sprintf(
+		"<NewRemoteHost></NewRemoteHost>"+
+			"<NewExternalPort>%d</NewExternalPort>"+
+			"<NewProtocol>%s</NewProtocol>",
+		externalPort, protocol,
+	)
+	_, err = g.soap("DeletePortMapping", arguments)
+
+	return
+}
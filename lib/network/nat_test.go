@@ -0,0 +1,198 @@
+package sebaknetwork
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewNATConfigFromString(t *testing.T) {
+	if config, err := NewNATConfigFromString("none"); err != nil || config.Method != NATNone {
+		t.Errorf("wrong nat config: %v %v", config, err)
+		return
+	}
+	if config, err := NewNATConfigFromString("stun"); err != nil || config.STUNServer != DefaultSTUNServer {
+		t.Errorf("default STUN server must be set: %v %v", config, err)
+		return
+	}
+	if config, err := NewNATConfigFromString("stun:stun.example.com:3478"); err != nil || config.STUNServer != "stun.example.com:3478" {
+		t.Errorf("STUN server must be set: %v %v", config, err)
+		return
+	}
+	for _, s := range []string{"", "pmp", "upnp:1234", "stun:example.com"} {
+		if _, err := NewNATConfigFromString(s); err == nil {
+			t.Errorf("invalid nat must be refused: '%s'", s)
+			return
+		}
+	}
+}
+
+// runTestSTUNServer answers the binding request with the XOR-MAPPED-ADDRESS
+// of `public`.
+func runTestSTUNServer(t *testing.T, public net.IP) net.PacketConn {
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		b := make([]byte, 1500)
+		for {
+			n, addr, err := conn.ReadFrom(b)
+			if err != nil {
+				return
+			}
+			if n < 20 {
+				continue
+			}
+
+			response := make([]byte, 32)
+			binary.BigEndian.PutUint16(response[0:2], stunBindingSuccess)
+			binary.BigEndian.PutUint16(response[2:4], 12)
+			copy(response[4:20], b[4:20])
+			binary.BigEndian.PutUint16(response[20:22], stunAttrXORMappedAddress)
+			binary.BigEndian.PutUint16(response[22:24], 8)
+			response[25] = 0x01
+			binary.BigEndian.PutUint16(response[26:28], 12345^uint16(stunMagicCookie>>16))
+			ip := public.To4()
+			for i := range ip {
+				response[28+i] = ip[i] ^ b[4+i]
+			}
+			conn.WriteTo(response, addr)
+		}
+	}()
+
+	return conn
+}
+
+func TestSTUNPublicIP(t *testing.T) {
+	public := net.ParseIP("203.0.113.7")
+	conn := runTestSTUNServer(t, public)
+	defer conn.Close()
+
+	ip, err := STUNPublicIP(conn.LocalAddr().String(), time.Second)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if !ip.Equal(public) {
+		t.Errorf("wrong public IP: %s", ip)
+		return
+	}
+
+	// the response of the other request
+	request, _ := makeSTUNBindingRequest()
+	if _, err := parseSTUNBindingResponse(request, make([]byte, 12)); err == nil {
+		t.Error("binding request must not be parsed as response")
+		return
+	}
+}
+
+func TestParseSSDPResponse(t *testing.T) {
+	response := "HTTP/1.1 200 OK\r\n" +
+		"CACHE-CONTROL: max-age=120\r\n" +
+		"ST: urn:schemas-upnp-org:device:InternetGatewayDevice:1\r\n" +
+		"LOCATION: http://192.168.0.1:5000/rootDesc.xml\r\n\r\n"
+	location, err := parseSSDPResponse([]byte(response))
+	if err != nil || location != "http://192.168.0.1:5000/rootDesc.xml" {
+		t.Errorf("wrong location: '%s' %v", location, err)
+		return
+	}
+
+	if _, err = parseSSDPResponse([]byte("HTTP/1.1 200 OK\r\n\r\n")); err == nil {
+		t.Error("response without location must be refused")
+		return
+	}
+}
+
+const testUPnPDescription = `<?xml version="1.0"?>
+<root xmlns="urn:schemas-upnp-org:device-1-0">
+  <device>
+    <deviceType>urn:schemas-upnp-org:device:InternetGatewayDevice:1</deviceType>
+    <deviceList>
+      <device>
+        <deviceType>urn:schemas-upnp-org:device:WANDevice:1</deviceType>
+        <deviceList>
+          <device>
+            <deviceType>urn:schemas-upnp-org:device:WANConnectionDevice:1</deviceType>
+            <serviceList>
+              <service>
+                <serviceType>urn:schemas-upnp-org:service:WANIPConnection:1</serviceType>
+                <controlURL>/ctl/IPConn</controlURL>
+              </service>
+            </serviceList>
+          </device>
+        </deviceList>
+      </device>
+    </deviceList>
+  </device>
+</root>`
+
+func TestUPnPGateway(t *testing.T) {
+	var actions []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/rootDesc.xml" {
+			fmt.Fprint(w, testUPnPDescription)
+			return
+		}
+		if r.URL.Path != "/ctl/IPConn" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		body, _ := ioutil.ReadAll(r.Body)
+		action := strings.Trim(r.Header.Get("SOAPAction"), `"`)
+		actions = append(actions, action)
+		switch {
+		case strings.HasSuffix(action, "#GetExternalIPAddress"):
+			fmt.Fprint(w, `<?xml version="1.0"?><s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/"><s:Body>`+
+				`<u:GetExternalIPAddressResponse xmlns:u="urn:schemas-upnp-org:service:WANIPConnection:1">`+
+				`<NewExternalIPAddress>203.0.113.7</NewExternalIPAddress>`+
+				`</u:GetExternalIPAddressResponse></s:Body></s:Envelope>`)
+		case strings.HasSuffix(action, "#AddPortMapping"):
+			if !strings.Contains(string(body), "<NewExternalPort>12345</NewExternalPort>") {
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+		case strings.HasSuffix(action, "#DeletePortMapping"):
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	gateway, err := NewUPnPGateway(server.URL+"/rootDesc.xml", time.Second)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if gateway.ControlURL != server.URL+"/ctl/IPConn" || gateway.ServiceType != upnpWANServices[0] {
+		t.Errorf("wrong WAN connection service: %v", gateway)
+		return
+	}
+
+	ip, err := gateway.ExternalIP()
+	if err != nil || !ip.Equal(net.ParseIP("203.0.113.7")) {
+		t.Errorf("wrong external IP: %s %v", ip, err)
+		return
+	}
+
+	mapping := NATMapping{IP: ip, Port: 12345, gateway: gateway}
+	if err = mapping.Renew(); err != nil {
+		t.Error(err)
+		return
+	}
+	if err = mapping.Close(); err != nil {
+		t.Error(err)
+		return
+	}
+	if len(actions) != 3 || !strings.HasSuffix(actions[1], "#AddPortMapping") || !strings.HasSuffix(actions[2], "#DeletePortMapping") {
+		t.Errorf("wrong UPnP actions: %v", actions)
+		return
+	}
+}
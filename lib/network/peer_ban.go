@@ -0,0 +1,320 @@
+package sebaknetwork
+
+import (
+	"context"
+	"math"
+	"net"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// The IP, which sends the malformed messages or the messages of the invalid
+// signatures repeatedly is banned from the node network. Each offense adds
+// it's weight to the score of IP, and the score is halved every
+// `PeerBanConfig.HalfLife` after `PeerBanDecayDelay` from the last offense,
+// so the occasional mistake is forgotten,
+//  * over `PeerBanConfig.GreylistScore`, the IP is greylisted; it's sync
+//  requests and peer exchange are refused, but it's consensus messages are
+//  still handled
+//  * over `PeerBanConfig.BanScore`, the IP is banned for
+//  `PeerBanConfig.BanDuration`; it's connections and requests are refused
+// The messages of the unknown node or the expired session key are refused,
+// but they are not the offenses, because they are not malformed.
+
+type PeerOffense string
+
+const (
+	PeerOffenseMalformed        PeerOffense = "malformed"
+	PeerOffenseInvalidSignature PeerOffense = "invalid-signature"
+)
+
+var peerOffenseWeights = map[PeerOffense]float64{
+	PeerOffenseMalformed:        1,
+	PeerOffenseInvalidSignature: 2,
+}
+
+type PeerBanConfig struct {
+	GreylistScore float64
+	BanScore      float64
+	HalfLife      time.Duration
+	BanDuration   time.Duration // 0 disables ban and greylist
+}
+
+var DefaultPeerBanConfig = PeerBanConfig{
+	GreylistScore: 5,
+	BanScore:      10,
+	HalfLife:      10 * time.Minute,
+	BanDuration:   24 * time.Hour,
+}
+
+const PeerBanPruneInterval time.Duration = time.Minute
+
+// PeerBanDecayDelay is the time after the last offense, in which the score is
+// not decayed; without it, the burst of offenses, which adds up to
+// `PeerBanConfig.BanScore` exactly is decayed just under it and never banned.
+const PeerBanDecayDelay time.Duration = time.Second
+
+// PeerBan is the ban of IP.
+type PeerBan struct {
+	IP     string      `json:"ip"`
+	Reason PeerOffense `json:"reason"` // the last offense
+	Since  time.Time   `json:"since"`
+	Until  time.Time   `json:"until"`
+}
+
+type peerOffender struct {
+	score   float64
+	updated time.Time
+}
+
+type PeerBanList struct {
+	sync.Mutex
+
+	config    PeerBanConfig
+	offenders map[string]*peerOffender
+	bans      map[string]PeerBan
+	offenses  map[PeerOffense]uint64
+	lastPrune time.Time
+
+	hook func(ban PeerBan, banned bool)
+	now  func() time.Time
+}
+
+func NewPeerBanList(config PeerBanConfig) *PeerBanList {
+	return &PeerBanList{
+		config:    config,
+		offenders: map[string]*peerOffender{},
+		bans:      map[string]PeerBan{},
+		offenses:  map[PeerOffense]uint64{},
+		lastPrune: time.Now(),
+		now:       time.Now,
+	}
+}
+
+func (l *PeerBanList) Config() PeerBanConfig {
+	l.Lock()
+	defer l.Unlock()
+
+	return l.config
+}
+
+func (l *PeerBanList) SetConfig(config PeerBanConfig) {
+	l.Lock()
+	defer l.Unlock()
+
+	l.config = config
+}
+
+// SetHook sets the hook, which is called when the IP is banned or the ban is
+// removed; it is for keeping the bans in storage.
+func (l *PeerBanList) SetHook(hook func(ban PeerBan, banned bool)) {
+	l.Lock()
+	defer l.Unlock()
+
+	l.hook = hook
+}
+
+// Restore adds the bans, which are kept before; the expired ones are
+// ignored and the hook is not called.
+func (l *PeerBanList) Restore(bans ...PeerBan) {
+	l.Lock()
+	defer l.Unlock()
+
+	now := l.now()
+	for _, ban := range bans {
+		if now.Before(ban.Until) {
+			l.bans[ban.IP] = ban
+		}
+	}
+}
+
+// score returns the decayed score of offender; the lock must be held.
+func (l *PeerBanList) score(offender *peerOffender, now time.Time) float64 {
+	elapsed := now.Sub(offender.updated)
+	if l.config.HalfLife <= 0 || elapsed < PeerBanDecayDelay {
+		return offender.score
+	}
+
+	return offender.score * math.Pow(0.5, float64(elapsed)/float64(l.config.HalfLife))
+}
+
+// Offend counts the offense of IP; it returns true when the IP is banned by
+// it.
+func (l *PeerBanList) Offend(ip string, offense PeerOffense) bool {
+	l.Lock()
+
+	l.offenses[offense]++
+	if l.config.BanDuration <= 0 || len(ip) < 1 {
+		l.Unlock()
+		return false
+	}
+
+	now := l.now()
+	l.prune(now)
+
+	offender, found := l.offenders[ip]
+	if !found {
+		offender = &peerOffender{}
+		l.offenders[ip] = offender
+	}
+	offender.score = l.score(offender, now) + peerOffenseWeights[offense]
+	offender.updated = now
+
+	if offender.score < l.config.BanScore {
+		l.Unlock()
+		return false
+	}
+
+	delete(l.offenders, ip)
+	ban := PeerBan{IP: ip, Reason: offense, Since: now, Until: now.Add(l.config.BanDuration)}
+	l.bans[ip] = ban
+	hook := l.hook
+	l.Unlock()
+
+	log.Warn("peer banned", "ip", ip, "reason", offense, "until", ban.Until)
+	if hook != nil {
+		hook(ban, true)
+	}
+
+	return true
+}
+
+// Unban removes the ban and the offenses of IP; it returns false if the IP is
+// not banned.
+func (l *PeerBanList) Unban(ip string) bool {
+	l.Lock()
+	ban, found := l.bans[ip]
+	delete(l.bans, ip)
+	delete(l.offenders, ip)
+	hook := l.hook
+	l.Unlock()
+
+	if found && hook != nil {
+		hook(ban, false)
+	}
+
+	return found
+}
+
+// IsBanned checks the IP is banned now; the expired ban is removed.
+func (l *PeerBanList) IsBanned(ip string) bool {
+	l.Lock()
+	ban, found := l.bans[ip]
+	if !found {
+		l.Unlock()
+		return false
+	}
+	if l.now().Before(ban.Until) {
+		l.Unlock()
+		return true
+	}
+	delete(l.bans, ip)
+	hook := l.hook
+	l.Unlock()
+
+	if hook != nil {
+		hook(ban, false)
+	}
+
+	return false
+}
+
+// IsGreylisted checks the score of IP is over `PeerBanConfig.GreylistScore`.
+func (l *PeerBanList) IsGreylisted(ip string) bool {
+	l.Lock()
+	defer l.Unlock()
+
+	if l.config.BanDuration <= 0 {
+		return false
+	}
+	offender, found := l.offenders[ip]
+	if !found {
+		return false
+	}
+
+	return l.score(offender, l.now()) >= l.config.GreylistScore
+}
+
+// Bans returns the bans, which are not expired, by IP.
+func (l *PeerBanList) Bans() []PeerBan {
+	l.Lock()
+	defer l.Unlock()
+
+	now := l.now()
+	bans := []PeerBan{}
+	for _, ban := range l.bans {
+		if now.Before(ban.Until) {
+			bans = append(bans, ban)
+		}
+	}
+	sort.Slice(bans, func(i, j int) bool { return bans[i].IP < bans[j].IP })
+
+	return bans
+}
+
+// Greylisted returns the greylisted IPs.
+func (l *PeerBanList) Greylisted() []string {
+	l.Lock()
+	defer l.Unlock()
+
+	now := l.now()
+	ips := []string{}
+	for ip, offender := range l.offenders {
+		if l.score(offender, now) >= l.config.GreylistScore {
+			ips = append(ips, ip)
+		}
+	}
+	sort.Strings(ips)
+
+	return ips
+}
+
+// Offenses returns the number of offenses by kind.
+func (l *PeerBanList) Offenses() map[PeerOffense]uint64 {
+	l.Lock()
+	defer l.Unlock()
+
+	offenses := map[PeerOffense]uint64{}
+	for offense, n := range l.offenses {
+		offenses[offense] = n
+	}
+
+	return offenses
+}
+
+// prune removes the offenders, whose score is almost forgotten; the lock must
+// be held.
+func (l *PeerBanList) prune(now time.Time) {
+	if now.Sub(l.lastPrune) < PeerBanPruneInterval {
+		return
+	}
+	l.lastPrune = now
+
+	for ip, offender := range l.offenders {
+		if l.score(offender, now) < 0.1 {
+			delete(l.offenders, ip)
+		}
+	}
+}
+
+// peerBanList returns the "peerBans" of context; nil if the network does not
+// ban the peers.
+func peerBanList(ctx context.Context) *PeerBanList {
+	if ctx == nil {
+		return nil
+	}
+	l, _ := ctx.Value("peerBans").(*PeerBanList)
+
+	return l
+}
+
+func remoteIP(r *http.Request) string {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return ip
+}
@@ -0,0 +1,152 @@
+package sebaknetwork
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestPeerBanList(t *testing.T) {
+	l := NewPeerBanList(DefaultPeerBanConfig)
+	now := time.Now()
+	l.now = func() time.Time { return now }
+
+	var hooked []PeerBan
+	l.SetHook(func(ban PeerBan, banned bool) {
+		if banned {
+			hooked = append(hooked, ban)
+		}
+	})
+
+	// the malformed messages greylist the IP before it is banned
+	for i := 0; i < 5; i++ {
+		if l.Offend("1.1.1.1", PeerOffenseMalformed) {
+			t.Error("IP must not be banned yet")
+			return
+		}
+	}
+	if !l.IsGreylisted("1.1.1.1") || l.IsBanned("1.1.1.1") {
+		t.Error("IP must be greylisted, but not banned")
+		return
+	}
+
+	// the score is halved in the half life
+	now = now.Add(DefaultPeerBanConfig.HalfLife)
+	if l.IsGreylisted("1.1.1.1") {
+		t.Error("score must be decayed")
+		return
+	}
+
+	// the invalid signatures weigh more
+	for i := 0; i < 3; i++ {
+		l.Offend("1.1.1.1", PeerOffenseInvalidSignature)
+	}
+	if !l.Offend("1.1.1.1", PeerOffenseInvalidSignature) || !l.IsBanned("1.1.1.1") {
+		t.Error("IP must be banned")
+		return
+	}
+	if len(hooked) != 1 || hooked[0].IP != "1.1.1.1" || hooked[0].Reason != PeerOffenseInvalidSignature {
+		t.Errorf("ban must be hooked: %v", hooked)
+		return
+	}
+	if l.IsBanned("2.2.2.2") {
+		t.Error("the other IP must not be banned")
+		return
+	}
+	if offenses := l.Offenses(); offenses[PeerOffenseMalformed] != 5 || offenses[PeerOffenseInvalidSignature] != 4 {
+		t.Errorf("wrong offenses: %v", offenses)
+		return
+	}
+
+	// the ban expires
+	now = now.Add(DefaultPeerBanConfig.BanDuration)
+	if l.IsBanned("1.1.1.1") || len(l.Bans()) != 0 {
+		t.Error("ban must be expired")
+		return
+	}
+
+	// the restored ban and unban
+	l.Restore(PeerBan{IP: "3.3.3.3", Until: now.Add(time.Hour)}, PeerBan{IP: "4.4.4.4", Until: now})
+	if !l.IsBanned("3.3.3.3") || l.IsBanned("4.4.4.4") {
+		t.Error("only the ban, which is not expired must be restored")
+		return
+	}
+	if !l.Unban("3.3.3.3") || l.IsBanned("3.3.3.3") || l.Unban("3.3.3.3") {
+		t.Error("failed to unban")
+		return
+	}
+}
+
+// TestPeerBanListBurst checks the burst of offenses, which adds up to the
+// `BanScore` exactly bans the IP, though the time passes between them.
+func TestPeerBanListBurst(t *testing.T) {
+	l := NewPeerBanList(DefaultPeerBanConfig)
+	now := time.Now()
+	l.now = func() time.Time { return now }
+
+	for i := 0; i < 4; i++ {
+		if l.Offend("1.1.1.1", PeerOffenseInvalidSignature) {
+			t.Error("IP must not be banned yet")
+			return
+		}
+		now = now.Add(100 * time.Millisecond)
+	}
+	if !l.Offend("1.1.1.1", PeerOffenseInvalidSignature) || !l.IsBanned("1.1.1.1") {
+		t.Error("IP must be banned by the burst of offenses")
+		return
+	}
+
+	// after the delay, the score is decayed
+	for i := 0; i < 4; i++ {
+		l.Offend("2.2.2.2", PeerOffenseInvalidSignature)
+	}
+	now = now.Add(PeerBanDecayDelay)
+	if l.Offend("2.2.2.2", PeerOffenseInvalidSignature) {
+		t.Error("decayed score must not ban the IP")
+		return
+	}
+}
+
+func TestPeerBanListDisabled(t *testing.T) {
+	l := NewPeerBanList(PeerBanConfig{GreylistScore: 1, BanScore: 1})
+	if l.Offend("1.1.1.1", PeerOffenseInvalidSignature) || l.IsGreylisted("1.1.1.1") {
+		t.Error("IP must not be banned without the ban duration")
+		return
+	}
+}
+
+func TestHTTP2NetworkRefuseBanned(t *testing.T) {
+	l := NewPeerBanList(DefaultPeerBanConfig)
+	h2n := &HTTP2Network{ctx: context.WithValue(context.Background(), "peerBans", l)}
+	handler := h2n.refuseBanned(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	request := func(path string) int {
+		r := httptest.NewRequest("POST", path, nil)
+		r.RemoteAddr = "1.1.1.1:12345"
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	for i := 0; i < 3; i++ {
+		l.Offend("1.1.1.1", PeerOffenseInvalidSignature)
+	}
+	if code := request("/ballot"); code != http.StatusOK {
+		t.Errorf("ballot of the greylisted peer must be handled: %d", code)
+		return
+	}
+	if code := request("/get-blocks"); code != http.StatusForbidden {
+		t.Errorf("sync request of the greylisted peer must be refused: %d", code)
+		return
+	}
+
+	for i := 0; i < 2; i++ {
+		l.Offend("1.1.1.1", PeerOffenseInvalidSignature)
+	}
+	if code := request("/ballot"); code != http.StatusForbidden {
+		t.Errorf("banned peer must be refused: %d", code)
+		return
+	}
+}
@@ -0,0 +1,223 @@
+package sebaknetwork
+
+import (
+	"sync"
+	"time"
+)
+
+// The health of validator is scored from 0 to 100 by,
+//  * the connection: the validator, which is not connected or whose circuit
+//  is open scores 0
+//  * the missed ballots: the ratio of the recent rounds, which the validator
+//  sent the ballot for
+//  * the failed requests: the ratio of the requests to the validator, which
+//  failed
+//  * the latency of ballots: the latency over `PeerScoreLatencyTarget` lowers
+//  the score, down to half at `PeerScoreLatencyMax`
+
+// PeerScoreRounds is the number of the recent rounds kept for each validator
+// to count the missed ballots.
+const PeerScoreRounds int = 100
+
+// PeerScoreBallotGrace is how long the ballots of the closed round are still
+// counted; the validator, which sends the ballot a little after the others is
+// not missed.
+const PeerScoreBallotGrace time.Duration = 2 * time.Second
+
+// MaxPeerScorePendingRounds is the maximum number of the rounds, which are not
+// closed; the oldest round is dropped without counting.
+const MaxPeerScorePendingRounds int = 1000
+
+const (
+	PeerScoreLatencyTarget time.Duration = 500 * time.Millisecond
+	PeerScoreLatencyMax    time.Duration = 5 * time.Second
+
+	// the validator under this score is lagging
+	PeerScoreHealthy int = 80
+)
+
+type PeerStatus string
+
+const (
+	PeerHealthy     PeerStatus = "healthy"
+	PeerLagging     PeerStatus = "lagging"
+	PeerUnreachable PeerStatus = "unreachable"
+)
+
+// PeerScore is the health of validator.
+type PeerScore struct {
+	Score  int        `json:"score"`
+	Status PeerStatus `json:"status"`
+
+	Rounds        int     `json:"rounds"` // the recent rounds, which are counted
+	MissedBallots int     `json:"missed_ballots"`
+	FailureRate   float64 `json:"failure_rate"`
+}
+
+// NewPeerScore scores the validator by the factors above.
+func NewPeerScore(connected bool, rounds, missed int, health PeerHealth, latency time.Duration) (score PeerScore) {
+	score = PeerScore{Rounds: rounds, MissedBallots: missed, Status: PeerUnreachable}
+	if health.TotalRequests > 0 {
+		score.FailureRate = float64(health.TotalFailures) / float64(health.TotalRequests)
+	}
+	if !connected || health.Circuit == CircuitOpen {
+		return
+	}
+
+	s := 1.0
+	if rounds > 0 {
+		s *= float64(rounds-missed) / float64(rounds)
+	}
+	s *= 1 - score.FailureRate
+	if latency > PeerScoreLatencyTarget {
+		over := float64(latency-PeerScoreLatencyTarget) / float64(PeerScoreLatencyMax-PeerScoreLatencyTarget)
+		if over > 1 {
+			over = 1
+		}
+		s *= 1 - over/2
+	}
+
+	score.Score = int(s*100 + 0.5)
+	if score.Score >= PeerScoreHealthy {
+		score.Status = PeerHealthy
+	} else {
+		score.Status = PeerLagging
+	}
+
+	return
+}
+
+// BallotParticipation counts the rounds, which each validator sent the
+// ballots for.
+type BallotParticipation struct {
+	sync.Mutex
+
+	voted    map[ /* round */ string]map[ /* validator */ string]bool
+	order    []string                                   // the rounds in the order of the first ballot
+	closed   map[ /* round */ string]time.Time          // the closed rounds in `PeerScoreBallotGrace`
+	finished map[ /* round */ string]bool               // the counted rounds, whose late ballots are ignored
+	done     []string                                   // the counted rounds in order
+	recent   map[ /* validator */ string]*participation // the recent rounds of the validator
+}
+
+type participation struct {
+	missed []bool
+	next   int
+}
+
+func (p *participation) add(missed bool) {
+	if len(p.missed) < PeerScoreRounds {
+		p.missed = append(p.missed, missed)
+		return
+	}
+
+	p.missed[p.next] = missed
+	p.next = (p.next + 1) % PeerScoreRounds
+}
+
+func (p *participation) count() (rounds, missed int) {
+	for _, m := range p.missed {
+		if m {
+			missed++
+		}
+	}
+
+	return len(p.missed), missed
+}
+
+func NewBallotParticipation() *BallotParticipation {
+	return &BallotParticipation{
+		voted:    map[string]map[string]bool{},
+		closed:   map[string]time.Time{},
+		finished: map[string]bool{},
+		recent:   map[string]*participation{},
+	}
+}
+
+// Observe records the ballot of the validator in the round.
+func (b *BallotParticipation) Observe(round, validator string) {
+	b.Lock()
+	defer b.Unlock()
+
+	if b.finished[round] {
+		return
+	}
+
+	voted, ok := b.voted[round]
+	if !ok {
+		voted = map[string]bool{}
+		b.voted[round] = voted
+		b.order = append(b.order, round)
+		if len(b.order) > MaxPeerScorePendingRounds {
+			b.drop(b.order[0])
+		}
+	}
+	voted[validator] = true
+}
+
+// Close closes the round; the validators, which do not send the ballot until
+// `PeerScoreBallotGrace` missed it.
+func (b *BallotParticipation) Close(round string) {
+	b.Lock()
+	defer b.Unlock()
+
+	if _, ok := b.voted[round]; !ok {
+		return
+	}
+	if _, ok := b.closed[round]; !ok {
+		b.closed[round] = time.Now()
+	}
+}
+
+// Count returns the recent rounds and the missed rounds of the validators.
+func (b *BallotParticipation) Count(validators []string) (rounds, missed map[string]int) {
+	b.Lock()
+	defer b.Unlock()
+
+	b.finish(validators)
+
+	rounds, missed = map[string]int{}, map[string]int{}
+	for _, validator := range validators {
+		if p, ok := b.recent[validator]; ok {
+			rounds[validator], missed[validator] = p.count()
+		}
+	}
+
+	return
+}
+
+// finish counts the closed rounds after `PeerScoreBallotGrace`.
+func (b *BallotParticipation) finish(validators []string) {
+	for round, closed := range b.closed {
+		if time.Since(closed) < PeerScoreBallotGrace {
+			continue
+		}
+		for _, validator := range validators {
+			p, ok := b.recent[validator]
+			if !ok {
+				p = &participation{}
+				b.recent[validator] = p
+			}
+			p.add(!b.voted[round][validator])
+		}
+		b.drop(round)
+
+		b.finished[round] = true
+		b.done = append(b.done, round)
+		if len(b.done) > MaxPeerScorePendingRounds {
+			delete(b.finished, b.done[0])
+			b.done = b.done[1:]
+		}
+	}
+}
+
+func (b *BallotParticipation) drop(round string) {
+	delete(b.voted, round)
+	delete(b.closed, round)
+	for i, r := range b.order {
+		if r == round {
+			b.order = append(b.order[:i], b.order[i+1:]...)
+			break
+		}
+	}
+}
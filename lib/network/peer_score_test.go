@@ -0,0 +1,79 @@
+package sebaknetwork
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewPeerScore(t *testing.T) {
+	healthy := PeerHealth{Circuit: CircuitClosed, TotalRequests: 100}
+
+	if score := NewPeerScore(true, 10, 0, healthy, 10*time.Millisecond); score.Score != 100 || score.Status != PeerHealthy {
+		t.Errorf("validator must be healthy: %v", score)
+		return
+	}
+	if score := NewPeerScore(false, 10, 0, healthy, 0); score.Score != 0 || score.Status != PeerUnreachable {
+		t.Errorf("disconnected validator must be unreachable: %v", score)
+		return
+	}
+	open := PeerHealth{Circuit: CircuitOpen, TotalRequests: 10, TotalFailures: 5}
+	if score := NewPeerScore(true, 10, 0, open, 0); score.Status != PeerUnreachable || score.FailureRate != 0.5 {
+		t.Errorf("validator of open circuit must be unreachable: %v", score)
+		return
+	}
+
+	// missed the half of ballots
+	if score := NewPeerScore(true, 10, 5, healthy, 0); score.Score != 50 || score.Status != PeerLagging {
+		t.Errorf("validator, which missed ballots must be lagging: %v", score)
+		return
+	}
+
+	// the latency over the maximum halves the score
+	if score := NewPeerScore(true, 0, 0, healthy, time.Minute); score.Score != 50 || score.Status != PeerLagging {
+		t.Errorf("slow validator must be lagging: %v", score)
+		return
+	}
+}
+
+func TestBallotParticipation(t *testing.T) {
+	b := NewBallotParticipation()
+	validators := []string{"v0", "v1"}
+
+	b.Observe("round0", "v0")
+	b.Observe("round0", "v1")
+	b.Observe("round1", "v0")
+	b.Close("round0")
+	b.Close("round1")
+
+	// the closed rounds are not counted in the grace
+	if rounds, _ := b.Count(validators); rounds["v0"] != 0 {
+		t.Errorf("rounds must not be counted in grace: %v", rounds)
+		return
+	}
+
+	// the ballot in the grace is counted
+	b.Observe("round1", "v1")
+	b.closed["round0"] = time.Now().Add(-PeerScoreBallotGrace)
+	b.closed["round1"] = time.Now().Add(-PeerScoreBallotGrace)
+	b.Observe("round2", "v0")
+	b.Close("round2")
+	b.closed["round2"] = time.Now().Add(-PeerScoreBallotGrace)
+
+	rounds, missed := b.Count(validators)
+	if rounds["v0"] != 3 || missed["v0"] != 0 {
+		t.Errorf("wrong rounds of v0: %d rounds, %d missed", rounds["v0"], missed["v0"])
+		return
+	}
+	if rounds["v1"] != 3 || missed["v1"] != 1 {
+		t.Errorf("wrong rounds of v1: %d rounds, %d missed", rounds["v1"], missed["v1"])
+		return
+	}
+
+	// the late ballot of the counted round is ignored
+	b.Observe("round2", "v1")
+	b.Close("round2")
+	if rounds, missed = b.Count(validators); rounds["v1"] != 3 || missed["v1"] != 1 {
+		t.Errorf("late ballot must be ignored: %d rounds, %d missed", rounds["v1"], missed["v1"])
+		return
+	}
+}
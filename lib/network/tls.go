@@ -0,0 +1,155 @@
+package sebaknetwork
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"time"
+)
+
+// The node serves the network by TLS of `HTTP2NetworkConfig`,
+//  * `TLSCertFile`, `TLSKeyFile`: the certificate of node; it is also the
+//  client certificate to the other validators
+//  * `TLSCAFile`: the CA of the certificates of validators; without it, the
+//  certificates are not verified, like the self-signed certificates
+//  * `TLSClientAuth`: the messages between the validators need the client
+//  certificate, which is verified by `TLSCAFile`
+// The clients of API and '/message' do not need the client certificate.
+
+// SelfSignedCertificateValidity is how long the generated self-signed
+// certificate is valid.
+const SelfSignedCertificateValidity time.Duration = 365 * 24 * time.Hour
+
+// LoadTLSCertPool loads the PEM certificates of the file.
+func LoadTLSCertPool(file string) (pool *x509.CertPool, err error) {
+	var b []byte
+	if b, err = ioutil.ReadFile(file); err != nil {
+		return
+	}
+
+	pool = x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(b) {
+		err = fmt.Errorf("no certificate in '%s'", file)
+		return
+	}
+
+	return
+}
+
+// GenerateSelfSignedCertificate writes the new self-signed certificate and
+// it's key of the hosts, which are the host names or the IP addresses. The
+// certificate is also the CA of itself, so it can be `TLSCAFile` of the test
+// network, whose nodes share the certificate.
+func GenerateSelfSignedCertificate(certFile, keyFile string, hosts ...string) (err error) {
+	var key *ecdsa.PrivateKey
+	if key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader); err != nil {
+		return
+	}
+
+	var serial *big.Int
+	if serial, err = rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128)); err != nil {
+		return
+	}
+
+	now := time.Now()
+	template := x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{Organization: []string{"sebak"}},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(SelfSignedCertificateValidity),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+
+	var der []byte
+	if der, err = x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key); err != nil {
+		return
+	}
+	var keyDER []byte
+	if keyDER, err = x509.MarshalECPrivateKey(key); err != nil {
+		return
+	}
+
+	if err = ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644); err != nil {
+		return
+	}
+	err = ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600)
+
+	return
+}
+
+// serverTLSConfig sets the certificate of node and the verification of the
+// client certificates to the TLS config of server.
+func (config HTTP2NetworkConfig) serverTLSConfig(tlsConfig *tls.Config) (err error) {
+	var certificate tls.Certificate
+	if certificate, err = tls.LoadX509KeyPair(config.TLSCertFile, config.TLSKeyFile); err != nil {
+		return
+	}
+	tlsConfig.Certificates = []tls.Certificate{certificate}
+
+	if !config.TLSClientAuth {
+		return
+	}
+	if tlsConfig.ClientCAs, err = LoadTLSCertPool(config.TLSCAFile); err != nil {
+		return
+	}
+	// the clients of API do not have the certificate, so it is checked by
+	// `requireClientCertificate()` for the messages between the validators
+	tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+
+	return
+}
+
+// clientTLSConfig is the TLS config of the clients to the other validators.
+func (config HTTP2NetworkConfig) clientTLSConfig() (tlsConfig *tls.Config, err error) {
+	tlsConfig = &tls.Config{}
+	if len(config.TLSCAFile) < 1 {
+		tlsConfig.InsecureSkipVerify = true
+	} else if tlsConfig.RootCAs, err = LoadTLSCertPool(config.TLSCAFile); err != nil {
+		return
+	}
+
+	if len(config.TLSCertFile) > 0 {
+		var certificate tls.Certificate
+		if certificate, err = tls.LoadX509KeyPair(config.TLSCertFile, config.TLSKeyFile); err != nil {
+			return
+		}
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+
+	return
+}
+
+var errClientCertificateRequired = errors.New("client certificate of validator is required")
+
+// requireClientCertificate checks the request has the verified client
+// certificate, when `TLSClientAuth` is set.
+func (t *HTTP2Network) requireClientCertificate(r *http.Request) error {
+	if !t.config.TLSClientAuth {
+		return nil
+	}
+	if r.TLS == nil || len(r.TLS.VerifiedChains) < 1 {
+		return errClientCertificateRequired
+	}
+
+	return nil
+}
@@ -0,0 +1,92 @@
+package sebaknetwork
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGenerateSelfSignedCertificate(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "sebak-tls")
+	defer os.RemoveAll(dir)
+
+	certFile, keyFile := filepath.Join(dir, "sebak.crt"), filepath.Join(dir, "sebak.key")
+	if err := GenerateSelfSignedCertificate(certFile, keyFile, "localhost", "127.0.0.1"); err != nil {
+		t.Error(err)
+		return
+	}
+
+	pool, err := LoadTLSCertPool(certFile)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	certificate, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	leaf, _ := x509.ParseCertificate(certificate.Certificate[0])
+
+	for _, host := range []string{"localhost", "127.0.0.1"} {
+		options := x509.VerifyOptions{
+			DNSName:   host,
+			Roots:     pool,
+			KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		}
+		if _, err = leaf.Verify(options); err != nil {
+			t.Errorf("certificate of '%s' must be verified: %v", host, err)
+			return
+		}
+	}
+
+	if _, err = LoadTLSCertPool(keyFile); err == nil {
+		t.Error("key file must not be loaded as certificate")
+		return
+	}
+}
+
+func TestHTTP2NetworkTLSClientAuth(t *testing.T) {
+	h2n := &HTTP2Network{
+		config:         HTTP2NetworkConfig{TLSClientAuth: true},
+		receiveChannel: make(chan Message, 1),
+	}
+	handler := BallotHandler(context.Background(), h2n)
+
+	request := func(state *tls.ConnectionState) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("POST", "/ballot", strings.NewReader("{}"))
+		r.Header.Set("Content-Type", "application/json")
+		r.TLS = state
+		w := httptest.NewRecorder()
+		handler(w, r)
+		return w
+	}
+
+	if w := request(nil); w.Code != http.StatusUnauthorized {
+		t.Errorf("ballot without tls must be refused: %d", w.Code)
+		return
+	}
+	if w := request(&tls.ConnectionState{}); w.Code != http.StatusUnauthorized {
+		t.Errorf("ballot without client certificate must be refused: %d", w.Code)
+		return
+	}
+	if len(h2n.receiveChannel) != 0 {
+		t.Error("refused ballot must not be received")
+		return
+	}
+
+	verified := &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{{}}}}
+	request(verified)
+	if message := <-h2n.receiveChannel; message.Type != BallotMessage {
+		t.Errorf("ballot with client certificate must be received: %v", message)
+		return
+	}
+}
@@ -0,0 +1,250 @@
+package sebak
+
+import (
+	"fmt"
+	"time"
+
+	"boscoin.io/sebak/lib/common"
+	"boscoin.io/sebak/lib/error"
+	"boscoin.io/sebak/lib/storage"
+)
+
+// NetworkParameters is the parameters of the network, which are decided at
+// genesis and must be same in every node of the network. It is stored by,
+//  * 'np-network-parameters': `NetworkParameters`
+
+const NetworkParametersKey string = "np-network-parameters"
+
+const (
+	// DefaultBlockTime is used when the network parameters are not stored.
+	DefaultBlockTime time.Duration = time.Millisecond * 100
+	MinBlockTime     time.Duration = time.Millisecond * 10
+)
+
+// The default percentages of validators to pass each ballot state.
+const (
+	DefaultThresholdINIT   int = 100
+	DefaultThresholdSIGN   int = 30
+	DefaultThresholdACCEPT int = 30
+)
+
+// The defaults of the protocol upgrades; see `Upgrade`.
+const (
+	DefaultUpgradeThreshold int    = 80
+	DefaultUpgradeWindow    uint64 = 1000
+	DefaultUpgradeDelay     uint64 = 1000
+)
+
+type NetworkParameters struct {
+	// BlockTime is the interval to start new ballots for the transactions in
+	// `TransactionPool`.
+	BlockTime time.Duration `json:"block_time"`
+
+	// The percentages of validators of `VotingThresholdPolicy`; 0 means the
+	// default.
+	ThresholdINIT   int `json:"threshold_init"`
+	ThresholdSIGN   int `json:"threshold_sign"`
+	ThresholdACCEPT int `json:"threshold_accept"`
+
+	// UpgradeThreshold is the percentage of the blocks in the window of
+	// `UpgradeWindow` blocks, which must be signaled for the feature to lock
+	// it in, and it activates `UpgradeDelay` blocks after it. 0 means the
+	// default.
+	UpgradeThreshold int    `json:"upgrade_threshold,omitempty"`
+	UpgradeWindow    uint64 `json:"upgrade_window,omitempty"`
+	UpgradeDelay     uint64 `json:"upgrade_delay,omitempty"`
+
+	// MinimumBalance is the minimum initial balance of the account, which is
+	// created by `OperationCreateAccount`; 0 is no minimum.
+	MinimumBalance Amount `json:"minimum_balance,omitempty"`
+
+	// FeePerOperation, FeePerByte and FeePerDataEntry make the minimum fee of
+	// transaction; see `MinimumFee()`. 0 of `FeePerOperation` means `BaseFee`
+	// and the others are no fee by 0.
+	FeePerOperation Amount `json:"fee_per_operation,omitempty"`
+	FeePerByte      Amount `json:"fee_per_byte,omitempty"`
+	FeePerDataEntry Amount `json:"fee_per_data_entry,omitempty"`
+
+	// RewardAmount is distributed to RewardAccount at every RewardInterval
+	// blocks; it is minted, or moved from RewardSource, if it is given. 0 of
+	// `RewardInterval` is no reward; see `DistributeReward()`.
+	RewardAccount  string `json:"reward_account,omitempty"`
+	RewardAmount   Amount `json:"reward_amount,omitempty"`
+	RewardInterval uint64 `json:"reward_interval,omitempty"`
+	RewardSource   string `json:"reward_source,omitempty"`
+
+	// MaxOperationsPerTransaction is the maximum number of operations in one
+	// transaction and MaxTransactionsPerBlock is the maximum number of
+	// transactions in one block; 0 is no limit. The block of ISAAC has the
+	// one transaction of the ballot, so `MaxTransactionsPerBlock` bounds the
+	// transactions, which are proposed at each block time.
+	MaxOperationsPerTransaction uint64 `json:"max_operations_per_transaction,omitempty"`
+	MaxTransactionsPerBlock     uint64 `json:"max_transactions_per_block,omitempty"`
+}
+
+func NewDefaultNetworkParameters() NetworkParameters {
+	return NetworkParameters{
+		BlockTime:       DefaultBlockTime,
+		ThresholdINIT:   DefaultThresholdINIT,
+		ThresholdSIGN:   DefaultThresholdSIGN,
+		ThresholdACCEPT: DefaultThresholdACCEPT,
+
+		UpgradeThreshold: DefaultUpgradeThreshold,
+		UpgradeWindow:    DefaultUpgradeWindow,
+		UpgradeDelay:     DefaultUpgradeDelay,
+	}
+}
+
+func (p NetworkParameters) IsWellFormed() (err error) {
+	if p.BlockTime < MinBlockTime {
+		err = fmt.Errorf("`BlockTime` must be greater than or equal to %s", MinBlockTime)
+		return
+	}
+
+	for _, t := range []int{p.ThresholdINIT, p.ThresholdSIGN, p.ThresholdACCEPT} {
+		if t < 0 || t > 100 {
+			err = fmt.Errorf("thresholds must be between 0 and 100; 0 is the default")
+			return
+		}
+	}
+	if p.UpgradeThreshold < 0 || p.UpgradeThreshold > 100 {
+		err = fmt.Errorf("`UpgradeThreshold` must be between 0 and 100; 0 is the default")
+		return
+	}
+	if p.FeePerOperation != 0 && p.FeePerOperation < BaseFee {
+		err = fmt.Errorf("`FeePerOperation` must not be lower than %d; 0 is the default", BaseFee)
+		return
+	}
+	if err = p.isRewardWellFormed(); err != nil {
+		return
+	}
+
+	return
+}
+
+// UpgradePolicy returns the threshold, the window and the delay of the
+// protocol upgrades with the defaults.
+func (p NetworkParameters) UpgradePolicy() (threshold int, window, delay uint64) {
+	threshold, window, delay = p.UpgradeThreshold, p.UpgradeWindow, p.UpgradeDelay
+	if threshold == 0 {
+		threshold = DefaultUpgradeThreshold
+	}
+	if window == 0 {
+		window = DefaultUpgradeWindow
+	}
+	if delay == 0 {
+		delay = DefaultUpgradeDelay
+	}
+
+	return
+}
+
+// MinimumFee returns the minimum fee of transaction; the fee of each
+// operation with the fee of each byte of the serialized transaction, and the
+// fee of each data entry, which is set by `OperationManageData`.
+func (p NetworkParameters) MinimumFee(tx Transaction) (fee Amount, err error) {
+	perOperation := p.FeePerOperation
+	if perOperation == 0 {
+		perOperation = BaseFee
+	}
+	fee = perOperation * Amount(len(tx.B.Operations))
+
+	if p.FeePerByte > 0 {
+		var encoded []byte
+		if encoded, err = tx.Serialize(); err != nil {
+			return
+		}
+		if fee, err = fee.Add(p.FeePerByte * Amount(len(encoded))); err != nil {
+			return
+		}
+	}
+
+	if p.FeePerDataEntry > 0 {
+		for _, op := range tx.B.Operations {
+			if body, ok := op.B.(OperationBodyManageData); !ok || len(body.Value) < 1 {
+				continue
+			}
+			if fee, err = fee.Add(p.FeePerDataEntry); err != nil {
+				return
+			}
+		}
+	}
+
+	return
+}
+
+// CheckTransactionFee checks the fee of transaction, which is charged for
+// each operation, is not lower than `MinimumFee()`.
+func CheckTransactionFee(p NetworkParameters, tx Transaction) (err error) {
+	var minimum Amount
+	if minimum, err = p.MinimumFee(tx); err != nil {
+		return
+	}
+	if tx.TotalFee() < minimum {
+		err = sebakerror.ErrorInvalidFee
+		return
+	}
+
+	return
+}
+
+// CheckTransactionOperationsLimit checks the number of operations in
+// transaction is not over `MaxOperationsPerTransaction`.
+func CheckTransactionOperationsLimit(p NetworkParameters, tx Transaction) (err error) {
+	if p.MaxOperationsPerTransaction > 0 && uint64(len(tx.B.Operations)) > p.MaxOperationsPerTransaction {
+		err = sebakerror.ErrorTransactionTooManyOperations
+		return
+	}
+
+	return
+}
+
+// VotingThresholdPolicy makes the policy by the thresholds.
+func (p NetworkParameters) VotingThresholdPolicy() (*ISAACVotingThresholdPolicy, error) {
+	init, sign, accept := p.ThresholdINIT, p.ThresholdSIGN, p.ThresholdACCEPT
+	if init == 0 {
+		init = DefaultThresholdINIT
+	}
+	if sign == 0 {
+		sign = DefaultThresholdSIGN
+	}
+	if accept == 0 {
+		accept = DefaultThresholdACCEPT
+	}
+
+	return NewDefaultVotingThresholdPolicy(init, sign, accept)
+}
+
+func (p NetworkParameters) Serialize() (encoded []byte, err error) {
+	encoded, err = sebakcommon.EncodeJSONValue(p)
+	return
+}
+
+// Save stores the network parameters; they can not be changed once saved.
+func (p NetworkParameters) Save(st *sebakstorage.LevelDBBackend) (err error) {
+	if err = p.IsWellFormed(); err != nil {
+		return
+	}
+
+	err = st.New(NetworkParametersKey, p)
+	return
+}
+
+// GetNetworkParameters loads the network parameters; if they are not stored,
+// the default parameters are returned.
+func GetNetworkParameters(st *sebakstorage.LevelDBBackend) (p NetworkParameters, err error) {
+	var exists bool
+	if exists, err = st.Has(NetworkParametersKey); err != nil {
+		return
+	} else if !exists {
+		p = NewDefaultNetworkParameters()
+		return
+	}
+
+	if err = st.Get(NetworkParametersKey, &p); err != nil {
+		return
+	}
+	err = p.IsWellFormed()
+
+	return
+}
@@ -0,0 +1,204 @@
+package sebak
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stellar/go/keypair"
+
+	"boscoin.io/sebak/lib/common"
+	"boscoin.io/sebak/lib/error"
+	"boscoin.io/sebak/lib/storage"
+)
+
+func TestNetworkParameters(t *testing.T) {
+	st, _ := sebakstorage.NewTestMemoryLevelDBBackend()
+
+	// not stored yet
+	p, err := GetNetworkParameters(st)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if p.BlockTime != DefaultBlockTime {
+		t.Error("default `BlockTime` must be returned")
+		return
+	}
+
+	if err = (NetworkParameters{BlockTime: time.Millisecond}).Save(st); err == nil {
+		t.Error("too short `BlockTime` must be refused")
+		return
+	}
+
+	if err = (NetworkParameters{BlockTime: time.Second, ThresholdSIGN: 101}).Save(st); err == nil {
+		t.Error("threshold over 100 must be refused")
+		return
+	}
+
+	if err = (NetworkParameters{BlockTime: time.Second}).Save(st); err != nil {
+		t.Error(err)
+		return
+	}
+	if p, err = GetNetworkParameters(st); err != nil {
+		t.Error(err)
+		return
+	}
+	if p.BlockTime != time.Second {
+		t.Errorf("wrong `BlockTime`: %s", p.BlockTime)
+		return
+	}
+
+	// not given thresholds are the default
+	policy, err := p.VotingThresholdPolicy()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	policy.SetValidators(100)
+	if policy.Threshold(sebakcommon.BallotStateSIGN) != DefaultThresholdSIGN {
+		t.Error("default threshold must be used")
+		return
+	}
+
+	// can not be changed once saved
+	if err = (NetworkParameters{BlockTime: time.Minute}).Save(st); err == nil {
+		t.Error("network parameters must not be changed")
+		return
+	}
+}
+
+func TestCreateAccountMinimumBalance(t *testing.T) {
+	st, _ := sebakstorage.NewTestMemoryLevelDBBackend()
+
+	kp, _ := keypair.Random()
+	source := NewBlockAccount(kp.Address(), BaseFee*100, uuid.New().String())
+	source.Save(st)
+
+	p := NetworkParameters{BlockTime: time.Second, MinimumBalance: BaseFee * 10}
+	p.Save(st)
+
+	makeTransaction := func(amount Amount) Transaction {
+		target, _ := keypair.Random()
+		op, _ := NewOperation(OperationCreateAccount, NewOperationBodyCreateAccount(target.Address(), amount))
+		tx, _ := NewTransaction(kp.Address(), source.Checkpoint, op)
+		tx.Sign(kp, networkID)
+		return tx
+	}
+
+	underfunded := makeTransaction(BaseFee)
+	if err := CheckCreateAccountMinimumBalance(p, underfunded); err != sebakerror.ErrorCreateAccountUnderfunded {
+		t.Errorf("create-account under the minimum balance must be refused: %v", err)
+		return
+	}
+	if err := FinishOperationCreateAccount(st, underfunded, underfunded.B.Operations[0]); err != sebakerror.ErrorCreateAccountUnderfunded {
+		t.Errorf("create-account under the minimum balance must not be applied: %v", err)
+		return
+	}
+
+	funded := makeTransaction(BaseFee * 10)
+	if err := CheckCreateAccountMinimumBalance(p, funded); err != nil {
+		t.Error(err)
+		return
+	}
+	if err := FinishOperationCreateAccount(st, funded, funded.B.Operations[0]); err != nil {
+		t.Error(err)
+		return
+	}
+
+	// the other operations are not checked
+	payment := TestMakeTransactionWithKeypair(networkID, 1, kp)
+	if err := CheckCreateAccountMinimumBalance(p, payment); err != nil {
+		t.Error(err)
+		return
+	}
+}
+
+func TestNetworkParametersMinimumFee(t *testing.T) {
+	kp, _ := keypair.Random()
+	tx := TestMakeTransactionWithKeypair(networkID, 2, kp)
+
+	// by default, the base fee of each operation
+	p := NewDefaultNetworkParameters()
+	if minimum, _ := p.MinimumFee(tx); minimum != BaseFee*2 {
+		t.Errorf("wrong minimum fee: %v", minimum)
+		return
+	}
+	if err := CheckTransactionFee(p, tx); err != nil {
+		t.Error(err)
+		return
+	}
+
+	p.FeePerOperation = BaseFee - 1
+	if err := p.IsWellFormed(); err == nil {
+		t.Error("fee per operation under the base fee must be refused")
+		return
+	}
+
+	p.FeePerOperation = BaseFee * 2
+	p.FeePerByte = Amount(10)
+	encoded, _ := tx.Serialize()
+	if minimum, _ := p.MinimumFee(tx); minimum != BaseFee*4+Amount(10*len(encoded)) {
+		t.Errorf("wrong minimum fee: %v", minimum)
+		return
+	}
+	if err := CheckTransactionFee(p, tx); err != sebakerror.ErrorInvalidFee {
+		t.Errorf("fee under the minimum fee must be refused: %v", err)
+		return
+	}
+
+	tx.B.Fee = BaseFee * 3
+	tx.Sign(kp, networkID)
+	if err := CheckTransactionFee(p, tx); err != nil {
+		t.Error(err)
+		return
+	}
+}
+
+func TestNetworkParametersFeePerDataEntry(t *testing.T) {
+	kp, _ := keypair.Random()
+	set, _ := NewOperation(OperationManageData, NewOperationBodyManageData("name", []byte("value")))
+	remove, _ := NewOperation(OperationManageData, NewOperationBodyManageData("other", nil))
+	tx, _ := NewTransaction(kp.Address(), uuid.New().String(), set, remove)
+
+	p := NewDefaultNetworkParameters()
+	p.FeePerDataEntry = BaseFee * 10
+
+	// only the entry, which is set is charged
+	if minimum, _ := p.MinimumFee(tx); minimum != BaseFee*2+BaseFee*10 {
+		t.Errorf("wrong minimum fee: %v", minimum)
+		return
+	}
+	if err := CheckTransactionFee(p, tx); err != sebakerror.ErrorInvalidFee {
+		t.Errorf("fee without the fee of data entry must be refused: %v", err)
+		return
+	}
+}
+
+func TestNetworkParametersOperationsLimit(t *testing.T) {
+	kp, _ := keypair.Random()
+	tx := TestMakeTransactionWithKeypair(networkID, 3, kp)
+
+	// by default, no limit
+	p := NewDefaultNetworkParameters()
+	if err := CheckTransactionOperationsLimit(p, tx); err != nil {
+		t.Error(err)
+		return
+	}
+
+	p.MaxOperationsPerTransaction = 3
+	if err := CheckTransactionOperationsLimit(p, tx); err != nil {
+		t.Error(err)
+		return
+	}
+
+	p.MaxOperationsPerTransaction = 2
+	if err := CheckTransactionOperationsLimit(p, tx); err != sebakerror.ErrorTransactionTooManyOperations {
+		t.Errorf("transaction over the maximum operations must be refused: %v", err)
+		return
+	}
+	if result := sebakerror.ResultOf(sebakerror.ErrorTransactionTooManyOperations); result != sebakerror.ResultTransactionMalformed {
+		t.Errorf("wrong result: %v", result)
+		return
+	}
+}
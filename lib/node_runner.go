@@ -2,10 +2,17 @@ package sebak
 
 import (
 	"context"
+	"errors"
+	"net/url"
+	"runtime"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	logging "github.com/inconshreveable/log15"
 	"boscoin.io/sebak/lib/common"
+	"boscoin.io/sebak/lib/error"
+	"boscoin.io/sebak/lib/graphql"
 	"boscoin.io/sebak/lib/network"
 	"boscoin.io/sebak/lib/storage"
 )
@@ -18,12 +25,72 @@ type NodeRunner struct {
 	consensus         Consensus
 	connectionManager *sebaknetwork.ConnectionManager
 	storage           *sebakstorage.LevelDBBackend
+	transactionPool   *TransactionPool
+	ballotVerifier    *BallotSignatureVerifier
+
+	futureTransactions *FutureTransactionQueue
+
+	transactionStatuses *TransactionStatusTracker
+	forwardingReceipts  bool
+
+	transactionOrderingPolicy TransactionOrderingPolicy
+	networkParameters         NetworkParameters
+
+	revalidationPolicy      RevalidationPolicy
+	revalidations           uint64 // the number of transactions validated again at commit
+	revalidationDivergences uint64 // the number of accepted transactions failed at commit
 
 	handleMessageFromClientCheckerFuncs []sebakcommon.CheckerFunc
 	handleBallotCheckerFuncs            []sebakcommon.CheckerFunc
+	proposeTransactionCheckerFuncs      []sebakcommon.CheckerFunc
 
 	handleMessageFromClientCheckerDeferFunc sebakcommon.CheckerDeferFunc
 	handleBallotCheckerDeferFunc            sebakcommon.CheckerDeferFunc
+	proposeTransactionCheckerDeferFunc      sebakcommon.CheckerDeferFunc
+
+	startupQuorumTimeout time.Duration
+	startupQuorumErr     error // set by the goroutines of startup; see `setStartupQuorumErr()`
+	startupQuorumErrLock sync.Mutex
+	quorumReady          int32
+
+	proposerTimeout time.Duration
+	adaptiveTimeout *AdaptiveTimeout // nil if the proposer timeout is static
+	viewChange      *ViewChangeState
+
+	state        *NodeStateMachine
+	forks        uint64 // the number of detected fork evidences
+	selfTestMode SelfTestMode
+
+	clockSkewExceeded bool
+	partitioned       bool
+
+	graphQLSchema *sebakgraphql.Schema // nil if GraphQL is disabled
+	faucet        *Faucet              // nil if faucet is disabled
+	rateLimiter   *RateLimiter         // nil if the API is not limited
+	apiConfig     APIConfig
+	apiCache      *APIResponseCache // nil if the responses are not cached
+	adminConfig   AdminConfig
+	grpcConfig    GRPCConfig
+	stream        *EventStream
+
+	readinessConfig ReadinessConfig
+	networkHeight   uint64 // the highest height announced by the validators
+	readinessProbes uint64
+
+	loadShedder *LoadShedder // nil if the load shedding is disabled
+
+	addressBook       *AddressBook
+	peerExchangeSeeds []*sebakcommon.Endpoint
+	stalePeers        []PeerAddress // the stored peers, which are asked until the network is found
+
+	messageSigner     MessageSigner
+	transactionGossip *TransactionGossip
+	blockSync         *BlockSync
+	messageQueue      *sebaknetwork.MessageQueue
+	peerBans          *sebaknetwork.PeerBanList
+
+	stop     chan struct{} // closed by `Stop()`, so the background loops exit
+	stopOnce sync.Once
 
 	ctx context.Context
 	log logging.Logger
@@ -44,10 +111,60 @@ func NewNodeRunner(
 		network:     network,
 		consensus:   consensus,
 		storage:     storage,
-		log:         log.New(logging.Ctx{"node": currentNode.Alias()}),
+
+		transactionPool:           NewTransactionPool(),
+		futureTransactions:        NewFutureTransactionQueue(),
+		transactionStatuses:       NewTransactionStatusTracker(MaxTransactionStatuses),
+		ballotVerifier:            NewBallotSignatureVerifier([]byte(networkID), runtime.NumCPU()),
+		transactionOrderingPolicy: DefaultTransactionOrderingPolicy,
+		revalidationPolicy:        DefaultRevalidationPolicy,
+		networkParameters:         NewDefaultNetworkParameters(),
+		viewChange:                NewViewChangeState(),
+		state:                     NewNodeStateMachine(),
+		selfTestMode:              SelfTestModeOff,
+		stream:                    NewEventStream(DefaultMaxStreamSubscribers),
+		readinessConfig:           NewDefaultReadinessConfig(),
+		addressBook:               NewAddressBook(currentNode.Address()),
+		messageSigner:             NewKeypairNodeSigner(currentNode, []byte(networkID)),
+		transactionGossip:         NewTransactionGossip(DefaultGossipFanout),
+		blockSync:                 NewBlockSync(DefaultBlockSyncConfig),
+		messageQueue:              sebaknetwork.NewMessageQueue(sebaknetwork.DefaultMessageQueueConfig),
+		peerBans:                  sebaknetwork.NewPeerBanList(sebaknetwork.DefaultPeerBanConfig),
+		stop:                      make(chan struct{}),
+
+		log: log.New(logging.Ctx{"node": currentNode.Alias()}),
 	}
+	if storage.NegativeCache() == nil {
+		storage.SetNegativeCache(
+			sebakstorage.NewNegativeCache(sebakstorage.DefaultNegativeCacheSize, NegativeCachePrefixes...),
+		)
+	}
+
+	nr.transactionPool.SetEvictedHook(func(hash string) {
+		nr.transactionStatuses.Rejected(hash, sebakerror.ErrorTransactionEvicted)
+	})
+	nr.messageQueue.SetDroppedHook(func(message sebaknetwork.Message) {
+		if message.Type != sebaknetwork.MessageFromClient {
+			return
+		}
+		if tx, err := NewTransactionFromJSON(message.Data); err == nil {
+			nr.transactionStatuses.Rejected(tx.GetHash(), sebakerror.ErrorMessageQueueFull)
+		}
+	})
+
+	nr.state.AddHook(func(from, to NodeState) {
+		nr.log.Info("node state changed", "from", from, "to", to)
+	})
+
 	nr.ctx = context.WithValue(context.Background(), "currentNode", currentNode)
 	nr.ctx = context.WithValue(nr.ctx, "networkID", nr.networkID)
+	nr.ctx = context.WithValue(nr.ctx, "peerExchange", sebaknetwork.PeerExchangeFunc(nr.serializePeerExchange))
+	nr.ctx = context.WithValue(nr.ctx, "messageSigner", sebaknetwork.MessageSignFunc(nr.signMessage))
+	nr.ctx = context.WithValue(nr.ctx, "messageVerifier", sebaknetwork.MessageVerifyFunc(nr.verifyMessage))
+	nr.ctx = context.WithValue(nr.ctx, "transactionFetch", sebaknetwork.TransactionFetchFunc(nr.serveTransactions))
+	nr.ctx = context.WithValue(nr.ctx, "blockFetch", sebaknetwork.BlockFetchFunc(nr.serveBlocks))
+	nr.ctx = context.WithValue(nr.ctx, "peerBans", nr.peerBans)
+	nr.ctx = context.WithValue(nr.ctx, "handshake", sebaknetwork.HandshakeFunc(nr.handshake))
 
 	nr.connectionManager = sebaknetwork.NewConnectionManager(
 		nr.currentNode,
@@ -59,22 +176,101 @@ func NewNodeRunner(
 
 	nr.SetHandleMessageFromClientCheckerFuncs(nil, DefaultHandleMessageFromClientCheckerFuncs...)
 	nr.SetHandleBallotCheckerFuncs(nil, DefaultHandleBallotCheckerFuncs...)
+	nr.SetProposeTransactionCheckerFuncs(nil, DefaultProposeTransactionCheckerFuncs...)
 
 	return nr
 }
 
 func (nr *NodeRunner) Ready() {
 	nr.network.SetContext(nr.ctx)
+	nr.addAPIHandlers()
+	nr.trustValidators()
 	nr.network.Ready()
 }
 
+// trustValidators exempts the validators from the admission control of the
+// inbound connections.
+func (nr *NodeRunner) trustValidators() {
+	h2n, ok := nr.network.(*sebaknetwork.HTTP2Network)
+	if !ok {
+		return
+	}
+
+	var hosts []string
+	for _, v := range nr.connectionManager.Validators() {
+		if v.Endpoint() == nil {
+			continue
+		}
+		hosts = append(hosts, (*url.URL)(v.Endpoint()).Hostname())
+	}
+	h2n.Admission().Trust(hosts...)
+}
+
 func (nr *NodeRunner) Start() (err error) {
+	if QualificationBuild {
+		nr.log.Warn("qualification build; the node halts on the invariant violation")
+	}
+
+	// the node, which fails the self test must not join the consensus
+	var results []SelfTestResult
+	if results, err = RunSelfTest(nr.storage, nr.networkID, nr.selfTestMode); err != nil {
+		for _, result := range results {
+			if !result.IsPassed() {
+				nr.log.Error("self test failed", "check", result.Name, "error", result.Error)
+			}
+		}
+		nr.state.Transit(NodeStateHalted)
+		return
+	}
+
+	// the transactions kept in pool before restart are loaded
+	if err = nr.transactionPool.SetStorage(nr.storage); err != nil {
+		nr.state.Transit(NodeStateHalted)
+		return
+	}
+	nr.transactionStatuses.SetStorage(nr.storage)
+
+	// the node, whose account state is different from the latest block, must
+	// not join the consensus
+	if err = VerifyStateHash(nr.storage); err != nil {
+		nr.log.Error("failed to verify the state hash of the latest block", "error", err)
+		nr.state.Transit(NodeStateHalted)
+		return
+	}
+
+	// the peers kept in storage before restart are loaded
+	if nr.stalePeers, err = nr.addressBook.SetStorage(nr.storage); err != nil {
+		nr.state.Transit(NodeStateHalted)
+		return
+	}
+
+	// the peers banned before restart are still banned
+	if err = nr.loadPeerBans(); err != nil {
+		nr.state.Transit(NodeStateHalted)
+		return
+	}
+
 	nr.Ready()
 
 	go nr.handleMessage()
 	go nr.ConnectValidators()
+	go nr.startPeerExchange()
+	nr.startAdminServer()
+	nr.startGRPCServer()
+
+	if nr.startupQuorumTimeout > 0 {
+		nr.state.Transit(NodeStateSyncing)
+		go nr.waitStartupQuorum()
+	} else {
+		atomic.StoreInt32(&nr.quorumReady, 1)
+		nr.state.Transit(NodeStateConsensus)
+	}
 
 	if err = nr.network.Start(); err != nil {
+		if e := nr.getStartupQuorumErr(); e != nil {
+			err = e
+		}
+		nr.state.Transit(NodeStateHalted)
 		return
 	}
 
@@ -82,7 +278,122 @@ func (nr *NodeRunner) Start() (err error) {
 }
 
 func (nr *NodeRunner) Stop() {
+	nr.state.Transit(NodeStateDraining)
+	nr.stopOnce.Do(func() { close(nr.stop) })
 	nr.network.Stop()
+	nr.state.Transit(NodeStateHalted)
+}
+
+// SetSelfTestMode sets the self test, which runs when the node starts.
+func (nr *NodeRunner) SetSelfTestMode(mode SelfTestMode) {
+	nr.selfTestMode = mode
+}
+
+// State returns the lifecycle state machine of node.
+func (nr *NodeRunner) State() *NodeStateMachine {
+	return nr.state
+}
+
+// updateQuorumState moves the node to `degraded` when it lost the quorum of
+// validators, and back to `consensus` when the quorum is restored.
+func (nr *NodeRunner) updateQuorumState() {
+	if !nr.IsQuorumReady() {
+		return
+	}
+
+	if nr.HasQuorum() {
+		nr.state.TransitIf(NodeStateDegraded, NodeStateConsensus)
+	} else {
+		nr.state.TransitIf(NodeStateConsensus, NodeStateDegraded)
+	}
+}
+
+// ClockSkew returns the spread of the clocks of the current node and the
+// connected validators.
+func (nr *NodeRunner) ClockSkew() time.Duration {
+	clocks := nr.connectionManager.PeerClocks()
+
+	var offsets []time.Duration
+	for _, v := range nr.connectionManager.AllConnected() {
+		if clock, ok := clocks[v.Address()]; ok {
+			offsets = append(offsets, clock.Offset)
+		}
+	}
+
+	return sebaknetwork.ClockSpread(offsets...)
+}
+
+// ClockSkewBudget is the clock skew, which the validators can tolerate; the
+// proposer timeout, or the block time if the view change is disabled. Over
+// it, the validators do not agree on the timeouts and the rounds churn.
+func (nr *NodeRunner) ClockSkewBudget() time.Duration {
+	if nr.proposerTimeout > 0 {
+		return nr.proposerTimeout
+	}
+
+	return nr.networkParameters.BlockTime
+}
+
+// checkClockSkew warns once when the clock skew of validators exceeds
+// `ClockSkewBudget()`, and again when it is recovered.
+func (nr *NodeRunner) checkClockSkew() {
+	skew, budget := nr.ClockSkew(), nr.ClockSkewBudget()
+
+	exceeded := skew > budget
+	if exceeded == nr.clockSkewExceeded {
+		return
+	}
+	nr.clockSkewExceeded = exceeded
+
+	if exceeded {
+		nr.log.Warn("clock skew of validators exceeds the consensus timeout; check the clocks", "skew", skew, "budget", budget)
+	} else {
+		nr.log.Info("clock skew of validators is recovered", "skew", skew, "budget", budget)
+	}
+}
+
+// IsPartitioned checks the node can reach the validators, which satisfy the
+// voting threshold including the current node; the validators, which are
+// unreachable by `sebaknetwork.PeerScore` are not counted, even if they are
+// connected.
+func (nr *NodeRunner) IsPartitioned() bool {
+	return nr.isPartitioned(nr.connectionManager.PeerScores())
+}
+
+func (nr *NodeRunner) isPartitioned(scores map[string]sebaknetwork.PeerScore) bool {
+	reachable := 1 // including 'self'
+	for _, score := range scores {
+		if score.Status != sebaknetwork.PeerUnreachable {
+			reachable++
+		}
+	}
+
+	return reachable < nr.requiredQuorum()
+}
+
+// checkPartition warns once when the node is partitioned from the quorum of
+// validators, and again when it is recovered.
+func (nr *NodeRunner) checkPartition() {
+	scores := nr.connectionManager.PeerScores()
+
+	partitioned := nr.isPartitioned(scores)
+	if partitioned == nr.partitioned {
+		return
+	}
+	nr.partitioned = partitioned
+
+	var unreachable []string
+	for address, score := range scores {
+		if score.Status == sebaknetwork.PeerUnreachable {
+			unreachable = append(unreachable, address)
+		}
+	}
+
+	if partitioned {
+		nr.log.Warn("node is partitioned from the quorum of validators", "unreachable", unreachable, "required", nr.requiredQuorum())
+	} else {
+		nr.log.Info("partition is recovered", "unreachable", unreachable)
+	}
 }
 
 func (nr *NodeRunner) Node() sebakcommon.Node {
@@ -105,10 +416,96 @@ func (nr *NodeRunner) ConnectionManager() *sebaknetwork.ConnectionManager {
 	return nr.connectionManager
 }
 
+// handshake returns the handshake of node, which is sent to the other nodes
+// with the node info.
+func (nr *NodeRunner) handshake() sebaknetwork.Handshake {
+	return nr.connectionManager.Handshake()
+}
+
 func (nr *NodeRunner) Storage() *sebakstorage.LevelDBBackend {
 	return nr.storage
 }
 
+func (nr *NodeRunner) TransactionPool() *TransactionPool {
+	return nr.transactionPool
+}
+
+func (nr *NodeRunner) TransactionStatuses() *TransactionStatusTracker {
+	return nr.transactionStatuses
+}
+
+func (nr *NodeRunner) TransactionOrderingPolicy() TransactionOrderingPolicy {
+	return nr.transactionOrderingPolicy
+}
+
+func (nr *NodeRunner) SetTransactionOrderingPolicy(policy TransactionOrderingPolicy) {
+	nr.transactionOrderingPolicy = policy
+}
+
+// SetForwardingReceipts makes the node forward the transaction submitted by
+// the API to the validators at once and return the `ForwardingReceipt`.
+func (nr *NodeRunner) SetForwardingReceipts(enabled bool) {
+	nr.forwardingReceipts = enabled
+}
+
+func (nr *NodeRunner) NetworkParameters() NetworkParameters {
+	return nr.networkParameters
+}
+
+// SetNetworkParameters sets the parameters of network, which are loaded from
+// the storage by `GetNetworkParameters`. It must be called before `Start()`.
+func (nr *NodeRunner) SetNetworkParameters(p NetworkParameters) {
+	nr.networkParameters = p
+}
+
+// SetProposerTimeout enables the view change; only the expected proposer of
+// `ProposerSchedule` proposes the transactions, and if it does not propose in
+// `timeout`, the other validators vote to pass it to the next proposer. If
+// `timeout` is 0, every node proposes the transactions of it's own pool.
+func (nr *NodeRunner) SetProposerTimeout(timeout time.Duration) {
+	nr.proposerTimeout = timeout
+}
+
+// SetAdaptiveProposerTimeout adapts the proposer timeout to the latencies of
+// ballots between `min` and the proposer timeout, so it must be called after
+// `SetProposerTimeout`.
+func (nr *NodeRunner) SetAdaptiveProposerTimeout(min time.Duration) (err error) {
+	if nr.proposerTimeout < 1 {
+		return errors.New("proposer timeout is not set")
+	}
+
+	var a AdaptiveTimeout
+	if a, err = NewAdaptiveTimeout(min, nr.proposerTimeout); err != nil {
+		return
+	}
+	nr.adaptiveTimeout = &a
+
+	return
+}
+
+// ProposerTimeout returns the current proposer timeout; with the adaptive
+// timeout, it is calculated from the latencies of the connected validators.
+func (nr *NodeRunner) ProposerTimeout() time.Duration {
+	if nr.adaptiveTimeout == nil || nr.proposerTimeout < 1 {
+		return nr.proposerTimeout
+	}
+
+	peers := nr.connectionManager.PeerLatencies()
+
+	var latencies []time.Duration
+	for _, v := range nr.connectionManager.AllConnected() {
+		if latency, ok := peers[v.Address()]; ok {
+			latencies = append(latencies, latency)
+		}
+	}
+
+	return nr.adaptiveTimeout.Timeout(latencies, nr.requiredQuorum())
+}
+
+func (nr *NodeRunner) ViewChange() *ViewChangeState {
+	return nr.viewChange
+}
+
 func (nr *NodeRunner) Policy() sebakcommon.VotingThresholdPolicy {
 	return nr.policy
 }
@@ -129,16 +526,132 @@ func (nr *NodeRunner) ConnectValidators() {
 		break
 	}
 	nr.log.Debug("current node is ready")
+
+	// the node serves the peer exchange while it discovers the validators,
+	// so the validators, which discover each other at once do not wait
+	// forever
+	if err := nr.DiscoverValidators(); err != nil {
+		nr.log.Crit("failed to discover validators; node will be stopped", "error", err)
+		nr.setStartupQuorumErr(err)
+		nr.Stop()
+		return
+	}
+	nr.trustValidators()
+
 	nr.log.Debug("trying to connect to the validators", "validators", nr.currentNode.GetValidators())
 
 	nr.log.Debug("initializing connectionManager for validators")
 	nr.connectionManager.Start()
 }
 
+// SetStartupQuorumTimeout enables the startup quorum gate. Until the node is
+// connected to enough validators to satisfy the voting threshold, incoming
+// messages and ballots are ignored, so the node does not vote in the rounds
+// which can not be agreed. If the quorum is not reached in `timeout`, the node
+// is stopped. With 0, the gate is disabled.
+func (nr *NodeRunner) SetStartupQuorumTimeout(timeout time.Duration) {
+	nr.startupQuorumTimeout = timeout
+}
+
+// setStartupQuorumErr keeps the reason, why the node is stopped while it
+// starts; it is set by the goroutines of `Start()` and read by `Start()`
+// after the network is stopped.
+func (nr *NodeRunner) setStartupQuorumErr(err error) {
+	nr.startupQuorumErrLock.Lock()
+	defer nr.startupQuorumErrLock.Unlock()
+
+	nr.startupQuorumErr = err
+}
+
+func (nr *NodeRunner) getStartupQuorumErr() error {
+	nr.startupQuorumErrLock.Lock()
+	defer nr.startupQuorumErrLock.Unlock()
+
+	return nr.startupQuorumErr
+}
+
+// HasQuorum checks the connected validators including the current node can
+// satisfy the voting threshold.
+func (nr *NodeRunner) HasQuorum() bool {
+	connected := nr.connectionManager.CountConnected() + 1 // including 'self'
+
+	return connected >= nr.requiredQuorum()
+}
+
+func (nr *NodeRunner) requiredQuorum() int {
+	required := nr.policy.Threshold(sebakcommon.BallotStateSIGN)
+	if t := nr.policy.Threshold(sebakcommon.BallotStateACCEPT); t > required {
+		required = t
+	}
+
+	return required
+}
+
+// IsQuorumReady returns `true` when the node passed the startup quorum gate
+// and can start voting.
+func (nr *NodeRunner) IsQuorumReady() bool {
+	return atomic.LoadInt32(&nr.quorumReady) == 1
+}
+
+func (nr *NodeRunner) waitStartupQuorum() {
+	if err := nr.waitQuorum(nr.startupQuorumTimeout); err != nil {
+		nr.log.Crit(
+			"failed to reach quorum; node will be stopped",
+			"timeout", nr.startupQuorumTimeout,
+			"connected", nr.connectionManager.CountConnected(),
+			"required", nr.requiredQuorum(),
+		)
+		nr.setStartupQuorumErr(err)
+		nr.Stop()
+		return
+	}
+
+	atomic.StoreInt32(&nr.quorumReady, 1)
+	nr.state.Transit(NodeStateConsensus)
+	nr.log.Info("quorum reached; node starts voting", "connected", nr.connectionManager.CountConnected())
+}
+
+func (nr *NodeRunner) waitQuorum(timeout time.Duration) (err error) {
+	ticker := time.NewTicker(time.Millisecond * 100)
+	defer ticker.Stop()
+
+	progress := time.NewTicker(time.Second * 1)
+	defer progress.Stop()
+
+	deadline := time.After(timeout)
+
+	for !nr.HasQuorum() {
+		select {
+		case <-ticker.C:
+		case <-progress.C:
+			nr.log.Info(
+				"waiting for quorum",
+				"connected", nr.connectionManager.CountConnected(),
+				"required", nr.requiredQuorum(),
+				"validators", len(nr.currentNode.GetValidators()),
+			)
+		case <-deadline:
+			err = sebakerror.ErrorStartupQuorumTimeout
+			return
+		}
+	}
+
+	return
+}
+
 var DefaultHandleMessageFromClientCheckerFuncs = []sebakcommon.CheckerFunc{
 	CheckNodeRunnerHandleMessageTransactionUnmarshal,
 	CheckNodeRunnerHandleMessageTransactionHasSameSource,
+	CheckNodeRunnerHandleMessageTimeBounds,
+	CheckNodeRunnerHandleMessageDoubleSpend,
 	CheckNodeRunnerHandleMessageHistory,
+	CheckNodeRunnerHandleMessagePushIntoTransactionPool,
+	CheckNodeRunnerHandleMessageBroadcastTransaction,
+}
+
+var DefaultProposeTransactionCheckerFuncs = []sebakcommon.CheckerFunc{
+	CheckNodeRunnerHandleMessageTimeBounds,
+	CheckNodeRunnerHandleMessageTransactionHasSameSource,
 	CheckNodeRunnerHandleMessageISAACReceiveMessage,
 	CheckNodeRunnerHandleMessageSignBallot,
 	CheckNodeRunnerHandleMessageBroadcast,
@@ -147,6 +660,7 @@ var DefaultHandleMessageFromClientCheckerFuncs = []sebakcommon.CheckerFunc{
 var DefaultHandleBallotCheckerFuncs = []sebakcommon.CheckerFunc{
 	CheckNodeRunnerHandleBallotIsWellformed,
 	CheckNodeRunnerHandleBallotNotFromKnownValidators,
+	CheckNodeRunnerHandleBallotParticipation,
 	CheckNodeRunnerHandleBallotCheckIsNew,
 	CheckNodeRunnerHandleBallotReceiveBallot,
 	CheckNodeRunnerHandleBallotHistory,
@@ -186,6 +700,21 @@ func (nr *NodeRunner) SetHandleBallotCheckerFuncs(
 	nr.handleBallotCheckerDeferFunc = deferFunc
 }
 
+func (nr *NodeRunner) SetProposeTransactionCheckerFuncs(
+	deferFunc sebakcommon.CheckerDeferFunc,
+	f ...sebakcommon.CheckerFunc,
+) {
+	if len(f) > 0 {
+		nr.proposeTransactionCheckerFuncs = f
+	}
+
+	if deferFunc == nil {
+		deferFunc = sebakcommon.DefaultDeferFunc
+	}
+
+	nr.proposeTransactionCheckerDeferFunc = deferFunc
+}
+
 func (nr *NodeRunner) SetHandleBallotCheckerDeferFuncs(deferFunc sebakcommon.CheckerDeferFunc) {
 	if deferFunc == nil {
 		deferFunc = sebakcommon.DefaultDeferFunc
@@ -194,74 +723,451 @@ func (nr *NodeRunner) SetHandleBallotCheckerDeferFuncs(deferFunc sebakcommon.Che
 	nr.handleBallotCheckerDeferFunc = deferFunc
 }
 
+// handleMessage handles the messages of `MessageQueue`; the client messages
+// are handled only when no consensus message is waiting.
 func (nr *NodeRunner) handleMessage() {
-	var err error
-	for message := range nr.network.ReceiveMessage() {
-		switch message.Type {
-		case sebaknetwork.ConnectMessage:
-			nr.log.Debug("got connect", "message", message.Head(50))
-			if _, err := sebakcommon.NewValidatorFromString(message.Data); err != nil {
-				nr.log.Error("invalid validator data was received", "data", message.Data)
-				continue
+	go nr.messageQueue.Run(nr.network.ReceiveMessage())
+
+	ticker := time.NewTicker(nr.networkParameters.BlockTime)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case message, ok := <-nr.messageQueue.Consensus():
+			if !ok {
+				return
+			}
+			nr.handleNetworkMessages(nr.receiveMessages(message))
+			continue
+		case <-ticker.C:
+			nr.tick()
+			continue
+		default:
+		}
+
+		select {
+		case message, ok := <-nr.messageQueue.Consensus():
+			if !ok {
+				return
 			}
-		case sebaknetwork.MessageFromClient:
-			if message.IsEmpty() {
-				nr.log.Error("got empty message from client`")
-				continue
+			nr.handleNetworkMessages(nr.receiveMessages(message))
+		case message, ok := <-nr.messageQueue.Client():
+			if !ok {
+				return
 			}
+			nr.handleNetworkMessages([]sebaknetwork.Message{message})
+		case <-ticker.C:
+			nr.tick()
+		}
+	}
+}
 
-			nr.log.Debug("got message from client`", "message", message.Head(50))
+func (nr *NodeRunner) tick() {
+	nr.updateQuorumState()
+	nr.checkClockSkew()
+	nr.checkPartition()
+	nr.checkBlockSync()
+	nr.shedLoad()
+	nr.proposeTransactions()
+}
 
-			checker := &NodeRunnerHandleMessageChecker{
-				DefaultChecker: sebakcommon.DefaultChecker{nr.handleMessageFromClientCheckerFuncs},
-				NodeRunner:     nr,
-				CurrentNode:    nr.currentNode,
-				NetworkID:      nr.networkID,
-				Message:        message,
+func (nr *NodeRunner) MessageQueue() *sebaknetwork.MessageQueue {
+	return nr.messageQueue
+}
+
+// receiveMessages collects the consensus messages, which are already waiting,
+// with the first message, so the ballots in them can be verified at once.
+func (nr *NodeRunner) receiveMessages(first sebaknetwork.Message) (messages []sebaknetwork.Message) {
+	messages = append(messages, first)
+
+	for len(messages) < MaxBallotVerifyBatch {
+		select {
+		case message, ok := <-nr.messageQueue.Consensus():
+			if !ok {
+				return
 			}
+			messages = append(messages, message)
+		default:
+			return
+		}
+	}
+
+	return
+}
+
+// handleNetworkMessages verifies the signatures of the ballots in messages
+// together and handles the messages in received order; the ballots which are
+// failed to verify or are signed by the expired session key are dropped.
+func (nr *NodeRunner) handleNetworkMessages(messages []sebaknetwork.Message) {
+	var ballots []Ballot
+	var indices []int
+	for i, message := range messages {
+		if message.Type != sebaknetwork.BallotMessage || message.IsEmpty() {
+			continue
+		}
+
+		ballot, err := NewBallotFromJSON(message.Data)
+		if err != nil {
+			// `CheckNodeRunnerHandleBallotIsWellformed` will report it
+			continue
+		}
+		ballots = append(ballots, ballot)
+		indices = append(indices, i)
+	}
 
-			if err = sebakcommon.RunChecker(checker, nr.handleMessageFromClientCheckerDeferFunc); err != nil {
-				if _, ok := err.(sebakcommon.CheckerErrorStop); ok {
-					continue
-				}
-				nr.log.Error("failed to handle message from client", "error", err)
-				continue
+	failed := map[int]error{}
+	for i, err := range nr.ballotVerifier.Verify(ballots) {
+		if err == nil {
+			err = nr.checkSessionKeyCertificate(ballots[i].H.Certificate)
+		}
+		if err != nil {
+			failed[indices[i]] = err
+		}
+	}
+
+	for i, message := range messages {
+		if err, found := failed[i]; found {
+			nr.log.Error("failed to verify ballot", "error", err, "message", message.Head(50))
+			continue
+		}
+		nr.handleNetworkMessage(message)
+	}
+}
+
+// handleNetworkMessage handles the message and returns the error if the
+// message is refused.
+func (nr *NodeRunner) handleNetworkMessage(message sebaknetwork.Message) (err error) {
+	switch message.Type {
+	case sebaknetwork.ConnectMessage:
+		nr.log.Debug("got connect", "message", message.Head(50))
+		if _, err = sebakcommon.NewValidatorFromString(message.Data); err != nil {
+			nr.log.Error("invalid validator data was received", "data", message.Data)
+			return
+		}
+	case sebaknetwork.MessageFromClient:
+		if message.IsEmpty() {
+			nr.log.Error("got empty message from client`")
+			err = sebakerror.ErrorEmptyMessage
+			return
+		}
+
+		nr.log.Debug("got message from client`", "message", message.Head(50))
+
+		if !nr.IsQuorumReady() {
+			nr.log.Debug("quorum is not ready; message from client is ignored", "message", message.Head(50))
+			return
+		}
+
+		checker := &NodeRunnerHandleMessageChecker{
+			DefaultChecker: sebakcommon.DefaultChecker{nr.handleMessageFromClientCheckerFuncs},
+			NodeRunner:     nr,
+			CurrentNode:    nr.currentNode,
+			NetworkID:      nr.networkID,
+			Message:        message,
+		}
+
+		if err = sebakcommon.RunChecker(checker, nr.handleMessageFromClientCheckerDeferFunc); err != nil {
+			if _, ok := err.(sebakcommon.CheckerErrorStop); ok {
+				return
+			}
+			nr.log.Error("failed to handle message from client", "error", err)
+			if len(checker.Transaction.GetHash()) > 0 {
+				nr.transactionStatuses.Rejected(checker.Transaction.GetHash(), err)
 			}
-		case sebaknetwork.BallotMessage:
-			if message.IsEmpty() {
-				nr.log.Error("got empty ballot message`")
-				continue
+			return
+		}
+	case sebaknetwork.TransactionInventoryMessage:
+		if !nr.IsQuorumReady() {
+			return
+		}
+
+		var inventory TransactionInventory
+		if inventory, err = NewTransactionInventoryFromJSON(message.Data); err != nil {
+			return
+		}
+		go func() {
+			if err := nr.handleTransactionInventory(inventory); err != nil {
+				nr.log.Debug("failed to fetch announced transactions", "source", inventory.Source, "error", err)
 			}
-			nr.log.Debug("got ballot", "message", message.Head(50))
-
-			checker := &NodeRunnerHandleBallotChecker{
-				DefaultChecker: sebakcommon.DefaultChecker{nr.handleBallotCheckerFuncs},
-				NodeRunner:     nr,
-				CurrentNode:    nr.currentNode,
-				NetworkID:      nr.networkID,
-				Message:        message,
-				VotingHole:     VotingNOTYET,
+		}()
+	case sebaknetwork.BallotMessage:
+		if message.IsEmpty() {
+			nr.log.Error("got empty ballot message`")
+			err = sebakerror.ErrorEmptyMessage
+			return
+		}
+		nr.log.Debug("got ballot", "message", message.Head(50))
+
+		if !nr.IsQuorumReady() {
+			nr.log.Debug("quorum is not ready; ballot is ignored", "message", message.Head(50))
+			return
+		}
+
+		checker := &NodeRunnerHandleBallotChecker{
+			DefaultChecker: sebakcommon.DefaultChecker{nr.handleBallotCheckerFuncs},
+			NodeRunner:     nr,
+			CurrentNode:    nr.currentNode,
+			NetworkID:      nr.networkID,
+			Message:        message,
+			VotingHole:     VotingNOTYET,
+		}
+		if err = sebakcommon.RunChecker(checker, nr.handleBallotCheckerDeferFunc); err != nil {
+			if _, ok := err.(sebakcommon.CheckerErrorStop); ok {
+				nr.closeConsensus(checker)
+				return
 			}
-			if err = sebakcommon.RunChecker(checker, nr.handleBallotCheckerDeferFunc); err != nil {
-				if _, ok := err.(sebakcommon.CheckerErrorStop); ok {
-					nr.closeConsensus(checker)
-					continue
-				}
-				nr.log.Error("failed to handle ballot", "error", err)
-
-				if err = nr.closeConsensus(checker); err != nil {
-					nr.Log().Error("failed to close consensus", "error", err)
-				} else {
-					nr.Log().Error("consensus closed")
-				}
-
-				continue
+			nr.log.Error("failed to handle ballot", "error", err)
+
+			if closeErr := nr.closeConsensus(checker); closeErr != nil {
+				nr.Log().Error("failed to close consensus", "error", closeErr)
+			} else {
+				nr.Log().Error("consensus closed")
 			}
-			nr.closeConsensus(checker)
-		default:
-			nr.log.Error("got unknown", "message", message.Head(50))
+
+			return
 		}
+		nr.closeConsensus(checker)
+	case sebaknetwork.ViewChangeMessage:
+		if !nr.IsQuorumReady() {
+			return
+		}
+
+		var vc ViewChange
+		if vc, err = NewViewChangeFromJSON(message.Data); err != nil {
+			return
+		}
+		if err = vc.IsWellFormed(nr.networkID); err != nil {
+			return
+		}
+		if err = nr.checkSessionKeyCertificate(vc.H.Certificate); err != nil {
+			return
+		}
+		if err = nr.handleViewChange(vc); err != nil {
+			nr.log.Error("failed to handle view change", "error", err)
+			return
+		}
+	case sebaknetwork.BlockAnnouncementMessage:
+		var ba BlockAnnouncement
+		if ba, err = NewBlockAnnouncementFromJSON(message.Data); err != nil {
+			return
+		}
+		if err = ba.IsWellFormed(nr.networkID); err != nil {
+			return
+		}
+		if err = nr.checkSessionKeyCertificate(ba.H.Certificate); err != nil {
+			return
+		}
+		if err = nr.handleBlockAnnouncement(ba); err != nil {
+			nr.log.Error("failed to handle block announcement", "error", err)
+			return
+		}
+	default:
+		nr.log.Error("got unknown", "message", message.Head(50))
+		err = sebakerror.ErrorUnknownMessageType
 	}
+
+	return
+}
+
+// proposeTransactions starts the ballots for the transactions in
+// `TransactionPool` by the order of `TransactionOrderingPolicy`. The
+// transaction, whose source already has the transaction in consensus, is kept
+// in pool for the next round. Up to `MaxTransactionsPerBlock` of the network
+// parameters are proposed at once, and under the resource pressure, only the
+// transactions of `LoadShedder.ProposalLimit()`. The
+// expired transactions are removed from pool and `FutureTransactionQueue`
+// before.
+func (nr *NodeRunner) proposeTransactions() {
+	for _, hash := range nr.transactionPool.RemoveExpired(time.Now()) {
+		nr.transactionStatuses.Rejected(hash, sebakerror.ErrorTransactionExpired)
+	}
+	for _, hash := range nr.futureTransactions.RemoveExpired(time.Now()) {
+		nr.transactionStatuses.Rejected(hash, sebakerror.ErrorFutureTransactionExpired)
+	}
+
+	if nr.transactionPool.Len() < 1 {
+		return
+	}
+
+	if nr.proposerTimeout > 0 {
+		isProposer, err := nr.isExpectedProposer()
+		if err != nil {
+			nr.log.Error("failed to find the expected proposer", "error", err)
+			return
+		}
+		if !isProposer {
+			nr.requestViewChange()
+			return
+		}
+	}
+
+	limit := nr.proposalLimit()
+	if max := int(nr.networkParameters.MaxTransactionsPerBlock); max > 0 && (limit < 1 || limit > max) {
+		limit = max
+	}
+	var proposed int
+	for _, item := range nr.transactionPool.Ordered(nr.transactionOrderingPolicy) {
+		if limit > 0 && proposed >= limit {
+			break
+		}
+
+		checker := &NodeRunnerHandleMessageChecker{
+			DefaultChecker: sebakcommon.DefaultChecker{nr.proposeTransactionCheckerFuncs},
+			NodeRunner:     nr,
+			CurrentNode:    nr.currentNode,
+			NetworkID:      nr.networkID,
+			Transaction:    item.Transaction,
+		}
+
+		err := sebakcommon.RunChecker(checker, nr.proposeTransactionCheckerDeferFunc)
+		if _, ok := err.(sebakcommon.CheckerErrorStop); ok {
+			continue
+		}
+
+		nr.transactionPool.Remove(item.Transaction.GetHash())
+		if err != nil {
+			nr.log.Error("failed to propose transaction", "transaction", item.Transaction.GetHash(), "error", err)
+			nr.transactionStatuses.Rejected(item.Transaction.GetHash(), err)
+			continue
+		}
+		proposed++
+	}
+}
+
+// viewChangeQuorum is the number of votes to change the view. It is at least
+// the majority of validators, so the validators can not move to the different
+// views.
+func (nr *NodeRunner) viewChangeQuorum() int {
+	required := nr.requiredQuorum()
+	if majority := (len(nr.currentNode.GetValidators())+1)/2 + 1; majority > required {
+		required = majority
+	}
+
+	return required
+}
+
+// isExpectedProposer checks whether the current node is the proposer of the
+// next block height in the current view.
+func (nr *NodeRunner) isExpectedProposer() (ok bool, err error) {
+	var latest Block
+	if latest, err = GetLatestBlock(nr.storage); err != nil {
+		return
+	}
+	nr.viewChange.SetHeight(latest.Height+1, nr.viewChangeQuorum())
+
+	height, view := nr.viewChange.Current()
+	proposer := NewProposerSchedule(nr.currentNode).ProposerOfView(height, view)
+	ok = proposer.Address == nr.currentNode.Address()
+
+	return
+}
+
+// requestViewChange votes for the next view, when the expected proposer does
+// not make progress in `proposerTimeout`.
+func (nr *NodeRunner) requestViewChange() {
+	isaac := nr.consensus.(*ISAAC)
+	if len(isaac.Boxes.WaitingBox.Hashes) > 0 || len(isaac.Boxes.VotingBox.Hashes) > 0 {
+		// the consensus is in progress
+		nr.viewChange.Touch()
+		return
+	}
+
+	if !nr.viewChange.IsExpired(nr.ProposerTimeout()) {
+		return
+	}
+
+	height, view, ok := nr.viewChange.Request()
+	if !ok {
+		return
+	}
+
+	vc := NewViewChange(nr.currentNode.Address(), height, view)
+	if err := nr.consensus.Signer().SignViewChange(&vc); err != nil {
+		nr.log.Error("failed to sign view change", "error", err)
+		return
+	}
+
+	nr.log.Debug("request view change", "height", height, "view", view)
+	nr.connectionManager.BroadcastViewChange(vc)
+	nr.handleViewChange(vc)
+}
+
+func (nr *NodeRunner) handleViewChange(vc ViewChange) (err error) {
+	if vc.B.NodeKey != nr.currentNode.Address() && !nr.currentNode.HasValidators(vc.B.NodeKey) {
+		err = sebakerror.ErrorViewChangeFromUnknownValidator
+		return
+	}
+
+	if !nr.viewChange.Vote(vc, nr.viewChangeQuorum()) {
+		return
+	}
+
+	height, view := nr.viewChange.Current()
+	nr.log.Debug(
+		"view changed",
+		"height", height,
+		"view", view,
+		"proposer", NewProposerSchedule(nr.currentNode).ProposerOfView(height, view).Address,
+	)
+
+	return
+}
+
+// announceBlock broadcasts the latest block to the validators, so they can
+// detect the fork.
+func (nr *NodeRunner) announceBlock() {
+	latest, err := GetLatestBlock(nr.storage)
+	if err != nil || latest.IsEmpty() {
+		return
+	}
+
+	ba := NewBlockAnnouncement(nr.currentNode.Address(), latest)
+	if err = nr.consensus.Signer().SignBlockAnnouncement(&ba); err != nil {
+		nr.log.Error("failed to sign block announcement", "error", err)
+		return
+	}
+
+	nr.connectionManager.BroadcastBlockAnnouncement(ba)
+}
+
+// handleBlockAnnouncement records the evidence, if the announced block
+// conflicts with the finalized block of same height.
+func (nr *NodeRunner) handleBlockAnnouncement(ba BlockAnnouncement) (err error) {
+	if !nr.currentNode.HasValidators(ba.B.NodeKey) {
+		err = sebakerror.ErrorBlockFromUnknownValidator
+		return
+	}
+	nr.observeNetworkHeight(ba.B.Block.Height)
+	nr.blockSync.observeHeight(ba.B.NodeKey, ba.B.Block.Height)
+
+	var evidence ForkEvidence
+	var conflicted bool
+	if evidence, conflicted, err = CheckBlockAnnouncement(nr.storage, ba); err != nil || !conflicted {
+		return
+	}
+
+	var saved bool
+	if saved, err = evidence.Save(nr.storage); err != nil || !saved {
+		return
+	}
+	atomic.AddUint64(&nr.forks, 1)
+
+	nr.log.Crit(
+		"fork detected",
+		"height", evidence.Height,
+		"validator", evidence.NodeKey,
+		"local-state-hash", evidence.Local.StateHash,
+		"remote-state-hash", evidence.Remote.StateHash,
+	)
+	nr.haltOnInvariantViolation(InvariantFork, sebakerror.ErrorStateHashDoesNotMatch, "height", evidence.Height, "validator", evidence.NodeKey)
+
+	return
+}
+
+// ForksDetected returns the number of fork evidences detected since the node
+// started.
+func (nr *NodeRunner) ForksDetected() uint64 {
+	return atomic.LoadUint64(&nr.forks)
 }
 
 func (nr *NodeRunner) closeConsensus(c sebakcommon.Checker) (err error) {
@@ -273,6 +1179,7 @@ func (nr *NodeRunner) closeConsensus(c sebakcommon.Checker) (err error) {
 	if !checker.VotingStateStaging.IsClosed() {
 		return
 	}
+	nr.connectionManager.CloseRound(checker.Ballot.MessageHash())
 
 	if err = nr.Consensus().CloseConsensus(checker.Ballot); err != nil {
 		nr.Log().Error("new failed to close consensus", "error", err)
@@ -0,0 +1,239 @@
+package sebak
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"boscoin.io/sebak/lib/error"
+	"boscoin.io/sebak/lib/network"
+)
+
+// APIVersionPrefix is the path prefix of the first version of the HTTP API for
+// clients; see `APIVersion`. The node to node messages, like '/ballot' do not
+// belong to it.
+const APIVersionPrefix string = "/api/v1"
+
+const GetResultsPattern string = "/results"
+
+const (
+	GetNextProposersPattern    string = "/consensus/next-proposers"
+	GetFinalityPattern         string = "/consensus/finality"
+	GetProposerSchedulePattern string = "/consensus/proposer-schedule"
+)
+
+const (
+	DefaultNextProposersLimit int = 5
+	MaxNextProposersLimit     int = 100
+
+	DefaultProposerScheduleRounds int = 1
+	MaxProposerScheduleRounds     int = 10
+)
+
+// APIError is the error response of the API; see `sebakerror.Problem`.
+type APIError = sebakerror.Problem
+
+func writeAPIJSON(w http.ResponseWriter, status int, v interface{}) {
+	v = serializeAPIResponse(w, v)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeAPIError(w http.ResponseWriter, r *http.Request, status int, err error) {
+	sebakerror.WriteProblem(w, r, status, err)
+}
+
+// parseAPILimit parses the 'limit' query; if it is not given, `defaultLimit`
+// is returned.
+func parseAPILimit(r *http.Request, defaultLimit, maxLimit int) (limit int, err error) {
+	s := r.URL.Query().Get("limit")
+	if len(s) < 1 {
+		limit = defaultLimit
+		return
+	}
+
+	if limit, err = strconv.Atoi(s); err != nil || limit < 1 || limit > maxLimit {
+		err = fmt.Errorf("'limit' must be between 1 and %d", maxLimit)
+		return
+	}
+
+	return
+}
+
+// APIHandlers returns the API handlers by their path pattern; they are made
+// from `APIRoutes()` under the prefix of every version.
+func (nr *NodeRunner) APIHandlers() map[string]http.HandlerFunc {
+	handlers := map[string]http.HandlerFunc{}
+	routes := nr.APIRoutes()
+	for _, group := range nr.APIVersionGroups() {
+		prefix := group.Version.Prefix()
+		handlers[prefix+GetOpenAPIPattern] = group.withAPIVersion(nr.apiConfig.BasePath, nr.handleAPIOpenAPI)
+		for _, route := range routes {
+			handlers[prefix+route.Pattern] = group.withAPIVersion(nr.apiConfig.BasePath, route.Handler)
+		}
+	}
+	if nr.rateLimiter != nil {
+		for pattern, handler := range handlers {
+			handlers[pattern] = nr.rateLimited(handler)
+		}
+	}
+	if len(nr.apiConfig.CORSOrigins) > 0 {
+		for pattern, handler := range handlers {
+			handlers[pattern] = nr.apiConfig.withCORS(handler)
+		}
+	}
+	if basePath := nr.apiConfig.BasePath; len(basePath) > 0 {
+		prefixed := map[string]http.HandlerFunc{}
+		for pattern, handler := range handlers {
+			prefixed[basePath+pattern] = http.StripPrefix(basePath, handler).ServeHTTP
+		}
+		handlers = prefixed
+	}
+	handlers[GetHealthzPattern] = nr.handleAPIHealthz
+	handlers[GetReadyzPattern] = nr.handleAPIReadyz
+
+	return handlers
+}
+
+func (nr *NodeRunner) addAPIHandlers() {
+	h2n, ok := nr.network.(*sebaknetwork.HTTP2Network)
+	if !ok {
+		return
+	}
+
+	for pattern, handler := range nr.APIHandlers() {
+		h2n.AddHandlerFunc(pattern, sebaknetwork.HandlerFunc(handler))
+	}
+}
+
+// handleAPIResults returns all the result codes, which are in the `result` of
+// the API errors, so the clients can decide to retry or not.
+func (nr *NodeRunner) handleAPIResults(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		writeAPIError(w, r, http.StatusMethodNotAllowed, nil)
+		return
+	}
+
+	writeAPIJSON(w, http.StatusOK, sebakerror.Results())
+}
+
+type NextProposersResponse struct {
+	Height    uint64              `json:"height"`
+	Proposers []ScheduledProposer `json:"proposers"`
+}
+
+// errProposerScheduleDisabled is returned by the proposer APIs, when the
+// view change is disabled; every node proposes the transactions of it's own
+// pool, so there is no expected proposer.
+var errProposerScheduleDisabled = errors.New("proposers are not scheduled without the proposer timeout")
+
+// handleAPINextProposers returns the expected proposers for the next heights,
+// so the clients can send transactions directly to them.
+func (nr *NodeRunner) handleAPINextProposers(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		writeAPIError(w, r, http.StatusMethodNotAllowed, nil)
+		return
+	}
+	if nr.proposerTimeout < 1 {
+		writeAPIError(w, r, http.StatusNotFound, errProposerScheduleDisabled)
+		return
+	}
+
+	limit, err := parseAPILimit(r, DefaultNextProposersLimit, MaxNextProposersLimit)
+	if err != nil {
+		writeAPIError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	latest, err := GetLatestBlock(nr.storage)
+	if err != nil {
+		writeAPIError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeAPIJSON(w, http.StatusOK, NextProposersResponse{
+		Height:    latest.Height,
+		Proposers: NewProposerSchedule(nr.currentNode).NextProposers(latest.Height, limit),
+	})
+}
+
+type ProposerScheduleResponse struct {
+	Height     uint64              `json:"height"`
+	Validators int                 `json:"validators"`
+	Rounds     int                 `json:"rounds"`
+	Proposers  []ScheduledProposer `json:"proposers"`
+	Address    string              `json:"address,omitempty"`
+	Turns      []uint64            `json:"turns,omitempty"`
+}
+
+// handleAPIProposerSchedule returns the proposers of the next 'rounds' rounds;
+// in one round, every validator proposes once. With 'address', the heights
+// which the validator proposes in the rounds are also returned, so the
+// operators can plan the maintenance of node between them.
+func (nr *NodeRunner) handleAPIProposerSchedule(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		writeAPIError(w, r, http.StatusMethodNotAllowed, nil)
+		return
+	}
+	if nr.proposerTimeout < 1 {
+		writeAPIError(w, r, http.StatusNotFound, errProposerScheduleDisabled)
+		return
+	}
+
+	rounds := DefaultProposerScheduleRounds
+	if s := r.URL.Query().Get("rounds"); len(s) > 0 {
+		var err error
+		if rounds, err = strconv.Atoi(s); err != nil || rounds < 1 || rounds > MaxProposerScheduleRounds {
+			writeAPIError(w, r, http.StatusBadRequest, fmt.Errorf("'rounds' must be between 1 and %d", MaxProposerScheduleRounds))
+			return
+		}
+	}
+
+	latest, err := GetLatestBlock(nr.storage)
+	if err != nil {
+		writeAPIError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	schedule := NewProposerSchedule(nr.currentNode)
+	response := ProposerScheduleResponse{
+		Height:     latest.Height,
+		Validators: schedule.Validators(),
+		Rounds:     rounds,
+		Proposers:  schedule.NextRounds(latest.Height, rounds),
+	}
+
+	if address := r.URL.Query().Get("address"); len(address) > 0 {
+		if response.Turns = schedule.NextTurns(latest.Height, address, rounds); len(response.Turns) < 1 {
+			writeAPIError(w, r, http.StatusNotFound, errors.New("validator not found"))
+			return
+		}
+		response.Address = address
+	}
+
+	writeAPIJSON(w, http.StatusOK, response)
+}
+
+// handleAPIFinality returns the last irreversible block; the blocks up to it
+// will never be changed.
+func (nr *NodeRunner) handleAPIFinality(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		writeAPIError(w, r, http.StatusMethodNotAllowed, nil)
+		return
+	}
+
+	finality, err := GetFinality(nr.storage)
+	if err != nil {
+		writeAPIError(w, r, http.StatusInternalServerError, err)
+		return
+	} else if finality.IsEmpty() {
+		writeAPIError(w, r, http.StatusNotFound, errors.New("no irreversible block yet"))
+		return
+	}
+
+	writeAPIJSON(w, http.StatusOK, finality)
+}
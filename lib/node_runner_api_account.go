@@ -0,0 +1,391 @@
+package sebak
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strconv"
+)
+
+const (
+	GetAccountsPattern               string = "/accounts/"
+	GetAccountDataSubPattern         string = "data"
+	GetAccountTransactionsSubPattern string = "transactions"
+	GetAccountStatementSubPattern    string = "statement"
+)
+
+const (
+	DefaultAccountDataLimit int = 20
+	MaxAccountDataLimit     int = 100
+
+	DefaultAccountTransactionsLimit int = 20
+	MaxAccountTransactionsLimit     int = 100
+
+	DefaultAccountStatementLimit int = 20
+	MaxAccountStatementLimit     int = 100
+)
+
+const (
+	AccountDataValueModeBase64 string = "base64"
+	AccountDataValueModeRaw    string = "raw"
+)
+
+const (
+	APIOrderAsc  string = "asc"
+	APIOrderDesc string = "desc"
+)
+
+// handleAPIAccounts dispatches the requests under '/accounts/{address}/' by
+// their sub resource.
+func (nr *NodeRunner) handleAPIAccounts(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		writeAPIError(w, r, http.StatusMethodNotAllowed, nil)
+		return
+	}
+
+	id, sub := SplitAPIPath(r.URL.Path, GetAccountsPattern)
+	if len(sub) != 1 {
+		writeAPIError(w, r, http.StatusNotFound, nil)
+		return
+	}
+
+	parsed, err := ParseAccountAddress(id)
+	if err != nil {
+		writeAPIError(w, r, http.StatusNotFound, errors.New("account not found; invalid address"))
+		return
+	}
+
+	address := parsed.String()
+	exists, err := ExistBlockAccount(nr.storage, address)
+	if err != nil {
+		writeAPIError(w, r, http.StatusInternalServerError, err)
+		return
+	} else if !exists {
+		writeAPIError(w, r, http.StatusNotFound, errors.New("account not found"))
+		return
+	}
+
+	switch sub[0] {
+	case GetAccountDataSubPattern:
+		nr.handleAPIAccountData(w, r, address)
+	case GetAccountTransactionsSubPattern:
+		nr.handleAPIAccountTransactions(w, r, address)
+	case GetAccountStatementSubPattern:
+		nr.handleAPIAccountStatement(w, r, address)
+	case GetAccountOperationsSubPattern:
+		nr.handleAPIAccountOperations(w, r, address)
+	case GetAccountSpendingLimitSubPattern:
+		nr.handleAPIAccountSpendingLimit(w, r, address)
+	case GetAccountSignersSubPattern:
+		nr.handleAPIAccountSigners(w, r, address)
+	case GetAccountFrozenSubPattern:
+		nr.handleAPIAccountFrozen(w, r, address)
+	default:
+		writeAPIError(w, r, http.StatusNotFound, nil)
+	}
+}
+
+type AccountResponse struct {
+	Address    string `json:"address"`
+	Balance    Amount `json:"balance"`
+	Checkpoint string `json:"checkpoint"`
+}
+
+func NewAccountResponse(ba *BlockAccount) AccountResponse {
+	return AccountResponse{
+		Address:    ba.Address,
+		Balance:    ba.GetBalance(),
+		Checkpoint: ba.Checkpoint,
+	}
+}
+
+type AccountDataEntry struct {
+	Name    string `json:"name"`
+	Value   string `json:"value"`
+	Updated string `json:"updated"`
+}
+
+type AccountDataResponse struct {
+	Address    string             `json:"address"`
+	Mode       string             `json:"mode"`
+	Entries    []AccountDataEntry `json:"entries"`
+	NextCursor string             `json:"next_cursor,omitempty"`
+}
+
+// handleAPIAccountData returns the data entries of account in name order.
+// The entries can be filtered by 'prefix' of name and paged by 'cursor', which
+// is the name of the last entry of the previous page. With 'name', only the
+// entry of the name is returned; it is not found, if it does not exist.
+func (nr *NodeRunner) handleAPIAccountData(w http.ResponseWriter, r *http.Request, address string) {
+	query := r.URL.Query()
+
+	limit, err := parseAPILimit(r, DefaultAccountDataLimit, MaxAccountDataLimit)
+	if err != nil {
+		writeAPIError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	mode := query.Get("mode")
+	switch mode {
+	case "":
+		mode = AccountDataValueModeBase64
+	case AccountDataValueModeBase64, AccountDataValueModeRaw:
+	default:
+		writeAPIError(w, r, http.StatusBadRequest, errors.New("'mode' must be 'base64' or 'raw'"))
+		return
+	}
+
+	newEntry := func(d *BlockAccountData) (entry AccountDataEntry) {
+		entry = AccountDataEntry{Name: d.Name, Updated: d.Updated}
+		if mode == AccountDataValueModeRaw {
+			entry.Value = string(d.Value)
+		} else {
+			entry.Value = base64.StdEncoding.EncodeToString(d.Value)
+		}
+		return
+	}
+
+	response := AccountDataResponse{
+		Address: address,
+		Mode:    mode,
+		Entries: []AccountDataEntry{},
+	}
+
+	if name := query.Get("name"); len(name) > 0 {
+		exists, err := nr.storage.Has(GetBlockAccountDataKey(address, name))
+		if err != nil {
+			writeAPIError(w, r, http.StatusInternalServerError, err)
+			return
+		} else if !exists {
+			writeAPIError(w, r, http.StatusNotFound, errors.New("data entry not found"))
+			return
+		}
+
+		d, err := GetBlockAccountData(nr.storage, address, name)
+		if err != nil {
+			writeAPIError(w, r, http.StatusInternalServerError, err)
+			return
+		}
+		response.Entries = append(response.Entries, newEntry(d))
+		writeAPIJSON(w, http.StatusOK, response)
+		return
+	}
+
+	iterFunc, closeFunc := GetBlockAccountDataByPrefix(nr.storage, address, query.Get("prefix"), query.Get("cursor"), false)
+	defer closeFunc()
+
+	for {
+		d, hasNext := iterFunc()
+		if !hasNext {
+			break
+		}
+		if len(response.Entries) == limit {
+			response.NextCursor = response.Entries[limit-1].Name
+			break
+		}
+
+		response.Entries = append(response.Entries, newEntry(d))
+	}
+
+	writeAPIJSON(w, http.StatusOK, response)
+}
+
+type AccountTransactionEntry struct {
+	Hash       string   `json:"hash"`
+	Source     string   `json:"source"`
+	Fee        Amount   `json:"fee"`
+	Amount     Amount   `json:"amount"`
+	Checkpoint string   `json:"checkpoint"`
+	Operations []string `json:"operations"`
+	Created    string   `json:"created"`
+	Confirmed  string   `json:"confirmed"`
+}
+
+func NewAccountTransactionEntry(bt BlockTransaction) AccountTransactionEntry {
+	return AccountTransactionEntry{
+		Hash:       bt.Hash,
+		Source:     bt.Source,
+		Fee:        bt.Fee,
+		Amount:     bt.Amount,
+		Checkpoint: bt.Checkpoint,
+		Operations: bt.Operations,
+		Created:    bt.Created,
+		Confirmed:  bt.Confirmed,
+	}
+}
+
+type AccountTransactionsResponse struct {
+	Address      string                    `json:"address"`
+	Order        string                    `json:"order"`
+	Transactions []AccountTransactionEntry `json:"transactions"`
+	NextCursor   string                    `json:"next_cursor,omitempty"`
+}
+
+// handleAPIAccountTransactions returns the transactions of account, which is
+// the source or the target of them, in confirmed order; 'order' is 'desc', the
+// latest first by default or 'asc'. The next page starts after the 'cursor',
+// which is the hash of the last transaction of the previous page.
+func (nr *NodeRunner) handleAPIAccountTransactions(w http.ResponseWriter, r *http.Request, address string) {
+	query := r.URL.Query()
+
+	limit, err := parseAPILimit(r, DefaultAccountTransactionsLimit, MaxAccountTransactionsLimit)
+	if err != nil {
+		writeAPIError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	order := query.Get("order")
+	switch order {
+	case "":
+		order = APIOrderDesc
+	case APIOrderAsc, APIOrderDesc:
+	default:
+		writeAPIError(w, r, http.StatusBadRequest, errors.New("'order' must be 'asc' or 'desc'"))
+		return
+	}
+
+	var from string
+	if cursor := query.Get("cursor"); len(cursor) > 0 {
+		var exists bool
+		if _, err = ParseTxHash(cursor); err != nil {
+			writeAPIError(w, r, http.StatusBadRequest, errors.New("'cursor' must be the hash of transaction"))
+			return
+		} else if exists, err = ExistBlockTransaction(nr.storage, cursor); err != nil {
+			writeAPIError(w, r, http.StatusInternalServerError, err)
+			return
+		} else if !exists {
+			writeAPIError(w, r, http.StatusBadRequest, errors.New("'cursor' must be the hash of transaction"))
+			return
+		}
+
+		var bt BlockTransaction
+		if bt, err = GetBlockTransaction(nr.storage, cursor); err != nil {
+			writeAPIError(w, r, http.StatusInternalServerError, err)
+			return
+		}
+		from = bt.NewBlockTransactionKeyAccount(address)
+	}
+
+	response := AccountTransactionsResponse{
+		Address:      address,
+		Order:        order,
+		Transactions: []AccountTransactionEntry{},
+	}
+
+	iterFunc, closeFunc := GetBlockTransactionsByAccount(nr.storage, address, from, order == APIOrderDesc)
+	defer closeFunc()
+
+	for {
+		bt, hasNext := iterFunc()
+		if !hasNext {
+			break
+		}
+		if len(response.Transactions) == limit {
+			response.NextCursor = response.Transactions[limit-1].Hash
+			break
+		}
+
+		response.Transactions = append(response.Transactions, NewAccountTransactionEntry(bt))
+	}
+
+	writeAPIJSON(w, http.StatusOK, response)
+}
+
+const (
+	AccountStatementSideDebit  string = "debit"
+	AccountStatementSideCredit string = "credit"
+)
+
+type AccountStatementEntry struct {
+	LedgerEntry
+	Side string `json:"side"`
+}
+
+type AccountStatementResponse struct {
+	Address string                  `json:"address"`
+	Order   string                  `json:"order"`
+	Balance Amount                  `json:"balance"`
+	Entries []AccountStatementEntry `json:"entries"`
+
+	// LedgerBalance is the sum of all the ledger entries of account; it is
+	// given with 'verify=1' and must be same with `Balance`.
+	LedgerBalance *int64 `json:"ledger_balance,omitempty"`
+
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// handleAPIAccountStatement returns the ledger entries of account, which is
+// the debit or the credit of them, in sequence order; 'order' is 'desc', the
+// latest first by default or 'asc'. The next page starts after the 'cursor',
+// which is the sequence of the last entry of the previous page.
+func (nr *NodeRunner) handleAPIAccountStatement(w http.ResponseWriter, r *http.Request, address string) {
+	query := r.URL.Query()
+
+	limit, err := parseAPILimit(r, DefaultAccountStatementLimit, MaxAccountStatementLimit)
+	if err != nil {
+		writeAPIError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	order := query.Get("order")
+	switch order {
+	case "":
+		order = APIOrderDesc
+	case APIOrderAsc, APIOrderDesc:
+	default:
+		writeAPIError(w, r, http.StatusBadRequest, errors.New("'order' must be 'asc' or 'desc'"))
+		return
+	}
+
+	var from uint64
+	if cursor := query.Get("cursor"); len(cursor) > 0 {
+		if from, err = strconv.ParseUint(cursor, 10, 64); err != nil || from < 1 {
+			writeAPIError(w, r, http.StatusBadRequest, errors.New("'cursor' must be the sequence of entry"))
+			return
+		}
+	}
+
+	var ba *BlockAccount
+	if ba, err = GetBlockAccount(nr.storage, address); err != nil {
+		writeAPIError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	response := AccountStatementResponse{
+		Address: address,
+		Order:   order,
+		Balance: ba.GetBalance(),
+		Entries: []AccountStatementEntry{},
+	}
+
+	if query.Get("verify") == "1" {
+		var balance int64
+		if balance, err = GetLedgerBalance(nr.storage, address); err != nil {
+			writeAPIError(w, r, http.StatusInternalServerError, err)
+			return
+		}
+		response.LedgerBalance = &balance
+	}
+
+	iterFunc, closeFunc := GetLedgerEntriesByAccount(nr.storage, address, from, order == APIOrderDesc)
+	defer closeFunc()
+
+	for {
+		entry, hasNext := iterFunc()
+		if !hasNext {
+			break
+		}
+		if len(response.Entries) == limit {
+			response.NextCursor = strconv.FormatUint(response.Entries[limit-1].Sequence, 10)
+			break
+		}
+
+		side := AccountStatementSideCredit
+		if entry.Debit == address {
+			side = AccountStatementSideDebit
+		}
+		response.Entries = append(response.Entries, AccountStatementEntry{LedgerEntry: entry, Side: side})
+	}
+
+	writeAPIJSON(w, http.StatusOK, response)
+}
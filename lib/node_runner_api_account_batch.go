@@ -0,0 +1,87 @@
+package sebak
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+const PostAccountsBatchGetPattern string = "/accounts:batchGet"
+
+// MaxAccountsBatchGetAddresses is the maximum number of addresses in one
+// request.
+const MaxAccountsBatchGetAddresses int = 100
+
+// MaxAccountsBatchGetRequestSize is the maximum size of the body of request;
+// enough for `MaxAccountsBatchGetAddresses` addresses.
+const MaxAccountsBatchGetRequestSize int64 = 16 * 1024
+
+type AccountsBatchGetRequest struct {
+	Addresses []string `json:"addresses"`
+}
+
+// AccountsBatchGetResponse has the found accounts in the order of request;
+// the addresses, which are not found are in `NotFound`.
+type AccountsBatchGetResponse struct {
+	Accounts []AccountResponse `json:"accounts"`
+	NotFound []string          `json:"not_found"`
+}
+
+// handleAPIAccountsBatchGet returns the balances and checkpoints of the
+// accounts of request at once, so the wallets of many keys do not need to
+// request them one by one. The invalid address fails the whole request.
+func (nr *NodeRunner) handleAPIAccountsBatchGet(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		writeAPIError(w, r, http.StatusMethodNotAllowed, nil)
+		return
+	}
+
+	var request AccountsBatchGetRequest
+	if err := json.NewDecoder(io.LimitReader(r.Body, MaxAccountsBatchGetRequestSize)).Decode(&request); err != nil {
+		writeAPIError(w, r, http.StatusBadRequest, err)
+		return
+	}
+	if len(request.Addresses) < 1 {
+		writeAPIError(w, r, http.StatusBadRequest, errors.New("'addresses' must not be empty"))
+		return
+	} else if len(request.Addresses) > MaxAccountsBatchGetAddresses {
+		writeAPIError(
+			w, r, http.StatusBadRequest,
+			fmt.Errorf("'addresses' must not be more than %d", MaxAccountsBatchGetAddresses),
+		)
+		return
+	}
+
+	response := AccountsBatchGetResponse{
+		Accounts: []AccountResponse{},
+		NotFound: []string{},
+	}
+	for _, s := range request.Addresses {
+		parsed, err := ParseAccountAddress(s)
+		if err != nil {
+			writeAPIError(w, r, http.StatusBadRequest, fmt.Errorf("invalid address, '%s'", s))
+			return
+		}
+
+		address := parsed.String()
+		exists, err := ExistBlockAccount(nr.storage, address)
+		if err != nil {
+			writeAPIError(w, r, http.StatusInternalServerError, err)
+			return
+		} else if !exists {
+			response.NotFound = append(response.NotFound, address)
+			continue
+		}
+
+		ba, err := GetBlockAccount(nr.storage, address)
+		if err != nil {
+			writeAPIError(w, r, http.StatusInternalServerError, err)
+			return
+		}
+		response.Accounts = append(response.Accounts, NewAccountResponse(ba))
+	}
+
+	writeAPIJSON(w, http.StatusOK, response)
+}
@@ -0,0 +1,35 @@
+package sebak
+
+import (
+	"errors"
+	"net/http"
+)
+
+const GetAccountSignersSubPattern string = "signers"
+
+type AccountSignersResponse struct {
+	Address      string          `json:"address"`
+	MasterWeight int             `json:"master_weight"`
+	Signers      []AccountSigner `json:"signers"`
+	Threshold    int             `json:"threshold"`
+}
+
+// handleAPIAccountSigners returns the signers of account; the account without
+// the additional signers is not found.
+func (nr *NodeRunner) handleAPIAccountSigners(w http.ResponseWriter, r *http.Request, address string) {
+	signers, found, err := GetAccountSigners(nr.storage, address)
+	if err != nil {
+		writeAPIError(w, r, http.StatusInternalServerError, err)
+		return
+	} else if !found {
+		writeAPIError(w, r, http.StatusNotFound, errors.New("account has no signers"))
+		return
+	}
+
+	writeAPIJSON(w, http.StatusOK, AccountSignersResponse{
+		Address:      signers.Address,
+		MasterWeight: signers.MasterWeight,
+		Signers:      signers.Signers,
+		Threshold:    signers.Threshold,
+	})
+}
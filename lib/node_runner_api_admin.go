@@ -0,0 +1,380 @@
+package sebak
+
+import (
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	logging "github.com/inconshreveable/log15"
+
+	"boscoin.io/sebak/lib/network"
+)
+
+const GetAdminForksPattern string = "/admin/forks"
+
+// The admin endpoints are served only by the admin listener of
+// `AdminConfig`, apart from the public API.
+const (
+	AdminPeersPattern    string = "/admin/peers"
+	AdminLogLevelPattern string = "/admin/log-level"
+	AdminMempoolPattern  string = "/admin/mempool"
+	AdminResyncPattern   string = "/admin/resync"
+	AdminBansPattern     string = "/admin/bans"
+)
+
+const (
+	DefaultForksLimit int = 20
+	MaxForksLimit     int = 100
+
+	DefaultAdminMempoolLimit int = 100
+	MaxAdminMempoolLimit     int = 1000
+)
+
+// MaxAdminRequestSize is the maximum size of the body of admin request.
+const MaxAdminRequestSize int64 = 4 * 1024
+
+// AdminConfig is the admin listener for the node management. The requests
+// must be authorized by the bearer `Token`, the client certificate signed by
+// `ClientCAs`, or both of them.
+//   - `Addr`: the address to listen, like '127.0.0.1:12346'
+//   - `LogHandler`: the log handler of node, whose level is changed by
+//     '/admin/log-level'; nil if the level can not be changed
+type AdminConfig struct {
+	Addr        string
+	TLSCertFile string
+	TLSKeyFile  string
+	Token       string
+	ClientCAs   *x509.CertPool
+	LogHandler  *LogLevelHandler
+}
+
+func (c AdminConfig) IsEmpty() bool {
+	return len(c.Addr) < 1
+}
+
+// SetAdminConfig enables the admin listener; it must be called before the
+// node starts.
+func (nr *NodeRunner) SetAdminConfig(config AdminConfig) error {
+	if !config.IsEmpty() && len(config.Token) < 1 && config.ClientCAs == nil {
+		return errors.New("admin listener must have the token or the client CAs")
+	}
+
+	nr.adminConfig = config
+
+	return nil
+}
+
+func (nr *NodeRunner) AdminConfig() AdminConfig {
+	return nr.adminConfig
+}
+
+// AdminHandlers returns the handlers of the admin listener by their path
+// pattern; every handler checks the token.
+func (nr *NodeRunner) AdminHandlers() map[string]http.HandlerFunc {
+	handlers := map[string]http.HandlerFunc{
+		APIVersionPrefix + GetAdminForksPattern:    nr.handleAPIAdminForks,
+		APIVersionPrefix + AdminPeersPattern:       nr.handleAdminPeers,
+		APIVersionPrefix + AdminPeersPattern + "/": nr.handleAdminPeer,
+		APIVersionPrefix + AdminLogLevelPattern:    nr.handleAdminLogLevel,
+		APIVersionPrefix + AdminMempoolPattern:     nr.handleAdminMempool,
+		APIVersionPrefix + AdminResyncPattern:      nr.handleAdminResync,
+		APIVersionPrefix + AdminBansPattern:        nr.handleAdminBans,
+		APIVersionPrefix + AdminBansPattern + "/":  nr.handleAdminBan,
+	}
+	for pattern, handler := range handlers {
+		handlers[pattern] = nr.adminAuthorized(handler)
+	}
+
+	return handlers
+}
+
+// adminAuthorized refuses the request without the token; the client
+// certificate is already verified in the TLS handshake.
+func (nr *NodeRunner) adminAuthorized(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := nr.adminConfig.Token
+		if len(token) > 0 && subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte("Bearer "+token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeAPIError(w, r, http.StatusUnauthorized, errors.New("invalid token"))
+			return
+		}
+
+		handler(w, r)
+	}
+}
+
+// startAdminServer serves the admin listener over TLS; with `ClientCAs`, the
+// client certificate is required.
+func (nr *NodeRunner) startAdminServer() {
+	if nr.adminConfig.IsEmpty() {
+		return
+	}
+
+	mux := http.NewServeMux()
+	for pattern, handler := range nr.AdminHandlers() {
+		mux.HandleFunc(pattern, handler)
+	}
+
+	server := &http.Server{
+		Addr:              nr.adminConfig.Addr,
+		Handler:           mux,
+		ReadHeaderTimeout: time.Second * 5,
+		TLSConfig:         &tls.Config{MinVersion: tls.VersionTLS12},
+	}
+	if nr.adminConfig.ClientCAs != nil {
+		server.TLSConfig.ClientAuth = tls.RequireAndVerifyClientCert
+		server.TLSConfig.ClientCAs = nr.adminConfig.ClientCAs
+	}
+
+	nr.log.Info("admin listener started", "addr", nr.adminConfig.Addr)
+	go func() {
+		if err := server.ListenAndServeTLS(nr.adminConfig.TLSCertFile, nr.adminConfig.TLSKeyFile); err != nil {
+			nr.log.Error("admin listener stopped", "error", err)
+		}
+	}()
+}
+
+type ForksResponse struct {
+	Detected  uint64         `json:"detected"` // since the node started
+	Evidences []ForkEvidence `json:"evidences"`
+}
+
+// handleAPIAdminForks returns the fork evidences from the highest block.
+func (nr *NodeRunner) handleAPIAdminForks(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		writeAPIError(w, r, http.StatusMethodNotAllowed, nil)
+		return
+	}
+
+	limit, err := parseAPILimit(r, DefaultForksLimit, MaxForksLimit)
+	if err != nil {
+		writeAPIError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	evidences, err := GetForkEvidences(nr.storage, limit)
+	if err != nil {
+		writeAPIError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	if evidences == nil {
+		evidences = []ForkEvidence{}
+	}
+
+	writeAPIJSON(w, http.StatusOK, ForksResponse{
+		Detected:  nr.ForksDetected(),
+		Evidences: evidences,
+	})
+}
+
+// handleAdminPeers returns the validators and the known peers with 'GET', and
+// adds the peer to the address book with 'POST' of `PeerAddress`; the added
+// peer is seen now.
+func (nr *NodeRunner) handleAdminPeers(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		writeAPIJSON(w, http.StatusOK, NodePeersResponse{
+			ClockSkew:       nr.ClockSkew(),
+			ClockSkewBudget: nr.ClockSkewBudget(),
+			Peers:           nr.nodePeers(),
+			KnownPeers:      nr.addressBook.Peers(),
+		})
+	case "POST":
+		var peer PeerAddress
+		if err := json.NewDecoder(io.LimitReader(r.Body, MaxAdminRequestSize)).Decode(&peer); err != nil {
+			writeAPIError(w, r, http.StatusBadRequest, err)
+			return
+		}
+		peer.Seen = time.Now().Format(time.RFC3339Nano)
+		if err := peer.IsWellFormed(); err != nil {
+			writeAPIError(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		nr.addressBook.Add(peer)
+		nr.log.Info("peer added by admin", "peer", peer.Address, "endpoint", peer.Endpoint)
+		writeAPIJSON(w, http.StatusOK, peer)
+	default:
+		writeAPIError(w, r, http.StatusMethodNotAllowed, nil)
+	}
+}
+
+// handleAdminPeer removes the peer of '/admin/peers/{address}' from the
+// address book with 'DELETE'; the validators can not be removed.
+func (nr *NodeRunner) handleAdminPeer(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "DELETE" {
+		writeAPIError(w, r, http.StatusMethodNotAllowed, nil)
+		return
+	}
+
+	address, sub := SplitAPIPath(r.URL.Path, AdminPeersPattern+"/")
+	if len(address) < 1 || len(sub) > 0 {
+		writeAPIError(w, r, http.StatusNotFound, nil)
+		return
+	}
+	if !nr.addressBook.Remove(address) {
+		writeAPIError(w, r, http.StatusNotFound, errors.New("peer not found"))
+		return
+	}
+
+	nr.log.Info("peer removed by admin", "peer", address)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type AdminLogLevel struct {
+	Level string `json:"level"`
+}
+
+// handleAdminLogLevel returns the log level with 'GET' and changes it with
+// 'PUT' of `AdminLogLevel`, like `{"level": "debug"}`.
+func (nr *NodeRunner) handleAdminLogLevel(w http.ResponseWriter, r *http.Request) {
+	handler := nr.adminConfig.LogHandler
+	if handler == nil {
+		writeAPIError(w, r, http.StatusNotFound, errors.New("log level can not be changed"))
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+	case "PUT":
+		var request AdminLogLevel
+		if err := json.NewDecoder(io.LimitReader(r.Body, MaxAdminRequestSize)).Decode(&request); err != nil {
+			writeAPIError(w, r, http.StatusBadRequest, err)
+			return
+		}
+		level, err := logging.LvlFromString(request.Level)
+		if err != nil {
+			writeAPIError(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		nr.log.Info("log level changed by admin", "from", handler.Level(), "to", level)
+		handler.SetLevel(level)
+	default:
+		writeAPIError(w, r, http.StatusMethodNotAllowed, nil)
+		return
+	}
+
+	writeAPIJSON(w, http.StatusOK, AdminLogLevel{Level: handler.Level().String()})
+}
+
+type AdminMempoolEntry struct {
+	Hash       string `json:"hash"`
+	Source     string `json:"source"`
+	Fee        Amount `json:"fee"`
+	Checkpoint string `json:"checkpoint"`
+	Operations int    `json:"operations"`
+	Received   string `json:"received"`
+}
+
+type AdminMempoolResponse struct {
+	Size          int                 `json:"size"`
+	MaxSize       int                 `json:"max_size"`
+	MaxPerAccount int                 `json:"max_per_account"`
+	Transactions  []AdminMempoolEntry `json:"transactions"`
+}
+
+// handleAdminMempool returns the transactions in pool in the order, which
+// they are proposed.
+func (nr *NodeRunner) handleAdminMempool(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		writeAPIError(w, r, http.StatusMethodNotAllowed, nil)
+		return
+	}
+
+	limit, err := parseAPILimit(r, DefaultAdminMempoolLimit, MaxAdminMempoolLimit)
+	if err != nil {
+		writeAPIError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	items := nr.transactionPool.Ordered(nr.transactionOrderingPolicy)
+
+	response := AdminMempoolResponse{
+		Size:         len(items),
+		Transactions: []AdminMempoolEntry{},
+	}
+	response.MaxSize, response.MaxPerAccount = nr.transactionPool.Limits()
+	for _, item := range items {
+		if len(response.Transactions) == limit {
+			break
+		}
+		tx := item.Transaction
+		response.Transactions = append(response.Transactions, AdminMempoolEntry{
+			Hash:       tx.GetHash(),
+			Source:     tx.B.Source,
+			Fee:        tx.B.Fee,
+			Checkpoint: tx.B.Checkpoint,
+			Operations: len(tx.B.Operations),
+			Received:   item.Received.Format(time.RFC3339Nano),
+		})
+	}
+
+	writeAPIJSON(w, http.StatusOK, response)
+}
+
+// handleAdminResync drops the connections to the validators, so they are
+// connected again in a second, exchanges the peers and syncs the missing
+// blocks at once.
+func (nr *NodeRunner) handleAdminResync(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		writeAPIError(w, r, http.StatusMethodNotAllowed, nil)
+		return
+	}
+
+	nr.log.Info("resync triggered by admin")
+	nr.connectionManager.ResetClients()
+	go nr.ExchangePeers()
+	go func() {
+		if applied, err := nr.SyncBlocks(); err != nil {
+			nr.log.Error("failed to sync blocks", "error", err, "applied", applied)
+		}
+	}()
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+type AdminBansResponse struct {
+	Bans       []sebaknetwork.PeerBan `json:"bans"`
+	Greylisted []string               `json:"greylisted"`
+}
+
+// handleAdminBans returns the banned and the greylisted IPs of peers.
+func (nr *NodeRunner) handleAdminBans(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		writeAPIError(w, r, http.StatusMethodNotAllowed, nil)
+		return
+	}
+
+	writeAPIJSON(w, http.StatusOK, AdminBansResponse{
+		Bans:       nr.peerBans.Bans(),
+		Greylisted: nr.peerBans.Greylisted(),
+	})
+}
+
+// handleAdminBan unbans the IP of '/admin/bans/{ip}' with 'DELETE'; the
+// offenses of the IP are also forgotten.
+func (nr *NodeRunner) handleAdminBan(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "DELETE" {
+		writeAPIError(w, r, http.StatusMethodNotAllowed, nil)
+		return
+	}
+
+	ip, sub := SplitAPIPath(r.URL.Path, AdminBansPattern+"/")
+	if len(ip) < 1 || len(sub) > 0 {
+		writeAPIError(w, r, http.StatusNotFound, nil)
+		return
+	}
+	if !nr.peerBans.Unban(ip) {
+		writeAPIError(w, r, http.StatusNotFound, errors.New("ip is not banned"))
+		return
+	}
+
+	nr.log.Info("peer unbanned by admin", "ip", ip)
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,106 @@
+package sebak
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	logging "github.com/inconshreveable/log15"
+	"github.com/stellar/go/keypair"
+
+	"boscoin.io/sebak/lib/network"
+)
+
+func TestNodeRunnerAdminAPI(t *testing.T) {
+	defer sebaknetwork.CleanUpMemoryNetwork()
+
+	nodeRunners := createNodeRunners(1)
+	nr := nodeRunners[0]
+
+	if err := nr.SetAdminConfig(AdminConfig{Addr: "127.0.0.1:0"}); err == nil {
+		t.Error("admin listener without the token and the client CAs must be refused")
+		return
+	}
+
+	logHandler := NewLogLevelHandler(logging.LvlInfo, logging.StreamHandler(os.Stdout, logging.TerminalFormat()))
+	if err := nr.SetAdminConfig(AdminConfig{Addr: "127.0.0.1:0", Token: "secret", LogHandler: logHandler}); err != nil {
+		t.Error(err)
+		return
+	}
+
+	kp, _ := keypair.Random()
+	tx := makeTransactionPayment(kp, testMakeBlockAccount().Address, Amount(1))
+	if err := nr.TransactionPool().Add(tx); err != nil {
+		t.Error(err)
+		return
+	}
+
+	handlers := nr.AdminHandlers()
+	request := func(method, pattern, path, body string) (w *httptest.ResponseRecorder) {
+		r := httptest.NewRequest(method, APIVersionPrefix+path, strings.NewReader(body))
+		r.Header.Set("Authorization", "Bearer secret")
+		w = httptest.NewRecorder()
+		handlers[APIVersionPrefix+pattern](w, r)
+		return
+	}
+
+	w := httptest.NewRecorder()
+	handlers[APIVersionPrefix+AdminMempoolPattern](w, httptest.NewRequest("GET", APIVersionPrefix+AdminMempoolPattern, nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("request without token must be refused: %d", w.Code)
+		return
+	}
+
+	w = request("GET", AdminMempoolPattern, AdminMempoolPattern, "")
+	var mempool AdminMempoolResponse
+	json.Unmarshal(w.Body.Bytes(), &mempool)
+	if w.Code != http.StatusOK || mempool.Size != 1 || mempool.Transactions[0].Hash != tx.GetHash() || mempool.Transactions[0].Source != kp.Address() {
+		t.Errorf("wrong mempool: %d %v", w.Code, mempool)
+		return
+	}
+
+	if w = request("PUT", AdminLogLevelPattern, AdminLogLevelPattern, `{"level": "debug"}`); w.Code != http.StatusOK || logHandler.Level() != logging.LvlDebug {
+		t.Errorf("failed to change log level: %d %s", w.Code, logHandler.Level())
+		return
+	}
+	if w = request("PUT", AdminLogLevelPattern, AdminLogLevelPattern, `{"level": "loud"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("unknown log level must be refused: %d", w.Code)
+		return
+	}
+
+	peer := testMakePeerAddress(time.Now())
+	body, _ := json.Marshal(peer)
+	if w = request("POST", AdminPeersPattern, AdminPeersPattern, string(body)); w.Code != http.StatusOK || nr.AddressBook().Len() != 1 {
+		t.Errorf("failed to add peer: %d", w.Code)
+		return
+	}
+	if w = request("DELETE", AdminPeersPattern+"/", AdminPeersPattern+"/"+peer.Address, ""); w.Code != http.StatusNoContent || nr.AddressBook().Len() != 0 {
+		t.Errorf("failed to remove peer: %d", w.Code)
+		return
+	}
+	if w = request("DELETE", AdminPeersPattern+"/", AdminPeersPattern+"/"+peer.Address, ""); w.Code != http.StatusNotFound {
+		t.Errorf("unknown peer must be not found: %d", w.Code)
+		return
+	}
+
+	for !nr.PeerBans().Offend("1.1.1.1", sebaknetwork.PeerOffenseMalformed) {
+	}
+	w = request("GET", AdminBansPattern, AdminBansPattern, "")
+	var bans AdminBansResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &bans); err != nil || len(bans.Bans) != 1 || bans.Bans[0].IP != "1.1.1.1" {
+		t.Errorf("banned IP must be listed: %s", w.Body.String())
+		return
+	}
+	if w = request("DELETE", AdminBansPattern+"/", AdminBansPattern+"/1.1.1.1", ""); w.Code != http.StatusNoContent || nr.PeerBans().IsBanned("1.1.1.1") {
+		t.Errorf("failed to unban: %d", w.Code)
+		return
+	}
+	if w = request("DELETE", AdminBansPattern+"/", AdminBansPattern+"/1.1.1.1", ""); w.Code != http.StatusNotFound {
+		t.Errorf("IP, which is not banned must be not found: %d", w.Code)
+		return
+	}
+}
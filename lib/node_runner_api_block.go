@@ -0,0 +1,130 @@
+package sebak
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+)
+
+const GetBlocksPattern string = "/blocks/"
+
+// BlockHeaderResponse is the block without the transactions; the light
+// clients can follow the chain by `PrevBlockHash` and check the state by
+// `StateHash`.
+type BlockHeaderResponse struct {
+	Hash             string `json:"hash"`
+	Height           uint64 `json:"height"`
+	PrevBlockHash    string `json:"prev_block_hash"`
+	StateHash        string `json:"state_hash"`
+	Confirmed        string `json:"confirmed"`
+	TransactionCount int    `json:"transaction_count"`
+}
+
+func NewBlockHeaderResponse(b Block) BlockHeaderResponse {
+	return BlockHeaderResponse{
+		Hash:             b.Hash,
+		Height:           b.Height,
+		PrevBlockHash:    b.PrevBlockHash,
+		StateHash:        b.StateHash,
+		Confirmed:        b.Confirmed,
+		TransactionCount: len(b.Transactions),
+	}
+}
+
+type BlockResponse struct {
+	BlockHeaderResponse
+	Transactions []AccountTransactionEntry `json:"transactions"`
+	Results      []TransactionResult       `json:"results"` // the blocks before the results are kept have none
+}
+
+// handleAPIBlock returns the block of '/blocks/{height or hash}' with it's
+// transactions and their results; with 'headerOnly=true', only the header is returned. The
+// finalized block is immutable, so it is cached.
+func (nr *NodeRunner) handleAPIBlock(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		writeAPIError(w, r, http.StatusMethodNotAllowed, nil)
+		return
+	}
+	if nr.serveAPICache(w, r) {
+		return
+	}
+
+	s, sub := SplitAPIPath(r.URL.Path, GetBlocksPattern)
+	if len(s) < 1 || len(sub) > 0 {
+		writeAPIError(w, r, http.StatusNotFound, nil)
+		return
+	}
+
+	id, err := ParseBlockID(s)
+	if err != nil {
+		writeAPIError(w, r, http.StatusNotFound, errors.New("block not found; invalid height or hash"))
+		return
+	}
+
+	var headerOnly bool
+	if v := r.URL.Query().Get("headerOnly"); len(v) > 0 {
+		if headerOnly, err = strconv.ParseBool(v); err != nil {
+			writeAPIError(w, r, http.StatusBadRequest, errors.New("'headerOnly' must be 'true' or 'false'"))
+			return
+		}
+	}
+
+	exists, err := nr.storage.Has(id.StorageKey())
+	if err != nil {
+		writeAPIError(w, r, http.StatusInternalServerError, err)
+		return
+	} else if !exists {
+		writeAPIError(w, r, http.StatusNotFound, errors.New("block not found"))
+		return
+	}
+
+	var b Block
+	if b, err = GetBlockByID(nr.storage, id); err != nil {
+		writeAPIError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	finalized, err := nr.isFinalizedHeight(b.Height)
+	if err != nil {
+		writeAPIError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	write := func(v interface{}) {
+		if finalized {
+			nr.writeAPIImmutableJSON(w, r, v)
+			return
+		}
+		writeAPIJSON(w, http.StatusOK, v)
+	}
+
+	header := NewBlockHeaderResponse(b)
+	if headerOnly {
+		write(header)
+		return
+	}
+
+	response := BlockResponse{
+		BlockHeaderResponse: header,
+		Transactions:        []AccountTransactionEntry{},
+		Results:             []TransactionResult{},
+	}
+	for _, hash := range b.Transactions {
+		var bt BlockTransaction
+		if bt, err = GetBlockTransaction(nr.storage, hash); err != nil {
+			writeAPIError(w, r, http.StatusInternalServerError, err)
+			return
+		}
+		response.Transactions = append(response.Transactions, NewAccountTransactionEntry(bt))
+
+		var tr TransactionResult
+		var found bool
+		if tr, found, err = GetTransactionResult(nr.storage, hash); err != nil {
+			writeAPIError(w, r, http.StatusInternalServerError, err)
+			return
+		} else if found {
+			response.Results = append(response.Results, tr)
+		}
+	}
+
+	write(response)
+}
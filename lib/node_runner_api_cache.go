@@ -0,0 +1,171 @@
+package sebak
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/btcsuite/btcutil/base58"
+)
+
+// The immutable resources, like the finalized blocks and the committed
+// operations never change, so they are served with the 'ETag' and the
+// 'Cache-Control' of `APICacheControlImmutable`; the clients and the proxies
+// keep them and ask again only with 'If-None-Match'. The encoded responses
+// are also kept in `APIResponseCache`, so the explorers, which request the
+// same blocks again and again do not read the storage and encode JSON every
+// time.
+
+const APICacheControlImmutable string = "public, max-age=31536000, immutable"
+
+// DefaultAPICacheSize is the number of the responses in cache by default.
+const DefaultAPICacheSize int = 1000
+
+type apiCacheEntry struct {
+	key  string
+	etag string
+	body []byte
+}
+
+// APIResponseCache is the LRU cache of the encoded responses by the path and
+// query of request.
+type APIResponseCache struct {
+	sync.Mutex
+
+	maxSize int
+	items   map[string]*list.Element
+	lru     *list.List // the recently used first
+}
+
+func NewAPIResponseCache(maxSize int) *APIResponseCache {
+	return &APIResponseCache{
+		maxSize: maxSize,
+		items:   map[string]*list.Element{},
+		lru:     list.New(),
+	}
+}
+
+func (c *APIResponseCache) Get(key string) (etag string, body []byte, found bool) {
+	c.Lock()
+	defer c.Unlock()
+
+	e, found := c.items[key]
+	if !found {
+		return
+	}
+	c.lru.MoveToFront(e)
+
+	entry := e.Value.(apiCacheEntry)
+	etag, body = entry.etag, entry.body
+
+	return
+}
+
+func (c *APIResponseCache) Add(key, etag string, body []byte) {
+	c.Lock()
+	defer c.Unlock()
+
+	if e, found := c.items[key]; found {
+		e.Value = apiCacheEntry{key: key, etag: etag, body: body}
+		c.lru.MoveToFront(e)
+		return
+	}
+
+	c.items[key] = c.lru.PushFront(apiCacheEntry{key: key, etag: etag, body: body})
+	for c.lru.Len() > c.maxSize {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.items, oldest.Value.(apiCacheEntry).key)
+	}
+}
+
+func (c *APIResponseCache) Len() int {
+	c.Lock()
+	defer c.Unlock()
+
+	return c.lru.Len()
+}
+
+func makeAPIETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + base58.Encode(sum[:]) + `"`
+}
+
+func apiCacheKey(r *http.Request) string {
+	return r.URL.Path + "?" + r.URL.RawQuery
+}
+
+// apiETagMatches checks the 'If-None-Match' header; the weak ETag is also
+// matched like the strong one.
+func apiETagMatches(header, etag string) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == etag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// serveAPICache writes the cached response of the request; it returns
+// `false`, if the response is not in cache.
+func (nr *NodeRunner) serveAPICache(w http.ResponseWriter, r *http.Request) bool {
+	if nr.apiCache == nil {
+		return false
+	}
+
+	etag, body, found := nr.apiCache.Get(apiCacheKey(r))
+	if !found {
+		return false
+	}
+	writeAPIImmutable(w, r, etag, body)
+
+	return true
+}
+
+// writeAPIImmutableJSON writes the immutable resource with the 'ETag' and
+// keeps it in cache.
+func (nr *NodeRunner) writeAPIImmutableJSON(w http.ResponseWriter, r *http.Request, v interface{}) {
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(serializeAPIResponse(w, v)); err != nil {
+		writeAPIError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	body := buf.Bytes()
+	etag := makeAPIETag(body)
+	if nr.apiCache != nil {
+		nr.apiCache.Add(apiCacheKey(r), etag, body)
+	}
+	writeAPIImmutable(w, r, etag, body)
+}
+
+func writeAPIImmutable(w http.ResponseWriter, r *http.Request, etag string, body []byte) {
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", APICacheControlImmutable)
+	if apiETagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
+
+// isFinalizedHeight checks whether the block of `height` is not above the
+// finality marker, so it's response never changes.
+func (nr *NodeRunner) isFinalizedHeight(height uint64) (finalized bool, err error) {
+	var f Finality
+	if f, err = GetFinality(nr.storage); err != nil || f.IsEmpty() {
+		return
+	}
+	finalized = height <= f.Height
+
+	return
+}
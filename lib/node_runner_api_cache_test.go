@@ -0,0 +1,81 @@
+package sebak
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"boscoin.io/sebak/lib/network"
+)
+
+func TestAPIResponseCache(t *testing.T) {
+	c := NewAPIResponseCache(2)
+	c.Add("a", `"a"`, []byte("a"))
+	c.Add("b", `"b"`, []byte("b"))
+
+	// 'a' is used recently, so 'b' is evicted
+	c.Get("a")
+	c.Add("c", `"c"`, []byte("c"))
+	if _, _, found := c.Get("b"); found || c.Len() != 2 {
+		t.Error("least recently used response must be evicted")
+		return
+	}
+	if etag, body, found := c.Get("a"); !found || etag != `"a"` || string(body) != "a" {
+		t.Errorf("wrong cached response: %s %s", etag, body)
+		return
+	}
+
+	for header, expected := range map[string]bool{`"a"`: true, `W/"a"`: true, `"b", "a"`: true, "*": true, `"b"`: false, "": false} {
+		if apiETagMatches(header, `"a"`) != expected {
+			t.Errorf("wrong match of '%s'", header)
+			return
+		}
+	}
+}
+
+func TestNodeRunnerAPIBlockCache(t *testing.T) {
+	defer sebaknetwork.CleanUpMemoryNetwork()
+
+	nr := createNodeRunners(1)[0]
+	nr.SetAPIConfig(APIConfig{CacheSize: DefaultAPICacheSize})
+
+	block := NewBlock(Block{}, "state")
+	block.Save(nr.Storage())
+
+	handler := nr.APIHandlers()[APIVersionPrefix+GetBlocksPattern]
+	request := func(etag string) (w *httptest.ResponseRecorder) {
+		r := httptest.NewRequest("GET", APIVersionPrefix+GetBlocksPattern+"1", nil)
+		if len(etag) > 0 {
+			r.Header.Set("If-None-Match", etag)
+		}
+		w = httptest.NewRecorder()
+		handler(w, r)
+		return
+	}
+
+	// not finalized yet
+	if w := request(""); w.Code != http.StatusOK || len(w.Header().Get("ETag")) > 0 || nr.apiCache.Len() != 0 {
+		t.Errorf("block above the finality marker must not be cached: %d %v", w.Code, w.Header())
+		return
+	}
+
+	NewFinality(block).Save(nr.Storage())
+	w := request("")
+	etag := w.Header().Get("ETag")
+	if w.Code != http.StatusOK || len(etag) < 1 || w.Header().Get("Cache-Control") != APICacheControlImmutable || nr.apiCache.Len() != 1 {
+		t.Errorf("finalized block must be cached: %d %v", w.Code, w.Header())
+		return
+	}
+	body := w.Body.String()
+
+	// the cached response is served without storage
+	nr.Storage().Remove(GetBlockKeyHeight(block.Height))
+	if w = request(""); w.Code != http.StatusOK || w.Body.String() != body || w.Header().Get("ETag") != etag {
+		t.Errorf("wrong cached response: %d %s", w.Code, w.Body.String())
+		return
+	}
+	if w = request(etag); w.Code != http.StatusNotModified || w.Body.Len() > 0 {
+		t.Errorf("same ETag must be not modified: %d", w.Code)
+		return
+	}
+}
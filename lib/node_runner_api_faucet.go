@@ -0,0 +1,89 @@
+package sebak
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"boscoin.io/sebak/lib/error"
+	"boscoin.io/sebak/lib/network"
+)
+
+const PostFaucetPattern string = "/faucet"
+
+// MaxFaucetRequestSize is the maximum size of the body of faucet request.
+const MaxFaucetRequestSize int64 = 4 * 1024
+
+type FaucetRequest struct {
+	Address string `json:"address"`
+	Token   string `json:"token"`
+}
+
+type FaucetResponse struct {
+	Hash    string `json:"hash"`
+	Address string `json:"address"`
+	Amount  Amount `json:"amount"`
+}
+
+// SetFaucet enables the faucet endpoint; it must be called before the node
+// starts.
+func (nr *NodeRunner) SetFaucet(faucet *Faucet) {
+	nr.faucet = faucet
+}
+
+func (nr *NodeRunner) Faucet() *Faucet {
+	return nr.faucet
+}
+
+// handleAPIFaucet funds the address of request from the faucet account. The
+// transaction is sent like the transaction from client, so the response is
+// 202 with the hash of transaction; the client can check the transaction by
+// it.
+func (nr *NodeRunner) handleAPIFaucet(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		writeAPIError(w, r, http.StatusMethodNotAllowed, nil)
+		return
+	}
+
+	var request FaucetRequest
+	if err := json.NewDecoder(io.LimitReader(r.Body, MaxFaucetRequestSize)).Decode(&request); err != nil {
+		writeAPIError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	if !nr.IsQuorumReady() {
+		writeAPIError(w, r, http.StatusServiceUnavailable, sebakerror.ErrorNodeNotReady)
+		return
+	}
+
+	ip := nr.apiConfig.ClientIP(r)
+	tx, err := nr.faucet.Fund(nr.storage, nr.transactionPool, request.Address, ip, request.Token)
+	if err != nil {
+		status := http.StatusBadRequest
+		switch err {
+		case sebakerror.ErrorFaucetInvalidToken:
+			status = http.StatusForbidden
+		case sebakerror.ErrorFaucetQuotaExceeded:
+			status = http.StatusTooManyRequests
+		case sebakerror.ErrorBlockAccountDoesNotExists:
+			status = http.StatusServiceUnavailable
+		}
+		writeAPIError(w, r, status, err)
+		return
+	}
+
+	var b []byte
+	if b, err = tx.Serialize(); err != nil {
+		writeAPIError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	nr.network.ReceiveChannel() <- sebaknetwork.Message{Type: sebaknetwork.MessageFromClient, Data: b}
+
+	nr.log.Debug("faucet funds", "address", request.Address, "ip", ip, "transaction", tx.GetHash())
+
+	writeAPIJSON(w, http.StatusAccepted, FaucetResponse{
+		Hash:    tx.GetHash(),
+		Address: request.Address,
+		Amount:  nr.faucet.Config().Amount,
+	})
+}
@@ -0,0 +1,24 @@
+package sebak
+
+import (
+	"net/http"
+)
+
+const GetFeesPattern string = "/fees"
+
+// handleAPIFees returns the fees for the new transaction, which are estimated
+// from the recent blocks and the transaction pool.
+func (nr *NodeRunner) handleAPIFees(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		writeAPIError(w, r, http.StatusMethodNotAllowed, nil)
+		return
+	}
+
+	estimate, err := EstimateFees(nr.storage, nr.transactionPool, nr.transactionOrderingPolicy, FeeEstimationBlocks)
+	if err != nil {
+		writeAPIError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeAPIJSON(w, http.StatusOK, estimate)
+}
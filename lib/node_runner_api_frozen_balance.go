@@ -0,0 +1,42 @@
+package sebak
+
+import (
+	"net/http"
+)
+
+const GetAccountFrozenSubPattern string = "frozen"
+
+type FrozenBalancesResponse struct {
+	Address  string          `json:"address"`
+	Frozen   []FrozenBalance `json:"frozen"`
+	Total    Amount          `json:"total"`
+	Unlocked Amount          `json:"unlocked"` // can be released at the next block
+}
+
+// handleAPIAccountFrozen returns the frozen balances of account; the account,
+// which froze nothing has the empty `frozen`.
+func (nr *NodeRunner) handleAPIAccountFrozen(w http.ResponseWriter, r *http.Request, address string) {
+	fb, _, err := GetFrozenBalances(nr.storage, address)
+	if err != nil {
+		writeAPIError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	height, err := getNextBlockHeight(nr.storage)
+	if err != nil {
+		writeAPIError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	frozen := fb.Frozen
+	if frozen == nil {
+		frozen = []FrozenBalance{}
+	}
+
+	writeAPIJSON(w, http.StatusOK, FrozenBalancesResponse{
+		Address:  address,
+		Frozen:   frozen,
+		Total:    fb.Total(),
+		Unlocked: fb.Unlocked(height),
+	})
+}
@@ -0,0 +1,378 @@
+package sebak
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+
+	"boscoin.io/sebak/lib/graphql"
+	"boscoin.io/sebak/lib/storage"
+)
+
+const GetGraphQLPattern string = "/graphql"
+
+const (
+	DefaultGraphQLPageSize int = 20
+	MaxGraphQLPageSize     int = 100
+
+	// MaxGraphQLRequestSize is the maximum size of the body of POST request.
+	MaxGraphQLRequestSize int64 = 100 * 1024
+)
+
+// graphQLPage is the source of the connection objects, like
+// `TransactionConnection`. `NextCursor` is given as `after` to get the next
+// page.
+type graphQLPage struct {
+	Nodes      []interface{}
+	NextCursor string
+}
+
+// paginateGraphQL reads the items from `next` by the `first` and `after`
+// arguments; `next` returns the item and it's cursor.
+func paginateGraphQL(args sebakgraphql.Args, next func() (interface{}, string, bool)) (page graphQLPage, err error) {
+	var first int
+	if first, err = args.Int("first", DefaultGraphQLPageSize); err != nil {
+		return
+	} else if first < 1 || first > MaxGraphQLPageSize {
+		err = fmt.Errorf("'first' must be between 1 and %d", MaxGraphQLPageSize)
+		return
+	}
+
+	var after string
+	if after, err = args.String("after"); err != nil {
+		return
+	}
+
+	page.Nodes = []interface{}{}
+	found := len(after) < 1
+	var last string
+	for {
+		item, cursor, hasNext := next()
+		if !hasNext {
+			break
+		}
+		if !found {
+			found = cursor == after
+			continue
+		}
+		if len(page.Nodes) == first {
+			page.NextCursor = last
+			break
+		}
+
+		page.Nodes = append(page.Nodes, item)
+		last = cursor
+	}
+
+	return
+}
+
+func newGraphQLConnectionObject(name string, node *sebakgraphql.Object) *sebakgraphql.Object {
+	return sebakgraphql.NewObject(name).
+		AddField("nodes", &sebakgraphql.Field{Type: node, List: true, Resolve: func(source interface{}, args sebakgraphql.Args) (interface{}, error) {
+			return source.(graphQLPage).Nodes, nil
+		}}).
+		AddField("nextCursor", &sebakgraphql.Field{Resolve: func(source interface{}, args sebakgraphql.Args) (interface{}, error) {
+			if cursor := source.(graphQLPage).NextCursor; len(cursor) > 0 {
+				return cursor, nil
+			}
+			return nil, nil
+		}})
+}
+
+// graphQLScalar makes the field of scalar from the getter of source.
+func graphQLScalar(get func(source interface{}) interface{}) *sebakgraphql.Field {
+	return &sebakgraphql.Field{Resolve: func(source interface{}, args sebakgraphql.Args) (interface{}, error) {
+		return get(source), nil
+	}}
+}
+
+var graphQLPageArgs = []string{"first", "after"}
+
+func transactionIteratorGraphQL(iterFunc func() (BlockTransaction, bool)) func() (interface{}, string, bool) {
+	return func() (interface{}, string, bool) {
+		bt, hasNext := iterFunc()
+		return bt, bt.Hash, hasNext
+	}
+}
+
+func operationIteratorGraphQL(iterFunc func() (BlockOperation, bool)) func() (interface{}, string, bool) {
+	return func() (interface{}, string, bool) {
+		bo, hasNext := iterFunc()
+		return bo, bo.Hash, hasNext
+	}
+}
+
+// NewGraphQLSchema makes the schema of the explorer queries over the blocks,
+// transactions, operations and accounts in storage. The lists are paginated
+// by `first` and `after`, and are ordered from the latest except the
+// operations of transaction and the data entries of account, which are in
+// name order.
+func NewGraphQLSchema(st *sebakstorage.LevelDBBackend) *sebakgraphql.Schema {
+	operation := sebakgraphql.NewObject("Operation")
+	transaction := sebakgraphql.NewObject("Transaction")
+	block := sebakgraphql.NewObject("Block")
+	account := sebakgraphql.NewObject("Account")
+	accountData := sebakgraphql.NewObject("AccountData")
+
+	operationConnection := newGraphQLConnectionObject("OperationConnection", operation)
+	transactionConnection := newGraphQLConnectionObject("TransactionConnection", transaction)
+	blockConnection := newGraphQLConnectionObject("BlockConnection", block)
+	accountDataConnection := newGraphQLConnectionObject("AccountDataConnection", accountData)
+
+	getTransaction := func(hash string) (interface{}, error) {
+		if exists, err := ExistBlockTransaction(st, hash); err != nil || !exists {
+			return nil, err
+		}
+		return GetBlockTransaction(st, hash)
+	}
+
+	operation.
+		AddField("hash", graphQLScalar(func(s interface{}) interface{} { return s.(BlockOperation).Hash })).
+		AddField("txHash", graphQLScalar(func(s interface{}) interface{} { return s.(BlockOperation).TxHash })).
+		AddField("type", graphQLScalar(func(s interface{}) interface{} { return s.(BlockOperation).Type })).
+		AddField("source", graphQLScalar(func(s interface{}) interface{} { return s.(BlockOperation).Source })).
+		AddField("target", graphQLScalar(func(s interface{}) interface{} { return s.(BlockOperation).Target })).
+		AddField("amount", graphQLScalar(func(s interface{}) interface{} { return s.(BlockOperation).Amount })).
+		AddField("confirmed", graphQLScalar(func(s interface{}) interface{} { return s.(BlockOperation).Confirmed })).
+		AddField("transaction", &sebakgraphql.Field{Type: transaction, Resolve: func(source interface{}, args sebakgraphql.Args) (interface{}, error) {
+			return getTransaction(source.(BlockOperation).TxHash)
+		}})
+
+	transaction.
+		AddField("hash", graphQLScalar(func(s interface{}) interface{} { return s.(BlockTransaction).Hash })).
+		AddField("source", graphQLScalar(func(s interface{}) interface{} { return s.(BlockTransaction).Source })).
+		AddField("fee", graphQLScalar(func(s interface{}) interface{} { return s.(BlockTransaction).Fee })).
+		AddField("amount", graphQLScalar(func(s interface{}) interface{} { return s.(BlockTransaction).Amount })).
+		AddField("checkpoint", graphQLScalar(func(s interface{}) interface{} { return s.(BlockTransaction).Checkpoint })).
+		AddField("signature", graphQLScalar(func(s interface{}) interface{} { return s.(BlockTransaction).Signature })).
+		AddField("created", graphQLScalar(func(s interface{}) interface{} { return s.(BlockTransaction).Created })).
+		AddField("confirmed", graphQLScalar(func(s interface{}) interface{} { return s.(BlockTransaction).Confirmed })).
+		AddField("operations", &sebakgraphql.Field{Type: operation, List: true, Resolve: func(source interface{}, args sebakgraphql.Args) (interface{}, error) {
+			iterFunc, closeFunc := GetBlockOperationsByTxHash(st, source.(BlockTransaction).Hash, false)
+			defer closeFunc()
+
+			operations := []BlockOperation{}
+			for {
+				bo, hasNext := iterFunc()
+				if !hasNext {
+					break
+				}
+				operations = append(operations, bo)
+			}
+			return operations, nil
+		}})
+
+	block.
+		AddField("hash", graphQLScalar(func(s interface{}) interface{} { return s.(Block).Hash })).
+		AddField("height", graphQLScalar(func(s interface{}) interface{} { return s.(Block).Height })).
+		AddField("prevBlockHash", graphQLScalar(func(s interface{}) interface{} { return s.(Block).PrevBlockHash })).
+		AddField("stateHash", graphQLScalar(func(s interface{}) interface{} { return s.(Block).StateHash })).
+		AddField("confirmed", graphQLScalar(func(s interface{}) interface{} { return s.(Block).Confirmed })).
+		AddField("transactionCount", graphQLScalar(func(s interface{}) interface{} { return len(s.(Block).Transactions) })).
+		AddField("transactions", &sebakgraphql.Field{Type: transactionConnection, Args: graphQLPageArgs, Resolve: func(source interface{}, args sebakgraphql.Args) (interface{}, error) {
+			hashes := source.(Block).Transactions
+			var err error
+			return paginateGraphQL(args, func() (interface{}, string, bool) {
+				for err == nil && len(hashes) > 0 {
+					hash := hashes[0]
+					hashes = hashes[1:]
+
+					var bt BlockTransaction
+					if bt, err = GetBlockTransaction(st, hash); err == nil {
+						return bt, hash, true
+					}
+				}
+				return nil, "", false
+			})
+		}})
+
+	// the value of data entry is in base64 like the default mode of
+	// 'GET /accounts/{address}/data'.
+	accountData.
+		AddField("name", graphQLScalar(func(s interface{}) interface{} { return s.(*BlockAccountData).Name })).
+		AddField("value", graphQLScalar(func(s interface{}) interface{} {
+			return base64.StdEncoding.EncodeToString(s.(*BlockAccountData).Value)
+		})).
+		AddField("updated", graphQLScalar(func(s interface{}) interface{} { return s.(*BlockAccountData).Updated }))
+
+	account.
+		AddField("address", graphQLScalar(func(s interface{}) interface{} { return s.(*BlockAccount).Address })).
+		AddField("balance", graphQLScalar(func(s interface{}) interface{} { return s.(*BlockAccount).Balance })).
+		AddField("checkpoint", graphQLScalar(func(s interface{}) interface{} { return s.(*BlockAccount).Checkpoint })).
+		AddField("transactions", &sebakgraphql.Field{Type: transactionConnection, Args: graphQLPageArgs, Resolve: func(source interface{}, args sebakgraphql.Args) (interface{}, error) {
+			iterFunc, closeFunc := GetBlockTransactionsByAccount(st, source.(*BlockAccount).Address, "", true)
+			defer closeFunc()
+
+			return paginateGraphQL(args, transactionIteratorGraphQL(iterFunc))
+		}}).
+		AddField("operations", &sebakgraphql.Field{Type: operationConnection, Args: graphQLPageArgs, Resolve: func(source interface{}, args sebakgraphql.Args) (interface{}, error) {
+			iterFunc, closeFunc := GetBlockOperationsByAccount(st, source.(*BlockAccount).Address, "", true)
+			defer closeFunc()
+
+			return paginateGraphQL(args, operationIteratorGraphQL(iterFunc))
+		}}).
+		AddField("data", &sebakgraphql.Field{Type: accountDataConnection, Args: []string{"prefix", "first", "after"}, Resolve: func(source interface{}, args sebakgraphql.Args) (interface{}, error) {
+			prefix, err := args.String("prefix")
+			if err != nil {
+				return nil, err
+			}
+
+			iterFunc, closeFunc := GetBlockAccountDataByPrefix(st, source.(*BlockAccount).Address, prefix, "", false)
+			defer closeFunc()
+
+			return paginateGraphQL(args, func() (interface{}, string, bool) {
+				d, hasNext := iterFunc()
+				if !hasNext {
+					return nil, "", false
+				}
+				return d, d.Name, true
+			})
+		}})
+
+	query := sebakgraphql.NewObject("Query")
+	query.
+		AddField("account", &sebakgraphql.Field{Type: account, Args: []string{"address"}, Resolve: func(source interface{}, args sebakgraphql.Args) (interface{}, error) {
+			address, err := args.String("address")
+			if err != nil {
+				return nil, err
+			}
+			if exists, err := ExistBlockAccount(st, address); err != nil || !exists {
+				return nil, err
+			}
+			return GetBlockAccount(st, address)
+		}}).
+		AddField("transaction", &sebakgraphql.Field{Type: transaction, Args: []string{"hash"}, Resolve: func(source interface{}, args sebakgraphql.Args) (interface{}, error) {
+			hash, err := args.String("hash")
+			if err != nil {
+				return nil, err
+			}
+			return getTransaction(hash)
+		}}).
+		AddField("transactions", &sebakgraphql.Field{Type: transactionConnection, Args: graphQLPageArgs, Resolve: func(source interface{}, args sebakgraphql.Args) (interface{}, error) {
+			iterFunc, closeFunc := GetBlockTransactionsByConfirmed(st, true)
+			defer closeFunc()
+
+			return paginateGraphQL(args, transactionIteratorGraphQL(iterFunc))
+		}}).
+		AddField("operation", &sebakgraphql.Field{Type: operation, Args: []string{"hash"}, Resolve: func(source interface{}, args sebakgraphql.Args) (interface{}, error) {
+			hash, err := args.String("hash")
+			if err != nil {
+				return nil, err
+			}
+			if exists, err := ExistBlockOperation(st, hash); err != nil || !exists {
+				return nil, err
+			}
+			return GetBlockOperation(st, hash)
+		}}).
+		AddField("block", &sebakgraphql.Field{Type: block, Args: []string{"hash", "height"}, Resolve: func(source interface{}, args sebakgraphql.Args) (interface{}, error) {
+			hash, err := args.String("hash")
+			if err != nil {
+				return nil, err
+			}
+			height, err := args.Int("height", -1)
+			if err != nil {
+				return nil, err
+			}
+
+			var id BlockID
+			switch {
+			case len(hash) > 0:
+				id = NewBlockIDFromHash(hash)
+			case height > 0:
+				id = NewBlockIDFromHeight(uint64(height))
+			default:
+				return nil, errors.New("'hash' or 'height' must be given")
+			}
+			if exists, err := st.Has(id.StorageKey()); err != nil || !exists {
+				return nil, err
+			}
+			return GetBlockByID(st, id)
+		}}).
+		AddField("latestBlock", &sebakgraphql.Field{Type: block, Resolve: func(source interface{}, args sebakgraphql.Args) (interface{}, error) {
+			b, err := GetLatestBlock(st)
+			if err != nil || b.IsEmpty() {
+				return nil, err
+			}
+			return b, nil
+		}}).
+		AddField("blocks", &sebakgraphql.Field{Type: blockConnection, Args: graphQLPageArgs, Resolve: func(source interface{}, args sebakgraphql.Args) (interface{}, error) {
+			iterFunc, closeFunc := st.GetIterator(BlockPrefixHeight, true)
+			defer closeFunc()
+
+			return paginateGraphQL(args, func() (interface{}, string, bool) {
+				item, hasNext := iterFunc()
+				if !hasNext {
+					return nil, "", false
+				}
+
+				var hash string
+				json.Unmarshal(item.Value, &hash)
+				b, err := GetBlock(st, hash)
+				if err != nil {
+					return nil, "", false
+				}
+				return b, strconv.FormatUint(b.Height, 10), true
+			})
+		}})
+
+	return &sebakgraphql.Schema{Query: query}
+}
+
+// SetGraphQL enables the GraphQL endpoint; it must be called before the node
+// starts.
+func (nr *NodeRunner) SetGraphQL(enabled bool) {
+	if !enabled {
+		nr.graphQLSchema = nil
+		return
+	}
+	nr.graphQLSchema = NewGraphQLSchema(nr.storage)
+}
+
+// handleAPIGraphQL runs the GraphQL query of 'query' of GET request or the
+// JSON body of POST request. Like the other GraphQL servers, the errors of
+// query are returned in the response with 200.
+func (nr *NodeRunner) handleAPIGraphQL(w http.ResponseWriter, r *http.Request) {
+	var request sebakgraphql.Request
+
+	switch r.Method {
+	case "GET":
+		query := r.URL.Query()
+		request.Query = query.Get("query")
+		request.OperationName = query.Get("operationName")
+		if variables := query.Get("variables"); len(variables) > 0 {
+			if err := json.Unmarshal([]byte(variables), &request.Variables); err != nil {
+				writeAPIError(w, r, http.StatusBadRequest, errors.New("'variables' must be JSON object"))
+				return
+			}
+		}
+	case "POST":
+		body, err := ioutil.ReadAll(io.LimitReader(r.Body, MaxGraphQLRequestSize+1))
+		if err != nil {
+			writeAPIError(w, r, http.StatusBadRequest, err)
+			return
+		} else if int64(len(body)) > MaxGraphQLRequestSize {
+			writeAPIError(w, r, http.StatusRequestEntityTooLarge, nil)
+			return
+		}
+		if err = json.Unmarshal(body, &request); err != nil {
+			writeAPIError(w, r, http.StatusBadRequest, err)
+			return
+		}
+	default:
+		writeAPIError(w, r, http.StatusMethodNotAllowed, nil)
+		return
+	}
+
+	if len(request.Query) < 1 {
+		writeAPIError(w, r, http.StatusBadRequest, errors.New("'query' must be given"))
+		return
+	}
+
+	writeAPIJSON(w, http.StatusOK, nr.graphQLSchema.Execute(request))
+}
@@ -0,0 +1,163 @@
+package sebak
+
+import (
+	"fmt"
+	"net/http"
+	"sync/atomic"
+)
+
+// The health endpoints are served at the root, not under `APIVersionPrefix`,
+// so the probes of Kubernetes and the load balancers do not follow the API
+// versions. They are not rate limited.
+const (
+	GetHealthzPattern string = "/healthz"
+	GetReadyzPattern  string = "/readyz"
+)
+
+// ReadinessProbeKey is written and removed by the readiness check to know the
+// storage is writable.
+const ReadinessProbeKey string = "hc-readiness-probe-"
+
+const DefaultReadyMaxBlocksBehind uint64 = 2
+
+// ReadinessConfig decides when the node is ready to serve the traffic.
+//  * `MaxBlocksBehind`: the latest block must not be behind the highest block
+//  announced by the validators more than this
+//  * `MinValidators`: the number of connected validators; 0 is the quorum of
+//  the voting threshold
+type ReadinessConfig struct {
+	MaxBlocksBehind uint64
+	MinValidators   int
+}
+
+func NewDefaultReadinessConfig() ReadinessConfig {
+	return ReadinessConfig{MaxBlocksBehind: DefaultReadyMaxBlocksBehind}
+}
+
+type HealthCheck struct {
+	Name   string `json:"name"`
+	OK     bool   `json:"ok"`
+	Detail string `json:"detail,omitempty"`
+}
+
+type ReadinessResponse struct {
+	Ready  bool          `json:"ready"`
+	State  NodeState     `json:"state"`
+	Checks []HealthCheck `json:"checks"`
+}
+
+// SetReadinessConfig sets the conditions of `/readyz`; it must be called
+// before the node starts.
+func (nr *NodeRunner) SetReadinessConfig(config ReadinessConfig) {
+	nr.readinessConfig = config
+}
+
+func (nr *NodeRunner) ReadinessConfig() ReadinessConfig {
+	return nr.readinessConfig
+}
+
+// NetworkHeight returns the highest block height, which is announced by the
+// validators.
+func (nr *NodeRunner) NetworkHeight() uint64 {
+	return atomic.LoadUint64(&nr.networkHeight)
+}
+
+func (nr *NodeRunner) observeNetworkHeight(height uint64) {
+	for {
+		current := atomic.LoadUint64(&nr.networkHeight)
+		if height <= current || atomic.CompareAndSwapUint64(&nr.networkHeight, current, height) {
+			return
+		}
+	}
+}
+
+func (nr *NodeRunner) checkStorageWritable() (err error) {
+	key := fmt.Sprintf("%s%d", ReadinessProbeKey, atomic.AddUint64(&nr.readinessProbes, 1))
+	if err = nr.storage.New(key, key); err != nil {
+		return
+	}
+
+	return nr.storage.Remove(key)
+}
+
+// Readiness runs the checks of readiness; the node is ready when all of them
+// pass.
+func (nr *NodeRunner) Readiness() (response ReadinessResponse) {
+	response.State = nr.state.State()
+	response.Checks = append(response.Checks, HealthCheck{
+		Name:   "state",
+		OK:     response.State == NodeStateConsensus,
+		Detail: string(response.State),
+	})
+
+	synced := HealthCheck{Name: "synced"}
+	if latest, err := GetLatestBlock(nr.storage); err != nil {
+		synced.Detail = err.Error()
+	} else {
+		networkHeight := nr.NetworkHeight()
+		synced.OK = networkHeight <= latest.Height+nr.readinessConfig.MaxBlocksBehind
+		synced.Detail = fmt.Sprintf("height=%d network-height=%d", latest.Height, networkHeight)
+	}
+	response.Checks = append(response.Checks, synced)
+
+	writable := HealthCheck{Name: "storage", OK: true}
+	if err := nr.checkStorageWritable(); err != nil {
+		writable.OK = false
+		writable.Detail = err.Error()
+	}
+	response.Checks = append(response.Checks, writable)
+
+	connected := nr.connectionManager.CountConnected()
+	validators := HealthCheck{Name: "validators"}
+	if min := nr.readinessConfig.MinValidators; min > 0 {
+		validators.OK = connected >= min
+		validators.Detail = fmt.Sprintf("connected=%d required=%d", connected, min)
+	} else {
+		validators.OK = nr.HasQuorum()
+		validators.Detail = fmt.Sprintf("connected=%d quorum=%d", connected+1, nr.requiredQuorum())
+	}
+	response.Checks = append(response.Checks, validators)
+
+	response.Ready = true
+	for _, check := range response.Checks {
+		if !check.OK {
+			response.Ready = false
+			break
+		}
+	}
+
+	return
+}
+
+// handleAPIHealthz answers while the process is alive and not halted.
+func (nr *NodeRunner) handleAPIHealthz(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" && r.Method != "HEAD" {
+		writeAPIError(w, r, http.StatusMethodNotAllowed, nil)
+		return
+	}
+
+	state := nr.state.State()
+	status := http.StatusOK
+	if state == NodeStateHalted {
+		status = http.StatusServiceUnavailable
+	}
+
+	writeAPIJSON(w, status, map[string]NodeState{"state": state})
+}
+
+// handleAPIReadyz returns 503 with the failed checks until the node is
+// synced, the storage is writable and enough validators are connected.
+func (nr *NodeRunner) handleAPIReadyz(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" && r.Method != "HEAD" {
+		writeAPIError(w, r, http.StatusMethodNotAllowed, nil)
+		return
+	}
+
+	response := nr.Readiness()
+	status := http.StatusOK
+	if !response.Ready {
+		status = http.StatusServiceUnavailable
+	}
+
+	writeAPIJSON(w, status, response)
+}
@@ -0,0 +1,177 @@
+package sebak
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// APIConfig configures how the API is served to the browsers and behind the
+// reverse proxy, like nginx.
+//  * `CORSOrigins`: the origins, which the browsers can call the API from; '*'
+//  allows every origin. Without it, the CORS headers are not sent.
+//  * `TrustedProxies`: the client IP is taken from the 'X-Forwarded-For'
+//  header, only when the request comes through these proxies.
+//  * `BasePath`: the path prefix of the API, like '/sebak'; '/api/v1/...' is
+//  served at '/sebak/api/v1/...'. The node to node messages are not affected.
+//  * `Deprecations`: the deprecated versions with their sunset; the zero time
+//  is no sunset. See `APIVersionGroup`.
+type APIConfig struct {
+	CORSOrigins    []string
+	TrustedProxies []*net.IPNet
+	BasePath       string
+	CacheSize      int // the number of the immutable responses in cache; 0 is disabled
+	Deprecations   map[APIVersion]time.Time
+}
+
+// CORSMaxAge is how long, in seconds, the browser can cache the preflight
+// response.
+const CORSMaxAge int = 600
+
+// ParseTrustedProxies parses the IPs and the CIDRs, like '10.0.0.0/8'.
+func ParseTrustedProxies(proxies []string) (nets []*net.IPNet, err error) {
+	for _, proxy := range proxies {
+		if !strings.Contains(proxy, "/") {
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				err = errors.New("invalid IP: " + proxy)
+				return
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		var n *net.IPNet
+		if _, n, err = net.ParseCIDR(proxy); err != nil {
+			return
+		}
+		nets = append(nets, n)
+	}
+
+	return
+}
+
+// NormalizeAPIBasePath makes the base path start with '/' and have no
+// trailing '/'; the empty path is ''.
+func NormalizeAPIBasePath(path string) string {
+	path = strings.Trim(strings.TrimSpace(path), "/")
+	if len(path) < 1 {
+		return ""
+	}
+
+	return "/" + path
+}
+
+func (c APIConfig) isTrustedProxy(ip string) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, n := range c.TrustedProxies {
+		if n.Contains(parsed) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ClientIP returns the IP of the API client. If the request comes through the
+// trusted proxies, the 'X-Forwarded-For' is followed from the right to the
+// first address, which is not the trusted proxy; the addresses left of it can
+// be forged by the client.
+func (c APIConfig) ClientIP(r *http.Request) string {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+	if !c.isTrustedProxy(ip) {
+		return ip
+	}
+
+	forwarded := strings.Split(strings.Join(r.Header["X-Forwarded-For"], ","), ",")
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		addr := strings.TrimSpace(forwarded[i])
+		if net.ParseIP(addr) == nil {
+			break
+		}
+		ip = addr
+		if !c.isTrustedProxy(addr) {
+			break
+		}
+	}
+
+	return ip
+}
+
+func (c APIConfig) allowedOrigin(origin string) (string, bool) {
+	for _, o := range c.CORSOrigins {
+		if o == "*" {
+			return "*", true
+		}
+		if strings.EqualFold(o, origin) {
+			return origin, true
+		}
+	}
+
+	return "", false
+}
+
+// withCORS sets the CORS headers for the allowed origin and answers the
+// preflight request by itself.
+func (c APIConfig) withCORS(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if len(origin) < 1 {
+			handler(w, r)
+			return
+		}
+
+		w.Header().Add("Vary", "Origin")
+		allowed, ok := c.allowedOrigin(origin)
+		isPreflight := r.Method == "OPTIONS" && len(r.Header.Get("Access-Control-Request-Method")) > 0
+		if !ok {
+			if isPreflight {
+				writeAPIError(w, r, http.StatusForbidden, errors.New("origin is not allowed"))
+				return
+			}
+			handler(w, r)
+			return
+		}
+
+		w.Header().Set("Access-Control-Allow-Origin", allowed)
+		if !isPreflight {
+			w.Header().Set("Access-Control-Expose-Headers", "Retry-After, ETag, Deprecation, Sunset, Link")
+			handler(w, r)
+			return
+		}
+
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Last-Event-ID, "+APIKeyHeader)
+		w.Header().Set("Access-Control-Max-Age", strconv.Itoa(CORSMaxAge))
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// SetAPIConfig sets how the API is served; it must be called before the node
+// starts.
+func (nr *NodeRunner) SetAPIConfig(config APIConfig) {
+	config.BasePath = NormalizeAPIBasePath(config.BasePath)
+	nr.apiConfig = config
+
+	nr.apiCache = nil
+	if config.CacheSize > 0 {
+		nr.apiCache = NewAPIResponseCache(config.CacheSize)
+	}
+}
+
+func (nr *NodeRunner) APIConfig() APIConfig {
+	return nr.apiConfig
+}
@@ -0,0 +1,404 @@
+package sebak
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"boscoin.io/sebak/lib/error"
+)
+
+const PostJSONRPCPattern string = "/rpc"
+
+const JSONRPCVersion string = "2.0"
+
+// MaxJSONRPCBatchSize is the maximum number of requests in one batch.
+const MaxJSONRPCBatchSize int = 50
+
+// The error codes of JSON-RPC 2.0; `JSONRPCErrorNode` is for the errors of
+// node, like the invalid transaction and it's `data` has the `APIError`.
+const (
+	JSONRPCErrorParse          int = -32700
+	JSONRPCErrorInvalidRequest int = -32600
+	JSONRPCErrorMethodNotFound int = -32601
+	JSONRPCErrorInvalidParams  int = -32602
+	JSONRPCErrorInternal       int = -32603
+	JSONRPCErrorNode           int = -32000
+)
+
+const (
+	JSONRPCMethodNetworkID       string = "sebak_networkID"
+	JSONRPCMethodGetAccount      string = "sebak_getAccount"
+	JSONRPCMethodGetTransaction  string = "sebak_getTransaction"
+	JSONRPCMethodGetOperation    string = "sebak_getOperation"
+	JSONRPCMethodGetBlock        string = "sebak_getBlock"
+	JSONRPCMethodGetLatestBlock  string = "sebak_getLatestBlock"
+	JSONRPCMethodSendTransaction string = "sebak_sendTransaction"
+)
+
+// JSONRPCRequest is the request of JSON-RPC 2.0. The request without `ID` is
+// the notification, which has no response. `Params` can be the array by
+// position or the object by name.
+type JSONRPCRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	ID      json.RawMessage `json:"id,omitempty"`
+}
+
+func (req JSONRPCRequest) IsNotification() bool {
+	return len(req.ID) < 1
+}
+
+type JSONRPCError struct {
+	Code    int         `json:"code"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+func (e *JSONRPCError) Error() string {
+	return e.Message
+}
+
+func newJSONRPCError(code int, message string) *JSONRPCError {
+	return &JSONRPCError{Code: code, Message: message}
+}
+
+// newJSONRPCNodeError wraps the error of node; the `data` has the `code` and
+// the `result` of `sebakerror.Error` like the other APIs.
+func newJSONRPCNodeError(status int, err error) *JSONRPCError {
+	e := sebakerror.NewProblem(status, err)
+
+	return &JSONRPCError{Code: JSONRPCErrorNode, Message: e.Detail, Data: e}
+}
+
+type JSONRPCResponse struct {
+	JSONRPC string
+	Result  interface{}
+	Error   *JSONRPCError
+	ID      json.RawMessage
+}
+
+// MarshalJSON keeps the members required by JSON-RPC 2.0; `result` must be
+// given even if it is null unless the error occurred, and `id` is null when it
+// could not be read from the request.
+func (res JSONRPCResponse) MarshalJSON() ([]byte, error) {
+	m := map[string]interface{}{"jsonrpc": JSONRPCVersion}
+	if res.Error != nil {
+		m["error"] = res.Error
+	} else {
+		m["result"] = res.Result
+	}
+	if len(res.ID) > 0 {
+		m["id"] = res.ID
+	} else {
+		m["id"] = nil
+	}
+
+	return json.Marshal(m)
+}
+
+type jsonRPCMethod struct {
+	params  []string // the names of params by position
+	handler func(nr *NodeRunner, params map[string]json.RawMessage) (interface{}, *JSONRPCError)
+}
+
+var jsonRPCMethods = map[string]jsonRPCMethod{
+	JSONRPCMethodNetworkID:       {nil, (*NodeRunner).jsonRPCNetworkID},
+	JSONRPCMethodGetAccount:      {[]string{"address"}, (*NodeRunner).jsonRPCGetAccount},
+	JSONRPCMethodGetTransaction:  {[]string{"hash"}, (*NodeRunner).jsonRPCGetTransaction},
+	JSONRPCMethodGetOperation:    {[]string{"hash"}, (*NodeRunner).jsonRPCGetOperation},
+	JSONRPCMethodGetBlock:        {[]string{"block"}, (*NodeRunner).jsonRPCGetBlock},
+	JSONRPCMethodGetLatestBlock:  {nil, (*NodeRunner).jsonRPCGetLatestBlock},
+	JSONRPCMethodSendTransaction: {[]string{"transaction"}, (*NodeRunner).jsonRPCSendTransaction},
+}
+
+// parseParams maps the params to their names; the positional params are named
+// by the order of `jsonRPCMethod.params`.
+func (m jsonRPCMethod) parseParams(raw json.RawMessage) (params map[string]json.RawMessage, err error) {
+	params = map[string]json.RawMessage{}
+
+	raw = bytes.TrimSpace(raw)
+	if len(raw) < 1 || bytes.Equal(raw, []byte("null")) {
+		return
+	}
+
+	switch raw[0] {
+	case '{':
+		err = json.Unmarshal(raw, &params)
+	case '[':
+		var list []json.RawMessage
+		if err = json.Unmarshal(raw, &list); err != nil {
+			return
+		}
+		if len(list) > len(m.params) {
+			err = fmt.Errorf("too many params; expected %d", len(m.params))
+			return
+		}
+		for i, p := range list {
+			params[m.params[i]] = p
+		}
+	default:
+		err = errors.New("'params' must be array or object")
+	}
+
+	return
+}
+
+func requiredJSONRPCParam(params map[string]json.RawMessage, name string, v interface{}) *JSONRPCError {
+	raw, found := params[name]
+	if !found {
+		return newJSONRPCError(JSONRPCErrorInvalidParams, fmt.Sprintf("'%s' must be given", name))
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		return newJSONRPCError(JSONRPCErrorInvalidParams, fmt.Sprintf("invalid '%s': %v", name, err))
+	}
+
+	return nil
+}
+
+func (nr *NodeRunner) jsonRPCNetworkID(params map[string]json.RawMessage) (interface{}, *JSONRPCError) {
+	return string(nr.networkID), nil
+}
+
+func (nr *NodeRunner) jsonRPCGetAccount(params map[string]json.RawMessage) (interface{}, *JSONRPCError) {
+	var address string
+	if e := requiredJSONRPCParam(params, "address", &address); e != nil {
+		return nil, e
+	}
+	if _, err := ParseAccountAddress(address); err != nil {
+		// the malformed address can not be found
+		return nil, nil
+	}
+
+	exists, err := ExistBlockAccount(nr.storage, address)
+	if err != nil {
+		return nil, newJSONRPCError(JSONRPCErrorInternal, err.Error())
+	} else if !exists {
+		return nil, nil
+	}
+
+	ba, err := GetBlockAccount(nr.storage, address)
+	if err != nil {
+		return nil, newJSONRPCError(JSONRPCErrorInternal, err.Error())
+	}
+
+	return NewAccountResponse(ba), nil
+}
+
+func (nr *NodeRunner) jsonRPCGetTransaction(params map[string]json.RawMessage) (interface{}, *JSONRPCError) {
+	var hash string
+	if e := requiredJSONRPCParam(params, "hash", &hash); e != nil {
+		return nil, e
+	}
+	if _, err := ParseTxHash(hash); err != nil {
+		return nil, nil
+	}
+
+	exists, err := ExistBlockTransaction(nr.storage, hash)
+	if err != nil {
+		return nil, newJSONRPCError(JSONRPCErrorInternal, err.Error())
+	} else if !exists {
+		return nil, nil
+	}
+
+	bt, err := GetBlockTransaction(nr.storage, hash)
+	if err != nil {
+		return nil, newJSONRPCError(JSONRPCErrorInternal, err.Error())
+	}
+
+	return NewAccountTransactionEntry(bt), nil
+}
+
+func (nr *NodeRunner) jsonRPCGetOperation(params map[string]json.RawMessage) (interface{}, *JSONRPCError) {
+	var hash string
+	if e := requiredJSONRPCParam(params, "hash", &hash); e != nil {
+		return nil, e
+	}
+	if _, err := ParseOpID(hash); err != nil {
+		return nil, nil
+	}
+
+	exists, err := ExistBlockOperation(nr.storage, hash)
+	if err != nil {
+		return nil, newJSONRPCError(JSONRPCErrorInternal, err.Error())
+	} else if !exists {
+		return nil, nil
+	}
+
+	bo, err := GetBlockOperation(nr.storage, hash)
+	if err != nil {
+		return nil, newJSONRPCError(JSONRPCErrorInternal, err.Error())
+	}
+
+	return NewOperationResponse(bo), nil
+}
+
+// jsonRPCGetBlock finds the block by it's height, the number or by the
+// `BlockID` string, the height or the hash.
+func (nr *NodeRunner) jsonRPCGetBlock(params map[string]json.RawMessage) (interface{}, *JSONRPCError) {
+	var block interface{}
+	if e := requiredJSONRPCParam(params, "block", &block); e != nil {
+		return nil, e
+	}
+
+	var id BlockID
+	switch v := block.(type) {
+	case float64:
+		if v < 1 || v != float64(uint64(v)) {
+			return nil, newJSONRPCError(JSONRPCErrorInvalidParams, "'block' must be the height or the hash")
+		}
+		id = NewBlockIDFromHeight(uint64(v))
+	case string:
+		var err error
+		if id, err = ParseBlockID(v); err != nil {
+			return nil, newJSONRPCError(JSONRPCErrorInvalidParams, "'block' must be the height or the hash")
+		}
+	default:
+		return nil, newJSONRPCError(JSONRPCErrorInvalidParams, "'block' must be the height or the hash")
+	}
+
+	exists, err := nr.storage.Has(id.StorageKey())
+	if err != nil {
+		return nil, newJSONRPCError(JSONRPCErrorInternal, err.Error())
+	} else if !exists {
+		return nil, nil
+	}
+
+	b, err := GetBlockByID(nr.storage, id)
+	if err != nil {
+		return nil, newJSONRPCError(JSONRPCErrorInternal, err.Error())
+	}
+
+	return b, nil
+}
+
+func (nr *NodeRunner) jsonRPCGetLatestBlock(params map[string]json.RawMessage) (interface{}, *JSONRPCError) {
+	b, err := GetLatestBlock(nr.storage)
+	if err != nil {
+		return nil, newJSONRPCError(JSONRPCErrorInternal, err.Error())
+	} else if len(b.Hash) < 1 {
+		return nil, nil
+	}
+
+	return b, nil
+}
+
+// jsonRPCSendTransaction submits the transaction like 'POST /transactions';
+// the result is the `TransactionSubmitResponse`.
+func (nr *NodeRunner) jsonRPCSendTransaction(params map[string]json.RawMessage) (interface{}, *JSONRPCError) {
+	raw, found := params["transaction"]
+	if !found {
+		return nil, newJSONRPCError(JSONRPCErrorInvalidParams, "'transaction' must be given")
+	}
+
+	response, status, err := nr.submitTransaction(raw)
+	if err != nil {
+		return nil, newJSONRPCNodeError(status, err)
+	}
+
+	return response, nil
+}
+
+func (nr *NodeRunner) handleJSONRPCRequest(raw json.RawMessage) (response JSONRPCResponse, ok bool) {
+	response = JSONRPCResponse{JSONRPC: JSONRPCVersion}
+
+	var req JSONRPCRequest
+	if err := json.Unmarshal(raw, &req); err != nil {
+		response.Error = newJSONRPCError(JSONRPCErrorInvalidRequest, err.Error())
+		return response, true
+	}
+	response.ID = req.ID
+
+	if req.JSONRPC != JSONRPCVersion || len(req.Method) < 1 {
+		response.Error = newJSONRPCError(JSONRPCErrorInvalidRequest, "'jsonrpc' must be '2.0' and 'method' must be given")
+		return response, true
+	}
+
+	method, found := jsonRPCMethods[req.Method]
+	if !found {
+		response.Error = newJSONRPCError(JSONRPCErrorMethodNotFound, fmt.Sprintf("method not found: %s", req.Method))
+		return response, !req.IsNotification()
+	}
+
+	params, err := method.parseParams(req.Params)
+	if err != nil {
+		response.Error = newJSONRPCError(JSONRPCErrorInvalidParams, err.Error())
+		return response, !req.IsNotification()
+	}
+
+	response.Result, response.Error = method.handler(nr, params)
+
+	return response, !req.IsNotification()
+}
+
+// handleAPIJSONRPC serves the JSON-RPC 2.0 requests, so the tools speaking
+// JSON-RPC can query the node and submit the transaction; the batch of
+// requests is also allowed. The methods are,
+//  * `sebak_networkID`
+//  * `sebak_getAccount(address)`
+//  * `sebak_getTransaction(hash)`
+//  * `sebak_getOperation(hash)`
+//  * `sebak_getBlock(block)`: `block` is the height or the hash
+//  * `sebak_getLatestBlock`
+//  * `sebak_sendTransaction(transaction)`
+// If the account, the transaction, the operation or the block is not found,
+// the `result` is null.
+func (nr *NodeRunner) handleAPIJSONRPC(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		writeAPIError(w, r, http.StatusMethodNotAllowed, nil)
+		return
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, MaxTransactionRequestSize+1))
+	if err != nil {
+		writeAPIError(w, r, http.StatusBadRequest, err)
+		return
+	} else if int64(len(body)) > MaxTransactionRequestSize {
+		writeAPIError(w, r, http.StatusRequestEntityTooLarge, nil)
+		return
+	}
+
+	body = bytes.TrimSpace(body)
+	if len(body) < 1 || body[0] != '[' {
+		if !json.Valid(body) {
+			writeAPIJSON(w, http.StatusOK, JSONRPCResponse{Error: newJSONRPCError(JSONRPCErrorParse, "parse error")})
+			return
+		}
+		response, ok := nr.handleJSONRPCRequest(body)
+		if !ok {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		writeAPIJSON(w, http.StatusOK, response)
+		return
+	}
+
+	var batch []json.RawMessage
+	if err = json.Unmarshal(body, &batch); err != nil {
+		writeAPIJSON(w, http.StatusOK, JSONRPCResponse{Error: newJSONRPCError(JSONRPCErrorParse, "parse error")})
+		return
+	}
+	if len(batch) < 1 || len(batch) > MaxJSONRPCBatchSize {
+		e := newJSONRPCError(JSONRPCErrorInvalidRequest, fmt.Sprintf("the batch must have 1 to %d requests", MaxJSONRPCBatchSize))
+		writeAPIJSON(w, http.StatusOK, JSONRPCResponse{Error: e})
+		return
+	}
+
+	responses := []JSONRPCResponse{}
+	for _, raw := range batch {
+		if response, ok := nr.handleJSONRPCRequest(raw); ok {
+			responses = append(responses, response)
+		}
+	}
+	if len(responses) < 1 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	writeAPIJSON(w, http.StatusOK, responses)
+}
@@ -0,0 +1,393 @@
+package sebak
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"boscoin.io/sebak/lib/network"
+)
+
+const (
+	GetNodePattern         string = "/node"
+	GetNodeMetricsPattern  string = "/node/metrics"
+	GetNodePeersPattern    string = "/node/peers"
+	GetNodeUpgradesPattern string = "/node/upgrades"
+)
+
+type NodeResponse struct {
+	Address     string               `json:"address"`
+	Alias       string               `json:"alias"`
+	Endpoint    string               `json:"endpoint"`
+	State       NodeState            `json:"state"`
+	StateSince  string               `json:"state_since"`
+	Transitions map[NodeState]uint64 `json:"transitions"`
+	Height      uint64               `json:"height"`
+	Validators  int                  `json:"validators"`
+	Connected   int                  `json:"connected"`
+	ClockSkew   time.Duration        `json:"clock_skew"`
+
+	ProposerTimeout time.Duration          `json:"proposer_timeout"` // 0 if the view change is disabled
+	Protocol        sebaknetwork.Handshake `json:"protocol"`
+}
+
+// NodePeerResponse is the validator, which the node connects to. The clock
+// offset is the clock of the validator minus the local clock, and it is
+// omitted until it is estimated.
+type NodePeerResponse struct {
+	Address     string         `json:"address"`
+	Alias       string         `json:"alias"`
+	Endpoint    string         `json:"endpoint"`
+	Connected   bool           `json:"connected"`
+	ClockOffset *time.Duration `json:"clock_offset,omitempty"`
+	RTT         *time.Duration `json:"rtt,omitempty"`
+	Measured    string         `json:"measured,omitempty"`
+
+	// BallotLatency is the latency of sending the ballots to the validator,
+	// which adapts the proposer timeout.
+	BallotLatency *time.Duration `json:"ballot_latency,omitempty"`
+
+	// FanoutRegion is the order of the region of the validator, which the
+	// ballots are sent to from 0 by the `latency` fanout policy.
+	FanoutRegion *int `json:"fanout_region,omitempty"`
+
+	// Circuit is the state of the circuit breaker of the pooled client to the
+	// validator; the messages are not sent while it is `open`.
+	Circuit  sebaknetwork.CircuitState `json:"circuit,omitempty"`
+	Failures int                       `json:"failures,omitempty"`
+
+	// Protocol is the protocol negotiated with the validator at the last
+	// connect; it is omitted until the validator is connected.
+	Protocol *sebaknetwork.Handshake `json:"protocol,omitempty"`
+
+	// Health is scored by the connection, the missed ballots, the failed
+	// requests and the latency of ballots.
+	Health sebaknetwork.PeerScore `json:"health"`
+}
+
+type NodePeersResponse struct {
+	ClockSkew       time.Duration      `json:"clock_skew"`
+	ClockSkewBudget time.Duration      `json:"clock_skew_budget"`
+	Partitioned     bool               `json:"partitioned"`
+	Peers           []NodePeerResponse `json:"peers"`
+	KnownPeers      []PeerAddress      `json:"known_peers"`
+}
+
+// handleAPINode returns the lifecycle state of node and the state of
+// consensus, which it is in.
+func (nr *NodeRunner) handleAPINode(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		writeAPIError(w, r, http.StatusMethodNotAllowed, nil)
+		return
+	}
+
+	latest, err := GetLatestBlock(nr.storage)
+	if err != nil {
+		writeAPIError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	var endpoint string
+	if nr.currentNode.Endpoint() != nil {
+		endpoint = nr.currentNode.Endpoint().String()
+	}
+
+	writeAPIJSON(w, http.StatusOK, NodeResponse{
+		Address:     nr.currentNode.Address(),
+		Alias:       nr.currentNode.Alias(),
+		Endpoint:    endpoint,
+		State:       nr.state.State(),
+		StateSince:  nr.state.Since().Format(time.RFC3339Nano),
+		Transitions: nr.state.Transitions(),
+		Height:      latest.Height,
+		Validators:  len(nr.currentNode.GetValidators()),
+		Connected:   nr.connectionManager.CountConnected(),
+		ClockSkew:   nr.ClockSkew(),
+
+		ProposerTimeout: nr.ProposerTimeout(),
+		Protocol:        nr.connectionManager.Handshake(),
+	})
+}
+
+func (nr *NodeRunner) nodePeers() (peers []NodePeerResponse) {
+	clocks := nr.connectionManager.PeerClocks()
+	latencies := nr.connectionManager.PeerLatencies()
+	health := nr.connectionManager.ClientPool().Health()
+	scores := nr.connectionManager.PeerScores()
+	handshakes := nr.connectionManager.PeerHandshakes()
+
+	regions := map[string]int{}
+	if nr.connectionManager.FanoutPolicy() == sebaknetwork.FanoutLatency {
+		for i, region := range nr.connectionManager.FanoutRegions() {
+			for _, v := range region {
+				regions[v.Address()] = i
+			}
+		}
+	}
+
+	for _, v := range nr.connectionManager.Validators() {
+		peer := NodePeerResponse{
+			Address:   v.Address(),
+			Alias:     v.Alias(),
+			Connected: nr.connectionManager.IsConnected(v),
+		}
+		if v.Endpoint() != nil { // not discovered yet
+			peer.Endpoint = v.Endpoint().String()
+			if h, ok := health[peer.Endpoint]; ok {
+				peer.Circuit = h.Circuit
+				peer.Failures = h.Failures
+			}
+		}
+		if clock, ok := clocks[v.Address()]; ok {
+			offset, rtt := clock.Offset, clock.RTT
+			peer.ClockOffset = &offset
+			peer.RTT = &rtt
+			peer.Measured = clock.Measured.Format(time.RFC3339Nano)
+		}
+		if latency, ok := latencies[v.Address()]; ok {
+			peer.BallotLatency = &latency
+		}
+		if region, ok := regions[v.Address()]; ok {
+			peer.FanoutRegion = &region
+		}
+		if handshake, ok := handshakes[v.Address()]; ok {
+			peer.Protocol = &handshake
+		}
+		peer.Health = scores[v.Address()]
+		peers = append(peers, peer)
+	}
+	sort.Slice(peers, func(i, j int) bool { return peers[i].Address < peers[j].Address })
+
+	return
+}
+
+// handleAPINodePeers returns the validators with the connection state and the
+// estimated clock offset of each validator.
+func (nr *NodeRunner) handleAPINodePeers(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		writeAPIError(w, r, http.StatusMethodNotAllowed, nil)
+		return
+	}
+
+	writeAPIJSON(w, http.StatusOK, NodePeersResponse{
+		ClockSkew:       nr.ClockSkew(),
+		ClockSkewBudget: nr.ClockSkewBudget(),
+		Partitioned:     nr.IsPartitioned(),
+		Peers:           nr.nodePeers(),
+		KnownPeers:      nr.addressBook.Peers(),
+	})
+}
+
+// NodeUpgradeResponse is the `Upgrade` with whether it is active at the
+// latest block.
+type NodeUpgradeResponse struct {
+	Upgrade
+	Active bool `json:"active"`
+}
+
+type NodeUpgradesResponse struct {
+	Height   uint64                `json:"height"`
+	Signals  []string              `json:"signals"` // the features, which the node signals for
+	Upgrades []NodeUpgradeResponse `json:"upgrades"`
+}
+
+// handleAPINodeUpgrades returns the protocol upgrades, which the validators
+// signaled for.
+func (nr *NodeRunner) handleAPINodeUpgrades(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		writeAPIError(w, r, http.StatusMethodNotAllowed, nil)
+		return
+	}
+
+	latest, err := GetLatestBlock(nr.storage)
+	if err != nil {
+		writeAPIError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	upgrades, err := GetUpgrades(nr.storage)
+	if err != nil {
+		writeAPIError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	response := NodeUpgradesResponse{
+		Height:   latest.Height,
+		Signals:  []string{},
+		Upgrades: []NodeUpgradeResponse{},
+	}
+	if is, ok := nr.consensus.(*ISAAC); ok && len(is.UpgradeSignals()) > 0 {
+		response.Signals = is.UpgradeSignals()
+	}
+	for _, u := range upgrades {
+		response.Upgrades = append(response.Upgrades, NodeUpgradeResponse{Upgrade: u, Active: u.IsActive(latest.Height)})
+	}
+
+	writeAPIJSON(w, http.StatusOK, response)
+}
+
+// handleAPINodeMetrics returns the metrics of node in the Prometheus text
+// format.
+func (nr *NodeRunner) handleAPINodeMetrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		writeAPIError(w, r, http.StatusMethodNotAllowed, nil)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(nr.state.Metrics()))
+
+	s := "# HELP sebak_forks_detected_total number of blocks of validators, which conflict with the finalized blocks\n"
+	s += "# TYPE sebak_forks_detected_total counter\n"
+	s += fmt.Sprintf("sebak_forks_detected_total %d\n", nr.ForksDetected())
+
+	s += "# HELP sebak_commit_revalidations_total number of accepted transactions validated again at commit\n"
+	s += "# TYPE sebak_commit_revalidations_total counter\n"
+	s += fmt.Sprintf("sebak_commit_revalidations_total{policy=%q} %d\n", nr.RevalidationPolicy(), nr.Revalidations())
+	s += "# HELP sebak_commit_divergences_total number of accepted transactions, which failed at commit\n"
+	s += "# TYPE sebak_commit_divergences_total counter\n"
+	s += fmt.Sprintf("sebak_commit_divergences_total{policy=%q} %d\n", nr.RevalidationPolicy(), nr.RevalidationDivergences())
+
+	s += "# HELP sebak_peer_clock_offset_seconds clock of validator minus the local clock\n"
+	s += "# TYPE sebak_peer_clock_offset_seconds gauge\n"
+	for _, peer := range nr.nodePeers() {
+		if peer.ClockOffset == nil {
+			continue
+		}
+		s += fmt.Sprintf("sebak_peer_clock_offset_seconds{peer=%q} %g\n", peer.Address, peer.ClockOffset.Seconds())
+	}
+
+	s += "# HELP sebak_clock_skew_seconds spread of the clocks of node and the connected validators\n"
+	s += "# TYPE sebak_clock_skew_seconds gauge\n"
+	s += fmt.Sprintf("sebak_clock_skew_seconds %g\n", nr.ClockSkew().Seconds())
+
+	s += "# HELP sebak_peer_health_score health score of validator from 0 to 100\n"
+	s += "# TYPE sebak_peer_health_score gauge\n"
+	for _, peer := range nr.nodePeers() {
+		s += fmt.Sprintf("sebak_peer_health_score{peer=%q,status=%q} %d\n", peer.Address, peer.Health.Status, peer.Health.Score)
+	}
+	s += "# HELP sebak_peer_missed_ballots number of the recent rounds, which validator did not send the ballot for\n"
+	s += "# TYPE sebak_peer_missed_ballots gauge\n"
+	for _, peer := range nr.nodePeers() {
+		s += fmt.Sprintf("sebak_peer_missed_ballots{peer=%q} %d\n", peer.Address, peer.Health.MissedBallots)
+	}
+	var partitioned int
+	if nr.IsPartitioned() {
+		partitioned = 1
+	}
+	s += "# HELP sebak_network_partitioned whether the node can not reach the quorum of validators\n"
+	s += "# TYPE sebak_network_partitioned gauge\n"
+	s += fmt.Sprintf("sebak_network_partitioned %d\n", partitioned)
+
+	s += "# HELP sebak_peer_circuit_open whether the circuit of the client to validator is open; the messages are not sent to it\n"
+	s += "# TYPE sebak_peer_circuit_open gauge\n"
+	for _, peer := range nr.nodePeers() {
+		if len(peer.Circuit) < 1 {
+			continue
+		}
+		var open int
+		if peer.Circuit != sebaknetwork.CircuitClosed {
+			open = 1
+		}
+		s += fmt.Sprintf("sebak_peer_circuit_open{peer=%q} %d\n", peer.Address, open)
+	}
+
+	ballots, messages := nr.connectionManager.BallotStats()
+	s += "# HELP sebak_ballots_sent_total number of ballots sent to validators\n"
+	s += "# TYPE sebak_ballots_sent_total counter\n"
+	s += fmt.Sprintf("sebak_ballots_sent_total %d\n", ballots)
+	s += "# HELP sebak_ballot_messages_sent_total number of network messages which have the ballots; with the ballot aggregation, one message has many ballots\n"
+	s += "# TYPE sebak_ballot_messages_sent_total counter\n"
+	s += fmt.Sprintf("sebak_ballot_messages_sent_total %d\n", messages)
+
+	s += "# HELP sebak_message_queue_length number of the inbound messages waiting in the queue of priority\n"
+	s += "# TYPE sebak_message_queue_length gauge\n"
+	for _, priority := range []sebaknetwork.MessagePriority{
+		sebaknetwork.MessagePriorityConsensus,
+		sebaknetwork.MessagePriorityClient,
+		sebaknetwork.MessagePrioritySync,
+	} {
+		s += fmt.Sprintf("sebak_message_queue_length{queue=%q} %d\n", priority, nr.messageQueue.Len(priority))
+	}
+	s += "# HELP sebak_message_queue_dropped_total number of the client messages dropped by the full queue\n"
+	s += "# TYPE sebak_message_queue_dropped_total counter\n"
+	s += fmt.Sprintf("sebak_message_queue_dropped_total %d\n", nr.messageQueue.Dropped())
+	s += "# HELP sebak_sync_requests_refused_total number of the sync requests of the other nodes refused while the node is busy\n"
+	s += "# TYPE sebak_sync_requests_refused_total counter\n"
+	s += fmt.Sprintf("sebak_sync_requests_refused_total %d\n", nr.messageQueue.SyncRefused())
+
+	synced, syncFailures := nr.blockSync.Stats()
+	s += "# HELP sebak_block_sync_blocks_total number of blocks applied by the block sync\n"
+	s += "# TYPE sebak_block_sync_blocks_total counter\n"
+	s += fmt.Sprintf("sebak_block_sync_blocks_total %d\n", synced)
+	s += "# HELP sebak_block_sync_failures_total number of block range requests, which failed or timed out\n"
+	s += "# TYPE sebak_block_sync_failures_total counter\n"
+	s += fmt.Sprintf("sebak_block_sync_failures_total %d\n", syncFailures)
+
+	offenses := nr.peerBans.Offenses()
+	s += "# HELP sebak_peer_offenses_total number of the malformed messages and the invalid signatures of peers\n"
+	s += "# TYPE sebak_peer_offenses_total counter\n"
+	for _, offense := range []sebaknetwork.PeerOffense{
+		sebaknetwork.PeerOffenseMalformed,
+		sebaknetwork.PeerOffenseInvalidSignature,
+	} {
+		s += fmt.Sprintf("sebak_peer_offenses_total{offense=%q} %d\n", offense, offenses[offense])
+	}
+	s += "# HELP sebak_peer_banned number of IPs banned by their messages\n"
+	s += "# TYPE sebak_peer_banned gauge\n"
+	s += fmt.Sprintf("sebak_peer_banned %d\n", len(nr.peerBans.Bans()))
+	s += "# HELP sebak_peer_greylisted number of IPs greylisted by their messages\n"
+	s += "# TYPE sebak_peer_greylisted gauge\n"
+	s += fmt.Sprintf("sebak_peer_greylisted %d\n", len(nr.peerBans.Greylisted()))
+
+	if h2n, ok := nr.network.(*sebaknetwork.HTTP2Network); ok {
+		rejected := h2n.Admission().Rejected()
+		s += "# HELP sebak_inbound_rejected_total number of inbound connections rejected by the admission control\n"
+		s += "# TYPE sebak_inbound_rejected_total counter\n"
+		for _, reason := range []sebaknetwork.AdmissionRejectReason{
+			sebaknetwork.AdmissionRejectBanned,
+			sebaknetwork.AdmissionRejectTooManyHandshakes,
+			sebaknetwork.AdmissionRejectTooManyConns,
+			sebaknetwork.AdmissionRejectHandshakeRate,
+			sebaknetwork.AdmissionRejectHandshakeFailed,
+		} {
+			s += fmt.Sprintf("sebak_inbound_rejected_total{reason=%q} %d\n", reason, rejected[reason])
+		}
+		s += "# HELP sebak_inbound_banned number of IPs banned by the admission control\n"
+		s += "# TYPE sebak_inbound_banned gauge\n"
+		s += fmt.Sprintf("sebak_inbound_banned %d\n", h2n.Admission().Banned())
+
+		if h2n.Bandwidth().IsLimited() {
+			bytes := h2n.Bandwidth().Bytes()
+			throttled := h2n.Bandwidth().Throttled()
+			s += "# HELP sebak_peer_bandwidth_bytes_total bytes of the requests and the responses of the peers, whose bandwidth is limited\n"
+			s += "# TYPE sebak_peer_bandwidth_bytes_total counter\n"
+			for _, direction := range []sebaknetwork.BandwidthDirection{sebaknetwork.BandwidthUpload, sebaknetwork.BandwidthDownload} {
+				s += fmt.Sprintf("sebak_peer_bandwidth_bytes_total{direction=%q} %d\n", direction, bytes[direction])
+			}
+			s += "# HELP sebak_peer_throttled_seconds_total time, which the requests and the responses of the peers waited for the bandwidth\n"
+			s += "# TYPE sebak_peer_throttled_seconds_total counter\n"
+			for _, direction := range []sebaknetwork.BandwidthDirection{sebaknetwork.BandwidthUpload, sebaknetwork.BandwidthDownload} {
+				s += fmt.Sprintf("sebak_peer_throttled_seconds_total{direction=%q} %g\n", direction, throttled[direction].Seconds())
+			}
+		}
+	}
+
+	if nr.loadShedder != nil {
+		sample := nr.loadShedder.Sample()
+		s += "# HELP sebak_load_level load shedding level; 0 is normal, 1 is high and 2 is critical\n"
+		s += "# TYPE sebak_load_level gauge\n"
+		s += fmt.Sprintf("sebak_load_level %d\n", nr.loadShedder.Level())
+		s += "# HELP sebak_load_cpu load average of 1 minute for one CPU\n"
+		s += "# TYPE sebak_load_cpu gauge\n"
+		s += fmt.Sprintf("sebak_load_cpu %g\n", sample.CPU)
+		s += "# HELP sebak_load_memory_bytes bytes of heap in use\n"
+		s += "# TYPE sebak_load_memory_bytes gauge\n"
+		s += fmt.Sprintf("sebak_load_memory_bytes %d\n", sample.Memory)
+		s += "# HELP sebak_load_disk_latency_seconds latency to write and remove the probe key of storage\n"
+		s += "# TYPE sebak_load_disk_latency_seconds gauge\n"
+		s += fmt.Sprintf("sebak_load_disk_latency_seconds %g\n", sample.DiskLatency.Seconds())
+	}
+	w.Write([]byte(s))
+}
@@ -0,0 +1,523 @@
+package sebak
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"boscoin.io/sebak/lib/error"
+	"boscoin.io/sebak/lib/graphql"
+)
+
+// The API routes are declared by `APIRoute`, the path pattern with it's
+// handler and the endpoints, which it serves; the handlers and the OpenAPI 3
+// document of '/api/v1/openapi.json' are made from the same routes, so the
+// clients generated from the document follow the handlers.
+
+const GetOpenAPIPattern string = "/openapi.json"
+
+const OpenAPIVersion string = "3.0.0"
+
+const (
+	APIParamInPath  string = "path"
+	APIParamInQuery string = "query"
+)
+
+// APIParam is the parameter of endpoint; the path parameters are always
+// required. `Type` is the type of OpenAPI schema, 'string', 'integer' or
+// 'boolean'.
+type APIParam struct {
+	Name        string
+	In          string
+	Type        string
+	Required    bool
+	Description string
+}
+
+// APIEndpoint is the one operation of OpenAPI.
+//  * `Path`: the path under `APIVersionPrefix` with the path parameters, like
+//  '/accounts/{address}/data'
+//  * `Request`: the zero value of the JSON body; nil if no body
+//  * `Response`: the zero value of the response; nil if it is not JSON
+//  * `ContentType`: the content type of response, which is not JSON, like
+//  'text/event-stream'
+//  * `Status`: the status of the successful response; 200 by default
+type APIEndpoint struct {
+	Method      string
+	Path        string
+	ID          string
+	Summary     string
+	Params      []APIParam
+	Request     interface{}
+	Response    interface{}
+	ContentType string
+	Status      int
+}
+
+// APIRoute is the pattern of handler under `APIVersionPrefix`; one handler
+// can serve many endpoints, like the sub resources of '/accounts/'.
+type APIRoute struct {
+	Pattern   string
+	Handler   http.HandlerFunc
+	Endpoints []APIEndpoint
+}
+
+func apiPathParam(name, description string) APIParam {
+	return APIParam{Name: name, In: APIParamInPath, Type: "string", Required: true, Description: description}
+}
+
+func apiQueryParam(name, typ, description string) APIParam {
+	return APIParam{Name: name, In: APIParamInQuery, Type: typ, Description: description}
+}
+
+func apiLimitParam(defaultLimit, maxLimit int) APIParam {
+	return apiQueryParam(
+		"limit",
+		"integer",
+		"number of items; "+strconv.Itoa(defaultLimit)+" by default, up to "+strconv.Itoa(maxLimit),
+	)
+}
+
+func apiOrderParam() APIParam {
+	return apiQueryParam("order", "string", "'desc', the latest first by default or 'asc'")
+}
+
+// APIRoutes returns the routes of the API, which are enabled.
+func (nr *NodeRunner) APIRoutes() []APIRoute {
+	addressParam := apiPathParam("address", "public address of account")
+
+	routes := []APIRoute{
+		{GetResultsPattern, nr.handleAPIResults, []APIEndpoint{
+			{Method: "GET", Path: GetResultsPattern, ID: "getResults", Summary: "all the result codes of the API errors", Response: []sebakerror.Result{}},
+		}},
+		{GetSpecPattern, nr.handleAPISpec, []APIEndpoint{
+			{Method: "GET", Path: GetSpecPattern, ID: "getSpec", Summary: "wire format and protocol reference generated from the source", Response: ProtocolSpec{}},
+		}},
+		{GetNextProposersPattern, nr.handleAPINextProposers, []APIEndpoint{
+			{Method: "GET", Path: GetNextProposersPattern, ID: "getNextProposers", Summary: "expected proposers of the next blocks",
+				Params:   []APIParam{apiLimitParam(DefaultNextProposersLimit, MaxNextProposersLimit)},
+				Response: NextProposersResponse{}},
+		}},
+		{GetFinalityPattern, nr.handleAPIFinality, []APIEndpoint{
+			{Method: "GET", Path: GetFinalityPattern, ID: "getFinality", Summary: "last irreversible block", Response: Finality{}},
+		}},
+		{GetProposerSchedulePattern, nr.handleAPIProposerSchedule, []APIEndpoint{
+			{Method: "GET", Path: GetProposerSchedulePattern, ID: "getProposerSchedule", Summary: "proposers of the next rounds",
+				Params: []APIParam{
+					apiQueryParam("rounds", "integer", "number of rounds; "+strconv.Itoa(DefaultProposerScheduleRounds)+" by default, up to "+strconv.Itoa(MaxProposerScheduleRounds)),
+					apiQueryParam("address", "string", "address of validator to get it's turns"),
+				},
+				Response: ProposerScheduleResponse{}},
+		}},
+		{GetAccountsPattern, nr.handleAPIAccounts, []APIEndpoint{
+			{Method: "GET", Path: GetAccountsPattern + "{address}/" + GetAccountDataSubPattern, ID: "getAccountData", Summary: "data entries of account in name order",
+				Params: []APIParam{
+					addressParam,
+					apiQueryParam("name", "string", "exact name of the entry; only the entry is returned"),
+					apiQueryParam("prefix", "string", "prefix of the name of entries"),
+					apiLimitParam(DefaultAccountDataLimit, MaxAccountDataLimit),
+					apiQueryParam("cursor", "string", "name of the last entry of the previous page"),
+					apiQueryParam("mode", "string", "'base64' by default or 'raw'"),
+				},
+				Response: AccountDataResponse{}},
+			{Method: "GET", Path: GetAccountsPattern + "{address}/" + GetAccountTransactionsSubPattern, ID: "getAccountTransactions", Summary: "transactions of account in confirmed order",
+				Params: []APIParam{
+					addressParam,
+					apiLimitParam(DefaultAccountTransactionsLimit, MaxAccountTransactionsLimit),
+					apiQueryParam("cursor", "string", "hash of the last transaction of the previous page"),
+					apiOrderParam(),
+				},
+				Response: AccountTransactionsResponse{}},
+			{Method: "GET", Path: GetAccountsPattern + "{address}/" + GetAccountOperationsSubPattern, ID: "getAccountOperations", Summary: "operations of account in confirmed order",
+				Params: []APIParam{
+					addressParam,
+					apiLimitParam(DefaultAccountOperationsLimit, MaxAccountOperationsLimit),
+					apiQueryParam("cursor", "string", "hash of the last operation of the previous page"),
+					apiOrderParam(),
+					apiQueryParam("type", "string", "type of operation"),
+					apiQueryParam("memo_type", "string", "type of memo of payment; 'text', 'hash' or 'id'"),
+					apiQueryParam("memo", "string", "memo of payment"),
+				},
+				Response: AccountOperationsResponse{}},
+			{Method: "GET", Path: GetAccountsPattern + "{address}/" + GetAccountStatementSubPattern, ID: "getAccountStatement", Summary: "ledger entries of account in sequence order",
+				Params: []APIParam{
+					addressParam,
+					apiLimitParam(DefaultAccountStatementLimit, MaxAccountStatementLimit),
+					apiQueryParam("cursor", "integer", "sequence of the last entry of the previous page"),
+					apiOrderParam(),
+					apiQueryParam("verify", "string", "'1' to return the sum of all the entries of account"),
+				},
+				Response: AccountStatementResponse{}},
+			{Method: "GET", Path: GetAccountsPattern + "{address}/" + GetAccountSpendingLimitSubPattern, ID: "getAccountSpendingLimit", Summary: "spending limit of account with the spending of today",
+				Params:   []APIParam{addressParam},
+				Response: SpendingLimitResponse{}},
+			{Method: "GET", Path: GetAccountsPattern + "{address}/" + GetAccountSignersSubPattern, ID: "getAccountSigners", Summary: "additional signers of account with their weights and threshold",
+				Params:   []APIParam{addressParam},
+				Response: AccountSignersResponse{}},
+			{Method: "GET", Path: GetAccountsPattern + "{address}/" + GetAccountFrozenSubPattern, ID: "getAccountFrozen", Summary: "frozen balances of account with their unlock heights",
+				Params:   []APIParam{addressParam},
+				Response: FrozenBalancesResponse{}},
+		}},
+		{PostAccountsBatchGetPattern, nr.handleAPIAccountsBatchGet, []APIEndpoint{
+			{Method: "POST", Path: PostAccountsBatchGetPattern, ID: "batchGetAccounts", Summary: "balances and checkpoints of the accounts at once",
+				Request: AccountsBatchGetRequest{}, Response: AccountsBatchGetResponse{}},
+		}},
+		{PostTransactionsPattern, nr.handleAPITransactions, []APIEndpoint{
+			{Method: "POST", Path: PostTransactionsPattern, ID: "submitTransaction", Summary: "validates the transaction and sends it to the node",
+				Request: Transaction{}, Response: TransactionSubmitResponse{}, Status: http.StatusAccepted},
+		}},
+		{GetTransactionsPattern, nr.handleAPITransaction, []APIEndpoint{
+			{Method: "GET", Path: GetTransactionsPattern + "{hash}/" + GetTransactionStatusSubPattern, ID: "getTransactionStatus", Summary: "status of transaction; submitted, queued, pending, included, finalized or rejected with the reason",
+				Params:   []APIParam{apiPathParam("hash", "hash of transaction")},
+				Response: TransactionStatus{}},
+			{Method: "GET", Path: GetTransactionsPattern + "{hash}/" + GetTransactionReceiptSubPattern, ID: "getTransactionReceipt", Summary: "signed forwarding receipt of the transaction submitted to this node",
+				Params:   []APIParam{apiPathParam("hash", "hash of transaction")},
+				Response: ForwardingReceipt{}},
+			{Method: "GET", Path: GetTransactionsPattern + "{hash}/" + GetTransactionResultSubPattern, ID: "getTransactionResult", Summary: "result of the included transaction or the transaction rejected by this node, with the result code of every operation and the charged fee",
+				Params:   []APIParam{apiPathParam("hash", "hash of transaction")},
+				Response: TransactionResult{}},
+		}},
+		{PostJSONRPCPattern, nr.handleAPIJSONRPC, []APIEndpoint{
+			{Method: "POST", Path: PostJSONRPCPattern, ID: "callJSONRPC", Summary: "JSON-RPC 2.0 request or the batch of them",
+				Request: JSONRPCRequest{}, Response: JSONRPCResponse{}},
+		}},
+		{GetOperationsPattern, nr.handleAPIOperation, []APIEndpoint{
+			{Method: "GET", Path: GetOperationsPattern + "{hash}", ID: "getOperation", Summary: "operation by it's hash",
+				Params:   []APIParam{apiPathParam("hash", "'<operation hash>-<transaction hash>'")},
+				Response: OperationResponse{}},
+		}},
+		{GetBlocksPattern, nr.handleAPIBlock, []APIEndpoint{
+			{Method: "GET", Path: GetBlocksPattern + "{block}", ID: "getBlock", Summary: "block by it's height or hash with the transactions",
+				Params: []APIParam{
+					apiPathParam("block", "height or hash of block"),
+					apiQueryParam("headerOnly", "boolean", "'true' to return only the header"),
+				},
+				Response: BlockResponse{}},
+		}},
+		{GetStreamBlocksPattern, nr.handleAPIStreamBlocks, []APIEndpoint{
+			{Method: "GET", Path: GetStreamBlocksPattern, ID: "streamBlocks", Summary: "Server-Sent Events of the new blocks",
+				ContentType: "text/event-stream"},
+		}},
+		{GetStreamTransactionsPattern, nr.handleAPIStreamTransactions, []APIEndpoint{
+			{Method: "GET", Path: GetStreamTransactionsPattern, ID: "streamTransactions", Summary: "Server-Sent Events of the new transactions",
+				Params:      []APIParam{apiQueryParam("account", "string", "only the transactions, which the account sends or receives")},
+				ContentType: "text/event-stream"},
+		}},
+		{GetWebSocketPattern, nr.handleAPIWebSocket, []APIEndpoint{
+			{Method: "GET", Path: GetWebSocketPattern, ID: "connectWebSocket", Summary: "WebSocket of the subscriptions; the messages are `WebSocketRequest` and `WebSocketMessage`",
+				Status: http.StatusSwitchingProtocols},
+		}},
+		{GetNodePattern, nr.handleAPINode, []APIEndpoint{
+			{Method: "GET", Path: GetNodePattern, ID: "getNode", Summary: "lifecycle state of node", Response: NodeResponse{}},
+		}},
+		{GetNodeMetricsPattern, nr.handleAPINodeMetrics, []APIEndpoint{
+			{Method: "GET", Path: GetNodeMetricsPattern, ID: "getNodeMetrics", Summary: "metrics of node in the Prometheus text format",
+				ContentType: "text/plain"},
+		}},
+		{GetNodePeersPattern, nr.handleAPINodePeers, []APIEndpoint{
+			{Method: "GET", Path: GetNodePeersPattern, ID: "getNodePeers", Summary: "validators with the connection state", Response: NodePeersResponse{}},
+		}},
+		{GetNodeUpgradesPattern, nr.handleAPINodeUpgrades, []APIEndpoint{
+			{Method: "GET", Path: GetNodeUpgradesPattern, ID: "getNodeUpgrades", Summary: "protocol upgrades signaled by the validators", Response: NodeUpgradesResponse{}},
+		}},
+		{GetStatsPattern, nr.handleAPIStats, []APIEndpoint{
+			{Method: "GET", Path: GetStatsPattern, ID: "getStats", Summary: "rollups of the chain statistics from the latest period",
+				Params: []APIParam{
+					apiQueryParam("period", "string", "'day' by default or 'epoch'"),
+					apiLimitParam(DefaultStatsLimit, MaxStatsLimit),
+				},
+				Response: StatsResponse{}},
+		}},
+		{GetFeesPattern, nr.handleAPIFees, []APIEndpoint{
+			{Method: "GET", Path: GetFeesPattern, ID: "getFees", Summary: "fees for the new transaction from the recent blocks and the pool",
+				Response: FeeEstimate{}},
+		}},
+		{GetAdminForksPattern, nr.handleAPIAdminForks, []APIEndpoint{
+			{Method: "GET", Path: GetAdminForksPattern, ID: "getForks", Summary: "fork evidences from the highest block",
+				Params:   []APIParam{apiLimitParam(DefaultForksLimit, MaxForksLimit)},
+				Response: ForksResponse{}},
+		}},
+	}
+
+	if nr.graphQLSchema != nil {
+		routes = append(routes, APIRoute{GetGraphQLPattern, nr.handleAPIGraphQL, []APIEndpoint{
+			{Method: "GET", Path: GetGraphQLPattern, ID: "queryGraphQL", Summary: "GraphQL query",
+				Params: []APIParam{
+					{Name: "query", In: APIParamInQuery, Type: "string", Required: true},
+					apiQueryParam("operationName", "string", ""),
+					apiQueryParam("variables", "string", "JSON object of the variables"),
+				},
+				Response: sebakgraphql.Response{}},
+			{Method: "POST", Path: GetGraphQLPattern, ID: "postGraphQL", Summary: "GraphQL query",
+				Request: sebakgraphql.Request{}, Response: sebakgraphql.Response{}},
+		}})
+	}
+	if nr.faucet != nil {
+		routes = append(routes, APIRoute{PostFaucetPattern, nr.handleAPIFaucet, []APIEndpoint{
+			{Method: "POST", Path: PostFaucetPattern, ID: "requestFaucet", Summary: "funds the address from the faucet account",
+				Request: FaucetRequest{}, Response: FaucetResponse{}, Status: http.StatusAccepted},
+		}})
+	}
+
+	return routes
+}
+
+type OpenAPISchema struct {
+	Ref                  string                    `json:"$ref,omitempty"`
+	Type                 string                    `json:"type,omitempty"`
+	Format               string                    `json:"format,omitempty"`
+	Items                *OpenAPISchema            `json:"items,omitempty"`
+	Properties           map[string]*OpenAPISchema `json:"properties,omitempty"`
+	AdditionalProperties *OpenAPISchema            `json:"additionalProperties,omitempty"`
+}
+
+type OpenAPIParameter struct {
+	Name        string         `json:"name"`
+	In          string         `json:"in"`
+	Required    bool           `json:"required,omitempty"`
+	Description string         `json:"description,omitempty"`
+	Schema      *OpenAPISchema `json:"schema"`
+}
+
+type OpenAPIMediaType struct {
+	Schema *OpenAPISchema `json:"schema,omitempty"`
+}
+
+type OpenAPIRequestBody struct {
+	Required bool                        `json:"required"`
+	Content  map[string]OpenAPIMediaType `json:"content"`
+}
+
+type OpenAPIResponse struct {
+	Description string                      `json:"description"`
+	Content     map[string]OpenAPIMediaType `json:"content,omitempty"`
+}
+
+type OpenAPIOperation struct {
+	OperationID string                     `json:"operationId"`
+	Summary     string                     `json:"summary,omitempty"`
+	Parameters  []OpenAPIParameter         `json:"parameters,omitempty"`
+	RequestBody *OpenAPIRequestBody        `json:"requestBody,omitempty"`
+	Responses   map[string]OpenAPIResponse `json:"responses"`
+	Deprecated  bool                       `json:"deprecated,omitempty"`
+}
+
+type OpenAPIInfo struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+type OpenAPIServer struct {
+	URL string `json:"url"`
+}
+
+type OpenAPIComponents struct {
+	Schemas map[string]*OpenAPISchema `json:"schemas"`
+}
+
+type OpenAPIDocument struct {
+	OpenAPI    string                                  `json:"openapi"`
+	Info       OpenAPIInfo                             `json:"info"`
+	Servers    []OpenAPIServer                         `json:"servers"`
+	Paths      map[string]map[string]*OpenAPIOperation `json:"paths"`
+	Components OpenAPIComponents                       `json:"components"`
+}
+
+// openAPISchemaOverrides are the types, which are encoded by their own
+// `MarshalJSON()` or not like their kind.
+var openAPISchemaOverrides = map[reflect.Type]*OpenAPISchema{
+	reflect.TypeOf(Amount(0)):        {Type: "string", Format: "int64"},
+	reflect.TypeOf(time.Duration(0)): {Type: "integer", Format: "int64"},
+	reflect.TypeOf(time.Time{}):      {Type: "string", Format: "date-time"},
+}
+
+func openAPISchemaName(t reflect.Type) string {
+	if t.PkgPath() == reflect.TypeOf(Block{}).PkgPath() {
+		return t.Name()
+	}
+
+	return t.String()
+}
+
+// openAPISchemaOf makes the schema of type by it's JSON encoding; the named
+// structs are added to `schemas` and referred by '$ref'.
+func openAPISchemaOf(t reflect.Type, schemas map[string]*OpenAPISchema) *OpenAPISchema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if override, found := openAPISchemaOverrides[t]; found {
+		return override
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &OpenAPISchema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &OpenAPISchema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &OpenAPISchema{Type: "number"}
+	case reflect.String:
+		return &OpenAPISchema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			// `json.RawMessage` is any JSON and the other bytes are base64
+			if t == reflect.TypeOf(json.RawMessage{}) {
+				return &OpenAPISchema{}
+			}
+			return &OpenAPISchema{Type: "string", Format: "byte"}
+		}
+		return &OpenAPISchema{Type: "array", Items: openAPISchemaOf(t.Elem(), schemas)}
+	case reflect.Map:
+		return &OpenAPISchema{Type: "object", AdditionalProperties: openAPISchemaOf(t.Elem(), schemas)}
+	case reflect.Struct:
+	default:
+		// interface; any JSON
+		return &OpenAPISchema{}
+	}
+
+	name := openAPISchemaName(t)
+	ref := &OpenAPISchema{Ref: "#/components/schemas/" + name}
+	if len(t.Name()) > 0 {
+		if _, found := schemas[name]; found {
+			return ref
+		}
+	}
+
+	schema := &OpenAPISchema{Type: "object", Properties: map[string]*OpenAPISchema{}}
+	if len(t.Name()) > 0 {
+		// added before the fields for the recursive types
+		schemas[name] = schema
+	}
+	openAPIAddFields(t, schema, schemas)
+
+	if len(t.Name()) < 1 {
+		return schema
+	}
+
+	return ref
+}
+
+func openAPIAddFields(t reflect.Type, schema *OpenAPISchema, schemas map[string]*OpenAPISchema) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+
+		if field.Anonymous && len(name) < 1 {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Ptr {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				openAPIAddFields(embedded, schema, schemas)
+				continue
+			}
+		}
+		if len(field.PkgPath) > 0 {
+			// unexported
+			continue
+		}
+		if len(name) < 1 {
+			name = field.Name
+		}
+
+		schema.Properties[name] = openAPISchemaOf(field.Type, schemas)
+	}
+}
+
+// NewOpenAPIDocument makes the OpenAPI document of the routes in the version;
+// `basePath` is the base path of `APIConfig`. The responses are in the shape
+// of the serializers of version.
+func NewOpenAPIDocument(group APIVersionGroup, routes []APIRoute, basePath string) OpenAPIDocument {
+	doc := OpenAPIDocument{
+		OpenAPI:    OpenAPIVersion,
+		Info:       OpenAPIInfo{Title: "SEBAK API", Version: Version},
+		Servers:    []OpenAPIServer{{URL: basePath + group.Version.Prefix()}},
+		Paths:      map[string]map[string]*OpenAPIOperation{},
+		Components: OpenAPIComponents{Schemas: map[string]*OpenAPISchema{}},
+	}
+	problem := openAPISchemaOf(reflect.TypeOf(sebakerror.Problem{}), doc.Components.Schemas)
+
+	for _, route := range routes {
+		for _, endpoint := range route.Endpoints {
+			op := &OpenAPIOperation{
+				OperationID: endpoint.ID,
+				Summary:     endpoint.Summary,
+				Deprecated:  group.Deprecated,
+				Responses: map[string]OpenAPIResponse{
+					"default": {
+						Description: "error",
+						Content:     map[string]OpenAPIMediaType{sebakerror.ProblemContentType: {Schema: problem}},
+					},
+				},
+			}
+
+			for _, param := range endpoint.Params {
+				op.Parameters = append(op.Parameters, OpenAPIParameter{
+					Name:        param.Name,
+					In:          param.In,
+					Required:    param.Required || param.In == APIParamInPath,
+					Description: param.Description,
+					Schema:      &OpenAPISchema{Type: param.Type},
+				})
+			}
+
+			if endpoint.Request != nil {
+				op.RequestBody = &OpenAPIRequestBody{
+					Required: true,
+					Content: map[string]OpenAPIMediaType{
+						"application/json": {Schema: openAPISchemaOf(reflect.TypeOf(endpoint.Request), doc.Components.Schemas)},
+					},
+				}
+			}
+
+			status := endpoint.Status
+			if status < 1 {
+				status = http.StatusOK
+			}
+			response := OpenAPIResponse{Description: http.StatusText(status)}
+			switch {
+			case endpoint.Response != nil:
+				t := reflect.TypeOf(endpoint.Response)
+				if serializer, found := group.Serializers[t]; found {
+					t = reflect.TypeOf(serializer.Response)
+				}
+				response.Content = map[string]OpenAPIMediaType{
+					"application/json": {Schema: openAPISchemaOf(t, doc.Components.Schemas)},
+				}
+			case len(endpoint.ContentType) > 0:
+				response.Content = map[string]OpenAPIMediaType{
+					endpoint.ContentType: {Schema: &OpenAPISchema{Type: "string"}},
+				}
+			}
+			op.Responses[strconv.Itoa(status)] = response
+
+			path := endpoint.Path
+			if _, found := doc.Paths[path]; !found {
+				doc.Paths[path] = map[string]*OpenAPIOperation{}
+			}
+			doc.Paths[path][strings.ToLower(endpoint.Method)] = op
+		}
+	}
+
+	return doc
+}
+
+// handleAPIOpenAPI returns the OpenAPI document of the enabled routes in the
+// version of request.
+func (nr *NodeRunner) handleAPIOpenAPI(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		writeAPIError(w, r, http.StatusMethodNotAllowed, nil)
+		return
+	}
+
+	writeAPIJSON(w, http.StatusOK, NewOpenAPIDocument(apiVersionGroupOf(w), nr.APIRoutes(), nr.apiConfig.BasePath))
+}
@@ -0,0 +1,194 @@
+package sebak
+
+import (
+	"errors"
+	"net/http"
+)
+
+const (
+	GetOperationsPattern           string = "/operations/"
+	GetAccountOperationsSubPattern string = "operations"
+)
+
+const (
+	DefaultAccountOperationsLimit int = 20
+	MaxAccountOperationsLimit     int = 100
+)
+
+type OperationResponse struct {
+	Hash      string        `json:"hash"`
+	TxHash    string        `json:"tx_hash"`
+	Type      OperationType `json:"type"`
+	Source    string        `json:"source"`
+	Target    string        `json:"target,omitempty"`
+	Amount    Amount        `json:"amount"`
+	Memo      *Memo         `json:"memo,omitempty"`
+	Confirmed string        `json:"confirmed"`
+}
+
+func NewOperationResponse(bo BlockOperation) OperationResponse {
+	return OperationResponse{
+		Hash:      bo.Hash,
+		TxHash:    bo.TxHash,
+		Type:      bo.Type,
+		Source:    bo.Source,
+		Target:    bo.Target,
+		Amount:    bo.Amount,
+		Memo:      bo.Memo,
+		Confirmed: bo.Confirmed,
+	}
+}
+
+// handleAPIOperation returns the operation of '/operations/{hash}'; the
+// committed operation is immutable, so it is cached.
+func (nr *NodeRunner) handleAPIOperation(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		writeAPIError(w, r, http.StatusMethodNotAllowed, nil)
+		return
+	}
+	if nr.serveAPICache(w, r) {
+		return
+	}
+
+	id, sub := SplitAPIPath(r.URL.Path, GetOperationsPattern)
+	if len(id) < 1 || len(sub) > 0 {
+		writeAPIError(w, r, http.StatusNotFound, nil)
+		return
+	}
+
+	parsed, err := ParseOpID(id)
+	if err != nil {
+		writeAPIError(w, r, http.StatusNotFound, errors.New("operation not found; invalid operation hash"))
+		return
+	}
+
+	hash := parsed.String()
+	exists, err := ExistBlockOperation(nr.storage, hash)
+	if err != nil {
+		writeAPIError(w, r, http.StatusInternalServerError, err)
+		return
+	} else if !exists {
+		writeAPIError(w, r, http.StatusNotFound, errors.New("operation not found"))
+		return
+	}
+
+	var bo BlockOperation
+	if bo, err = GetBlockOperation(nr.storage, hash); err != nil {
+		writeAPIError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	// in ISAAC, every committed block is final with the marker, so is the
+	// operation in it
+	var f Finality
+	if f, err = GetFinality(nr.storage); err != nil {
+		writeAPIError(w, r, http.StatusInternalServerError, err)
+		return
+	} else if f.IsEmpty() {
+		writeAPIJSON(w, http.StatusOK, NewOperationResponse(bo))
+		return
+	}
+
+	nr.writeAPIImmutableJSON(w, r, NewOperationResponse(bo))
+}
+
+type AccountOperationsResponse struct {
+	Address    string              `json:"address"`
+	Order      string              `json:"order"`
+	Operations []OperationResponse `json:"operations"`
+	NextCursor string              `json:"next_cursor,omitempty"`
+}
+
+// handleAPIAccountOperations returns the operations of account, which is the
+// source or the target of them, in confirmed order; 'order' is 'desc', the
+// latest first by default or 'asc'. With 'type', only the operations of the
+// type are returned, and with 'memo_type' and 'memo', only the payments of the
+// memo. The next page starts after the 'cursor', which is the hash of the
+// last operation of the previous page.
+func (nr *NodeRunner) handleAPIAccountOperations(w http.ResponseWriter, r *http.Request, address string) {
+	query := r.URL.Query()
+
+	limit, err := parseAPILimit(r, DefaultAccountOperationsLimit, MaxAccountOperationsLimit)
+	if err != nil {
+		writeAPIError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	order := query.Get("order")
+	switch order {
+	case "":
+		order = APIOrderDesc
+	case APIOrderAsc, APIOrderDesc:
+	default:
+		writeAPIError(w, r, http.StatusBadRequest, errors.New("'order' must be 'asc' or 'desc'"))
+		return
+	}
+
+	opType := OperationType(query.Get("type"))
+	if len(opType) > 0 && !IsKnownOperationType(opType) {
+		writeAPIError(w, r, http.StatusBadRequest, errors.New("unknown operation 'type'"))
+		return
+	}
+
+	var memo *Memo
+	if len(query.Get("memo_type")) > 0 || len(query.Get("memo")) > 0 {
+		m := NewMemo(MemoType(query.Get("memo_type")), query.Get("memo"))
+		if err = m.IsWellFormed(); err != nil {
+			writeAPIError(w, r, http.StatusBadRequest, err)
+			return
+		}
+		memo = &m
+	}
+
+	var from string
+	if cursor := query.Get("cursor"); len(cursor) > 0 {
+		var exists bool
+		if _, err = ParseOpID(cursor); err != nil {
+			writeAPIError(w, r, http.StatusBadRequest, errors.New("'cursor' must be the hash of operation"))
+			return
+		} else if exists, err = ExistBlockOperation(nr.storage, cursor); err != nil {
+			writeAPIError(w, r, http.StatusInternalServerError, err)
+			return
+		} else if !exists {
+			writeAPIError(w, r, http.StatusBadRequest, errors.New("'cursor' must be the hash of operation"))
+			return
+		}
+
+		var bo BlockOperation
+		if bo, err = GetBlockOperation(nr.storage, cursor); err != nil {
+			writeAPIError(w, r, http.StatusInternalServerError, err)
+			return
+		}
+		from = bo.NewBlockOperationKeyAccount(address)
+	}
+
+	response := AccountOperationsResponse{
+		Address:    address,
+		Order:      order,
+		Operations: []OperationResponse{},
+	}
+
+	iterFunc, closeFunc := GetBlockOperationsByAccount(nr.storage, address, from, order == APIOrderDesc)
+	defer closeFunc()
+
+	for {
+		bo, hasNext := iterFunc()
+		if !hasNext {
+			break
+		}
+		if len(opType) > 0 && bo.Type != opType {
+			continue
+		}
+		if memo != nil && (bo.Memo == nil || !bo.Memo.Equal(*memo)) {
+			continue
+		}
+		if len(response.Operations) == limit {
+			response.NextCursor = response.Operations[limit-1].Hash
+			break
+		}
+
+		response.Operations = append(response.Operations, NewOperationResponse(bo))
+	}
+
+	writeAPIJSON(w, http.StatusOK, response)
+}
@@ -0,0 +1,45 @@
+package sebak
+
+import (
+	"errors"
+	"net/http"
+	"time"
+)
+
+const GetAccountSpendingLimitSubPattern string = "spending-limit"
+
+type SpendingLimitResponse struct {
+	Address        string `json:"address"`
+	PerTransaction Amount `json:"per_transaction"`
+	Daily          Amount `json:"daily"`
+	Day            string `json:"day"`   // today in UTC
+	Spent          Amount `json:"spent"` // the spending of today
+}
+
+// handleAPIAccountSpendingLimit returns the spending limit of account with
+// the spending of today; the account without limit is not found.
+func (nr *NodeRunner) handleAPIAccountSpendingLimit(w http.ResponseWriter, r *http.Request, address string) {
+	limit, found, err := GetSpendingLimit(nr.storage, address)
+	if err != nil {
+		writeAPIError(w, r, http.StatusInternalServerError, err)
+		return
+	} else if !found {
+		writeAPIError(w, r, http.StatusNotFound, errors.New("account has no spending limit"))
+		return
+	}
+
+	day := GetSpendingLimitDay(time.Now())
+	spent, err := GetSpendingLimitSpent(nr.storage, address, day)
+	if err != nil {
+		writeAPIError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeAPIJSON(w, http.StatusOK, SpendingLimitResponse{
+		Address:        limit.Address,
+		PerTransaction: limit.PerTransaction,
+		Daily:          limit.Daily,
+		Day:            day,
+		Spent:          spent,
+	})
+}
@@ -0,0 +1,57 @@
+package sebak
+
+import (
+	"net/http"
+)
+
+const GetStatsPattern string = "/stats"
+
+const (
+	DefaultStatsLimit int = 30
+	MaxStatsLimit     int = 365
+)
+
+type StatsResponse struct {
+	Period      string       `json:"period"`
+	EpochBlocks uint64       `json:"epoch_blocks"`
+	Stats       []ChainStats `json:"stats"`
+}
+
+// handleAPIStats returns the rollups of the chain statistics from the latest
+// one; 'period' is 'day', the default or 'epoch'.
+func (nr *NodeRunner) handleAPIStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		writeAPIError(w, r, http.StatusMethodNotAllowed, nil)
+		return
+	}
+
+	limit, err := parseAPILimit(r, DefaultStatsLimit, MaxStatsLimit)
+	if err != nil {
+		writeAPIError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	period := r.URL.Query().Get("period")
+	if len(period) < 1 {
+		period = ChainStatsPeriodDay
+	}
+	if period != ChainStatsPeriodDay && period != ChainStatsPeriodEpoch {
+		writeAPIError(w, r, http.StatusBadRequest, nil)
+		return
+	}
+
+	stats, err := GetChainStats(nr.storage, period, limit)
+	if err != nil {
+		writeAPIError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	if stats == nil {
+		stats = []ChainStats{}
+	}
+
+	writeAPIJSON(w, http.StatusOK, StatsResponse{
+		Period:      period,
+		EpochBlocks: ChainStatsEpochBlocks,
+		Stats:       stats,
+	})
+}
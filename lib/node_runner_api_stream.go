@@ -0,0 +1,176 @@
+package sebak
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	GetStreamBlocksPattern       string = "/stream/blocks"
+	GetStreamTransactionsPattern string = "/stream/transactions"
+)
+
+// StreamHeartbeatInterval is the interval of the comment line, which keeps
+// the idle stream connection alive through the proxies.
+const StreamHeartbeatInterval time.Duration = 15 * time.Second
+
+// publishBlock sends the events of the new block, it's transaction, the
+// operations and the changed accounts to the stream subscribers.
+func (nr *NodeRunner) publishBlock(tx Transaction) {
+	block, err := GetLatestBlock(nr.storage)
+	if err != nil || block.IsEmpty() {
+		return
+	}
+	bt, err := GetBlockTransaction(nr.storage, tx.GetHash())
+	if err != nil {
+		return
+	}
+	accounts := NewBlockTransactionFromTransaction(tx, nil).Accounts()
+
+	events := []StreamEvent{
+		{
+			ID:   strconv.FormatUint(block.Height, 10),
+			Type: StreamEventBlock,
+			Data: block,
+		},
+		{
+			ID:       bt.Hash,
+			Type:     StreamEventTransaction,
+			Data:     NewAccountTransactionEntry(bt),
+			Accounts: accounts,
+		},
+	}
+
+	for _, op := range tx.B.Operations {
+		var bo BlockOperation
+		if bo, err = GetBlockOperation(nr.storage, NewBlockOperationKey(op, tx)); err != nil {
+			continue
+		}
+		events = append(events, StreamEvent{
+			ID:       bo.Hash,
+			Type:     StreamEventOperation,
+			Data:     NewOperationResponse(bo),
+			Accounts: bo.Accounts(),
+		})
+	}
+
+	for _, address := range accounts {
+		var ba *BlockAccount
+		if ba, err = GetBlockAccount(nr.storage, address); err != nil {
+			continue
+		}
+		events = append(events, StreamEvent{
+			ID:       bt.Hash,
+			Type:     StreamEventAccount,
+			Data:     NewAccountResponse(ba),
+			Accounts: []string{address},
+		})
+	}
+
+	nr.stream.Publish(events...)
+}
+
+func writeStreamEvent(w http.ResponseWriter, event StreamEvent) (err error) {
+	var b []byte
+	if b, err = json.Marshal(event.Data); err != nil {
+		return
+	}
+	_, err = fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", event.ID, event.Type, b)
+
+	return
+}
+
+// handleStream sends the selected events to the client as the Server-Sent
+// Events until the client closes the connection.
+func (nr *NodeRunner) handleStream(w http.ResponseWriter, r *http.Request, filter StreamFilterFunc) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeAPIError(w, r, http.StatusInternalServerError, errors.New("streaming is not supported"))
+		return
+	}
+
+	sub, err := nr.stream.Subscribe(filter)
+	if err != nil {
+		writeAPIError(w, r, http.StatusServiceUnavailable, err)
+		return
+	}
+	defer nr.stream.Unsubscribe(sub)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	ticker := time.NewTicker(StreamHeartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+			if _, err = fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+		case event, ok := <-sub.Events:
+			if !ok { // dropped, because the client is too slow
+				return
+			}
+			if err = writeStreamEvent(w, event); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
+
+// handleAPIStreamBlocks streams the new blocks; the event id is the height
+// of block.
+func (nr *NodeRunner) handleAPIStreamBlocks(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		writeAPIError(w, r, http.StatusMethodNotAllowed, nil)
+		return
+	}
+
+	nr.handleStream(w, r, func(event StreamEvent) bool {
+		return event.Type == StreamEventBlock
+	})
+}
+
+// handleAPIStreamTransactions streams the new transactions; with 'account',
+// only the transactions, which the account sends or receives. The event id is
+// the hash of transaction.
+func (nr *NodeRunner) handleAPIStreamTransactions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		writeAPIError(w, r, http.StatusMethodNotAllowed, nil)
+		return
+	}
+
+	account := r.URL.Query().Get("account")
+	if len(account) > 0 {
+		if _, err := ParseAccountAddress(account); err != nil {
+			writeAPIError(w, r, http.StatusBadRequest, errors.New("'account' must be the address"))
+			return
+		}
+	}
+
+	nr.handleStream(w, r, func(event StreamEvent) bool {
+		if event.Type != StreamEventTransaction {
+			return false
+		}
+		if len(account) < 1 {
+			return true
+		}
+		for _, a := range event.Accounts {
+			if a == account {
+				return true
+			}
+		}
+		return false
+	})
+}
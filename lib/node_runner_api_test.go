@@ -0,0 +1,1747 @@
+package sebak
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stellar/go/keypair"
+	"golang.org/x/net/websocket"
+
+	"boscoin.io/sebak/lib/error"
+	"boscoin.io/sebak/lib/graphql"
+	"boscoin.io/sebak/lib/network"
+)
+
+func TestNodeRunnerAPINextProposers(t *testing.T) {
+	defer sebaknetwork.CleanUpMemoryNetwork()
+
+	nodeRunners := createNodeRunners(3)
+	nr := nodeRunners[0]
+
+	var prev Block
+	for i := 0; i < 2; i++ {
+		prev = NewBlock(prev, "")
+		prev.Save(nr.Storage())
+	}
+
+	handler := nr.APIHandlers()[APIVersionPrefix+GetNextProposersPattern]
+
+	// without the view change, every node proposes, so no proposer is
+	// expected
+	req := httptest.NewRequest("GET", APIVersionPrefix+GetNextProposersPattern, nil)
+	w := httptest.NewRecorder()
+	handler(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("proposers must not be scheduled without the proposer timeout: %d", w.Code)
+		return
+	}
+	nr.SetProposerTimeout(time.Second)
+
+	req = httptest.NewRequest("GET", APIVersionPrefix+GetNextProposersPattern+"?limit=4", nil)
+	w = httptest.NewRecorder()
+	handler(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("failed to get next proposers: %d", w.Code)
+		return
+	}
+
+	var response NextProposersResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Error(err)
+		return
+	}
+	if response.Height != 2 || len(response.Proposers) != 4 {
+		t.Error("wrong next proposers")
+		return
+	}
+
+	// all the nodes have same schedule
+	for _, other := range nodeRunners[1:] {
+		expected := NewProposerSchedule(other.Node()).NextProposers(2, 4)
+		for i, p := range response.Proposers {
+			if p.Height != uint64(3+i) || p.Address != expected[i].Address {
+				t.Error("proposer schedule does not match")
+				return
+			}
+		}
+	}
+
+	// round-robin
+	if response.Proposers[0].Address != response.Proposers[3].Address {
+		t.Error("proposers must be scheduled by round-robin")
+		return
+	}
+
+	req = httptest.NewRequest("GET", APIVersionPrefix+GetNextProposersPattern+"?limit=0", nil)
+	w = httptest.NewRecorder()
+	handler(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Error("invalid limit must be refused")
+		return
+	}
+}
+
+func TestNodeRunnerAPIProposerSchedule(t *testing.T) {
+	defer sebaknetwork.CleanUpMemoryNetwork()
+
+	nodeRunners := createNodeRunners(3)
+	nr := nodeRunners[0]
+
+	var prev Block
+	for i := 0; i < 4; i++ {
+		prev = NewBlock(prev, "")
+		prev.Save(nr.Storage())
+	}
+
+	handler := nr.APIHandlers()[APIVersionPrefix+GetProposerSchedulePattern]
+
+	// without the view change, every node proposes, so no proposer is
+	// expected
+	req := httptest.NewRequest("GET", APIVersionPrefix+GetProposerSchedulePattern, nil)
+	w := httptest.NewRecorder()
+	handler(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("proposers must not be scheduled without the proposer timeout: %d", w.Code)
+		return
+	}
+	nr.SetProposerTimeout(time.Second)
+
+	address := nodeRunners[1].Node().Address()
+	req = httptest.NewRequest("GET", APIVersionPrefix+GetProposerSchedulePattern+"?rounds=2&address="+address, nil)
+	w = httptest.NewRecorder()
+	handler(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("failed to get proposer schedule: %d", w.Code)
+		return
+	}
+
+	var response ProposerScheduleResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Error(err)
+		return
+	}
+	if response.Height != 4 || response.Validators != 3 || len(response.Proposers) != 6 {
+		t.Errorf("wrong proposer schedule: %v", response)
+		return
+	}
+
+	// every turn of the validator is in the schedule
+	if len(response.Turns) != 2 || response.Turns[1]-response.Turns[0] != 3 {
+		t.Errorf("wrong turns: %v", response.Turns)
+		return
+	}
+	var turns []uint64
+	for _, p := range response.Proposers {
+		if p.Address == address {
+			turns = append(turns, p.Height)
+		}
+	}
+	if len(turns) != 2 || turns[0] != response.Turns[0] || turns[1] != response.Turns[1] {
+		t.Errorf("turns do not match with schedule: %v != %v", turns, response.Turns)
+		return
+	}
+
+	for _, query := range []string{"?rounds=0", "?rounds=11"} {
+		req = httptest.NewRequest("GET", APIVersionPrefix+GetProposerSchedulePattern+query, nil)
+		w = httptest.NewRecorder()
+		handler(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("invalid rounds must be refused: %s", query)
+			return
+		}
+	}
+
+	req = httptest.NewRequest("GET", APIVersionPrefix+GetProposerSchedulePattern+"?address=unknown", nil)
+	w = httptest.NewRecorder()
+	handler(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Error("unknown validator must be not found")
+		return
+	}
+}
+
+func TestNodeRunnerAPIAccountData(t *testing.T) {
+	defer sebaknetwork.CleanUpMemoryNetwork()
+
+	nodeRunners := createNodeRunners(1)
+	nr := nodeRunners[0]
+
+	ba := testMakeBlockAccount()
+	ba.Save(nr.Storage())
+
+	// the entries has the confirmed time of block transaction
+	tx := Transaction{H: TransactionHeader{Hash: "tx-hash"}, B: TransactionBody{Source: ba.Address}}
+	bt := NewBlockTransactionFromTransaction(tx, nil)
+	bt.Confirmed = "2018-09-01T00:00:00.000000000Z"
+	if err := bt.Save(nr.Storage()); err != nil {
+		t.Error(err)
+		return
+	}
+
+	for _, name := range []string{"anchor-3", "anchor-1", "memo", "anchor-2"} {
+		op := Operation{
+			H: OperationHeader{Type: OperationManageData},
+			B: NewOperationBodyManageData(name, []byte(name)),
+		}
+		if err := FinishOperation(nr.Storage(), tx, op); err != nil {
+			t.Error(err)
+			return
+		}
+	}
+
+	// empty value removes the entry
+	op := Operation{
+		H: OperationHeader{Type: OperationManageData},
+		B: NewOperationBodyManageData("memo", nil),
+	}
+	if err := FinishOperation(nr.Storage(), tx, op); err != nil {
+		t.Error(err)
+		return
+	}
+
+	handler := nr.APIHandlers()[APIVersionPrefix+GetAccountsPattern]
+	request := func(query string) (w *httptest.ResponseRecorder, response AccountDataResponse) {
+		path := APIVersionPrefix + GetAccountsPattern + ba.Address + "/" + GetAccountDataSubPattern + query
+		w = httptest.NewRecorder()
+		handler(w, httptest.NewRequest("GET", path, nil))
+		json.Unmarshal(w.Body.Bytes(), &response)
+		return
+	}
+
+	w, response := request("?limit=2&mode=raw")
+	if w.Code != http.StatusOK {
+		t.Errorf("failed to get account data: %d", w.Code)
+		return
+	}
+	if len(response.Entries) != 2 || response.Entries[0].Name != "anchor-1" || response.Entries[0].Value != "anchor-1" {
+		t.Errorf("wrong entries: %v", response.Entries)
+		return
+	}
+	if response.Entries[0].Updated != bt.Confirmed {
+		t.Errorf("wrong updated time: %s", response.Entries[0].Updated)
+		return
+	}
+	if response.NextCursor != "anchor-2" {
+		t.Errorf("wrong next cursor: %s", response.NextCursor)
+		return
+	}
+
+	_, response = request("?prefix=anchor-&cursor=" + response.NextCursor)
+	if len(response.Entries) != 1 || response.Entries[0].Name != "anchor-3" || len(response.NextCursor) > 0 {
+		t.Errorf("wrong next page: %v", response)
+		return
+	}
+	if response.Entries[0].Value != "YW5jaG9yLTM=" {
+		t.Error("value must be base64 encoded by default")
+		return
+	}
+
+	// unknown account
+	w = httptest.NewRecorder()
+	handler(w, httptest.NewRequest("GET", APIVersionPrefix+GetAccountsPattern+testMakeBlockAccount().Address+"/data", nil))
+	if w.Code != http.StatusNotFound {
+		t.Error("unknown account must be not found")
+		return
+	}
+
+	if w, _ = request("?mode=hex"); w.Code != http.StatusBadRequest {
+		t.Error("invalid mode must be refused")
+		return
+	}
+
+	// by the exact name
+	if _, response = request("?mode=raw&name=anchor-2"); len(response.Entries) != 1 || response.Entries[0].Value != "anchor-2" {
+		t.Errorf("wrong entry: %v", response.Entries)
+		return
+	}
+	if w, _ = request("?name=memo"); w.Code != http.StatusNotFound {
+		t.Error("removed entry must be not found")
+		return
+	}
+}
+
+func TestNodeRunnerAPIAccountTransactions(t *testing.T) {
+	defer sebaknetwork.CleanUpMemoryNetwork()
+
+	nodeRunners := createNodeRunners(1)
+	nr := nodeRunners[0]
+
+	kp, _ := keypair.Random()
+	target := testMakeBlockAccount()
+	target.Save(nr.Storage())
+
+	var hashes []string
+	for i := 0; i < 3; i++ {
+		bt := NewBlockTransactionFromTransaction(makeTransactionPayment(kp, target.Address, Amount(1)), nil)
+		if err := bt.Save(nr.Storage()); err != nil {
+			t.Error(err)
+			return
+		}
+		hashes = append(hashes, bt.Hash)
+	}
+
+	handler := nr.APIHandlers()[APIVersionPrefix+GetAccountsPattern]
+	request := func(query string) (w *httptest.ResponseRecorder, response AccountTransactionsResponse) {
+		path := APIVersionPrefix + GetAccountsPattern + target.Address + "/" + GetAccountTransactionsSubPattern + query
+		w = httptest.NewRecorder()
+		handler(w, httptest.NewRequest("GET", path, nil))
+		json.Unmarshal(w.Body.Bytes(), &response)
+		return
+	}
+
+	// the received transactions are also in the history of target
+	w, response := request("?limit=2")
+	if w.Code != http.StatusOK {
+		t.Errorf("failed to get account transactions: %d", w.Code)
+		return
+	}
+	if response.Order != APIOrderDesc || len(response.Transactions) != 2 || response.Transactions[0].Hash != hashes[2] {
+		t.Errorf("wrong transactions: %v", response)
+		return
+	}
+	if response.NextCursor != hashes[1] || response.Transactions[0].Source != kp.Address() {
+		t.Errorf("wrong next cursor: %v", response)
+		return
+	}
+
+	_, response = request("?limit=2&cursor=" + response.NextCursor)
+	if len(response.Transactions) != 1 || response.Transactions[0].Hash != hashes[0] || len(response.NextCursor) > 0 {
+		t.Errorf("wrong next page: %v", response)
+		return
+	}
+
+	_, response = request("?order=asc")
+	if len(response.Transactions) != 3 || response.Transactions[0].Hash != hashes[0] {
+		t.Errorf("wrong ascending order: %v", response)
+		return
+	}
+
+	if w, _ = request("?order=random"); w.Code != http.StatusBadRequest {
+		t.Error("invalid order must be refused")
+		return
+	}
+	if w, _ = request("?cursor=unknown"); w.Code != http.StatusBadRequest {
+		t.Error("unknown cursor must be refused")
+		return
+	}
+}
+
+func TestNodeRunnerAPIOperations(t *testing.T) {
+	defer sebaknetwork.CleanUpMemoryNetwork()
+
+	nodeRunners := createNodeRunners(1)
+	nr := nodeRunners[0]
+
+	kp, _ := keypair.Random()
+	target := testMakeBlockAccount()
+	target.Save(nr.Storage())
+
+	var hashes []string
+	for i := 0; i < 3; i++ {
+		tx := makeTransactionPayment(kp, target.Address, Amount(1))
+		if i == 1 {
+			tx.B.Operations[0] = Operation{
+				H: OperationHeader{Type: OperationCreateAccount},
+				B: NewOperationBodyCreateAccount(target.Address, Amount(1)),
+			}
+		}
+		bt := NewBlockTransactionFromTransaction(tx, nil)
+		if err := bt.Save(nr.Storage()); err != nil {
+			t.Error(err)
+			return
+		}
+		hashes = append(hashes, NewBlockOperationKey(tx.B.Operations[0], tx))
+	}
+
+	w := httptest.NewRecorder()
+	nr.APIHandlers()[APIVersionPrefix+GetOperationsPattern](w, httptest.NewRequest("GET", APIVersionPrefix+GetOperationsPattern+hashes[0], nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("failed to get operation: %d", w.Code)
+		return
+	}
+	var operation OperationResponse
+	json.Unmarshal(w.Body.Bytes(), &operation)
+	if operation.Hash != hashes[0] || operation.Source != kp.Address() || operation.Target != target.Address || operation.Type != OperationPayment {
+		t.Errorf("wrong operation: %v", operation)
+		return
+	}
+
+	w = httptest.NewRecorder()
+	nr.APIHandlers()[APIVersionPrefix+GetOperationsPattern](w, httptest.NewRequest("GET", APIVersionPrefix+GetOperationsPattern+"unknown", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("unknown operation must be not found: %d", w.Code)
+		return
+	}
+
+	handler := nr.APIHandlers()[APIVersionPrefix+GetAccountsPattern]
+	request := func(query string) (w *httptest.ResponseRecorder, response AccountOperationsResponse) {
+		path := APIVersionPrefix + GetAccountsPattern + target.Address + "/" + GetAccountOperationsSubPattern + query
+		w = httptest.NewRecorder()
+		handler(w, httptest.NewRequest("GET", path, nil))
+		json.Unmarshal(w.Body.Bytes(), &response)
+		return
+	}
+
+	w, response := request("?limit=2")
+	if w.Code != http.StatusOK {
+		t.Errorf("failed to get account operations: %d", w.Code)
+		return
+	}
+	if len(response.Operations) != 2 || response.Operations[0].Hash != hashes[2] || response.NextCursor != hashes[1] {
+		t.Errorf("wrong operations: %v", response)
+		return
+	}
+
+	_, response = request("?limit=2&cursor=" + response.NextCursor)
+	if len(response.Operations) != 1 || response.Operations[0].Hash != hashes[0] || len(response.NextCursor) > 0 {
+		t.Errorf("wrong next page: %v", response)
+		return
+	}
+
+	_, response = request("?order=asc&type=" + OperationPayment)
+	if len(response.Operations) != 2 || response.Operations[0].Hash != hashes[0] || response.Operations[1].Hash != hashes[2] {
+		t.Errorf("operations must be filtered by type: %v", response)
+		return
+	}
+
+	if w, _ = request("?type=unknown"); w.Code != http.StatusBadRequest {
+		t.Error("unknown type must be refused")
+		return
+	}
+	if w, _ = request("?cursor=unknown"); w.Code != http.StatusBadRequest {
+		t.Error("unknown cursor must be refused")
+		return
+	}
+}
+
+func TestNodeRunnerAPIBlock(t *testing.T) {
+	defer sebaknetwork.CleanUpMemoryNetwork()
+
+	nodeRunners := createNodeRunners(1)
+	nr := nodeRunners[0]
+
+	var hashes []string
+	for i := 0; i < 2; i++ {
+		_, tx := TestMakeTransaction(networkID, 1)
+		message, _ := tx.Serialize()
+		bt := NewBlockTransactionFromTransaction(tx, message)
+		if err := bt.Save(nr.Storage()); err != nil {
+			t.Error(err)
+			return
+		}
+		hashes = append(hashes, tx.GetHash())
+	}
+	block := NewBlock(Block{}, "state", hashes...)
+	if err := block.Save(nr.Storage()); err != nil {
+		t.Error(err)
+		return
+	}
+
+	handler := nr.APIHandlers()[APIVersionPrefix+GetBlocksPattern]
+	request := func(path string) (w *httptest.ResponseRecorder) {
+		w = httptest.NewRecorder()
+		handler(w, httptest.NewRequest("GET", APIVersionPrefix+GetBlocksPattern+path, nil))
+		return
+	}
+
+	for _, id := range []string{"1", block.Hash} {
+		w := request(id)
+		if w.Code != http.StatusOK {
+			t.Errorf("failed to get block by '%s': %d", id, w.Code)
+			return
+		}
+		var response BlockResponse
+		json.Unmarshal(w.Body.Bytes(), &response)
+		if response.Hash != block.Hash || response.Height != 1 || response.TransactionCount != 2 || len(response.Transactions) != 2 {
+			t.Errorf("wrong block: %v", response)
+			return
+		}
+		if response.Transactions[0].Hash != hashes[0] || response.Transactions[1].Hash != hashes[1] {
+			t.Errorf("wrong transactions: %v", response.Transactions)
+			return
+		}
+	}
+
+	w := request(block.Hash + "?headerOnly=true")
+	if w.Code != http.StatusOK {
+		t.Errorf("failed to get block header: %d", w.Code)
+		return
+	}
+	var header map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &header)
+	if _, found := header["transactions"]; found || header["state_hash"] != "state" || header["transaction_count"] != float64(2) {
+		t.Errorf("wrong block header: %v", header)
+		return
+	}
+
+	for _, id := range []string{"2", "unknown"} {
+		if w = request(id); w.Code != http.StatusNotFound {
+			t.Errorf("block '%s' must be not found: %d", id, w.Code)
+			return
+		}
+	}
+	if w = request("1?headerOnly=maybe"); w.Code != http.StatusBadRequest {
+		t.Errorf("invalid headerOnly must be refused: %d", w.Code)
+		return
+	}
+}
+
+func TestNodeRunnerAPIAccountsBatchGet(t *testing.T) {
+	defer sebaknetwork.CleanUpMemoryNetwork()
+
+	nodeRunners := createNodeRunners(1)
+	nr := nodeRunners[0]
+
+	var accounts []*BlockAccount
+	for i := 0; i < 2; i++ {
+		ba := testMakeBlockAccount()
+		ba.Save(nr.Storage())
+		accounts = append(accounts, ba)
+	}
+	unknown, _ := keypair.Random()
+
+	handler := nr.APIHandlers()[APIVersionPrefix+PostAccountsBatchGetPattern]
+	request := func(addresses ...string) (w *httptest.ResponseRecorder, response AccountsBatchGetResponse) {
+		body, _ := json.Marshal(AccountsBatchGetRequest{Addresses: addresses})
+		w = httptest.NewRecorder()
+		handler(w, httptest.NewRequest("POST", APIVersionPrefix+PostAccountsBatchGetPattern, strings.NewReader(string(body))))
+		json.Unmarshal(w.Body.Bytes(), &response)
+		return
+	}
+
+	w, response := request(accounts[1].Address, unknown.Address(), accounts[0].Address)
+	if w.Code != http.StatusOK {
+		t.Errorf("failed to get accounts: %d", w.Code)
+		return
+	}
+	if len(response.Accounts) != 2 || response.Accounts[0].Address != accounts[1].Address || response.Accounts[1].Address != accounts[0].Address {
+		t.Errorf("wrong accounts: %v", response.Accounts)
+		return
+	}
+	if response.Accounts[0].Balance != accounts[1].GetBalance() || response.Accounts[0].Checkpoint != accounts[1].Checkpoint {
+		t.Errorf("wrong account: %v", response.Accounts[0])
+		return
+	}
+	if len(response.NotFound) != 1 || response.NotFound[0] != unknown.Address() {
+		t.Errorf("wrong not found: %v", response.NotFound)
+		return
+	}
+
+	if w, _ = request(); w.Code != http.StatusBadRequest {
+		t.Error("empty addresses must be refused")
+		return
+	}
+	if w, _ = request(accounts[0].Address, "unknown"); w.Code != http.StatusBadRequest {
+		t.Error("invalid address must be refused")
+		return
+	}
+	many := make([]string, MaxAccountsBatchGetAddresses+1)
+	for i := range many {
+		many[i] = accounts[0].Address
+	}
+	if w, _ = request(many...); w.Code != http.StatusBadRequest {
+		t.Error("too many addresses must be refused")
+		return
+	}
+}
+
+func TestNodeRunnerAPIAccountStatement(t *testing.T) {
+	defer sebaknetwork.CleanUpMemoryNetwork()
+
+	nodeRunners := createNodeRunners(1)
+	nr := nodeRunners[0]
+
+	kp, _ := keypair.Random()
+	target := testMakeBlockAccount()
+	target.Save(nr.Storage())
+
+	initial := LedgerEntry{Reason: LedgerReasonGenesis, Debit: LedgerAccountGenesis, Credit: target.Address, Amount: target.GetBalance()}
+	if err := SaveLedgerEntries(nr.Storage(), initial); err != nil {
+		t.Error(err)
+		return
+	}
+	tx := makeTransactionPayment(kp, target.Address, Amount(1))
+	if err := SaveLedgerEntries(nr.Storage(), NewLedgerEntriesFromTransaction(tx, "")...); err != nil {
+		t.Error(err)
+		return
+	}
+
+	handler := nr.APIHandlers()[APIVersionPrefix+GetAccountsPattern]
+	request := func(query string) (w *httptest.ResponseRecorder, response AccountStatementResponse) {
+		path := APIVersionPrefix + GetAccountsPattern + target.Address + "/" + GetAccountStatementSubPattern + query
+		w = httptest.NewRecorder()
+		handler(w, httptest.NewRequest("GET", path, nil))
+		json.Unmarshal(w.Body.Bytes(), &response)
+		return
+	}
+
+	// the fee entry of payment is not in the statement of target
+	w, response := request("?limit=1")
+	if w.Code != http.StatusOK {
+		t.Errorf("failed to get account statement: %d", w.Code)
+		return
+	}
+	if len(response.Entries) != 1 || response.Entries[0].Reason != LedgerReasonPayment || response.Entries[0].Side != AccountStatementSideCredit {
+		t.Errorf("wrong entries: %v", response)
+		return
+	}
+	if response.NextCursor != "2" || response.LedgerBalance != nil {
+		t.Errorf("wrong next cursor: %v", response)
+		return
+	}
+
+	_, response = request("?limit=1&cursor=" + response.NextCursor)
+	if len(response.Entries) != 1 || response.Entries[0].Reason != LedgerReasonGenesis || len(response.NextCursor) > 0 {
+		t.Errorf("wrong next page: %v", response)
+		return
+	}
+
+	_, response = request("?order=asc&verify=1")
+	if len(response.Entries) != 2 || response.Entries[0].Sequence != 1 {
+		t.Errorf("wrong ascending order: %v", response)
+		return
+	}
+	if response.LedgerBalance == nil || *response.LedgerBalance != int64(target.GetBalance())+1 {
+		t.Errorf("wrong ledger balance: %v", response)
+		return
+	}
+
+	if w, _ = request("?cursor=first"); w.Code != http.StatusBadRequest {
+		t.Error("invalid cursor must be refused")
+		return
+	}
+}
+
+func TestNodeRunnerAPIStream(t *testing.T) {
+	defer sebaknetwork.CleanUpMemoryNetwork()
+
+	nodeRunners := createNodeRunners(1)
+	nr := nodeRunners[0]
+
+	mux := http.NewServeMux()
+	for pattern, handler := range nr.APIHandlers() {
+		mux.HandleFunc(pattern, handler)
+	}
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	kp, _ := keypair.Random()
+	subscribe := func(path string) (*http.Response, *bufio.Reader) {
+		response, err := http.Get(server.URL + APIVersionPrefix + path)
+		if err != nil {
+			return nil, nil
+		}
+		return response, bufio.NewReader(response.Body)
+	}
+	readEvent := func(reader *bufio.Reader) (lines []string) {
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil || line == "\n" {
+				return
+			}
+			lines = append(lines, strings.TrimSpace(line))
+		}
+	}
+
+	blocks, blocksReader := subscribe(GetStreamBlocksPattern)
+	if blocks == nil || blocks.StatusCode != http.StatusOK || blocks.Header.Get("Content-Type") != "text/event-stream" {
+		t.Error("failed to subscribe blocks")
+		return
+	}
+	defer blocks.Body.Close()
+
+	transactions, transactionsReader := subscribe(GetStreamTransactionsPattern + "?account=" + kp.Address())
+	if transactions == nil || transactions.StatusCode != http.StatusOK {
+		t.Error("failed to subscribe transactions")
+		return
+	}
+	defer transactions.Body.Close()
+
+	if response, _ := subscribe(GetStreamTransactionsPattern + "?account=unknown"); response == nil || response.StatusCode != http.StatusBadRequest {
+		t.Error("invalid account must be refused")
+		return
+	}
+
+	other, _ := keypair.Random()
+	nr.stream.Publish(
+		StreamEvent{ID: "2", Type: StreamEventBlock, Data: Block{Height: 2}},
+		StreamEvent{ID: "other", Type: StreamEventTransaction, Data: AccountTransactionEntry{Hash: "other"}, Accounts: []string{other.Address()}},
+		StreamEvent{ID: "mine", Type: StreamEventTransaction, Data: AccountTransactionEntry{Hash: "mine"}, Accounts: []string{other.Address(), kp.Address()}},
+	)
+
+	if lines := readEvent(blocksReader); len(lines) != 3 || lines[0] != "id: 2" || lines[1] != "event: block" || !strings.Contains(lines[2], `"height":2`) {
+		t.Errorf("wrong block event: %v", lines)
+		return
+	}
+	if lines := readEvent(transactionsReader); len(lines) != 3 || lines[0] != "id: mine" || lines[1] != "event: transaction" {
+		t.Errorf("only the transactions of account must be sent: %v", lines)
+		return
+	}
+}
+
+func TestNodeRunnerAPIWebSocket(t *testing.T) {
+	defer sebaknetwork.CleanUpMemoryNetwork()
+
+	nodeRunners := createNodeRunners(1)
+	nr := nodeRunners[0]
+
+	mux := http.NewServeMux()
+	for pattern, handler := range nr.APIHandlers() {
+		mux.HandleFunc(pattern, handler)
+	}
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	conn, err := websocket.Dial("ws"+strings.TrimPrefix(server.URL, "http")+APIVersionPrefix+GetWebSocketPattern, "", server.URL)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	request := func(req WebSocketRequest) (message WebSocketMessage) {
+		websocket.JSON.Send(conn, req)
+		websocket.JSON.Receive(conn, &message)
+		return
+	}
+
+	kp, _ := keypair.Random()
+	requests := []WebSocketRequest{
+		{Op: WebSocketOpSubscribe, ID: "blocks", Topic: WebSocketTopicBlocks},
+		{Op: WebSocketOpSubscribe, ID: "account", Topic: WebSocketTopicAccount, Account: kp.Address()},
+		{Op: WebSocketOpSubscribe, ID: "payments", Topic: WebSocketTopicOperations, Type: OperationPayment},
+	}
+	for _, req := range requests {
+		if message := request(req); message.Type != WebSocketMessageSubscribed || message.ID != req.ID {
+			t.Errorf("failed to subscribe: %v", message)
+			return
+		}
+	}
+
+	if message := request(requests[0]); message.Type != WebSocketMessageError {
+		t.Error("same subscription id must be refused")
+		return
+	}
+	message := request(WebSocketRequest{Op: WebSocketOpSubscribe, ID: "wrong", Topic: WebSocketTopicAccount, Account: "unknown"})
+	if message.Type != WebSocketMessageError || message.Error.Result != sebakerror.ResultOperationMalformed {
+		t.Errorf("invalid account must be refused: %v", message)
+		return
+	}
+	if message := request(WebSocketRequest{Op: WebSocketOpUnsubscribe, ID: "blocks"}); message.Type != WebSocketMessageUnsubscribed {
+		t.Errorf("failed to unsubscribe: %v", message)
+		return
+	}
+
+	other, _ := keypair.Random()
+	nr.stream.Publish(
+		StreamEvent{ID: "2", Type: StreamEventBlock, Data: Block{Height: 2}},
+		StreamEvent{ID: "a", Type: StreamEventOperation, Data: OperationResponse{Type: OperationCreateAccount}, Accounts: []string{kp.Address()}},
+		StreamEvent{ID: "b", Type: StreamEventAccount, Data: AccountResponse{Address: other.Address()}, Accounts: []string{other.Address()}},
+		StreamEvent{ID: "c", Type: StreamEventOperation, Data: OperationResponse{Hash: "c", Type: OperationPayment}, Accounts: []string{other.Address()}},
+		StreamEvent{ID: "d", Type: StreamEventAccount, Data: AccountResponse{Address: kp.Address()}, Accounts: []string{kp.Address()}},
+	)
+
+	// only the events of the subscriptions are sent in order
+	var received []string
+	for i := 0; i < 2; i++ {
+		var message WebSocketMessage
+		if err = websocket.JSON.Receive(conn, &message); err != nil {
+			t.Error(err)
+			return
+		}
+		received = append(received, message.ID+":"+message.Event)
+	}
+	if strings.Join(received, ",") != "payments:operation,account:account" {
+		t.Errorf("wrong events: %v", received)
+		return
+	}
+}
+
+func TestNodeRunnerAPIResults(t *testing.T) {
+	defer sebaknetwork.CleanUpMemoryNetwork()
+
+	nodeRunners := createNodeRunners(1)
+	nr := nodeRunners[0]
+
+	w := httptest.NewRecorder()
+	nr.APIHandlers()[APIVersionPrefix+GetResultsPattern](w, httptest.NewRequest("GET", APIVersionPrefix+GetResultsPattern, nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("failed to get results: %d", w.Code)
+		return
+	}
+
+	var results []sebakerror.Result
+	json.Unmarshal(w.Body.Bytes(), &results)
+
+	var found bool
+	for _, result := range results {
+		if result.Code == sebakerror.ResultTransactionPoolFull {
+			found = result.Retryable
+		}
+	}
+	if len(results) != len(sebakerror.Results()) || !found {
+		t.Errorf("wrong results: %v", results)
+		return
+	}
+}
+
+func TestNodeRunnerAPINode(t *testing.T) {
+	defer sebaknetwork.CleanUpMemoryNetwork()
+
+	nodeRunners := createNodeRunners(3)
+	nr := nodeRunners[0]
+
+	get := func(pattern string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", APIVersionPrefix+pattern, nil)
+		w := httptest.NewRecorder()
+		nr.APIHandlers()[APIVersionPrefix+pattern](w, req)
+		return w
+	}
+
+	w := get(GetNodePattern)
+	if w.Code != http.StatusOK {
+		t.Errorf("unexpected status: %d", w.Code)
+		return
+	}
+
+	var response NodeResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Error(err)
+		return
+	}
+	if response.Address != nr.Node().Address() || response.State != NodeStateBooting || response.Validators != 2 {
+		t.Errorf("unexpected response: %v", response)
+		return
+	}
+
+	nr.State().Transit(NodeStateConsensus)
+	json.Unmarshal(get(GetNodePattern).Body.Bytes(), &response)
+	if response.State != NodeStateConsensus || response.Transitions[NodeStateConsensus] != 1 {
+		t.Errorf("state change is not exposed: %v", response)
+		return
+	}
+
+	w = get(GetNodeMetricsPattern)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `sebak_node_state{state="consensus"} 1`) {
+		t.Errorf("unexpected metrics: %s", w.Body.String())
+		return
+	}
+}
+
+func TestNodeRunnerAPIOpenAPI(t *testing.T) {
+	defer sebaknetwork.CleanUpMemoryNetwork()
+
+	nr := createNodeRunners(1)[0]
+	nr.SetAPIConfig(APIConfig{BasePath: "/sebak"})
+
+	w := httptest.NewRecorder()
+	pattern := APIVersionPrefix + GetOpenAPIPattern
+	nr.APIHandlers()["/sebak"+pattern](w, httptest.NewRequest("GET", "/sebak"+pattern, nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("failed to get OpenAPI document: %d", w.Code)
+		return
+	}
+
+	var doc OpenAPIDocument
+	if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+		t.Error(err)
+		return
+	}
+	if doc.OpenAPI != OpenAPIVersion || len(doc.Servers) != 1 || doc.Servers[0].URL != "/sebak"+APIVersionPrefix {
+		t.Errorf("wrong document: %v %v", doc.OpenAPI, doc.Servers)
+		return
+	}
+
+	// every handler has the endpoints in the document
+	for _, route := range nr.APIRoutes() {
+		if _, found := nr.APIHandlers()["/sebak"+APIVersionPrefix+route.Pattern]; !found {
+			t.Errorf("handler of route is not found: %s", route.Pattern)
+			return
+		}
+		if len(route.Endpoints) < 1 {
+			t.Errorf("route has no endpoints: %s", route.Pattern)
+			return
+		}
+		for _, endpoint := range route.Endpoints {
+			if !strings.HasPrefix(endpoint.Path, strings.TrimSuffix(route.Pattern, "/")) {
+				t.Errorf("endpoint is not under the route: %s %s", endpoint.Path, route.Pattern)
+				return
+			}
+			if _, found := doc.Paths[endpoint.Path][strings.ToLower(endpoint.Method)]; !found {
+				t.Errorf("endpoint is not in the document: %s %s", endpoint.Method, endpoint.Path)
+				return
+			}
+		}
+	}
+
+	op := doc.Paths[GetAccountsPattern+"{address}/"+GetAccountDataSubPattern]["get"]
+	if op == nil || op.Parameters[0].Name != "address" || !op.Parameters[0].Required {
+		t.Errorf("wrong operation: %v", op)
+		return
+	}
+	if ref := op.Responses["200"].Content["application/json"].Schema.Ref; ref != "#/components/schemas/AccountDataResponse" {
+		t.Errorf("wrong response schema: %s", ref)
+		return
+	}
+	// `Amount` is string and the embedded `LedgerEntry` is flattened
+	if schema := doc.Components.Schemas["AccountStatementResponse"]; schema == nil || schema.Properties["balance"].Type != "string" {
+		t.Errorf("wrong schema: %v", schema)
+		return
+	}
+	if schema := doc.Components.Schemas["AccountStatementEntry"]; schema == nil || schema.Properties["debit"] == nil || schema.Properties["side"] == nil {
+		t.Errorf("wrong schema: %v", schema)
+		return
+	}
+}
+
+func TestNodeRunnerAPIHealth(t *testing.T) {
+	defer sebaknetwork.CleanUpMemoryNetwork()
+
+	nr := createNodeRunners(1)[0]
+
+	var prev Block
+	for i := 0; i < 3; i++ {
+		prev = NewBlock(prev, "")
+		prev.Save(nr.Storage())
+	}
+
+	get := func(pattern string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", pattern, nil)
+		w := httptest.NewRecorder()
+		nr.APIHandlers()[pattern](w, req)
+		return w
+	}
+
+	if w := get(GetHealthzPattern); w.Code != http.StatusOK {
+		t.Errorf("alive node must be healthy: %d", w.Code)
+		return
+	}
+
+	// the booting node is not ready
+	var response ReadinessResponse
+	w := get(GetReadyzPattern)
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if w.Code != http.StatusServiceUnavailable || response.Ready || response.Checks[0].OK {
+		t.Errorf("booting node must not be ready: %d %v", w.Code, response)
+		return
+	}
+
+	nr.State().Transit(NodeStateConsensus)
+	if w = get(GetReadyzPattern); w.Code != http.StatusOK {
+		t.Errorf("node must be ready: %s", w.Body.String())
+		return
+	}
+
+	// the validators announced the higher block
+	nr.observeNetworkHeight(prev.Height + DefaultReadyMaxBlocksBehind)
+	if w = get(GetReadyzPattern); w.Code != http.StatusOK {
+		t.Errorf("node within the max blocks behind must be ready: %s", w.Body.String())
+		return
+	}
+	nr.observeNetworkHeight(prev.Height + DefaultReadyMaxBlocksBehind + 1)
+	w = get(GetReadyzPattern)
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if w.Code != http.StatusServiceUnavailable || response.Checks[1].Name != "synced" || response.Checks[1].OK {
+		t.Errorf("node behind the network must not be ready: %s", w.Body.String())
+		return
+	}
+
+	// the probe of storage is removed
+	if exists, _ := nr.Storage().Has(ReadinessProbeKey + "1"); exists {
+		t.Error("readiness probe must be removed")
+		return
+	}
+
+	nr.State().Transit(NodeStateHalted)
+	if w = get(GetHealthzPattern); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("halted node must not be healthy: %d", w.Code)
+		return
+	}
+}
+
+func TestNodeRunnerAPINodePeers(t *testing.T) {
+	defer sebaknetwork.CleanUpMemoryNetwork()
+
+	nodeRunners := createNodeRunners(3)
+	nr := nodeRunners[0]
+
+	req := httptest.NewRequest("GET", APIVersionPrefix+GetNodePeersPattern, nil)
+	w := httptest.NewRecorder()
+	nr.APIHandlers()[APIVersionPrefix+GetNodePeersPattern](w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("unexpected status: %d", w.Code)
+		return
+	}
+
+	var response NodePeersResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Error(err)
+		return
+	}
+	if len(response.Peers) != 2 || response.ClockSkew != 0 || response.ClockSkewBudget != nr.NetworkParameters().BlockTime {
+		t.Errorf("unexpected response: %v", response)
+		return
+	}
+	for _, peer := range response.Peers {
+		if peer.Address == nr.Node().Address() {
+			t.Error("current node is listed in peers")
+			return
+		}
+		// not connected yet, so the clock is not estimated
+		if peer.Connected || peer.ClockOffset != nil {
+			t.Errorf("unexpected peer: %v", peer)
+			return
+		}
+	}
+
+	nr.SetProposerTimeout(time.Second)
+	if nr.ClockSkewBudget() != time.Second {
+		t.Errorf("proposer timeout must be the budget: %s", nr.ClockSkewBudget())
+		return
+	}
+}
+
+func TestNodeRunnerAPIFinality(t *testing.T) {
+	defer sebaknetwork.CleanUpMemoryNetwork()
+
+	nodeRunners := createNodeRunners(1)
+	nr := nodeRunners[0]
+
+	handler := nr.APIHandlers()[APIVersionPrefix+GetFinalityPattern]
+
+	req := httptest.NewRequest("GET", APIVersionPrefix+GetFinalityPattern, nil)
+	w := httptest.NewRecorder()
+	handler(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("no finality must be not found: %d", w.Code)
+		return
+	}
+
+	var apiError APIError
+	json.Unmarshal(w.Body.Bytes(), &apiError)
+	if apiError.Result != sebakerror.ResultNotFound || apiError.Instance != APIVersionPrefix+GetFinalityPattern {
+		t.Errorf("wrong error: %s", w.Body.String())
+		return
+	}
+
+	block := NewBlock(Block{}, "")
+	block.Save(nr.Storage())
+	NewFinality(block).Save(nr.Storage())
+
+	w = httptest.NewRecorder()
+	handler(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("failed to get finality: %d", w.Code)
+		return
+	}
+
+	var f Finality
+	if err := json.Unmarshal(w.Body.Bytes(), &f); err != nil {
+		t.Error(err)
+		return
+	}
+	if f.Hash != block.Hash || f.Height != block.Height {
+		t.Errorf("wrong finality: %v", f)
+		return
+	}
+}
+
+func TestNodeRunnerAPIStats(t *testing.T) {
+	defer sebaknetwork.CleanUpMemoryNetwork()
+
+	nodeRunners := createNodeRunners(1)
+	nr := nodeRunners[0]
+
+	_, tx := TestMakeTransaction(networkID, 1)
+	UpdateChainStats(nr.Storage(), NewBlock(Block{}, ""), tx)
+
+	handler := nr.APIHandlers()[APIVersionPrefix+GetStatsPattern]
+
+	req := httptest.NewRequest("GET", APIVersionPrefix+GetStatsPattern+"?period=epoch", nil)
+	w := httptest.NewRecorder()
+	handler(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("failed to get stats: %d", w.Code)
+		return
+	}
+
+	var response StatsResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Error(err)
+		return
+	}
+	if response.Period != ChainStatsPeriodEpoch || len(response.Stats) != 1 || response.Stats[0].TransactionCount != 1 {
+		t.Errorf("wrong stats: %v", response)
+		return
+	}
+
+	req = httptest.NewRequest("GET", APIVersionPrefix+GetStatsPattern+"?period=week", nil)
+	w = httptest.NewRecorder()
+	handler(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Error("unknown period must be refused")
+		return
+	}
+}
+
+func TestNodeRunnerAPIGraphQL(t *testing.T) {
+	defer sebaknetwork.CleanUpMemoryNetwork()
+
+	nodeRunners := createNodeRunners(1)
+	nr := nodeRunners[0]
+
+	if _, found := nr.APIHandlers()[APIVersionPrefix+GetGraphQLPattern]; found {
+		t.Error("GraphQL must be disabled by default")
+		return
+	}
+	nr.SetGraphQL(true)
+
+	kp, _ := keypair.Random()
+	ba := NewBlockAccount(kp.Address(), Amount(BaseFee*100), "")
+	ba.Save(nr.Storage())
+
+	var hashes []string
+	for i := 0; i < 3; i++ {
+		tx := makeTransactionPayment(kp, testMakeBlockAccount().Address, Amount(1))
+		bt := NewBlockTransactionFromTransaction(tx, nil)
+		if err := bt.Save(nr.Storage()); err != nil {
+			t.Error(err)
+			return
+		}
+		hashes = append(hashes, tx.GetHash())
+	}
+	block := NewBlock(Block{}, "", hashes...)
+	block.Save(nr.Storage())
+
+	handler := nr.APIHandlers()[APIVersionPrefix+GetGraphQLPattern]
+
+	type page struct {
+		Nodes []struct {
+			Hash       string
+			Source     string
+			Operations []struct {
+				Type   string
+				Amount string
+			}
+		}
+		NextCursor string
+	}
+	var response struct {
+		Data struct {
+			Account struct {
+				Balance      string
+				Transactions page
+				Data         struct {
+					Nodes []struct {
+						Name  string
+						Value string
+					}
+					NextCursor string
+				}
+			}
+			LatestBlock struct {
+				Height       uint64
+				Transactions page
+			}
+			Unknown interface{}
+		}
+		Errors []sebakgraphql.Error
+	}
+
+	query := func(method string, body string, after string) (code int) {
+		response.Data.Account.Transactions = page{}
+		response.Errors = nil
+
+		var r *http.Request
+		if method == "GET" {
+			r = httptest.NewRequest("GET", APIVersionPrefix+GetGraphQLPattern+"?query="+url.QueryEscape(body), nil)
+		} else {
+			request := sebakgraphql.Request{
+				Query:     body,
+				Variables: map[string]interface{}{"address": kp.Address(), "after": after},
+			}
+			b, _ := json.Marshal(request)
+			r = httptest.NewRequest("POST", APIVersionPrefix+GetGraphQLPattern, strings.NewReader(string(b)))
+		}
+
+		w := httptest.NewRecorder()
+		handler(w, r)
+		json.Unmarshal(w.Body.Bytes(), &response)
+		return w.Code
+	}
+
+	accountQuery := `query ($address: String!, $after: String) {
+		account(address: $address) {
+			balance
+			transactions(first: 2, after: $after) { nodes { hash source operations { type amount } } nextCursor }
+		}
+	}`
+	if code := query("POST", accountQuery, ""); code != http.StatusOK || len(response.Errors) > 0 {
+		t.Errorf("failed to query account: %d %v", code, response.Errors)
+		return
+	}
+	account := response.Data.Account
+	if account.Balance != ba.Balance || len(account.Transactions.Nodes) != 2 || len(account.Transactions.NextCursor) < 1 {
+		t.Errorf("unexpected account: %v", account)
+		return
+	}
+	if node := account.Transactions.Nodes[0]; node.Source != kp.Address() || len(node.Operations) != 1 || node.Operations[0].Type != string(OperationPayment) || node.Operations[0].Amount != "1" {
+		t.Errorf("unexpected transaction: %v", node)
+		return
+	}
+
+	found := map[string]bool{}
+	for _, node := range account.Transactions.Nodes {
+		found[node.Hash] = true
+	}
+
+	if code := query("POST", accountQuery, account.Transactions.NextCursor); code != http.StatusOK || len(response.Errors) > 0 {
+		t.Errorf("failed to query next page: %d %v", code, response.Errors)
+		return
+	}
+	next := response.Data.Account.Transactions
+	if len(next.Nodes) != 1 || len(next.NextCursor) > 0 || found[next.Nodes[0].Hash] {
+		t.Errorf("unexpected next page: %v", next)
+		return
+	}
+	found[next.Nodes[0].Hash] = true
+	for _, hash := range hashes {
+		if !found[hash] {
+			t.Errorf("transaction is missing in pages: %s", hash)
+			return
+		}
+	}
+
+	if code := query("GET", `{ latestBlock { height transactions { nodes { hash } } } }`, ""); code != http.StatusOK {
+		t.Errorf("failed to query block: %d", code)
+		return
+	}
+	if b := response.Data.LatestBlock; b.Height != block.Height || len(b.Transactions.Nodes) != 3 || b.Transactions.Nodes[0].Hash != hashes[0] {
+		t.Errorf("unexpected block: %v", b)
+		return
+	}
+
+	NewBlockAccountData(kp.Address(), "profile.name", []byte("sebak"), "2018-09-01T00:00:00.000000000Z").Save(nr.Storage())
+	NewBlockAccountData(kp.Address(), "profile.site", []byte("boscoin.io"), "2018-09-01T00:00:00.000000000Z").Save(nr.Storage())
+	NewBlockAccountData(kp.Address(), "key", []byte("value"), "2018-09-01T00:00:00.000000000Z").Save(nr.Storage())
+
+	dataQuery := `query ($address: String!) {
+		account(address: $address) { data(prefix: "profile.", first: 1) { nodes { name value } nextCursor } }
+	}`
+	if code := query("POST", dataQuery, ""); code != http.StatusOK || len(response.Errors) > 0 {
+		t.Errorf("failed to query data: %d %v", code, response.Errors)
+		return
+	}
+	data := response.Data.Account.Data
+	if len(data.Nodes) != 1 || data.Nodes[0].Name != "profile.name" || data.Nodes[0].Value != base64.StdEncoding.EncodeToString([]byte("sebak")) || data.NextCursor != "profile.name" {
+		t.Errorf("unexpected data: %v", data)
+		return
+	}
+
+	if query("GET", `{ unknown }`, ""); len(response.Errors) != 1 || response.Data.Unknown != nil {
+		t.Errorf("unknown field must be reported: %v", response.Errors)
+		return
+	}
+	if code := query("GET", "", ""); code != http.StatusBadRequest {
+		t.Error("empty query must be refused")
+		return
+	}
+}
+
+func TestNodeRunnerAPIFaucet(t *testing.T) {
+	defer sebaknetwork.CleanUpMemoryNetwork()
+
+	nodeRunners := createNodeRunners(1)
+	nr := nodeRunners[0]
+
+	if _, found := nr.APIHandlers()[APIVersionPrefix+PostFaucetPattern]; found {
+		t.Error("faucet must be disabled by default")
+		return
+	}
+
+	kp, _ := keypair.Random()
+	NewBlockAccount(kp.Address(), Amount(BaseFee*1000000), "checkpoint").Save(nr.Storage())
+	faucet, _ := NewFaucet(networkID, kp, NewDefaultFaucetConfig())
+	nr.SetFaucet(faucet)
+	atomic.StoreInt32(&nr.quorumReady, 1)
+
+	received := make(chan sebaknetwork.Message, 1)
+	go func() {
+		received <- <-nr.Network().ReceiveMessage()
+	}()
+
+	handler := nr.APIHandlers()[APIVersionPrefix+PostFaucetPattern]
+	request := func(address string) (w *httptest.ResponseRecorder) {
+		body := strings.NewReader(`{"address": "` + address + `"}`)
+		w = httptest.NewRecorder()
+		handler(w, httptest.NewRequest("POST", APIVersionPrefix+PostFaucetPattern, body))
+		return
+	}
+
+	target, _ := keypair.Random()
+	w := request(target.Address())
+	if w.Code != http.StatusAccepted {
+		t.Errorf("failed to fund: %d %s", w.Code, w.Body.String())
+		return
+	}
+
+	var response FaucetResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+
+	select {
+	case message := <-received:
+		tx, err := NewTransactionFromJSON(message.Data)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if message.Type != sebaknetwork.MessageFromClient || tx.GetHash() != response.Hash {
+			t.Errorf("wrong message: %v", message)
+			return
+		}
+	case <-time.After(time.Second):
+		t.Error("transaction of faucet is not sent")
+		return
+	}
+
+	if w = request(target.Address()); w.Code != http.StatusTooManyRequests {
+		t.Errorf("address quota must be exceeded: %d", w.Code)
+		return
+	}
+	if w = request("invalid"); w.Code != http.StatusBadRequest {
+		t.Errorf("invalid address must be refused: %d", w.Code)
+		return
+	}
+}
+
+func TestNodeRunnerAPITransactions(t *testing.T) {
+	defer sebaknetwork.CleanUpMemoryNetwork()
+
+	nodeRunners := createNodeRunners(1)
+	nr := nodeRunners[0]
+	atomic.StoreInt32(&nr.quorumReady, 1)
+
+	kp, _ := keypair.Random()
+	target := testMakeBlockAccount()
+	target.Save(nr.Storage())
+	tx := makeTransactionPayment(kp, target.Address, Amount(1))
+	NewBlockAccount(kp.Address(), Amount(BaseFee*100), tx.B.Checkpoint).Save(nr.Storage())
+
+	received := make(chan sebaknetwork.Message, 1)
+	go func() {
+		received <- <-nr.Network().ReceiveMessage()
+	}()
+
+	handler := nr.APIHandlers()[APIVersionPrefix+PostTransactionsPattern]
+	submit := func(body string) (w *httptest.ResponseRecorder) {
+		w = httptest.NewRecorder()
+		handler(w, httptest.NewRequest("POST", APIVersionPrefix+PostTransactionsPattern, strings.NewReader(body)))
+		return
+	}
+
+	b, _ := tx.Serialize()
+	w := submit(string(b))
+	if w.Code != http.StatusAccepted {
+		t.Errorf("failed to submit transaction: %d %s", w.Code, w.Body.String())
+		return
+	}
+
+	var response TransactionSubmitResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if response.Hash != tx.GetHash() || response.Status != TransactionSubmitStatusAccepted || response.Result != sebakerror.ResultTransactionAccepted {
+		t.Errorf("wrong response: %v", response)
+		return
+	}
+
+	select {
+	case message := <-received:
+		if message.Type != sebaknetwork.MessageFromClient || string(message.Data) != string(b) {
+			t.Errorf("wrong message: %v", message)
+			return
+		}
+	case <-time.After(time.Second):
+		t.Error("transaction is not sent")
+		return
+	}
+
+	// the transaction in pool spends the checkpoint
+	nr.TransactionPool().Add(tx)
+	another := makeTransactionPayment(kp, testMakeBlockAccount().Address, Amount(2))
+	another.B.Checkpoint = tx.B.Checkpoint
+	another.H.Hash = another.B.MakeHashString()
+	another.Sign(kp, networkID)
+	b, _ = another.Serialize()
+	if w = submit(string(b)); w.Code != http.StatusConflict {
+		t.Errorf("double spend must be refused: %d", w.Code)
+		return
+	}
+
+	var apiError APIError
+	json.Unmarshal(w.Body.Bytes(), &apiError)
+	if apiError.Code != sebakerror.ErrorTransactionDoubleSpend.Code || apiError.Result != sebakerror.ResultTransactionDoubleSpend {
+		t.Errorf("error must have the code: %v", apiError)
+		return
+	}
+
+	// the batch of payments reports the failed operation
+	unknown, _ := keypair.Random()
+	batch, _ := NewTransaction(
+		kp.Address(),
+		tx.NextCheckpoint(),
+		Operation{H: OperationHeader{Type: OperationPayment}, B: NewOperationBodyPayment(target.Address, Amount(1))},
+		Operation{H: OperationHeader{Type: OperationPayment}, B: NewOperationBodyPayment(unknown.Address(), Amount(1))},
+	)
+	batch.Sign(kp, networkID)
+	b, _ = batch.Serialize()
+	if w = submit(string(b)); w.Code != http.StatusBadRequest {
+		t.Errorf("batch with the unknown target must be refused: %d", w.Code)
+		return
+	}
+
+	var problem sebakerror.Problem
+	json.Unmarshal(w.Body.Bytes(), &problem)
+	if problem.Result != sebakerror.ResultTransactionOperationsFailed || len(problem.Operations) != 2 {
+		t.Errorf("error must have the results of operations: %s", w.Body.String())
+		return
+	}
+	if problem.Operations[0].IsFailed() || problem.Operations[1].Result != sebakerror.ResultTransactionNoAccount {
+		t.Errorf("wrong results of operations: %v", problem.Operations)
+		return
+	}
+
+	// the wrong signature
+	another.B.Checkpoint = tx.NextCheckpoint()
+	b, _ = another.Serialize()
+	if w = submit(string(b)); w.Code != http.StatusBadRequest {
+		t.Errorf("transaction, which is not well-formed must be refused: %d", w.Code)
+		return
+	}
+
+	// the checkpoint, which is not reached yet is queued
+	future := makeTransactionPayment(kp, target.Address, Amount(1))
+	b, _ = future.Serialize()
+	if w = submit(string(b)); w.Code != http.StatusAccepted {
+		t.Errorf("future transaction must be queued: %d %s", w.Code, w.Body.String())
+		return
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if response.Status != TransactionSubmitStatusQueued || response.Result != sebakerror.ResultTransactionQueued {
+		t.Errorf("wrong response: %v", response)
+		return
+	}
+	if status, _ := nr.TransactionStatuses().Get(future.GetHash()); status.State != TransactionStateQueued {
+		t.Errorf("wrong status: %v", status)
+		return
+	}
+
+	if w = submit("{"); w.Code != http.StatusBadRequest {
+		t.Errorf("invalid JSON must be refused: %d", w.Code)
+		return
+	}
+}
+
+func TestNodeRunnerAPIJSONRPC(t *testing.T) {
+	defer sebaknetwork.CleanUpMemoryNetwork()
+
+	nodeRunners := createNodeRunners(1)
+	nr := nodeRunners[0]
+	atomic.StoreInt32(&nr.quorumReady, 1)
+
+	kp, _ := keypair.Random()
+	target := testMakeBlockAccount()
+	target.Save(nr.Storage())
+	tx := makeTransactionPayment(kp, target.Address, Amount(1))
+	NewBlockAccount(kp.Address(), Amount(BaseFee*100), tx.B.Checkpoint).Save(nr.Storage())
+
+	handler := nr.APIHandlers()[APIVersionPrefix+PostJSONRPCPattern]
+	call := func(body string) (w *httptest.ResponseRecorder) {
+		w = httptest.NewRecorder()
+		handler(w, httptest.NewRequest("POST", APIVersionPrefix+PostJSONRPCPattern, strings.NewReader(body)))
+		return
+	}
+
+	type response struct {
+		JSONRPC string           `json:"jsonrpc"`
+		Result  *json.RawMessage `json:"result"`
+		Error   *JSONRPCError    `json:"error"`
+		ID      *json.RawMessage `json:"id"`
+	}
+
+	var res response
+	w := call(`{"jsonrpc": "2.0", "method": "sebak_getAccount", "params": ["` + kp.Address() + `"], "id": 1}`)
+	json.Unmarshal(w.Body.Bytes(), &res)
+	if res.JSONRPC != JSONRPCVersion || res.Error != nil || string(*res.ID) != "1" {
+		t.Errorf("wrong response: %s", w.Body.String())
+		return
+	}
+
+	var account AccountResponse
+	json.Unmarshal(*res.Result, &account)
+	if account.Address != kp.Address() || account.Balance != Amount(BaseFee*100) {
+		t.Errorf("wrong account: %v", account)
+		return
+	}
+
+	// not found
+	res = response{}
+	w = call(`{"jsonrpc": "2.0", "method": "sebak_getTransaction", "params": {"hash": "unknown"}, "id": "a"}`)
+	json.Unmarshal(w.Body.Bytes(), &res)
+	if res.Error != nil || res.Result != nil || !strings.Contains(w.Body.String(), `"result":null`) {
+		t.Errorf("result must be null: %s", w.Body.String())
+		return
+	}
+
+	// batch with the errors and the notification
+	w = call(`[
+		{"jsonrpc": "2.0", "method": "sebak_networkID", "id": 1},
+		{"jsonrpc": "2.0", "method": "sebak_unknown", "id": 2},
+		{"jsonrpc": "2.0", "method": "sebak_getAccount", "id": 3},
+		{"jsonrpc": "2.0", "method": "sebak_networkID"},
+		1
+	]`)
+	var batch []response
+	json.Unmarshal(w.Body.Bytes(), &batch)
+	if len(batch) != 4 {
+		t.Errorf("wrong batch response: %s", w.Body.String())
+		return
+	}
+
+	var id string
+	json.Unmarshal(*batch[0].Result, &id)
+	if id != string(networkID) {
+		t.Errorf("wrong network id: %s", id)
+		return
+	}
+	for i, code := range []int{JSONRPCErrorMethodNotFound, JSONRPCErrorInvalidParams, JSONRPCErrorInvalidRequest} {
+		if batch[i+1].Error == nil || batch[i+1].Error.Code != code {
+			t.Errorf("expected error %d: %s", code, w.Body.String())
+			return
+		}
+	}
+
+	if w = call(`{"jsonrpc": "2.0", "method": "sebak_networkID"}`); w.Code != http.StatusNoContent {
+		t.Errorf("notification must not have response: %d", w.Code)
+		return
+	}
+
+	res = response{}
+	w = call(`{"jsonrpc": "2.0", "method"`)
+	json.Unmarshal(w.Body.Bytes(), &res)
+	if res.Error == nil || res.Error.Code != JSONRPCErrorParse || res.ID != nil || !strings.Contains(w.Body.String(), `"id":null`) {
+		t.Errorf("expected parse error: %s", w.Body.String())
+		return
+	}
+
+	// submit transaction
+	received := make(chan sebaknetwork.Message, 1)
+	go func() {
+		received <- <-nr.Network().ReceiveMessage()
+	}()
+
+	b, _ := tx.Serialize()
+	res = response{}
+	w = call(`{"jsonrpc": "2.0", "method": "sebak_sendTransaction", "params": [` + string(b) + `], "id": 4}`)
+	json.Unmarshal(w.Body.Bytes(), &res)
+	if res.Error != nil {
+		t.Errorf("failed to submit transaction: %s", w.Body.String())
+		return
+	}
+
+	var submitted TransactionSubmitResponse
+	json.Unmarshal(*res.Result, &submitted)
+	if submitted.Hash != tx.GetHash() || submitted.Result != sebakerror.ResultTransactionAccepted {
+		t.Errorf("wrong response: %v", submitted)
+		return
+	}
+
+	select {
+	case <-received:
+	case <-time.After(time.Second):
+		t.Error("transaction is not sent")
+		return
+	}
+
+	// the node error has the result code
+	nr.TransactionPool().Add(tx)
+	another := makeTransactionPayment(kp, testMakeBlockAccount().Address, Amount(2))
+	another.B.Checkpoint = tx.B.Checkpoint
+	another.H.Hash = another.B.MakeHashString()
+	another.Sign(kp, networkID)
+	b, _ = another.Serialize()
+
+	res = response{}
+	w = call(`{"jsonrpc": "2.0", "method": "sebak_sendTransaction", "params": {"transaction": ` + string(b) + `}, "id": 5}`)
+	json.Unmarshal(w.Body.Bytes(), &res)
+	if res.Error == nil || res.Error.Code != JSONRPCErrorNode {
+		t.Errorf("double spend must be refused: %s", w.Body.String())
+		return
+	}
+
+	data, _ := json.Marshal(res.Error.Data)
+	var apiError APIError
+	json.Unmarshal(data, &apiError)
+	if apiError.Code != sebakerror.ErrorTransactionDoubleSpend.Code || apiError.Result != sebakerror.ResultTransactionDoubleSpend {
+		t.Errorf("error must have the code: %v", apiError)
+		return
+	}
+}
+
+func TestNodeRunnerAPIRateLimit(t *testing.T) {
+	defer sebaknetwork.CleanUpMemoryNetwork()
+
+	nodeRunners := createNodeRunners(1)
+	nr := nodeRunners[0]
+	nr.SetRateLimiter(NewRateLimiter(RateLimitConfig{
+		IP:      RateLimitRule{Limit: 1, Burst: 1},
+		APIKeys: []string{"showme"},
+	}))
+
+	handler := nr.APIHandlers()[APIVersionPrefix+GetNodePattern]
+	request := func(ip, apiKey string) (w *httptest.ResponseRecorder) {
+		r := httptest.NewRequest("GET", APIVersionPrefix+GetNodePattern, nil)
+		r.RemoteAddr = ip + ":12345"
+		if len(apiKey) > 0 {
+			r.Header.Set(APIKeyHeader, apiKey)
+		}
+		w = httptest.NewRecorder()
+		handler(w, r)
+		return
+	}
+
+	if w := request("1.1.1.1", ""); w.Code != http.StatusOK {
+		t.Errorf("first request must be allowed: %d", w.Code)
+		return
+	}
+
+	w := request("1.1.1.1", "")
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "1" {
+		t.Errorf("exceeded request must be refused: %d %v", w.Code, w.Header())
+		return
+	}
+
+	var apiError APIError
+	json.Unmarshal(w.Body.Bytes(), &apiError)
+	if apiError.Code != sebakerror.ErrorRateLimitExceeded.Code || apiError.Result != sebakerror.ResultRateLimited {
+		t.Errorf("error must have the code: %v", apiError)
+		return
+	}
+
+	if w = request("2.2.2.2", ""); w.Code != http.StatusOK {
+		t.Errorf("other IP must be allowed: %d", w.Code)
+		return
+	}
+	// the API key is not limited without --api-key-rate-limit
+	if w = request("1.1.1.1", "showme"); w.Code != http.StatusOK {
+		t.Errorf("request with API key must be allowed: %d", w.Code)
+		return
+	}
+	if w = request("1.1.1.1", "unknown"); w.Code != http.StatusForbidden {
+		t.Errorf("unknown API key must be refused: %d", w.Code)
+		return
+	}
+}
+
+func TestNodeRunnerAPIConfig(t *testing.T) {
+	defer sebaknetwork.CleanUpMemoryNetwork()
+
+	nodeRunners := createNodeRunners(1)
+	nr := nodeRunners[0]
+
+	proxies, err := ParseTrustedProxies([]string{"10.0.0.0/8", "192.168.1.1"})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	nr.SetAPIConfig(APIConfig{
+		CORSOrigins:    []string{"https://explorer.example.com"},
+		TrustedProxies: proxies,
+		BasePath:       "sebak/",
+	})
+
+	handlers := nr.APIHandlers()
+	if _, found := handlers[APIVersionPrefix+GetNodePattern]; found {
+		t.Error("handler must be served under the base path")
+		return
+	}
+	handler := handlers["/sebak"+APIVersionPrefix+GetNodePattern]
+
+	// the handler sees the path without the base path
+	r := httptest.NewRequest("GET", "/sebak"+APIVersionPrefix+GetAccountsPattern+nr.Node().Address()+"/data", nil)
+	w := httptest.NewRecorder()
+	handlers["/sebak"+APIVersionPrefix+GetAccountsPattern](w, r)
+	if !strings.Contains(w.Body.String(), "account not found") {
+		t.Errorf("path must be stripped: %s", w.Body.String())
+		return
+	}
+
+	// the allowed origin
+	r = httptest.NewRequest("GET", "/sebak"+APIVersionPrefix+GetNodePattern, nil)
+	r.Header.Set("Origin", "https://explorer.example.com")
+	w = httptest.NewRecorder()
+	handler(w, r)
+	if w.Code != http.StatusOK || w.Header().Get("Access-Control-Allow-Origin") != "https://explorer.example.com" {
+		t.Errorf("allowed origin must have the CORS headers: %d %v", w.Code, w.Header())
+		return
+	}
+
+	// the preflight
+	r = httptest.NewRequest("OPTIONS", "/sebak"+APIVersionPrefix+GetNodePattern, nil)
+	r.Header.Set("Origin", "https://explorer.example.com")
+	r.Header.Set("Access-Control-Request-Method", "POST")
+	w = httptest.NewRecorder()
+	handler(w, r)
+	if w.Code != http.StatusNoContent || !strings.Contains(w.Header().Get("Access-Control-Allow-Headers"), APIKeyHeader) {
+		t.Errorf("preflight must be answered: %d %v", w.Code, w.Header())
+		return
+	}
+
+	// the other origin
+	r.Header.Set("Origin", "https://evil.example.com")
+	w = httptest.NewRecorder()
+	handler(w, r)
+	if w.Code != http.StatusForbidden || len(w.Header().Get("Access-Control-Allow-Origin")) > 0 {
+		t.Errorf("preflight of other origin must be refused: %d %v", w.Code, w.Header())
+		return
+	}
+
+	// the client IP
+	config := nr.APIConfig()
+	cases := []struct {
+		remote    string
+		forwarded string
+		expected  string
+	}{
+		{"1.1.1.1", "2.2.2.2", "1.1.1.1"},                        // not from the proxy
+		{"10.0.0.1", "2.2.2.2", "2.2.2.2"},                       // from the proxy
+		{"10.0.0.1", "3.3.3.3, 2.2.2.2, 192.168.1.1", "2.2.2.2"}, // through proxies
+		{"10.0.0.1", "", "10.0.0.1"},                             // without header
+		{"10.0.0.1", "invalid, 10.0.0.2", "10.0.0.2"},            // invalid address
+		{"192.168.1.2", "2.2.2.2", "192.168.1.2"},                // not the trusted IP
+	}
+	for _, c := range cases {
+		r = httptest.NewRequest("GET", "/", nil)
+		r.RemoteAddr = c.remote + ":12345"
+		if len(c.forwarded) > 0 {
+			r.Header.Set("X-Forwarded-For", c.forwarded)
+		}
+		if ip := config.ClientIP(r); ip != c.expected {
+			t.Errorf("wrong client ip of %v: %s", c, ip)
+			return
+		}
+	}
+
+	if _, err = ParseTrustedProxies([]string{"not-ip"}); err == nil {
+		t.Error("invalid proxy must be refused")
+		return
+	}
+}
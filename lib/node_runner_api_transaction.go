@@ -0,0 +1,230 @@
+package sebak
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"boscoin.io/sebak/lib/error"
+	"boscoin.io/sebak/lib/network"
+)
+
+const (
+	PostTransactionsPattern         string = "/transactions"
+	GetTransactionsPattern          string = "/transactions/"
+	GetTransactionStatusSubPattern  string = "status"
+	GetTransactionReceiptSubPattern string = "receipt"
+	GetTransactionResultSubPattern  string = "result"
+)
+
+// MaxTransactionRequestSize is the maximum size of the submitted transaction.
+const MaxTransactionRequestSize int64 = 100 * 1024
+
+const (
+	TransactionSubmitStatusAccepted string = "accepted"
+	TransactionSubmitStatusQueued   string = "queued"
+)
+
+type TransactionSubmitResponse struct {
+	Hash   string                `json:"hash"`
+	Status string                `json:"status"`
+	Result sebakerror.ResultCode `json:"result"`
+
+	Receipt *ForwardingReceipt `json:"receipt,omitempty"` // with `SetForwardingReceipts()`
+}
+
+// handleAPITransactions validates the submitted transaction before it is
+// sent to the node, so the client gets the result at once; the well-formed
+// checks, like the signature, the double spend, the checkpoint and the balance
+// of source account. The error has the `code` of `sebakerror.Error` and it's
+// `result`, the `sebakerror.ResultCode`. The accepted transaction is handled
+// like the transaction from client, so the response is 202 with the hash of
+// transaction. The transaction, whose checkpoint is not reached yet, is held
+// in `FutureTransactionQueue` and the response is also 202 with `queued`.
+func (nr *NodeRunner) handleAPITransactions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		writeAPIError(w, r, http.StatusMethodNotAllowed, nil)
+		return
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, MaxTransactionRequestSize+1))
+	if err != nil {
+		writeAPIError(w, r, http.StatusBadRequest, err)
+		return
+	} else if int64(len(body)) > MaxTransactionRequestSize {
+		writeAPIError(w, r, http.StatusRequestEntityTooLarge, nil)
+		return
+	}
+
+	response, status, err := nr.submitTransaction(body)
+	if err != nil {
+		writeAPIError(w, r, status, err)
+		return
+	}
+
+	writeAPIJSON(w, status, response)
+}
+
+// submitTransaction validates the transaction and sends it to the node; the
+// status is the HTTP status of the result.
+func (nr *NodeRunner) submitTransaction(body []byte) (response TransactionSubmitResponse, status int, err error) {
+	status = http.StatusBadRequest
+	received := time.Now()
+
+	var tx Transaction
+	if tx, err = NewTransactionFromJSON(body); err != nil {
+		return
+	}
+	if err = tx.IsWellFormed(nr.networkID); err != nil {
+		return
+	}
+	if err = tx.CheckTimeBounds(received); err != nil {
+		return
+	}
+	if err = CheckTransactionOperationsLimit(nr.networkParameters, tx); err != nil {
+		return
+	}
+	if err = CheckTransactionFee(nr.networkParameters, tx); err != nil {
+		return
+	}
+
+	response = TransactionSubmitResponse{
+		Hash:   tx.GetHash(),
+		Status: TransactionSubmitStatusAccepted,
+		Result: sebakerror.ResultTransactionAccepted,
+	}
+
+	if hash, found := nr.transactionPool.SpentBy(tx.B.Source, tx.B.Checkpoint); found {
+		if hash == tx.GetHash() {
+			status = http.StatusAccepted
+			return
+		}
+		status, err = http.StatusConflict, sebakerror.ErrorTransactionDoubleSpend
+		return
+	}
+	if bt, e := GetBlockTransactionByCheckpoint(nr.storage, tx.B.Checkpoint); e == nil && bt.Source == tx.B.Source {
+		status, err = http.StatusConflict, sebakerror.ErrorTransactionDoubleSpend
+		return
+	}
+
+	// the checkpoint, which is not spent yet, may be reached by the
+	// transactions, which are not received yet
+	if err = ValidateTransactionState(nr.storage, nr.transactionPool, tx); err == sebakerror.ErrorTransactionInvalidCheckpoint {
+		if err = nr.queueFutureTransaction(tx, body, received); err != nil {
+			return
+		}
+		response.Status = TransactionSubmitStatusQueued
+		response.Result = sebakerror.ResultTransactionQueued
+		status = http.StatusAccepted
+		return
+	} else if err != nil {
+		return
+	}
+	if err = CheckCreateAccountMinimumBalance(nr.networkParameters, tx); err != nil {
+		return
+	}
+	if err = ValidateTransactionOperations(nr.storage, tx); err != nil {
+		return
+	}
+	if err = CheckSpendingLimit(nr.storage, nr.networkID, tx, GetSpendingLimitDay(time.Now())); err != nil {
+		status = http.StatusForbidden
+		return
+	}
+	if err = CheckAccountSigners(nr.storage, nr.networkID, tx); err != nil {
+		status = http.StatusForbidden
+		return
+	}
+
+	if !nr.IsQuorumReady() {
+		status, err = http.StatusServiceUnavailable, sebakerror.ErrorNodeNotReady
+		return
+	}
+	if nr.messageQueue.IsClientFull() {
+		status, err = http.StatusServiceUnavailable, sebakerror.ErrorMessageQueueFull
+		return
+	}
+
+	nr.transactionStatuses.Submitted(tx.GetHash())
+	nr.network.ReceiveChannel() <- sebaknetwork.Message{Type: sebaknetwork.MessageFromClient, Data: body}
+	status = http.StatusAccepted
+
+	if nr.forwardingReceipts {
+		receipt := nr.forwardTransaction(tx, received)
+		response.Receipt = &receipt
+	}
+
+	return
+}
+
+// handleAPITransaction returns the sub-resources of the transaction,
+// '/transactions/{hash}/{status or receipt}'.
+func (nr *NodeRunner) handleAPITransaction(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		writeAPIError(w, r, http.StatusMethodNotAllowed, nil)
+		return
+	}
+
+	hash, sub := SplitAPIPath(r.URL.Path, GetTransactionsPattern)
+	if len(hash) < 1 || len(sub) != 1 {
+		writeAPIError(w, r, http.StatusNotFound, nil)
+		return
+	}
+
+	switch sub[0] {
+	case GetTransactionStatusSubPattern:
+		nr.handleAPITransactionStatus(w, r, hash)
+	case GetTransactionReceiptSubPattern:
+		nr.handleAPITransactionReceipt(w, r, hash)
+	case GetTransactionResultSubPattern:
+		nr.handleAPITransactionResult(w, r, hash)
+	default:
+		writeAPIError(w, r, http.StatusNotFound, nil)
+	}
+}
+
+// handleAPITransactionStatus returns the status of transaction; the
+// transaction, which this node has never seen is not found.
+func (nr *NodeRunner) handleAPITransactionStatus(w http.ResponseWriter, r *http.Request, hash string) {
+	status, found, err := GetTransactionStatus(nr.storage, nr.transactionPool, nr.transactionStatuses, hash)
+	if err != nil {
+		writeAPIError(w, r, http.StatusInternalServerError, err)
+		return
+	} else if !found {
+		writeAPIError(w, r, http.StatusNotFound, errors.New("transaction not found"))
+		return
+	}
+
+	writeAPIJSON(w, http.StatusOK, status)
+}
+
+// handleAPITransactionReceipt returns the forwarding receipt of the
+// transaction, which is submitted to this node.
+func (nr *NodeRunner) handleAPITransactionReceipt(w http.ResponseWriter, r *http.Request, hash string) {
+	receipt, found, err := GetForwardingReceipt(nr.storage, hash)
+	if err != nil {
+		writeAPIError(w, r, http.StatusInternalServerError, err)
+		return
+	} else if !found {
+		writeAPIError(w, r, http.StatusNotFound, errors.New("forwarding receipt not found"))
+		return
+	}
+
+	writeAPIJSON(w, http.StatusOK, receipt)
+}
+
+// handleAPITransactionResult returns the result of the included transaction
+// or the transaction rejected by this node.
+func (nr *NodeRunner) handleAPITransactionResult(w http.ResponseWriter, r *http.Request, hash string) {
+	result, found, err := GetTransactionResult(nr.storage, hash)
+	if err != nil {
+		writeAPIError(w, r, http.StatusInternalServerError, err)
+		return
+	} else if !found {
+		writeAPIError(w, r, http.StatusNotFound, errors.New("transaction result not found"))
+		return
+	}
+
+	writeAPIJSON(w, http.StatusOK, result)
+}
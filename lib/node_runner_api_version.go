@@ -0,0 +1,198 @@
+package sebak
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// APIVersion is the version of the HTTP API for clients; it is served under
+// it's prefix, like '/api/v2'. Every version is served by the same handlers,
+// and the version, which changes the shape of the resources converts the
+// responses of handlers by it's serializers, so the wallets of the older
+// versions are not broken by the new shapes.
+type APIVersion string
+
+const (
+	APIVersion1 APIVersion = "v1"
+	APIVersion2 APIVersion = "v2"
+)
+
+// APIVersions are the served versions in order; the next version is the
+// successor of the deprecated version.
+var APIVersions = []APIVersion{APIVersion1, APIVersion2}
+
+func (v APIVersion) Prefix() string {
+	return "/api/" + string(v)
+}
+
+func NewAPIVersionFromString(s string) (v APIVersion, err error) {
+	for _, version := range APIVersions {
+		if string(version) == s {
+			v = version
+			return
+		}
+	}
+	err = errors.New("unknown API version: '" + s + "'")
+
+	return
+}
+
+// APISerializer converts the response of handler to the shape of version.
+// `Response` is the zero value of the converted response for the OpenAPI
+// document.
+type APISerializer struct {
+	Response  interface{}
+	Serialize func(v interface{}) interface{}
+}
+
+// APIVersionGroup is the routes of one version.
+//  * `Serializers`: the serializers by the type of the response of handler;
+//  the response, which has no serializer is written as it is
+//  * `Deprecated`: the responses have the 'Deprecation' header and the 'Link'
+//  header to the `Successor`; with `Sunset`, the 'Sunset' header tells when
+//  the version will be removed
+type APIVersionGroup struct {
+	Version     APIVersion
+	Serializers map[reflect.Type]APISerializer
+	Deprecated  bool
+	Sunset      time.Time
+	Successor   APIVersion
+}
+
+// BlockResponseV2 is the block of v2; the transactions are only their hashes,
+// so the response of the big block is not too big. The transactions are
+// found by '/transactions/{hash}'.
+type BlockResponseV2 struct {
+	BlockHeaderResponse
+	Transactions []string `json:"transactions"`
+}
+
+// apiSerializersV2 are the changes of v2 from v1.
+var apiSerializersV2 = map[reflect.Type]APISerializer{
+	reflect.TypeOf(BlockResponse{}): {
+		Response: BlockResponseV2{},
+		Serialize: func(v interface{}) interface{} {
+			b := v.(BlockResponse)
+			response := BlockResponseV2{BlockHeaderResponse: b.BlockHeaderResponse, Transactions: []string{}}
+			for _, entry := range b.Transactions {
+				response.Transactions = append(response.Transactions, entry.Hash)
+			}
+			return response
+		},
+	},
+}
+
+// ParseAPIDeprecations parses the deprecated versions with their sunset, like
+// 'v1=2027-06-30'; the version without the date has no sunset.
+func ParseAPIDeprecations(entries []string) (deprecations map[APIVersion]time.Time, err error) {
+	deprecations = map[APIVersion]time.Time{}
+	for _, entry := range entries {
+		parts := strings.SplitN(entry, "=", 2)
+
+		var version APIVersion
+		if version, err = NewAPIVersionFromString(parts[0]); err != nil {
+			return
+		}
+		if version == APIVersions[len(APIVersions)-1] {
+			err = errors.New("the latest API version can not be deprecated: " + parts[0])
+			return
+		}
+
+		var sunset time.Time
+		if len(parts) > 1 {
+			if sunset, err = time.Parse("2006-01-02", parts[1]); err != nil {
+				err = errors.New("invalid sunset date of " + parts[0] + ": " + parts[1])
+				return
+			}
+		}
+		deprecations[version] = sunset
+	}
+
+	return
+}
+
+// APIVersionGroups returns the groups of the served versions; the deprecated
+// versions are set by `APIConfig.Deprecations`.
+func (nr *NodeRunner) APIVersionGroups() []APIVersionGroup {
+	var groups []APIVersionGroup
+	for i, version := range APIVersions {
+		group := APIVersionGroup{Version: version}
+		if version == APIVersion2 {
+			group.Serializers = apiSerializersV2
+		}
+		if sunset, found := nr.apiConfig.Deprecations[version]; found && i < len(APIVersions)-1 {
+			group.Deprecated = true
+			group.Sunset = sunset
+			group.Successor = APIVersions[i+1]
+		}
+		groups = append(groups, group)
+	}
+
+	return groups
+}
+
+// withAPIVersion serves the handler in the version; the response writer
+// carries the group to `writeAPIJSON()`.
+func (g APIVersionGroup) withAPIVersion(basePath string, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if g.Deprecated {
+			w.Header().Set("Deprecation", "true")
+			if !g.Sunset.IsZero() {
+				w.Header().Set("Sunset", g.Sunset.UTC().Format(http.TimeFormat))
+			}
+			w.Header().Set("Link", "<"+basePath+g.Successor.Prefix()+">; rel=\"successor-version\"")
+		}
+
+		handler(&apiVersionWriter{ResponseWriter: w, group: g}, r)
+	}
+}
+
+// apiVersionWriter is the response writer of the versioned handler; the
+// streams and the websocket still work through it.
+type apiVersionWriter struct {
+	http.ResponseWriter
+	group APIVersionGroup
+}
+
+func (w *apiVersionWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (w *apiVersionWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("hijack is not supported")
+	}
+	return hijacker.Hijack()
+}
+
+// apiVersionGroupOf returns the group of the versioned handler; out of them,
+// like the admin API, it is v1.
+func apiVersionGroupOf(w http.ResponseWriter) APIVersionGroup {
+	if vw, ok := w.(*apiVersionWriter); ok {
+		return vw.group
+	}
+
+	return APIVersionGroup{Version: APIVersion1}
+}
+
+// serializeAPIResponse converts the response to the shape of the version of
+// handler.
+func serializeAPIResponse(w http.ResponseWriter, v interface{}) interface{} {
+	if v == nil {
+		return v
+	}
+	serializer, found := apiVersionGroupOf(w).Serializers[reflect.TypeOf(v)]
+	if !found {
+		return v
+	}
+
+	return serializer.Serialize(v)
+}
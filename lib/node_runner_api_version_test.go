@@ -0,0 +1,121 @@
+package sebak
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"boscoin.io/sebak/lib/error"
+	"boscoin.io/sebak/lib/network"
+)
+
+func TestParseAPIDeprecations(t *testing.T) {
+	deprecations, err := ParseAPIDeprecations([]string{"v1=2027-06-30"})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if sunset, found := deprecations[APIVersion1]; !found || !sunset.Equal(time.Date(2027, 6, 30, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("wrong deprecations: %v", deprecations)
+		return
+	}
+
+	if deprecations, err = ParseAPIDeprecations([]string{"v1"}); err != nil || !deprecations[APIVersion1].IsZero() {
+		t.Errorf("version without sunset must be parsed: %v %v", deprecations, err)
+		return
+	}
+
+	for _, entry := range []string{"v0", "v2", "v1=30-06-2027"} {
+		if _, err = ParseAPIDeprecations([]string{entry}); err == nil {
+			t.Errorf("'%s' must be refused", entry)
+			return
+		}
+	}
+}
+
+func TestNodeRunnerAPIVersion(t *testing.T) {
+	defer sebaknetwork.CleanUpMemoryNetwork()
+
+	nr := createNodeRunners(1)[0]
+	nr.SetAPIConfig(APIConfig{
+		Deprecations: map[APIVersion]time.Time{APIVersion1: time.Date(2027, 6, 30, 0, 0, 0, 0, time.UTC)},
+	})
+
+	var hashes []string
+	for i := 0; i < 2; i++ {
+		_, tx := TestMakeTransaction(networkID, 1)
+		bt := NewBlockTransactionFromTransaction(tx, nil)
+		if err := bt.Save(nr.Storage()); err != nil {
+			t.Error(err)
+			return
+		}
+		hashes = append(hashes, tx.GetHash())
+	}
+	block := NewBlock(Block{}, "state", hashes...)
+	block.Save(nr.Storage())
+
+	handlers := nr.APIHandlers()
+	request := func(version APIVersion, pattern, path string) (w *httptest.ResponseRecorder) {
+		w = httptest.NewRecorder()
+		handlers[version.Prefix()+pattern](w, httptest.NewRequest("GET", version.Prefix()+pattern+path, nil))
+		return
+	}
+
+	// v1 is deprecated, but it's shape is not changed
+	w := request(APIVersion1, GetBlocksPattern, "1")
+	var v1 BlockResponse
+	json.Unmarshal(w.Body.Bytes(), &v1)
+	if w.Code != http.StatusOK || len(v1.Transactions) != 2 || v1.Transactions[0].Hash != hashes[0] {
+		t.Errorf("wrong v1 block: %d %v", w.Code, v1)
+		return
+	}
+	if w.Header().Get("Deprecation") != "true" || w.Header().Get("Sunset") != "Wed, 30 Jun 2027 00:00:00 GMT" {
+		t.Errorf("v1 must be deprecated: %v", w.Header())
+		return
+	}
+	if link := w.Header().Get("Link"); link != `</api/v2>; rel="successor-version"` {
+		t.Errorf("wrong successor: %s", link)
+		return
+	}
+
+	w = request(APIVersion2, GetBlocksPattern, "1")
+	var v2 BlockResponseV2
+	json.Unmarshal(w.Body.Bytes(), &v2)
+	if w.Code != http.StatusOK || v2.Hash != block.Hash || len(v2.Transactions) != 2 || v2.Transactions[1] != hashes[1] {
+		t.Errorf("wrong v2 block: %d %s", w.Code, w.Body.String())
+		return
+	}
+	if len(w.Header().Get("Deprecation")) > 0 {
+		t.Error("v2 must not be deprecated")
+		return
+	}
+
+	// the resource without serializer is same in every version
+	w = request(APIVersion2, GetResultsPattern, "")
+	var results []sebakerror.Result
+	if json.Unmarshal(w.Body.Bytes(), &results); w.Code != http.StatusOK || len(results) != len(sebakerror.Results()) {
+		t.Errorf("failed to get results in v2: %d", w.Code)
+		return
+	}
+
+	var doc OpenAPIDocument
+	json.Unmarshal(request(APIVersion2, GetOpenAPIPattern, "").Body.Bytes(), &doc)
+	op := doc.Paths[GetBlocksPattern+"{block}"]["get"]
+	if doc.Servers[0].URL != APIVersion2.Prefix() || op == nil || op.Deprecated {
+		t.Errorf("wrong v2 document: %v %v", doc.Servers, op)
+		return
+	}
+	if ref := op.Responses["200"].Content["application/json"].Schema.Ref; ref != "#/components/schemas/BlockResponseV2" {
+		t.Errorf("wrong v2 response schema: %s", ref)
+		return
+	}
+
+	doc = OpenAPIDocument{}
+	json.Unmarshal(request(APIVersion1, GetOpenAPIPattern, "").Body.Bytes(), &doc)
+	if op = doc.Paths[GetBlocksPattern+"{block}"]["get"]; op == nil || !op.Deprecated {
+		t.Errorf("v1 operations must be deprecated: %v", op)
+		return
+	}
+}
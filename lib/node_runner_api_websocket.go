@@ -0,0 +1,263 @@
+package sebak
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/net/websocket"
+
+	"boscoin.io/sebak/lib/error"
+)
+
+const GetWebSocketPattern string = "/ws"
+
+// MaxWebSocketSubscriptions is the maximum number of subscriptions of one
+// WebSocket connection.
+const MaxWebSocketSubscriptions int = 20
+
+// MaxWebSocketMessageSize is the maximum size of the message from client.
+const MaxWebSocketMessageSize int = 4 * 1024
+
+const (
+	WebSocketOpSubscribe   string = "subscribe"
+	WebSocketOpUnsubscribe string = "unsubscribe"
+)
+
+const (
+	WebSocketTopicBlocks     string = "blocks"
+	WebSocketTopicAccount    string = "account"
+	WebSocketTopicOperations string = "operations"
+)
+
+const (
+	WebSocketMessageSubscribed   string = "subscribed"
+	WebSocketMessageUnsubscribed string = "unsubscribed"
+	WebSocketMessageEvent        string = "event"
+	WebSocketMessageError        string = "error"
+	WebSocketMessageHeartbeat    string = "heartbeat"
+)
+
+// WebSocketRequest is the message from client; `ID` is given by the client
+// to identify the subscription. The 'account' topic has the changes of the
+// `Account`, and the 'operations' topic has the operations of `Type` and, if
+// given, of the `Account`.
+type WebSocketRequest struct {
+	Op      string        `json:"op"`
+	ID      string        `json:"id"`
+	Topic   string        `json:"topic,omitempty"`
+	Account string        `json:"account,omitempty"`
+	Type    OperationType `json:"type,omitempty"`
+}
+
+type WebSocketMessage struct {
+	Type  string      `json:"type"`
+	ID    string      `json:"id,omitempty"`
+	Event string      `json:"event,omitempty"`
+	Data  interface{} `json:"data,omitempty"`
+	Error *APIError   `json:"error,omitempty"`
+}
+
+func newWebSocketError(id string, err error) WebSocketMessage {
+	e := sebakerror.NewProblem(http.StatusBadRequest, err)
+
+	return WebSocketMessage{Type: WebSocketMessageError, ID: id, Error: &e}
+}
+
+// Match checks whether the event belongs to the subscription.
+func (req WebSocketRequest) Match(event StreamEvent) bool {
+	switch req.Topic {
+	case WebSocketTopicBlocks:
+		return event.Type == StreamEventBlock
+	case WebSocketTopicAccount:
+		return event.Type == StreamEventAccount && event.Accounts[0] == req.Account
+	case WebSocketTopicOperations:
+		if event.Type != StreamEventOperation {
+			return false
+		}
+		if len(req.Type) > 0 && event.Data.(OperationResponse).Type != req.Type {
+			return false
+		}
+		if len(req.Account) < 1 {
+			return true
+		}
+		for _, a := range event.Accounts {
+			if a == req.Account {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func (req WebSocketRequest) Validate() (err error) {
+	if len(req.ID) < 1 {
+		return errors.New("'id' must be given")
+	}
+	if req.Op == WebSocketOpUnsubscribe {
+		return
+	} else if req.Op != WebSocketOpSubscribe {
+		return errors.New("'op' must be 'subscribe' or 'unsubscribe'")
+	}
+
+	switch req.Topic {
+	case WebSocketTopicBlocks:
+	case WebSocketTopicAccount:
+		if len(req.Account) < 1 {
+			return errors.New("'account' must be given")
+		}
+	case WebSocketTopicOperations:
+		if len(req.Type) > 0 && !IsKnownOperationType(req.Type) {
+			return sebakerror.ErrorUnknownOperationType
+		}
+	default:
+		return errors.New("'topic' must be 'blocks', 'account' or 'operations'")
+	}
+
+	if len(req.Account) > 0 {
+		if _, err = ParseAccountAddress(req.Account); err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+// webSocketSession keeps the subscriptions of one connection; the events of
+// all the subscriptions are received by one `StreamSubscriber`.
+type webSocketSession struct {
+	sync.Mutex
+
+	conn          *websocket.Conn
+	subscriptions map[string]WebSocketRequest
+	sendLock      sync.Mutex
+}
+
+func (s *webSocketSession) send(message WebSocketMessage) error {
+	s.sendLock.Lock()
+	defer s.sendLock.Unlock()
+
+	return websocket.JSON.Send(s.conn, message)
+}
+
+// matched returns the ids of the subscriptions, which the event belongs to.
+func (s *webSocketSession) matched(event StreamEvent) (ids []string) {
+	s.Lock()
+	defer s.Unlock()
+
+	for id, req := range s.subscriptions {
+		if req.Match(event) {
+			ids = append(ids, id)
+		}
+	}
+
+	return
+}
+
+func (s *webSocketSession) handleRequest(req WebSocketRequest) WebSocketMessage {
+	if err := req.Validate(); err != nil {
+		return newWebSocketError(req.ID, err)
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	_, found := s.subscriptions[req.ID]
+	if req.Op == WebSocketOpUnsubscribe {
+		if !found {
+			return newWebSocketError(req.ID, errors.New("subscription not found"))
+		}
+		delete(s.subscriptions, req.ID)
+		return WebSocketMessage{Type: WebSocketMessageUnsubscribed, ID: req.ID}
+	}
+
+	if found {
+		return newWebSocketError(req.ID, errors.New("subscription already exists"))
+	}
+	if len(s.subscriptions) >= MaxWebSocketSubscriptions {
+		return newWebSocketError(req.ID, errors.New("too many subscriptions"))
+	}
+	s.subscriptions[req.ID] = req
+
+	return WebSocketMessage{Type: WebSocketMessageSubscribed, ID: req.ID}
+}
+
+func (nr *NodeRunner) serveWebSocket(conn *websocket.Conn) {
+	defer conn.Close()
+	conn.MaxPayloadBytes = MaxWebSocketMessageSize
+
+	session := &webSocketSession{
+		conn:          conn,
+		subscriptions: map[string]WebSocketRequest{},
+	}
+
+	sub, err := nr.stream.Subscribe(func(event StreamEvent) bool {
+		return len(session.matched(event)) > 0
+	})
+	if err != nil {
+		session.send(newWebSocketError("", err))
+		return
+	}
+	defer nr.stream.Unsubscribe(sub)
+
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			var req WebSocketRequest
+			if err := websocket.JSON.Receive(conn, &req); err != nil {
+				if _, ok := err.(*websocket.ProtocolError); ok || err == websocket.ErrFrameTooLarge {
+					session.send(newWebSocketError("", err))
+				}
+				return
+			}
+			if err := session.send(session.handleRequest(req)); err != nil {
+				return
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(StreamHeartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-closed:
+			return
+		case <-ticker.C:
+			if err = session.send(WebSocketMessage{Type: WebSocketMessageHeartbeat}); err != nil {
+				return
+			}
+		case event, ok := <-sub.Events:
+			if !ok { // dropped, because the client is too slow
+				return
+			}
+			for _, id := range session.matched(event) {
+				message := WebSocketMessage{Type: WebSocketMessageEvent, ID: id, Event: event.Type, Data: event.Data}
+				if err = session.send(message); err != nil {
+					return
+				}
+			}
+		}
+	}
+}
+
+// handleAPIWebSocket serves the WebSocket connection, which multiplexes the
+// subscriptions of the blocks, the account changes and the operations, for
+// the clients which can not use the Server-Sent Events. The connection counts
+// one of the stream subscribers.
+func (nr *NodeRunner) handleAPIWebSocket(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		writeAPIError(w, r, http.StatusMethodNotAllowed, nil)
+		return
+	}
+
+	server := websocket.Server{
+		// the API is public, so the connection from any origin is allowed
+		Handshake: func(*websocket.Config, *http.Request) error { return nil },
+		Handler:   nr.serveWebSocket,
+	}
+	server.ServeHTTP(w, r)
+}
@@ -1,7 +1,10 @@
 package sebak
 
 import (
+	"time"
+
 	"boscoin.io/sebak/lib/common"
+	"boscoin.io/sebak/lib/error"
 	"boscoin.io/sebak/lib/network"
 )
 
@@ -53,6 +56,54 @@ func CheckNodeRunnerHandleMessageTransactionHasSameSource(c sebakcommon.Checker,
 	return
 }
 
+// CheckNodeRunnerHandleMessageTimeBounds rejects the transaction out of it's
+// time bounds; the expired transaction is not proposed.
+func CheckNodeRunnerHandleMessageTimeBounds(c sebakcommon.Checker, args ...interface{}) (err error) {
+	checker := c.(*NodeRunnerHandleMessageChecker)
+
+	if err = checker.Transaction.CheckTimeBounds(time.Now()); err != nil {
+		checker.NodeRunner.Log().Debug("transaction out of time bounds", "transaction", checker.Transaction.GetHash(), "error", err)
+		return
+	}
+
+	return
+}
+
+// CheckNodeRunnerHandleMessageDoubleSpend rejects the transaction, which
+// spends the checkpoint of source account already spent by the other
+// transaction in `TransactionPool` or in block.
+func CheckNodeRunnerHandleMessageDoubleSpend(c sebakcommon.Checker, args ...interface{}) (err error) {
+	checker := c.(*NodeRunnerHandleMessageChecker)
+
+	tx := checker.Transaction
+	if hash, found := checker.NodeRunner.TransactionPool().SpentBy(tx.B.Source, tx.B.Checkpoint); found {
+		if hash == tx.GetHash() {
+			err = sebakcommon.CheckerErrorStop{"transaction already in transaction pool"}
+			return
+		}
+
+		checker.NodeRunner.Log().Debug(
+			"checkpoint already spent in transaction pool",
+			"transaction", tx.GetHash(),
+			"spent-by", hash,
+		)
+		err = sebakerror.ErrorTransactionDoubleSpend
+		return
+	}
+
+	if bt, e := GetBlockTransactionByCheckpoint(checker.NodeRunner.Storage(), tx.B.Checkpoint); e == nil && bt.Source == tx.B.Source {
+		checker.NodeRunner.Log().Debug(
+			"checkpoint already spent in block",
+			"transaction", tx.GetHash(),
+			"spent-by", bt.Hash,
+		)
+		err = sebakerror.ErrorTransactionDoubleSpend
+		return
+	}
+
+	return
+}
+
 func CheckNodeRunnerHandleMessageHistory(c sebakcommon.Checker, args ...interface{}) (err error) {
 	checker := c.(*NodeRunnerHandleMessageChecker)
 
@@ -66,6 +117,41 @@ func CheckNodeRunnerHandleMessageHistory(c sebakcommon.Checker, args ...interfac
 	return
 }
 
+func CheckNodeRunnerHandleMessagePushIntoTransactionPool(c sebakcommon.Checker, args ...interface{}) (err error) {
+	checker := c.(*NodeRunnerHandleMessageChecker)
+
+	if err = checker.NodeRunner.TransactionPool().Add(checker.Transaction); err == sebakerror.ErrorTransactionAlreadyInPool {
+		err = sebakcommon.CheckerErrorStop{"transaction already in transaction pool"}
+		return
+	} else if err != nil {
+		return
+	}
+
+	checker.NodeRunner.TransactionStatuses().Pending(checker.Transaction.GetHash())
+	checker.NodeRunner.Log().Debug("pushed into transaction pool", "transaction", checker.Transaction.GetHash())
+
+	go checker.NodeRunner.admitFutureTransactions(checker.Transaction.B.Source)
+
+	return
+}
+
+// CheckNodeRunnerHandleMessageBroadcastTransaction propagates the transaction
+// to the other validators by `GossipTransaction()`, when the view change is
+// enabled, so the next proposer also has it. The validators, which already
+// have it, do not fetch it, or stop by
+// `CheckNodeRunnerHandleMessageDoubleSpend`.
+func CheckNodeRunnerHandleMessageBroadcastTransaction(c sebakcommon.Checker, args ...interface{}) (err error) {
+	checker := c.(*NodeRunnerHandleMessageChecker)
+
+	if checker.NodeRunner.ProposerTimeout() < 1 {
+		return
+	}
+
+	checker.NodeRunner.GossipTransaction(checker.Transaction)
+
+	return
+}
+
 func CheckNodeRunnerHandleMessageISAACReceiveMessage(c sebakcommon.Checker, args ...interface{}) (err error) {
 	checker := c.(*NodeRunnerHandleMessageChecker)
 
@@ -85,7 +171,7 @@ func CheckNodeRunnerHandleMessageSignBallot(c sebakcommon.Checker, args ...inter
 	// self-sign
 	checker.Ballot.Vote(VotingYES)
 	checker.Ballot.UpdateHash()
-	checker.Ballot.Sign(checker.CurrentNode.Keypair(), checker.NetworkID)
+	err = checker.NodeRunner.Consensus().Signer().SignBallot(&checker.Ballot)
 
 	return
 }
@@ -187,6 +273,17 @@ func CheckNodeRunnerHandleBallotHistory(c sebakcommon.Checker, args ...interface
 	}
 
 	tx := checker.GetTransaction()
+
+	// when the view change is enabled, the transactions from client are shared
+	// with the other validators by `CheckNodeRunnerHandleMessageBroadcastTransaction`,
+	// so they are already in history.
+	if checker.NodeRunner.ProposerTimeout() > 0 {
+		var exists bool
+		if exists, err = ExistBlockTransactionHistory(checker.NodeRunner.Storage(), tx.GetHash()); err != nil || exists {
+			return
+		}
+	}
+
 	bt := NewTransactionHistoryFromTransaction(tx, raw)
 	if err = bt.Save(checker.NodeRunner.Storage()); err != nil {
 		return
@@ -197,17 +294,47 @@ func CheckNodeRunnerHandleBallotHistory(c sebakcommon.Checker, args ...interface
 	return
 }
 
+// CheckNodeRunnerHandleBallotParticipation records the ballot of validator
+// to count the ballots, which the validator missed.
+func CheckNodeRunnerHandleBallotParticipation(c sebakcommon.Checker, args ...interface{}) (err error) {
+	checker := c.(*NodeRunnerHandleBallotChecker)
+
+	checker.NodeRunner.ConnectionManager().ObserveBallot(checker.Ballot.MessageHash(), checker.Ballot.B.NodeKey)
+
+	return
+}
+
 func CheckNodeRunnerHandleBallotStore(c sebakcommon.Checker, args ...interface{}) (err error) {
 	checker := c.(*NodeRunnerHandleBallotChecker)
 
+	if checker.VotingStateStaging.IsClosed() && checker.VotingStateStaging.VotingHole == VotingNO {
+		checker.NodeRunner.TransactionStatuses().Rejected(checker.GetTransaction().GetHash(), nil)
+	}
 	if !checker.VotingStateStaging.IsStorable() || !checker.VotingStateStaging.IsClosed() {
 		return
 	}
 
-	if err = FinishTransaction(checker.NodeRunner.Storage(), checker.Ballot, checker.GetTransaction()); err != nil {
+	if err = checker.NodeRunner.revalidateTransaction(checker.GetTransaction()); err != nil {
+		checker.NodeRunner.TransactionStatuses().Rejected(checker.GetTransaction().GetHash(), err)
 		return
 	}
 
+	deferStats := checker.NodeRunner.defersWork(DeferrableWorkChainStats)
+	if err = FinishTransaction(checker.NodeRunner.Storage(), checker.NodeRunner.NetworkID(), checker.Ballot, checker.GetTransaction(), deferStats); err != nil {
+		checker.NodeRunner.divergeTransaction(checker.GetTransaction(), err)
+		checker.NodeRunner.TransactionStatuses().Rejected(checker.GetTransaction().GetHash(), err)
+		return
+	}
+	if latest, e := GetLatestBlock(checker.NodeRunner.Storage()); e == nil {
+		checker.NodeRunner.TransactionStatuses().Included(checker.GetTransaction().GetHash(), latest)
+		checker.NodeRunner.updateUpgrades(latest)
+	}
+	checker.NodeRunner.checkInvariants(checker.GetTransaction())
+	checker.NodeRunner.TransactionPool().Remove(checker.GetTransaction().GetHash())
+	go checker.NodeRunner.admitFutureTransactions(checker.GetTransaction().B.Source)
+	checker.NodeRunner.announceBlock()
+	checker.NodeRunner.publishBlock(checker.GetTransaction())
+
 	checker.NodeRunner.Log().Debug(
 		"got consensus",
 		"ballot", checker.Ballot.MessageHash(),
@@ -247,10 +374,33 @@ func CheckNodeRunnerHandleBallotVotingHole(c sebakcommon.Checker, args ...interf
 	tx := checker.GetTransaction()
 
 	votingHole := VotingYES
-
-	if tx.B.Fee < Amount(BaseFee) {
-		checker.NodeRunner.Log().Debug("VotingNO: tx.B.Fee < Amount(BaseFee)")
-		votingHole = VotingNO
+	var reason error
+
+	// the checks by time use the proposed time of ballot, which every
+	// validator has, not the clock of node, so the validators vote same
+	proposed, _ := checker.Ballot.ProposedTime()
+
+	if checker.Ballot.IsProposedTimeDrifted(time.Now()) {
+		checker.NodeRunner.Log().Debug("VotingNO: proposed time is too far from the clock", "proposed", checker.Ballot.B.Proposed)
+		votingHole, reason = VotingNO, sebakerror.ErrorBallotInvalidProposedTime
+	} else if latest, err := GetLatestBlock(checker.NodeRunner.Storage()); err != nil {
+		checker.NodeRunner.Log().Debug("VotingNO: failed to get the latest block", "error", err)
+		votingHole, reason = VotingNO, err
+	} else if !checker.Ballot.IsProposedAfter(latest) {
+		checker.NodeRunner.Log().Debug("VotingNO: proposed time is before the latest block", "proposed", checker.Ballot.B.Proposed, "latest", latest.Confirmed)
+		votingHole, reason = VotingNO, sebakerror.ErrorBallotInvalidProposedTime
+	} else if err := CheckTransactionOperationsLimit(checker.NodeRunner.NetworkParameters(), tx); err != nil {
+		checker.NodeRunner.Log().Debug("VotingNO: too many operations", "error", err)
+		votingHole, reason = VotingNO, err
+	} else if err := CheckTransactionFee(checker.NodeRunner.NetworkParameters(), tx); err != nil {
+		checker.NodeRunner.Log().Debug("VotingNO: fee is lower than the minimum fee", "error", err)
+		votingHole, reason = VotingNO, err
+	} else if err := tx.CheckTimeBounds(proposed); err != nil {
+		checker.NodeRunner.Log().Debug("VotingNO: out of time bounds", "error", err)
+		votingHole, reason = VotingNO, err
+	} else if err := CheckCreateAccountMinimumBalance(checker.NodeRunner.NetworkParameters(), tx); err != nil {
+		checker.NodeRunner.Log().Debug("VotingNO: create-account under the minimum balance")
+		votingHole, reason = VotingNO, err
 	}
 
 	// NOTE(CheckNodeRunnerHandleBallotVotingHole): if BlockTransaction was
@@ -261,7 +411,7 @@ func CheckNodeRunnerHandleBallotVotingHole(c sebakcommon.Checker, args ...interf
 			// compare the stored BlockTransaction with tx
 			if votingHole == VotingYES && tx.B.Source != bt.Source {
 				checker.NodeRunner.Log().Debug("VotingNO: tx.B.Source != bt.Source", "tx.B.Source", tx.B.Source, "bt.Source", bt.Source)
-				votingHole = VotingNO
+				votingHole, reason = VotingNO, sebakerror.ErrorTransactionDoubleSpend
 			}
 		}
 	}
@@ -269,24 +419,48 @@ func CheckNodeRunnerHandleBallotVotingHole(c sebakcommon.Checker, args ...interf
 	if votingHole == VotingYES {
 		if ba, err := GetBlockAccount(checker.NodeRunner.Storage(), tx.B.Source); err != nil {
 			checker.NodeRunner.Log().Debug("VotingNO: ", "error", err)
-			votingHole = VotingNO
+			votingHole, reason = VotingNO, err
 		} else if tx.B.Checkpoint != ba.Checkpoint {
 			checker.NodeRunner.Log().Debug(
 				"VotingNO: tx.B.Checkpoint != ba.Checkpoint",
 				"tx.B.Checkpoint", tx.B.Checkpoint,
 				"ba.Checkpoint", ba.Checkpoint,
 			)
-			votingHole = VotingNO
+			votingHole, reason = VotingNO, sebakerror.ErrorTransactionInvalidCheckpoint
 		} else if tx.TotalAmount(true) > MustAmountFromString(ba.Balance) {
 			checker.NodeRunner.Log().Debug(
 				"VotingNO: tx.TotalAmount(true) > MustAmountFromString(ba.Balance)",
 				"tx.TotalAmount(true)", tx.TotalAmount(true),
 				"MustAmountFromString(ba.Balance)", MustAmountFromString(ba.Balance),
 			)
-			votingHole = VotingNO
+			votingHole, reason = VotingNO, sebakerror.ErrorAccountBalanceUnderZero
+		} else if err := CheckFrozenBalance(checker.NodeRunner.Storage(), tx, MustAmountFromString(ba.Balance)); err != nil {
+			checker.NodeRunner.Log().Debug("VotingNO: frozen balance", "error", err)
+			votingHole, reason = VotingNO, err
 		}
 	}
 
+	if votingHole == VotingYES {
+		if err := ValidateTransactionOperations(checker.NodeRunner.Storage(), tx); err != nil {
+			checker.NodeRunner.Log().Debug("VotingNO: operations failed", "error", err)
+			votingHole, reason = VotingNO, err
+		}
+	}
+
+	if votingHole == VotingYES {
+		day := GetSpendingLimitDay(proposed)
+		if err := CheckSpendingLimit(checker.NodeRunner.Storage(), checker.NodeRunner.NetworkID(), tx, day); err != nil {
+			checker.NodeRunner.Log().Debug("VotingNO: spending limit", "error", err)
+			votingHole, reason = VotingNO, err
+		} else if err := CheckAccountSigners(checker.NodeRunner.Storage(), checker.NodeRunner.NetworkID(), tx); err != nil {
+			checker.NodeRunner.Log().Debug("VotingNO: account signers", "error", err)
+			votingHole, reason = VotingNO, err
+		}
+	}
+
+	if votingHole == VotingNO {
+		checker.NodeRunner.TransactionStatuses().Refused(tx.GetHash(), reason)
+	}
 	checker.VotingHole = votingHole
 
 	return
@@ -325,7 +499,9 @@ func CheckNodeRunnerHandleBallotBroadcast(c sebakcommon.Checker, args ...interfa
 
 	newBallot.SetState(state)
 	newBallot.Vote(checker.VotingHole)
-	newBallot.Sign(checker.CurrentNode.Keypair(), checker.NetworkID)
+	if err = checker.NodeRunner.Consensus().Signer().SignBallot(&newBallot); err != nil {
+		return
+	}
 
 	checker.NodeRunner.Consensus().AddBallot(newBallot)
 
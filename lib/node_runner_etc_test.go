@@ -3,11 +3,13 @@ package sebak
 import (
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/stellar/go/keypair"
 
 	"boscoin.io/sebak/lib/common"
+	"boscoin.io/sebak/lib/error"
 	"boscoin.io/sebak/lib/network"
 )
 
@@ -99,3 +101,125 @@ func TestNodeRunnerLimitIncomingBallotsFromUnknownValidator(t *testing.T) {
 		return
 	}
 }
+
+// TestNodeRunnerStartupQuorumGate checks, the node with startup quorum gate
+// starts voting after it is connected to the quorum of validators.
+func TestNodeRunnerStartupQuorumGate(t *testing.T) {
+	defer sebaknetwork.CleanUpMemoryNetwork()
+
+	numberOfNodes := 3
+	nodeRunners := createNodeRunners(numberOfNodes)
+	for _, nr := range nodeRunners {
+		nr.Policy().Reset(sebakcommon.BallotStateSIGN, 66)
+		nr.Policy().Reset(sebakcommon.BallotStateACCEPT, 66)
+		nr.SetStartupQuorumTimeout(5 * time.Second)
+		if nr.IsQuorumReady() {
+			t.Error("quorum must not be ready before starting")
+			return
+		}
+	}
+
+	for _, nr := range nodeRunners {
+		go nr.Start()
+		defer nr.Stop()
+	}
+
+	deadline := time.After(5 * time.Second)
+	for _, nr := range nodeRunners {
+		for !nr.IsQuorumReady() {
+			select {
+			case <-deadline:
+				t.Error("failed to reach quorum")
+				return
+			case <-time.After(100 * time.Millisecond):
+			}
+		}
+		if !nr.HasQuorum() {
+			t.Error("quorum is ready, but not enough validators are connected")
+			return
+		}
+		if state := nr.State().State(); state != NodeStateConsensus {
+			t.Errorf("node must be in consensus after quorum reached, not %s", state)
+			return
+		}
+		if nr.State().Transitions()[NodeStateSyncing] != 1 {
+			t.Error("node must be syncing before quorum reached")
+			return
+		}
+	}
+}
+
+// TestNodeRunnerStartupQuorumTimeout checks, waiting quorum is failed when
+// validators can not be connected in time.
+func TestNodeRunnerStartupQuorumTimeout(t *testing.T) {
+	defer sebaknetwork.CleanUpMemoryNetwork()
+
+	nodeRunners := createNodeRunners(3)
+	nr := nodeRunners[0]
+	nr.Policy().Reset(sebakcommon.BallotStateSIGN, 66)
+	nr.Policy().Reset(sebakcommon.BallotStateACCEPT, 66)
+
+	// connection manager is not started, so no validators are connected
+	if nr.HasQuorum() {
+		t.Error("quorum must not be satisfied without connected validators")
+		return
+	}
+
+	if err := nr.waitQuorum(100 * time.Millisecond); err != sebakerror.ErrorStartupQuorumTimeout {
+		t.Errorf("expected error, `ErrorStartupQuorumTimeout`, but got %v", err)
+		return
+	}
+}
+
+// TestNodeRunnerDoubleSpend checks, the transaction which spends the
+// checkpoint already spent by the pending transaction or in block is
+// rejected.
+func TestNodeRunnerDoubleSpend(t *testing.T) {
+	defer sebaknetwork.CleanUpMemoryNetwork()
+
+	nr := createNodeRunners(1)[0]
+
+	kp, _ := keypair.Random()
+	checkpoint := uuid.New().String()
+	a := makeTransactionPoolItem(kp, checkpoint, BaseFee, time.Now()).Transaction
+	b := makeTransactionPoolItem(kp, checkpoint, BaseFee*2, time.Now()).Transaction
+
+	check := func(tx Transaction) error {
+		checker := &NodeRunnerHandleMessageChecker{
+			NodeRunner:  nr,
+			Transaction: tx,
+		}
+		return CheckNodeRunnerHandleMessageDoubleSpend(checker)
+	}
+
+	if err := check(a); err != nil {
+		t.Error(err)
+		return
+	}
+	nr.TransactionPool().Add(a)
+
+	if err := check(a); err == nil {
+		t.Error("same transaction must be stopped")
+		return
+	} else if _, ok := err.(sebakcommon.CheckerErrorStop); !ok {
+		t.Errorf("same transaction must be stopped, not %v", err)
+		return
+	}
+	if err := check(b); err != sebakerror.ErrorTransactionDoubleSpend {
+		t.Errorf("double spend in pool must be rejected: %v", err)
+		return
+	}
+
+	// spent in block
+	nr.TransactionPool().Remove(a.GetHash())
+	raw, _ := a.Serialize()
+	bt := NewBlockTransactionFromTransaction(a, raw)
+	if err := bt.Save(nr.Storage()); err != nil {
+		t.Error(err)
+		return
+	}
+	if err := check(b); err != sebakerror.ErrorTransactionDoubleSpend {
+		t.Errorf("double spend in block must be rejected: %v", err)
+		return
+	}
+}
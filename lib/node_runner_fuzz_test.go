@@ -0,0 +1,153 @@
+package sebak
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stellar/go/keypair"
+
+	"boscoin.io/sebak/lib/network"
+)
+
+const numberOfFuzzSeeds int64 = 64
+
+// handleFuzzMessage handles the message and turns the panic into the test
+// failure with the seed, which can reproduce it.
+func handleFuzzMessage(nr *NodeRunner, seed int64, message sebaknetwork.Message) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic with seed=%d: %v", seed, r)
+			panic(err)
+		}
+	}()
+
+	err = nr.handleNetworkMessage(message)
+	return
+}
+
+// sendFuzzMessages sends the messages from `from` to the memory network
+// wrapped by `FuzzNetwork` of `seed` and returns the mutated messages, which
+// `to` receives. The number of received messages is known by replaying the
+// mutations of same seed.
+func sendFuzzMessages(from, to *NodeRunner, seed int64, messages []sebaknetwork.Message) (received []sebaknetwork.Message, err error) {
+	var expected int
+	replay := sebaknetwork.NewFuzzMutator(seed)
+	for _, message := range messages {
+		_, mutated := replay.Mutate(message)
+		expected += len(mutated)
+	}
+
+	network := sebaknetwork.NewMemoryNetwork()
+	network.SetContext(to.ctx)
+	fuzz := sebaknetwork.NewFuzzNetwork(network, seed, expected)
+	go fuzz.Start()
+
+	client := from.Network().GetClient(network.Endpoint())
+	go func() {
+		for _, message := range messages {
+			switch message.Type {
+			case sebaknetwork.BallotMessage:
+				client.SendBallot(rawFuzzMessage(message.Data))
+			default:
+				client.SendMessage(rawFuzzMessage(message.Data))
+			}
+		}
+	}()
+
+	for len(received) < expected {
+		select {
+		case message := <-fuzz.ReceiveMessage():
+			received = append(received, message)
+		case <-time.After(time.Second * 3):
+			err = fmt.Errorf("timeout to receive messages with seed=%d: %d/%d", seed, len(received), expected)
+			return
+		}
+	}
+
+	return
+}
+
+// rawFuzzMessage sends the serialized message as it is.
+type rawFuzzMessage []byte
+
+func (r rawFuzzMessage) Serialize() ([]byte, error) {
+	return []byte(r), nil
+}
+
+// isCorruptedFuzzMessage checks, the received message must be refused; the
+// broken JSON by the truncation or the garbage, or the message of which type
+// is swapped.
+func isCorruptedFuzzMessage(message sebaknetwork.Message, originals []sebaknetwork.Message) bool {
+	if !json.Valid(message.Data) {
+		return true
+	}
+	for _, original := range originals {
+		if bytes.Equal(message.Data, original.Data) && message.Type != original.Type {
+			return true
+		}
+	}
+
+	return false
+}
+
+// TestNodeRunnerFuzzMessages checks, the mutated messages from the other
+// node through `FuzzNetwork` never make the node panic and the corrupted
+// messages are always refused.
+func TestNodeRunnerFuzzMessages(t *testing.T) {
+	defer sebaknetwork.CleanUpMemoryNetwork()
+
+	nodeRunners := createNodeRunners(2)
+	nr0, nr1 := nodeRunners[0], nodeRunners[1]
+	nr1.Network().SetContext(nr1.ctx)
+	atomic.StoreInt32(&nr0.quorumReady, 1)
+
+	kp, _ := keypair.Random()
+	kpNewAccount, _ := keypair.Random()
+	checkpoint := uuid.New().String()
+	NewBlockAccount(kp.Address(), BaseFee.MustAdd(1), checkpoint).Save(nr0.Storage())
+
+	tx := makeTransactionCreateAccount(kp, kpNewAccount.Address(), Amount(1))
+	tx.B.Checkpoint = checkpoint
+	tx.Sign(kp, networkID)
+
+	txManageData := makeTransactionCreateAccount(kp, kpNewAccount.Address(), Amount(1))
+	txManageData.B.Checkpoint = checkpoint
+	txManageData.B.Operations = []Operation{{
+		H: OperationHeader{Type: OperationManageData},
+		B: NewOperationBodyManageData("anchor", []byte("value")),
+	}}
+	txManageData.Sign(kp, networkID)
+
+	ballot, _ := NewBallotFromMessage(nr1.Node().Address(), tx)
+	ballot.Sign(nr1.Node().Keypair(), networkID)
+
+	txData, _ := tx.Serialize()
+	txManageDataData, _ := txManageData.Serialize()
+	ballotData, _ := ballot.Serialize()
+	messages := []sebaknetwork.Message{
+		sebaknetwork.NewMessage(sebaknetwork.MessageFromClient, txData),
+		sebaknetwork.NewMessage(sebaknetwork.MessageFromClient, txManageDataData),
+		sebaknetwork.NewMessage(sebaknetwork.BallotMessage, ballotData),
+	}
+
+	for seed := int64(0); seed < numberOfFuzzSeeds; seed++ {
+		received, err := sendFuzzMessages(nr1, nr0, seed, messages)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		for _, m := range received {
+			err := handleFuzzMessage(nr0, seed, m)
+			if isCorruptedFuzzMessage(m, messages) && err == nil {
+				t.Errorf("corrupted message must be refused: seed=%d message=%s", seed, m)
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,299 @@
+package sebak
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"time"
+
+	"boscoin.io/sebak/lib/error"
+	"boscoin.io/sebak/lib/grpc"
+)
+
+// GRPCConfig is the gRPC listener of the node API for the backends, which
+// want the typed messages and the streams; the services are in
+// 'lib/grpc/sebak.proto'. It is served over TLS, so HTTP/2 is negotiated.
+type GRPCConfig struct {
+	Addr        string
+	TLSCertFile string
+	TLSKeyFile  string
+}
+
+func (c GRPCConfig) IsEmpty() bool {
+	return len(c.Addr) < 1
+}
+
+// SetGRPCConfig enables the gRPC listener; it must be called before the node
+// starts.
+func (nr *NodeRunner) SetGRPCConfig(config GRPCConfig) {
+	nr.grpcConfig = config
+}
+
+func (nr *NodeRunner) GRPCConfig() GRPCConfig {
+	return nr.grpcConfig
+}
+
+// GRPCServer returns the server of the gRPC services.
+func (nr *NodeRunner) GRPCServer() *sebakgrpc.Server {
+	s := sebakgrpc.NewServer()
+
+	s.Handle(sebakgrpc.ServiceQuery, "GetAccount", sebakgrpc.Method{
+		NewRequest: func() sebakgrpc.Message { return &sebakgrpc.GetAccountRequest{} },
+		Unary:      nr.grpcGetAccount,
+	})
+	s.Handle(sebakgrpc.ServiceQuery, "GetTransaction", sebakgrpc.Method{
+		NewRequest: func() sebakgrpc.Message { return &sebakgrpc.GetTransactionRequest{} },
+		Unary:      nr.grpcGetTransaction,
+	})
+	s.Handle(sebakgrpc.ServiceQuery, "GetBlock", sebakgrpc.Method{
+		NewRequest: func() sebakgrpc.Message { return &sebakgrpc.GetBlockRequest{} },
+		Unary:      nr.grpcGetBlock,
+	})
+	s.Handle(sebakgrpc.ServiceSubmit, "SubmitTransaction", sebakgrpc.Method{
+		NewRequest: func() sebakgrpc.Message { return &sebakgrpc.SubmitTransactionRequest{} },
+		Unary:      nr.grpcSubmitTransaction,
+	})
+	s.Handle(sebakgrpc.ServiceStream, "StreamBlocks", sebakgrpc.Method{
+		NewRequest: func() sebakgrpc.Message { return &sebakgrpc.Empty{} },
+		Stream:     nr.grpcStreamBlocks,
+	})
+	s.Handle(sebakgrpc.ServiceStream, "StreamTransactions", sebakgrpc.Method{
+		NewRequest: func() sebakgrpc.Message { return &sebakgrpc.StreamTransactionsRequest{} },
+		Stream:     nr.grpcStreamTransactions,
+	})
+
+	return s
+}
+
+func (nr *NodeRunner) startGRPCServer() {
+	if nr.grpcConfig.IsEmpty() {
+		return
+	}
+
+	server := &http.Server{
+		Addr:              nr.grpcConfig.Addr,
+		Handler:           nr.GRPCServer(),
+		ReadHeaderTimeout: time.Second * 5,
+		TLSConfig:         &tls.Config{MinVersion: tls.VersionTLS12},
+	}
+
+	nr.log.Info("gRPC listener started", "addr", nr.grpcConfig.Addr)
+	go func() {
+		if err := server.ListenAndServeTLS(nr.grpcConfig.TLSCertFile, nr.grpcConfig.TLSKeyFile); err != nil {
+			nr.log.Error("gRPC listener stopped", "error", err)
+		}
+	}()
+}
+
+// grpcCodes are the gRPC status codes of the HTTP statuses of
+// `submitTransaction()`.
+var grpcCodes = map[int]sebakgrpc.Code{
+	http.StatusBadRequest:         sebakgrpc.InvalidArgument,
+	http.StatusForbidden:          sebakgrpc.PermissionDenied,
+	http.StatusConflict:           sebakgrpc.FailedPrecondition,
+	http.StatusServiceUnavailable: sebakgrpc.Unavailable,
+}
+
+// newGRPCNodeError wraps the error of node; the message starts with the
+// result code of `sebakerror.Results()`, like 'tx_double_spend: ...'.
+func newGRPCNodeError(status int, err error) *sebakgrpc.Status {
+	code, found := grpcCodes[status]
+	if !found {
+		code = sebakgrpc.Internal
+	}
+	p := sebakerror.NewProblem(status, err)
+
+	return sebakgrpc.Errorf(code, "%s: %s", p.Result, p.Detail)
+}
+
+func newGRPCBlock(b Block) *sebakgrpc.Block {
+	return &sebakgrpc.Block{
+		Hash:          b.Hash,
+		Height:        b.Height,
+		PrevBlockHash: b.PrevBlockHash,
+		StateHash:     b.StateHash,
+		Transactions:  b.Transactions,
+		Confirmed:     b.Confirmed,
+	}
+}
+
+func newGRPCTransaction(entry AccountTransactionEntry) *sebakgrpc.Transaction {
+	return &sebakgrpc.Transaction{
+		Hash:       entry.Hash,
+		Source:     entry.Source,
+		Fee:        uint64(entry.Fee),
+		Amount:     uint64(entry.Amount),
+		Checkpoint: entry.Checkpoint,
+		Operations: entry.Operations,
+		Created:    entry.Created,
+		Confirmed:  entry.Confirmed,
+	}
+}
+
+func (nr *NodeRunner) grpcGetAccount(ctx context.Context, req sebakgrpc.Message) (sebakgrpc.Message, error) {
+	address := req.(*sebakgrpc.GetAccountRequest).Address
+	if _, err := ParseAccountAddress(address); err != nil {
+		return nil, sebakgrpc.Errorf(sebakgrpc.InvalidArgument, "'address' must be the address")
+	}
+
+	exists, err := ExistBlockAccount(nr.storage, address)
+	if err != nil {
+		return nil, sebakgrpc.Errorf(sebakgrpc.Internal, "%v", err)
+	} else if !exists {
+		return nil, sebakgrpc.Errorf(sebakgrpc.NotFound, "account not found")
+	}
+
+	ba, err := GetBlockAccount(nr.storage, address)
+	if err != nil {
+		return nil, sebakgrpc.Errorf(sebakgrpc.Internal, "%v", err)
+	}
+
+	return &sebakgrpc.Account{
+		Address:    ba.Address,
+		Balance:    uint64(ba.GetBalance()),
+		Checkpoint: ba.Checkpoint,
+	}, nil
+}
+
+func (nr *NodeRunner) grpcGetTransaction(ctx context.Context, req sebakgrpc.Message) (sebakgrpc.Message, error) {
+	hash := req.(*sebakgrpc.GetTransactionRequest).Hash
+	if _, err := ParseTxHash(hash); err != nil {
+		return nil, sebakgrpc.Errorf(sebakgrpc.InvalidArgument, "'hash' must be the hash of transaction")
+	}
+
+	exists, err := ExistBlockTransaction(nr.storage, hash)
+	if err != nil {
+		return nil, sebakgrpc.Errorf(sebakgrpc.Internal, "%v", err)
+	} else if !exists {
+		return nil, sebakgrpc.Errorf(sebakgrpc.NotFound, "transaction not found")
+	}
+
+	bt, err := GetBlockTransaction(nr.storage, hash)
+	if err != nil {
+		return nil, sebakgrpc.Errorf(sebakgrpc.Internal, "%v", err)
+	}
+
+	return newGRPCTransaction(NewAccountTransactionEntry(bt)), nil
+}
+
+// grpcGetBlock finds the block by the height or the hash; without both, the
+// latest block is returned.
+func (nr *NodeRunner) grpcGetBlock(ctx context.Context, req sebakgrpc.Message) (sebakgrpc.Message, error) {
+	r := req.(*sebakgrpc.GetBlockRequest)
+
+	var id BlockID
+	switch {
+	case r.Height > 0 && len(r.Hash) > 0:
+		return nil, sebakgrpc.Errorf(sebakgrpc.InvalidArgument, "only one of 'height' and 'hash' must be given")
+	case r.Height > 0:
+		id = NewBlockIDFromHeight(r.Height)
+	case len(r.Hash) > 0:
+		if !IsValidHash(r.Hash) {
+			return nil, sebakgrpc.Errorf(sebakgrpc.InvalidArgument, "'hash' must be the hash of block")
+		}
+		id = NewBlockIDFromHash(r.Hash)
+	default:
+		b, err := GetLatestBlock(nr.storage)
+		if err != nil {
+			return nil, sebakgrpc.Errorf(sebakgrpc.Internal, "%v", err)
+		} else if b.IsEmpty() {
+			return nil, sebakgrpc.Errorf(sebakgrpc.NotFound, "block not found")
+		}
+		return newGRPCBlock(b), nil
+	}
+
+	exists, err := nr.storage.Has(id.StorageKey())
+	if err != nil {
+		return nil, sebakgrpc.Errorf(sebakgrpc.Internal, "%v", err)
+	} else if !exists {
+		return nil, sebakgrpc.Errorf(sebakgrpc.NotFound, "block not found")
+	}
+
+	b, err := GetBlockByID(nr.storage, id)
+	if err != nil {
+		return nil, sebakgrpc.Errorf(sebakgrpc.Internal, "%v", err)
+	}
+
+	return newGRPCBlock(b), nil
+}
+
+// grpcSubmitTransaction submits the transaction like 'POST /transactions'.
+func (nr *NodeRunner) grpcSubmitTransaction(ctx context.Context, req sebakgrpc.Message) (sebakgrpc.Message, error) {
+	body := req.(*sebakgrpc.SubmitTransactionRequest).Transaction
+	if int64(len(body)) > MaxTransactionRequestSize {
+		return nil, sebakgrpc.Errorf(sebakgrpc.ResourceExhausted, "transaction is larger than %d bytes", MaxTransactionRequestSize)
+	}
+
+	response, status, err := nr.submitTransaction(body)
+	if err != nil {
+		return nil, newGRPCNodeError(status, err)
+	}
+
+	return &sebakgrpc.SubmitTransactionResponse{
+		Hash:   response.Hash,
+		Status: response.Status,
+		Result: string(response.Result),
+	}, nil
+}
+
+// grpcStream sends the selected events until the client cancels; the slow
+// client, which is dropped by `EventStream` gets `ResourceExhausted`.
+func (nr *NodeRunner) grpcStream(ctx context.Context, filter StreamFilterFunc, send func(StreamEvent) error) error {
+	sub, err := nr.stream.Subscribe(filter)
+	if err != nil {
+		return sebakgrpc.Errorf(sebakgrpc.Unavailable, "%v", err)
+	}
+	defer nr.stream.Unsubscribe(sub)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-sub.Events:
+			if !ok {
+				return sebakgrpc.Errorf(sebakgrpc.ResourceExhausted, "client is too slow")
+			}
+			if err = send(event); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (nr *NodeRunner) grpcStreamBlocks(ctx context.Context, req sebakgrpc.Message, send func(sebakgrpc.Message) error) error {
+	filter := func(event StreamEvent) bool {
+		return event.Type == StreamEventBlock
+	}
+
+	return nr.grpcStream(ctx, filter, func(event StreamEvent) error {
+		return send(newGRPCBlock(event.Data.(Block)))
+	})
+}
+
+func (nr *NodeRunner) grpcStreamTransactions(ctx context.Context, req sebakgrpc.Message, send func(sebakgrpc.Message) error) error {
+	account := req.(*sebakgrpc.StreamTransactionsRequest).Account
+	if len(account) > 0 {
+		if _, err := ParseAccountAddress(account); err != nil {
+			return sebakgrpc.Errorf(sebakgrpc.InvalidArgument, "'account' must be the address")
+		}
+	}
+
+	filter := func(event StreamEvent) bool {
+		if event.Type != StreamEventTransaction {
+			return false
+		}
+		if len(account) < 1 {
+			return true
+		}
+		for _, a := range event.Accounts {
+			if a == account {
+				return true
+			}
+		}
+		return false
+	}
+
+	return nr.grpcStream(ctx, filter, func(event StreamEvent) error {
+		return send(newGRPCTransaction(event.Data.(AccountTransactionEntry)))
+	})
+}
@@ -0,0 +1,150 @@
+package sebak
+
+import (
+	"bytes"
+	"context"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stellar/go/keypair"
+
+	"boscoin.io/sebak/lib/error"
+	"boscoin.io/sebak/lib/grpc"
+	"boscoin.io/sebak/lib/network"
+)
+
+func testCallGRPC(nr *NodeRunner, service, method string, req, res sebakgrpc.Message) *sebakgrpc.Status {
+	var body bytes.Buffer
+	sebakgrpc.WriteMessage(&body, req)
+
+	r := httptest.NewRequest("POST", sebakgrpc.MethodPath(service, method), &body)
+	r.Header.Set("Content-Type", sebakgrpc.ContentType)
+	w := httptest.NewRecorder()
+	nr.GRPCServer().ServeHTTP(w, r)
+
+	trailer := w.Result().Trailer
+	if code := trailer.Get("Grpc-Status"); code != "0" {
+		n, _ := strconv.Atoi(code)
+		return &sebakgrpc.Status{Code: sebakgrpc.Code(n), Message: trailer.Get("Grpc-Message")}
+	}
+	sebakgrpc.ReadMessage(w.Body, res)
+
+	return nil
+}
+
+func TestNodeRunnerGRPC(t *testing.T) {
+	defer sebaknetwork.CleanUpMemoryNetwork()
+
+	nr := createNodeRunners(1)[0]
+	atomic.StoreInt32(&nr.quorumReady, 1)
+
+	kp, _ := keypair.Random()
+	target := testMakeBlockAccount()
+	target.Save(nr.Storage())
+	tx := makeTransactionPayment(kp, target.Address, Amount(1))
+	NewBlockAccount(kp.Address(), Amount(BaseFee*100), tx.B.Checkpoint).Save(nr.Storage())
+
+	var account sebakgrpc.Account
+	if s := testCallGRPC(nr, sebakgrpc.ServiceQuery, "GetAccount", &sebakgrpc.GetAccountRequest{Address: kp.Address()}, &account); s != nil {
+		t.Error(s)
+		return
+	}
+	if account.Address != kp.Address() || account.Balance != uint64(BaseFee*100) || account.Checkpoint != tx.B.Checkpoint {
+		t.Errorf("wrong account: %v", account)
+		return
+	}
+
+	unknown, _ := keypair.Random()
+	if s := testCallGRPC(nr, sebakgrpc.ServiceQuery, "GetAccount", &sebakgrpc.GetAccountRequest{Address: unknown.Address()}, &account); s == nil || s.Code != sebakgrpc.NotFound {
+		t.Errorf("unknown account must be not found: %v", s)
+		return
+	}
+
+	block := NewBlock(Block{}, "state")
+	block.Save(nr.Storage())
+	var b sebakgrpc.Block
+	if s := testCallGRPC(nr, sebakgrpc.ServiceQuery, "GetBlock", &sebakgrpc.GetBlockRequest{}, &b); s != nil || b.Hash != block.Hash || b.Height != block.Height {
+		t.Errorf("wrong latest block: %v %v", b, s)
+		return
+	}
+	if s := testCallGRPC(nr, sebakgrpc.ServiceQuery, "GetBlock", &sebakgrpc.GetBlockRequest{Height: block.Height + 1}, &b); s == nil || s.Code != sebakgrpc.NotFound {
+		t.Errorf("unknown block must be not found: %v", s)
+		return
+	}
+
+	// submit transaction
+	go func() {
+		<-nr.Network().ReceiveMessage()
+	}()
+	body, _ := tx.Serialize()
+	var submitted sebakgrpc.SubmitTransactionResponse
+	if s := testCallGRPC(nr, sebakgrpc.ServiceSubmit, "SubmitTransaction", &sebakgrpc.SubmitTransactionRequest{Transaction: body}, &submitted); s != nil {
+		t.Error(s)
+		return
+	}
+	if submitted.Hash != tx.GetHash() || submitted.Result != string(sebakerror.ResultTransactionAccepted) {
+		t.Errorf("wrong response: %v", submitted)
+		return
+	}
+
+	// the node error has the result code
+	nr.TransactionPool().Add(tx)
+	another := makeTransactionPayment(kp, testMakeBlockAccount().Address, Amount(2))
+	another.B.Checkpoint = tx.B.Checkpoint
+	another.H.Hash = another.B.MakeHashString()
+	another.Sign(kp, networkID)
+	body, _ = another.Serialize()
+
+	s := testCallGRPC(nr, sebakgrpc.ServiceSubmit, "SubmitTransaction", &sebakgrpc.SubmitTransactionRequest{Transaction: body}, &submitted)
+	if s == nil || s.Code != sebakgrpc.FailedPrecondition || !strings.HasPrefix(s.Message, string(sebakerror.ResultTransactionDoubleSpend)+":") {
+		t.Errorf("double spend must be refused: %v", s)
+		return
+	}
+}
+
+func TestNodeRunnerGRPCStreamBlocks(t *testing.T) {
+	defer sebaknetwork.CleanUpMemoryNetwork()
+
+	nr := createNodeRunners(1)[0]
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sent := make(chan sebakgrpc.Message, 1)
+	done := make(chan error)
+	go func() {
+		done <- nr.grpcStreamBlocks(ctx, &sebakgrpc.Empty{}, func(m sebakgrpc.Message) error {
+			sent <- m
+			return nil
+		})
+	}()
+
+	for nr.stream.Len() < 1 {
+		time.Sleep(time.Millisecond)
+	}
+	block := NewBlock(Block{}, "state")
+	nr.stream.Publish(
+		StreamEvent{Type: StreamEventTransaction, Data: AccountTransactionEntry{}},
+		StreamEvent{Type: StreamEventBlock, Data: block},
+	)
+
+	select {
+	case m := <-sent:
+		if m.(*sebakgrpc.Block).Hash != block.Hash {
+			t.Errorf("wrong streamed block: %v", m)
+			return
+		}
+	case <-time.After(time.Second):
+		t.Error("block is not streamed")
+		return
+	}
+
+	cancel()
+	if err := <-done; err != nil || nr.stream.Len() != 0 {
+		t.Errorf("stream must be closed by the client: %v", err)
+		return
+	}
+}
@@ -143,7 +143,13 @@ func createNodeRunners(n int) []*NodeRunner {
 }
 
 func createNodeRunnersWithReady(n int) []*NodeRunner {
-	nodeRunners := createNodeRunners(n)
+	return startNodeRunnersWithReady(createNodeRunners(n))
+}
+
+// startNodeRunnersWithReady starts the node runners and waits until they are
+// connected to each other, so they can be configured before they start.
+func startNodeRunnersWithReady(nodeRunners []*NodeRunner) []*NodeRunner {
+	n := len(nodeRunners)
 
 	for _, nr := range nodeRunners {
 		go nr.Start()
@@ -121,6 +121,30 @@ func TestNodeRunnerPayment(t *testing.T) {
 		t.Error("failed to subtract the transfered amount from source")
 		return
 	}
+
+	// check new block
+	var stateHash string
+	for _, nr := range nodeRunners {
+		block, err := GetLatestBlock(nr.Storage())
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if block.Height != 1 || len(block.Transactions) != 1 || block.Transactions[0] != tx.GetHash() {
+			t.Error("new block was not made by consensus")
+			return
+		}
+
+		if len(block.StateHash) < 1 || (len(stateHash) > 0 && block.StateHash != stateHash) {
+			t.Error("state hash of block must be same in every node")
+			return
+		}
+		stateHash = block.StateHash
+		if err = VerifyStateHash(nr.Storage()); err != nil {
+			t.Error(err)
+			return
+		}
+	}
 }
 
 func doConsensus(nodeRunners []*NodeRunner, tx Transaction) []VotingStateStaging {
@@ -0,0 +1,105 @@
+package sebak
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stellar/go/keypair"
+
+	"boscoin.io/sebak/lib/common"
+	"boscoin.io/sebak/lib/network"
+)
+
+// TestNodeRunnerViewChange checks, when the expected proposer does not
+// propose, the other validators change the view and the next proposer makes
+// the block. The proposer timeout is not waited; the clock of view is moved,
+// so the test does not depend on the load of machine.
+func TestNodeRunnerViewChange(t *testing.T) {
+	defer sebaknetwork.CleanUpMemoryNetwork()
+
+	numberOfNodes := 3
+	nodeRunners := createNodeRunners(numberOfNodes)
+
+	var skew int64
+	clock := func() time.Time {
+		return time.Now().Add(time.Duration(atomic.LoadInt64(&skew)))
+	}
+
+	kp, _ := keypair.Random()
+	kpNewAccount, _ := keypair.Random()
+
+	checkpoint := uuid.New().String()
+	for _, nr := range nodeRunners {
+		NewBlockAccount(kp.Address(), BaseFee.MustAdd(1), checkpoint).Save(nr.Storage())
+		nr.SetProposerTimeout(time.Hour)
+		nr.ViewChange().SetClock(clock)
+	}
+
+	startNodeRunnersWithReady(nodeRunners)
+	for _, nr := range nodeRunners {
+		defer nr.Stop()
+	}
+
+	// the expected proposer of the first block is down
+	expected := NewProposerSchedule(nodeRunners[0].Node()).ProposerOfView(1, 0)
+	var down *NodeRunner
+	var healthy []*NodeRunner
+	for _, nr := range nodeRunners {
+		if nr.Node().Address() == expected.Address {
+			down = nr
+			continue
+		}
+		healthy = append(healthy, nr)
+	}
+	down.SetProposeTransactionCheckerFuncs(nil, func(c sebakcommon.Checker, args ...interface{}) error {
+		return sebakcommon.CheckerErrorStop{"proposer is down"}
+	})
+
+	tx := makeTransactionCreateAccount(kp, kpNewAccount.Address(), Amount(1))
+	tx.B.Checkpoint = checkpoint
+	tx.Sign(kp, networkID)
+
+	client := healthy[0].Network().GetClient(healthy[0].Node().Endpoint())
+	client.SendMessage(tx)
+
+	timeout := time.After(time.Minute)
+
+	// the view is expired after the transaction is shared with the validators
+	for _, nr := range healthy {
+		for !nr.TransactionPool().Has(tx.GetHash()) {
+			select {
+			case <-timeout:
+				t.Error("transaction is not shared with the validators")
+				return
+			case <-time.After(time.Millisecond * 10):
+			}
+		}
+	}
+	atomic.StoreInt64(&skew, int64(time.Hour*2))
+	for _, nr := range healthy {
+		for {
+			block, _ := GetLatestBlock(nr.Storage())
+			if block.Height == 1 {
+				if block.Transactions[0] != tx.GetHash() {
+					t.Error("wrong transaction in block")
+					return
+				}
+				break
+			}
+
+			select {
+			case <-timeout:
+				t.Error("failed to make block after view change")
+				return
+			case <-time.After(time.Millisecond * 100):
+			}
+		}
+
+		if _, err := GetBlockAccount(nr.Storage(), kpNewAccount.Address()); err != nil {
+			t.Error("failed to create account")
+			return
+		}
+	}
+}
@@ -0,0 +1,307 @@
+package sebak
+
+import (
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/btcsuite/btcutil/base58"
+
+	"boscoin.io/sebak/lib/common"
+	"boscoin.io/sebak/lib/error"
+)
+
+// NodeSigner signs the messages of the node itself, the ballots, the view
+// changes and the block announcements. By default the node signs them with
+// it's keypair, but with `ThresholdNodeSigner` the signature is made by the
+// quorum of signer daemons, which keep the shares of the validator key.
+type NodeSigner interface {
+	Address() string
+	SignBallot(*Ballot) error
+	SignViewChange(*ViewChange) error
+	SignBlockAnnouncement(*BlockAnnouncement) error
+}
+
+// KeypairNodeSigner signs with the keypair of node.
+type KeypairNodeSigner struct {
+	node      sebakcommon.Node
+	networkID []byte
+}
+
+func NewKeypairNodeSigner(node sebakcommon.Node, networkID []byte) *KeypairNodeSigner {
+	return &KeypairNodeSigner{node: node, networkID: networkID}
+}
+
+func (s *KeypairNodeSigner) Address() string {
+	return s.node.Address()
+}
+
+func (s *KeypairNodeSigner) SignBallot(ballot *Ballot) error {
+	ballot.Sign(s.node.Keypair(), s.networkID)
+	return nil
+}
+
+func (s *KeypairNodeSigner) SignViewChange(vc *ViewChange) error {
+	vc.Sign(s.node.Keypair(), s.networkID)
+	return nil
+}
+
+func (s *KeypairNodeSigner) SignBlockAnnouncement(ba *BlockAnnouncement) error {
+	ba.Sign(s.node.Keypair(), s.networkID)
+	return nil
+}
+
+// ThresholdSignerClient is the connection to one signer daemon.
+type ThresholdSignerClient interface {
+	Commit() (ThresholdCommitment, error)
+	Sign(ThresholdSignRequest) (ThresholdPartialSignature, error)
+}
+
+const (
+	ThresholdSignerCommitPattern string = "/commit"
+	ThresholdSignerSignPattern   string = "/sign"
+)
+
+// MaxThresholdSignRequestSize is the maximum size of the sign request; the
+// ballot has the whole transaction with the commitments.
+const MaxThresholdSignRequestSize int64 = 2 * MaxTransactionRequestSize
+
+// HTTPThresholdSignerClient requests to the signer daemon over HTTPS; the
+// certificate of daemon must be verified by `roots`, the pinned CA of the
+// signers, and the daemon allows the request with the bearer `token`.
+type HTTPThresholdSignerClient struct {
+	endpoint string
+	token    string
+	client   *sebakcommon.HTTP2Client
+}
+
+func NewHTTPThresholdSignerClient(endpoint, token string, timeout time.Duration, roots *x509.CertPool) (c *HTTPThresholdSignerClient, err error) {
+	var parsed *url.URL
+	if parsed, err = url.Parse(endpoint); err != nil {
+		return
+	}
+	if parsed.Scheme != "https" || roots == nil {
+		err = sebakerror.ErrorThresholdSignerInsecure
+		return
+	}
+
+	var client *sebakcommon.HTTP2Client
+	if client, err = sebakcommon.NewHTTP2ClientWithTLS(timeout, 0, false, &tls.Config{RootCAs: roots}); err != nil {
+		return
+	}
+
+	c = &HTTPThresholdSignerClient{
+		endpoint: strings.TrimRight(endpoint, "/"),
+		token:    token,
+		client:   client,
+	}
+
+	return
+}
+
+func (c *HTTPThresholdSignerClient) request(pattern string, body []byte, v interface{}) (err error) {
+	headers := http.Header{}
+	headers.Set("Content-Type", "application/json")
+	if len(c.token) > 0 {
+		headers.Set("Authorization", "Bearer "+c.token)
+	}
+
+	var response *http.Response
+	if response, err = c.client.Post(c.endpoint+pattern, body, headers); err != nil {
+		return
+	}
+	defer response.Body.Close()
+
+	var b []byte
+	if b, err = ioutil.ReadAll(response.Body); err != nil {
+		return
+	}
+
+	if response.StatusCode != http.StatusOK {
+		var e APIError
+		if err = json.Unmarshal(b, &e); err != nil {
+			return
+		}
+		if e.Code > 0 {
+			return sebakerror.NewError(e.Code, e.Detail)
+		}
+		return fmt.Errorf("signer responded %d: %s", e.Status, e.Detail)
+	}
+
+	return json.Unmarshal(b, v)
+}
+
+func (c *HTTPThresholdSignerClient) Commit() (commitment ThresholdCommitment, err error) {
+	err = c.request(ThresholdSignerCommitPattern, []byte("{}"), &commitment)
+	return
+}
+
+func (c *HTTPThresholdSignerClient) Sign(request ThresholdSignRequest) (partial ThresholdPartialSignature, err error) {
+	var b []byte
+	if b, err = json.Marshal(request); err != nil {
+		return
+	}
+	err = c.request(ThresholdSignerSignPattern, b, &partial)
+
+	return
+}
+
+// ThresholdNodeSigner collects the partial signatures from the signer
+// daemons and aggregates them to the signature of validator. The signers are
+// tried in order, so the signing works while `threshold` signers respond.
+type ThresholdNodeSigner struct {
+	validator string
+	networkID []byte
+	threshold int
+	signers   []ThresholdSignerClient
+}
+
+func NewThresholdNodeSigner(validator string, networkID []byte, threshold int, signers []ThresholdSignerClient) (s *ThresholdNodeSigner, err error) {
+	if _, err = validatorPublicKey(validator); err != nil {
+		return
+	}
+	if threshold < 2 || threshold > len(signers) {
+		err = sebakerror.ErrorThresholdNotEnoughSigners
+		return
+	}
+
+	s = &ThresholdNodeSigner{
+		validator: validator,
+		networkID: networkID,
+		threshold: threshold,
+		signers:   signers,
+	}
+
+	return
+}
+
+func (s *ThresholdNodeSigner) Address() string {
+	return s.validator
+}
+
+func (s *ThresholdNodeSigner) sign(t, hash string, v interface{}) (signature string, err error) {
+	request := ThresholdSignRequest{Type: t}
+	if request.Data, err = json.Marshal(v); err != nil {
+		return
+	}
+
+	var signers []ThresholdSignerClient
+	var errs []string
+	for _, signer := range s.signers {
+		if len(signers) == s.threshold {
+			break
+		}
+
+		commitment, commitErr := signer.Commit()
+		if commitErr != nil {
+			errs = append(errs, commitErr.Error())
+			continue
+		}
+		signers = append(signers, signer)
+		request.Commitments = append(request.Commitments, commitment)
+	}
+	if len(signers) < s.threshold {
+		err = fmt.Errorf("%v: %s", sebakerror.ErrorThresholdNotEnoughSigners, strings.Join(errs, ", "))
+		return
+	}
+
+	var partials []ThresholdPartialSignature
+	for _, signer := range signers {
+		var partial ThresholdPartialSignature
+		if partial, err = signer.Sign(request); err != nil {
+			return
+		}
+		partials = append(partials, partial)
+	}
+
+	message := append(append([]byte{}, s.networkID...), []byte(hash)...)
+
+	var b []byte
+	if b, err = AggregateThresholdSignature(s.validator, message, request.Commitments, partials); err != nil {
+		return
+	}
+
+	return base58.Encode(b), nil
+}
+
+func (s *ThresholdNodeSigner) SignBallot(ballot *Ballot) (err error) {
+	ballot.B.NodeKey = s.validator
+	ballot.H.Certificate = nil
+	ballot.UpdateHash()
+	ballot.H.Signature, err = s.sign(ballot.T, ballot.GetHash(), ballot)
+
+	return
+}
+
+func (s *ThresholdNodeSigner) SignViewChange(vc *ViewChange) (err error) {
+	vc.H.Hash = vc.B.MakeHashString()
+	vc.H.Signature, err = s.sign(vc.T, vc.H.Hash, vc)
+
+	return
+}
+
+func (s *ThresholdNodeSigner) SignBlockAnnouncement(ba *BlockAnnouncement) (err error) {
+	ba.H.Hash = ba.B.MakeHashString()
+	ba.H.Signature, err = s.sign(ba.T, ba.H.Hash, ba)
+
+	return
+}
+
+// NewThresholdSignerHandler is the HTTP handler of signer daemon; without
+// the valid bearer `token`, the request is refused.
+func NewThresholdSignerHandler(signer *ThresholdSigner, token string) http.Handler {
+	authorized := func(w http.ResponseWriter, r *http.Request) bool {
+		if r.Method != "POST" {
+			writeAPIError(w, r, http.StatusMethodNotAllowed, nil)
+			return false
+		}
+		if len(token) > 0 && subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte("Bearer "+token)) != 1 {
+			writeAPIError(w, r, http.StatusUnauthorized, errors.New("invalid token"))
+			return false
+		}
+		return true
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(ThresholdSignerCommitPattern, func(w http.ResponseWriter, r *http.Request) {
+		if !authorized(w, r) {
+			return
+		}
+
+		commitment, err := signer.Commit()
+		if err != nil {
+			writeAPIError(w, r, http.StatusServiceUnavailable, err)
+			return
+		}
+		writeAPIJSON(w, http.StatusOK, commitment)
+	})
+	mux.HandleFunc(ThresholdSignerSignPattern, func(w http.ResponseWriter, r *http.Request) {
+		if !authorized(w, r) {
+			return
+		}
+
+		var request ThresholdSignRequest
+		if err := json.NewDecoder(io.LimitReader(r.Body, MaxThresholdSignRequestSize)).Decode(&request); err != nil {
+			writeAPIError(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		partial, err := signer.Sign(request)
+		if err != nil {
+			writeAPIError(w, r, http.StatusBadRequest, err)
+			return
+		}
+		writeAPIJSON(w, http.StatusOK, partial)
+	})
+
+	return mux
+}
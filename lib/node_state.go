@@ -0,0 +1,188 @@
+package sebak
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"boscoin.io/sebak/lib/error"
+)
+
+// NodeState is the lifecycle state of node.
+//  * `booting`: the node is loading storage and not yet connected
+//  * `syncing`: the node is waiting for the quorum of validators
+//  * `consensus`: the node is voting with the quorum of validators
+//  * `degraded`: the node lost the quorum of validators after `consensus`
+//  * `draining`: the node is stopping
+//  * `halted`: the node is stopped or failed to start
+type NodeState string
+
+const (
+	NodeStateBooting   NodeState = "booting"
+	NodeStateSyncing   NodeState = "syncing"
+	NodeStateConsensus NodeState = "consensus"
+	NodeStateDegraded  NodeState = "degraded"
+	NodeStateDraining  NodeState = "draining"
+	NodeStateHalted    NodeState = "halted"
+)
+
+var NodeStates = []NodeState{
+	NodeStateBooting,
+	NodeStateSyncing,
+	NodeStateConsensus,
+	NodeStateDegraded,
+	NodeStateDraining,
+	NodeStateHalted,
+}
+
+// nodeStateTransitions is the allowed next states of each state.
+var nodeStateTransitions = map[NodeState][]NodeState{
+	NodeStateBooting:   {NodeStateSyncing, NodeStateConsensus, NodeStateDraining, NodeStateHalted},
+	NodeStateSyncing:   {NodeStateConsensus, NodeStateDraining, NodeStateHalted},
+	NodeStateConsensus: {NodeStateDegraded, NodeStateSyncing, NodeStateDraining, NodeStateHalted},
+	NodeStateDegraded:  {NodeStateConsensus, NodeStateSyncing, NodeStateDraining, NodeStateHalted},
+	NodeStateDraining:  {NodeStateHalted},
+	NodeStateHalted:    {},
+}
+
+func (s NodeState) CanTransitTo(to NodeState) bool {
+	for _, next := range nodeStateTransitions[s] {
+		if next == to {
+			return true
+		}
+	}
+
+	return false
+}
+
+// NodeStateHook is called after the state is changed. It must not change the
+// state again.
+type NodeStateHook func(from, to NodeState)
+
+type NodeStateMachine struct {
+	sync.RWMutex
+
+	state       NodeState
+	since       time.Time
+	transitions map[NodeState]uint64 // the number of entering to the state
+	hooks       []NodeStateHook
+}
+
+func NewNodeStateMachine() *NodeStateMachine {
+	return &NodeStateMachine{
+		state:       NodeStateBooting,
+		since:       time.Now(),
+		transitions: map[NodeState]uint64{NodeStateBooting: 1},
+	}
+}
+
+func (m *NodeStateMachine) State() NodeState {
+	m.RLock()
+	defer m.RUnlock()
+
+	return m.state
+}
+
+// Since returns the time when the current state started.
+func (m *NodeStateMachine) Since() time.Time {
+	m.RLock()
+	defer m.RUnlock()
+
+	return m.since
+}
+
+func (m *NodeStateMachine) AddHook(hook NodeStateHook) {
+	m.Lock()
+	defer m.Unlock()
+
+	m.hooks = append(m.hooks, hook)
+}
+
+// Transit changes the state; if it is not allowed from the current state,
+// `ErrorInvalidNodeStateTransition` is returned. Transiting to the current
+// state does nothing.
+func (m *NodeStateMachine) Transit(to NodeState) (err error) {
+	m.Lock()
+
+	from := m.state
+	if from == to {
+		m.Unlock()
+		return
+	}
+	if !from.CanTransitTo(to) {
+		m.Unlock()
+		err = sebakerror.ErrorInvalidNodeStateTransition
+		return
+	}
+
+	m.state = to
+	m.since = time.Now()
+	m.transitions[to]++
+	hooks := m.hooks
+	m.Unlock()
+
+	for _, hook := range hooks {
+		hook(from, to)
+	}
+
+	return
+}
+
+// TransitIf changes the state only if the current state is `from`.
+func (m *NodeStateMachine) TransitIf(from, to NodeState) (changed bool, err error) {
+	m.RLock()
+	current := m.state
+	m.RUnlock()
+
+	if current != from {
+		return
+	}
+	if err = m.Transit(to); err != nil {
+		return
+	}
+	changed = true
+
+	return
+}
+
+// Transitions returns the number of entering to each state.
+func (m *NodeStateMachine) Transitions() map[NodeState]uint64 {
+	m.RLock()
+	defer m.RUnlock()
+
+	transitions := map[NodeState]uint64{}
+	for _, s := range NodeStates {
+		transitions[s] = m.transitions[s]
+	}
+
+	return transitions
+}
+
+// Metrics returns the state metrics in the Prometheus text format.
+func (m *NodeStateMachine) Metrics() string {
+	state := m.State()
+	since := m.Since()
+	transitions := m.Transitions()
+
+	s := "# HELP sebak_node_state current lifecycle state of node\n"
+	s += "# TYPE sebak_node_state gauge\n"
+	for _, ns := range NodeStates {
+		var v int
+		if ns == state {
+			v = 1
+		}
+		s += fmt.Sprintf("sebak_node_state{state=\"%s\"} %d\n", ns, v)
+	}
+
+	s += "# HELP sebak_node_state_transitions_total number of transitions into the state\n"
+	s += "# TYPE sebak_node_state_transitions_total counter\n"
+	for _, ns := range NodeStates {
+		s += fmt.Sprintf("sebak_node_state_transitions_total{state=\"%s\"} %d\n", ns, transitions[ns])
+	}
+
+	s += "# HELP sebak_node_state_seconds seconds since the current state started\n"
+	s += "# TYPE sebak_node_state_seconds gauge\n"
+	s += fmt.Sprintf("sebak_node_state_seconds %f\n", time.Since(since).Seconds())
+
+	return s
+}
@@ -0,0 +1,72 @@
+package sebak
+
+import (
+	"strings"
+	"testing"
+
+	"boscoin.io/sebak/lib/error"
+)
+
+func TestNodeStateMachine(t *testing.T) {
+	m := NewNodeStateMachine()
+	if m.State() != NodeStateBooting {
+		t.Error("initial state must be booting")
+		return
+	}
+
+	var changes []string
+	m.AddHook(func(from, to NodeState) {
+		changes = append(changes, string(from)+">"+string(to))
+	})
+
+	for _, s := range []NodeState{NodeStateSyncing, NodeStateConsensus, NodeStateDegraded, NodeStateConsensus} {
+		if err := m.Transit(s); err != nil {
+			t.Error(err)
+			return
+		}
+	}
+	if m.State() != NodeStateConsensus {
+		t.Errorf("unexpected state: %s", m.State())
+		return
+	}
+	if len(changes) != 4 || changes[3] != "degraded>consensus" {
+		t.Errorf("hooks must be called for each transition: %v", changes)
+		return
+	}
+
+	// same state does nothing
+	if err := m.Transit(NodeStateConsensus); err != nil || len(changes) != 4 {
+		t.Error("transition to the same state must do nothing")
+		return
+	}
+
+	if err := m.Transit(NodeStateBooting); err != sebakerror.ErrorInvalidNodeStateTransition {
+		t.Error("node can not go back to booting")
+		return
+	}
+
+	if changed, _ := m.TransitIf(NodeStateDegraded, NodeStateConsensus); changed {
+		t.Error("state must not be changed if current state does not match")
+		return
+	}
+
+	m.Transit(NodeStateDraining)
+	m.Transit(NodeStateHalted)
+	if err := m.Transit(NodeStateConsensus); err != sebakerror.ErrorInvalidNodeStateTransition {
+		t.Error("halted node can not be changed")
+		return
+	}
+
+	transitions := m.Transitions()
+	if transitions[NodeStateConsensus] != 2 || transitions[NodeStateHalted] != 1 {
+		t.Errorf("unexpected transitions: %v", transitions)
+		return
+	}
+
+	metrics := m.Metrics()
+	if !strings.Contains(metrics, `sebak_node_state{state="halted"} 1`) ||
+		!strings.Contains(metrics, `sebak_node_state_transitions_total{state="consensus"} 2`) {
+		t.Errorf("unexpected metrics: %s", metrics)
+		return
+	}
+}
@@ -2,7 +2,6 @@ package sebak
 
 import (
 	"encoding/json"
-	"fmt"
 
 	"github.com/btcsuite/btcutil/base58"
 
@@ -14,8 +13,13 @@ import (
 type OperationType string
 
 const (
-	OperationCreateAccount OperationType = "create-account"
-	OperationPayment                     = "payment"
+	OperationCreateAccount    OperationType = "create-account"
+	OperationPayment                        = "payment"
+	OperationManageData                     = "manage-data"
+	OperationSetSpendingLimit               = "set-spending-limit"
+	OperationSetSigners                     = "set-signers"
+	OperationFreeze                         = "freeze"
+	OperationUnfreeze                       = "unfreeze"
 )
 
 type Operation struct {
@@ -31,8 +35,23 @@ func (o Operation) MakeHashString() string {
 	return base58.Encode(o.MakeHash())
 }
 
+func (o Operation) EncodeCanonical(e *sebakcommon.CanonicalEncoder) {
+	e.String(string(o.H.Type))
+	o.B.EncodeCanonical(e)
+}
+
+// IsWellFormed checks the operation by the registered `OperationKind` of it's
+// type.
 func (o Operation) IsWellFormed(networkID []byte) (err error) {
-	if err = o.B.IsWellFormed(networkID); err != nil {
+	var kind OperationKind
+	if kind, err = GetOperationKind(o.H.Type); err != nil {
+		return
+	}
+	if !kind.IsBody(o.B) {
+		err = sebakerror.ErrorTypeOperationBodyNotMatched
+		return
+	}
+	if err = kind.IsWellFormed(networkID, o); err != nil {
 		return
 	}
 
@@ -78,22 +97,18 @@ func NewOperationFromBytes(b []byte) (op Operation, err error) {
 func NewOperationFromInterface(oj OperationFromJSON) (op Operation, err error) {
 	op.H = oj.H
 
-	body := oj.B.(map[string]interface{})
-	switch op.H.Type {
-	case OperationCreateAccount:
-		var amount Amount
-		amount, err = AmountFromString(fmt.Sprintf("%v", body["amount"]))
-		if err != nil {
-			return
-		}
-		op.B = NewOperationBodyCreateAccount(body["target"].(string), amount)
-	case OperationPayment:
-		var amount Amount
-		amount, err = AmountFromString(fmt.Sprintf("%v", body["amount"]))
-		if err != nil {
-			return
-		}
-		op.B = NewOperationBodyPayment(body["target"].(string), amount)
+	body, ok := oj.B.(map[string]interface{})
+	if !ok {
+		err = sebakerror.ErrorInvalidOperation
+		return
+	}
+
+	var kind OperationKind
+	if kind, err = GetOperationKind(op.H.Type); err != nil {
+		return
+	}
+	if op.B, err = kind.NewBody(body); err != nil {
+		return
 	}
 
 	return
@@ -104,19 +119,12 @@ func NewOperation(t OperationType, body OperationBody) (op Operation, err error)
 		return
 	}
 
-	switch t {
-	case OperationCreateAccount:
-		if _, ok := body.(OperationBodyCreateAccount); !ok {
-			err = sebakerror.ErrorTypeOperationBodyNotMatched
-			return
-		}
-	case OperationPayment:
-		if _, ok := body.(OperationBodyPayment); !ok {
-			err = sebakerror.ErrorTypeOperationBodyNotMatched
-			return
-		}
-	default:
-		err = sebakerror.ErrorUnknownOperationType
+	var kind OperationKind
+	if kind, err = GetOperationKind(t); err != nil {
+		return
+	}
+	if !kind.IsBody(body) {
+		err = sebakerror.ErrorTypeOperationBodyNotMatched
 		return
 	}
 
@@ -132,23 +140,35 @@ type OperationHeader struct {
 }
 
 type OperationBody interface {
+	sebakcommon.CanonicalEncodable
+
 	Validate(sebakstorage.LevelDBBackend) error
 	IsWellFormed([]byte) error
 	TargetAddress() string
 	GetAmount() Amount
 }
 
-// FinishOperation do finish the task after consensus by the type of each operation.
+// FinishOperation do finish the task after consensus by the registered
+// `OperationKind` of each operation.
 func FinishOperation(st *sebakstorage.LevelDBBackend, tx Transaction, op Operation) (err error) {
-	switch op.H.Type {
-	case OperationCreateAccount:
-		return FinishOperationCreateAccount(st, tx, op)
-	case OperationPayment:
-		return FinishOperationPayment(st, tx, op)
-	default:
-		err = sebakerror.ErrorUnknownOperationType
+	var kind OperationKind
+	if kind, err = GetOperationKind(op.H.Type); err != nil {
 		return
 	}
 
-	return
+	return kind.Apply(st, tx, op)
+}
+
+// ValidateOperation checks the operation against the state by the registered
+// `OperationKind`; the operation without `Validate` is always valid.
+func ValidateOperation(st *sebakstorage.LevelDBBackend, tx Transaction, op Operation) (err error) {
+	var kind OperationKind
+	if kind, err = GetOperationKind(op.H.Type); err != nil {
+		return
+	}
+	if kind.Validate == nil {
+		return
+	}
+
+	return kind.Validate(st, tx, op)
 }
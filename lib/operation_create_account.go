@@ -5,10 +5,14 @@ import (
 
 	"github.com/stellar/go/keypair"
 
+	"boscoin.io/sebak/lib/common"
 	"boscoin.io/sebak/lib/error"
 	"boscoin.io/sebak/lib/storage"
 )
 
+// OperationBodyCreateAccount creates the new account of `Target` with
+// `Amount`, which must not be lower than the `MinimumBalance` of network; the
+// other operations never create the account.
 type OperationBodyCreateAccount struct {
 	Target string `json:"target"`
 	Amount Amount `json:"amount"`
@@ -21,6 +25,11 @@ func NewOperationBodyCreateAccount(target string, amount Amount) OperationBodyCr
 	}
 }
 
+func (o OperationBodyCreateAccount) EncodeCanonical(e *sebakcommon.CanonicalEncoder) {
+	e.String(o.Target)
+	e.Uint64(uint64(o.Amount))
+}
+
 func (o OperationBodyCreateAccount) IsWellFormed([]byte) (err error) {
 	if _, err = keypair.Parse(o.Target); err != nil {
 		return
@@ -48,12 +57,37 @@ func (o OperationBodyCreateAccount) GetAmount() Amount {
 	return o.Amount
 }
 
+// CheckCreateAccountMinimumBalance checks every `OperationCreateAccount` of
+// the transaction funds the new account with the minimum balance of network.
+func CheckCreateAccountMinimumBalance(p NetworkParameters, tx Transaction) (err error) {
+	for _, op := range tx.B.Operations {
+		if op.H.Type != OperationCreateAccount {
+			continue
+		}
+		if op.B.GetAmount() < p.MinimumBalance {
+			err = sebakerror.ErrorCreateAccountUnderfunded
+			return
+		}
+	}
+
+	return
+}
+
 func FinishOperationCreateAccount(st *sebakstorage.LevelDBBackend, tx Transaction, op Operation) (err error) {
 	var baSource, baTarget *BlockAccount
 	if baSource, err = GetBlockAccount(st, tx.B.Source); err != nil {
 		err = sebakerror.ErrorBlockAccountDoesNotExists
 		return
 	}
+
+	var p NetworkParameters
+	if p, err = GetNetworkParameters(st); err != nil {
+		return
+	}
+	if op.B.GetAmount() < p.MinimumBalance {
+		err = sebakerror.ErrorCreateAccountUnderfunded
+		return
+	}
 	if baTarget, err = GetBlockAccount(st, op.B.TargetAddress()); err == nil {
 		err = sebakerror.ErrorBlockAccountAlreadyExists
 		return
@@ -74,3 +108,46 @@ func FinishOperationCreateAccount(st *sebakstorage.LevelDBBackend, tx Transactio
 
 	return
 }
+
+func newOperationBodyCreateAccountFromInterface(body map[string]interface{}) (o OperationBodyCreateAccount, err error) {
+	if o.Amount, err = AmountFromString(fmt.Sprintf("%v", body["amount"])); err != nil {
+		return
+	}
+
+	var ok bool
+	if o.Target, ok = body["target"].(string); !ok {
+		err = sebakerror.ErrorInvalidOperation
+		return
+	}
+
+	return
+}
+
+// validateOperationCreateAccount checks the target account does not exist.
+func validateOperationCreateAccount(st *sebakstorage.LevelDBBackend, tx Transaction, op Operation) (err error) {
+	var exists bool
+	if exists, err = ExistBlockAccount(st, op.B.TargetAddress()); err != nil {
+		return
+	} else if exists {
+		err = sebakerror.ErrorBlockAccountAlreadyExists
+		return
+	}
+
+	return
+}
+
+func init() {
+	RegisterOperationKind(OperationKind{
+		Type: OperationCreateAccount,
+		NewBody: func(body map[string]interface{}) (OperationBody, error) {
+			return newOperationBodyCreateAccountFromInterface(body)
+		},
+		IsBody: func(body OperationBody) bool {
+			_, ok := body.(OperationBodyCreateAccount)
+			return ok
+		},
+		Validate:     validateOperationCreateAccount,
+		Apply:        FinishOperationCreateAccount,
+		LedgerReason: LedgerReasonCreateAccount,
+	})
+}
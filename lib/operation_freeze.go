@@ -0,0 +1,126 @@
+package sebak
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"boscoin.io/sebak/lib/common"
+	"boscoin.io/sebak/lib/error"
+	"boscoin.io/sebak/lib/storage"
+)
+
+// OperationBodyFreeze freezes `Amount` of the balance of source account for
+// `Blocks` blocks; the frozen balance can not be spent until it is released
+// by `OperationUnfreeze`.
+type OperationBodyFreeze struct {
+	Amount Amount `json:"amount"`
+	Blocks uint64 `json:"blocks"`
+}
+
+func NewOperationBodyFreeze(amount Amount, blocks uint64) OperationBodyFreeze {
+	return OperationBodyFreeze{
+		Amount: amount,
+		Blocks: blocks,
+	}
+}
+
+func (o OperationBodyFreeze) EncodeCanonical(e *sebakcommon.CanonicalEncoder) {
+	e.Uint64(uint64(o.Amount))
+	e.Uint64(o.Blocks)
+}
+
+func (o OperationBodyFreeze) Serialize() (encoded []byte, err error) {
+	encoded, err = json.Marshal(o)
+	return
+}
+
+func (o OperationBodyFreeze) IsWellFormed([]byte) (err error) {
+	if int64(o.Amount) < 1 {
+		err = fmt.Errorf("invalid `Amount`")
+		return
+	}
+	if o.Blocks < 1 {
+		err = fmt.Errorf("invalid `Blocks`: must be greater than 0")
+		return
+	}
+
+	return
+}
+
+func (o OperationBodyFreeze) Validate(st sebakstorage.LevelDBBackend) (err error) {
+	return
+}
+
+// TargetAddress returns empty string; the frozen balance belongs to the
+// source account.
+func (o OperationBodyFreeze) TargetAddress() string {
+	return ""
+}
+
+// GetAmount returns 0; the frozen balance is not withdrawn from the source
+// account.
+func (o OperationBodyFreeze) GetAmount() Amount {
+	return Amount(0)
+}
+
+func FinishOperationFreeze(st *sebakstorage.LevelDBBackend, tx Transaction, op Operation) (err error) {
+	var exists bool
+	if exists, err = ExistBlockAccount(st, tx.B.Source); err != nil {
+		return
+	} else if !exists {
+		err = sebakerror.ErrorBlockAccountDoesNotExists
+		return
+	}
+
+	var height uint64
+	if height, err = getNextBlockHeight(st); err != nil {
+		return
+	}
+
+	var fb FrozenBalances
+	if fb, _, err = GetFrozenBalances(st, tx.B.Source); err != nil {
+		return
+	}
+	fb.Address = tx.B.Source
+
+	body := op.B.(OperationBodyFreeze)
+	if err = fb.Freeze(body.Amount, height, body.Blocks); err != nil {
+		return
+	}
+	if err = fb.Save(st); err != nil {
+		return
+	}
+
+	log.Debug("balance frozen", "source", tx.B.Source, "amount", body.Amount, "unlock-height", height+body.Blocks)
+
+	return
+}
+
+func newOperationBodyFreezeFromInterface(body map[string]interface{}) (o OperationBodyFreeze, err error) {
+	if o.Amount, err = AmountFromString(fmt.Sprintf("%v", body["amount"])); err != nil {
+		return
+	}
+
+	blocks, ok := body["blocks"].(float64)
+	if !ok || blocks < 0 || blocks != float64(uint64(blocks)) {
+		err = sebakerror.ErrorInvalidOperation
+		return
+	}
+	o.Blocks = uint64(blocks)
+
+	return
+}
+
+func init() {
+	RegisterOperationKind(OperationKind{
+		Type: OperationFreeze,
+		NewBody: func(body map[string]interface{}) (OperationBody, error) {
+			return newOperationBodyFreezeFromInterface(body)
+		},
+		IsBody: func(body OperationBody) bool {
+			_, ok := body.(OperationBodyFreeze)
+			return ok
+		},
+		Apply: FinishOperationFreeze,
+	})
+}
@@ -0,0 +1,181 @@
+package sebak
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"unicode"
+
+	"boscoin.io/sebak/lib/common"
+	"boscoin.io/sebak/lib/error"
+	"boscoin.io/sebak/lib/storage"
+)
+
+const (
+	MaxDataEntryNameLength  int = 64
+	MaxDataEntryValueLength int = 64
+
+	// MaxAccountDataEntries is the maximum number of data entries of one
+	// account.
+	MaxAccountDataEntries int = 100
+)
+
+// OperationBodyManageData sets the data entry, `Name` of source account. If
+// `Value` is empty, the entry will be removed.
+type OperationBodyManageData struct {
+	Name  string `json:"name"`
+	Value []byte `json:"value"` // base64 encoded in JSON
+}
+
+func NewOperationBodyManageData(name string, value []byte) OperationBodyManageData {
+	return OperationBodyManageData{
+		Name:  name,
+		Value: value,
+	}
+}
+
+func (o OperationBodyManageData) EncodeCanonical(e *sebakcommon.CanonicalEncoder) {
+	e.String(o.Name)
+	e.Bytes(o.Value)
+}
+
+func (o OperationBodyManageData) Serialize() (encoded []byte, err error) {
+	encoded, err = json.Marshal(o)
+	return
+}
+
+func (o OperationBodyManageData) IsWellFormed([]byte) (err error) {
+	if len(o.Name) < 1 || len(o.Name) > MaxDataEntryNameLength {
+		err = fmt.Errorf("invalid `Name`: length must be between 1 and %d", MaxDataEntryNameLength)
+		return
+	}
+	for _, r := range o.Name {
+		if !unicode.IsPrint(r) {
+			err = fmt.Errorf("invalid `Name`: not printable character found")
+			return
+		}
+	}
+
+	if len(o.Value) > MaxDataEntryValueLength {
+		err = fmt.Errorf("invalid `Value`: longer than %d", MaxDataEntryValueLength)
+		return
+	}
+
+	return
+}
+
+func (o OperationBodyManageData) Validate(st sebakstorage.LevelDBBackend) (err error) {
+	return
+}
+
+// TargetAddress returns empty string; the data entry belongs to the source
+// account.
+func (o OperationBodyManageData) TargetAddress() string {
+	return ""
+}
+
+func (o OperationBodyManageData) GetAmount() Amount {
+	return Amount(0)
+}
+
+// CheckManageData checks the new data entry does not exceed
+// `MaxAccountDataEntries` of account; the existing entry can be always
+// changed or removed.
+func CheckManageData(st *sebakstorage.LevelDBBackend, address string, body OperationBodyManageData) (err error) {
+	if len(body.Value) < 1 {
+		return
+	}
+
+	var exists bool
+	if exists, err = st.Has(GetBlockAccountDataKey(address, body.Name)); err != nil || exists {
+		return
+	}
+
+	var n int
+	if n, err = CountBlockAccountData(st, address); err != nil {
+		return
+	}
+	if n >= MaxAccountDataEntries {
+		err = sebakerror.ErrorTooManyAccountDataEntries
+		return
+	}
+
+	return
+}
+
+func FinishOperationManageData(st *sebakstorage.LevelDBBackend, tx Transaction, op Operation) (err error) {
+	var exists bool
+	if exists, err = ExistBlockAccount(st, tx.B.Source); err != nil {
+		return
+	} else if !exists {
+		err = sebakerror.ErrorBlockAccountDoesNotExists
+		return
+	}
+
+	body := op.B.(OperationBodyManageData)
+	if err = CheckManageData(st, tx.B.Source, body); err != nil {
+		return
+	}
+
+	if len(body.Value) < 1 {
+		if exists, err = st.Has(GetBlockAccountDataKey(tx.B.Source, body.Name)); err != nil || !exists {
+			return
+		}
+		err = RemoveBlockAccountData(st, tx.B.Source, body.Name)
+	} else {
+		// the entry has the time of block, which is saved with the
+		// transaction before the operations
+		var bt BlockTransaction
+		if bt, err = GetBlockTransaction(st, tx.GetHash()); err != nil {
+			return
+		}
+		err = NewBlockAccountData(tx.B.Source, body.Name, body.Value, bt.Confirmed).Save(st)
+	}
+	if err != nil {
+		return
+	}
+
+	log.Debug("manage data done", "source", tx.B.Source, "name", body.Name)
+
+	return
+}
+
+func newOperationBodyManageDataFromInterface(body map[string]interface{}) (o OperationBodyManageData, err error) {
+	name, ok := body["name"].(string)
+	if !ok {
+		err = sebakerror.ErrorInvalidOperation
+		return
+	}
+
+	var value []byte
+	if v, found := body["value"]; found && v != nil {
+		var s string
+		if s, ok = v.(string); !ok {
+			err = sebakerror.ErrorInvalidOperation
+			return
+		}
+		if value, err = base64.StdEncoding.DecodeString(s); err != nil {
+			return
+		}
+	}
+	o = NewOperationBodyManageData(name, value)
+
+	return
+}
+
+func init() {
+	RegisterOperationKind(OperationKind{
+		Type: OperationManageData,
+		NewBody: func(body map[string]interface{}) (OperationBody, error) {
+			return newOperationBodyManageDataFromInterface(body)
+		},
+		IsBody: func(body OperationBody) bool {
+			_, ok := body.(OperationBodyManageData)
+			return ok
+		},
+		Validate: func(st *sebakstorage.LevelDBBackend, tx Transaction, op Operation) error {
+			return CheckManageData(st, tx.B.Source, op.B.(OperationBodyManageData))
+		},
+		Apply: FinishOperationManageData,
+	})
+}
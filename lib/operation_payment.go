@@ -3,16 +3,22 @@ package sebak
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 
+	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/stellar/go/keypair"
 
+	"boscoin.io/sebak/lib/common"
 	"boscoin.io/sebak/lib/error"
 	"boscoin.io/sebak/lib/storage"
 )
 
+// OperationBodyPayment sends `Amount` to `Target`; the optional `Memo` is
+// for the receiver.
 type OperationBodyPayment struct {
 	Target string `json:"target"`
 	Amount Amount `json:"amount"`
+	Memo   *Memo  `json:"memo,omitempty"`
 }
 
 func NewOperationBodyPayment(target string, amount Amount) OperationBodyPayment {
@@ -22,6 +28,41 @@ func NewOperationBodyPayment(target string, amount Amount) OperationBodyPayment
 	}
 }
 
+func NewOperationBodyPaymentWithMemo(target string, amount Amount, memo Memo) OperationBodyPayment {
+	return OperationBodyPayment{
+		Target: target,
+		Amount: amount,
+		Memo:   &memo,
+	}
+}
+
+// EncodeRLP encodes the payment without memo like before the memo, so the
+// hashes of the existing payments are not changed.
+func (o OperationBodyPayment) EncodeRLP(w io.Writer) error {
+	if o.Memo == nil {
+		return rlp.Encode(w, struct {
+			Target string
+			Amount Amount
+		}{o.Target, o.Amount})
+	}
+
+	return rlp.Encode(w, struct {
+		Target string
+		Amount Amount
+		Memo   Memo
+	}{o.Target, o.Amount, *o.Memo})
+}
+
+func (o OperationBodyPayment) EncodeCanonical(e *sebakcommon.CanonicalEncoder) {
+	e.String(o.Target)
+	e.Uint64(uint64(o.Amount))
+	e.Bool(o.Memo != nil)
+	if o.Memo != nil {
+		e.String(string(o.Memo.Type))
+		e.String(o.Memo.Value)
+	}
+}
+
 func (o OperationBodyPayment) Serialize() (encoded []byte, err error) {
 	encoded, err = json.Marshal(o)
 	return
@@ -37,6 +78,12 @@ func (o OperationBodyPayment) IsWellFormed([]byte) (err error) {
 		return
 	}
 
+	if o.Memo != nil {
+		if err = o.Memo.IsWellFormed(); err != nil {
+			return
+		}
+	}
+
 	return
 }
 
@@ -72,7 +119,62 @@ func FinishOperationPayment(st *sebakstorage.LevelDBBackend, tx Transaction, op
 		return
 	}
 
-	log.Debug("payment done", "source", baSource, "target", baTarget, "amount", op.B.GetAmount())
+	log.Debug("payment done", "source", baSource, "target", baTarget, "amount", op.B.GetAmount(), "memo", op.B.(OperationBodyPayment).Memo)
 
 	return
 }
+
+func newOperationBodyPaymentFromInterface(body map[string]interface{}) (o OperationBodyPayment, err error) {
+	if o.Amount, err = AmountFromString(fmt.Sprintf("%v", body["amount"])); err != nil {
+		return
+	}
+
+	var ok bool
+	if o.Target, ok = body["target"].(string); !ok {
+		err = sebakerror.ErrorInvalidOperation
+		return
+	}
+	if o.Memo, err = newMemoFromInterface(body["memo"]); err != nil {
+		return
+	}
+
+	return
+}
+
+// validateOperationPayment checks the target account exists and it's balance
+// does not overflow.
+func validateOperationPayment(st *sebakstorage.LevelDBBackend, tx Transaction, op Operation) (err error) {
+	var exists bool
+	if exists, err = ExistBlockAccount(st, op.B.TargetAddress()); err != nil {
+		return
+	} else if !exists {
+		err = sebakerror.ErrorBlockAccountDoesNotExists
+		return
+	}
+
+	var ba *BlockAccount
+	if ba, err = GetBlockAccount(st, op.B.TargetAddress()); err != nil {
+		return
+	}
+	if _, err = ba.GetBalance().Add(op.B.GetAmount()); err != nil {
+		return
+	}
+
+	return
+}
+
+func init() {
+	RegisterOperationKind(OperationKind{
+		Type: OperationPayment,
+		NewBody: func(body map[string]interface{}) (OperationBody, error) {
+			return newOperationBodyPaymentFromInterface(body)
+		},
+		IsBody: func(body OperationBody) bool {
+			_, ok := body.(OperationBodyPayment)
+			return ok
+		},
+		Validate:     validateOperationPayment,
+		Apply:        FinishOperationPayment,
+		LedgerReason: LedgerReasonPayment,
+	})
+}
@@ -0,0 +1,94 @@
+package sebak
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"boscoin.io/sebak/lib/error"
+	"boscoin.io/sebak/lib/storage"
+)
+
+// OperationKind is how the operation of `Type` is decoded, checked and
+// applied. Every operation type registers it's kind by
+// `RegisterOperationKind()` in the file of the operation, so the new
+// operation type is added without changing the decoding of transaction, the
+// checks of node and the applying of block,
+//  * `NewBody`: makes the body from the JSON object of body
+//  * `IsBody`: checks the body is of the operation type
+//  * `IsWellFormed`: checks the operation without the state; by default, the
+//  `IsWellFormed()` of body
+//  * `Validate`: checks the operation against the state before it is
+//  accepted, like the target of payment exists; it is optional
+//  * `Apply`: applies the operation to the state, when the block is made
+//  * `LedgerReason`: the reason of the ledger entry of the amount moved from
+//  the source to the target; the operation without it moves no balance
+type OperationKind struct {
+	Type         OperationType
+	NewBody      func(body map[string]interface{}) (OperationBody, error)
+	IsBody       func(body OperationBody) bool
+	IsWellFormed func(networkID []byte, op Operation) error
+	Validate     func(st *sebakstorage.LevelDBBackend, tx Transaction, op Operation) error
+	Apply        func(st *sebakstorage.LevelDBBackend, tx Transaction, op Operation) error
+	LedgerReason LedgerReason
+}
+
+var (
+	operationKindsLock sync.RWMutex
+	operationKinds     = map[OperationType]OperationKind{}
+)
+
+// RegisterOperationKind registers the kind of operation type; it panics, if
+// the kind is not complete or the type is already registered, because it is
+// the mistake of the code.
+func RegisterOperationKind(kind OperationKind) {
+	if len(kind.Type) < 1 || kind.NewBody == nil || kind.IsBody == nil || kind.Apply == nil {
+		panic(fmt.Errorf("operation kind of '%s' must have the type, `NewBody`, `IsBody` and `Apply`", kind.Type))
+	}
+	if kind.IsWellFormed == nil {
+		kind.IsWellFormed = func(networkID []byte, op Operation) error {
+			return op.B.IsWellFormed(networkID)
+		}
+	}
+
+	operationKindsLock.Lock()
+	defer operationKindsLock.Unlock()
+
+	if _, found := operationKinds[kind.Type]; found {
+		panic(fmt.Errorf("operation kind of '%s' is already registered", kind.Type))
+	}
+	operationKinds[kind.Type] = kind
+}
+
+// GetOperationKind returns the registered kind of operation type; the unknown
+// type is `ErrorUnknownOperationType`.
+func GetOperationKind(t OperationType) (kind OperationKind, err error) {
+	operationKindsLock.RLock()
+	defer operationKindsLock.RUnlock()
+
+	var found bool
+	if kind, found = operationKinds[t]; !found {
+		err = sebakerror.ErrorUnknownOperationType
+		return
+	}
+
+	return
+}
+
+func IsKnownOperationType(t OperationType) bool {
+	_, err := GetOperationKind(t)
+	return err == nil
+}
+
+// OperationTypes returns the registered operation types in order.
+func OperationTypes() (types []OperationType) {
+	operationKindsLock.RLock()
+	defer operationKindsLock.RUnlock()
+
+	for t := range operationKinds {
+		types = append(types, t)
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+
+	return
+}
@@ -0,0 +1,116 @@
+package sebak
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stellar/go/keypair"
+
+	"boscoin.io/sebak/lib/error"
+	"boscoin.io/sebak/lib/storage"
+)
+
+func TestOperationKinds(t *testing.T) {
+	for _, opType := range []OperationType{
+		OperationCreateAccount,
+		OperationPayment,
+		OperationManageData,
+		OperationSetSpendingLimit,
+		OperationSetSigners,
+		OperationFreeze,
+		OperationUnfreeze,
+	} {
+		if !IsKnownOperationType(opType) {
+			t.Errorf("operation type, '%s' must be registered", opType)
+			return
+		}
+	}
+	if IsKnownOperationType("unknown") {
+		t.Error("unknown operation type must not be registered")
+		return
+	}
+
+	// the body of the other type is refused
+	kp, _ := keypair.Random()
+	op := Operation{H: OperationHeader{Type: OperationCreateAccount}, B: NewOperationBodyPayment(kp.Address(), Amount(1))}
+	if err := op.IsWellFormed(networkID); err != sebakerror.ErrorTypeOperationBodyNotMatched {
+		t.Errorf("body of the other type must be refused: %v", err)
+		return
+	}
+	if _, err := NewOperation(OperationCreateAccount, NewOperationBodyPayment(kp.Address(), Amount(1))); err != sebakerror.ErrorTypeOperationBodyNotMatched {
+		t.Errorf("body of the other type must be refused: %v", err)
+		return
+	}
+}
+
+func TestRegisterOperationKind(t *testing.T) {
+	const opType OperationType = "test-register"
+	defer delete(operationKinds, opType)
+
+	var applied int
+	invalid := errors.New("invalid")
+	RegisterOperationKind(OperationKind{
+		Type: opType,
+		NewBody: func(body map[string]interface{}) (OperationBody, error) {
+			return newOperationBodyUnfreezeFromInterface(body)
+		},
+		IsBody: func(body OperationBody) bool {
+			_, ok := body.(OperationBodyUnfreeze)
+			return ok
+		},
+		Validate: func(st *sebakstorage.LevelDBBackend, tx Transaction, op Operation) error {
+			if op.B.(OperationBodyUnfreeze).Amount > BaseFee {
+				return invalid
+			}
+			return nil
+		},
+		Apply: func(st *sebakstorage.LevelDBBackend, tx Transaction, op Operation) error {
+			applied++
+			return nil
+		},
+	})
+
+	op, err := NewOperationFromInterface(OperationFromJSON{H: OperationHeader{Type: opType}, B: map[string]interface{}{"amount": "2"}})
+	if err != nil || op.B.(OperationBodyUnfreeze).Amount != Amount(2) {
+		t.Errorf("failed to decode the operation of the registered type: %v", err)
+		return
+	}
+	// the default `IsWellFormed` is of the body
+	if err = op.IsWellFormed(networkID); err != nil {
+		t.Error(err)
+		return
+	}
+
+	st, _ := sebakstorage.NewTestMemoryLevelDBBackend()
+	defer st.Close()
+
+	if err = ValidateOperation(st, Transaction{}, op); err != nil {
+		t.Error(err)
+		return
+	}
+	if err = FinishOperation(st, Transaction{}, op); err != nil || applied != 1 {
+		t.Errorf("operation must be applied by the registered kind: %v", err)
+		return
+	}
+
+	op.B = NewOperationBodyUnfreeze(BaseFee + 1)
+	if err = ValidateOperation(st, Transaction{}, op); err != invalid {
+		t.Errorf("operation must be validated by the registered kind: %v", err)
+		return
+	}
+
+	// no ledger entry without `LedgerReason`
+	if entries := NewLedgerEntriesFromTransaction(Transaction{B: TransactionBody{Operations: []Operation{op}}}, ""); len(entries) != 0 {
+		t.Errorf("wrong ledger entries: %v", entries)
+		return
+	}
+
+	// the same type can not be registered again
+	kind, _ := GetOperationKind(opType)
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("same operation type must not be registered again")
+		}
+	}()
+	RegisterOperationKind(kind)
+}
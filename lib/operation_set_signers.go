@@ -0,0 +1,220 @@
+package sebak
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/stellar/go/keypair"
+
+	"boscoin.io/sebak/lib/common"
+	"boscoin.io/sebak/lib/error"
+	"boscoin.io/sebak/lib/storage"
+)
+
+// OperationBodySetSigners sets the `AccountSigners` of source account. If the
+// body is empty, the signers will be removed. When the source account already
+// has the signers, the transaction must reach it's threshold like the other
+// transactions of the account.
+type OperationBodySetSigners struct {
+	MasterWeight int             `json:"master_weight"` // weight of source
+	Signers      []AccountSigner `json:"signers"`
+	Threshold    int             `json:"threshold"`
+}
+
+func NewOperationBodySetSigners(masterWeight, threshold int, signers ...AccountSigner) OperationBodySetSigners {
+	return OperationBodySetSigners{
+		MasterWeight: masterWeight,
+		Signers:      signers,
+		Threshold:    threshold,
+	}
+}
+
+func (o OperationBodySetSigners) EncodeCanonical(e *sebakcommon.CanonicalEncoder) {
+	e.Int64(int64(o.MasterWeight))
+	e.Len(len(o.Signers))
+	for _, signer := range o.Signers {
+		e.String(signer.Address)
+		e.Int64(int64(signer.Weight))
+	}
+	e.Int64(int64(o.Threshold))
+}
+
+func (o OperationBodySetSigners) Serialize() (encoded []byte, err error) {
+	encoded, err = json.Marshal(o)
+	return
+}
+
+func (o OperationBodySetSigners) IsEmpty() bool {
+	return o.MasterWeight == 0 && len(o.Signers) < 1 && o.Threshold == 0
+}
+
+// TotalWeight returns the sum of weights of source and signers.
+func (o OperationBodySetSigners) TotalWeight() (weight int) {
+	weight = o.MasterWeight
+	for _, signer := range o.Signers {
+		weight += signer.Weight
+	}
+
+	return
+}
+
+func (o OperationBodySetSigners) IsWellFormed([]byte) (err error) {
+	if o.IsEmpty() {
+		return
+	}
+
+	if o.MasterWeight < 0 || o.MasterWeight > MaxAccountSignerWeight {
+		err = fmt.Errorf("invalid `MasterWeight`: must be between 0 and %d", MaxAccountSignerWeight)
+		return
+	}
+	if len(o.Signers) < 1 || len(o.Signers) > MaxAccountSigners {
+		err = fmt.Errorf("invalid `Signers`: number must be between 1 and %d", MaxAccountSigners)
+		return
+	}
+
+	var signers []string
+	for _, signer := range o.Signers {
+		if _, err = keypair.Parse(signer.Address); err != nil {
+			err = sebakerror.ErrorBadPublicAddress
+			return
+		}
+		if _, found := sebakcommon.InStringArray(signers, signer.Address); found {
+			err = fmt.Errorf("invalid `Signers`: duplicated signer found")
+			return
+		}
+		if signer.Weight < 1 || signer.Weight > MaxAccountSignerWeight {
+			err = fmt.Errorf("invalid `Signers`: weight must be between 1 and %d", MaxAccountSignerWeight)
+			return
+		}
+		signers = append(signers, signer.Address)
+	}
+
+	if o.Threshold < 1 || o.Threshold > o.TotalWeight() {
+		err = fmt.Errorf("invalid `Threshold`: must be between 1 and the sum of weights")
+		return
+	}
+
+	return
+}
+
+func (o OperationBodySetSigners) Validate(st sebakstorage.LevelDBBackend) (err error) {
+	return
+}
+
+// TargetAddress returns empty string; the signers belong to the source
+// account.
+func (o OperationBodySetSigners) TargetAddress() string {
+	return ""
+}
+
+func (o OperationBodySetSigners) GetAmount() Amount {
+	return Amount(0)
+}
+
+func FinishOperationSetSigners(st *sebakstorage.LevelDBBackend, tx Transaction, op Operation) (err error) {
+	var exists bool
+	if exists, err = ExistBlockAccount(st, tx.B.Source); err != nil {
+		return
+	} else if !exists {
+		err = sebakerror.ErrorBlockAccountDoesNotExists
+		return
+	}
+
+	body := op.B.(OperationBodySetSigners)
+	for _, signer := range body.Signers {
+		// the source can not co-sign it's own transaction, so it's weight can
+		// never be signed
+		if signer.Address == tx.B.Source {
+			err = sebakerror.ErrorInvalidOperation
+			return
+		}
+	}
+
+	if body.IsEmpty() {
+		var found bool
+		if _, found, err = GetAccountSigners(st, tx.B.Source); err != nil || !found {
+			return
+		}
+		// the limit needs the signers to be co-signed
+		if _, found, err = GetSpendingLimit(st, tx.B.Source); err != nil {
+			return
+		} else if found {
+			err = sebakerror.ErrorSpendingLimitRequiresSigners
+			return
+		}
+		err = RemoveAccountSigners(st, tx.B.Source)
+	} else {
+		err = NewAccountSigners(tx.B.Source, body).Save(st)
+	}
+	if err != nil {
+		return
+	}
+
+	log.Debug("account signers set", "source", tx.B.Source, "signers", body)
+
+	return
+}
+
+func newOperationBodySetSignersFromInterface(body map[string]interface{}) (o OperationBodySetSigners, err error) {
+	parseInt := func(v interface{}) (i int, err error) {
+		f, ok := v.(float64)
+		if !ok || f != float64(int(f)) {
+			err = sebakerror.ErrorInvalidOperation
+			return
+		}
+		i = int(f)
+		return
+	}
+
+	if v, found := body["master_weight"]; found && v != nil {
+		if o.MasterWeight, err = parseInt(v); err != nil {
+			return
+		}
+	}
+
+	if v, found := body["signers"]; found && v != nil {
+		signers, ok := v.([]interface{})
+		if !ok {
+			err = sebakerror.ErrorInvalidOperation
+			return
+		}
+		for _, s := range signers {
+			m, ok := s.(map[string]interface{})
+			if !ok {
+				err = sebakerror.ErrorInvalidOperation
+				return
+			}
+			var signer AccountSigner
+			if signer.Address, ok = m["address"].(string); !ok {
+				err = sebakerror.ErrorInvalidOperation
+				return
+			}
+			if signer.Weight, err = parseInt(m["weight"]); err != nil {
+				return
+			}
+			o.Signers = append(o.Signers, signer)
+		}
+	}
+
+	if v, found := body["threshold"]; found && v != nil {
+		if o.Threshold, err = parseInt(v); err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+func init() {
+	RegisterOperationKind(OperationKind{
+		Type: OperationSetSigners,
+		NewBody: func(body map[string]interface{}) (OperationBody, error) {
+			return newOperationBodySetSignersFromInterface(body)
+		},
+		IsBody: func(body OperationBody) bool {
+			_, ok := body.(OperationBodySetSigners)
+			return ok
+		},
+		Apply: FinishOperationSetSigners,
+	})
+}
@@ -0,0 +1,126 @@
+package sebak
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"boscoin.io/sebak/lib/common"
+	"boscoin.io/sebak/lib/error"
+	"boscoin.io/sebak/lib/storage"
+)
+
+// OperationBodySetSpendingLimit sets the `SpendingLimit` of source account.
+// If the body is empty, the limit will be removed. The source account must
+// have the `AccountSigners`, which co-sign the transactions over the limit;
+// when the source account already has the limit, the transaction must be
+// co-signed by them.
+type OperationBodySetSpendingLimit struct {
+	PerTransaction Amount `json:"per_transaction"` // 0 is unlimited
+	Daily          Amount `json:"daily"`           // 0 is unlimited
+}
+
+func NewOperationBodySetSpendingLimit(perTransaction, daily Amount) OperationBodySetSpendingLimit {
+	return OperationBodySetSpendingLimit{
+		PerTransaction: perTransaction,
+		Daily:          daily,
+	}
+}
+
+func (o OperationBodySetSpendingLimit) EncodeCanonical(e *sebakcommon.CanonicalEncoder) {
+	e.Uint64(uint64(o.PerTransaction))
+	e.Uint64(uint64(o.Daily))
+}
+
+func (o OperationBodySetSpendingLimit) Serialize() (encoded []byte, err error) {
+	encoded, err = json.Marshal(o)
+	return
+}
+
+func (o OperationBodySetSpendingLimit) IsEmpty() bool {
+	return o.PerTransaction == 0 && o.Daily == 0
+}
+
+// IsWellFormed always passes; the empty body removes the limit, and the other
+// has `PerTransaction` or `Daily`.
+func (o OperationBodySetSpendingLimit) IsWellFormed([]byte) (err error) {
+	return
+}
+
+func (o OperationBodySetSpendingLimit) Validate(st sebakstorage.LevelDBBackend) (err error) {
+	return
+}
+
+// TargetAddress returns empty string; the limit belongs to the source
+// account.
+func (o OperationBodySetSpendingLimit) TargetAddress() string {
+	return ""
+}
+
+func (o OperationBodySetSpendingLimit) GetAmount() Amount {
+	return Amount(0)
+}
+
+func FinishOperationSetSpendingLimit(st *sebakstorage.LevelDBBackend, tx Transaction, op Operation) (err error) {
+	var exists bool
+	if exists, err = ExistBlockAccount(st, tx.B.Source); err != nil {
+		return
+	} else if !exists {
+		err = sebakerror.ErrorBlockAccountDoesNotExists
+		return
+	}
+
+	body := op.B.(OperationBodySetSpendingLimit)
+	if body.IsEmpty() {
+		var found bool
+		if _, found, err = GetSpendingLimit(st, tx.B.Source); err != nil || !found {
+			return
+		}
+		err = RemoveSpendingLimit(st, tx.B.Source)
+	} else {
+		// without the signers, the transactions over the limit and the limit
+		// itself can never be co-signed
+		var found bool
+		if _, found, err = GetAccountSigners(st, tx.B.Source); err != nil {
+			return
+		} else if !found {
+			err = sebakerror.ErrorSpendingLimitRequiresSigners
+			return
+		}
+		err = NewSpendingLimit(tx.B.Source, body).Save(st)
+	}
+	if err != nil {
+		return
+	}
+
+	log.Debug("spending limit set", "source", tx.B.Source, "limit", body)
+
+	return
+}
+
+func newOperationBodySetSpendingLimitFromInterface(body map[string]interface{}) (o OperationBodySetSpendingLimit, err error) {
+	for name, amount := range map[string]*Amount{"per_transaction": &o.PerTransaction, "daily": &o.Daily} {
+		v, found := body[name]
+		if !found || v == nil {
+			continue
+		}
+		if *amount, err = AmountFromString(fmt.Sprintf("%v", v)); err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+func init() {
+	RegisterOperationKind(OperationKind{
+		Type: OperationSetSpendingLimit,
+		NewBody: func(body map[string]interface{}) (OperationBody, error) {
+			return newOperationBodySetSpendingLimitFromInterface(body)
+		},
+		IsBody: func(body OperationBody) bool {
+			_, ok := body.(OperationBodySetSpendingLimit)
+			return ok
+		},
+		Apply: FinishOperationSetSpendingLimit,
+	})
+}
@@ -4,6 +4,8 @@ import (
 	"testing"
 
 	"github.com/stellar/go/keypair"
+
+	"boscoin.io/sebak/lib/error"
 )
 
 func TestMakeHashOfOperationBodyPayment(t *testing.T) {
@@ -53,3 +55,58 @@ func TestSerializeOperation(t *testing.T) {
 		t.Errorf("failed to unserialize operation data: %v", err)
 	}
 }
+
+func TestIsWellFormedOperationBodyManageData(t *testing.T) {
+	if err := NewOperationBodyManageData("anchor", []byte("value")).IsWellFormed(networkID); err != nil {
+		t.Error(err)
+		return
+	}
+	if err := NewOperationBodyManageData("", []byte("value")).IsWellFormed(networkID); err == nil {
+		t.Error("empty `Name` must occur error")
+		return
+	}
+
+	long := make([]byte, MaxDataEntryValueLength+1)
+	if err := NewOperationBodyManageData("anchor", long).IsWellFormed(networkID); err == nil {
+		t.Error("too long `Value` must occur error")
+		return
+	}
+}
+
+func TestSerializeOperationManageData(t *testing.T) {
+	op := Operation{
+		H: OperationHeader{Type: OperationManageData},
+		B: NewOperationBodyManageData("anchor", []byte{0x00, 0xff}),
+	}
+
+	b, err := op.Serialize()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	var unserialized Operation
+	if unserialized, err = NewOperationFromBytes(b); err != nil {
+		t.Errorf("failed to unserialize operation data: %v", err)
+		return
+	}
+	body := unserialized.B.(OperationBodyManageData)
+	if body.Name != "anchor" || string(body.Value) != string([]byte{0x00, 0xff}) {
+		t.Error("failed to unserialize `OperationBodyManageData`")
+		return
+	}
+}
+
+func TestSerializeOperationManageDataInvalidName(t *testing.T) {
+	for _, b := range []string{
+		`{"H": {"type": "manage-data"}, "B": {"value": "dmFsdWU="}}`,
+		`{"H": {"type": "manage-data"}, "B": {"name": null, "value": "dmFsdWU="}}`,
+		`{"H": {"type": "manage-data"}, "B": {"name": 1, "value": "dmFsdWU="}}`,
+		`{"H": {"type": "manage-data"}, "B": {"name": "anchor", "value": 1}}`,
+	} {
+		if _, err := NewOperationFromBytes([]byte(b)); err != sebakerror.ErrorInvalidOperation {
+			t.Errorf("invalid operation must occur error: %s", b)
+			return
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package sebak
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"boscoin.io/sebak/lib/common"
+	"boscoin.io/sebak/lib/error"
+	"boscoin.io/sebak/lib/storage"
+)
+
+// OperationBodyUnfreeze releases `Amount` from the frozen balances of source
+// account, whose `UnlockHeight` has passed, so it can be spent again.
+type OperationBodyUnfreeze struct {
+	Amount Amount `json:"amount"`
+}
+
+func NewOperationBodyUnfreeze(amount Amount) OperationBodyUnfreeze {
+	return OperationBodyUnfreeze{Amount: amount}
+}
+
+func (o OperationBodyUnfreeze) EncodeCanonical(e *sebakcommon.CanonicalEncoder) {
+	e.Uint64(uint64(o.Amount))
+}
+
+func (o OperationBodyUnfreeze) Serialize() (encoded []byte, err error) {
+	encoded, err = json.Marshal(o)
+	return
+}
+
+func (o OperationBodyUnfreeze) IsWellFormed([]byte) (err error) {
+	if int64(o.Amount) < 1 {
+		err = fmt.Errorf("invalid `Amount`")
+		return
+	}
+
+	return
+}
+
+func (o OperationBodyUnfreeze) Validate(st sebakstorage.LevelDBBackend) (err error) {
+	return
+}
+
+// TargetAddress returns empty string; the frozen balance belongs to the
+// source account.
+func (o OperationBodyUnfreeze) TargetAddress() string {
+	return ""
+}
+
+func (o OperationBodyUnfreeze) GetAmount() Amount {
+	return Amount(0)
+}
+
+// CheckUnfreeze checks the frozen balances of `source` have the unlocked
+// `amount` at the next block.
+func CheckUnfreeze(st *sebakstorage.LevelDBBackend, source string, amount Amount) (err error) {
+	var height uint64
+	if height, err = getNextBlockHeight(st); err != nil {
+		return
+	}
+
+	var fb FrozenBalances
+	if fb, _, err = GetFrozenBalances(st, source); err != nil {
+		return
+	}
+	if fb.Unlocked(height) < amount {
+		err = sebakerror.ErrorFrozenBalanceNotUnlocked
+		return
+	}
+
+	return
+}
+
+func FinishOperationUnfreeze(st *sebakstorage.LevelDBBackend, tx Transaction, op Operation) (err error) {
+	var height uint64
+	if height, err = getNextBlockHeight(st); err != nil {
+		return
+	}
+
+	var fb FrozenBalances
+	var found bool
+	if fb, found, err = GetFrozenBalances(st, tx.B.Source); err != nil {
+		return
+	} else if !found {
+		err = sebakerror.ErrorFrozenBalanceNotUnlocked
+		return
+	}
+
+	body := op.B.(OperationBodyUnfreeze)
+	if err = fb.Release(body.Amount, height); err != nil {
+		return
+	}
+
+	if len(fb.Frozen) < 1 {
+		err = RemoveFrozenBalances(st, tx.B.Source)
+	} else {
+		err = fb.Save(st)
+	}
+	if err != nil {
+		return
+	}
+
+	log.Debug("balance unfrozen", "source", tx.B.Source, "amount", body.Amount)
+
+	return
+}
+
+func newOperationBodyUnfreezeFromInterface(body map[string]interface{}) (o OperationBodyUnfreeze, err error) {
+	o.Amount, err = AmountFromString(fmt.Sprintf("%v", body["amount"]))
+	return
+}
+
+func init() {
+	RegisterOperationKind(OperationKind{
+		Type: OperationUnfreeze,
+		NewBody: func(body map[string]interface{}) (OperationBody, error) {
+			return newOperationBodyUnfreezeFromInterface(body)
+		},
+		IsBody: func(body OperationBody) bool {
+			_, ok := body.(OperationBodyUnfreeze)
+			return ok
+		},
+		Validate: func(st *sebakstorage.LevelDBBackend, tx Transaction, op Operation) error {
+			return CheckUnfreeze(st, tx.B.Source, op.B.(OperationBodyUnfreeze).Amount)
+		},
+		Apply: FinishOperationUnfreeze,
+	})
+}
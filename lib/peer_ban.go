@@ -0,0 +1,100 @@
+package sebak
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"boscoin.io/sebak/lib/network"
+	"boscoin.io/sebak/lib/storage"
+)
+
+// The bans of the peers are kept in storage, so the banned peer is still
+// banned after the node restarts,
+//  * 'pb-<PeerBan.IP>': `sebaknetwork.PeerBan`
+// The expired bans are removed when they are found.
+
+const PeerBanPrefix string = "pb-" // pb-<PeerBan.IP>
+
+func GetPeerBanKey(ip string) string {
+	return fmt.Sprintf("%s%s", PeerBanPrefix, ip)
+}
+
+func savePeerBan(st *sebakstorage.LevelDBBackend, ban sebaknetwork.PeerBan) (err error) {
+	key := GetPeerBanKey(ban.IP)
+
+	var exists bool
+	if exists, err = st.Has(key); err != nil {
+		return
+	}
+
+	if exists {
+		err = st.Set(key, ban)
+	} else {
+		err = st.New(key, ban)
+	}
+
+	return
+}
+
+// GetPeerBans returns the bans kept in storage, including the expired ones.
+func GetPeerBans(st *sebakstorage.LevelDBBackend) (bans []sebaknetwork.PeerBan, err error) {
+	iterFunc, closeFunc := st.GetIterator(PeerBanPrefix, false)
+	defer closeFunc()
+
+	for {
+		item, hasNext := iterFunc()
+		if !hasNext {
+			break
+		}
+
+		var ban sebaknetwork.PeerBan
+		if err = json.Unmarshal(item.Value, &ban); err != nil {
+			return
+		}
+		bans = append(bans, ban)
+	}
+
+	return
+}
+
+func (nr *NodeRunner) PeerBans() *sebaknetwork.PeerBanList {
+	return nr.peerBans
+}
+
+// loadPeerBans restores the bans kept in storage and keeps the new bans in
+// storage from now.
+func (nr *NodeRunner) loadPeerBans() (err error) {
+	var bans []sebaknetwork.PeerBan
+	if bans, err = GetPeerBans(nr.storage); err != nil {
+		return
+	}
+	nr.peerBans.Restore(bans...)
+
+	// the expired bans are removed
+	restored := map[string]bool{}
+	for _, ban := range nr.peerBans.Bans() {
+		restored[ban.IP] = true
+	}
+	for _, ban := range bans {
+		if restored[ban.IP] {
+			continue
+		}
+		if err = nr.storage.Remove(GetPeerBanKey(ban.IP)); err != nil {
+			return
+		}
+	}
+
+	nr.peerBans.SetHook(func(ban sebaknetwork.PeerBan, banned bool) {
+		var err error
+		if banned {
+			err = savePeerBan(nr.storage, ban)
+		} else {
+			err = nr.storage.Remove(GetPeerBanKey(ban.IP))
+		}
+		if err != nil {
+			nr.log.Error("failed to keep peer ban", "ip", ban.IP, "banned", banned, "error", err)
+		}
+	})
+
+	return
+}
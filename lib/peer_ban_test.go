@@ -0,0 +1,44 @@
+package sebak
+
+import (
+	"testing"
+
+	"boscoin.io/sebak/lib/network"
+)
+
+func TestNodeRunnerPeerBansKept(t *testing.T) {
+	defer sebaknetwork.CleanUpMemoryNetwork()
+
+	nr := createNodeRunners(1)[0]
+	if err := nr.loadPeerBans(); err != nil {
+		t.Error(err)
+		return
+	}
+
+	for !nr.PeerBans().Offend("1.1.1.1", sebaknetwork.PeerOffenseInvalidSignature) {
+	}
+	if bans, err := GetPeerBans(nr.Storage()); err != nil || len(bans) != 1 || bans[0].IP != "1.1.1.1" {
+		t.Errorf("ban must be kept in storage: %v %v", bans, err)
+		return
+	}
+
+	// the restarted node still bans the IP
+	nr.peerBans = sebaknetwork.NewPeerBanList(sebaknetwork.DefaultPeerBanConfig)
+	if err := nr.loadPeerBans(); err != nil {
+		t.Error(err)
+		return
+	}
+	if !nr.PeerBans().IsBanned("1.1.1.1") {
+		t.Error("ban must be restored")
+		return
+	}
+
+	if !nr.PeerBans().Unban("1.1.1.1") {
+		t.Error("failed to unban")
+		return
+	}
+	if bans, _ := GetPeerBans(nr.Storage()); len(bans) != 0 {
+		t.Errorf("unbanned IP must be removed from storage: %v", bans)
+		return
+	}
+}
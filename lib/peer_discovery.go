@@ -0,0 +1,75 @@
+package sebak
+
+import (
+	"fmt"
+	"time"
+
+	"boscoin.io/sebak/lib/common"
+)
+
+const (
+	// ValidatorDiscoveryTimeout is how long the node looks for the endpoints
+	// of the validators, which are given only by the address, at start.
+	ValidatorDiscoveryTimeout time.Duration = time.Minute * 2
+
+	// ValidatorDiscoveryInterval is the interval of the peer exchanges until
+	// every validator is found.
+	ValidatorDiscoveryInterval time.Duration = time.Second * 3
+)
+
+// DiscoverValidators finds the endpoints of the validators, which are given
+// only by the address; the peers are exchanged with the seeds and the known
+// peers until every validator is found in the address book, or
+// `ValidatorDiscoveryTimeout`. The validators, which have the endpoint are
+// not changed.
+func (nr *NodeRunner) DiscoverValidators() (err error) {
+	unresolved := nr.resolveValidators()
+	if len(unresolved) < 1 {
+		return
+	}
+
+	deadline := time.Now().Add(ValidatorDiscoveryTimeout)
+	for {
+		nr.log.Debug("discovering validators", "validators", unresolved)
+
+		nr.ExchangePeers()
+		if unresolved = nr.resolveValidators(); len(unresolved) < 1 {
+			return
+		}
+		if time.Now().After(deadline) {
+			err = fmt.Errorf("endpoints of validators are not found: %v", unresolved)
+			return
+		}
+
+		time.Sleep(ValidatorDiscoveryInterval)
+	}
+}
+
+// resolveValidators sets the endpoints of the validators, which are found in
+// the address book, and returns the addresses of the validators, which are
+// not found yet. The peer in the address book is learned from the other
+// nodes, so the endpoint is taken only when the node at it proves that it is
+// the validator by it's signed peer exchange.
+func (nr *NodeRunner) resolveValidators() (unresolved []string) {
+	for _, v := range nr.connectionManager.Validators() {
+		if v.Endpoint() != nil {
+			continue
+		}
+
+		if peer, found := nr.addressBook.Get(v.Address()); found {
+			endpoint, err := sebakcommon.ParseNodeEndpoint(peer.Endpoint)
+			if err == nil {
+				var pe PeerExchange
+				if pe, err = nr.exchangePeers(endpoint); err == nil && pe.B.NodeKey == v.Address() {
+					v.SetEndpoint(endpoint)
+					nr.log.Info("validator discovered", "validator", v.Address(), "endpoint", endpoint)
+					continue
+				}
+			}
+		}
+
+		unresolved = append(unresolved, v.Address())
+	}
+
+	return
+}
@@ -0,0 +1,586 @@
+package sebak
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/btcsuite/btcutil/base58"
+	"github.com/stellar/go/keypair"
+
+	"boscoin.io/sebak/lib/common"
+	"boscoin.io/sebak/lib/error"
+	"boscoin.io/sebak/lib/storage"
+)
+
+// PeerExchange is the signed list of the healthy peers, which the node knows;
+// the nodes get it from each other by '/peers' of the node network, so the new
+// node can find the network from only one seed. The peers in it are the node
+// itself, the connected validators and the fresh peers of `AddressBook`, and
+// `PeerAddress.Seen` is not updated until the peer is reached directly, so the
+// peer, which is gone is dropped from the address books after
+// `PeerAddressMaxAge`.
+
+const (
+	// PeerExchangeInterval is the interval to get the peer exchanges of the
+	// other nodes.
+	PeerExchangeInterval time.Duration = time.Second * 30
+
+	// PeerExchangeStartJitter is the maximum of the random delay of the first
+	// exchange, so the nodes, which start together do not ask each other at
+	// once.
+	PeerExchangeStartJitter time.Duration = time.Second * 3
+
+	// PeerExchangeFanout is the number of nodes, which are asked in one
+	// interval.
+	PeerExchangeFanout int = 3
+
+	// MaxPeerExchangePeers is the maximum number of peers in one exchange.
+	MaxPeerExchangePeers int = 30
+
+	// PeerExchangeMaxAge is the freshness limit of exchange; the exchange,
+	// which is older or newer than this is refused.
+	PeerExchangeMaxAge time.Duration = time.Minute * 5
+
+	// PeerAddressMaxAge is the freshness limit of peer; the peer, which is not
+	// seen longer than this is not shared and is dropped.
+	PeerAddressMaxAge time.Duration = time.Minute * 30
+
+	// MaxAddressBookSize is the maximum number of peers in `AddressBook`; the
+	// oldest peer is dropped for the new one.
+	MaxAddressBookSize int = 1000
+)
+
+type PeerAddress struct {
+	Address  string `json:"address"`
+	Endpoint string `json:"endpoint"`
+	Seen     string `json:"seen"`
+}
+
+func (p PeerAddress) SeenTime() time.Time {
+	t, _ := time.Parse(time.RFC3339Nano, p.Seen)
+	return t
+}
+
+func (p PeerAddress) IsWellFormed() (err error) {
+	if _, err = ParseAccountAddress(p.Address); err != nil {
+		return
+	}
+	if _, err = sebakcommon.ParseNodeEndpoint(p.Endpoint); err != nil {
+		return
+	}
+	if p.SeenTime().IsZero() {
+		err = sebakerror.ErrorInvalidMessage
+		return
+	}
+
+	return
+}
+
+// Save stores the peer; the known peer is replaced.
+func (p PeerAddress) Save(st *sebakstorage.LevelDBBackend) (err error) {
+	key := GetPeerAddressKey(p.Address)
+
+	var exists bool
+	if exists, err = st.Has(key); err != nil {
+		return
+	}
+
+	if exists {
+		err = st.Set(key, p)
+	} else {
+		err = st.New(key, p)
+	}
+
+	return
+}
+
+type PeerExchange struct {
+	T string
+	H PeerExchangeHeader
+	B PeerExchangeBody
+}
+
+type PeerExchangeHeader struct {
+	Hash      string `json:"hash"`
+	Signature string `json:"signature"`
+}
+
+type PeerExchangeBody struct {
+	NodeKey string        `json:"node_key"`
+	Created string        `json:"created"`
+	Peers   []PeerAddress `json:"peers"`
+}
+
+func (pb PeerExchangeBody) MakeHashString() string {
+	return base58.Encode(sebakcommon.MustMakeObjectHash(pb))
+}
+
+func NewPeerExchange(nodeKey string, peers []PeerAddress) PeerExchange {
+	body := PeerExchangeBody{
+		NodeKey: nodeKey,
+		Created: time.Now().Format(time.RFC3339Nano),
+		Peers:   peers,
+	}
+
+	return PeerExchange{
+		T: "peer-exchange",
+		H: PeerExchangeHeader{Hash: body.MakeHashString()},
+		B: body,
+	}
+}
+
+func NewPeerExchangeFromJSON(b []byte) (pe PeerExchange, err error) {
+	err = json.Unmarshal(b, &pe)
+	return
+}
+
+func (pe *PeerExchange) Sign(kp keypair.KP, networkID []byte) {
+	pe.H.Hash = pe.B.MakeHashString()
+	signature, _ := kp.Sign(append(networkID, []byte(pe.H.Hash)...))
+
+	pe.H.Signature = base58.Encode(signature)
+}
+
+// IsWellFormed checks the signature and the freshness of exchange; the peer,
+// which is not well-formed is ignored by `AddressBook`.
+func (pe PeerExchange) IsWellFormed(networkID []byte) (err error) {
+	if len(pe.B.Peers) > MaxPeerExchangePeers {
+		err = sebakerror.ErrorPeerExchangeTooManyPeers
+		return
+	}
+
+	created, err := time.Parse(time.RFC3339Nano, pe.B.Created)
+	if err != nil {
+		err = sebakerror.ErrorInvalidMessage
+		return
+	}
+	if d := time.Since(created); d > PeerExchangeMaxAge || d < -PeerExchangeMaxAge {
+		err = sebakerror.ErrorPeerExchangeExpired
+		return
+	}
+
+	if pe.H.Hash != pe.B.MakeHashString() {
+		err = sebakerror.ErrorHashDoesNotMatch
+		return
+	}
+
+	var kp keypair.KP
+	if kp, err = keypair.Parse(pe.B.NodeKey); err != nil {
+		err = sebakerror.ErrorBadPublicAddress
+		return
+	}
+
+	if err = kp.Verify(append(networkID, []byte(pe.H.Hash)...), base58.Decode(pe.H.Signature)); err != nil {
+		err = sebakerror.ErrorSignatureVerificationFailed
+		return
+	}
+
+	return
+}
+
+func (pe PeerExchange) GetType() string {
+	return pe.T
+}
+
+func (pe PeerExchange) GetHash() string {
+	return pe.H.Hash
+}
+
+func (pe PeerExchange) Equal(m sebakcommon.Message) bool {
+	return pe.H.Hash == m.GetHash()
+}
+
+func (pe PeerExchange) Serialize() (encoded []byte, err error) {
+	encoded, err = json.Marshal(pe)
+	return
+}
+
+func (pe PeerExchange) String() string {
+	encoded, _ := json.MarshalIndent(pe, "", "  ")
+	return string(encoded)
+}
+
+// AddressBook keeps the peers, which are learned by the peer exchanges. With
+// `SetStorage()`, the peers are also kept in storage, so the restarted node
+// finds the network without the seeds,
+//  * 'pa-<PeerAddress.Address>': `PeerAddress`
+type AddressBook struct {
+	sync.RWMutex
+
+	self    string
+	peers   map[ /* address */ string]PeerAddress
+	storage *sebakstorage.LevelDBBackend
+}
+
+const PeerAddressPrefix string = "pa-"
+
+func GetPeerAddressKey(address string) string {
+	return fmt.Sprintf("%s%s", PeerAddressPrefix, address)
+}
+
+func NewAddressBook(self string) *AddressBook {
+	return &AddressBook{
+		self:  self,
+		peers: map[string]PeerAddress{},
+	}
+}
+
+// Add adds the peer or updates the known peer with the newer one; it returns
+// true when the peer is added or updated. The node itself, the peer, which is
+// not well-formed, and the peer, which is older than `PeerAddressMaxAge` are
+// ignored.
+func (ab *AddressBook) Add(peer PeerAddress) bool {
+	if peer.Address == ab.self || peer.IsWellFormed() != nil {
+		return false
+	}
+	seen := peer.SeenTime()
+	if time.Since(seen) > PeerAddressMaxAge || time.Until(seen) > PeerExchangeMaxAge {
+		return false
+	}
+
+	ab.Lock()
+	defer ab.Unlock()
+
+	if known, found := ab.peers[peer.Address]; found {
+		if !seen.After(known.SeenTime()) {
+			return false
+		}
+	} else if len(ab.peers) >= MaxAddressBookSize {
+		var oldest string
+		for address, p := range ab.peers {
+			if len(oldest) < 1 || p.SeenTime().Before(ab.peers[oldest].SeenTime()) {
+				oldest = address
+			}
+		}
+		if !seen.After(ab.peers[oldest].SeenTime()) {
+			return false
+		}
+		ab.delete(oldest)
+	}
+	ab.peers[peer.Address] = peer
+
+	if ab.storage != nil {
+		if err := peer.Save(ab.storage); err != nil {
+			log.Error("failed to save peer", "peer", peer.Address, "error", err)
+		}
+	}
+
+	return true
+}
+
+// delete removes the peer from the book and the storage; the lock must be
+// held.
+func (ab *AddressBook) delete(address string) {
+	delete(ab.peers, address)
+
+	if ab.storage != nil {
+		if err := ab.storage.Remove(GetPeerAddressKey(address)); err != nil {
+			log.Error("failed to remove peer", "peer", address, "error", err)
+		}
+	}
+}
+
+// SetStorage loads the peers kept in storage into the book and keeps the
+// changes of book in storage from now. The peers, which are older than
+// `PeerAddressMaxAge` are not loaded, but they are returned, so the node can
+// ask them for the network once; they are still kept in storage until
+// `ForgetStale()`.
+func (ab *AddressBook) SetStorage(st *sebakstorage.LevelDBBackend) (stale []PeerAddress, err error) {
+	var stored []PeerAddress
+
+	iterFunc, closeFunc := st.GetIterator(PeerAddressPrefix, false)
+	for {
+		item, hasNext := iterFunc()
+		if !hasNext {
+			break
+		}
+
+		var peer PeerAddress
+		if err = json.Unmarshal(item.Value, &peer); err != nil {
+			closeFunc()
+			return
+		}
+		stored = append(stored, peer)
+	}
+	closeFunc()
+
+	ab.Lock()
+	defer ab.Unlock()
+
+	// the peers added before storage is set are also kept
+	for _, peer := range ab.peers {
+		if err = peer.Save(st); err != nil {
+			return
+		}
+	}
+	ab.storage = st
+
+	for _, peer := range stored {
+		if _, found := ab.peers[peer.Address]; found {
+			continue
+		}
+		if peer.IsWellFormed() != nil || time.Since(peer.SeenTime()) > PeerAddressMaxAge {
+			stale = append(stale, peer)
+			continue
+		}
+		ab.peers[peer.Address] = peer
+	}
+
+	return
+}
+
+// ForgetStale removes the stale peers of `SetStorage()` from storage unless
+// they are learned again.
+func (ab *AddressBook) ForgetStale(stale []PeerAddress) {
+	ab.Lock()
+	defer ab.Unlock()
+
+	for _, peer := range stale {
+		if _, found := ab.peers[peer.Address]; found || ab.storage == nil {
+			continue
+		}
+		if err := ab.storage.Remove(GetPeerAddressKey(peer.Address)); err != nil {
+			log.Error("failed to remove peer", "peer", peer.Address, "error", err)
+		}
+	}
+}
+
+// Merge adds the peers of the well-formed exchange and returns the number of
+// the added or updated peers.
+func (ab *AddressBook) Merge(pe PeerExchange, networkID []byte) (added int, err error) {
+	if err = pe.IsWellFormed(networkID); err != nil {
+		return
+	}
+
+	for _, peer := range pe.B.Peers {
+		if ab.Add(peer) {
+			added++
+		}
+	}
+
+	return
+}
+
+// Remove removes the peer; it returns false if the peer is not known.
+func (ab *AddressBook) Remove(address string) bool {
+	ab.Lock()
+	defer ab.Unlock()
+
+	if _, found := ab.peers[address]; !found {
+		return false
+	}
+	ab.delete(address)
+
+	return true
+}
+
+// Get returns the known peer regardless of it's freshness.
+func (ab *AddressBook) Get(address string) (peer PeerAddress, found bool) {
+	ab.RLock()
+	defer ab.RUnlock()
+
+	peer, found = ab.peers[address]
+
+	return
+}
+
+// Prune removes the peers, which are not seen longer than
+// `PeerAddressMaxAge`.
+func (ab *AddressBook) Prune() {
+	ab.Lock()
+	defer ab.Unlock()
+
+	for address, peer := range ab.peers {
+		if time.Since(peer.SeenTime()) > PeerAddressMaxAge {
+			ab.delete(address)
+		}
+	}
+}
+
+// Peers returns the fresh peers in address order.
+func (ab *AddressBook) Peers() (peers []PeerAddress) {
+	ab.RLock()
+	defer ab.RUnlock()
+
+	for _, peer := range ab.peers {
+		if time.Since(peer.SeenTime()) > PeerAddressMaxAge {
+			continue
+		}
+		peers = append(peers, peer)
+	}
+	sort.Slice(peers, func(i, j int) bool { return peers[i].Address < peers[j].Address })
+
+	return
+}
+
+// Sample returns the `n` fresh peers at random.
+func (ab *AddressBook) Sample(n int) []PeerAddress {
+	peers := ab.Peers()
+
+	var sampled []PeerAddress
+	for _, i := range rand.Perm(len(peers)) {
+		if len(sampled) == n {
+			break
+		}
+		sampled = append(sampled, peers[i])
+	}
+
+	return sampled
+}
+
+func (ab *AddressBook) Len() int {
+	ab.RLock()
+	defer ab.RUnlock()
+
+	return len(ab.peers)
+}
+
+// SetPeerExchangeSeeds sets the nodes, which are asked for the peers with the
+// validators and the known peers; it must be called before the node starts.
+func (nr *NodeRunner) SetPeerExchangeSeeds(seeds []*sebakcommon.Endpoint) {
+	nr.peerExchangeSeeds = seeds
+}
+
+func (nr *NodeRunner) AddressBook() *AddressBook {
+	return nr.addressBook
+}
+
+// makePeerExchange makes the signed exchange of the node itself, the
+// connected validators and the fresh peers of address book.
+func (nr *NodeRunner) makePeerExchange() PeerExchange {
+	now := time.Now().Format(time.RFC3339Nano)
+
+	var peers []PeerAddress
+	included := map[string]bool{}
+	if nr.currentNode.Endpoint() != nil {
+		peers = append(peers, PeerAddress{
+			Address:  nr.currentNode.Address(),
+			Endpoint: nr.currentNode.Endpoint().String(),
+			Seen:     now,
+		})
+		included[nr.currentNode.Address()] = true
+	}
+	for _, v := range nr.connectionManager.AllConnected() {
+		peers = append(peers, PeerAddress{Address: v.Address(), Endpoint: v.Endpoint().String(), Seen: now})
+		included[v.Address()] = true
+	}
+	for _, peer := range nr.addressBook.Sample(MaxPeerExchangePeers) {
+		if len(peers) >= MaxPeerExchangePeers {
+			break
+		}
+		if included[peer.Address] {
+			continue
+		}
+		peers = append(peers, peer)
+	}
+
+	pe := NewPeerExchange(nr.currentNode.Address(), peers)
+	pe.Sign(nr.currentNode.Keypair(), nr.networkID)
+
+	return pe
+}
+
+func (nr *NodeRunner) serializePeerExchange() ([]byte, error) {
+	return nr.makePeerExchange().Serialize()
+}
+
+// ExchangePeers asks `PeerExchangeFanout` nodes at random among the seeds,
+// the validators and the known peers for their peers, and adds them to the
+// address book. Until the address book has the peers, the stale peers of
+// storage are also asked, so the restarted node finds the network without
+// the seeds.
+func (nr *NodeRunner) ExchangePeers() {
+	nr.addressBook.Prune()
+
+	var endpoints []*sebakcommon.Endpoint
+	endpoints = append(endpoints, nr.peerExchangeSeeds...)
+	for _, v := range nr.connectionManager.Validators() {
+		if v.Endpoint() != nil {
+			endpoints = append(endpoints, v.Endpoint())
+		}
+	}
+	peers := nr.addressBook.Peers()
+	if len(peers) < 1 {
+		peers = nr.stalePeers
+	}
+	for _, peer := range peers {
+		if endpoint, err := sebakcommon.ParseNodeEndpoint(peer.Endpoint); err == nil {
+			endpoints = append(endpoints, endpoint)
+		}
+	}
+
+	asked := map[string]bool{}
+	for _, i := range rand.Perm(len(endpoints)) {
+		if len(asked) == PeerExchangeFanout {
+			break
+		}
+		endpoint := endpoints[i]
+		if asked[endpoint.String()] {
+			continue
+		}
+		asked[endpoint.String()] = true
+
+		if _, err := nr.exchangePeers(endpoint); err != nil {
+			nr.log.Debug("failed to exchange peers", "endpoint", endpoint, "error", err)
+		}
+	}
+
+	if len(nr.stalePeers) > 0 && nr.addressBook.Len() > 0 {
+		nr.addressBook.ForgetStale(nr.stalePeers)
+		nr.stalePeers = nil
+	}
+}
+
+func (nr *NodeRunner) exchangePeers(endpoint *sebakcommon.Endpoint) (pe PeerExchange, err error) {
+	client := nr.connectionManager.ClientPool().Get(endpoint)
+
+	var b []byte
+	if b, err = client.GetPeers(); err != nil {
+		return
+	}
+
+	if pe, err = NewPeerExchangeFromJSON(b); err != nil {
+		return
+	}
+
+	var added int
+	if added, err = nr.addressBook.Merge(pe, nr.networkID); err != nil {
+		return
+	}
+	if added > 0 {
+		nr.log.Debug("peers exchanged", "node", pe.B.NodeKey, "added", added, "known", nr.addressBook.Len())
+	}
+
+	return
+}
+
+// startPeerExchange exchanges the peers every `PeerExchangeInterval` until
+// the node is stopped. With the seeds, the first exchange is done after the
+// random delay of `PeerExchangeStartJitter`; without them, the validators are
+// asked at the first interval.
+func (nr *NodeRunner) startPeerExchange() {
+	if len(nr.peerExchangeSeeds) > 0 {
+		select {
+		case <-time.After(time.Duration(rand.Int63n(int64(PeerExchangeStartJitter)))):
+			nr.ExchangePeers()
+		case <-nr.stop:
+			return
+		}
+	}
+
+	ticker := time.NewTicker(PeerExchangeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			nr.ExchangePeers()
+		case <-nr.stop:
+			return
+		}
+	}
+}
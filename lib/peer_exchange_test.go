@@ -0,0 +1,244 @@
+package sebak
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stellar/go/keypair"
+
+	"boscoin.io/sebak/lib/common"
+	"boscoin.io/sebak/lib/error"
+	"boscoin.io/sebak/lib/network"
+	"boscoin.io/sebak/lib/storage"
+)
+
+func testMakePeerAddress(seen time.Time) PeerAddress {
+	kp, _ := keypair.Random()
+	return PeerAddress{
+		Address:  kp.Address(),
+		Endpoint: sebaknetwork.CreateNewMemoryEndpoint().String(),
+		Seen:     seen.Format(time.RFC3339Nano),
+	}
+}
+
+func TestPeerExchange(t *testing.T) {
+	kp, _ := keypair.Random()
+
+	pe := NewPeerExchange(kp.Address(), []PeerAddress{testMakePeerAddress(time.Now())})
+	pe.Sign(kp, networkID)
+	if err := pe.IsWellFormed(networkID); err != nil {
+		t.Error(err)
+		return
+	}
+
+	tampered := pe
+	tampered.B.Peers = []PeerAddress{testMakePeerAddress(time.Now())}
+	if err := tampered.IsWellFormed(networkID); err != sebakerror.ErrorHashDoesNotMatch {
+		t.Errorf("tampered exchange must be refused: %v", err)
+		return
+	}
+
+	expired := pe
+	expired.B.Created = time.Now().Add(-PeerExchangeMaxAge * 2).Format(time.RFC3339Nano)
+	expired.Sign(kp, networkID)
+	if err := expired.IsWellFormed(networkID); err != sebakerror.ErrorPeerExchangeExpired {
+		t.Errorf("expired exchange must be refused: %v", err)
+		return
+	}
+
+	var peers []PeerAddress
+	for i := 0; i < MaxPeerExchangePeers+1; i++ {
+		peers = append(peers, testMakePeerAddress(time.Now()))
+	}
+	many := NewPeerExchange(kp.Address(), peers)
+	many.Sign(kp, networkID)
+	if err := many.IsWellFormed(networkID); err != sebakerror.ErrorPeerExchangeTooManyPeers {
+		t.Errorf("too many peers must be refused: %v", err)
+		return
+	}
+}
+
+func TestAddressBook(t *testing.T) {
+	kp, _ := keypair.Random()
+	ab := NewAddressBook(kp.Address())
+
+	fresh := testMakePeerAddress(time.Now().Add(-time.Minute))
+	stale := testMakePeerAddress(time.Now().Add(-PeerAddressMaxAge * 2))
+	self := testMakePeerAddress(time.Now())
+	self.Address = kp.Address()
+	invalid := testMakePeerAddress(time.Now())
+	invalid.Endpoint = "unknown"
+
+	other, _ := keypair.Random()
+	pe := NewPeerExchange(other.Address(), []PeerAddress{fresh, stale, self, invalid})
+	pe.Sign(other, networkID)
+	if added, err := ab.Merge(pe, networkID); err != nil || added != 1 {
+		t.Errorf("only the fresh peer must be added: %d %v", added, err)
+		return
+	}
+
+	older := fresh
+	older.Seen = time.Now().Add(-time.Minute * 2).Format(time.RFC3339Nano)
+	if ab.Add(older) {
+		t.Error("older peer must not update the known peer")
+		return
+	}
+	newer := fresh
+	newer.Seen = time.Now().Format(time.RFC3339Nano)
+	if !ab.Add(newer) {
+		t.Error("newer peer must update the known peer")
+		return
+	}
+	if peers := ab.Peers(); len(peers) != 1 || peers[0].Seen != newer.Seen {
+		t.Errorf("wrong peers: %v", peers)
+		return
+	}
+}
+
+func TestNodeRunnerExchangePeers(t *testing.T) {
+	defer sebaknetwork.CleanUpMemoryNetwork()
+
+	nodeRunners := createNodeRunners(3)
+	for _, nr := range nodeRunners {
+		nr.Ready()
+	}
+
+	seed := nodeRunners[0]
+	seed.ExchangePeers()
+	if seed.AddressBook().Len() != 2 {
+		t.Errorf("seed must know the validators: %v", seed.AddressBook().Peers())
+		return
+	}
+
+	// the new node, which does not know the validators finds them from the
+	// seed
+	watcher := createNodeRunners(1)[0]
+	watcher.Ready()
+	watcher.SetPeerExchangeSeeds([]*sebakcommon.Endpoint{seed.Network().Endpoint()})
+	watcher.ExchangePeers()
+
+	known := map[string]bool{}
+	for _, peer := range watcher.AddressBook().Peers() {
+		known[peer.Address] = true
+	}
+	for _, nr := range nodeRunners {
+		if !known[nr.Node().Address()] {
+			t.Errorf("'%s' must be found: %v", nr.Node().Address(), watcher.AddressBook().Peers())
+			return
+		}
+	}
+}
+
+// TestNodeRunnerStopPeerExchange checks, the peer exchange of node exits when
+// the node is stopped, even while it waits for the first exchange.
+func TestNodeRunnerStopPeerExchange(t *testing.T) {
+	defer sebaknetwork.CleanUpMemoryNetwork()
+
+	nodeRunners := createNodeRunners(2)
+	nr := nodeRunners[0]
+	nr.Ready()
+	nr.SetPeerExchangeSeeds([]*sebakcommon.Endpoint{nodeRunners[1].Network().Endpoint()})
+
+	done := make(chan struct{})
+	go func() {
+		nr.startPeerExchange()
+		close(done)
+	}()
+
+	nr.stopOnce.Do(func() { close(nr.stop) })
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Error("peer exchange must exit when the node is stopped")
+	}
+}
+
+func TestAddressBookStorage(t *testing.T) {
+	st, _ := sebakstorage.NewTestMemoryLevelDBBackend()
+	defer st.Close()
+
+	kp, _ := keypair.Random()
+	ab := NewAddressBook(kp.Address())
+
+	// the peer added before storage is set is also kept
+	before := testMakePeerAddress(time.Now())
+	ab.Add(before)
+	if stale, err := ab.SetStorage(st); err != nil || len(stale) != 0 {
+		t.Errorf("failed to set storage: %v %v", stale, err)
+		return
+	}
+	after := testMakePeerAddress(time.Now())
+	ab.Add(after)
+	removed := testMakePeerAddress(time.Now())
+	ab.Add(removed)
+	ab.Remove(removed.Address)
+
+	// the peer, which is not seen for long is kept in storage for the
+	// restart after the long stop
+	stale := testMakePeerAddress(time.Now().Add(-PeerAddressMaxAge * 2))
+	if err := stale.Save(st); err != nil {
+		t.Error(err)
+		return
+	}
+
+	restarted := NewAddressBook(kp.Address())
+	restored, err := restarted.SetStorage(st)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if len(restored) != 1 || restored[0].Address != stale.Address {
+		t.Errorf("stale peer must be returned: %v", restored)
+		return
+	}
+
+	peers := restarted.Peers()
+	if len(peers) != 2 {
+		t.Errorf("stored peers must be restored: %v", peers)
+		return
+	}
+	for _, peer := range peers {
+		if peer.Address != before.Address && peer.Address != after.Address {
+			t.Errorf("unknown peer restored: %v", peer)
+			return
+		}
+	}
+
+	restarted.ForgetStale(restored)
+	if exists, _ := st.Has(GetPeerAddressKey(stale.Address)); exists {
+		t.Error("stale peer must be removed from storage")
+		return
+	}
+}
+
+func TestNodeRunnerDiscoverValidators(t *testing.T) {
+	defer sebaknetwork.CleanUpMemoryNetwork()
+
+	nodeRunners := createNodeRunners(3)
+	for _, nr := range nodeRunners {
+		nr.Ready()
+	}
+	seed := nodeRunners[0]
+	seed.ExchangePeers()
+
+	// the new node knows the validators only by the address
+	node := createNodeRunners(1)[0]
+	for _, nr := range nodeRunners {
+		v, _ := sebakcommon.NewValidator(nr.Node().Address(), nil, "")
+		node.Node().AddValidators(v)
+	}
+	node.Ready()
+	node.SetPeerExchangeSeeds([]*sebakcommon.Endpoint{seed.Network().Endpoint()})
+
+	if err := node.DiscoverValidators(); err != nil {
+		t.Error(err)
+		return
+	}
+	for _, nr := range nodeRunners {
+		v := node.Node().GetValidators()[nr.Node().Address()]
+		if v.Endpoint() == nil || v.Endpoint().String() != nr.Node().Endpoint().String() {
+			t.Errorf("endpoint of '%s' must be discovered: %v", nr.Node().Address(), v.Endpoint())
+			return
+		}
+	}
+}
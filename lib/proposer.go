@@ -0,0 +1,103 @@
+package sebak
+
+import (
+	"sort"
+
+	"boscoin.io/sebak/lib/common"
+)
+
+// ProposerSchedule decides the expected proposer of each block height by
+// round-robin over the validators, including the current node, ordered by
+// their addresses. Every node, which has same validators, gets the same
+// schedule.
+type ProposerSchedule struct {
+	validators []*sebakcommon.Validator
+}
+
+type ScheduledProposer struct {
+	Height   uint64 `json:"height"`
+	Address  string `json:"address"`
+	Alias    string `json:"alias"`
+	Endpoint string `json:"endpoint"`
+}
+
+func NewProposerSchedule(node sebakcommon.Node) ProposerSchedule {
+	self, _ := sebakcommon.NewValidator(node.Address(), node.Endpoint(), node.Alias())
+
+	validators := []*sebakcommon.Validator{self}
+	for _, v := range node.GetValidators() {
+		validators = append(validators, v)
+	}
+
+	sort.Slice(validators, func(i, j int) bool {
+		return validators[i].Address() < validators[j].Address()
+	})
+
+	return ProposerSchedule{validators: validators}
+}
+
+func (p ProposerSchedule) Proposer(height uint64) ScheduledProposer {
+	return p.ProposerOfView(height, 0)
+}
+
+// ProposerOfView returns the proposer of the height after the view changes;
+// every view change passes the proposer to the next validator.
+func (p ProposerSchedule) ProposerOfView(height, view uint64) ScheduledProposer {
+	v := p.validators[(height+view)%uint64(len(p.validators))]
+
+	proposer := ScheduledProposer{
+		Height:  height,
+		Address: v.Address(),
+		Alias:   v.Alias(),
+	}
+	if v.Endpoint() != nil { // not discovered yet
+		proposer.Endpoint = v.Endpoint().String()
+	}
+
+	return proposer
+}
+
+// NextProposers returns the proposers of the next `n` heights after `height`.
+func (p ProposerSchedule) NextProposers(height uint64, n int) (proposers []ScheduledProposer) {
+	for i := 1; i <= n; i++ {
+		proposers = append(proposers, p.Proposer(height+uint64(i)))
+	}
+
+	return
+}
+
+// Validators returns the number of validators; every validator proposes once
+// in one round of schedule.
+func (p ProposerSchedule) Validators() int {
+	return len(p.validators)
+}
+
+// NextRounds returns the proposers of the next `rounds` rounds after
+// `height`.
+func (p ProposerSchedule) NextRounds(height uint64, rounds int) []ScheduledProposer {
+	return p.NextProposers(height, rounds*len(p.validators))
+}
+
+// NextTurns returns the next `n` heights after `height`, which `address`
+// proposes. If `address` is not in the validators, nothing is returned.
+func (p ProposerSchedule) NextTurns(height uint64, address string, n int) (heights []uint64) {
+	index := -1
+	for i, v := range p.validators {
+		if v.Address() == address {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return
+	}
+
+	count := uint64(len(p.validators))
+	next := height + 1
+	next += (uint64(index) + count - next%count) % count
+	for i := 0; i < n; i++ {
+		heights = append(heights, next+uint64(i)*count)
+	}
+
+	return
+}
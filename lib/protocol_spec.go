@@ -0,0 +1,152 @@
+package sebak
+
+//go:generate go run protocol_spec_generate.go
+
+import (
+	"net/http"
+	"reflect"
+
+	"boscoin.io/sebak/lib/common"
+	"boscoin.io/sebak/lib/error"
+	"boscoin.io/sebak/lib/network"
+)
+
+// ProtocolSpec is the machine-readable reference of the wire format for the
+// external implementations; the messages between nodes with their schemas,
+// the result codes and errors, the storage keys and the protocol parameters.
+// The storage keys and errors are generated from the annotations of source
+// by `go generate`, so the spec is always same with the code,
+//  * the storage keys: the "//  * '<key>': <description>" lists in the doc
+//    comments and the line comments of the prefix constants
+//  * the errors: `sebakerror.NewError()` of 'lib/error/errors.go'
+
+const GetSpecPattern string = "/spec"
+
+type ProtocolSpecMessage struct {
+	Name        string         `json:"name"`
+	Path        string         `json:"path"` // the path of node network
+	Description string         `json:"description"`
+	Schema      *OpenAPISchema `json:"schema"`
+
+	message interface{}
+}
+
+type ProtocolSpecError struct {
+	Name    string                `json:"name"`
+	Code    uint                  `json:"code"`
+	Message string                `json:"message"`
+	Result  sebakerror.ResultCode `json:"result"`
+}
+
+type ProtocolSpecStorageKey struct {
+	Key         string `json:"key"`
+	Description string `json:"description"`
+	Source      string `json:"source"`
+}
+
+type ProtocolSpecParameter struct {
+	Name        string      `json:"name"`
+	Value       interface{} `json:"value"`
+	Description string      `json:"description"`
+}
+
+type ProtocolSpec struct {
+	Version     string                   `json:"version"`
+	Messages    []ProtocolSpecMessage    `json:"messages"`
+	Results     []sebakerror.Result      `json:"results"`
+	Errors      []ProtocolSpecError      `json:"errors"`
+	StorageKeys []ProtocolSpecStorageKey `json:"storage_keys"`
+	Parameters  []ProtocolSpecParameter  `json:"parameters"`
+	Components  OpenAPIComponents        `json:"components"`
+}
+
+// protocolSpecConnectSchema is the node in '/connect'; `sebakcommon.Validator`
+// is encoded by it's own `MarshalJSON()`.
+var protocolSpecConnectSchema = &OpenAPISchema{
+	Type: "object",
+	Properties: map[string]*OpenAPISchema{
+		"address":  {Type: "string"},
+		"alias":    {Type: "string"},
+		"endpoint": {Type: "string"},
+	},
+}
+
+var protocolSpecMessages = []ProtocolSpecMessage{
+	{Name: "connect", Path: "/connect", Description: "node, which connects to the validator", Schema: protocolSpecConnectSchema},
+	{Name: "transaction", Path: "/message", Description: "transaction from the client or the other node", message: Transaction{}},
+	{Name: "ballot", Path: "/ballot", Description: "ballot of the consensus", message: Ballot{}},
+	{Name: "ballots", Path: "/ballots", Description: "batch of ballots; the encoding byte, 'j' for JSON or 's' for snappy, and the JSON list of ballots", message: []Ballot{}},
+	{Name: "view-change", Path: "/view-change", Description: "vote to change the proposer of the stuck round", message: ViewChange{}},
+	{Name: "block-announcement", Path: "/block-announcement", Description: "signed announcement of the new block to detect the fork", message: BlockAnnouncement{}},
+	{Name: "peer-exchange", Path: "/peers", Description: "signed peers, which the node knows", message: PeerExchange{}},
+	{Name: "tx-inventory", Path: "/tx-inventory", Description: "hashes of the new transactions, which the node announces", message: TransactionInventory{}},
+	{Name: "get-transactions", Path: "/get-transactions", Description: "transactions of the announced hashes in the JSON list of hashes; the response is the JSON list of transactions", message: []Transaction{}},
+	{Name: "get-blocks", Path: "/get-blocks", Description: "blocks of the range, 'from' to 'to' for the block sync; the response is the JSON list of blocks with their transactions", message: []SyncBlock{}},
+}
+
+func protocolSpecParameters() []ProtocolSpecParameter {
+	p := NewDefaultNetworkParameters()
+
+	return []ProtocolSpecParameter{
+		{"version", Version, "version of node"},
+		{"base_fee", BaseFee, "minimum fee of one operation"},
+		{"block_time", p.BlockTime, "default interval of blocks in nanoseconds"},
+		{"min_block_time", MinBlockTime, "minimum interval of blocks in nanoseconds"},
+		{"minimum_balance", p.MinimumBalance, "default minimum balance of the new account; 0 is no minimum"},
+		{"threshold_init", p.ThresholdINIT, "default percentage of validators to pass INIT"},
+		{"threshold_sign", p.ThresholdSIGN, "default percentage of validators to pass SIGN"},
+		{"threshold_accept", p.ThresholdACCEPT, "default percentage of validators to pass ACCEPT"},
+		{"max_transaction_request_size", MaxTransactionRequestSize, "maximum size of the submitted transaction in bytes"},
+		{"max_ballot_batch_size", sebaknetwork.MaxBallotBatchSize, "maximum number of ballots in one batch"},
+		{"max_peer_exchange_peers", MaxPeerExchangePeers, "maximum number of peers in one peer exchange"},
+		{"max_transaction_inventory", MaxTransactionInventory, "maximum number of hashes in one transaction inventory"},
+		{"max_sync_blocks", MaxSyncBlocks, "maximum number of blocks in one range of block sync"},
+		{"peer_exchange_max_age", PeerExchangeMaxAge, "freshness limit of peer exchange in nanoseconds"},
+		{"max_account_signers", MaxAccountSigners, "maximum number of signers of account"},
+		{"max_account_signer_weight", MaxAccountSignerWeight, "maximum weight of one signer of account"},
+		{"max_transaction_co_signatures", MaxTransactionCoSignatures, "maximum number of co-signatures of transaction"},
+		{"max_memo_text_size", MaxMemoTextSize, "maximum size of the text memo of payment in bytes"},
+		{"max_transaction_payments", MaxTransactionPayments, "maximum number of payments in one transaction"},
+		{"max_frozen_balances", MaxFrozenBalances, "maximum number of frozen balances of account"},
+		{"max_data_entry_name_length", MaxDataEntryNameLength, "maximum length of the name of data entry in bytes"},
+		{"max_data_entry_value_length", MaxDataEntryValueLength, "maximum length of the value of data entry in bytes"},
+		{"max_account_data_entries", MaxAccountDataEntries, "maximum number of data entries of account"},
+		{"encoding_version", sebakcommon.EncodingVersionCanonical, "encoding version of the new transactions and ballots for the hash; the empty one is RLP"},
+	}
+}
+
+// NewProtocolSpec makes the spec; the schemas of messages refer
+// `Components`.
+func NewProtocolSpec() ProtocolSpec {
+	spec := ProtocolSpec{
+		Version:     Version,
+		Results:     sebakerror.Results(),
+		StorageKeys: protocolSpecStorageKeys,
+		Parameters:  protocolSpecParameters(),
+		Components:  OpenAPIComponents{Schemas: map[string]*OpenAPISchema{}},
+	}
+
+	for _, m := range protocolSpecMessages {
+		if m.Schema == nil {
+			m.Schema = openAPISchemaOf(reflect.TypeOf(m.message), spec.Components.Schemas)
+		}
+		spec.Messages = append(spec.Messages, m)
+	}
+
+	for _, e := range protocolSpecErrors {
+		e.Result = sebakerror.ResultOf(sebakerror.NewError(e.Code, e.Message))
+		spec.Errors = append(spec.Errors, e)
+	}
+
+	return spec
+}
+
+// handleAPISpec returns `ProtocolSpec`.
+func (nr *NodeRunner) handleAPISpec(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		writeAPIError(w, r, http.StatusMethodNotAllowed, nil)
+		return
+	}
+
+	writeAPIJSON(w, http.StatusOK, NewProtocolSpec())
+}
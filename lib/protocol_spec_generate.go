@@ -0,0 +1,243 @@
+// +build ignore
+
+// protocol_spec_generate.go generates protocol_spec_generated.go from the
+// annotations of the source code; it is run by `go generate` in 'lib/'. The
+// annotations are,
+//  * the storage keys in the doc comments, like "//  * 'fr-<transaction hash>': `ForwardingReceipt`"
+//  * the storage prefixes, like "BlockPrefixHash string = \"bk-hash-\" // bk-hash-<Block.Hash>"
+//  * the errors of 'lib/error/errors.go', like "ErrorX = NewError(100, \"message\")"
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const output string = "protocol_spec_generated.go"
+
+type storageKey struct {
+	Key         string
+	Description string
+	Source      string
+
+	prefix string // the value of prefix constant
+}
+
+type specError struct {
+	Name    string
+	Code    int
+	Message string
+}
+
+var storageKeyAnnotation = regexp.MustCompile(`^//  \* '([^']+)'(?:: (.*))?$`)
+var continuationAnnotation = regexp.MustCompile(`^//  +([^* ].*)$`)
+
+// isStorageKey checks the key of the doc list; the other lists like the
+// encoding bytes or the file paths are not the storage keys.
+func isStorageKey(key string) bool {
+	return strings.Contains(key, "-") && !strings.Contains(key, "/")
+}
+
+func parseStorageKeyComments(f *ast.File, source string) (keys []storageKey) {
+	for _, group := range f.Comments {
+		var current *storageKey
+		for _, c := range group.List {
+			if m := storageKeyAnnotation.FindStringSubmatch(c.Text); m != nil {
+				if current != nil {
+					keys = append(keys, *current)
+					current = nil
+				}
+				if isStorageKey(m[1]) {
+					current = &storageKey{Key: m[1], Description: m[2], Source: source}
+				}
+				continue
+			}
+			if m := continuationAnnotation.FindStringSubmatch(c.Text); m != nil && current != nil {
+				current.Description += " " + m[1]
+				continue
+			}
+			if current != nil {
+				keys = append(keys, *current)
+				current = nil
+			}
+		}
+		if current != nil {
+			keys = append(keys, *current)
+		}
+	}
+
+	return
+}
+
+// parseStorageKeyPrefixes finds the exported prefix constants; the line
+// comment after the constant describes the rest of key.
+func parseStorageKeyPrefixes(f *ast.File, source string) (keys []storageKey) {
+	for _, decl := range f.Decls {
+		gd, ok := decl.(*ast.GenDecl)
+		if !ok || gd.Tok != token.CONST {
+			continue
+		}
+		for _, spec := range gd.Specs {
+			vs := spec.(*ast.ValueSpec)
+			if len(vs.Names) != 1 || len(vs.Values) != 1 {
+				continue
+			}
+			name := vs.Names[0].Name
+			if !ast.IsExported(name) || !strings.Contains(name, "Prefix") {
+				continue
+			}
+			lit, ok := vs.Values[0].(*ast.BasicLit)
+			if !ok || lit.Kind != token.STRING {
+				continue
+			}
+			value, _ := strconv.Unquote(lit.Value)
+			if !strings.HasSuffix(value, "-") {
+				continue
+			}
+
+			key := storageKey{Key: value + "*", Description: fmt.Sprintf("`%s`", name), Source: source, prefix: value}
+			if vs.Comment != nil {
+				// the prefix in comment is replaced by the value of constant
+				comment := strings.TrimSpace(vs.Comment.Text())
+				if i := strings.Index(comment, "<"); i >= 0 {
+					key.Key = value + comment[i:]
+				}
+			}
+			keys = append(keys, key)
+		}
+	}
+
+	return
+}
+
+func parseErrors(f *ast.File) (errors []specError) {
+	ast.Inspect(f, func(n ast.Node) bool {
+		vs, ok := n.(*ast.ValueSpec)
+		if !ok || len(vs.Names) != 1 || len(vs.Values) != 1 {
+			return true
+		}
+		call, ok := vs.Values[0].(*ast.CallExpr)
+		if !ok || len(call.Args) != 2 {
+			return true
+		}
+		if fun, ok := call.Fun.(*ast.Ident); !ok || fun.Name != "NewError" {
+			return true
+		}
+		code, ok := call.Args[0].(*ast.BasicLit)
+		if !ok || code.Kind != token.INT {
+			return true
+		}
+		message, ok := call.Args[1].(*ast.BasicLit)
+		if !ok || message.Kind != token.STRING {
+			return true
+		}
+
+		e := specError{Name: vs.Names[0].Name}
+		e.Code, _ = strconv.Atoi(code.Value)
+		e.Message, _ = strconv.Unquote(message.Value)
+		errors = append(errors, e)
+
+		return true
+	})
+
+	return
+}
+
+func main() {
+	fset := token.NewFileSet()
+
+	var keys []storageKey
+	var errors []specError
+	err := filepath.Walk(".", func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || !strings.HasSuffix(path, ".go") || strings.HasSuffix(path, "_test.go") {
+			return nil
+		}
+		if path == output || filepath.Base(path) == "protocol_spec_generate.go" {
+			return nil
+		}
+
+		f, err := parser.ParseFile(fset, path, nil, parser.ParseComments)
+		if err != nil {
+			return err
+		}
+
+		source := filepath.ToSlash(filepath.Join("lib", path))
+		keys = append(keys, parseStorageKeyComments(f, source)...)
+		keys = append(keys, parseStorageKeyPrefixes(f, source)...)
+		if path == filepath.Join("error", "errors.go") {
+			errors = append(errors, parseErrors(f)...)
+		}
+
+		return nil
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// the keys in doc comments are preferred to the prefixes, which they
+	// already describe
+	seen := map[string]bool{}
+	var unique []storageKey
+	for _, k := range keys {
+		if seen[k.Key] {
+			continue
+		}
+		if len(k.prefix) > 0 {
+			covered := false
+			for _, d := range keys {
+				if len(d.prefix) < 1 && strings.HasPrefix(d.Key, k.prefix) {
+					covered = true
+					break
+				}
+			}
+			if covered {
+				continue
+			}
+		}
+		seen[k.Key] = true
+		unique = append(unique, k)
+	}
+	sort.Slice(unique, func(i, j int) bool { return unique[i].Key < unique[j].Key })
+	sort.Slice(errors, func(i, j int) bool { return errors[i].Code < errors[j].Code })
+
+	var buf bytes.Buffer
+	fmt.Fprintln(&buf, "// Code generated by protocol_spec_generate.go; DO NOT EDIT.")
+	fmt.Fprintln(&buf)
+	fmt.Fprintln(&buf, "package sebak")
+	fmt.Fprintln(&buf)
+	fmt.Fprintln(&buf, "var protocolSpecStorageKeys = []ProtocolSpecStorageKey{")
+	for _, k := range unique {
+		fmt.Fprintf(&buf, "{Key: %q, Description: %q, Source: %q},\n", k.Key, k.Description, k.Source)
+	}
+	fmt.Fprintln(&buf, "}")
+	fmt.Fprintln(&buf)
+	fmt.Fprintln(&buf, "var protocolSpecErrors = []ProtocolSpecError{")
+	for _, e := range errors {
+		fmt.Fprintf(&buf, "{Name: %q, Code: %d, Message: %q},\n", e.Name, e.Code, e.Message)
+	}
+	fmt.Fprintln(&buf, "}")
+
+	formatted, err := format.Source(buf.Bytes())
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err = ioutil.WriteFile(output, formatted, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,72 @@
+package sebak
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"boscoin.io/sebak/lib/error"
+	"boscoin.io/sebak/lib/network"
+)
+
+func TestProtocolSpec(t *testing.T) {
+	spec := NewProtocolSpec()
+
+	if len(spec.Results) != len(sebakerror.Results()) {
+		t.Errorf("wrong results: %d", len(spec.Results))
+		return
+	}
+
+	var evicted ProtocolSpecError
+	for _, e := range spec.Errors {
+		if e.Code == sebakerror.ErrorTransactionEvicted.Code {
+			evicted = e
+		}
+	}
+	if evicted.Name != "ErrorTransactionEvicted" || evicted.Message != sebakerror.ErrorTransactionEvicted.Message || evicted.Result != sebakerror.ResultTransactionEvicted {
+		t.Errorf("wrong generated error: %v", evicted)
+		return
+	}
+
+	keys := map[string]ProtocolSpecStorageKey{}
+	for _, k := range spec.StorageKeys {
+		keys[k.Key] = k
+	}
+	// from the doc comment and from the line comment of prefix
+	if k, found := keys["fr-<transaction hash>"]; !found || k.Source != "lib/forwarding_receipt.go" {
+		t.Errorf("wrong generated storage key: %v", k)
+		return
+	}
+	if _, found := keys["bk-hash-<Block.Hash>"]; !found {
+		t.Error("storage key of prefix must be generated")
+		return
+	}
+
+	for _, m := range spec.Messages {
+		if m.Schema == nil {
+			t.Errorf("message without schema: %s", m.Name)
+			return
+		}
+	}
+	if _, found := spec.Components.Schemas["Ballot"]; !found {
+		t.Error("schema of message must be in components")
+		return
+	}
+}
+
+func TestNodeRunnerAPISpec(t *testing.T) {
+	defer sebaknetwork.CleanUpMemoryNetwork()
+
+	nr := createNodeRunners(1)[0]
+
+	w := httptest.NewRecorder()
+	nr.handleAPISpec(w, httptest.NewRequest("GET", APIVersionPrefix+GetSpecPattern, nil))
+
+	var spec ProtocolSpec
+	json.Unmarshal(w.Body.Bytes(), &spec)
+	if w.Code != http.StatusOK || spec.Version != Version || len(spec.Messages) != len(protocolSpecMessages) || len(spec.Errors) != len(protocolSpecErrors) {
+		t.Errorf("wrong spec: %d %s", w.Code, w.Body.String())
+		return
+	}
+}
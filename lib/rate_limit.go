@@ -0,0 +1,191 @@
+package sebak
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"boscoin.io/sebak/lib/error"
+)
+
+// RateLimiter limits the requests of the API clients by token bucket; every
+// client IP has it's own bucket, and the client with the API key gets the
+// bucket of the key, which has the higher quota. The bucket is filled by
+// `Limit` tokens in a second up to `Burst`, and one request takes one token.
+
+const (
+	// RateLimitBurstSeconds is the default burst of `RateLimitRule`; the
+	// tokens of 2 seconds can be used at once.
+	RateLimitBurstSeconds float64 = 2
+
+	// RateLimitPruneInterval is how often the full buckets, which are not
+	// needed anymore are removed.
+	RateLimitPruneInterval time.Duration = time.Minute
+)
+
+// APIKeyHeader is the header of API key; the clients, which can not set the
+// header, like `EventSource` can give it by the 'api_key' query.
+const APIKeyHeader string = "X-API-Key"
+
+type RateLimitRule struct {
+	Limit float64 // requests in a second; 0 is unlimited
+	Burst int
+}
+
+// NewRateLimitRule makes the rule with the default burst.
+func NewRateLimitRule(limit float64) RateLimitRule {
+	return RateLimitRule{
+		Limit: limit,
+		Burst: int(math.Max(1, math.Ceil(limit*RateLimitBurstSeconds))),
+	}
+}
+
+func (r RateLimitRule) IsUnlimited() bool {
+	return r.Limit <= 0
+}
+
+type RateLimitConfig struct {
+	IP      RateLimitRule
+	APIKey  RateLimitRule
+	APIKeys []string
+}
+
+type rateLimitBucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+type RateLimiter struct {
+	sync.Mutex
+
+	config    RateLimitConfig
+	apiKeys   map[string]bool
+	buckets   map[string]*rateLimitBucket
+	lastPrune time.Time
+
+	now func() time.Time
+}
+
+func NewRateLimiter(config RateLimitConfig) *RateLimiter {
+	apiKeys := map[string]bool{}
+	for _, key := range config.APIKeys {
+		apiKeys[key] = true
+	}
+
+	return &RateLimiter{
+		config:    config,
+		apiKeys:   apiKeys,
+		buckets:   map[string]*rateLimitBucket{},
+		lastPrune: time.Now(),
+		now:       time.Now,
+	}
+}
+
+func (l *RateLimiter) Config() RateLimitConfig {
+	return l.config
+}
+
+// Allow takes one token from the bucket of the API key or, without key, of
+// the IP. If the bucket is empty, `retryAfter` is how long the client should
+// wait for the next token. The unknown API key is refused by
+// `ErrorInvalidAPIKey`.
+func (l *RateLimiter) Allow(ip, apiKey string) (retryAfter time.Duration, err error) {
+	name, rule := "ip-"+ip, l.config.IP
+	if len(apiKey) > 0 {
+		if !l.apiKeys[apiKey] {
+			err = sebakerror.ErrorInvalidAPIKey
+			return
+		}
+		name, rule = "key-"+apiKey, l.config.APIKey
+	}
+	if rule.IsUnlimited() {
+		return
+	}
+
+	l.Lock()
+	defer l.Unlock()
+
+	now := l.now()
+	l.prune(now)
+
+	bucket, found := l.buckets[name]
+	if !found {
+		bucket = &rateLimitBucket{tokens: float64(rule.Burst), updated: now}
+		l.buckets[name] = bucket
+	}
+	bucket.tokens = math.Min(float64(rule.Burst), bucket.tokens+now.Sub(bucket.updated).Seconds()*rule.Limit)
+	bucket.updated = now
+
+	if bucket.tokens < 1 {
+		retryAfter = time.Duration((1 - bucket.tokens) / rule.Limit * float64(time.Second))
+		err = sebakerror.ErrorRateLimitExceeded
+		return
+	}
+	bucket.tokens--
+
+	return
+}
+
+// prune removes the buckets, which are full by now; they are same with the
+// new bucket, so the clients gone do not stay in memory.
+func (l *RateLimiter) prune(now time.Time) {
+	if now.Sub(l.lastPrune) < RateLimitPruneInterval {
+		return
+	}
+	l.lastPrune = now
+
+	for name, bucket := range l.buckets {
+		rule := l.config.IP
+		if strings.HasPrefix(name, "key-") {
+			rule = l.config.APIKey
+		}
+		if bucket.tokens+now.Sub(bucket.updated).Seconds()*rule.Limit >= float64(rule.Burst) {
+			delete(l.buckets, name)
+		}
+	}
+}
+
+func (l *RateLimiter) Len() int {
+	l.Lock()
+	defer l.Unlock()
+
+	return len(l.buckets)
+}
+
+func apiKeyOf(r *http.Request) string {
+	if key := r.Header.Get(APIKeyHeader); len(key) > 0 {
+		return key
+	}
+
+	return r.URL.Query().Get("api_key")
+}
+
+// SetRateLimiter enables the rate limit of the API; it must be called before
+// the node starts.
+func (nr *NodeRunner) SetRateLimiter(limiter *RateLimiter) {
+	nr.rateLimiter = limiter
+}
+
+func (nr *NodeRunner) RateLimiter() *RateLimiter {
+	return nr.rateLimiter
+}
+
+// rateLimited wraps the API handler; the exceeded request is 429 with the
+// 'Retry-After' header in seconds.
+func (nr *NodeRunner) rateLimited(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		retryAfter, err := nr.rateLimiter.Allow(nr.apiConfig.ClientIP(r), apiKeyOf(r))
+		switch err {
+		case nil:
+			handler(w, r)
+		case sebakerror.ErrorInvalidAPIKey:
+			writeAPIError(w, r, http.StatusForbidden, err)
+		default:
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			writeAPIError(w, r, http.StatusTooManyRequests, err)
+		}
+	}
+}
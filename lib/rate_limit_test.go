@@ -0,0 +1,88 @@
+package sebak
+
+import (
+	"testing"
+	"time"
+
+	"boscoin.io/sebak/lib/error"
+)
+
+func TestRateLimiter(t *testing.T) {
+	limiter := NewRateLimiter(RateLimitConfig{
+		IP:      RateLimitRule{Limit: 1, Burst: 2},
+		APIKey:  RateLimitRule{Limit: 10, Burst: 20},
+		APIKeys: []string{"showme"},
+	})
+	now := time.Now()
+	limiter.now = func() time.Time { return now }
+
+	// the burst is allowed at once
+	for i := 0; i < 2; i++ {
+		if _, err := limiter.Allow("1.1.1.1", ""); err != nil {
+			t.Errorf("request within burst must be allowed: %v", err)
+			return
+		}
+	}
+
+	retryAfter, err := limiter.Allow("1.1.1.1", "")
+	if err != sebakerror.ErrorRateLimitExceeded {
+		t.Errorf("request over burst must be refused: %v", err)
+		return
+	} else if retryAfter != time.Second {
+		t.Errorf("wrong retry after: %v", retryAfter)
+		return
+	}
+
+	// the other IP and the API key have their own buckets
+	if _, err = limiter.Allow("2.2.2.2", ""); err != nil {
+		t.Errorf("other IP must be allowed: %v", err)
+		return
+	}
+	for i := 0; i < 20; i++ {
+		if _, err = limiter.Allow("1.1.1.1", "showme"); err != nil {
+			t.Errorf("request with API key must have higher quota: %v", err)
+			return
+		}
+	}
+	if _, err = limiter.Allow("1.1.1.1", "unknown"); err != sebakerror.ErrorInvalidAPIKey {
+		t.Errorf("unknown API key must be refused: %v", err)
+		return
+	}
+
+	// the bucket is filled by time
+	now = now.Add(time.Second)
+	if _, err = limiter.Allow("1.1.1.1", ""); err != nil {
+		t.Errorf("filled bucket must be allowed: %v", err)
+		return
+	}
+	if _, err = limiter.Allow("1.1.1.1", ""); err != sebakerror.ErrorRateLimitExceeded {
+		t.Errorf("only one token must be filled: %v", err)
+		return
+	}
+
+	// the full buckets are pruned
+	now = now.Add(RateLimitPruneInterval)
+	if _, err = limiter.Allow("3.3.3.3", ""); err != nil || limiter.Len() != 1 {
+		t.Errorf("full buckets must be pruned: %d", limiter.Len())
+		return
+	}
+}
+
+func TestRateLimiterUnlimited(t *testing.T) {
+	limiter := NewRateLimiter(RateLimitConfig{IP: NewRateLimitRule(0), APIKeys: []string{"showme"}})
+	for i := 0; i < 100; i++ {
+		if _, err := limiter.Allow("1.1.1.1", ""); err != nil {
+			t.Errorf("unlimited must be allowed: %v", err)
+			return
+		}
+	}
+	if limiter.Len() != 0 {
+		t.Error("unlimited must not keep buckets")
+		return
+	}
+
+	if rule := NewRateLimitRule(0.2); rule.Burst != 1 {
+		t.Errorf("burst must be at least 1: %d", rule.Burst)
+		return
+	}
+}
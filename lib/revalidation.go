@@ -0,0 +1,127 @@
+package sebak
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	"boscoin.io/sebak/lib/error"
+	"boscoin.io/sebak/lib/storage"
+)
+
+// RevalidationPolicy decides whether the transaction of the accepted ballot
+// is validated again against the current state when it is committed,
+//  * `full`: the fee, the double spend, the checkpoint and the balance of the
+//  source account are checked again, so the transaction, which became
+//  invalid after it was validated in SIGN is not committed
+//  * `trust`: the validation in SIGN is trusted and the commit is faster;
+//  only the failures of applying, like the negative balance stop the commit
+// Every transaction, which fails at commit after it is accepted, is the
+// divergence from the validation in SIGN and it is counted in
+// `RevalidationDivergences()`.
+type RevalidationPolicy string
+
+const (
+	RevalidationFull  RevalidationPolicy = "full"
+	RevalidationTrust RevalidationPolicy = "trust"
+)
+
+const DefaultRevalidationPolicy = RevalidationFull
+
+func NewRevalidationPolicyFromString(s string) (policy RevalidationPolicy, err error) {
+	policy = RevalidationPolicy(s)
+	switch policy {
+	case RevalidationFull, RevalidationTrust:
+	default:
+		err = fmt.Errorf("unknown revalidation policy: '%s'", s)
+	}
+
+	return
+}
+
+// RevalidateTransaction validates the transaction against the current state
+// before it is committed.
+func RevalidateTransaction(st *sebakstorage.LevelDBBackend, tx Transaction) (err error) {
+	var parameters NetworkParameters
+	if parameters, err = GetNetworkParameters(st); err != nil {
+		return
+	}
+	if err = CheckTransactionOperationsLimit(parameters, tx); err != nil {
+		return
+	}
+	if err = CheckTransactionFee(parameters, tx); err != nil {
+		return
+	}
+
+	if bt, e := GetBlockTransactionByCheckpoint(st, tx.B.Checkpoint); e == nil && bt.Source == tx.B.Source {
+		err = sebakerror.ErrorTransactionDoubleSpend
+		return
+	}
+
+	var exists bool
+	if exists, err = ExistBlockAccount(st, tx.B.Source); err != nil {
+		return
+	} else if !exists {
+		err = sebakerror.ErrorBlockAccountDoesNotExists
+		return
+	}
+
+	var ba *BlockAccount
+	if ba, err = GetBlockAccount(st, tx.B.Source); err != nil {
+		return
+	}
+	if tx.B.Checkpoint != ba.Checkpoint {
+		err = sebakerror.ErrorTransactionInvalidCheckpoint
+		return
+	}
+	if tx.TotalAmount(true) > ba.GetBalance() {
+		err = sebakerror.ErrorAccountBalanceUnderZero
+		return
+	}
+
+	return
+}
+
+func (nr *NodeRunner) RevalidationPolicy() RevalidationPolicy {
+	return nr.revalidationPolicy
+}
+
+func (nr *NodeRunner) SetRevalidationPolicy(policy RevalidationPolicy) {
+	nr.revalidationPolicy = policy
+}
+
+// Revalidations returns the number of transactions validated again at commit
+// since the node started.
+func (nr *NodeRunner) Revalidations() uint64 {
+	return atomic.LoadUint64(&nr.revalidations)
+}
+
+// RevalidationDivergences returns the number of accepted transactions, which
+// failed at commit since the node started.
+func (nr *NodeRunner) RevalidationDivergences() uint64 {
+	return atomic.LoadUint64(&nr.revalidationDivergences)
+}
+
+// revalidateTransaction validates the accepted transaction again by the
+// policy.
+func (nr *NodeRunner) revalidateTransaction(tx Transaction) (err error) {
+	if nr.revalidationPolicy == RevalidationTrust {
+		return
+	}
+
+	atomic.AddUint64(&nr.revalidations, 1)
+	if err = RevalidateTransaction(nr.storage, tx); err != nil {
+		nr.divergeTransaction(tx, err)
+	}
+
+	return
+}
+
+func (nr *NodeRunner) divergeTransaction(tx Transaction, err error) {
+	atomic.AddUint64(&nr.revalidationDivergences, 1)
+	nr.log.Warn(
+		"accepted transaction failed at commit",
+		"transaction", tx.GetHash(),
+		"policy", nr.revalidationPolicy,
+		"error", err,
+	)
+}
@@ -0,0 +1,58 @@
+package sebak
+
+import (
+	"testing"
+
+	"github.com/stellar/go/keypair"
+
+	"boscoin.io/sebak/lib/error"
+	"boscoin.io/sebak/lib/network"
+)
+
+func TestRevalidateTransaction(t *testing.T) {
+	defer sebaknetwork.CleanUpMemoryNetwork()
+
+	nr := createNodeRunners(1)[0]
+	st := nr.Storage()
+
+	kp, _ := keypair.Random()
+	tx := makeTransactionPayment(kp, testMakeBlockAccount().Address, Amount(1))
+	ba := NewBlockAccount(kp.Address(), Amount(BaseFee*100), tx.B.Checkpoint)
+	ba.Save(st)
+
+	if err := nr.revalidateTransaction(tx); err != nil {
+		t.Error(err)
+		return
+	}
+
+	// the source account is changed after the validation in SIGN
+	ba.Withdraw(Amount(BaseFee*100), "changed")
+	ba.Save(st)
+	if err := nr.revalidateTransaction(tx); err != sebakerror.ErrorTransactionInvalidCheckpoint {
+		t.Errorf("changed checkpoint must be refused: %v", err)
+		return
+	}
+	if nr.Revalidations() != 2 || nr.RevalidationDivergences() != 1 {
+		t.Errorf("wrong metrics: %d %d", nr.Revalidations(), nr.RevalidationDivergences())
+		return
+	}
+
+	ba.Checkpoint = tx.B.Checkpoint
+	ba.Save(st)
+	if err := RevalidateTransaction(st, tx); err != sebakerror.ErrorAccountBalanceUnderZero {
+		t.Errorf("insufficient balance must be refused: %v", err)
+		return
+	}
+
+	// the validation in SIGN is trusted
+	nr.SetRevalidationPolicy(RevalidationTrust)
+	if err := nr.revalidateTransaction(tx); err != nil || nr.Revalidations() != 2 {
+		t.Errorf("transaction must not be validated again: %v", err)
+		return
+	}
+
+	if _, err := NewRevalidationPolicyFromString("unknown"); err == nil {
+		t.Error("unknown policy must be refused")
+		return
+	}
+}
@@ -0,0 +1,144 @@
+package sebak
+
+import (
+	"fmt"
+
+	"github.com/stellar/go/keypair"
+
+	"boscoin.io/sebak/lib/storage"
+)
+
+// The reward is distributed to `NetworkParameters.RewardAccount` at every
+// `RewardInterval` blocks by the protocol, not by the transaction. Every node
+// applies it to the block, whose height is the multiple of `RewardInterval`,
+// before the state hash is made, so the reward is the part of the state of
+// the block. Without `RewardSource`, `RewardAmount` is newly minted from the
+// pseudo account, `LedgerAccountInflation`; with it, the amount is moved from
+// `RewardSource`, like the common budget account. The reward is skipped, if
+// the accounts do not exist or the spendable balance of `RewardSource` is not
+// enough; it depends only on the state, so every node skips it together.
+
+// LedgerAccountInflation is the pseudo account, which the minted rewards are
+// debited from.
+const LedgerAccountInflation string = "inflation"
+
+// IsRewardEnabled checks the reward distribution is configured.
+func (p NetworkParameters) IsRewardEnabled() bool {
+	return p.RewardInterval > 0
+}
+
+// IsRewardHeight checks the reward is distributed at the block of `height`.
+func (p NetworkParameters) IsRewardHeight(height uint64) bool {
+	return p.IsRewardEnabled() && height%p.RewardInterval == 0
+}
+
+func (p NetworkParameters) isRewardWellFormed() (err error) {
+	if !p.IsRewardEnabled() {
+		if len(p.RewardAccount) > 0 || p.RewardAmount > 0 || len(p.RewardSource) > 0 {
+			err = fmt.Errorf("`RewardInterval` must be given with the reward")
+			return
+		}
+		return
+	}
+
+	if _, err = keypair.Parse(p.RewardAccount); err != nil {
+		err = fmt.Errorf("invalid `RewardAccount`, '%s': %v", p.RewardAccount, err)
+		return
+	}
+	if p.RewardAmount < 1 {
+		err = fmt.Errorf("`RewardAmount` must be greater than 0")
+		return
+	}
+	if len(p.RewardSource) > 0 {
+		if _, err = keypair.Parse(p.RewardSource); err != nil {
+			err = fmt.Errorf("invalid `RewardSource`, '%s': %v", p.RewardSource, err)
+			return
+		}
+		if p.RewardSource == p.RewardAccount {
+			err = fmt.Errorf("`RewardSource` must be different from `RewardAccount`")
+			return
+		}
+	}
+
+	return
+}
+
+// DistributeReward distributes the reward of the block of `height`, which is
+// made by the transaction, `txHash`; `distributed` is `false`, if it is not
+// the reward height or the reward is skipped. The checkpoints of accounts are
+// not changed, so their pending transactions are still valid.
+func DistributeReward(st *sebakstorage.LevelDBBackend, p NetworkParameters, height uint64, txHash, confirmed string) (distributed bool, err error) {
+	if !p.IsRewardHeight(height) {
+		return
+	}
+
+	var exists bool
+	if exists, err = ExistBlockAccount(st, p.RewardAccount); err != nil {
+		return
+	} else if !exists {
+		log.Debug("reward skipped; account does not exist", "height", height, "account", p.RewardAccount)
+		return
+	}
+
+	var baAccount *BlockAccount
+	if baAccount, err = GetBlockAccount(st, p.RewardAccount); err != nil {
+		return
+	}
+	if baAccount.Deposit(p.RewardAmount, baAccount.Checkpoint) != nil {
+		log.Debug("reward skipped; balance overflows", "height", height, "account", p.RewardAccount)
+		return
+	}
+
+	debit := LedgerAccountInflation
+	if len(p.RewardSource) > 0 {
+		debit = p.RewardSource
+
+		if exists, err = ExistBlockAccount(st, p.RewardSource); err != nil {
+			return
+		} else if !exists {
+			log.Debug("reward skipped; source does not exist", "height", height, "source", p.RewardSource)
+			return
+		}
+
+		var baSource *BlockAccount
+		if baSource, err = GetBlockAccount(st, p.RewardSource); err != nil {
+			return
+		}
+
+		var frozen Amount
+		if frozen, err = GetFrozenAmount(st, p.RewardSource); err != nil {
+			return
+		}
+		if baSource.GetBalance() < frozen || baSource.GetBalance()-frozen < p.RewardAmount {
+			log.Debug("reward skipped; source is underfunded", "height", height, "source", p.RewardSource)
+			return
+		}
+		if err = baSource.Withdraw(p.RewardAmount, baSource.Checkpoint); err != nil {
+			return
+		}
+		if err = baSource.Save(st); err != nil {
+			return
+		}
+	}
+
+	if err = baAccount.Save(st); err != nil {
+		return
+	}
+
+	entry := LedgerEntry{
+		TxHash:    txHash,
+		Reason:    LedgerReasonReward,
+		Debit:     debit,
+		Credit:    p.RewardAccount,
+		Amount:    p.RewardAmount,
+		Confirmed: confirmed,
+	}
+	if err = SaveLedgerEntries(st, entry); err != nil {
+		return
+	}
+
+	distributed = true
+	log.Debug("reward distributed", "height", height, "account", p.RewardAccount, "source", debit, "amount", p.RewardAmount)
+
+	return
+}
@@ -0,0 +1,101 @@
+package sebak
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stellar/go/keypair"
+
+	"boscoin.io/sebak/lib/storage"
+)
+
+func TestNetworkParametersReward(t *testing.T) {
+	account, _ := keypair.Random()
+	source, _ := keypair.Random()
+
+	p := NetworkParameters{BlockTime: time.Second, RewardAccount: account.Address(), RewardAmount: BaseFee, RewardInterval: 10}
+	if err := p.IsWellFormed(); err != nil {
+		t.Error(err)
+		return
+	}
+	if p.IsRewardHeight(9) || !p.IsRewardHeight(10) || !p.IsRewardHeight(20) {
+		t.Error("reward must be distributed at every interval")
+		return
+	}
+
+	for _, invalid := range []NetworkParameters{
+		{BlockTime: time.Second, RewardAccount: account.Address(), RewardAmount: BaseFee},
+		{BlockTime: time.Second, RewardAccount: "invalid", RewardAmount: BaseFee, RewardInterval: 10},
+		{BlockTime: time.Second, RewardAccount: account.Address(), RewardInterval: 10},
+		{BlockTime: time.Second, RewardAccount: account.Address(), RewardAmount: BaseFee, RewardInterval: 10, RewardSource: account.Address()},
+	} {
+		if err := invalid.IsWellFormed(); err == nil {
+			t.Errorf("'%v' must not be well-formed", invalid)
+			return
+		}
+	}
+
+	p.RewardSource = source.Address()
+	if err := p.IsWellFormed(); err != nil {
+		t.Error(err)
+		return
+	}
+}
+
+func TestDistributeReward(t *testing.T) {
+	st, _ := sebakstorage.NewTestMemoryLevelDBBackend()
+	defer st.Close()
+
+	account, _ := keypair.Random()
+	source, _ := keypair.Random()
+	checkpoint := uuid.New().String()
+	NewBlockAccount(account.Address(), BaseFee, checkpoint).Save(st)
+	NewBlockAccount(source.Address(), BaseFee*3, uuid.New().String()).Save(st)
+
+	p := NetworkParameters{BlockTime: time.Second, RewardAccount: account.Address(), RewardAmount: BaseFee * 2, RewardInterval: 2}
+
+	if distributed, err := DistributeReward(st, p, 1, "", ""); err != nil || distributed {
+		t.Errorf("reward must not be distributed out of the interval: %v", err)
+		return
+	}
+
+	// minted
+	if distributed, err := DistributeReward(st, p, 2, "", ""); err != nil || !distributed {
+		t.Errorf("reward must be distributed: %v", err)
+		return
+	}
+	ba, _ := GetBlockAccount(st, account.Address())
+	if ba.GetBalance() != BaseFee*3 || ba.Checkpoint != checkpoint {
+		t.Errorf("wrong account: %v", ba)
+		return
+	}
+	if balance, _ := GetLedgerBalance(st, LedgerAccountInflation); balance != -int64(BaseFee*2) {
+		t.Errorf("wrong ledger balance of inflation: %d", balance)
+		return
+	}
+
+	// moved from the source
+	p.RewardSource = source.Address()
+	if distributed, err := DistributeReward(st, p, 4, "", ""); err != nil || !distributed {
+		t.Errorf("reward must be distributed: %v", err)
+		return
+	}
+	ba, _ = GetBlockAccount(st, account.Address())
+	baSource, _ := GetBlockAccount(st, source.Address())
+	if ba.GetBalance() != BaseFee*5 || baSource.GetBalance() != BaseFee {
+		t.Errorf("wrong accounts: %v %v", ba, baSource)
+		return
+	}
+
+	// the underfunded source is skipped
+	if distributed, err := DistributeReward(st, p, 6, "", ""); err != nil || distributed {
+		t.Errorf("reward from the underfunded source must be skipped: %v", err)
+		return
+	}
+	ba, _ = GetBlockAccount(st, account.Address())
+	if ba.GetBalance() != BaseFee*5 {
+		t.Errorf("wrong account: %v", ba)
+		return
+	}
+}
@@ -0,0 +1,265 @@
+package sebak
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/btcsuite/btcutil/base58"
+	"github.com/google/uuid"
+	"github.com/stellar/go/keypair"
+
+	"boscoin.io/sebak/lib/common"
+	"boscoin.io/sebak/lib/error"
+	"boscoin.io/sebak/lib/storage"
+)
+
+// SelfTestMode decides which checks of self test run,
+//  * `off`: nothing
+//  * `quick`: the checks of node startup; storage read/write, keypair
+//  sign/verify, one hashing vector and clock
+//  * `full`: the quick checks with storage transaction, iteration and every
+//  hashing vector
+type SelfTestMode string
+
+const (
+	SelfTestModeOff   SelfTestMode = "off"
+	SelfTestModeQuick SelfTestMode = "quick"
+	SelfTestModeFull  SelfTestMode = "full"
+)
+
+func NewSelfTestModeFromString(s string) (mode SelfTestMode, err error) {
+	switch mode = SelfTestMode(s); mode {
+	case SelfTestModeOff, SelfTestModeQuick, SelfTestModeFull:
+	default:
+		err = fmt.Errorf("unknown self test mode, '%s'", s)
+	}
+
+	return
+}
+
+const selfTestKeyPrefix string = "selftest-"
+
+// selfTestHashVectors are the results of `sebakcommon.MakeHash`; if they are
+// different, the node can not agree with the other nodes on any hash.
+var selfTestHashVectors = []struct {
+	Input    string
+	Expected string
+}{
+	{"sebak", "93EBz6G7ako8BBSYJjcZPNtXfbSaih6K4mPJcUpKzZur"},
+	{"", "6jYkHR5JLTQS1DQAZmEENj9N2NeoQQ2HmqYh8YbcqrJK"},
+	{"boscoin.io/sebak self test", "7rECCza9gUDLqKUU8j7U2CXnFwCaGe4HYVJtbAfxsckA"},
+}
+
+// MinSelfTestClock is the earliest sane clock; the clock before it is surely
+// not set.
+var MinSelfTestClock = time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// MaxSelfTestClockDrift is how far the clock can be behind the latest block.
+const MaxSelfTestClockDrift = time.Minute
+
+type SelfTestResult struct {
+	Name    string        `json:"name"`
+	Error   string        `json:"error,omitempty"`
+	Elapsed time.Duration `json:"elapsed"`
+}
+
+func (r SelfTestResult) IsPassed() bool {
+	return len(r.Error) < 1
+}
+
+type selfTestCheck struct {
+	name  string
+	quick bool
+	f     func(*sebakstorage.LevelDBBackend, []byte, SelfTestMode) error
+}
+
+var selfTestChecks = []selfTestCheck{
+	{"storage", true, selfTestStorage},
+	{"storage-transaction", false, selfTestStorageTransaction},
+	{"keypair", true, selfTestKeypair},
+	{"hashing", true, selfTestHashing},
+	{"clock", true, selfTestClock},
+}
+
+// RunSelfTest runs the checks of `mode` and returns the result of each check.
+// If any check fails, `ErrorSelfTestFailed` is returned.
+func RunSelfTest(st *sebakstorage.LevelDBBackend, networkID []byte, mode SelfTestMode) (results []SelfTestResult, err error) {
+	if mode == SelfTestModeOff {
+		return
+	}
+
+	for _, check := range selfTestChecks {
+		if mode == SelfTestModeQuick && !check.quick {
+			continue
+		}
+
+		started := time.Now()
+		result := SelfTestResult{Name: check.name}
+		if e := check.f(st, networkID, mode); e != nil {
+			result.Error = e.Error()
+			err = sebakerror.ErrorSelfTestFailed
+		}
+		result.Elapsed = time.Since(started)
+		results = append(results, result)
+	}
+
+	return
+}
+
+func selfTestStorage(st *sebakstorage.LevelDBBackend, _ []byte, _ SelfTestMode) (err error) {
+	key := selfTestKeyPrefix + uuid.New().String()
+	defer st.Remove(key)
+
+	if err = st.New(key, key); err != nil {
+		return
+	}
+
+	var value string
+	if err = st.Get(key, &value); err != nil {
+		return
+	} else if value != key {
+		err = errors.New("stored value does not match")
+		return
+	}
+
+	if err = st.Remove(key); err != nil {
+		return
+	}
+
+	var exists bool
+	if exists, err = st.Has(key); err != nil {
+		return
+	} else if exists {
+		err = errors.New("removed key still exists")
+		return
+	}
+
+	return
+}
+
+func selfTestStorageTransaction(st *sebakstorage.LevelDBBackend, _ []byte, _ SelfTestMode) (err error) {
+	prefix := selfTestKeyPrefix + uuid.New().String() + "-"
+	keys := []string{prefix + "0", prefix + "1", prefix + "2"}
+	defer func() {
+		for _, key := range keys {
+			st.Remove(key)
+		}
+	}()
+
+	// discarded transaction must not leave anything
+	var ts *sebakstorage.LevelDBBackend
+	if ts, err = st.OpenTransaction(); err != nil {
+		return
+	}
+	if err = ts.New(keys[0], 0); err != nil {
+		ts.Discard()
+		return
+	}
+	ts.Discard()
+
+	var exists bool
+	if exists, err = st.Has(keys[0]); err != nil {
+		return
+	} else if exists {
+		err = errors.New("discarded transaction is stored")
+		return
+	}
+
+	if ts, err = st.OpenTransaction(); err != nil {
+		return
+	}
+	for i, key := range keys {
+		if err = ts.New(key, i); err != nil {
+			ts.Discard()
+			return
+		}
+	}
+	if err = ts.Commit(); err != nil {
+		return
+	}
+
+	var found int
+	iterFunc, closeFunc := st.GetIterator(prefix, false)
+	for {
+		if _, hasNext := iterFunc(); !hasNext {
+			break
+		}
+		found++
+	}
+	closeFunc()
+	if found != len(keys) {
+		err = fmt.Errorf("committed transaction stored %d keys, not %d", found, len(keys))
+		return
+	}
+
+	return
+}
+
+func selfTestKeypair(_ *sebakstorage.LevelDBBackend, networkID []byte, _ SelfTestMode) (err error) {
+	var kp *keypair.Full
+	if kp, err = keypair.Random(); err != nil {
+		return
+	}
+
+	message := append(append([]byte{}, networkID...), []byte("self test")...)
+
+	var signature []byte
+	if signature, err = kp.Sign(message); err != nil {
+		return
+	}
+
+	var parsed keypair.KP
+	if parsed, err = keypair.Parse(kp.Address()); err != nil {
+		return
+	}
+	if err = parsed.Verify(message, signature); err != nil {
+		return
+	}
+	if parsed.Verify(append(message, '!'), signature) == nil {
+		err = errors.New("signature of the different message is verified")
+		return
+	}
+
+	return
+}
+
+func selfTestHashing(_ *sebakstorage.LevelDBBackend, _ []byte, mode SelfTestMode) (err error) {
+	vectors := selfTestHashVectors
+	if mode == SelfTestModeQuick {
+		vectors = vectors[:1]
+	}
+
+	for _, v := range vectors {
+		if hashed := base58.Encode(sebakcommon.MakeHash([]byte(v.Input))); hashed != v.Expected {
+			err = fmt.Errorf("hash of '%s' is '%s', not '%s'", v.Input, hashed, v.Expected)
+			return
+		}
+	}
+
+	return
+}
+
+func selfTestClock(st *sebakstorage.LevelDBBackend, _ []byte, _ SelfTestMode) (err error) {
+	now := time.Now()
+	if now.Before(MinSelfTestClock) {
+		err = fmt.Errorf("clock is not set: %s", now)
+		return
+	}
+
+	var latest Block
+	if latest, err = GetLatestBlock(st); err != nil || latest.IsEmpty() {
+		return
+	}
+
+	var confirmed time.Time
+	if confirmed, err = time.Parse(time.RFC3339Nano, latest.Confirmed); err != nil {
+		return
+	}
+	if now.Add(MaxSelfTestClockDrift).Before(confirmed) {
+		err = fmt.Errorf("clock is behind the latest block: %s < %s", now, confirmed)
+		return
+	}
+
+	return
+}
@@ -0,0 +1,105 @@
+package sebak
+
+import (
+	"testing"
+	"time"
+
+	"boscoin.io/sebak/lib/error"
+	"boscoin.io/sebak/lib/network"
+	"boscoin.io/sebak/lib/storage"
+)
+
+func TestSelfTest(t *testing.T) {
+	st, _ := sebakstorage.NewTestMemoryLevelDBBackend()
+
+	if results, err := RunSelfTest(st, networkID, SelfTestModeOff); err != nil || len(results) != 0 {
+		t.Error("nothing must run in `off` mode")
+		return
+	}
+
+	quick, err := RunSelfTest(st, networkID, SelfTestModeQuick)
+	if err != nil {
+		t.Error(err, quick)
+		return
+	}
+	full, err := RunSelfTest(st, networkID, SelfTestModeFull)
+	if err != nil {
+		t.Error(err, full)
+		return
+	}
+	if len(quick) >= len(full) {
+		t.Error("`quick` mode must run the less checks")
+		return
+	}
+
+	// nothing is left in storage
+	iterFunc, closeFunc := st.GetIterator(selfTestKeyPrefix, false)
+	_, hasNext := iterFunc()
+	closeFunc()
+	if hasNext {
+		t.Error("self test must not leave keys in storage")
+		return
+	}
+
+	if _, err = NewSelfTestModeFromString("slow"); err == nil {
+		t.Error("unknown mode must be refused")
+		return
+	}
+}
+
+func TestSelfTestFailed(t *testing.T) {
+	st, _ := sebakstorage.NewTestMemoryLevelDBBackend()
+
+	vectors := selfTestHashVectors
+	defer func() { selfTestHashVectors = vectors }()
+
+	selfTestHashVectors = append([]struct {
+		Input    string
+		Expected string
+	}{{"sebak", "wrong"}}, vectors[1:]...)
+
+	results, err := RunSelfTest(st, networkID, SelfTestModeQuick)
+	if err != sebakerror.ErrorSelfTestFailed {
+		t.Error("wrong hashing vector must fail")
+		return
+	}
+	for _, result := range results {
+		if result.IsPassed() == (result.Name == "hashing") {
+			t.Errorf("only hashing must fail: %v", result)
+			return
+		}
+	}
+	selfTestHashVectors = vectors
+
+	// the latest block from the future
+	block := NewBlock(Block{}, "")
+	block.Confirmed = time.Now().Add(time.Hour).Format(time.RFC3339Nano)
+	block.Hash = block.MakeHashString()
+	block.Save(st)
+
+	if _, err = RunSelfTest(st, networkID, SelfTestModeQuick); err != sebakerror.ErrorSelfTestFailed {
+		t.Error("clock behind the latest block must fail")
+		return
+	}
+}
+
+func TestNodeRunnerStartSelfTestFailed(t *testing.T) {
+	defer sebaknetwork.CleanUpMemoryNetwork()
+
+	nr := createNodeRunners(1)[0]
+	nr.SetSelfTestMode(SelfTestModeQuick)
+
+	block := NewBlock(Block{}, "")
+	block.Confirmed = time.Now().Add(time.Hour).Format(time.RFC3339Nano)
+	block.Hash = block.MakeHashString()
+	block.Save(nr.Storage())
+
+	if err := nr.Start(); err != sebakerror.ErrorSelfTestFailed {
+		t.Errorf("node must not start: %v", err)
+		return
+	}
+	if nr.State().State() != NodeStateHalted {
+		t.Error("node must be halted")
+		return
+	}
+}
@@ -0,0 +1,216 @@
+package sebak
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/btcsuite/btcutil/base58"
+	"github.com/stellar/go/keypair"
+
+	"boscoin.io/sebak/lib/common"
+	"boscoin.io/sebak/lib/error"
+)
+
+// SessionKeyCertificate certifies the short-lived session key of validator.
+// The identity key, the address of validator, stays offline and signs only
+// the certificate; the session key on the node signs the ballots, the view
+// changes and the block announcements with the certificate in their header.
+// The certificate expires when the block of `ExpiresAt` is confirmed, so the
+// leaked session key can not sign after it, and the validator certifies the
+// new session key before that.
+type SessionKeyCertificate struct {
+	H SessionKeyCertificateHeader
+	B SessionKeyCertificateBody
+}
+
+type SessionKeyCertificateHeader struct {
+	Hash      string `json:"hash"`
+	Signature string `json:"signature"` // signed by the identity key
+}
+
+type SessionKeyCertificateBody struct {
+	Identity   string `json:"identity"`
+	SessionKey string `json:"session_key"`
+	ExpiresAt  uint64 `json:"expires_at"` // the height of block
+}
+
+func (cb SessionKeyCertificateBody) MakeHashString() string {
+	return base58.Encode(sebakcommon.MustMakeObjectHash(cb))
+}
+
+func NewSessionKeyCertificate(identity, sessionKey string, expiresAt uint64) SessionKeyCertificate {
+	body := SessionKeyCertificateBody{
+		Identity:   identity,
+		SessionKey: sessionKey,
+		ExpiresAt:  expiresAt,
+	}
+
+	return SessionKeyCertificate{
+		H: SessionKeyCertificateHeader{Hash: body.MakeHashString()},
+		B: body,
+	}
+}
+
+func NewSessionKeyCertificateFromJSON(b []byte) (c SessionKeyCertificate, err error) {
+	err = json.Unmarshal(b, &c)
+	return
+}
+
+func (c *SessionKeyCertificate) Sign(kp keypair.KP, networkID []byte) {
+	c.H.Hash = c.B.MakeHashString()
+	signature, _ := kp.Sign(append(networkID, []byte(c.H.Hash)...))
+
+	c.H.Signature = base58.Encode(signature)
+}
+
+// IsWellFormed checks the hash and the signature of the identity key.
+func (c SessionKeyCertificate) IsWellFormed(networkID []byte) (err error) {
+	if c.H.Hash != c.B.MakeHashString() {
+		err = sebakerror.ErrorHashDoesNotMatch
+		return
+	}
+	if _, err = keypair.Parse(c.B.SessionKey); err != nil {
+		err = sebakerror.ErrorBadPublicAddress
+		return
+	}
+	if c.B.SessionKey == c.B.Identity {
+		err = errors.New("session key must not be the identity key")
+		return
+	}
+
+	return verifyNodeKeySignature(c.B.Identity, networkID, c.H.Hash, c.H.Signature)
+}
+
+// IsExpired checks whether the certificate is expired at the latest block of
+// `height`.
+func (c SessionKeyCertificate) IsExpired(height uint64) bool {
+	return height >= c.B.ExpiresAt
+}
+
+func (c SessionKeyCertificate) Serialize() (encoded []byte, err error) {
+	encoded, err = json.Marshal(c)
+	return
+}
+
+func verifyNodeKeySignature(nodeKey string, networkID []byte, hash, signature string) (err error) {
+	var kp keypair.KP
+	if kp, err = keypair.Parse(nodeKey); err != nil {
+		err = sebakerror.ErrorBadPublicAddress
+		return
+	}
+
+	if err = kp.Verify(append(networkID, []byte(hash)...), base58.Decode(signature)); err != nil {
+		err = sebakerror.ErrorSignatureVerificationFailed
+		return
+	}
+
+	return
+}
+
+// VerifyNodeSignature verifies the signature of the message of validator;
+// with the certificate, the message must be signed by it's session key, which
+// is certified by the validator.
+func VerifyNodeSignature(nodeKey string, certificate *SessionKeyCertificate, networkID []byte, hash, signature string) (err error) {
+	if certificate == nil {
+		return verifyNodeKeySignature(nodeKey, networkID, hash, signature)
+	}
+
+	if certificate.B.Identity != nodeKey {
+		err = sebakerror.ErrorSessionKeyIdentityNotMatched
+		return
+	}
+	if err = certificate.IsWellFormed(networkID); err != nil {
+		return
+	}
+
+	return verifyNodeKeySignature(certificate.B.SessionKey, networkID, hash, signature)
+}
+
+// checkSessionKeyCertificate refuses the message of the expired session key
+// by the latest block.
+func (nr *NodeRunner) checkSessionKeyCertificate(certificate *SessionKeyCertificate) (err error) {
+	if certificate == nil {
+		return
+	}
+
+	var latest Block
+	if latest, err = GetLatestBlock(nr.storage); err != nil {
+		return
+	}
+	if certificate.IsExpired(latest.Height) {
+		err = sebakerror.ErrorSessionKeyExpired
+		return
+	}
+
+	return
+}
+
+// SessionKeyNodeSigner signs with the session key and attaches the
+// certificate; the node does not have the secret seed of identity.
+type SessionKeyNodeSigner struct {
+	session     *keypair.Full
+	certificate SessionKeyCertificate
+	networkID   []byte
+}
+
+func NewSessionKeyNodeSigner(session *keypair.Full, certificate SessionKeyCertificate, networkID []byte) (s *SessionKeyNodeSigner, err error) {
+	if certificate.B.SessionKey != session.Address() {
+		err = errors.New("certificate is not of the session key")
+		return
+	}
+	if err = certificate.IsWellFormed(networkID); err != nil {
+		return
+	}
+
+	s = &SessionKeyNodeSigner{
+		session:     session,
+		certificate: certificate,
+		networkID:   networkID,
+	}
+
+	return
+}
+
+func (s *SessionKeyNodeSigner) Address() string {
+	return s.certificate.B.Identity
+}
+
+func (s *SessionKeyNodeSigner) Certificate() SessionKeyCertificate {
+	return s.certificate
+}
+
+func (s *SessionKeyNodeSigner) sign(hash string) string {
+	signature, _ := s.session.Sign(append(s.networkID, []byte(hash)...))
+	return base58.Encode(signature)
+}
+
+func (s *SessionKeyNodeSigner) SignBallot(ballot *Ballot) error {
+	certificate := s.certificate
+
+	ballot.B.NodeKey = s.certificate.B.Identity
+	ballot.UpdateHash()
+	ballot.H.Signature = s.sign(ballot.GetHash())
+	ballot.H.Certificate = &certificate
+
+	return nil
+}
+
+func (s *SessionKeyNodeSigner) SignViewChange(vc *ViewChange) error {
+	certificate := s.certificate
+
+	vc.H.Hash = vc.B.MakeHashString()
+	vc.H.Signature = s.sign(vc.H.Hash)
+	vc.H.Certificate = &certificate
+
+	return nil
+}
+
+func (s *SessionKeyNodeSigner) SignBlockAnnouncement(ba *BlockAnnouncement) error {
+	certificate := s.certificate
+
+	ba.H.Hash = ba.B.MakeHashString()
+	ba.H.Signature = s.sign(ba.H.Hash)
+	ba.H.Certificate = &certificate
+
+	return nil
+}
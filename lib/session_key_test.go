@@ -0,0 +1,176 @@
+package sebak
+
+import (
+	"testing"
+
+	"github.com/stellar/go/keypair"
+
+	"boscoin.io/sebak/lib/common"
+	"boscoin.io/sebak/lib/error"
+	"boscoin.io/sebak/lib/network"
+)
+
+func makeSessionKeyNodeSigner(t *testing.T, identity *keypair.Full, expiresAt uint64) (session *keypair.Full, signer *SessionKeyNodeSigner) {
+	session, _ = keypair.Random()
+	certificate := NewSessionKeyCertificate(identity.Address(), session.Address(), expiresAt)
+	certificate.Sign(identity, networkID)
+
+	var err error
+	if signer, err = NewSessionKeyNodeSigner(session, certificate, networkID); err != nil {
+		t.Fatal(err)
+	}
+
+	return
+}
+
+func TestSessionKeyCertificate(t *testing.T) {
+	identity, _ := keypair.Random()
+	session, _ := keypair.Random()
+
+	certificate := NewSessionKeyCertificate(identity.Address(), session.Address(), 10)
+	certificate.Sign(identity, networkID)
+	if err := certificate.IsWellFormed(networkID); err != nil {
+		t.Errorf("certificate must be well-formed: %v", err)
+		return
+	}
+
+	// the certificate of the other network
+	if err := certificate.IsWellFormed([]byte("other-network")); err != sebakerror.ErrorSignatureVerificationFailed {
+		t.Errorf("certificate of the other network must be refused: %v", err)
+		return
+	}
+
+	// signed by the session key itself
+	forged := NewSessionKeyCertificate(identity.Address(), session.Address(), 10)
+	forged.Sign(session, networkID)
+	if err := forged.IsWellFormed(networkID); err != sebakerror.ErrorSignatureVerificationFailed {
+		t.Errorf("certificate, which is not signed by the identity must be refused: %v", err)
+		return
+	}
+
+	// the extended expiry
+	extended := certificate
+	extended.B.ExpiresAt = 100
+	if err := extended.IsWellFormed(networkID); err != sebakerror.ErrorHashDoesNotMatch {
+		t.Errorf("modified certificate must be refused: %v", err)
+		return
+	}
+
+	if certificate.IsExpired(9) || !certificate.IsExpired(10) {
+		t.Error("certificate must be expired at the block of `ExpiresAt`")
+		return
+	}
+
+	// the certificate of the other session key
+	other, _ := keypair.Random()
+	if _, err := NewSessionKeyNodeSigner(other, certificate, networkID); err == nil {
+		t.Error("signer must not be made by the other session key")
+		return
+	}
+}
+
+func TestSessionKeyNodeSignerBallot(t *testing.T) {
+	identity, _ := keypair.Random()
+	_, signer := makeSessionKeyNodeSigner(t, identity, 10)
+
+	_, tx := TestMakeTransaction(networkID, 1)
+	ballot, _ := NewBallotFromMessage(identity.Address(), tx)
+	ballot.SetState(sebakcommon.BallotStateSIGN)
+	ballot.Vote(VotingYES)
+	if err := signer.SignBallot(&ballot); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if ballot.B.NodeKey != identity.Address() || ballot.H.Certificate == nil {
+		t.Error("ballot must be of the identity with the certificate")
+		return
+	}
+	if err := ballot.VerifySignature(networkID); err != nil {
+		t.Errorf("ballot signed by the session key must be verified: %v", err)
+		return
+	}
+
+	// the ballot through the network
+	data, _ := ballot.Serialize()
+	received, err := NewBallotFromJSON(data)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if err = received.VerifySignature(networkID); err != nil {
+		t.Errorf("received ballot must be verified: %v", err)
+		return
+	}
+
+	// the certificate of the other validator
+	other, _ := keypair.Random()
+	_, otherSigner := makeSessionKeyNodeSigner(t, other, 10)
+	certificate := otherSigner.Certificate()
+	received.H.Certificate = &certificate
+	if err = received.VerifySignature(networkID); err != sebakerror.ErrorSessionKeyIdentityNotMatched {
+		t.Errorf("certificate of the other validator must be refused: %v", err)
+		return
+	}
+
+	// without the certificate, the signature of session key is not of the
+	// validator
+	received.H.Certificate = nil
+	if err = received.VerifySignature(networkID); err != sebakerror.ErrorSignatureVerificationFailed {
+		t.Errorf("ballot without certificate must be refused: %v", err)
+		return
+	}
+}
+
+func TestSessionKeyNodeSignerViewChange(t *testing.T) {
+	identity, _ := keypair.Random()
+	_, signer := makeSessionKeyNodeSigner(t, identity, 10)
+
+	vc := NewViewChange(identity.Address(), 3, 1)
+	if err := signer.SignViewChange(&vc); err != nil {
+		t.Error(err)
+		return
+	}
+	if err := vc.IsWellFormed(networkID); err != nil {
+		t.Errorf("view change signed by the session key must be well-formed: %v", err)
+		return
+	}
+}
+
+func TestNodeRunnerSessionKeyExpired(t *testing.T) {
+	defer sebaknetwork.CleanUpMemoryNetwork()
+
+	nr := createNodeRunners(1)[0]
+
+	var prev Block
+	for i := 0; i < 3; i++ {
+		_, tx := TestMakeTransaction(networkID, 1)
+		b := NewBlock(prev, "", tx.GetHash())
+		if err := b.Save(nr.Storage()); err != nil {
+			t.Error(err)
+			return
+		}
+		prev = b
+	}
+
+	identity, _ := keypair.Random()
+
+	_, signer := makeSessionKeyNodeSigner(t, identity, 4)
+	certificate := signer.Certificate()
+	if err := nr.checkSessionKeyCertificate(&certificate); err != nil {
+		t.Errorf("certificate must not be expired before the block of `ExpiresAt`: %v", err)
+		return
+	}
+
+	_, signer = makeSessionKeyNodeSigner(t, identity, 3)
+	certificate = signer.Certificate()
+	if err := nr.checkSessionKeyCertificate(&certificate); err != sebakerror.ErrorSessionKeyExpired {
+		t.Errorf("expired certificate must be refused: %v", err)
+		return
+	}
+
+	if err := nr.checkSessionKeyCertificate(nil); err != nil {
+		t.Errorf("message without certificate must not be checked: %v", err)
+		return
+	}
+}
@@ -0,0 +1,294 @@
+package sebak
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/btcsuite/btcutil/base58"
+	"github.com/stellar/go/keypair"
+
+	"boscoin.io/sebak/lib/common"
+	"boscoin.io/sebak/lib/error"
+	"boscoin.io/sebak/lib/storage"
+)
+
+// SpendingLimit is the limit of spending of account, which is set by
+// `OperationSetSpendingLimit`. The transaction, which spends more than
+// `PerTransaction`, or more than `Daily` with the spending of the day, must be
+// co-signed by the `AccountSigners` of account, whose weights reach their
+// threshold without the source, so the compromised key of account alone can
+// not drain it. The limit and the signers are changed only with the
+// co-signatures, so the account must have the signers while it has the
+// limit. The storage should support,
+//  * find by `Address`
+//  * 'sl-<SpendingLimit.Address>': `SpendingLimit`
+//  * 'sls-<SpendingLimit.Address>': `SpendingLimitSpent`, the spending of
+//  the day in UTC
+
+const (
+	SpendingLimitPrefixAddress string = "sl-"
+	SpendingLimitPrefixSpent   string = "sls-"
+)
+
+type SpendingLimit struct {
+	Address        string
+	PerTransaction Amount // 0 is unlimited
+	Daily          Amount // 0 is unlimited
+}
+
+func NewSpendingLimit(address string, body OperationBodySetSpendingLimit) SpendingLimit {
+	return SpendingLimit{
+		Address:        address,
+		PerTransaction: body.PerTransaction,
+		Daily:          body.Daily,
+	}
+}
+
+func GetSpendingLimitKey(address string) string {
+	return fmt.Sprintf("%s%s", SpendingLimitPrefixAddress, address)
+}
+
+func GetSpendingLimitSpentKey(address string) string {
+	return fmt.Sprintf("%s%s", SpendingLimitPrefixSpent, address)
+}
+
+func (l SpendingLimit) Save(st *sebakstorage.LevelDBBackend) (err error) {
+	key := GetSpendingLimitKey(l.Address)
+	if err = UpdateStateHash(st, key, l); err != nil {
+		return
+	}
+
+	var exists bool
+	if exists, err = st.Has(key); err != nil {
+		return
+	}
+
+	if exists {
+		err = st.Set(key, l)
+	} else {
+		err = st.New(key, l)
+	}
+
+	return
+}
+
+func (l SpendingLimit) Serialize() (encoded []byte, err error) {
+	encoded, err = sebakcommon.EncodeJSONValue(l)
+	return
+}
+
+// GetSpendingLimit returns the spending limit of account; `found` is `false`,
+// if the account has no limit.
+func GetSpendingLimit(st *sebakstorage.LevelDBBackend, address string) (limit SpendingLimit, found bool, err error) {
+	key := GetSpendingLimitKey(address)
+	if found, err = st.Has(key); err != nil || !found {
+		return
+	}
+
+	err = st.Get(key, &limit)
+
+	return
+}
+
+func RemoveSpendingLimit(st *sebakstorage.LevelDBBackend, address string) (err error) {
+	key := GetSpendingLimitKey(address)
+	if err = UpdateStateHash(st, key, nil); err != nil {
+		return
+	}
+	if err = st.Remove(key); err != nil {
+		return
+	}
+
+	spentKey := GetSpendingLimitSpentKey(address)
+
+	var exists bool
+	if exists, err = st.Has(spentKey); err != nil || !exists {
+		return
+	}
+	if err = UpdateStateHash(st, spentKey, nil); err != nil {
+		return
+	}
+	err = st.Remove(spentKey)
+
+	return
+}
+
+type SpendingLimitSpent struct {
+	Day    string // UTC, like '2018-07-01'
+	Amount Amount
+}
+
+func GetSpendingLimitDay(t time.Time) string {
+	return t.UTC().Format("2006-01-02")
+}
+
+// GetSpendingLimitSpent returns the spending of account in `day`.
+func GetSpendingLimitSpent(st *sebakstorage.LevelDBBackend, address, day string) (spent Amount, err error) {
+	key := GetSpendingLimitSpentKey(address)
+
+	var exists bool
+	if exists, err = st.Has(key); err != nil || !exists {
+		return
+	}
+
+	var s SpendingLimitSpent
+	if err = st.Get(key, &s); err != nil {
+		return
+	}
+	if s.Day == day {
+		spent = s.Amount
+	}
+
+	return
+}
+
+func addSpendingLimitSpent(st *sebakstorage.LevelDBBackend, address, day string, amount Amount) (err error) {
+	var spent Amount
+	if spent, err = GetSpendingLimitSpent(st, address, day); err != nil {
+		return
+	}
+
+	key := GetSpendingLimitSpentKey(address)
+	s := SpendingLimitSpent{Day: day, Amount: spent + amount}
+	if err = UpdateStateHash(st, key, s); err != nil {
+		return
+	}
+
+	var exists bool
+	if exists, err = st.Has(key); err != nil {
+		return
+	}
+	if exists {
+		err = st.Set(key, s)
+	} else {
+		err = st.New(key, s)
+	}
+
+	return
+}
+
+// RequiresCoSigners returns `true`, if the transaction must be co-signed; it
+// exceeds the limits with `spent`, the spending of the day, or it changes the
+// limit or the signers of account.
+func (l SpendingLimit) RequiresCoSigners(tx Transaction, spent Amount) bool {
+	for _, op := range tx.B.Operations {
+		if op.H.Type == OperationSetSpendingLimit || op.H.Type == OperationSetSigners {
+			return true
+		}
+	}
+
+	amount := tx.TotalAmount(true)
+	if l.PerTransaction > 0 && amount > l.PerTransaction {
+		return true
+	}
+	if l.Daily > 0 && spent+amount > l.Daily {
+		return true
+	}
+
+	return false
+}
+
+// CheckSpendingLimit checks the transaction against the spending limit of
+// source account in `day`; if the transaction requires the co-signers, the
+// weights of the valid co-signatures of the account signers must reach their
+// threshold.
+func CheckSpendingLimit(st *sebakstorage.LevelDBBackend, networkID []byte, tx Transaction, day string) (err error) {
+	var limit SpendingLimit
+	var found bool
+	if limit, found, err = GetSpendingLimit(st, tx.B.Source); err != nil || !found {
+		return
+	}
+
+	var spent Amount
+	if spent, err = GetSpendingLimitSpent(st, tx.B.Source, day); err != nil {
+		return
+	}
+	if !limit.RequiresCoSigners(tx, spent) {
+		return
+	}
+
+	var signers AccountSigners
+	if signers, found, err = GetAccountSigners(st, tx.B.Source); err != nil {
+		return
+	}
+	if !found || signers.CoSignedWeight(tx, networkID) < signers.Threshold {
+		err = sebakerror.ErrorSpendingLimitExceeded
+		return
+	}
+
+	return
+}
+
+// RecordSpendingLimitSpent adds the spending of the transaction to the day,
+// when the source account has the limit after the transaction.
+func RecordSpendingLimitSpent(st *sebakstorage.LevelDBBackend, tx Transaction, day string) (err error) {
+	var found bool
+	if _, found, err = GetSpendingLimit(st, tx.B.Source); err != nil || !found {
+		return
+	}
+
+	return addSpendingLimitSpent(st, tx.B.Source, day, tx.TotalAmount(true))
+}
+
+func verifyTransactionSignature(tx Transaction, networkID []byte, s TransactionEnvelopeSignature) (err error) {
+	var kp keypair.KP
+	if kp, err = keypair.Parse(s.Signer); err != nil {
+		return
+	}
+
+	return kp.Verify(append(networkID, []byte(tx.H.Hash)...), base58.Decode(s.Signature))
+}
+
+// stateSpendingLimitLeaf and stateSpendingLimitSpentLeaf are the leaves of
+// `SpendingLimit` and `SpendingLimitSpent` in the state hash; the spending
+// has the address of account from it's key.
+type stateSpendingLimitLeaf struct {
+	Kind           string
+	Address        string
+	PerTransaction Amount
+	Daily          Amount
+}
+
+type stateSpendingLimitSpentLeaf struct {
+	Kind    string
+	Address string
+	Day     string
+	Amount  Amount
+}
+
+func init() {
+	RegisterStateHashLeaf(StateHashLeaf{
+		Prefix: SpendingLimitPrefixAddress,
+		Leaf: func(_ string, value []byte) ([]byte, error) {
+			var l SpendingLimit
+			if err := json.Unmarshal(value, &l); err != nil {
+				return nil, err
+			}
+
+			return sebakcommon.MustJSONMarshal(stateSpendingLimitLeaf{
+				Kind:           "spending-limit",
+				Address:        l.Address,
+				PerTransaction: l.PerTransaction,
+				Daily:          l.Daily,
+			}), nil
+		},
+	})
+	RegisterStateHashLeaf(StateHashLeaf{
+		Prefix: SpendingLimitPrefixSpent,
+		Leaf: func(key string, value []byte) ([]byte, error) {
+			var s SpendingLimitSpent
+			if err := json.Unmarshal(value, &s); err != nil {
+				return nil, err
+			}
+
+			return sebakcommon.MustJSONMarshal(stateSpendingLimitSpentLeaf{
+				Kind:    "spending-limit-spent",
+				Address: strings.TrimPrefix(key, SpendingLimitPrefixSpent),
+				Day:     s.Day,
+				Amount:  s.Amount,
+			}), nil
+		},
+	})
+}
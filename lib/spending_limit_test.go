@@ -0,0 +1,191 @@
+package sebak
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stellar/go/keypair"
+
+	"boscoin.io/sebak/lib/error"
+	"boscoin.io/sebak/lib/storage"
+)
+
+func TestOperationBodySetSpendingLimitIsWellFormed(t *testing.T) {
+	valid := []OperationBodySetSpendingLimit{
+		OperationBodySetSpendingLimit{}, // removes the limit
+		NewOperationBodySetSpendingLimit(BaseFee*10, 0),
+		NewOperationBodySetSpendingLimit(0, BaseFee*100),
+	}
+	for _, body := range valid {
+		if err := body.IsWellFormed(networkID); err != nil {
+			t.Errorf("'%v' must be well-formed: %v", body, err)
+			return
+		}
+	}
+
+	op, _ := NewOperation(OperationSetSpendingLimit, valid[2])
+	encoded, _ := op.Serialize()
+	decoded, err := NewOperationFromBytes(encoded)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if decoded.MakeHashString() != op.MakeHashString() {
+		t.Errorf("wrong operation: %v", decoded)
+		return
+	}
+}
+
+func TestTransactionCoSignatures(t *testing.T) {
+	kp, _ := keypair.Random()
+	coSigner, _ := keypair.Random()
+
+	tx := TestMakeTransactionWithKeypair(networkID, 1, kp)
+	tx.CoSign(coSigner, networkID)
+	if err := tx.IsWellFormed(networkID); err != nil {
+		t.Error(err)
+		return
+	}
+
+	forged := tx
+	forged.H.Signatures = []TransactionEnvelopeSignature{{Signer: coSigner.Address(), Signature: tx.H.Signature}}
+	if err := forged.IsWellFormed(networkID); err != sebakerror.ErrorTransactionInvalidCoSignatures {
+		t.Errorf("forged co-signature must be refused: %v", err)
+		return
+	}
+
+	bySource := TestMakeTransactionWithKeypair(networkID, 1, kp)
+	bySource.CoSign(kp, networkID)
+	if err := bySource.IsWellFormed(networkID); err != sebakerror.ErrorTransactionInvalidCoSignatures {
+		t.Errorf("co-signature of source must be refused: %v", err)
+		return
+	}
+}
+
+func TestCheckSpendingLimit(t *testing.T) {
+	st, _ := sebakstorage.NewTestMemoryLevelDBBackend()
+	defer st.Close()
+
+	kp, _ := keypair.Random()
+	a, _ := keypair.Random()
+	b, _ := keypair.Random()
+	day := "2018-07-01"
+
+	makeTransaction := func(amount int, coSigners ...*keypair.Full) Transaction {
+		tx, _ := NewTransaction(kp.Address(), uuid.New().String(), TestMakeOperation(amount))
+		tx.Sign(kp, networkID)
+		for _, c := range coSigners {
+			tx.CoSign(c, networkID)
+		}
+		return tx
+	}
+
+	// without limit, anything goes
+	if err := CheckSpendingLimit(st, networkID, makeTransaction(int(BaseFee*100)), day); err != nil {
+		t.Error(err)
+		return
+	}
+
+	// the key of account alone reaches the threshold of signers, but the
+	// transaction over the limit needs the signers without it
+	signers := NewOperationBodySetSigners(2, 2, AccountSigner{a.Address(), 1}, AccountSigner{b.Address(), 1})
+	NewAccountSigners(kp.Address(), signers).Save(st)
+	limit := NewSpendingLimit(kp.Address(), NewOperationBodySetSpendingLimit(BaseFee*10, BaseFee*15))
+	limit.Save(st)
+
+	small := makeTransaction(int(BaseFee * 5))
+	if err := CheckSpendingLimit(st, networkID, small, day); err != nil {
+		t.Error(err)
+		return
+	}
+	large := makeTransaction(int(BaseFee * 10))
+	if err := CheckSpendingLimit(st, networkID, large, day); err != sebakerror.ErrorSpendingLimitExceeded {
+		t.Errorf("transaction over the limit must be refused: %v", err)
+		return
+	}
+	if err := CheckSpendingLimit(st, networkID, makeTransaction(int(BaseFee*10), a), day); err != sebakerror.ErrorSpendingLimitExceeded {
+		t.Errorf("co-signatures under the threshold must be refused: %v", err)
+		return
+	}
+	if err := CheckSpendingLimit(st, networkID, makeTransaction(int(BaseFee*10), a, b), day); err != nil {
+		t.Error(err)
+		return
+	}
+
+	// the daily limit counts the spending of the day
+	RecordSpendingLimitSpent(st, small, day)
+	RecordSpendingLimitSpent(st, small, day)
+	if err := CheckSpendingLimit(st, networkID, small, day); err != sebakerror.ErrorSpendingLimitExceeded {
+		t.Errorf("transaction over the daily limit must be refused: %v", err)
+		return
+	}
+	if err := CheckSpendingLimit(st, networkID, small, "2018-07-02"); err != nil {
+		t.Errorf("next day must be reset: %v", err)
+		return
+	}
+
+	// changing the limit or the signers requires the co-signers
+	for _, body := range []OperationBody{OperationBodySetSpendingLimit{}, NewOperationBodySetSigners(1, 1, AccountSigner{a.Address(), 1})} {
+		var op Operation
+		if _, ok := body.(OperationBodySetSigners); ok {
+			op, _ = NewOperation(OperationSetSigners, body)
+		} else {
+			op, _ = NewOperation(OperationSetSpendingLimit, body)
+		}
+		change, _ := NewTransaction(kp.Address(), uuid.New().String(), op)
+		change.Sign(kp, networkID)
+		if err := CheckSpendingLimit(st, networkID, change, "2018-07-02"); err != sebakerror.ErrorSpendingLimitExceeded {
+			t.Errorf("'%s' without co-signers must be refused: %v", op.H.Type, err)
+			return
+		}
+		change.CoSign(a, networkID)
+		change.CoSign(b, networkID)
+		if err := CheckSpendingLimit(st, networkID, change, "2018-07-02"); err != nil {
+			t.Error(err)
+			return
+		}
+	}
+
+	// without the signers, nothing over the limit can be co-signed
+	RemoveAccountSigners(st, kp.Address())
+	if err := CheckSpendingLimit(st, networkID, makeTransaction(int(BaseFee*10), a, b), "2018-07-02"); err != sebakerror.ErrorSpendingLimitExceeded {
+		t.Errorf("transaction over the limit without signers must be refused: %v", err)
+		return
+	}
+}
+
+// TestSpendingLimitStateHash checks, the spending limit and the spending of
+// the day are the part of state hash.
+func TestSpendingLimitStateHash(t *testing.T) {
+	st, _ := sebakstorage.NewTestMemoryLevelDBBackend()
+	defer st.Close()
+
+	kp, _ := keypair.Random()
+	NewBlockAccount(kp.Address(), BaseFee.MustAdd(1), uuid.New().String()).Save(st)
+	hash, _ := MakeStateHash(st)
+
+	limit := SpendingLimit{Address: kp.Address(), Daily: Amount(100)}
+	limit.Save(st)
+	withLimit, _ := MakeStateHash(st)
+	if withLimit == hash {
+		t.Error("spending limit must change state hash")
+		return
+	}
+
+	addSpendingLimitSpent(st, kp.Address(), "2018-07-01", Amount(10))
+	withSpent, _ := MakeStateHash(st)
+	if withSpent == withLimit {
+		t.Error("spending of the day must change state hash")
+		return
+	}
+	if summed, _ := makeStateHashFromState(st); summed != withSpent {
+		t.Error("stored state hash must be same with the sum of state")
+		return
+	}
+
+	RemoveSpendingLimit(st, kp.Address())
+	if removed, _ := MakeStateHash(st); removed != hash {
+		t.Error("removed spending limit must be subtracted from state hash")
+		return
+	}
+}
@@ -0,0 +1,289 @@
+package sebak
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/btcsuite/btcutil/base58"
+
+	"boscoin.io/sebak/lib/common"
+	"boscoin.io/sebak/lib/error"
+	"boscoin.io/sebak/lib/storage"
+)
+
+// The state hash is the hash of the account state after every block. The
+// state is the set of leaves of the registered `StateHashLeaf`s, and the hash
+// is the homomorphic sum of the leaves, LtHash
+// (https://eprint.iacr.org/2019/227); the changed state subtracts it's
+// previous leaf and adds the new one, so only the changed state is hashed for
+// the block, not all the state. The sum is stored by,
+//  * 'sh-state': `StateHashSum`
+
+const StateHashKey string = "sh-state"
+
+// stateHashLanes is the number of 16 bits lanes of `StateHashSum`.
+const stateHashLanes int = 1024
+
+// StateHashLeaf is the kind of state, which is the part of state hash. The
+// state stored under `Prefix` must be saved and removed after
+// `UpdateStateHash()`.
+type StateHashLeaf struct {
+	Prefix string
+	// Leaf makes the leaf of state from it's key and stored value; it has
+	// only the fields, which are the state itself, with the kind of state, so
+	// the different kinds of state never have the same leaf.
+	Leaf func(key string, value []byte) ([]byte, error)
+}
+
+var (
+	stateHashLeavesLock sync.RWMutex
+	stateHashLeaves     []StateHashLeaf
+)
+
+// RegisterStateHashLeaf adds the state of prefix to the state hash; it
+// panics, if the prefix overlaps with the registered one, because it is the
+// mistake of the code.
+func RegisterStateHashLeaf(leaf StateHashLeaf) {
+	if len(leaf.Prefix) < 1 || leaf.Leaf == nil {
+		panic(fmt.Errorf("state hash leaf of '%s' must have the prefix and `Leaf`", leaf.Prefix))
+	}
+
+	stateHashLeavesLock.Lock()
+	defer stateHashLeavesLock.Unlock()
+
+	for _, registered := range stateHashLeaves {
+		if strings.HasPrefix(registered.Prefix, leaf.Prefix) || strings.HasPrefix(leaf.Prefix, registered.Prefix) {
+			panic(fmt.Errorf("state hash leaf of '%s' overlaps with '%s'", leaf.Prefix, registered.Prefix))
+		}
+	}
+	stateHashLeaves = append(stateHashLeaves, leaf)
+}
+
+func getStateHashLeaf(key string) (leaf StateHashLeaf, found bool) {
+	stateHashLeavesLock.RLock()
+	defer stateHashLeavesLock.RUnlock()
+
+	for _, leaf = range stateHashLeaves {
+		if strings.HasPrefix(key, leaf.Prefix) {
+			return leaf, true
+		}
+	}
+
+	return
+}
+
+// StateHashSum is the sum of the leaves of state; `Sum` has the little endian
+// 16 bits lanes.
+type StateHashSum struct {
+	Sum    []byte
+	Leaves uint64
+}
+
+func newStateHashSum() StateHashSum {
+	return StateHashSum{Sum: make([]byte, stateHashLanes*2)}
+}
+
+// expandStateHashLeaf expands the leaf to the lanes of sum by SHA-512 in
+// counter mode.
+func expandStateHashLeaf(leaf []byte) []byte {
+	seed := sha512.Sum512(leaf)
+
+	expanded := make([]byte, 0, stateHashLanes*2)
+	block := make([]byte, len(seed)+4)
+	copy(block, seed[:])
+	for i := uint32(0); len(expanded) < stateHashLanes*2; i++ {
+		binary.BigEndian.PutUint32(block[len(seed):], i)
+		h := sha512.Sum512(block)
+		expanded = append(expanded, h[:]...)
+	}
+
+	return expanded
+}
+
+func (s *StateHashSum) add(leaf []byte) {
+	expanded := expandStateHashLeaf(leaf)
+	for i := 0; i < len(s.Sum); i += 2 {
+		lane := binary.LittleEndian.Uint16(s.Sum[i:]) + binary.LittleEndian.Uint16(expanded[i:])
+		binary.LittleEndian.PutUint16(s.Sum[i:], lane)
+	}
+	s.Leaves++
+}
+
+func (s *StateHashSum) remove(leaf []byte) {
+	expanded := expandStateHashLeaf(leaf)
+	for i := 0; i < len(s.Sum); i += 2 {
+		lane := binary.LittleEndian.Uint16(s.Sum[i:]) - binary.LittleEndian.Uint16(expanded[i:])
+		binary.LittleEndian.PutUint16(s.Sum[i:], lane)
+	}
+	s.Leaves--
+}
+
+// Hash returns the state hash of sum; if no leaves, empty string is
+// returned.
+func (s StateHashSum) Hash() string {
+	if s.Leaves < 1 {
+		return ""
+	}
+
+	h := sha256.Sum256(s.Sum)
+	return base58.Encode(h[:])
+}
+
+func (s StateHashSum) Serialize() (encoded []byte, err error) {
+	encoded, err = json.Marshal(s)
+	return
+}
+
+func (s StateHashSum) Save(st *sebakstorage.LevelDBBackend) (err error) {
+	var exists bool
+	if exists, err = st.Has(StateHashKey); err != nil {
+		return
+	}
+
+	if exists {
+		err = st.Set(StateHashKey, s)
+	} else {
+		err = st.New(StateHashKey, s)
+	}
+
+	return
+}
+
+// GetStateHashSum returns the stored sum; without state, it is empty.
+func GetStateHashSum(st *sebakstorage.LevelDBBackend) (s StateHashSum, err error) {
+	var exists bool
+	if exists, err = st.Has(StateHashKey); err != nil || !exists {
+		s = newStateHashSum()
+		return
+	}
+
+	if err = st.Get(StateHashKey, &s); err != nil {
+		return
+	}
+	if len(s.Sum) != stateHashLanes*2 {
+		err = fmt.Errorf("invalid state hash sum: %d bytes", len(s.Sum))
+		return
+	}
+
+	return
+}
+
+// UpdateStateHash updates the stored sum by the state of `key`, which is
+// going to be saved with `value`, or removed with nil; it must be called
+// before the state is changed, because the previous leaf is subtracted. The
+// key, which is not registered by `RegisterStateHashLeaf()` is ignored.
+func UpdateStateHash(st *sebakstorage.LevelDBBackend, key string, value interface{}) (err error) {
+	leaf, found := getStateHashLeaf(key)
+	if !found {
+		return
+	}
+
+	var sum StateHashSum
+	if sum, err = GetStateHashSum(st); err != nil {
+		return
+	}
+
+	var exists bool
+	if exists, err = st.Has(key); err != nil {
+		return
+	}
+	if exists {
+		var previous, b []byte
+		if previous, err = st.GetRaw(key); err != nil {
+			return
+		}
+		if b, err = leaf.Leaf(key, previous); err != nil {
+			return
+		}
+		sum.remove(b)
+	}
+
+	if value != nil {
+		var encoded, b []byte
+		if encoded, err = sebakcommon.EncodeJSONValue(value); err != nil {
+			return
+		}
+		if b, err = leaf.Leaf(key, encoded); err != nil {
+			return
+		}
+		sum.add(b)
+	}
+
+	err = sum.Save(st)
+
+	return
+}
+
+// MakeStateHash returns the state hash of the stored sum. If no state exists,
+// empty string is returned.
+func MakeStateHash(st *sebakstorage.LevelDBBackend) (hash string, err error) {
+	var sum StateHashSum
+	if sum, err = GetStateHashSum(st); err != nil {
+		return
+	}
+
+	hash = sum.Hash()
+
+	return
+}
+
+// makeStateHashFromState sums all the registered state again, regardless of
+// the stored sum.
+func makeStateHashFromState(st *sebakstorage.LevelDBBackend) (hash string, err error) {
+	stateHashLeavesLock.RLock()
+	leaves := make([]StateHashLeaf, len(stateHashLeaves))
+	copy(leaves, stateHashLeaves)
+	stateHashLeavesLock.RUnlock()
+
+	sum := newStateHashSum()
+	for _, leaf := range leaves {
+		iterFunc, closeFunc := st.GetIterator(leaf.Prefix, false)
+		for {
+			item, hasNext := iterFunc()
+			if !hasNext {
+				break
+			}
+
+			var b []byte
+			if b, err = leaf.Leaf(string(item.Key), item.Value); err != nil {
+				closeFunc()
+				return
+			}
+			sum.add(b)
+		}
+		closeFunc()
+	}
+
+	hash = sum.Hash()
+
+	return
+}
+
+// VerifyStateHash checks the current account state and the stored sum are
+// same with the state of the latest block; the state is summed again, so it
+// finds the state, which is changed without updating the sum.
+func VerifyStateHash(st *sebakstorage.LevelDBBackend) (err error) {
+	var latest Block
+	if latest, err = GetLatestBlock(st); err != nil || latest.IsEmpty() {
+		return
+	}
+
+	var stored, hash string
+	if stored, err = MakeStateHash(st); err != nil {
+		return
+	}
+	if hash, err = makeStateHashFromState(st); err != nil {
+		return
+	}
+	if stored != latest.StateHash || hash != latest.StateHash {
+		err = sebakerror.ErrorStateHashDoesNotMatch
+		return
+	}
+
+	return
+}
@@ -0,0 +1,145 @@
+package sebak
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stellar/go/keypair"
+
+	"boscoin.io/sebak/lib/error"
+	"boscoin.io/sebak/lib/storage"
+)
+
+func TestMakeStateHash(t *testing.T) {
+	st, _ := sebakstorage.NewTestMemoryLevelDBBackend()
+	defer st.Close()
+
+	if hash, err := MakeStateHash(st); err != nil || len(hash) > 0 {
+		t.Error("state hash of empty state must be empty")
+		return
+	}
+
+	kp, _ := keypair.Random()
+	ba := NewBlockAccount(kp.Address(), BaseFee.MustAdd(1), uuid.New().String())
+	ba.Save(st)
+
+	hash, _ := MakeStateHash(st)
+	if len(hash) < 1 {
+		t.Error("state hash must not be empty")
+		return
+	}
+
+	// `Updated` of data entry is not the part of state
+	NewBlockAccountData(kp.Address(), "name", []byte("value"), "2018-09-01T00:00:00.000000000Z").Save(st)
+	withData, _ := MakeStateHash(st)
+	if withData == hash {
+		t.Error("data entry must change state hash")
+		return
+	}
+	NewBlockAccountData(kp.Address(), "name", []byte("value"), "2018-09-02T00:00:00.000000000Z").Save(st)
+	if again, _ := MakeStateHash(st); again != withData {
+		t.Error("saving same data entry again must not change state hash")
+		return
+	}
+
+	ba.Balance = BaseFee.MustAdd(2).String()
+	ba.Save(st)
+	if changed, _ := MakeStateHash(st); changed == withData {
+		t.Error("balance must change state hash")
+		return
+	}
+}
+
+// TestStateHashIncremental checks, the stored sum, which is updated by the
+// changed state is same with the sum of all the state, and it does not depend
+// on the order of changes.
+func TestStateHashIncremental(t *testing.T) {
+	st, _ := sebakstorage.NewTestMemoryLevelDBBackend()
+	defer st.Close()
+	other, _ := sebakstorage.NewTestMemoryLevelDBBackend()
+	defer other.Close()
+
+	var accounts []*BlockAccount
+	for i := 0; i < 3; i++ {
+		kp, _ := keypair.Random()
+		accounts = append(accounts, NewBlockAccount(kp.Address(), BaseFee.MustAdd(1), uuid.New().String()))
+	}
+
+	for _, ba := range accounts {
+		ba.Save(st)
+		NewBlockAccountData(ba.Address, "name", []byte(ba.Address), "2018-09-01T00:00:00.000000000Z").Save(st)
+	}
+	for i := len(accounts) - 1; i >= 0; i-- {
+		NewBlockAccountData(accounts[i].Address, "name", []byte(accounts[i].Address), "2018-09-01T00:00:00.000000000Z").Save(other)
+		accounts[i].Save(other)
+	}
+
+	hash, _ := MakeStateHash(st)
+	if summed, _ := makeStateHashFromState(st); summed != hash {
+		t.Error("stored state hash must be same with the sum of state")
+		return
+	}
+	if reordered, _ := MakeStateHash(other); reordered != hash {
+		t.Error("state hash must not depend on the order of changes")
+		return
+	}
+
+	// the removed state is subtracted
+	NewBlockAccountData(accounts[0].Address, "removed", []byte("value"), "2018-09-01T00:00:00.000000000Z").Save(st)
+	if changed, _ := MakeStateHash(st); changed == hash {
+		t.Error("new data entry must change state hash")
+		return
+	}
+	RemoveBlockAccountData(st, accounts[0].Address, "removed")
+	if removed, _ := MakeStateHash(st); removed != hash {
+		t.Error("removed data entry must be subtracted from state hash")
+		return
+	}
+}
+
+func TestVerifyStateHash(t *testing.T) {
+	st, _ := sebakstorage.NewTestMemoryLevelDBBackend()
+	defer st.Close()
+
+	kp, _ := keypair.Random()
+	ba := NewBlockAccount(kp.Address(), BaseFee.MustAdd(1), uuid.New().String())
+	ba.Save(st)
+
+	// without block, nothing to verify
+	if err := VerifyStateHash(st); err != nil {
+		t.Error(err)
+		return
+	}
+
+	hash, _ := MakeStateHash(st)
+	_, tx := TestMakeTransaction(networkID, 1)
+	block := NewBlock(Block{}, hash, tx.GetHash())
+	block.Save(st)
+
+	if err := VerifyStateHash(st); err != nil {
+		t.Error(err)
+		return
+	}
+
+	ba.Balance = BaseFee.MustAdd(2).String()
+	ba.Save(st)
+	if err := VerifyStateHash(st); err != sebakerror.ErrorStateHashDoesNotMatch {
+		t.Error("changed state must be found")
+		return
+	}
+
+	// the state, which is changed without updating the stored sum is also
+	// found
+	ba.Balance = BaseFee.MustAdd(1).String()
+	ba.Save(st)
+	if err := VerifyStateHash(st); err != nil {
+		t.Error(err)
+		return
+	}
+	ba.Balance = BaseFee.MustAdd(3).String()
+	st.Set(GetBlockAccountKey(ba.Address), ba)
+	if err := VerifyStateHash(st); err != sebakerror.ErrorStateHashDoesNotMatch {
+		t.Error("state changed without the state hash must be found")
+		return
+	}
+}
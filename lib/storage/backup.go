@@ -0,0 +1,59 @@
+package sebakstorage
+
+import (
+	"errors"
+	"os"
+
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+// backupBatchSize is the number of records written to the backup database at
+// once.
+const backupBatchSize int = 1000
+
+// Backup copies the consistent point-in-time view of the whole storage into
+// the new leveldb database in `path`. `path` must not exist.
+func (st *LevelDBBackend) Backup(path string) (err error) {
+	if _, err = os.Stat(path); err == nil {
+		err = errors.New("backup path already exists")
+		return
+	} else if !os.IsNotExist(err) {
+		return
+	}
+
+	var snapshot *leveldb.Snapshot
+	if snapshot, err = st.DB.GetSnapshot(); err != nil {
+		return
+	}
+	defer snapshot.Release()
+
+	var db *leveldb.DB
+	if db, err = leveldb.OpenFile(path, nil); err != nil {
+		return
+	}
+	defer db.Close()
+
+	iter := snapshot.NewIterator(nil, nil)
+	defer iter.Release()
+
+	batch := new(leveldb.Batch)
+	for iter.Next() {
+		batch.Put(iter.Key(), iter.Value())
+		if batch.Len() < backupBatchSize {
+			continue
+		}
+		if err = db.Write(batch, nil); err != nil {
+			return
+		}
+		batch.Reset()
+	}
+	if err = iter.Error(); err != nil {
+		return
+	}
+
+	if batch.Len() > 0 {
+		err = db.Write(batch, nil)
+	}
+
+	return
+}
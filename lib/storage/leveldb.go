@@ -1,6 +1,7 @@
 package sebakstorage
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -22,27 +23,70 @@ type LevelDBCore interface {
 	Delete([]byte, *leveldbOpt.WriteOptions) error
 }
 
+// SyncMode decides when the writes are synced to the disk, it is set by the
+// 'sync' query of storage uri, like 'file:///tmp/db?sync=deferred'.
+//  * `none`: the writes are not synced; they are written to the OS, so they
+//  survive the crash of process, but the latest writes can be lost by the
+//  crash of OS or power failure.
+//  * `deferred`: the routine writes are not synced, but `SyncBarrier()`, which
+//  is called after block is committed, syncs all the previous writes. The
+//  committed blocks survive the crash of OS; the writes after the last block,
+//  like the transaction history, can be lost.
+//  * `always`: every write is synced; the slowest, but nothing is lost.
+type SyncMode string
+
+const (
+	SyncModeNone     SyncMode = "none"
+	SyncModeDeferred SyncMode = "deferred"
+	SyncModeAlways   SyncMode = "always"
+)
+
+const DefaultSyncMode SyncMode = SyncModeNone
+
+// SyncBarrierKey is written with sync by `SyncBarrier()`; it keeps the time of
+// the last barrier.
+const SyncBarrierKey string = "_sync-barrier"
+
+func NewSyncModeFromString(s string) (mode SyncMode, err error) {
+	switch mode = SyncMode(s); mode {
+	case "":
+		mode = DefaultSyncMode
+	case SyncModeNone, SyncModeDeferred, SyncModeAlways:
+	default:
+		err = fmt.Errorf("unknown sync mode, '%s'", s)
+		return
+	}
+
+	return
+}
+
 type LevelDBBackend struct {
 	DB *leveldb.DB
 
-	core LevelDBCore
+	core     LevelDBCore
+	syncMode SyncMode
+
+	negativeCache *NegativeCache
+	written       []string // the keys written in transaction
 }
 
 func (st *LevelDBBackend) Init(config *Config) (err error) {
-	var sto leveldbStorage.Storage
+	if st.syncMode, err = NewSyncModeFromString(config.Query().Get("sync")); err != nil {
+		return
+	}
+
+	var db *leveldb.DB
 	if config.Scheme == "memory" {
-		sto = leveldbStorage.NewMemStorage()
+		if db, err = leveldb.Open(leveldbStorage.NewMemStorage(), nil); err != nil {
+			return
+		}
 	} else if config.Scheme == "file" {
-		if sto, err = leveldbStorage.OpenFile(config.Path, false); err != nil {
+		// NOTE `leveldb.OpenFile` releases the file lock when `DB` is closed.
+		if db, err = leveldb.OpenFile(config.Path, nil); err != nil {
 			return
 		}
 	}
 
-	var db *leveldb.DB
-	if db, err = leveldb.Open(sto, nil); err != nil {
-		return
-	}
-
 	st.DB = db
 	st.core = db
 
@@ -65,8 +109,10 @@ func (st *LevelDBBackend) OpenTransaction() (*LevelDBBackend, error) {
 	}
 
 	return &LevelDBBackend{
-		DB:   st.DB,
-		core: transaction,
+		DB:            st.DB,
+		core:          transaction,
+		syncMode:      st.syncMode,
+		negativeCache: st.negativeCache,
 	}, nil
 }
 
@@ -86,15 +132,108 @@ func (st *LevelDBBackend) Commit() error {
 		return errors.New("this is not *leveldb.Transaction")
 	}
 
-	return ts.Commit()
+	if err := ts.Commit(); err != nil {
+		return err
+	}
+
+	// the written keys can be cached as absent by the lookup outside of
+	// transaction before commit.
+	st.invalidate(st.written...)
+	st.written = nil
+
+	return nil
+}
+
+func (st *LevelDBBackend) isTransaction() bool {
+	_, ok := st.core.(*leveldb.Transaction)
+	return ok
+}
+
+// SetNegativeCache sets the cache for the keys, which do not exist. It must
+// be set before the storage is used by the others.
+func (st *LevelDBBackend) SetNegativeCache(cache *NegativeCache) {
+	st.negativeCache = cache
+}
+
+func (st *LevelDBBackend) NegativeCache() *NegativeCache {
+	return st.negativeCache
+}
+
+func (st *LevelDBBackend) invalidate(keys ...string) {
+	if st.negativeCache == nil {
+		return
+	}
+
+	if st.isTransaction() {
+		for _, k := range keys {
+			if st.negativeCache.Covers(k) {
+				st.written = append(st.written, k)
+			}
+		}
+	}
+	st.negativeCache.Invalidate(keys...)
+}
+
+func (st *LevelDBBackend) SyncMode() SyncMode {
+	return st.syncMode
+}
+
+// SyncBarrier syncs all the previous writes to the disk in `deferred` mode;
+// in the other modes, it does nothing. It must not be called in transaction.
+func (st *LevelDBBackend) SyncBarrier() (err error) {
+	if st.syncMode != SyncModeDeferred {
+		return
+	}
+	if _, ok := st.core.(*leveldb.Transaction); ok {
+		err = errors.New("sync barrier can not be in *leveldb.Transaction")
+		return
+	}
+
+	// NOTE the synced write also syncs the journal, which has all the previous
+	// writes.
+	err = st.DB.Put(
+		st.makeKey(SyncBarrierKey),
+		[]byte(sebakcommon.NowISO8601()),
+		&leveldbOpt.WriteOptions{Sync: true},
+	)
+
+	return
+}
+
+func (st *LevelDBBackend) writeOptions() *leveldbOpt.WriteOptions {
+	if st.syncMode == SyncModeAlways {
+		return &leveldbOpt.WriteOptions{Sync: true}
+	}
+
+	return nil
 }
 
 func (st *LevelDBBackend) makeKey(key string) []byte {
 	return []byte(key)
 }
 
-func (st *LevelDBBackend) Has(k string) (bool, error) {
-	return st.core.Has(st.makeKey(k), nil)
+func (st *LevelDBBackend) Has(k string) (exists bool, err error) {
+	cache := st.negativeCache
+	if cache == nil || !cache.Covers(k) {
+		return st.core.Has(st.makeKey(k), nil)
+	}
+
+	if cache.IsAbsent(k) {
+		return
+	}
+
+	generation := cache.Generation()
+	if exists, err = st.core.Has(st.makeKey(k), nil); exists || err != nil {
+		return
+	}
+
+	// the miss in transaction can be from the discarded writes, so it is not
+	// cached.
+	if !st.isTransaction() {
+		cache.Add(k, generation)
+	}
+
+	return
 }
 
 func (st *LevelDBBackend) GetRaw(k string) (b []byte, err error) {
@@ -144,7 +283,8 @@ func (st *LevelDBBackend) New(k string, v interface{}) (err error) {
 		return
 	}
 
-	err = st.core.Put(st.makeKey(k), encoded, nil)
+	err = st.core.Put(st.makeKey(k), encoded, st.writeOptions())
+	st.invalidate(k)
 
 	return
 }
@@ -175,7 +315,8 @@ func (st *LevelDBBackend) News(vs ...Item) (err error) {
 		batch.Put(st.makeKey(v.Key), encoded)
 	}
 
-	err = st.core.Write(batch, nil)
+	err = st.core.Write(batch, st.writeOptions())
+	st.invalidateItems(vs)
 
 	return
 }
@@ -194,7 +335,8 @@ func (st *LevelDBBackend) Set(k string, v interface{}) (err error) {
 		return
 	}
 
-	err = st.core.Put(st.makeKey(k), encoded, nil)
+	err = st.core.Put(st.makeKey(k), encoded, st.writeOptions())
+	st.invalidate(k)
 
 	return
 }
@@ -225,7 +367,8 @@ func (st *LevelDBBackend) Sets(vs ...Item) (err error) {
 		batch.Put(st.makeKey(v.Key), encoded)
 	}
 
-	err = st.core.Write(batch, nil)
+	err = st.core.Write(batch, st.writeOptions())
+	st.invalidateItems(vs)
 
 	return
 }
@@ -239,17 +382,102 @@ func (st *LevelDBBackend) Remove(k string) (err error) {
 		return
 	}
 
-	err = st.core.Delete(st.makeKey(k), nil)
+	err = st.core.Delete(st.makeKey(k), st.writeOptions())
+	st.invalidate(k)
 
 	return
 }
 
+func (st *LevelDBBackend) invalidateItems(vs []Item) {
+	keys := make([]string, len(vs))
+	for i, v := range vs {
+		keys[i] = v.Key
+	}
+	st.invalidate(keys...)
+}
+
 func (st *LevelDBBackend) GetIterator(prefix string, reverse bool) (func() (IterItem, bool), func()) {
 	var dbRange *leveldbUtil.Range
 	if len(prefix) > 0 {
 		dbRange = leveldbUtil.BytesPrefix(st.makeKey(prefix))
 	}
 
+	return st.newIterator(dbRange, reverse)
+}
+
+// GetIteratorFrom iterates the items of `prefix` after the key, `from`; in
+// reverse, the items before it. `from` itself is not included, so the last key
+// of the previous page can be given to get the next page without scanning the
+// previous items. If `from` is empty, it is same with `GetIterator()`. The
+// range never goes beyond `prefix`, even if `from` is out of it.
+func (st *LevelDBBackend) GetIteratorFrom(prefix, from string, reverse bool) (func() (IterItem, bool), func()) {
+	if len(from) < 1 {
+		return st.GetIterator(prefix, reverse)
+	}
+
+	dbRange := leveldbUtil.BytesPrefix(st.makeKey(prefix))
+	if reverse {
+		// `Limit` is nil if the prefix has no upper bound
+		if limit := st.makeKey(from); dbRange.Limit == nil || bytes.Compare(limit, dbRange.Limit) < 0 {
+			dbRange.Limit = limit
+		}
+	} else {
+		if start := append(st.makeKey(from), 0); bytes.Compare(start, dbRange.Start) > 0 {
+			dbRange.Start = start
+		}
+	}
+
+	return st.newIterator(dbRange, reverse)
+}
+
+// SeekLast returns the last item of `prefix`; with empty `prefix`, the last
+// item of storage.
+func (st *LevelDBBackend) SeekLast(prefix string) (item IterItem, found bool) {
+	iterFunc, closeFunc := st.GetIterator(prefix, true)
+	defer closeFunc()
+
+	if item, found = iterFunc(); found {
+		item.Key = append([]byte{}, item.Key...)
+		item.Value = append([]byte{}, item.Value...)
+	}
+
+	return
+}
+
+// GetPage returns up to `limit` items of `prefix` after `from` like
+// `GetIteratorFrom()`. `next` is the key of the last item only when more
+// items remain, so it can be given as `from` of the next page; the empty
+// `next` means there is no next page.
+func (st *LevelDBBackend) GetPage(prefix, from string, limit int, reverse bool) (items []IterItem, next string) {
+	if limit < 1 {
+		return
+	}
+
+	iterFunc, closeFunc := st.GetIteratorFrom(prefix, from, reverse)
+	defer closeFunc()
+
+	for {
+		item, hasNext := iterFunc()
+		if !hasNext {
+			break
+		}
+		if len(items) == limit {
+			next = string(items[limit-1].Key)
+			break
+		}
+
+		// the iterator reuses the buffers of key and value
+		items = append(items, IterItem{
+			N:     item.N,
+			Key:   append([]byte{}, item.Key...),
+			Value: append([]byte{}, item.Value...),
+		})
+	}
+
+	return
+}
+
+func (st *LevelDBBackend) newIterator(dbRange *leveldbUtil.Range, reverse bool) (func() (IterItem, bool), func()) {
 	iter := st.core.NewIterator(dbRange, nil)
 
 	var funcNext func() bool
@@ -266,10 +494,12 @@ func (st *LevelDBBackend) GetIterator(prefix string, reverse bool) (func() (Iter
 		hasUnsent = false
 	}
 
+	// `N` starts from 1 in both directions
 	var n int64
 	return (func() (IterItem, bool) {
 			if hasUnsent {
 				hasUnsent = false
+				n++
 				return IterItem{N: n, Key: iter.Key(), Value: iter.Value()}, true
 			}
 
@@ -1,12 +1,15 @@
 package sebakstorage
 
 import (
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"math"
 	"math/rand"
 	"os"
 	"reflect"
+	"sort"
+	"strings"
 	"testing"
 
 	"github.com/google/uuid"
@@ -355,6 +358,199 @@ func TestLevelDBIteratorReverseOrder(t *testing.T) {
 	return
 }
 
+func TestLevelDBIteratorFrom(t *testing.T) {
+	st, _ := NewTestMemoryLevelDBBackend()
+	defer st.Close()
+
+	for i := 0; i < 10; i++ {
+		st.New(fmt.Sprintf("a-%03d", i), 0)
+		st.New(fmt.Sprintf("b-%03d", i), 0)
+	}
+
+	collect := func(from string, reverse bool) (keys []string) {
+		it, closeFunc := st.GetIteratorFrom("a-", from, reverse)
+		defer closeFunc()
+		for {
+			v, hasNext := it()
+			if !hasNext {
+				break
+			}
+			keys = append(keys, string(v.Key))
+		}
+		return
+	}
+
+	if keys := collect("a-007", false); !reflect.DeepEqual(keys, []string{"a-008", "a-009"}) {
+		t.Errorf("wrong items after key: %v", keys)
+		return
+	}
+	if keys := collect("a-002", true); !reflect.DeepEqual(keys, []string{"a-001", "a-000"}) {
+		t.Errorf("wrong items before key in reverse: %v", keys)
+		return
+	}
+	if keys := collect("a-009", false); len(keys) != 0 {
+		t.Errorf("no items must be after the last key: %v", keys)
+		return
+	}
+	if keys := collect("", true); len(keys) != 10 || keys[0] != "a-009" {
+		t.Errorf("empty key must iterate all the items of prefix: %v", keys)
+		return
+	}
+}
+
+// pagingTestKeys has the adjacent prefixes and the keys, which are same with
+// the prefix, so the boundaries of prefix are checked.
+var pagingTestKeys = []string{
+	"a", "a-", "a--", "a-0", "a-00", "a-01", "a-1", "a-10", "a-\xff", "a-\xff\xff",
+	"a.", "a.0", "ab", "b", "b-", "b-0", "\xff", "\xff\xff",
+}
+
+// expectedPagingKeys is the brute force of `GetIteratorFrom()`.
+func expectedPagingKeys(prefix, from string, reverse bool) (keys []string) {
+	sorted := append([]string{}, pagingTestKeys...)
+	sort.Strings(sorted)
+	for _, key := range sorted {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		if len(from) > 0 && ((!reverse && key <= from) || (reverse && key >= from)) {
+			continue
+		}
+		keys = append(keys, key)
+	}
+
+	if reverse {
+		for i, j := 0, len(keys)-1; i < j; i, j = i+1, j-1 {
+			keys[i], keys[j] = keys[j], keys[i]
+		}
+	}
+
+	return
+}
+
+func TestLevelDBIteratorFromPrefixBoundary(t *testing.T) {
+	st, _ := NewTestMemoryLevelDBBackend()
+	defer st.Close()
+
+	for _, key := range pagingTestKeys {
+		st.New(key, 0)
+	}
+
+	prefixes := []string{"", "a", "a-", "a-0", "a-\xff", "a.", "b-", "c", "\xff"}
+	froms := append([]string{"", "0", "a-/", "a-05", "z", "\xff\xff\xff"}, pagingTestKeys...)
+
+	for _, prefix := range prefixes {
+		for _, from := range froms {
+			for _, reverse := range []bool{false, true} {
+				var keys []string
+				iterFunc, closeFunc := st.GetIteratorFrom(prefix, from, reverse)
+				for {
+					item, hasNext := iterFunc()
+					if !hasNext {
+						break
+					}
+					if item.N != int64(len(keys)+1) {
+						t.Errorf("wrong N: prefix=%q from=%q reverse=%v: %d", prefix, from, reverse, item.N)
+					}
+					keys = append(keys, string(item.Key))
+				}
+				closeFunc()
+
+				if expected := expectedPagingKeys(prefix, from, reverse); !reflect.DeepEqual(expected, keys) {
+					t.Errorf("prefix=%q from=%q reverse=%v: expected=%q collected=%q", prefix, from, reverse, expected, keys)
+				}
+			}
+		}
+	}
+}
+
+func TestLevelDBSeekLast(t *testing.T) {
+	st, _ := NewTestMemoryLevelDBBackend()
+	defer st.Close()
+
+	if _, found := st.SeekLast(""); found {
+		t.Error("empty storage must have no last item")
+		return
+	}
+
+	for _, key := range pagingTestKeys {
+		st.New(key, 0)
+	}
+
+	for _, prefix := range []string{"", "a", "a-", "a-0", "a-1", "a.", "b", "b-0", "c", "\xff"} {
+		expected := expectedPagingKeys(prefix, "", true)
+		item, found := st.SeekLast(prefix)
+		if found != (len(expected) > 0) {
+			t.Errorf("prefix=%q: found=%v", prefix, found)
+			continue
+		}
+		if found && string(item.Key) != expected[0] {
+			t.Errorf("prefix=%q: expected=%q last=%q", prefix, expected[0], string(item.Key))
+		}
+	}
+}
+
+func TestLevelDBGetPage(t *testing.T) {
+	st, _ := NewTestMemoryLevelDBBackend()
+	defer st.Close()
+
+	for i, key := range pagingTestKeys {
+		st.New(key, i)
+	}
+
+	for _, prefix := range []string{"", "a", "a-", "a-0", "a.", "b-", "c"} {
+		for _, reverse := range []bool{false, true} {
+			expected := expectedPagingKeys(prefix, "", reverse)
+			for limit := 1; limit <= len(pagingTestKeys)+1; limit++ {
+				var keys []string
+				var pages int
+				var from string
+				for {
+					items, next := st.GetPage(prefix, from, limit, reverse)
+					if len(items) < 1 && pages > 0 {
+						t.Errorf("prefix=%q reverse=%v limit=%d: empty page after %q", prefix, reverse, limit, from)
+						break
+					}
+					for _, item := range items {
+						keys = append(keys, string(item.Key))
+					}
+					pages++
+
+					if len(next) < 1 {
+						break
+					} else if next != string(items[len(items)-1].Key) {
+						t.Errorf("next must be the last key of page: %q", next)
+						break
+					}
+					from = next
+				}
+
+				if !reflect.DeepEqual(expected, keys) {
+					t.Errorf("prefix=%q reverse=%v limit=%d: expected=%q collected=%q", prefix, reverse, limit, expected, keys)
+				}
+				// the exact number of pages; no empty page at the end
+				if n := (len(expected) + limit - 1) / limit; len(expected) > 0 && pages != n {
+					t.Errorf("prefix=%q reverse=%v limit=%d: expected %d pages, but %d", prefix, reverse, limit, n, pages)
+				}
+			}
+		}
+	}
+
+	// the values are kept after the iterator is released
+	items, _ := st.GetPage("a-", "", 3, false)
+	for i, key := range []string{"a-", "a--", "a-0"} {
+		var v int
+		json.Unmarshal(items[i].Value, &v)
+		if string(items[i].Key) != key || pagingTestKeys[v] != key {
+			t.Errorf("wrong item: %q %d", string(items[i].Key), v)
+		}
+	}
+
+	if items, next := st.GetPage("a-", "", 0, false); len(items) != 0 || len(next) != 0 {
+		t.Error("zero limit must return nothing")
+	}
+}
+
 func TestLevelDBBackendTransactionNew(t *testing.T) {
 	dbpath := fmt.Sprintf("/tmp/%s", sebakcommon.GetUniqueIDFromUUID())
 	defer os.RemoveAll(dbpath)
@@ -432,3 +628,69 @@ func TestLevelDBBackendTransactionDiscard(t *testing.T) {
 
 	return
 }
+
+func TestLevelDBBackendSyncMode(t *testing.T) {
+	for _, s := range []string{"", "none", "deferred", "always"} {
+		config, _ := NewConfigFromString("memory://?sync=" + s)
+		st := &LevelDBBackend{}
+		if err := st.Init(config); err != nil {
+			t.Errorf("failed to initialize with sync mode, '%s': %v", s, err)
+			return
+		}
+		st.Close()
+	}
+
+	config, _ := NewConfigFromString("memory://?sync=sometimes")
+	st := &LevelDBBackend{}
+	if err := st.Init(config); err == nil {
+		t.Error("unknown sync mode must be refused")
+		return
+	}
+}
+
+func TestLevelDBBackendSyncBarrier(t *testing.T) {
+	path, _ := ioutil.TempDir("/tmp", "sebak")
+	defer CleanDB(path)
+
+	config, _ := NewConfigFromString(fmt.Sprintf("file://%s?sync=deferred", path))
+	st, err := NewStorage(config)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if st.SyncMode() != SyncModeDeferred {
+		t.Errorf("wrong sync mode: %s", st.SyncMode())
+		return
+	}
+
+	ts, _ := st.OpenTransaction()
+	ts.New("showme", 1)
+	if err = ts.SyncBarrier(); err == nil {
+		t.Error("sync barrier must not be in transaction")
+		return
+	}
+	ts.Commit()
+
+	if err = st.SyncBarrier(); err != nil {
+		t.Error(err)
+		return
+	}
+	if exists, _ := st.Has(SyncBarrierKey); !exists {
+		t.Error("sync barrier must be written")
+		return
+	}
+	st.Close()
+
+	// reopen
+	if st, err = NewStorage(config); err != nil {
+		t.Error(err)
+		return
+	}
+	defer st.Close()
+
+	var v int
+	if err = st.Get("showme", &v); err != nil || v != 1 {
+		t.Error("committed data must be kept")
+		return
+	}
+}
@@ -0,0 +1,135 @@
+package sebakstorage
+
+import (
+	"strings"
+	"sync"
+)
+
+const DefaultNegativeCacheSize int = 100000
+
+// NegativeCache keeps the keys, which are known not to exist in storage, for
+// the prefixes dominated by the misses, like the checks whether the
+// transaction is already seen. `LevelDBBackend.Has()` answers from the cache
+// without reading storage. The cached key is invalidated when it is written.
+//
+// To prevent the concurrent lookup from caching the key, which is written
+// during the lookup, the key is cached only if no key of the cache is written
+// since the lookup is started; see `Generation()`.
+type NegativeCache struct {
+	sync.Mutex
+
+	prefixes   []string
+	keys       map[string]int // slot of `order`
+	order      []string       // ring of cached keys; the oldest is evicted first
+	next       int
+	generation uint64
+
+	hits   uint64
+	misses uint64
+}
+
+func NewNegativeCache(size int, prefixes ...string) *NegativeCache {
+	if size < 1 {
+		size = DefaultNegativeCacheSize
+	}
+
+	return &NegativeCache{
+		prefixes: prefixes,
+		keys:     map[string]int{},
+		order:    make([]string, size),
+	}
+}
+
+// Covers returns `true` if the key is under the prefixes of cache.
+func (c *NegativeCache) Covers(key string) bool {
+	for _, prefix := range c.prefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsAbsent returns `true` if the key is known not to exist.
+func (c *NegativeCache) IsAbsent(key string) bool {
+	c.Lock()
+	defer c.Unlock()
+
+	_, found := c.keys[key]
+	if found {
+		c.hits++
+	} else {
+		c.misses++
+	}
+
+	return found
+}
+
+// Generation is changed whenever the key of cache is written.
+func (c *NegativeCache) Generation() uint64 {
+	c.Lock()
+	defer c.Unlock()
+
+	return c.generation
+}
+
+// Add caches the key as absent only if the generation is not changed since
+// `generation`; otherwise the key may be written during the lookup.
+func (c *NegativeCache) Add(key string, generation uint64) bool {
+	c.Lock()
+	defer c.Unlock()
+
+	if generation != c.generation {
+		return false
+	}
+	if _, found := c.keys[key]; found {
+		return true
+	}
+
+	if evicted := c.order[c.next]; len(evicted) > 0 && c.keys[evicted] == c.next {
+		delete(c.keys, evicted)
+	}
+	c.order[c.next] = key
+	c.keys[key] = c.next
+	c.next = (c.next + 1) % len(c.order)
+
+	return true
+}
+
+// Invalidate removes the written keys from cache.
+func (c *NegativeCache) Invalidate(keys ...string) {
+	var covered bool
+	for _, key := range keys {
+		if c.Covers(key) {
+			covered = true
+			break
+		}
+	}
+	if !covered {
+		return
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	c.generation++
+	for _, key := range keys {
+		delete(c.keys, key)
+	}
+}
+
+func (c *NegativeCache) Len() int {
+	c.Lock()
+	defer c.Unlock()
+
+	return len(c.keys)
+}
+
+// Stats returns the number of lookups answered by cache and not.
+func (c *NegativeCache) Stats() (hits, misses uint64) {
+	c.Lock()
+	defer c.Unlock()
+
+	return c.hits, c.misses
+}
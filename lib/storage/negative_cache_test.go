@@ -0,0 +1,124 @@
+package sebakstorage
+
+import (
+	"testing"
+)
+
+func TestNegativeCache(t *testing.T) {
+	cache := NewNegativeCache(2, "seen-")
+
+	if cache.Covers("other-a") || !cache.Covers("seen-a") {
+		t.Error("only the keys under prefixes must be covered")
+		return
+	}
+
+	cache.Add("seen-a", cache.Generation())
+	cache.Add("seen-b", cache.Generation())
+	if !cache.IsAbsent("seen-a") || !cache.IsAbsent("seen-b") {
+		t.Error("failed to cache absent keys")
+		return
+	}
+
+	// the oldest is evicted
+	cache.Add("seen-c", cache.Generation())
+	if cache.Len() != 2 || cache.IsAbsent("seen-a") || !cache.IsAbsent("seen-c") {
+		t.Error("the oldest key must be evicted")
+		return
+	}
+
+	// written during lookup
+	generation := cache.Generation()
+	cache.Invalidate("seen-d")
+	if cache.Add("seen-d", generation) || cache.IsAbsent("seen-d") {
+		t.Error("the key written during lookup must not be cached")
+		return
+	}
+
+	cache.Invalidate("seen-c")
+	if cache.IsAbsent("seen-c") {
+		t.Error("the written key must be invalidated")
+		return
+	}
+}
+
+func TestLevelDBBackendNegativeCache(t *testing.T) {
+	st, _ := NewTestMemoryLevelDBBackend()
+	defer st.Close()
+
+	cache := NewNegativeCache(10, "seen-")
+	st.SetNegativeCache(cache)
+
+	if exists, _ := st.Has("seen-a"); exists {
+		t.Error("key must not exist")
+		return
+	}
+	if cache.Len() != 1 {
+		t.Error("missing key must be cached")
+		return
+	}
+	st.Has("seen-a")
+	if hits, _ := cache.Stats(); hits != 1 {
+		t.Error("second lookup must be answered by cache")
+		return
+	}
+
+	// not covered key is not cached
+	st.Has("other-a")
+	if cache.Len() != 1 {
+		t.Error("key out of prefixes must not be cached")
+		return
+	}
+
+	if err := st.New("seen-a", 1); err != nil {
+		t.Error(err)
+		return
+	}
+	if exists, _ := st.Has("seen-a"); !exists {
+		t.Error("written key must be found")
+		return
+	}
+
+	if err := st.Remove("seen-a"); err != nil {
+		t.Error(err)
+		return
+	}
+	if exists, _ := st.Has("seen-a"); exists {
+		t.Error("removed key must not be found")
+		return
+	}
+}
+
+func TestLevelDBBackendNegativeCacheTransaction(t *testing.T) {
+	st, _ := NewTestMemoryLevelDBBackend()
+	defer st.Close()
+
+	cache := NewNegativeCache(10, "seen-")
+	st.SetNegativeCache(cache)
+
+	ts, _ := st.OpenTransaction()
+	if exists, _ := ts.Has("seen-b"); exists {
+		t.Error("key must not exist")
+		return
+	}
+	if cache.Len() != 0 {
+		t.Error("miss in transaction must not be cached")
+		return
+	}
+
+	ts.New("seen-b", 1)
+
+	// looked up outside of transaction before commit
+	if exists, _ := st.Has("seen-b"); exists {
+		t.Error("key in transaction must not be found before commit")
+		return
+	}
+
+	if err := ts.Commit(); err != nil {
+		t.Error(err)
+		return
+	}
+	if exists, _ := st.Has("seen-b"); !exists {
+		t.Error("committed key must be found")
+		return
+	}
+}
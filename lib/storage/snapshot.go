@@ -0,0 +1,296 @@
+package sebakstorage
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"time"
+)
+
+// Snapshot is the named backup of storage. Each snapshot is kept in it's own
+// directory under the snapshot directory,
+//
+//  * '<snapshot directory>/<name>/db': backup of storage
+//  * '<snapshot directory>/<name>/snapshot.json': `Snapshot` itself
+
+const (
+	snapshotDBDirectory = "db"
+	snapshotMetaFile    = "snapshot.json"
+)
+
+var snapshotNameRegexp = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._-]*$`)
+
+type Snapshot struct {
+	Name    string    `json:"name"`
+	Created time.Time `json:"created"`
+
+	path string
+}
+
+func (s Snapshot) Path() string {
+	return s.path
+}
+
+func (s Snapshot) DBPath() string {
+	return filepath.Join(s.path, snapshotDBDirectory)
+}
+
+func (s Snapshot) Age() time.Duration {
+	return time.Since(s.Created)
+}
+
+// Size returns the total bytes of the files in snapshot.
+func (s Snapshot) Size() (size int64, err error) {
+	err = filepath.Walk(s.path, func(_ string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			size += info.Size()
+		}
+		return nil
+	})
+
+	return
+}
+
+func CheckSnapshotName(name string) error {
+	if !snapshotNameRegexp.MatchString(name) {
+		return errors.New("invalid snapshot name; only alphanumeric, '.', '_' and '-' are allowed")
+	}
+
+	return nil
+}
+
+// CreateSnapshot makes new snapshot, `name` of the storage in the snapshot
+// directory, `dir`.
+func CreateSnapshot(st *LevelDBBackend, dir, name string) (snapshot Snapshot, err error) {
+	if err = CheckSnapshotName(name); err != nil {
+		return
+	}
+
+	path := filepath.Join(dir, name)
+	if _, err = os.Stat(path); err == nil {
+		err = errors.New("snapshot already exists")
+		return
+	} else if !os.IsNotExist(err) {
+		return
+	}
+
+	if err = os.MkdirAll(path, 0755); err != nil {
+		return
+	}
+
+	snapshot = Snapshot{Name: name, Created: time.Now(), path: path}
+	if err = st.Backup(snapshot.DBPath()); err != nil {
+		os.RemoveAll(path)
+		return
+	}
+
+	var b []byte
+	if b, err = json.Marshal(snapshot); err != nil {
+		os.RemoveAll(path)
+		return
+	}
+	if err = ioutil.WriteFile(filepath.Join(path, snapshotMetaFile), b, 0644); err != nil {
+		os.RemoveAll(path)
+		return
+	}
+
+	return
+}
+
+func GetSnapshot(dir, name string) (snapshot Snapshot, err error) {
+	if err = CheckSnapshotName(name); err != nil {
+		return
+	}
+
+	path := filepath.Join(dir, name)
+
+	var b []byte
+	if b, err = ioutil.ReadFile(filepath.Join(path, snapshotMetaFile)); err != nil {
+		return
+	}
+	if err = json.Unmarshal(b, &snapshot); err != nil {
+		return
+	}
+	snapshot.path = path
+
+	return
+}
+
+// ListSnapshots returns the snapshots in the snapshot directory, `dir`, sorted
+// by created time; the oldest one comes first.
+func ListSnapshots(dir string) (snapshots []Snapshot, err error) {
+	var infos []os.FileInfo
+	if infos, err = ioutil.ReadDir(dir); err != nil {
+		if os.IsNotExist(err) {
+			err = nil
+		}
+		return
+	}
+
+	for _, info := range infos {
+		if !info.IsDir() {
+			continue
+		}
+
+		snapshot, err := GetSnapshot(dir, info.Name())
+		if err != nil {
+			// not snapshot directory
+			continue
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].Created.Before(snapshots[j].Created)
+	})
+
+	return
+}
+
+func RemoveSnapshot(dir, name string) (err error) {
+	var snapshot Snapshot
+	if snapshot, err = GetSnapshot(dir, name); err != nil {
+		return
+	}
+
+	return os.RemoveAll(snapshot.Path())
+}
+
+// SnapshotRestoreCheck decides whether the snapshot can replace the current
+// storage.
+type SnapshotRestoreCheck func(current, snapshot *LevelDBBackend) error
+
+// RestoreSnapshot replaces the file storage of `config` with the snapshot. The
+// storage must not be opened while restoring.
+func RestoreSnapshot(dir, name string, config *Config) (err error) {
+	return RestoreSnapshotWithCheck(dir, name, config, nil)
+}
+
+// RestoreSnapshotWithCheck is `RestoreSnapshot`, but if the current storage
+// exists, the snapshot is restored only when `check` passes.
+func RestoreSnapshotWithCheck(dir, name string, config *Config, check SnapshotRestoreCheck) (err error) {
+	if config.Scheme != "file" {
+		err = errors.New("only file storage can be restored")
+		return
+	}
+
+	var snapshot Snapshot
+	if snapshot, err = GetSnapshot(dir, name); err != nil {
+		return
+	}
+
+	var sst *LevelDBBackend
+	if sst, err = NewStorage(&Config{Scheme: "file", Path: snapshot.DBPath()}); err != nil {
+		return
+	}
+	defer sst.Close()
+
+	if check != nil {
+		if _, e := os.Stat(config.Path); e == nil {
+			var st *LevelDBBackend
+			if st, err = NewStorage(config); err != nil {
+				return
+			}
+			err = check(st, sst)
+			st.Close()
+			if err != nil {
+				return
+			}
+		} else if !os.IsNotExist(e) {
+			err = e
+			return
+		}
+	}
+
+	// restore into the temporary path first, so the failed restore does not
+	// break the current storage.
+	restorePath := config.Path + ".restoring"
+	os.RemoveAll(restorePath)
+	if err = sst.Backup(restorePath); err != nil {
+		os.RemoveAll(restorePath)
+		return
+	}
+
+	if err = os.RemoveAll(config.Path); err != nil {
+		return
+	}
+
+	err = os.Rename(restorePath, config.Path)
+
+	return
+}
+
+// SnapshotRetentionPolicy decides which snapshots will be kept,
+//  * `KeepLast`: the latest N snapshots are kept
+//  * `KeepDaily`: for the latest N days which have snapshots, the newest
+//  snapshot of each day is kept
+// Snapshots matching any of them are kept. If both are 0, nothing will be
+// removed.
+type SnapshotRetentionPolicy struct {
+	KeepLast  int
+	KeepDaily int
+}
+
+func (p SnapshotRetentionPolicy) IsEmpty() bool {
+	return p.KeepLast < 1 && p.KeepDaily < 1
+}
+
+// Expired returns the snapshots which are not kept by the policy. `snapshots`
+// must be sorted by created time like `ListSnapshots()`.
+func (p SnapshotRetentionPolicy) Expired(snapshots []Snapshot) (expired []Snapshot) {
+	if p.IsEmpty() {
+		return
+	}
+
+	keep := map[string]bool{}
+
+	for i := len(snapshots) - 1; i >= 0 && len(snapshots)-i <= p.KeepLast; i-- {
+		keep[snapshots[i].Name] = true
+	}
+
+	var days []string
+	for i := len(snapshots) - 1; i >= 0; i-- {
+		day := snapshots[i].Created.Local().Format("2006-01-02")
+		if len(days) > 0 && days[len(days)-1] == day {
+			continue
+		}
+		if len(days) >= p.KeepDaily {
+			break
+		}
+		days = append(days, day)
+		keep[snapshots[i].Name] = true
+	}
+
+	for _, s := range snapshots {
+		if keep[s.Name] {
+			continue
+		}
+		expired = append(expired, s)
+	}
+
+	return
+}
+
+// ApplySnapshotRetention removes the expired snapshots by the policy.
+func ApplySnapshotRetention(dir string, policy SnapshotRetentionPolicy) (removed []Snapshot, err error) {
+	var snapshots []Snapshot
+	if snapshots, err = ListSnapshots(dir); err != nil {
+		return
+	}
+
+	for _, s := range policy.Expired(snapshots) {
+		if err = os.RemoveAll(s.Path()); err != nil {
+			return
+		}
+		removed = append(removed, s)
+	}
+
+	return
+}
@@ -0,0 +1,149 @@
+package sebakstorage
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSnapshotCreateAndRestore(t *testing.T) {
+	dir, _ := ioutil.TempDir("/tmp", "sebak")
+	defer CleanDB(dir)
+
+	dbPath := filepath.Join(dir, "db")
+	st, _ := NewTestFileLevelDBBackend(dbPath)
+
+	for i := 0; i < 10; i++ {
+		st.New(fmt.Sprintf("key-%d", i), i)
+	}
+
+	snapshotDir := filepath.Join(dir, "snapshots")
+	snapshot, err := CreateSnapshot(st, snapshotDir, "pre-upgrade")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if size, err := snapshot.Size(); err != nil || size < 1 {
+		t.Error("empty snapshot")
+		return
+	}
+
+	if _, err := CreateSnapshot(st, snapshotDir, "pre-upgrade"); err == nil {
+		t.Error("snapshot with same name must not be created")
+		return
+	}
+	if _, err := CreateSnapshot(st, snapshotDir, "../escape"); err == nil {
+		t.Error("invalid snapshot name must be refused")
+		return
+	}
+
+	// changes after snapshot
+	st.Set("key-0", 100)
+	st.New("key-new", 1)
+	st.Close()
+
+	config, _ := NewConfigFromString(fmt.Sprintf("file://%s", dbPath))
+	if err := RestoreSnapshot(snapshotDir, "pre-upgrade", config); err != nil {
+		t.Error(err)
+		return
+	}
+
+	st, _ = NewStorage(config)
+	defer st.Close()
+
+	var v int
+	if err := st.Get("key-0", &v); err != nil || v != 0 {
+		t.Error("failed to restore snapshot")
+		return
+	}
+	if exists, _ := st.Has("key-new"); exists {
+		t.Error("changes after snapshot must not be restored")
+		return
+	}
+}
+
+func TestSnapshotList(t *testing.T) {
+	dir, _ := ioutil.TempDir("/tmp", "sebak")
+	defer CleanDB(dir)
+
+	st, _ := NewTestMemoryLevelDBBackend()
+	defer st.Close()
+	st.New("showme", 1)
+
+	names := []string{"a", "b", "c"}
+	for _, name := range names {
+		if _, err := CreateSnapshot(st, dir, name); err != nil {
+			t.Error(err)
+			return
+		}
+	}
+
+	snapshots, err := ListSnapshots(dir)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if len(snapshots) != len(names) {
+		t.Errorf("wrong number of snapshots; %d != %d", len(snapshots), len(names))
+		return
+	}
+	for i, s := range snapshots {
+		if s.Name != names[i] {
+			t.Error("snapshots must be sorted by created")
+			return
+		}
+	}
+
+	if err := RemoveSnapshot(dir, "b"); err != nil {
+		t.Error(err)
+		return
+	}
+	if snapshots, _ = ListSnapshots(dir); len(snapshots) != len(names)-1 {
+		t.Error("failed to remove snapshot")
+		return
+	}
+}
+
+func TestSnapshotRetentionPolicy(t *testing.T) {
+	day := func(d, h int) time.Time {
+		return time.Date(2018, 6, d, h, 0, 0, 0, time.Local)
+	}
+
+	snapshots := []Snapshot{
+		{Name: "1-0", Created: day(1, 0)},
+		{Name: "1-1", Created: day(1, 1)},
+		{Name: "2-0", Created: day(2, 0)},
+		{Name: "3-0", Created: day(3, 0)},
+		{Name: "3-1", Created: day(3, 1)},
+		{Name: "3-2", Created: day(3, 2)},
+	}
+
+	expiredNames := func(policy SnapshotRetentionPolicy) (names []string) {
+		for _, s := range policy.Expired(snapshots) {
+			names = append(names, s.Name)
+		}
+		return
+	}
+
+	if names := expiredNames(SnapshotRetentionPolicy{}); len(names) != 0 {
+		t.Error("empty policy must not expire snapshots")
+		return
+	}
+
+	if names := expiredNames(SnapshotRetentionPolicy{KeepLast: 2}); fmt.Sprint(names) != "[1-0 1-1 2-0 3-0]" {
+		t.Errorf("wrong expired snapshots by `KeepLast`: %v", names)
+		return
+	}
+
+	if names := expiredNames(SnapshotRetentionPolicy{KeepDaily: 2}); fmt.Sprint(names) != "[1-0 1-1 3-0 3-1]" {
+		t.Errorf("wrong expired snapshots by `KeepDaily`: %v", names)
+		return
+	}
+
+	if names := expiredNames(SnapshotRetentionPolicy{KeepLast: 2, KeepDaily: 3}); fmt.Sprint(names) != "[1-0 3-0]" {
+		t.Errorf("wrong expired snapshots: %v", names)
+		return
+	}
+}
@@ -0,0 +1,677 @@
+package sebak
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+
+	"github.com/agl/ed25519/edwards25519"
+	"github.com/btcsuite/btcutil/base58"
+	"github.com/stellar/go/keypair"
+	"github.com/stellar/go/strkey"
+
+	"boscoin.io/sebak/lib/error"
+)
+
+// The threshold signature splits the secret key of validator into the shares
+// by Shamir's secret sharing, and `Threshold` of the signers, which keep one
+// share each, sign together by the two rounds of FROST,
+//  * commit: every signer makes the pair of nonces and sends their
+//  commitments, `ThresholdCommitment`
+//  * sign: with the commitments of all the participants, every signer makes
+//  the partial signature, `ThresholdPartialSignature`
+// The sum of the partial signatures is the plain ed25519 signature of the
+// validator, so the other validators verify it like before. No signer can sign
+// alone, and every signer refuses the message, which conflicts with the
+// messages signed before, so the validator can not equivocate by one
+// compromised box.
+
+// The secret scalars, like the shares and the nonces, are handled only by the
+// constant-time `ScReduce()` and `ScMulAdd()` of edwards25519, which are used
+// by ed25519 itself; `math/big` is used only for the public values, like the
+// Lagrange coefficients.
+
+// thresholdOrder is the order of the base point of ed25519, 'L'.
+var thresholdOrder, _ = new(big.Int).SetString(
+	"7237005577332262213973186563042994240857116359379907606001950938285454250989",
+	10,
+)
+
+// thresholdD2 is '2 * d' of the curve, which is needed to add the points.
+var thresholdD2 = func() (d2 edwards25519.FieldElement) {
+	d := [32]byte{
+		0xa3, 0x78, 0x59, 0x13, 0xca, 0x4d, 0xeb, 0x75, 0xab, 0xd8, 0x41, 0x41, 0x4d, 0x0a, 0x70, 0x00,
+		0x98, 0xe8, 0x79, 0x77, 0x79, 0x40, 0xc7, 0x8c, 0x73, 0xfe, 0x6f, 0x2b, 0xee, 0x6c, 0x03, 0x52,
+	}
+
+	var fe edwards25519.FieldElement
+	edwards25519.FeFromBytes(&fe, &d)
+	edwards25519.FeAdd(&fe, &fe, &fe)
+
+	// normalize the limbs
+	edwards25519.FeToBytes(&d, &fe)
+	edwards25519.FeFromBytes(&d2, &d)
+
+	return
+}()
+
+var scalarZero, scalarOne = [32]byte{}, [32]byte{1}
+
+// scalarFromBytes reads the little-endian integer of up to 64 bytes modulo
+// 'L'.
+func scalarFromBytes(b []byte) (s [32]byte) {
+	var wide [64]byte
+	copy(wide[:], b)
+	edwards25519.ScReduce(&s, &wide)
+
+	return
+}
+
+// scalarFromBig converts the public integer, like the Lagrange coefficient.
+func scalarFromBig(i *big.Int) (s [32]byte) {
+	be := new(big.Int).Mod(i, thresholdOrder).Bytes()
+	for j := range be {
+		s[j] = be[len(be)-1-j]
+	}
+
+	return
+}
+
+// scalarMulAdd returns 'a * b + c' modulo 'L'.
+func scalarMulAdd(a, b, c [32]byte) (s [32]byte) {
+	edwards25519.ScMulAdd(&s, &a, &b, &c)
+	return
+}
+
+func scalarMul(a, b [32]byte) [32]byte {
+	return scalarMulAdd(a, b, scalarZero)
+}
+
+func scalarAdd(a, b [32]byte) [32]byte {
+	return scalarMulAdd(a, scalarOne, b)
+}
+
+func hashToScalar(parts ...[]byte) [32]byte {
+	h := sha512.New()
+	for _, part := range parts {
+		h.Write(part)
+	}
+
+	return scalarFromBytes(h.Sum(nil))
+}
+
+func randomScalar() (s [32]byte, err error) {
+	b := make([]byte, 64)
+	if _, err = rand.Read(b); err != nil {
+		return
+	}
+
+	return scalarFromBytes(b), nil
+}
+
+func scalarMultBase(s [32]byte) (p [32]byte) {
+	var e edwards25519.ExtendedGroupElement
+	edwards25519.GeScalarMultBase(&e, &s)
+	e.ToBytes(&p)
+
+	return
+}
+
+// scalarMultPoint is not constant-time, so `s` must be public, like the
+// binding factor and the challenge.
+func scalarMultPoint(s [32]byte, p [32]byte) (r [32]byte, err error) {
+	var e edwards25519.ExtendedGroupElement
+	if !e.FromBytes(&p) {
+		err = sebakerror.ErrorThresholdInvalidPoint
+		return
+	}
+
+	var projective edwards25519.ProjectiveGroupElement
+	edwards25519.GeDoubleScalarMultVartime(&projective, &s, &e, &scalarZero)
+	projective.ToBytes(&r)
+
+	return
+}
+
+// addPoints adds the points in the extended coordinates,
+// 'add-2008-hwcd-3' of https://hyperelliptic.org/EFD/g1p/auto-twisted-extended-1.html .
+func addPoints(a, b [32]byte) (r [32]byte, err error) {
+	var p, q edwards25519.ExtendedGroupElement
+	if !p.FromBytes(&a) || !q.FromBytes(&b) {
+		err = sebakerror.ErrorThresholdInvalidPoint
+		return
+	}
+
+	var t0, t1, fa, fb, fc, fd edwards25519.FieldElement
+	edwards25519.FeSub(&t0, &p.Y, &p.X)
+	edwards25519.FeSub(&t1, &q.Y, &q.X)
+	edwards25519.FeMul(&fa, &t0, &t1)
+	edwards25519.FeAdd(&t0, &p.Y, &p.X)
+	edwards25519.FeAdd(&t1, &q.Y, &q.X)
+	edwards25519.FeMul(&fb, &t0, &t1)
+	edwards25519.FeMul(&fc, &p.T, &q.T)
+	edwards25519.FeMul(&fc, &fc, &thresholdD2)
+	edwards25519.FeMul(&fd, &p.Z, &q.Z)
+	edwards25519.FeAdd(&fd, &fd, &fd)
+
+	var fe, ff, fg, fh edwards25519.FieldElement
+	edwards25519.FeSub(&fe, &fb, &fa)
+	edwards25519.FeSub(&ff, &fd, &fc)
+	edwards25519.FeAdd(&fg, &fd, &fc)
+	edwards25519.FeAdd(&fh, &fb, &fa)
+
+	var s edwards25519.ExtendedGroupElement
+	edwards25519.FeMul(&s.X, &fe, &ff)
+	edwards25519.FeMul(&s.Y, &fg, &fh)
+	edwards25519.FeMul(&s.T, &fe, &fh)
+	edwards25519.FeMul(&s.Z, &ff, &fg)
+	s.ToBytes(&r)
+
+	return
+}
+
+func decodePoint(s string) (p [32]byte, err error) {
+	b := base58.Decode(s)
+	if len(b) != 32 {
+		err = sebakerror.ErrorThresholdInvalidPoint
+		return
+	}
+	copy(p[:], b)
+
+	return
+}
+
+// thresholdLagrange is the Lagrange coefficient of the share, `index` at 0
+// among the shares of `indexes`.
+func thresholdLagrange(index int, indexes []int) [32]byte {
+	num, den := big.NewInt(1), big.NewInt(1)
+	for _, j := range indexes {
+		if j == index {
+			continue
+		}
+		num.Mul(num, big.NewInt(int64(j)))
+		den.Mul(den, big.NewInt(int64(j-index)))
+	}
+	den.Mod(den, thresholdOrder)
+	den.ModInverse(den, thresholdOrder)
+
+	return scalarFromBig(num.Mul(num, den))
+}
+
+func validatorPublicKey(address string) (p [32]byte, err error) {
+	var b []byte
+	if b, err = strkey.Decode(strkey.VersionByteAccountID, address); err != nil || len(b) != 32 {
+		err = sebakerror.ErrorBadPublicAddress
+		return
+	}
+	copy(p[:], b)
+
+	return
+}
+
+// ThresholdShare is one share of the secret key of `Validator`; `Secret` must
+// be kept by only one signer.
+type ThresholdShare struct {
+	Validator string `json:"validator"`
+	Index     int    `json:"index"` // from 1
+	Threshold int    `json:"threshold"`
+	Total     int    `json:"total"`
+	Secret    string `json:"secret"`
+}
+
+func (s ThresholdShare) secret() [32]byte {
+	return scalarFromBytes(base58.Decode(s.Secret))
+}
+
+// Public is the public key of share, which verifies the partial signatures.
+func (s ThresholdShare) Public() string {
+	p := scalarMultBase(s.secret())
+	return base58.Encode(p[:])
+}
+
+// SplitKeypair splits the secret key of validator into `total` shares, and
+// any `threshold` of them can sign together. The secret seed must be removed
+// from the node after the shares are given to the signers.
+func SplitKeypair(kp *keypair.Full, threshold, total int) (shares []ThresholdShare, err error) {
+	if threshold < 2 || threshold > total {
+		err = fmt.Errorf("threshold must be between 2 and %d", total)
+		return
+	}
+
+	var seed []byte
+	if seed, err = strkey.Decode(strkey.VersionByteSeed, kp.Seed()); err != nil {
+		return
+	}
+
+	// the secret scalar of ed25519
+	digest := sha512.Sum512(seed)
+	digest[0] &= 248
+	digest[31] &= 127
+	digest[31] |= 64
+
+	coefficients := [][32]byte{scalarFromBytes(digest[:32])}
+	for i := 1; i < threshold; i++ {
+		var c [32]byte
+		if c, err = randomScalar(); err != nil {
+			return
+		}
+		coefficients = append(coefficients, c)
+	}
+
+	for index := 1; index <= total; index++ {
+		x := scalarFromBig(big.NewInt(int64(index)))
+		var y [32]byte
+		for i := len(coefficients) - 1; i >= 0; i-- {
+			y = scalarMulAdd(y, x, coefficients[i])
+		}
+
+		shares = append(shares, ThresholdShare{
+			Validator: kp.Address(),
+			Index:     index,
+			Threshold: threshold,
+			Total:     total,
+			Secret:    base58.Encode(y[:]),
+		})
+	}
+
+	return
+}
+
+type ThresholdCommitment struct {
+	Index   int    `json:"index"`
+	Hiding  string `json:"hiding"`
+	Binding string `json:"binding"`
+	Public  string `json:"public"` // the public key of share
+}
+
+type ThresholdPartialSignature struct {
+	Index     int    `json:"index"`
+	Signature string `json:"signature"`
+}
+
+// ThresholdSignRequest asks the signer to sign the message, like the ballot;
+// `Type` is the `T` of message and `Commitments` are of all the participants.
+type ThresholdSignRequest struct {
+	Type        string                `json:"type"`
+	Data        json.RawMessage       `json:"data"`
+	Commitments []ThresholdCommitment `json:"commitments"`
+}
+
+// thresholdSession has the values, which are derived from the commitments of
+// participants and the message.
+type thresholdSession struct {
+	indexes   []int
+	bindings  map[int][32]byte
+	commits   map[int][32]byte // hiding + binding * binding factor
+	r         [32]byte
+	challenge [32]byte
+}
+
+func newThresholdSession(validator string, message []byte, commitments []ThresholdCommitment) (session thresholdSession, err error) {
+	sorted := append([]ThresholdCommitment{}, commitments...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Index < sorted[j].Index })
+
+	var encoded bytes.Buffer
+	for i, c := range sorted {
+		if c.Index < 1 || (i > 0 && sorted[i-1].Index == c.Index) {
+			err = sebakerror.ErrorThresholdInvalidCommitment
+			return
+		}
+		binary.Write(&encoded, binary.BigEndian, uint64(c.Index))
+		encoded.WriteString(c.Hiding)
+		encoded.WriteString(c.Binding)
+		session.indexes = append(session.indexes, c.Index)
+	}
+
+	session.bindings = map[int][32]byte{}
+	session.commits = map[int][32]byte{}
+	for i, c := range sorted {
+		var index [8]byte
+		binary.BigEndian.PutUint64(index[:], uint64(c.Index))
+		binding := hashToScalar([]byte("sebak-threshold-binding"), index[:], message, encoded.Bytes())
+
+		var hiding, bound [32]byte
+		if hiding, err = decodePoint(c.Hiding); err != nil {
+			return
+		}
+		if bound, err = decodePoint(c.Binding); err != nil {
+			return
+		}
+		if bound, err = scalarMultPoint(binding, bound); err != nil {
+			return
+		}
+		var commit [32]byte
+		if commit, err = addPoints(hiding, bound); err != nil {
+			return
+		}
+
+		session.bindings[c.Index] = binding
+		session.commits[c.Index] = commit
+		if i == 0 {
+			session.r = commit
+		} else if session.r, err = addPoints(session.r, commit); err != nil {
+			return
+		}
+	}
+
+	var public [32]byte
+	if public, err = validatorPublicKey(validator); err != nil {
+		return
+	}
+	session.challenge = hashToScalar(session.r[:], public[:], message)
+
+	return
+}
+
+// AggregateThresholdSignature verifies the partial signatures and sums them
+// into the ed25519 signature of validator.
+func AggregateThresholdSignature(
+	validator string,
+	message []byte,
+	commitments []ThresholdCommitment,
+	partials []ThresholdPartialSignature,
+) (signature []byte, err error) {
+	var session thresholdSession
+	if session, err = newThresholdSession(validator, message, commitments); err != nil {
+		return
+	}
+	if len(partials) != len(commitments) {
+		err = sebakerror.ErrorThresholdInvalidSignature
+		return
+	}
+
+	publics := map[int]string{}
+	for _, c := range commitments {
+		publics[c.Index] = c.Public
+	}
+
+	var sum [32]byte
+	for _, partial := range partials {
+		commit, found := session.commits[partial.Index]
+		if !found {
+			err = sebakerror.ErrorThresholdInvalidSignature
+			return
+		}
+
+		// z * B == commit + challenge * lambda * public
+		z := scalarFromBytes(base58.Decode(partial.Signature))
+		var public, expected [32]byte
+		if public, err = decodePoint(publics[partial.Index]); err != nil {
+			return
+		}
+		k := scalarMul(session.challenge, thresholdLagrange(partial.Index, session.indexes))
+		if expected, err = scalarMultPoint(k, public); err != nil {
+			return
+		}
+		if expected, err = addPoints(commit, expected); err != nil {
+			return
+		}
+		if scalarMultBase(z) != expected {
+			err = sebakerror.ErrorThresholdInvalidSignature
+			return
+		}
+
+		sum = scalarAdd(sum, z)
+	}
+
+	signature = append(append([]byte{}, session.r[:]...), sum[:]...)
+
+	var kp keypair.KP
+	if kp, err = keypair.Parse(validator); err != nil {
+		return
+	}
+	if err = kp.Verify(message, signature); err != nil {
+		err = sebakerror.ErrorThresholdInvalidSignature
+		return
+	}
+
+	return
+}
+
+// MaxThresholdSignerNonces is the maximum number of the nonces, which are
+// committed, but not used yet; the oldest ones are dropped.
+const MaxThresholdSignerNonces int = 1000
+
+// MaxThresholdSignerVotes is the maximum number of the signed votes and the
+// announced blocks, which are kept to refuse the conflicting messages.
+const MaxThresholdSignerVotes int = 100000
+
+type thresholdNonce struct {
+	hiding  [32]byte
+	binding [32]byte
+}
+
+// ThresholdSigner keeps one share in the signer daemon.
+type ThresholdSigner struct {
+	sync.Mutex
+
+	networkID []byte
+	share     ThresholdShare
+	secret    [32]byte
+	public    string
+
+	nonces     map[string]thresholdNonce // by the hiding commitment
+	nonceOrder []string
+	votes      map[string]VotingHole // by '<message hash>-<ballot state>'
+	voteOrder  []string
+	announced  map[uint64]string // block hash by height
+	blockOrder []uint64
+	// the highest height of the announced blocks; the view change for the
+	// height, which is already committed, is refused.
+	announcedHeight uint64
+}
+
+func NewThresholdSigner(networkID []byte, share ThresholdShare) (signer *ThresholdSigner, err error) {
+	if _, err = validatorPublicKey(share.Validator); err != nil {
+		return
+	}
+	if share.Index < 1 || share.Index > share.Total || len(base58.Decode(share.Secret)) != 32 {
+		err = sebakerror.ErrorThresholdInvalidShare
+		return
+	}
+
+	signer = &ThresholdSigner{
+		networkID: networkID,
+		share:     share,
+		secret:    share.secret(),
+		public:    share.Public(),
+		nonces:    map[string]thresholdNonce{},
+		votes:     map[string]VotingHole{},
+		announced: map[uint64]string{},
+	}
+
+	return
+}
+
+func (s *ThresholdSigner) Share() ThresholdShare {
+	return s.share
+}
+
+// Commit makes the new nonces; they are used only once by `Sign()`.
+func (s *ThresholdSigner) Commit() (commitment ThresholdCommitment, err error) {
+	var nonce thresholdNonce
+	if nonce.hiding, err = randomScalar(); err != nil {
+		return
+	}
+	if nonce.binding, err = randomScalar(); err != nil {
+		return
+	}
+
+	hiding, binding := scalarMultBase(nonce.hiding), scalarMultBase(nonce.binding)
+	commitment = ThresholdCommitment{
+		Index:   s.share.Index,
+		Hiding:  base58.Encode(hiding[:]),
+		Binding: base58.Encode(binding[:]),
+		Public:  s.public,
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	s.nonces[commitment.Hiding] = nonce
+	s.nonceOrder = append(s.nonceOrder, commitment.Hiding)
+	if len(s.nonceOrder) > MaxThresholdSignerNonces {
+		delete(s.nonces, s.nonceOrder[0])
+		s.nonceOrder = s.nonceOrder[1:]
+	}
+
+	return
+}
+
+// checkMessage checks the message of request is for the validator and
+// returns it's hash. The message, which conflicts with the signed ones, is
+// refused,
+//  * ballot: the different vote for the same message and state
+//  * view change: the height, which is already committed by the announced
+//  block
+//  * block announcement: the different block at the announced height
+// `record` keeps the message after it is signed.
+func (s *ThresholdSigner) checkMessage(request ThresholdSignRequest) (hash string, record func(), err error) {
+	var nodeKey, expected string
+	switch request.Type {
+	case "ballot":
+		var ballot Ballot
+		if err = json.Unmarshal(request.Data, &ballot); err != nil {
+			return
+		}
+		nodeKey, hash, expected = ballot.B.NodeKey, ballot.H.Hash, ballot.MakeHashString()
+
+		vote, votingHole := fmt.Sprintf("%s-%s", ballot.B.Hash, ballot.B.State), ballot.B.VotingHole
+		if voted, found := s.votes[vote]; found && voted != votingHole {
+			err = sebakerror.ErrorThresholdEquivocation
+			return
+		}
+		record = func() { s.recordVote(vote, votingHole) }
+	case "view-change":
+		var vc ViewChange
+		if err = json.Unmarshal(request.Data, &vc); err != nil {
+			return
+		}
+		nodeKey, hash, expected = vc.B.NodeKey, vc.H.Hash, vc.B.MakeHashString()
+
+		if vc.B.Height <= s.announcedHeight {
+			err = sebakerror.ErrorThresholdEquivocation
+			return
+		}
+	case "block-announcement":
+		var ba BlockAnnouncement
+		if err = json.Unmarshal(request.Data, &ba); err != nil {
+			return
+		}
+		nodeKey, hash, expected = ba.B.NodeKey, ba.H.Hash, ba.B.MakeHashString()
+
+		block := ba.B.Block
+		if announced, found := s.announced[block.Height]; found && announced != block.Hash {
+			err = sebakerror.ErrorThresholdEquivocation
+			return
+		}
+		record = func() { s.recordBlock(block) }
+	default:
+		err = sebakerror.ErrorUnknownMessageType
+		return
+	}
+
+	if nodeKey != s.share.Validator {
+		err = sebakerror.ErrorBadPublicAddress
+		return
+	}
+	if hash != expected {
+		err = sebakerror.ErrorHashDoesNotMatch
+		return
+	}
+
+	return
+}
+
+func (s *ThresholdSigner) recordVote(vote string, votingHole VotingHole) {
+	if _, found := s.votes[vote]; found {
+		return
+	}
+
+	s.votes[vote] = votingHole
+	s.voteOrder = append(s.voteOrder, vote)
+	if len(s.voteOrder) > MaxThresholdSignerVotes {
+		delete(s.votes, s.voteOrder[0])
+		s.voteOrder = s.voteOrder[1:]
+	}
+}
+
+func (s *ThresholdSigner) recordBlock(block Block) {
+	if block.Height > s.announcedHeight {
+		s.announcedHeight = block.Height
+	}
+	if _, found := s.announced[block.Height]; found {
+		return
+	}
+
+	s.announced[block.Height] = block.Hash
+	s.blockOrder = append(s.blockOrder, block.Height)
+	if len(s.blockOrder) > MaxThresholdSignerVotes {
+		delete(s.announced, s.blockOrder[0])
+		s.blockOrder = s.blockOrder[1:]
+	}
+}
+
+// Sign makes the partial signature of the message with the nonces of it's
+// commitment in the request.
+func (s *ThresholdSigner) Sign(request ThresholdSignRequest) (partial ThresholdPartialSignature, err error) {
+	s.Lock()
+	defer s.Unlock()
+
+	var nonce thresholdNonce
+	var found bool
+	for _, c := range request.Commitments {
+		if c.Index != s.share.Index {
+			continue
+		}
+		if nonce, found = s.nonces[c.Hiding]; !found {
+			break
+		}
+		// the nonces must not be used twice even if the signing fails
+		delete(s.nonces, c.Hiding)
+		for i, h := range s.nonceOrder {
+			if h == c.Hiding {
+				s.nonceOrder = append(s.nonceOrder[:i], s.nonceOrder[i+1:]...)
+				break
+			}
+		}
+	}
+	if !found {
+		err = sebakerror.ErrorThresholdInvalidCommitment
+		return
+	}
+	if len(request.Commitments) < s.share.Threshold {
+		err = sebakerror.ErrorThresholdNotEnoughSigners
+		return
+	}
+
+	hash, record, err := s.checkMessage(request)
+	if err != nil {
+		return
+	}
+	message := append(append([]byte{}, s.networkID...), []byte(hash)...)
+
+	var session thresholdSession
+	if session, err = newThresholdSession(s.share.Validator, message, request.Commitments); err != nil {
+		return
+	}
+
+	// z = hiding + binding * binding factor + lambda * secret * challenge
+	z := scalarMulAdd(nonce.binding, session.bindings[s.share.Index], nonce.hiding)
+	k := scalarMul(thresholdLagrange(s.share.Index, session.indexes), s.secret)
+	z = scalarMulAdd(k, session.challenge, z)
+
+	partial = ThresholdPartialSignature{Index: s.share.Index, Signature: base58.Encode(z[:])}
+
+	if record != nil {
+		record()
+	}
+
+	return
+}
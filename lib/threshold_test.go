@@ -0,0 +1,282 @@
+package sebak
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stellar/go/keypair"
+
+	"boscoin.io/sebak/lib/common"
+	"boscoin.io/sebak/lib/error"
+)
+
+func makeThresholdSigners(t *testing.T, kp *keypair.Full, threshold, total int) (signers []*ThresholdSigner) {
+	shares, err := SplitKeypair(kp, threshold, total)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, share := range shares {
+		signer, err := NewThresholdSigner(networkID, share)
+		if err != nil {
+			t.Fatal(err)
+		}
+		signers = append(signers, signer)
+	}
+
+	return
+}
+
+func makeThresholdBallot(kp *keypair.Full, votingHole VotingHole) (ballot Ballot, request ThresholdSignRequest) {
+	_, tx := TestMakeTransaction(networkID, 1)
+	ballot, _ = NewBallotFromMessage(kp.Address(), tx)
+	ballot.SetState(sebakcommon.BallotStateSIGN)
+	ballot.Vote(votingHole)
+	ballot.UpdateHash()
+
+	data, _ := json.Marshal(ballot)
+	request = ThresholdSignRequest{Type: ballot.T, Data: data}
+
+	return
+}
+
+// thresholdSign runs the two rounds with the signers.
+func thresholdSign(signers []*ThresholdSigner, request ThresholdSignRequest) (commitments []ThresholdCommitment, partials []ThresholdPartialSignature, err error) {
+	for _, signer := range signers {
+		var c ThresholdCommitment
+		if c, err = signer.Commit(); err != nil {
+			return
+		}
+		commitments = append(commitments, c)
+	}
+
+	request.Commitments = commitments
+	for _, signer := range signers {
+		var partial ThresholdPartialSignature
+		if partial, err = signer.Sign(request); err != nil {
+			return
+		}
+		partials = append(partials, partial)
+	}
+
+	return
+}
+
+func TestThresholdSignature(t *testing.T) {
+	kp, _ := keypair.Random()
+	signers := makeThresholdSigners(t, kp, 2, 3)
+
+	// every group of the threshold signers makes the valid signature
+	for _, group := range [][]*ThresholdSigner{
+		{signers[0], signers[1]},
+		{signers[1], signers[2]},
+		{signers[2], signers[0]},
+		signers,
+	} {
+		ballot, request := makeThresholdBallot(kp, VotingYES)
+		commitments, partials, err := thresholdSign(group, request)
+		if err != nil {
+			t.Errorf("failed to sign: %v", err)
+			return
+		}
+
+		message := append(append([]byte{}, networkID...), []byte(ballot.GetHash())...)
+		signature, err := AggregateThresholdSignature(kp.Address(), message, commitments, partials)
+		if err != nil {
+			t.Errorf("failed to aggregate: %v", err)
+			return
+		}
+		if err = kp.Verify(message, signature); err != nil {
+			t.Errorf("signature must be verified by the validator: %v", err)
+			return
+		}
+	}
+
+	// one signer can not sign alone
+	_, request := makeThresholdBallot(kp, VotingYES)
+	if _, _, err := thresholdSign(signers[:1], request); err != sebakerror.ErrorThresholdNotEnoughSigners {
+		t.Errorf("one signer must not sign: %v", err)
+		return
+	}
+
+	// the wrong partial signature is found
+	ballot, request := makeThresholdBallot(kp, VotingYES)
+	commitments, partials, _ := thresholdSign(signers[:2], request)
+	partials[1].Signature = partials[0].Signature
+	message := append(append([]byte{}, networkID...), []byte(ballot.GetHash())...)
+	if _, err := AggregateThresholdSignature(kp.Address(), message, commitments, partials); err != sebakerror.ErrorThresholdInvalidSignature {
+		t.Errorf("wrong partial signature must be found: %v", err)
+		return
+	}
+}
+
+func TestThresholdSignerRefuses(t *testing.T) {
+	kp, _ := keypair.Random()
+	signers := makeThresholdSigners(t, kp, 2, 2)
+
+	ballot, request := makeThresholdBallot(kp, VotingYES)
+	if _, _, err := thresholdSign(signers, request); err != nil {
+		t.Error(err)
+		return
+	}
+
+	// same vote can be signed again
+	if _, _, err := thresholdSign(signers, request); err != nil {
+		t.Errorf("same vote must be signed: %v", err)
+		return
+	}
+
+	// the other vote of same message and state is the equivocation
+	ballot.Vote(VotingNO)
+	ballot.UpdateHash()
+	request.Data, _ = json.Marshal(ballot)
+	if _, _, err := thresholdSign(signers, request); err != sebakerror.ErrorThresholdEquivocation {
+		t.Errorf("conflicting ballot must be refused: %v", err)
+		return
+	}
+
+	// the nonces are used only once
+	_, request = makeThresholdBallot(kp, VotingYES)
+	commitments, _, _ := thresholdSign(signers, request)
+	request.Commitments = commitments
+	if _, err := signers[0].Sign(request); err != sebakerror.ErrorThresholdInvalidCommitment {
+		t.Errorf("used nonce must be refused: %v", err)
+		return
+	}
+
+	// the ballot of the other validator and the wrong hash are refused
+	other, _ := keypair.Random()
+	_, request = makeThresholdBallot(other, VotingYES)
+	if _, _, err := thresholdSign(signers, request); err != sebakerror.ErrorBadPublicAddress {
+		t.Errorf("ballot of other validator must be refused: %v", err)
+		return
+	}
+
+	ballot, request = makeThresholdBallot(kp, VotingYES)
+	ballot.H.Hash = "wrong"
+	request.Data, _ = json.Marshal(ballot)
+	if _, _, err := thresholdSign(signers, request); err != sebakerror.ErrorHashDoesNotMatch {
+		t.Errorf("wrong hash must be refused: %v", err)
+		return
+	}
+
+	// the view change for the height of announced block and the other block
+	// of the announced height are refused
+	ba := NewBlockAnnouncement(kp.Address(), Block{Hash: "block-10", Height: 10})
+	ba.H.Hash = ba.B.MakeHashString()
+	request = ThresholdSignRequest{Type: ba.T}
+	request.Data, _ = json.Marshal(ba)
+	if _, _, err := thresholdSign(signers, request); err != nil {
+		t.Errorf("block announcement must be signed: %v", err)
+		return
+	}
+
+	ba.B.Block.Hash = "findme"
+	ba.H.Hash = ba.B.MakeHashString()
+	request.Data, _ = json.Marshal(ba)
+	if _, _, err := thresholdSign(signers, request); err != sebakerror.ErrorThresholdEquivocation {
+		t.Errorf("other block of announced height must be refused: %v", err)
+		return
+	}
+
+	for height, expected := range map[uint64]error{10: sebakerror.ErrorThresholdEquivocation, 11: nil} {
+		vc := NewViewChange(kp.Address(), height, 1)
+		vc.H.Hash = vc.B.MakeHashString()
+		request = ThresholdSignRequest{Type: vc.T}
+		request.Data, _ = json.Marshal(vc)
+		if _, _, err := thresholdSign(signers, request); err != expected {
+			t.Errorf("view change of height %d: expected %v, got %v", height, expected, err)
+			return
+		}
+	}
+
+	if _, err := SplitKeypair(kp, 1, 3); err == nil {
+		t.Error("threshold must be at least 2")
+		return
+	}
+}
+
+func TestThresholdNodeSigner(t *testing.T) {
+	kp, _ := keypair.Random()
+	signers := makeThresholdSigners(t, kp, 2, 3)
+
+	var clients []ThresholdSignerClient
+	for i, signer := range signers {
+		server := httptest.NewTLSServer(NewThresholdSignerHandler(signer, "token"))
+		defer server.Close()
+		roots := x509.NewCertPool()
+		roots.AddCert(server.Certificate())
+		if i == 0 { // the first signer is down
+			server.Close()
+		}
+
+		client, err := NewHTTPThresholdSignerClient(server.URL, "token", time.Second, roots)
+		if err != nil {
+			t.Fatal(err)
+		}
+		clients = append(clients, client)
+	}
+
+	nodeSigner, err := NewThresholdNodeSigner(kp.Address(), networkID, 2, clients)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	ballot, _ := makeThresholdBallot(kp, VotingYES)
+	if err = nodeSigner.SignBallot(&ballot); err != nil {
+		t.Errorf("failed to sign ballot: %v", err)
+		return
+	}
+	if err = ballot.VerifySignature(networkID); err != nil {
+		t.Errorf("ballot must be signed by the validator: %v", err)
+		return
+	}
+
+	vc := NewViewChange(kp.Address(), 1, 1)
+	if err = nodeSigner.SignViewChange(&vc); err != nil {
+		t.Error(err)
+		return
+	}
+	if err = vc.IsWellFormed(networkID); err != nil {
+		t.Errorf("view change must be signed by the validator: %v", err)
+		return
+	}
+
+	// without the valid token, the signers refuse
+	server := httptest.NewTLSServer(NewThresholdSignerHandler(signers[1], "token"))
+	defer server.Close()
+	roots := x509.NewCertPool()
+	roots.AddCert(server.Certificate())
+	client, _ := NewHTTPThresholdSignerClient(server.URL, "wrong", time.Second, roots)
+	if _, err = client.Commit(); err == nil {
+		t.Error("request with wrong token must be refused")
+		return
+	}
+
+	// the certificate of signer must be verified by the pinned CA
+	if _, err = NewHTTPThresholdSignerClient(server.URL, "token", time.Second, nil); err != sebakerror.ErrorThresholdSignerInsecure {
+		t.Errorf("client without CA must be refused: %v", err)
+		return
+	}
+	if _, err = NewHTTPThresholdSignerClient("http://127.0.0.1:12345", "token", time.Second, roots); err != sebakerror.ErrorThresholdSignerInsecure {
+		t.Errorf("plain http endpoint must be refused: %v", err)
+		return
+	}
+	other := httptest.NewTLSServer(NewThresholdSignerHandler(signers[1], "token"))
+	defer other.Close()
+	client, _ = NewHTTPThresholdSignerClient(other.URL, "token", time.Second, roots)
+	if _, err = client.Commit(); err == nil {
+		t.Error("certificate, which is not signed by the CA, must be refused")
+		return
+	}
+
+	// the refusal of signer is returned
+	ballot.Vote(VotingNO)
+	if err = nodeSigner.SignBallot(&ballot); err == nil || err.Error() != sebakerror.ErrorThresholdEquivocation.Error() {
+		t.Errorf("conflicting ballot must be refused: %v", err)
+		return
+	}
+}
@@ -3,8 +3,11 @@ package sebak
 import (
 	"encoding/json"
 	"fmt"
+	"io"
+	"time"
 
 	"github.com/btcsuite/btcutil/base58"
+	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/stellar/go/keypair"
 
 	"boscoin.io/sebak/lib/common"
@@ -14,6 +17,11 @@ import (
 
 // TODO versioning
 
+// MaxTransactionPayments is the maximum number of payments in one
+// transaction; the payments to the different targets are applied at once or
+// not at all.
+const MaxTransactionPayments int = 100
+
 type Transaction struct {
 	T string
 	H TransactionHeader
@@ -30,6 +38,8 @@ type TransactionBodyFromJSON struct {
 	Source     string              `json:"source"`
 	Fee        Amount              `json:"fee"`
 	Checkpoint string              `json:"checkpoint"`
+	ValidAfter string              `json:"valid_after,omitempty"`
+	ValidUntil string              `json:"valid_until,omitempty"`
 	Operations []OperationFromJSON `json:"operations"`
 }
 
@@ -54,6 +64,8 @@ func NewTransactionFromJSON(b []byte) (tx Transaction, err error) {
 		Source:     txt.B.Source,
 		Fee:        txt.B.Fee,
 		Checkpoint: txt.B.Checkpoint,
+		ValidAfter: txt.B.ValidAfter,
+		ValidUntil: txt.B.ValidUntil,
 		Operations: operations,
 	}
 
@@ -76,8 +88,9 @@ func NewTransaction(source, checkpoint string, ops ...Operation) (tx Transaction
 	tx = Transaction{
 		T: "transaction",
 		H: TransactionHeader{
+			Version: sebakcommon.EncodingVersionCanonical,
 			Created: sebakcommon.NowISO8601(),
-			Hash:    txBody.MakeHashString(),
+			Hash:    txBody.MakeHashStringByVersion(sebakcommon.EncodingVersionCanonical),
 		},
 		B: txBody,
 	}
@@ -86,10 +99,13 @@ func NewTransaction(source, checkpoint string, ops ...Operation) (tx Transaction
 }
 
 var TransactionWellFormedCheckerFuncs = []sebakcommon.CheckerFunc{
+	CheckTransactionEncodingVersion,
 	CheckTransactionSource,
 	CheckTransactionBaseFee,
+	CheckTransactionTimeBounds,
 	CheckTransactionOperation,
 	CheckTransactionVerifySignature,
+	CheckTransactionVerifyCoSignatures,
 	CheckTransactionHashMatch,
 }
 
@@ -117,6 +133,67 @@ func (o Transaction) Validate(st *sebakstorage.LevelDBBackend) (err error) {
 	return
 }
 
+// ValidateTransactionState checks the transaction against the state of
+// source account; the source account must exist, the checkpoint must be the
+// latest checkpoint of account or the next checkpoint of the transaction of
+// same source in `tp`, and the balance except the frozen balances must cover
+// the amount, fee and freezes with the transactions in `tp`.
+func ValidateTransactionState(st *sebakstorage.LevelDBBackend, tp *TransactionPool, tx Transaction) (err error) {
+	var exists bool
+	if exists, err = ExistBlockAccount(st, tx.B.Source); err != nil {
+		return
+	} else if !exists {
+		err = sebakerror.ErrorBlockAccountDoesNotExists
+		return
+	}
+
+	var ba *BlockAccount
+	if ba, err = GetBlockAccount(st, tx.B.Source); err != nil {
+		return
+	}
+
+	pending := tp.BySource(tx.B.Source)
+
+	found := tx.B.Checkpoint == ba.Checkpoint
+	balance := ba.GetBalance()
+	for _, p := range pending {
+		found = found || tx.B.Checkpoint == p.NextCheckpoint()
+		if balance, err = balance.Sub(p.TotalAmount(true)); err != nil {
+			return
+		}
+		// the freezes of the pending transactions are not spendable
+		if balance, err = balance.Sub(p.FreezingAmount()); err != nil {
+			return
+		}
+	}
+	if !found {
+		err = sebakerror.ErrorTransactionInvalidCheckpoint
+		return
+	}
+
+	if _, err = balance.Sub(tx.TotalAmount(true)); err != nil {
+		return
+	}
+	if err = CheckFrozenBalance(st, tx, balance); err != nil {
+		return
+	}
+
+	return
+}
+
+// ValidateTransactionOperations checks the state of every operation, like
+// the target of payment exists, so the batch of payments is refused with the
+// results of the failed operations by `sebakerror.OperationsError` before it
+// fails as a whole when the block is applied.
+func ValidateTransactionOperations(st *sebakstorage.LevelDBBackend, tx Transaction) (err error) {
+	errs := make([]error, len(tx.B.Operations))
+	for i, op := range tx.B.Operations {
+		errs[i] = ValidateOperation(st, tx, op)
+	}
+
+	return sebakerror.NewOperationsError(errs)
+}
+
 func (o Transaction) GetType() string {
 	return o.T
 }
@@ -142,6 +219,43 @@ func (o Transaction) TotalAmount(withFee bool) Amount {
 	return Amount(amount)
 }
 
+// TotalFee returns the fee of transaction, which is charged for each
+// operation.
+func (o Transaction) TotalFee() Amount {
+	return o.B.Fee * Amount(len(o.B.Operations))
+}
+
+// FreezingAmount returns the sum of the amounts, which are frozen by the
+// freeze operations of transaction.
+func (o Transaction) FreezingAmount() (amount Amount) {
+	for _, op := range o.B.Operations {
+		if body, ok := op.B.(OperationBodyFreeze); ok {
+			amount += body.Amount
+		}
+	}
+
+	return
+}
+
+// CheckTimeBounds checks `t` is in the time bounds of transaction.
+func (o Transaction) CheckTimeBounds(t time.Time) (err error) {
+	var after, until time.Time
+	if after, until, err = o.B.TimeBounds(); err != nil {
+		return
+	}
+
+	if !after.IsZero() && t.Before(after) {
+		err = sebakerror.ErrorTransactionNotValidYet
+		return
+	}
+	if !until.IsZero() && t.After(until) {
+		err = sebakerror.ErrorTransactionExpired
+		return
+	}
+
+	return
+}
+
 func (o Transaction) Serialize() (encoded []byte, err error) {
 	encoded, err = json.Marshal(o)
 	return
@@ -152,8 +266,13 @@ func (o Transaction) String() string {
 	return string(encoded)
 }
 
+// MakeHashString returns the hash of body by the encoding of `H.Version`.
+func (o Transaction) MakeHashString() string {
+	return o.B.MakeHashStringByVersion(o.H.Version)
+}
+
 func (o *Transaction) Sign(kp keypair.KP, networkID []byte) {
-	o.H.Hash = o.B.MakeHashString()
+	o.H.Hash = o.MakeHashString()
 	signature, _ := kp.Sign(append(networkID, []byte(o.H.Hash)...))
 
 	o.H.Signature = base58.Encode(signature)
@@ -161,6 +280,23 @@ func (o *Transaction) Sign(kp keypair.KP, networkID []byte) {
 	return
 }
 
+// CoSign adds the co-signature of `kp`; the previous co-signature of the same
+// signer is replaced. It must be called after `Sign()`.
+func (o *Transaction) CoSign(kp keypair.KP, networkID []byte) {
+	signature, _ := kp.Sign(append(networkID, []byte(o.H.Hash)...))
+	s := TransactionEnvelopeSignature{Signer: kp.Address(), Signature: base58.Encode(signature)}
+
+	for i, c := range o.H.Signatures {
+		if c.Signer == s.Signer {
+			o.H.Signatures[i] = s
+			return
+		}
+	}
+	o.H.Signatures = append(o.H.Signatures, s)
+
+	return
+}
+
 func (o Transaction) NextCheckpoint() string {
 	return string(
 		base58.Encode(
@@ -171,20 +307,78 @@ func (o Transaction) NextCheckpoint() string {
 	)
 }
 
+// TransactionHeader has the signature of source account and the
+// co-signatures, `Signatures`, which are required by the `SpendingLimit` or
+// the `AccountSigners` of source account. `Version` is the encoding of body
+// for the hash; the empty one is the RLP of the transactions before the
+// versioning and `NewTransaction()` makes the canonical one, see
+// `sebakcommon.CanonicalEncoder`.
 type TransactionHeader struct {
-	Version   string `json:"version"`
-	Created   string `json:"created"`
-	Hash      string `json:"hash"`
-	Signature string `json:"signature"`
+	Version    string                         `json:"version"`
+	Created    string                         `json:"created"`
+	Hash       string                         `json:"hash"`
+	Signature  string                         `json:"signature"`
+	Signatures []TransactionEnvelopeSignature `json:"signatures,omitempty"`
 }
 
+// TransactionBody can have the time bounds, `ValidAfter` and `ValidUntil` in
+// RFC3339; the transaction is refused before `ValidAfter` and after
+// `ValidUntil`, so the client can sign the new transaction safely after the
+// stuck one expires. The empty bound is not checked.
 type TransactionBody struct {
 	Source     string      `json:"source"`
 	Fee        Amount      `json:"fee"`
 	Checkpoint string      `json:"checkpoint"`
+	ValidAfter string      `json:"valid_after,omitempty"`
+	ValidUntil string      `json:"valid_until,omitempty"`
 	Operations []Operation `json:"operations"`
 }
 
+// EncodeRLP encodes the body without time bounds like before the time
+// bounds, so the hashes of the existing transactions are not changed.
+func (tb TransactionBody) EncodeRLP(w io.Writer) error {
+	if len(tb.ValidAfter) < 1 && len(tb.ValidUntil) < 1 {
+		return rlp.Encode(w, struct {
+			Source     string
+			Fee        Amount
+			Checkpoint string
+			Operations []Operation
+		}{tb.Source, tb.Fee, tb.Checkpoint, tb.Operations})
+	}
+
+	return rlp.Encode(w, struct {
+		Source     string
+		Fee        Amount
+		Checkpoint string
+		ValidAfter string
+		ValidUntil string
+		Operations []Operation
+	}{tb.Source, tb.Fee, tb.Checkpoint, tb.ValidAfter, tb.ValidUntil, tb.Operations})
+}
+
+// TimeBounds parses `ValidAfter` and `ValidUntil`; the empty bound is zero
+// time.
+func (tb TransactionBody) TimeBounds() (after, until time.Time, err error) {
+	if len(tb.ValidAfter) > 0 {
+		if after, err = time.Parse(time.RFC3339Nano, tb.ValidAfter); err != nil {
+			err = sebakerror.ErrorTransactionInvalidTimeBounds
+			return
+		}
+	}
+	if len(tb.ValidUntil) > 0 {
+		if until, err = time.Parse(time.RFC3339Nano, tb.ValidUntil); err != nil {
+			err = sebakerror.ErrorTransactionInvalidTimeBounds
+			return
+		}
+	}
+	if !after.IsZero() && !until.IsZero() && !after.Before(until) {
+		err = sebakerror.ErrorTransactionInvalidTimeBounds
+		return
+	}
+
+	return
+}
+
 func (tb TransactionBody) MakeHash() []byte {
 	return sebakcommon.MustMakeObjectHash(tb)
 }
@@ -193,23 +387,115 @@ func (tb TransactionBody) MakeHashString() string {
 	return base58.Encode(tb.MakeHash())
 }
 
-func FinishTransaction(st *sebakstorage.LevelDBBackend, ballot Ballot, tx Transaction) (err error) {
+// MakeHashStringByVersion returns the hash by the encoding `version`; the
+// unknown version is refused by `CheckTransactionEncodingVersion`.
+func (tb TransactionBody) MakeHashStringByVersion(version string) string {
+	if version == sebakcommon.EncodingVersionCanonical {
+		return base58.Encode(sebakcommon.MakeCanonicalHash("transaction", tb))
+	}
+
+	return tb.MakeHashString()
+}
+
+func (tb TransactionBody) EncodeCanonical(e *sebakcommon.CanonicalEncoder) {
+	e.String(tb.Source)
+	e.Uint64(uint64(tb.Fee))
+	e.String(tb.Checkpoint)
+	e.String(tb.ValidAfter)
+	e.String(tb.ValidUntil)
+	e.Len(len(tb.Operations))
+	for _, op := range tb.Operations {
+		op.EncodeCanonical(e)
+	}
+}
+
+// FinishTransaction saves the transaction and it's block; with `deferStats`,
+// the rollups of `ChainStats` are deferred and caught up by
+// `CatchUpChainStats()`. The transaction, which violates the `SpendingLimit`
+// of source account is refused. The block has the proposed time and the
+// signals of proposer of `ballot`, not the clock of node, so every node makes
+// the same block.
+func FinishTransaction(st *sebakstorage.LevelDBBackend, networkID []byte, ballot Ballot, tx Transaction, deferStats bool) (err error) {
+	if _, err = ballot.ProposedTime(); err != nil {
+		return
+	}
+
 	var raw []byte
 	raw, err = ballot.Data().Serialize()
 	if err != nil {
 		return
 	}
 
+	return finishTransaction(st, networkID, raw, tx, ballot.B.Proposed, ballot.B.ProposerSignals, Block{}, deferStats)
+}
+
+// ApplySyncedBlock saves the block, which is fetched by the block sync, with
+// it's transaction like `FinishTransaction`; `raw` is the ballot data of the
+// transaction. The block must be the next of the latest block and must have
+// the same state hash after the transaction is applied.
+func ApplySyncedBlock(st *sebakstorage.LevelDBBackend, networkID []byte, block Block, raw []byte, tx Transaction, deferStats bool) (err error) {
+	return finishTransaction(st, networkID, raw, tx, block.Confirmed, block.Signals, block, deferStats)
+}
+
+// finishTransaction makes the new block of `tx` at `confirmed`, the time
+// agreed by the validators, with the upgrade signals of proposer; if `synced`
+// is not empty, it is saved instead.
+func finishTransaction(st *sebakstorage.LevelDBBackend, networkID []byte, raw []byte, tx Transaction, confirmed string, signals []string, synced Block, deferStats bool) (err error) {
 	var ts *sebakstorage.LevelDBBackend
 	if ts, err = st.OpenTransaction(); err != nil {
 		return
 	}
 
 	bt := NewBlockTransactionFromTransaction(tx, raw)
+	bt.Confirmed = confirmed
 	if err = bt.Save(ts); err != nil {
 		ts.Discard()
 		return
 	}
+
+	var confirmedTime time.Time
+	if confirmedTime, err = time.Parse(time.RFC3339Nano, confirmed); err != nil {
+		ts.Discard()
+		return
+	}
+	if err = tx.CheckTimeBounds(confirmedTime); err != nil {
+		ts.Discard()
+		return
+	}
+	var parameters NetworkParameters
+	if parameters, err = GetNetworkParameters(ts); err != nil {
+		ts.Discard()
+		return
+	}
+	if err = CheckTransactionOperationsLimit(parameters, tx); err != nil {
+		ts.Discard()
+		return
+	}
+	if err = CheckTransactionFee(parameters, tx); err != nil {
+		ts.Discard()
+		return
+	}
+	day := GetSpendingLimitDay(confirmedTime)
+	if err = CheckSpendingLimit(ts, networkID, tx, day); err != nil {
+		ts.Discard()
+		return
+	}
+	if err = CheckAccountSigners(ts, networkID, tx); err != nil {
+		ts.Discard()
+		return
+	}
+
+	var baSource *BlockAccount
+	if baSource, err = GetBlockAccount(ts, tx.B.Source); err != nil {
+		err = sebakerror.ErrorBlockAccountDoesNotExists
+		ts.Discard()
+		return
+	}
+	if err = CheckFrozenBalance(ts, tx, baSource.GetBalance()); err != nil {
+		ts.Discard()
+		return
+	}
+
 	for _, op := range tx.B.Operations {
 		if err = FinishOperation(ts, tx, op); err != nil {
 			ts.Discard()
@@ -217,7 +503,6 @@ func FinishTransaction(st *sebakstorage.LevelDBBackend, ballot Ballot, tx Transa
 		}
 	}
 
-	var baSource *BlockAccount
 	if baSource, err = GetBlockAccount(ts, tx.B.Source); err != nil {
 		err = sebakerror.ErrorBlockAccountDoesNotExists
 		ts.Discard()
@@ -233,11 +518,80 @@ func FinishTransaction(st *sebakstorage.LevelDBBackend, ballot Ballot, tx Transa
 		ts.Discard()
 		return
 	}
+	if err = RecordSpendingLimitSpent(ts, tx, day); err != nil {
+		ts.Discard()
+		return
+	}
+	if err = SaveLedgerEntries(ts, NewLedgerEntriesFromTransaction(tx, bt.Confirmed)...); err != nil {
+		ts.Discard()
+		return
+	}
+
+	var latest Block
+	if latest, err = GetLatestBlock(ts); err != nil {
+		ts.Discard()
+		return
+	}
+	if _, err = DistributeReward(ts, parameters, latest.Height+1, tx.GetHash(), bt.Confirmed); err != nil {
+		ts.Discard()
+		return
+	}
+
+	var stateHash string
+	if stateHash, err = MakeStateHash(ts); err != nil {
+		ts.Discard()
+		return
+	}
+
+	block := NewBlockAt(latest, stateHash, confirmed, tx.GetHash()).WithSignals(signals)
+	if !synced.IsEmpty() {
+		if synced.Height != latest.Height+1 || synced.PrevBlockHash != latest.Hash {
+			err = sebakerror.ErrorBlockNotNext
+			ts.Discard()
+			return
+		}
+		if synced.StateHash != stateHash {
+			err = sebakerror.ErrorStateHashDoesNotMatch
+			ts.Discard()
+			return
+		}
+		block = synced
+	}
+	if err = block.Save(ts); err != nil {
+		ts.Discard()
+		return
+	}
+	if err = NewFinality(block).Save(ts); err != nil {
+		ts.Discard()
+		return
+	}
+	if err = NewIncludedTransactionResult(tx, block).Save(ts); err != nil {
+		ts.Discard()
+		return
+	}
+
+	var pendingStats bool
+	if pendingStats, err = HasPendingChainStats(ts); err != nil {
+		ts.Discard()
+		return
+	}
+	if deferStats || pendingStats {
+		err = deferChainStats(ts, block)
+	} else {
+		err = UpdateChainStats(ts, block, tx)
+	}
+	if err != nil {
+		ts.Discard()
+		return
+	}
 
 	if err = ts.Commit(); err != nil {
 		ts.Discard()
 		return
 	}
 
+	// the committed block is durable after the barrier in `deferred` sync mode
+	err = st.SyncBarrier()
+
 	return
 }
@@ -37,11 +37,27 @@ func CheckTransactionBaseFee(c sebakcommon.Checker, args ...interface{}) (err er
 	return
 }
 
+func CheckTransactionTimeBounds(c sebakcommon.Checker, args ...interface{}) (err error) {
+	checker := c.(*TransactionChecker)
+	if _, _, err = checker.Transaction.B.TimeBounds(); err != nil {
+		return
+	}
+
+	return
+}
+
 func CheckTransactionOperation(c sebakcommon.Checker, args ...interface{}) (err error) {
 	checker := c.(*TransactionChecker)
 
 	var hashes []string
+	var payments int
 	for _, op := range checker.Transaction.B.Operations {
+		if op.H.Type == OperationPayment {
+			if payments++; payments > MaxTransactionPayments {
+				err = sebakerror.ErrorTransactionTooManyPayments
+				return
+			}
+		}
 		if checker.Transaction.B.Source == op.B.TargetAddress() {
 			err = sebakerror.ErrorInvalidOperation
 			return
@@ -50,8 +66,20 @@ func CheckTransactionOperation(c sebakcommon.Checker, args ...interface{}) (err
 			return
 		}
 		// if there are multiple operations which has same 'Type' and same
-		// 'TargetAddress()', this transaction will be invalid.
+		// 'TargetAddress()', this transaction will be invalid. For
+		// `OperationManageData`, the name of data entry is used instead.
 		u := fmt.Sprintf("%s-%s", op.H.Type, op.B.TargetAddress())
+		if body, ok := op.B.(OperationBodyManageData); ok {
+			u = fmt.Sprintf("%s-%s", op.H.Type, body.Name)
+		}
+		if body, ok := op.B.(OperationBodySetSigners); ok {
+			for _, signer := range body.Signers {
+				if signer.Address == checker.Transaction.B.Source {
+					err = sebakerror.ErrorInvalidOperation
+					return
+				}
+			}
+		}
 		if _, found := sebakcommon.InStringArray(hashes, u); found {
 			err = sebakerror.ErrorDuplicatedOperation
 			return
@@ -80,9 +108,51 @@ func CheckTransactionVerifySignature(c sebakcommon.Checker, args ...interface{})
 	return
 }
 
+// CheckTransactionVerifyCoSignatures checks the co-signatures; every
+// co-signer must be the other account than source and sign only once.
+func CheckTransactionVerifyCoSignatures(c sebakcommon.Checker, args ...interface{}) (err error) {
+	checker := c.(*TransactionChecker)
+
+	signatures := checker.Transaction.H.Signatures
+	if len(signatures) > MaxTransactionCoSignatures {
+		err = sebakerror.ErrorTransactionInvalidCoSignatures
+		return
+	}
+
+	var signers []string
+	for _, s := range signatures {
+		if s.Signer == checker.Transaction.B.Source {
+			err = sebakerror.ErrorTransactionInvalidCoSignatures
+			return
+		}
+		if _, found := sebakcommon.InStringArray(signers, s.Signer); found {
+			err = sebakerror.ErrorTransactionInvalidCoSignatures
+			return
+		}
+		if verifyTransactionSignature(checker.Transaction, checker.NetworkID, s) != nil {
+			err = sebakerror.ErrorTransactionInvalidCoSignatures
+			return
+		}
+		signers = append(signers, s.Signer)
+	}
+
+	return
+}
+
+// CheckTransactionEncodingVersion checks the encoding of the hash is known.
+func CheckTransactionEncodingVersion(c sebakcommon.Checker, args ...interface{}) (err error) {
+	checker := c.(*TransactionChecker)
+	if !sebakcommon.IsKnownEncodingVersion(checker.Transaction.H.Version) {
+		err = sebakerror.ErrorUnknownEncodingVersion
+		return
+	}
+
+	return
+}
+
 func CheckTransactionHashMatch(c sebakcommon.Checker, args ...interface{}) (err error) {
 	checker := c.(*TransactionChecker)
-	if checker.Transaction.H.Hash != checker.Transaction.B.MakeHashString() {
+	if checker.Transaction.H.Hash != checker.Transaction.MakeHashString() {
 		err = sebakerror.ErrorHashDoesNotMatch
 		return
 	}
@@ -0,0 +1,240 @@
+package sebak
+
+import (
+	"encoding/json"
+	"sort"
+
+	"github.com/btcsuite/btcutil/base58"
+	"github.com/stellar/go/keypair"
+
+	"boscoin.io/sebak/lib/common"
+	"boscoin.io/sebak/lib/error"
+)
+
+// TransactionEnvelope collects the signatures of multiple parties for one
+// transaction. The transaction can be submitted only when the valid
+// signatures from `Signers` reach `Threshold`; the source account must be one
+// of the signers, because the network always verifies the signature of
+// source. The signatures of the other signers are submitted as the
+// co-signatures, which the `SpendingLimit` of source account requires.
+type TransactionEnvelope struct {
+	Transaction Transaction                    `json:"transaction"`
+	Threshold   int                            `json:"threshold"`
+	Signers     []string                       `json:"signers"`
+	Signatures  []TransactionEnvelopeSignature `json:"signatures"`
+}
+
+type TransactionEnvelopeSignature struct {
+	Signer    string `json:"signer"`
+	Signature string `json:"signature"`
+}
+
+func NewTransactionEnvelope(tx Transaction, threshold int, signers ...string) (envelope TransactionEnvelope, err error) {
+	var sorted []string
+	var hasSource bool
+	for _, signer := range signers {
+		if _, err = keypair.Parse(signer); err != nil {
+			err = sebakerror.ErrorBadPublicAddress
+			return
+		}
+		if signer == tx.B.Source {
+			hasSource = true
+		}
+		if _, found := sebakcommon.InStringArray(sorted, signer); found {
+			continue
+		}
+		sorted = append(sorted, signer)
+	}
+	if !hasSource {
+		sorted = append(sorted, tx.B.Source)
+	}
+	sort.Strings(sorted)
+
+	if threshold < 1 || threshold > len(sorted) {
+		err = sebakerror.ErrorEnvelopeInvalidThreshold
+		return
+	}
+
+	tx.H.Hash = tx.MakeHashString()
+	tx.H.Signature = ""
+
+	envelope = TransactionEnvelope{
+		Transaction: tx,
+		Threshold:   threshold,
+		Signers:     sorted,
+		Signatures:  []TransactionEnvelopeSignature{},
+	}
+
+	return
+}
+
+func NewTransactionEnvelopeFromJSON(b []byte) (envelope TransactionEnvelope, err error) {
+	var raw struct {
+		Transaction json.RawMessage                `json:"transaction"`
+		Threshold   int                            `json:"threshold"`
+		Signers     []string                       `json:"signers"`
+		Signatures  []TransactionEnvelopeSignature `json:"signatures"`
+	}
+	if err = json.Unmarshal(b, &raw); err != nil {
+		return
+	}
+
+	if envelope.Transaction, err = NewTransactionFromJSON(raw.Transaction); err != nil {
+		return
+	}
+	envelope.Threshold = raw.Threshold
+	envelope.Signers = raw.Signers
+	envelope.Signatures = raw.Signatures
+
+	return
+}
+
+func (e TransactionEnvelope) Serialize() (encoded []byte, err error) {
+	encoded, err = json.MarshalIndent(e, "", "  ")
+	return
+}
+
+func (e TransactionEnvelope) GetHash() string {
+	return e.Transaction.GetHash()
+}
+
+// Sign appends the signature of `kp`; the previous signature of the same
+// signer is replaced.
+func (e *TransactionEnvelope) Sign(kp keypair.KP, networkID []byte) (err error) {
+	if !e.isSigner(kp.Address()) {
+		err = sebakerror.ErrorEnvelopeUnknownSigner
+		return
+	}
+
+	var signature []byte
+	if signature, err = kp.Sign(append(networkID, []byte(e.GetHash())...)); err != nil {
+		return
+	}
+
+	e.addSignature(TransactionEnvelopeSignature{
+		Signer:    kp.Address(),
+		Signature: base58.Encode(signature),
+	})
+
+	return
+}
+
+// Merge collects the signatures of the other envelope of the same transaction
+// and the same signers.
+func (e *TransactionEnvelope) Merge(other TransactionEnvelope) (err error) {
+	if e.GetHash() != other.GetHash() || e.Threshold != other.Threshold || len(e.Signers) != len(other.Signers) {
+		err = sebakerror.ErrorEnvelopeNotMatched
+		return
+	}
+	for i, signer := range e.Signers {
+		if other.Signers[i] != signer {
+			err = sebakerror.ErrorEnvelopeNotMatched
+			return
+		}
+	}
+
+	for _, s := range other.Signatures {
+		if !e.isSigner(s.Signer) {
+			err = sebakerror.ErrorEnvelopeUnknownSigner
+			return
+		}
+		if _, found := e.signatureOf(s.Signer); found {
+			continue
+		}
+		e.addSignature(s)
+	}
+
+	return
+}
+
+func (e TransactionEnvelope) isSigner(address string) bool {
+	_, found := sebakcommon.InStringArray(e.Signers, address)
+	return found
+}
+
+func (e *TransactionEnvelope) addSignature(signature TransactionEnvelopeSignature) {
+	for i, s := range e.Signatures {
+		if s.Signer == signature.Signer {
+			e.Signatures[i] = signature
+			return
+		}
+	}
+
+	e.Signatures = append(e.Signatures, signature)
+	sort.Slice(e.Signatures, func(i, j int) bool {
+		return e.Signatures[i].Signer < e.Signatures[j].Signer
+	})
+}
+
+func (e TransactionEnvelope) signatureOf(signer string) (signature string, found bool) {
+	for _, s := range e.Signatures {
+		if s.Signer == signer {
+			return s.Signature, true
+		}
+	}
+
+	return
+}
+
+// ValidSigners returns the signers, whose signature is verified.
+func (e TransactionEnvelope) ValidSigners(networkID []byte) (signers []string) {
+	if e.GetHash() != e.Transaction.MakeHashString() {
+		return
+	}
+
+	for _, s := range e.Signatures {
+		if !e.isSigner(s.Signer) {
+			continue
+		}
+		if _, found := sebakcommon.InStringArray(signers, s.Signer); found {
+			continue
+		}
+
+		kp, err := keypair.Parse(s.Signer)
+		if err != nil {
+			continue
+		}
+		if err = kp.Verify(append(networkID, []byte(e.GetHash())...), base58.Decode(s.Signature)); err != nil {
+			continue
+		}
+		signers = append(signers, s.Signer)
+	}
+
+	return
+}
+
+// IsSatisfied checks the valid signatures reach the threshold and the source
+// account signed.
+func (e TransactionEnvelope) IsSatisfied(networkID []byte) (err error) {
+	signers := e.ValidSigners(networkID)
+	_, sourceSigned := sebakcommon.InStringArray(signers, e.Transaction.B.Source)
+	if len(signers) < e.Threshold || !sourceSigned {
+		err = sebakerror.ErrorEnvelopeThresholdNotSatisfied
+		return
+	}
+
+	return
+}
+
+// SignedTransaction returns the transaction signed by the source account and
+// co-signed by the other valid signers, which can be submitted to the network,
+// only if the envelope is satisfied.
+func (e TransactionEnvelope) SignedTransaction(networkID []byte) (tx Transaction, err error) {
+	if err = e.IsSatisfied(networkID); err != nil {
+		return
+	}
+
+	tx = e.Transaction
+	tx.H.Signature, _ = e.signatureOf(tx.B.Source)
+	tx.H.Signatures = nil
+	for _, signer := range e.ValidSigners(networkID) {
+		if signer == tx.B.Source {
+			continue
+		}
+		signature, _ := e.signatureOf(signer)
+		tx.H.Signatures = append(tx.H.Signatures, TransactionEnvelopeSignature{Signer: signer, Signature: signature})
+	}
+	err = tx.IsWellFormed(networkID)
+
+	return
+}
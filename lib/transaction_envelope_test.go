@@ -0,0 +1,116 @@
+package sebak
+
+import (
+	"testing"
+
+	"github.com/stellar/go/keypair"
+
+	"boscoin.io/sebak/lib/error"
+)
+
+func TestTransactionEnvelopeThreshold(t *testing.T) {
+	kpSource, tx := TestMakeTransaction(networkID, 1)
+	kpA, _ := keypair.Random()
+	kpB, _ := keypair.Random()
+	kpOther, _ := keypair.Random()
+
+	if _, err := NewTransactionEnvelope(tx, 4, kpA.Address(), kpB.Address()); err != sebakerror.ErrorEnvelopeInvalidThreshold {
+		t.Error("threshold greater than the number of signers must be refused")
+		return
+	}
+
+	envelope, err := NewTransactionEnvelope(tx, 2, kpA.Address(), kpB.Address(), kpA.Address())
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if len(envelope.Signers) != 3 {
+		t.Errorf("source must be included and duplicated signer removed; %v", envelope.Signers)
+		return
+	}
+
+	if err = envelope.Sign(kpOther, networkID); err != sebakerror.ErrorEnvelopeUnknownSigner {
+		t.Error("unknown signer must be refused")
+		return
+	}
+
+	// 2 of 3 signed, but without source
+	envelope.Sign(kpA, networkID)
+	envelope.Sign(kpB, networkID)
+	if err = envelope.IsSatisfied(networkID); err != sebakerror.ErrorEnvelopeThresholdNotSatisfied {
+		t.Error("envelope must not be satisfied without source signature")
+		return
+	}
+	if _, err = envelope.SignedTransaction(networkID); err == nil {
+		t.Error("unsatisfied envelope must not produce signed transaction")
+		return
+	}
+
+	envelope.Sign(kpSource, networkID)
+	if err = envelope.IsSatisfied(networkID); err != nil {
+		t.Error(err)
+		return
+	}
+
+	var signed Transaction
+	if signed, err = envelope.SignedTransaction(networkID); err != nil {
+		t.Error(err)
+		return
+	}
+	if err = signed.IsWellFormed(networkID); err != nil {
+		t.Error(err)
+		return
+	}
+
+	// signatures from the other network are not valid
+	if len(envelope.ValidSigners([]byte("other-network"))) != 0 {
+		t.Error("signatures must be verified with network id")
+		return
+	}
+}
+
+func TestTransactionEnvelopeMerge(t *testing.T) {
+	kpSource, tx := TestMakeTransaction(networkID, 1)
+	kpA, _ := keypair.Random()
+
+	base, _ := NewTransactionEnvelope(tx, 2, kpA.Address())
+
+	var b []byte
+	var err error
+	if b, err = base.Serialize(); err != nil {
+		t.Error(err)
+		return
+	}
+
+	var envelopeSource, envelopeA TransactionEnvelope
+	if envelopeSource, err = NewTransactionEnvelopeFromJSON(b); err != nil {
+		t.Error(err)
+		return
+	}
+	envelopeA, _ = NewTransactionEnvelopeFromJSON(b)
+
+	envelopeSource.Sign(kpSource, networkID)
+	envelopeA.Sign(kpA, networkID)
+
+	if err = envelopeSource.IsSatisfied(networkID); err == nil {
+		t.Error("one signature must not satisfy the threshold, 2")
+		return
+	}
+
+	if err = envelopeSource.Merge(envelopeA); err != nil {
+		t.Error(err)
+		return
+	}
+	if err = envelopeSource.IsSatisfied(networkID); err != nil {
+		t.Error(err)
+		return
+	}
+
+	// envelope of different transaction
+	_, otherTx := TestMakeTransaction(networkID, 1)
+	other, _ := NewTransactionEnvelope(otherTx, 1)
+	if err = envelopeSource.Merge(other); err != sebakerror.ErrorEnvelopeNotMatched {
+		t.Error("envelope of different transaction must not be merged")
+		return
+	}
+}
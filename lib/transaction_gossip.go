@@ -0,0 +1,238 @@
+package sebak
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"boscoin.io/sebak/lib/error"
+	"boscoin.io/sebak/lib/network"
+)
+
+// The new transactions are propagated by the gossip. The node, which accepts
+// the transaction into `TransactionPool` announces it's hash by
+// `TransactionInventory` to `DefaultGossipFanout` validators at random; the
+// validators fetch only the transactions, which they do not have, from the
+// announcer, and announce them again. So the full transaction is sent about
+// once to each validator, instead of every validator sending it to all the
+// others.
+
+// DefaultGossipFanout is the number of validators, which the node announces
+// the new transactions to; 0 sends the full transactions to every validator.
+const DefaultGossipFanout int = 3
+
+// MaxTransactionInventory is the maximum number of hashes in one
+// `TransactionInventory` and in one fetch.
+const MaxTransactionInventory int = 100
+
+// GossipFetchTimeout is how long the announced transaction is not fetched
+// again from the other announcers, while it is being fetched.
+const GossipFetchTimeout time.Duration = 10 * time.Second
+
+type TransactionInventory struct {
+	Source string   `json:"source"` // the address of announcer
+	Hashes []string `json:"hashes"`
+}
+
+func NewTransactionInventoryFromJSON(b []byte) (inventory TransactionInventory, err error) {
+	if err = json.Unmarshal(b, &inventory); err != nil {
+		return
+	}
+	if len(inventory.Hashes) < 1 {
+		err = errors.New("empty transaction inventory")
+		return
+	}
+	if len(inventory.Hashes) > MaxTransactionInventory {
+		err = fmt.Errorf("too many hashes in transaction inventory; the maximum is %d", MaxTransactionInventory)
+		return
+	}
+
+	return
+}
+
+func (i TransactionInventory) Serialize() ([]byte, error) {
+	return json.Marshal(i)
+}
+
+// TransactionGossip tracks the announced transactions, which are being
+// fetched.
+type TransactionGossip struct {
+	sync.Mutex
+
+	fanout   int
+	fetching map[ /* Transaction.GetHash() */ string]time.Time
+}
+
+func NewTransactionGossip(fanout int) *TransactionGossip {
+	return &TransactionGossip{
+		fanout:   fanout,
+		fetching: map[string]time.Time{},
+	}
+}
+
+func (g *TransactionGossip) Fanout() int {
+	g.Lock()
+	defer g.Unlock()
+
+	return g.fanout
+}
+
+func (g *TransactionGossip) SetFanout(fanout int) {
+	g.Lock()
+	defer g.Unlock()
+
+	g.fanout = fanout
+}
+
+// startFetch returns the hashes, which are not being fetched, and marks them
+// as being fetched.
+func (g *TransactionGossip) startFetch(hashes []string) (started []string) {
+	g.Lock()
+	defer g.Unlock()
+
+	now := time.Now()
+	for hash, since := range g.fetching {
+		if now.Sub(since) > GossipFetchTimeout {
+			delete(g.fetching, hash)
+		}
+	}
+
+	for _, hash := range hashes {
+		if _, found := g.fetching[hash]; found {
+			continue
+		}
+		g.fetching[hash] = now
+		started = append(started, hash)
+	}
+
+	return
+}
+
+func (g *TransactionGossip) finishFetch(hashes []string) {
+	g.Lock()
+	defer g.Unlock()
+
+	for _, hash := range hashes {
+		delete(g.fetching, hash)
+	}
+}
+
+func (nr *NodeRunner) TransactionGossip() *TransactionGossip {
+	return nr.transactionGossip
+}
+
+// GossipTransaction propagates the transaction, which is accepted into
+// `TransactionPool`, to the other validators.
+func (nr *NodeRunner) GossipTransaction(tx Transaction) {
+	fanout := nr.transactionGossip.Fanout()
+	if fanout < 1 {
+		nr.connectionManager.BroadcastTransaction(tx)
+		return
+	}
+
+	inventory := TransactionInventory{Source: nr.currentNode.Address(), Hashes: []string{tx.GetHash()}}
+	nr.connectionManager.AnnounceTransactions(inventory, tx, fanout)
+}
+
+// hasTransaction checks the transaction is in `TransactionPool` or in block.
+func (nr *NodeRunner) hasTransaction(hash string) bool {
+	if nr.transactionPool.Has(hash) {
+		return true
+	}
+	exists, err := ExistBlockTransaction(nr.storage, hash)
+
+	return err == nil && exists
+}
+
+// handleTransactionInventory fetches the announced transactions, which the
+// node does not have, from the announcer; they are handled like the
+// transactions from client.
+func (nr *NodeRunner) handleTransactionInventory(inventory TransactionInventory) (err error) {
+	var unknown []string
+	for _, hash := range inventory.Hashes {
+		if !nr.hasTransaction(hash) {
+			unknown = append(unknown, hash)
+		}
+	}
+
+	hashes := nr.transactionGossip.startFetch(unknown)
+	if len(hashes) < 1 {
+		return
+	}
+	// the fetched transactions are not in pool until they are handled, so
+	// they are kept as being fetched until `GossipFetchTimeout`
+	defer func() {
+		if err != nil {
+			nr.transactionGossip.finishFetch(hashes)
+		}
+	}()
+
+	client := nr.connectionManager.GetConnection(inventory.Source)
+	if client == nil {
+		err = sebakerror.ErrorMessageNotFromValidator
+		return
+	}
+
+	var b []byte
+	if b, err = client.GetTransactions(hashes); err != nil {
+		return
+	}
+
+	var raws []json.RawMessage
+	if err = json.Unmarshal(b, &raws); err != nil {
+		return
+	}
+
+	requested := map[string]bool{}
+	for _, hash := range hashes {
+		requested[hash] = true
+	}
+	for _, raw := range raws {
+		var tx Transaction
+		if tx, err = NewTransactionFromJSON(raw); err != nil {
+			return
+		}
+		// the announcer can not send the other transactions
+		if !requested[tx.GetHash()] {
+			err = fmt.Errorf("transaction, '%s' is not requested", tx.GetHash())
+			return
+		}
+		delete(requested, tx.GetHash())
+
+		nr.network.ReceiveChannel() <- sebaknetwork.Message{Type: sebaknetwork.MessageFromClient, Data: raw}
+	}
+
+	return
+}
+
+// serveTransactions returns the transactions of the hashes in
+// `TransactionPool` for the fetch of the other validators; the hashes, which
+// are not in pool are skipped. It is refused while the node is busy by
+// `MessageQueue.AcquireSync()`.
+func (nr *NodeRunner) serveTransactions(hashes []string) ([]byte, error) {
+	if !nr.messageQueue.AcquireSync() {
+		return nil, sebakerror.ErrorMessageQueueFull
+	}
+	defer nr.messageQueue.ReleaseSync()
+
+	if len(hashes) > MaxTransactionInventory {
+		return nil, fmt.Errorf("too many hashes; the maximum is %d", MaxTransactionInventory)
+	}
+
+	raws := []json.RawMessage{}
+	for _, hash := range hashes {
+		tx, found := nr.transactionPool.Get(hash)
+		if !found {
+			continue
+		}
+		b, err := tx.Serialize()
+		if err != nil {
+			return nil, err
+		}
+		raws = append(raws, b)
+	}
+
+	return json.Marshal(raws)
+}
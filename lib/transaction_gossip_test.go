@@ -0,0 +1,92 @@
+package sebak
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stellar/go/keypair"
+
+	"boscoin.io/sebak/lib/network"
+)
+
+func TestNewTransactionInventoryFromJSON(t *testing.T) {
+	if _, err := NewTransactionInventoryFromJSON([]byte(`{"source":"node","hashes":[]}`)); err == nil {
+		t.Error("empty inventory must be refused")
+		return
+	}
+
+	hashes := make([]string, MaxTransactionInventory+1)
+	b, _ := TransactionInventory{Source: "node", Hashes: hashes}.Serialize()
+	if _, err := NewTransactionInventoryFromJSON(b); err == nil {
+		t.Error("too large inventory must be refused")
+		return
+	}
+
+	b, _ = TransactionInventory{Source: "node", Hashes: []string{"hash"}}.Serialize()
+	if inventory, err := NewTransactionInventoryFromJSON(b); err != nil || inventory.Hashes[0] != "hash" {
+		t.Errorf("inventory must be loaded: %v %v", inventory, err)
+		return
+	}
+}
+
+func TestNodeRunnerTransactionGossip(t *testing.T) {
+	defer sebaknetwork.CleanUpMemoryNetwork()
+
+	nodeRunners := createNodeRunners(2)
+	nr0, nr1 := nodeRunners[0], nodeRunners[1]
+	for _, nr := range nodeRunners {
+		nr.Network().SetContext(nr.ctx)
+	}
+
+	kp, _ := keypair.Random()
+	tx := makeTransaction(kp)
+	if err := nr0.TransactionPool().Add(tx); err != nil {
+		t.Error(err)
+		return
+	}
+
+	// the transactions, which are not in pool are skipped
+	b, err := nr0.serveTransactions([]string{"unknown", tx.GetHash()})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	var raws []json.RawMessage
+	if json.Unmarshal(b, &raws); len(raws) != 1 {
+		t.Errorf("only the transaction in pool must be served: %s", b)
+		return
+	}
+
+	inventory := TransactionInventory{Source: nr0.Node().Address(), Hashes: []string{tx.GetHash()}}
+	errs := make(chan error, 1)
+	go func() {
+		errs <- nr1.handleTransactionInventory(inventory)
+	}()
+
+	message := <-nr1.Network().ReceiveMessage()
+	if err = <-errs; err != nil {
+		t.Error(err)
+		return
+	}
+	if message.Type != sebaknetwork.MessageFromClient {
+		t.Errorf("fetched transaction must be handled like the message from client: %v", message)
+		return
+	}
+	if fetched, _ := NewTransactionFromJSON(message.Data); fetched.GetHash() != tx.GetHash() {
+		t.Errorf("wrong transaction is fetched: %s", message.Data)
+		return
+	}
+
+	// the transaction, which is being fetched is not fetched again
+	if err = nr1.handleTransactionInventory(inventory); err != nil {
+		t.Error(err)
+		return
+	}
+
+	// the node, which has the transaction does not fetch it
+	inventory.Source = nr1.Node().Address()
+	if err = nr0.handleTransactionInventory(inventory); err != nil {
+		t.Error(err)
+		return
+	}
+}
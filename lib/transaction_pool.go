@@ -0,0 +1,577 @@
+package sebak
+
+import (
+	"container/heap"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"boscoin.io/sebak/lib/error"
+	"boscoin.io/sebak/lib/storage"
+)
+
+// TransactionOrderingPolicy decides the order of transactions in
+// `TransactionPool` when the proposer starts new ballots.
+//  * `fee`: higher fee first, and then received time
+//  * `fifo`: received time
+//  * `nonce`: the transactions of same source account are ordered by it's
+//  checkpoint chain; accounts are ordered by received time of their first
+//  transaction
+// In every policy, the tie is broken by the transaction hash, so the same
+// transactions are always ordered in the same way.
+type TransactionOrderingPolicy string
+
+const (
+	TransactionOrderingFee   TransactionOrderingPolicy = "fee"
+	TransactionOrderingFIFO  TransactionOrderingPolicy = "fifo"
+	TransactionOrderingNonce TransactionOrderingPolicy = "nonce"
+)
+
+const DefaultTransactionOrderingPolicy = TransactionOrderingFIFO
+
+func NewTransactionOrderingPolicyFromString(s string) (policy TransactionOrderingPolicy, err error) {
+	policy = TransactionOrderingPolicy(s)
+	switch policy {
+	case TransactionOrderingFee, TransactionOrderingFIFO, TransactionOrderingNonce:
+	default:
+		err = fmt.Errorf("unknown transaction ordering policy: '%s'", s)
+	}
+
+	return
+}
+
+// Sort orders the items by the policy.
+func (p TransactionOrderingPolicy) Sort(items []TransactionPoolItem) {
+	switch p {
+	case TransactionOrderingFee:
+		sort.Slice(items, func(i, j int) bool {
+			if items[i].Transaction.B.Fee != items[j].Transaction.B.Fee {
+				return items[i].Transaction.B.Fee > items[j].Transaction.B.Fee
+			}
+			return items[i].receivedBefore(items[j])
+		})
+	case TransactionOrderingNonce:
+		sortTransactionPoolItemsByNonce(items)
+	default:
+		sort.Slice(items, func(i, j int) bool {
+			return items[i].receivedBefore(items[j])
+		})
+	}
+}
+
+func sortTransactionPoolItemsByNonce(items []TransactionPoolItem) {
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].receivedBefore(items[j])
+	})
+
+	var sources []string
+	bySource := map[string][]TransactionPoolItem{}
+	for _, item := range items {
+		source := item.Transaction.B.Source
+		if _, ok := bySource[source]; !ok {
+			sources = append(sources, source)
+		}
+		bySource[source] = append(bySource[source], item)
+	}
+
+	var ordered []TransactionPoolItem
+	for _, source := range sources {
+		ordered = append(ordered, orderByCheckpoint(bySource[source])...)
+	}
+
+	copy(items, ordered)
+}
+
+// orderByCheckpoint orders the transactions of same source by checkpoint
+// chain; the transaction which uses the next checkpoint of the other
+// transaction comes after it. The transactions out of the chain follow in
+// received order.
+func orderByCheckpoint(items []TransactionPoolItem) (ordered []TransactionPoolItem) {
+	byCheckpoint := map[string]int{}
+	nexts := map[string]bool{}
+	for i, item := range items {
+		if _, found := byCheckpoint[item.Transaction.B.Checkpoint]; !found {
+			byCheckpoint[item.Transaction.B.Checkpoint] = i
+		}
+		nexts[item.Transaction.NextCheckpoint()] = true
+	}
+
+	used := make([]bool, len(items))
+	for i, item := range items {
+		if used[i] || nexts[item.Transaction.B.Checkpoint] {
+			continue
+		}
+
+		for j := i; ; {
+			used[j] = true
+			ordered = append(ordered, items[j])
+
+			next, found := byCheckpoint[items[j].Transaction.NextCheckpoint()]
+			if !found || used[next] {
+				break
+			}
+			j = next
+		}
+	}
+
+	for i, item := range items {
+		if !used[i] {
+			ordered = append(ordered, item)
+		}
+	}
+
+	return
+}
+
+type TransactionPoolItem struct {
+	Transaction Transaction
+	Received    time.Time
+}
+
+func (i TransactionPoolItem) receivedBefore(o TransactionPoolItem) bool {
+	if !i.Received.Equal(o.Received) {
+		return i.Received.Before(o.Received)
+	}
+
+	return i.Transaction.GetHash() < o.Transaction.GetHash()
+}
+
+const (
+	DefaultTransactionPoolMaxSize       int = 10000
+	DefaultTransactionPoolMaxPerAccount int = 100
+)
+
+// TransactionPool keeps the received transactions until the proposer starts
+// the ballot for them. The pool is bounded by `maxSize` and `maxPerAccount`;
+// when it is full, the transaction with the lowest fee is evicted for the
+// transaction with the higher fee. With `SetStorage()`, the transactions in
+// pool are also kept in storage, so they survive the restart of node,
+//  * 'tp-<Transaction.GetHash()>': `TransactionPoolRecord`
+type TransactionPool struct {
+	sync.RWMutex
+
+	items map[ /* Transaction.GetHash() */ string]TransactionPoolItem
+
+	// evictions orders the items to be evicted, so the full pool does not
+	// look up all the items for the lowest fee.
+	evictions *transactionPoolEvictions
+
+	// spent tracks the checkpoints of source accounts, which are spent by the
+	// transactions in pool.
+	spent map[ /* Transaction.B.Source */ string]map[ /* Transaction.B.Checkpoint */ string] /* Transaction.GetHash() */ string
+
+	maxSize       int // 0 is unlimited
+	maxPerAccount int // 0 is unlimited
+	storage       *sebakstorage.LevelDBBackend
+	evictedHook   func(hash string)
+}
+
+func NewTransactionPool() *TransactionPool {
+	return &TransactionPool{
+		items:         map[string]TransactionPoolItem{},
+		evictions:     newTransactionPoolEvictions(),
+		spent:         map[string]map[string]string{},
+		maxSize:       DefaultTransactionPoolMaxSize,
+		maxPerAccount: DefaultTransactionPoolMaxPerAccount,
+	}
+}
+
+// SetLimits sets the maximum number of transactions in pool and the maximum
+// number of transactions of one source account; 0 is unlimited. The
+// transactions already in pool are not evicted by the new limits.
+func (tp *TransactionPool) SetLimits(maxSize, maxPerAccount int) {
+	tp.Lock()
+	defer tp.Unlock()
+
+	tp.maxSize = maxSize
+	tp.maxPerAccount = maxPerAccount
+}
+
+func (tp *TransactionPool) Limits() (maxSize, maxPerAccount int) {
+	tp.RLock()
+	defer tp.RUnlock()
+
+	return tp.maxSize, tp.maxPerAccount
+}
+
+// SetStorage loads the transactions kept in storage into pool and keeps the
+// changes of pool in storage from now. The transactions, which are already
+// in block, are dropped.
+func (tp *TransactionPool) SetStorage(st *sebakstorage.LevelDBBackend) (err error) {
+	tp.Lock()
+	defer tp.Unlock()
+
+	var records []TransactionPoolRecord
+	var dropped []string
+
+	iterFunc, closeFunc := st.GetIterator(TransactionPoolPrefixHash, false)
+	for {
+		item, hasNext := iterFunc()
+		if !hasNext {
+			break
+		}
+
+		var record TransactionPoolRecord
+		if record, err = NewTransactionPoolRecordFromJSON(item.Value); err != nil {
+			closeFunc()
+			return
+		}
+		records = append(records, record)
+	}
+	closeFunc()
+
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].Item().receivedBefore(records[j].Item())
+	})
+
+	for _, record := range records {
+		hash := record.Transaction.GetHash()
+
+		var exists bool
+		if exists, err = ExistBlockTransaction(st, hash); err != nil {
+			return
+		}
+		if exists {
+			dropped = append(dropped, hash)
+			continue
+		}
+
+		var evicted []string
+		if evicted, err = tp.add(record.Item()); err == sebakerror.ErrorTransactionAlreadyInPool {
+			err = nil
+			continue
+		} else if err != nil {
+			dropped = append(dropped, hash)
+			err = nil
+			continue
+		}
+		dropped = append(dropped, evicted...)
+	}
+
+	for _, hash := range dropped {
+		if err = st.Remove(GetTransactionPoolKey(hash)); err != nil {
+			return
+		}
+	}
+
+	// the transactions added before storage is set are also kept
+	for hash, item := range tp.items {
+		var exists bool
+		if exists, err = st.Has(GetTransactionPoolKey(hash)); err != nil {
+			return
+		} else if exists {
+			continue
+		}
+		if err = st.New(GetTransactionPoolKey(hash), NewTransactionPoolRecord(item)); err != nil {
+			return
+		}
+	}
+
+	tp.storage = st
+	log.Debug("transaction pool loaded", "transactions", len(tp.items), "dropped", len(dropped))
+
+	return
+}
+
+func (tp *TransactionPool) Len() int {
+	tp.RLock()
+	defer tp.RUnlock()
+
+	return len(tp.items)
+}
+
+func (tp *TransactionPool) Has(hash string) bool {
+	tp.RLock()
+	defer tp.RUnlock()
+
+	_, found := tp.items[hash]
+	return found
+}
+
+func (tp *TransactionPool) Get(hash string) (tx Transaction, found bool) {
+	tp.RLock()
+	defer tp.RUnlock()
+
+	var item TransactionPoolItem
+	if item, found = tp.items[hash]; found {
+		tx = item.Transaction
+	}
+
+	return
+}
+
+// BySource returns the transactions of source account in pool.
+func (tp *TransactionPool) BySource(source string) (txs []Transaction) {
+	tp.RLock()
+	defer tp.RUnlock()
+
+	for _, hash := range tp.spent[source] {
+		txs = append(txs, tp.items[hash].Transaction)
+	}
+
+	return
+}
+
+// SetEvictedHook sets the function, which is called with the hash of the
+// transaction evicted by the transaction of higher fee.
+func (tp *TransactionPool) SetEvictedHook(f func(hash string)) {
+	tp.Lock()
+	defer tp.Unlock()
+
+	tp.evictedHook = f
+}
+
+// SpentBy returns the hash of transaction in pool, which spends the
+// checkpoint of source account.
+func (tp *TransactionPool) SpentBy(source, checkpoint string) (hash string, found bool) {
+	tp.RLock()
+	defer tp.RUnlock()
+
+	hash, found = tp.spent[source][checkpoint]
+	return
+}
+
+// Add returns error if the transaction is already in pool, the other
+// transaction in pool already spends the same checkpoint of source account,
+// or the transaction exceeds the limits of pool.
+func (tp *TransactionPool) Add(tx Transaction) (err error) {
+	tp.Lock()
+	defer tp.Unlock()
+
+	item := TransactionPoolItem{Transaction: tx, Received: time.Now()}
+
+	var evicted []string
+	if evicted, err = tp.add(item); err != nil {
+		return
+	}
+
+	if len(evicted) > 0 {
+		log.Debug("evicted from transaction pool", "transactions", evicted, "by", tx.GetHash())
+	}
+	if tp.evictedHook != nil {
+		for _, hash := range evicted {
+			tp.evictedHook(hash)
+		}
+	}
+
+	if tp.storage == nil {
+		return
+	}
+
+	for _, hash := range evicted {
+		if err = tp.storage.Remove(GetTransactionPoolKey(hash)); err != nil {
+			return
+		}
+	}
+	if err = tp.storage.New(GetTransactionPoolKey(tx.GetHash()), NewTransactionPoolRecord(item)); err != nil {
+		tp.remove(tx.GetHash())
+		return
+	}
+
+	return
+}
+
+func (tp *TransactionPool) add(item TransactionPoolItem) (evicted []string, err error) {
+	tx := item.Transaction
+	if _, found := tp.items[tx.GetHash()]; found {
+		err = sebakerror.ErrorTransactionAlreadyInPool
+		return
+	}
+	if _, found := tp.spent[tx.B.Source][tx.B.Checkpoint]; found {
+		err = sebakerror.ErrorTransactionDoubleSpend
+		return
+	}
+	if tp.maxPerAccount > 0 && len(tp.spent[tx.B.Source]) >= tp.maxPerAccount {
+		err = sebakerror.ErrorTransactionPoolAccountLimit
+		return
+	}
+	if tp.maxSize > 0 && len(tp.items) >= tp.maxSize {
+		lowest, found := tp.lowestFee()
+		if !found || lowest.Transaction.B.Fee >= tx.B.Fee {
+			err = sebakerror.ErrorTransactionPoolFull
+			return
+		}
+		tp.remove(lowest.Transaction.GetHash())
+		evicted = append(evicted, lowest.Transaction.GetHash())
+	}
+
+	tp.items[tx.GetHash()] = item
+	heap.Push(tp.evictions, item)
+	if _, found := tp.spent[tx.B.Source]; !found {
+		tp.spent[tx.B.Source] = map[string]string{}
+	}
+	tp.spent[tx.B.Source][tx.B.Checkpoint] = tx.GetHash()
+
+	return
+}
+
+// lowestFee returns the item to be evicted first; the lowest fee, and then
+// the latest received.
+func (tp *TransactionPool) lowestFee() (lowest TransactionPoolItem, found bool) {
+	if tp.evictions.Len() < 1 {
+		return
+	}
+
+	return tp.evictions.items[0], true
+}
+
+// transactionPoolEvictions is the heap of the items in pool, which pops the
+// item to be evicted first; it implements `heap.Interface`.
+type transactionPoolEvictions struct {
+	items []TransactionPoolItem
+	index map[ /* Transaction.GetHash() */ string]int
+}
+
+func newTransactionPoolEvictions() *transactionPoolEvictions {
+	return &transactionPoolEvictions{index: map[string]int{}}
+}
+
+func (e *transactionPoolEvictions) Len() int {
+	return len(e.items)
+}
+
+func (e *transactionPoolEvictions) Less(i, j int) bool {
+	a, b := e.items[i], e.items[j]
+	if a.Transaction.B.Fee != b.Transaction.B.Fee {
+		return a.Transaction.B.Fee < b.Transaction.B.Fee
+	}
+
+	return b.receivedBefore(a)
+}
+
+func (e *transactionPoolEvictions) Swap(i, j int) {
+	e.items[i], e.items[j] = e.items[j], e.items[i]
+	e.index[e.items[i].Transaction.GetHash()] = i
+	e.index[e.items[j].Transaction.GetHash()] = j
+}
+
+func (e *transactionPoolEvictions) Push(x interface{}) {
+	item := x.(TransactionPoolItem)
+	e.index[item.Transaction.GetHash()] = len(e.items)
+	e.items = append(e.items, item)
+}
+
+func (e *transactionPoolEvictions) Pop() interface{} {
+	last := len(e.items) - 1
+	item := e.items[last]
+	e.items = e.items[:last]
+	delete(e.index, item.Transaction.GetHash())
+
+	return item
+}
+
+func (e *transactionPoolEvictions) remove(hash string) {
+	if i, found := e.index[hash]; found {
+		heap.Remove(e, i)
+	}
+}
+
+func (tp *TransactionPool) Remove(hashes ...string) {
+	tp.Lock()
+	defer tp.Unlock()
+
+	for _, hash := range hashes {
+		if !tp.remove(hash) || tp.storage == nil {
+			continue
+		}
+		if err := tp.storage.Remove(GetTransactionPoolKey(hash)); err != nil {
+			log.Error("failed to remove transaction from storage", "transaction", hash, "error", err)
+		}
+	}
+}
+
+// RemoveExpired removes the transactions, which are expired at `now` by
+// their `ValidUntil`, so they do not stay in pool forever.
+func (tp *TransactionPool) RemoveExpired(now time.Time) (expired []string) {
+	tp.RLock()
+	for hash, item := range tp.items {
+		if item.Transaction.CheckTimeBounds(now) == sebakerror.ErrorTransactionExpired {
+			expired = append(expired, hash)
+		}
+	}
+	tp.RUnlock()
+
+	if len(expired) > 0 {
+		tp.Remove(expired...)
+	}
+
+	return
+}
+
+func (tp *TransactionPool) remove(hash string) bool {
+	item, found := tp.items[hash]
+	if !found {
+		return false
+	}
+	delete(tp.items, hash)
+	tp.evictions.remove(hash)
+
+	tx := item.Transaction
+	delete(tp.spent[tx.B.Source], tx.B.Checkpoint)
+	if len(tp.spent[tx.B.Source]) < 1 {
+		delete(tp.spent, tx.B.Source)
+	}
+
+	return true
+}
+
+// Ordered returns the transactions in pool ordered by the policy.
+func (tp *TransactionPool) Ordered(policy TransactionOrderingPolicy) []TransactionPoolItem {
+	tp.RLock()
+	items := make([]TransactionPoolItem, 0, len(tp.items))
+	for _, item := range tp.items {
+		items = append(items, item)
+	}
+	tp.RUnlock()
+
+	policy.Sort(items)
+
+	return items
+}
+
+const TransactionPoolPrefixHash string = "tp-"
+
+func GetTransactionPoolKey(hash string) string {
+	return fmt.Sprintf("%s%s", TransactionPoolPrefixHash, hash)
+}
+
+// TransactionPoolRecord is the transaction in pool kept in storage.
+type TransactionPoolRecord struct {
+	Transaction Transaction
+	Received    time.Time
+}
+
+func NewTransactionPoolRecord(item TransactionPoolItem) TransactionPoolRecord {
+	return TransactionPoolRecord{
+		Transaction: item.Transaction,
+		Received:    item.Received,
+	}
+}
+
+func NewTransactionPoolRecordFromJSON(b []byte) (record TransactionPoolRecord, err error) {
+	var raw struct {
+		Transaction json.RawMessage
+		Received    time.Time
+	}
+	if err = json.Unmarshal(b, &raw); err != nil {
+		return
+	}
+
+	if record.Transaction, err = NewTransactionFromJSON(raw.Transaction); err != nil {
+		return
+	}
+	record.Received = raw.Received
+
+	return
+}
+
+func (r TransactionPoolRecord) Serialize() (encoded []byte, err error) {
+	encoded, err = json.Marshal(r)
+	return
+}
+
+func (r TransactionPoolRecord) Item() TransactionPoolItem {
+	return TransactionPoolItem{Transaction: r.Transaction, Received: r.Received}
+}
@@ -0,0 +1,340 @@
+package sebak
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stellar/go/keypair"
+
+	"boscoin.io/sebak/lib/error"
+	"boscoin.io/sebak/lib/storage"
+)
+
+func makeTransactionPoolItem(kp *keypair.Full, checkpoint string, fee Amount, received time.Time) TransactionPoolItem {
+	tx := TestMakeTransactionWithKeypair(networkID, 1, kp)
+	tx.B.Checkpoint = checkpoint
+	tx.B.Fee = fee
+	tx.Sign(kp, networkID)
+
+	return TransactionPoolItem{Transaction: tx, Received: received}
+}
+
+func TestTransactionPool(t *testing.T) {
+	tp := NewTransactionPool()
+
+	_, tx := TestMakeTransaction(networkID, 1)
+	if err := tp.Add(tx); err != nil {
+		t.Error("failed to add transaction")
+		return
+	}
+	if err := tp.Add(tx); err == nil {
+		t.Error("same transaction must not be added again")
+		return
+	}
+	if !tp.Has(tx.GetHash()) || tp.Len() != 1 {
+		t.Error("transaction not found in pool")
+		return
+	}
+
+	tp.Remove(tx.GetHash())
+	if tp.Has(tx.GetHash()) || tp.Len() != 0 {
+		t.Error("failed to remove transaction")
+		return
+	}
+}
+
+func TestTransactionPoolDoubleSpend(t *testing.T) {
+	tp := NewTransactionPool()
+
+	kp, _ := keypair.Random()
+	checkpoint := uuid.New().String()
+	a := makeTransactionPoolItem(kp, checkpoint, BaseFee, time.Now()).Transaction
+	b := makeTransactionPoolItem(kp, checkpoint, BaseFee*2, time.Now()).Transaction
+
+	if err := tp.Add(a); err != nil {
+		t.Error("failed to add transaction")
+		return
+	}
+	if err := tp.Add(b); err == nil {
+		t.Error("transaction spending same checkpoint must not be added")
+		return
+	}
+	if hash, found := tp.SpentBy(kp.Address(), checkpoint); !found || hash != a.GetHash() {
+		t.Error("failed to track spent checkpoint")
+		return
+	}
+
+	// next checkpoint can be added
+	c := makeTransactionPoolItem(kp, a.NextCheckpoint(), BaseFee, time.Now()).Transaction
+	if err := tp.Add(c); err != nil {
+		t.Error("failed to add transaction spending next checkpoint")
+		return
+	}
+
+	tp.Remove(a.GetHash())
+	if _, found := tp.SpentBy(kp.Address(), checkpoint); found {
+		t.Error("spent checkpoint must be released with transaction")
+		return
+	}
+	if err := tp.Add(b); err != nil {
+		t.Error("failed to add transaction after the conflicting one removed")
+		return
+	}
+}
+
+func TestTransactionOrderingPolicyFromString(t *testing.T) {
+	for _, s := range []string{"fee", "fifo", "nonce"} {
+		if _, err := NewTransactionOrderingPolicyFromString(s); err != nil {
+			t.Error(err)
+			return
+		}
+	}
+
+	if _, err := NewTransactionOrderingPolicyFromString("random"); err == nil {
+		t.Error("unknown policy must be refused")
+		return
+	}
+}
+
+func TestTransactionOrderingPolicyFIFOAndFee(t *testing.T) {
+	now := time.Now()
+
+	kpA, _ := keypair.Random()
+	kpB, _ := keypair.Random()
+	kpC, _ := keypair.Random()
+
+	a := makeTransactionPoolItem(kpA, uuid.New().String(), BaseFee, now)
+	b := makeTransactionPoolItem(kpB, uuid.New().String(), BaseFee*3, now.Add(time.Second))
+	c := makeTransactionPoolItem(kpC, uuid.New().String(), BaseFee*2, now.Add(time.Second*2))
+
+	items := []TransactionPoolItem{c, a, b}
+	TransactionOrderingFIFO.Sort(items)
+	if items[0].Transaction.GetHash() != a.Transaction.GetHash() ||
+		items[1].Transaction.GetHash() != b.Transaction.GetHash() ||
+		items[2].Transaction.GetHash() != c.Transaction.GetHash() {
+		t.Error("wrong order by `fifo`")
+		return
+	}
+
+	TransactionOrderingFee.Sort(items)
+	if items[0].Transaction.GetHash() != b.Transaction.GetHash() ||
+		items[1].Transaction.GetHash() != c.Transaction.GetHash() ||
+		items[2].Transaction.GetHash() != a.Transaction.GetHash() {
+		t.Error("wrong order by `fee`")
+		return
+	}
+}
+
+// TestTransactionOrderingPolicyTieBreak checks, the transactions which can not
+// be ordered by the policy are ordered by their hash.
+func TestTransactionOrderingPolicyTieBreak(t *testing.T) {
+	now := time.Now()
+
+	var items []TransactionPoolItem
+	for i := 0; i < 5; i++ {
+		kp, _ := keypair.Random()
+		items = append(items, makeTransactionPoolItem(kp, uuid.New().String(), BaseFee, now))
+	}
+
+	for _, policy := range []TransactionOrderingPolicy{TransactionOrderingFee, TransactionOrderingFIFO, TransactionOrderingNonce} {
+		policy.Sort(items)
+		for i := 1; i < len(items); i++ {
+			if items[i-1].Transaction.GetHash() > items[i].Transaction.GetHash() {
+				t.Errorf("transactions must be ordered by hash in `%s`", policy)
+				return
+			}
+		}
+	}
+}
+
+func TestTransactionOrderingPolicyNonce(t *testing.T) {
+	now := time.Now()
+
+	kpA, _ := keypair.Random()
+	kpB, _ := keypair.Random()
+
+	a0 := makeTransactionPoolItem(kpA, uuid.New().String(), BaseFee, now.Add(time.Second*3))
+	a1 := makeTransactionPoolItem(kpA, a0.Transaction.NextCheckpoint(), BaseFee, now.Add(time.Second*1))
+	a2 := makeTransactionPoolItem(kpA, a1.Transaction.NextCheckpoint(), BaseFee, now)
+	b0 := makeTransactionPoolItem(kpB, uuid.New().String(), BaseFee, now.Add(time.Second*2))
+
+	items := []TransactionPoolItem{b0, a0, a2, a1}
+	TransactionOrderingNonce.Sort(items)
+
+	expected := []TransactionPoolItem{a0, a1, a2, b0}
+	for i, item := range items {
+		if item.Transaction.GetHash() != expected[i].Transaction.GetHash() {
+			t.Errorf("wrong order by `nonce` at %d", i)
+			return
+		}
+	}
+}
+
+func TestTransactionPoolEvictByFee(t *testing.T) {
+	tp := NewTransactionPool()
+	tp.SetLimits(2, 0)
+
+	kpA, _ := keypair.Random()
+	kpB, _ := keypair.Random()
+	kpC, _ := keypair.Random()
+	kpD, _ := keypair.Random()
+	a := makeTransactionPoolItem(kpA, uuid.New().String(), BaseFee*2, time.Now()).Transaction
+	b := makeTransactionPoolItem(kpB, uuid.New().String(), BaseFee, time.Now()).Transaction
+	c := makeTransactionPoolItem(kpC, uuid.New().String(), BaseFee, time.Now()).Transaction
+	d := makeTransactionPoolItem(kpD, uuid.New().String(), BaseFee*3, time.Now()).Transaction
+
+	tp.Add(a)
+	tp.Add(b)
+	if err := tp.Add(c); err != sebakerror.ErrorTransactionPoolFull {
+		t.Errorf("transaction with the same lowest fee must be refused: %v", err)
+		return
+	}
+
+	if err := tp.Add(d); err != nil {
+		t.Error(err)
+		return
+	}
+	if tp.Len() != 2 || tp.Has(b.GetHash()) || !tp.Has(a.GetHash()) || !tp.Has(d.GetHash()) {
+		t.Error("transaction with the lowest fee must be evicted")
+		return
+	}
+	if _, found := tp.SpentBy(kpB.Address(), b.B.Checkpoint); found {
+		t.Error("checkpoint of evicted transaction must be released")
+		return
+	}
+}
+
+func TestTransactionPoolAccountLimit(t *testing.T) {
+	tp := NewTransactionPool()
+	tp.SetLimits(0, 2)
+
+	kp, _ := keypair.Random()
+	a := makeTransactionPoolItem(kp, uuid.New().String(), BaseFee, time.Now()).Transaction
+	b := makeTransactionPoolItem(kp, a.NextCheckpoint(), BaseFee, time.Now()).Transaction
+	c := makeTransactionPoolItem(kp, b.NextCheckpoint(), BaseFee, time.Now()).Transaction
+
+	tp.Add(a)
+	tp.Add(b)
+	if err := tp.Add(c); err != sebakerror.ErrorTransactionPoolAccountLimit {
+		t.Errorf("transaction over the account limit must be refused: %v", err)
+		return
+	}
+
+	tp.Remove(a.GetHash())
+	if err := tp.Add(c); err != nil {
+		t.Error(err)
+		return
+	}
+}
+
+func TestTransactionPoolPersistence(t *testing.T) {
+	st, _ := sebakstorage.NewTestMemoryLevelDBBackend()
+
+	kpA, _ := keypair.Random()
+	kpB, _ := keypair.Random()
+	a := makeTransactionPoolItem(kpA, uuid.New().String(), BaseFee, time.Now()).Transaction
+	b := makeTransactionPoolItem(kpB, uuid.New().String(), BaseFee, time.Now()).Transaction
+	c := makeTransactionPoolItem(kpB, b.NextCheckpoint(), BaseFee, time.Now()).Transaction
+
+	tp := NewTransactionPool()
+	tp.Add(a) // added before storage is set
+	if err := tp.SetStorage(st); err != nil {
+		t.Error(err)
+		return
+	}
+	tp.Add(b)
+	tp.Add(c)
+	tp.Remove(c.GetHash())
+
+	// `b` is stored in block before restart
+	bt := NewBlockTransactionFromTransaction(b, []byte{})
+	bt.Save(st)
+
+	restarted := NewTransactionPool()
+	if err := restarted.SetStorage(st); err != nil {
+		t.Error(err)
+		return
+	}
+	if restarted.Len() != 1 || !restarted.Has(a.GetHash()) {
+		t.Errorf("only the transaction, which is not in block, must be loaded; %d", restarted.Len())
+		return
+	}
+	if exists, _ := st.Has(GetTransactionPoolKey(b.GetHash())); exists {
+		t.Error("transaction in block must be removed from storage")
+		return
+	}
+
+	loaded := restarted.Ordered(TransactionOrderingFIFO)[0]
+	if loaded.Transaction.GetHash() != a.GetHash() {
+		t.Error("loaded transaction does not match")
+		return
+	}
+	if err := loaded.Transaction.IsWellFormed(networkID); err != nil {
+		t.Error(err)
+		return
+	}
+}
+
+func TestTransactionPoolRemoveExpired(t *testing.T) {
+	tp := NewTransactionPool()
+
+	kp, _ := keypair.Random()
+	expiring := makeTransactionPoolItem(kp, uuid.New().String(), BaseFee, time.Now()).Transaction
+	expiring.B.ValidUntil = time.Now().Add(time.Minute).Format(time.RFC3339)
+	expiring.Sign(kp, networkID)
+	unbounded := makeTransactionPoolItem(kp, uuid.New().String(), BaseFee, time.Now()).Transaction
+
+	tp.Add(expiring)
+	tp.Add(unbounded)
+
+	if expired := tp.RemoveExpired(time.Now()); len(expired) != 0 {
+		t.Errorf("transaction must not be expired yet: %v", expired)
+		return
+	}
+	expired := tp.RemoveExpired(time.Now().Add(time.Hour))
+	if len(expired) != 1 || expired[0] != expiring.GetHash() {
+		t.Errorf("wrong expired transactions: %v", expired)
+		return
+	}
+	if tp.Has(expiring.GetHash()) || !tp.Has(unbounded.GetHash()) {
+		t.Error("only the expired transaction must be removed")
+		return
+	}
+}
+
+// TestTransactionPoolEvictionOrder checks, the transaction to be evicted is
+// the lowest fee and then the latest received, after the transactions are
+// added and removed.
+func TestTransactionPoolEvictionOrder(t *testing.T) {
+	tp := NewTransactionPool()
+
+	now := time.Now()
+	var items []TransactionPoolItem
+	for i, fee := range []Amount{BaseFee * 3, BaseFee, BaseFee * 2, BaseFee, BaseFee * 4, BaseFee} {
+		kp, _ := keypair.Random()
+		item := makeTransactionPoolItem(kp, uuid.New().String(), fee, now.Add(time.Duration(i)*time.Second))
+		if _, err := tp.add(item); err != nil {
+			t.Error(err)
+			return
+		}
+		items = append(items, item)
+	}
+
+	// the removed transaction is not evicted
+	tp.Remove(items[2].Transaction.GetHash())
+
+	for _, expected := range []int{5, 3, 1, 0, 4} {
+		lowest, found := tp.lowestFee()
+		if !found || lowest.Transaction.GetHash() != items[expected].Transaction.GetHash() {
+			t.Errorf("wrong transaction to be evicted; expected=%d", expected)
+			return
+		}
+		tp.Remove(lowest.Transaction.GetHash())
+	}
+
+	if _, found := tp.lowestFee(); found {
+		t.Error("empty pool must not have the transaction to be evicted")
+		return
+	}
+}
@@ -0,0 +1,122 @@
+package sebak
+
+import (
+	"fmt"
+
+	"boscoin.io/sebak/lib/common"
+	"boscoin.io/sebak/lib/error"
+	"boscoin.io/sebak/lib/storage"
+)
+
+// TransactionResult is the outcome of transaction, which is kept after the
+// transaction is forgotten by `TransactionStatusTracker`, so the clients can
+// tell why the transaction failed, like `tx_bad_checkpoint` from
+// `tx_insufficient_balance`, after the fact.
+//  * the included transaction: saved with it's block in `FinishTransaction()`
+//  and `ApplySyncedBlock()`, so every node has the same result; it has the
+//  block and the charged fee, and every operation is `success`.
+//  * the rejected transaction: saved by the node, which rejected it, so it is
+//  found only in that node; it has no block and no fee is charged.
+// The result of the rejected transaction is replaced, when the transaction is
+// submitted again and included, but the result of the included one is never
+// replaced. The result is stored by,
+//  * 'tr-<transaction hash>': `TransactionResult`
+
+const TransactionResultPrefixHash string = "tr-"
+
+type TransactionResult struct {
+	Hash        string                       `json:"hash"`
+	BlockHash   string                       `json:"block_hash,omitempty"`
+	BlockHeight uint64                       `json:"block_height,omitempty"`
+	Result      sebakerror.ResultCode        `json:"result"`
+	Reason      string                       `json:"reason,omitempty"`
+	Fee         Amount                       `json:"fee"` // the charged fee
+	Operations  []sebakerror.OperationResult `json:"operations"`
+	Confirmed   string                       `json:"confirmed"`
+}
+
+// NewIncludedTransactionResult makes the result of the transaction included
+// in `block`.
+func NewIncludedTransactionResult(tx Transaction, block Block) TransactionResult {
+	operations := []sebakerror.OperationResult{}
+	for i := range tx.B.Operations {
+		operations = append(operations, sebakerror.OperationResult{Index: i, Result: sebakerror.ResultSuccess})
+	}
+
+	return TransactionResult{
+		Hash:        tx.GetHash(),
+		BlockHash:   block.Hash,
+		BlockHeight: block.Height,
+		Result:      sebakerror.ResultSuccess,
+		Fee:         tx.TotalFee(),
+		Operations:  operations,
+		Confirmed:   block.Confirmed,
+	}
+}
+
+// NewRejectedTransactionResult makes the result of the transaction rejected by
+// `reason`; the results of operations are kept from
+// `sebakerror.OperationsError`.
+func NewRejectedTransactionResult(hash string, reason error) TransactionResult {
+	tr := TransactionResult{
+		Hash:       hash,
+		Result:     sebakerror.ResultOf(reason),
+		Reason:     reason.Error(),
+		Operations: []sebakerror.OperationResult{},
+		Confirmed:  sebakcommon.NowISO8601(),
+	}
+	if e, ok := reason.(*sebakerror.OperationsError); ok {
+		tr.Operations = e.Operations
+	}
+
+	return tr
+}
+
+func (tr TransactionResult) IsIncluded() bool {
+	return len(tr.BlockHash) > 0
+}
+
+func GetTransactionResultKey(hash string) string {
+	return fmt.Sprintf("%s%s", TransactionResultPrefixHash, hash)
+}
+
+// Save stores the result; the result of the included transaction is not
+// replaced by the rejected one.
+func (tr TransactionResult) Save(st *sebakstorage.LevelDBBackend) (err error) {
+	key := GetTransactionResultKey(tr.Hash)
+
+	var exists bool
+	if exists, err = st.Has(key); err != nil {
+		return
+	}
+
+	if !exists {
+		err = st.New(key, tr)
+		return
+	}
+
+	if !tr.IsIncluded() {
+		var saved TransactionResult
+		if err = st.Get(key, &saved); err != nil {
+			return
+		}
+		if saved.IsIncluded() {
+			return
+		}
+	}
+
+	err = st.Set(key, tr)
+
+	return
+}
+
+func GetTransactionResult(st *sebakstorage.LevelDBBackend, hash string) (tr TransactionResult, found bool, err error) {
+	key := GetTransactionResultKey(hash)
+	if found, err = st.Has(key); err != nil || !found {
+		return
+	}
+
+	err = st.Get(key, &tr)
+
+	return
+}
@@ -0,0 +1,92 @@
+package sebak
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"boscoin.io/sebak/lib/error"
+	"boscoin.io/sebak/lib/network"
+	"boscoin.io/sebak/lib/storage"
+)
+
+func TestTransactionResult(t *testing.T) {
+	st, _ := sebakstorage.NewTestMemoryLevelDBBackend()
+	defer st.Close()
+
+	pool := NewTransactionPool()
+	tracker := NewTransactionStatusTracker(MaxTransactionStatuses)
+	tracker.SetStorage(st)
+
+	// the rejected transaction is kept with it's result code
+	bad := testMakeTransactionWithFee(BaseFee)
+	poor := testMakeTransactionWithFee(BaseFee)
+	tracker.Rejected(bad.GetHash(), sebakerror.ErrorTransactionInvalidCheckpoint)
+	tracker.Rejected(poor.GetHash(), sebakerror.NewOperationsError([]error{sebakerror.ErrorAccountBalanceUnderZero}))
+
+	tr, found, err := GetTransactionResult(st, bad.GetHash())
+	if err != nil || !found || tr.Result != sebakerror.ResultTransactionBadCheckpoint || tr.IsIncluded() || tr.Fee != 0 {
+		t.Errorf("wrong result of rejected transaction: %v %v", tr, err)
+		return
+	}
+	if tr, _, _ = GetTransactionResult(st, poor.GetHash()); tr.Result == sebakerror.ResultTransactionBadCheckpoint || len(tr.Operations) != 1 || !tr.Operations[0].IsFailed() {
+		t.Errorf("wrong result of rejected transaction: %v", tr)
+		return
+	}
+
+	// the forgotten rejected transaction is found in storage
+	status, found, _ := GetTransactionStatus(st, pool, NewTransactionStatusTracker(0), bad.GetHash())
+	if !found || status.State != TransactionStateRejected || status.Result != sebakerror.ResultTransactionBadCheckpoint {
+		t.Errorf("wrong status of forgotten transaction: %v", status)
+		return
+	}
+
+	// the included transaction replaces the rejected result, but it is never
+	// replaced
+	block := NewBlock(Block{}, "", bad.GetHash())
+	included := NewIncludedTransactionResult(bad, block)
+	if err = included.Save(st); err != nil {
+		t.Error(err)
+		return
+	}
+	NewRejectedTransactionResult(bad.GetHash(), sebakerror.ErrorTransactionDoubleSpend).Save(st)
+
+	tr, _, _ = GetTransactionResult(st, bad.GetHash())
+	if !tr.IsIncluded() || tr.Result != sebakerror.ResultSuccess || tr.BlockHeight != block.Height || tr.Fee != bad.TotalFee() {
+		t.Errorf("wrong result of included transaction: %v", tr)
+		return
+	}
+	if len(tr.Operations) != len(bad.B.Operations) || tr.Operations[0].IsFailed() {
+		t.Errorf("wrong results of operations: %v", tr.Operations)
+		return
+	}
+}
+
+func TestNodeRunnerAPITransactionResult(t *testing.T) {
+	defer sebaknetwork.CleanUpMemoryNetwork()
+
+	nr := createNodeRunners(1)[0]
+
+	tx := testMakeTransactionWithFee(BaseFee)
+	NewRejectedTransactionResult(tx.GetHash(), sebakerror.ErrorTransactionInvalidCheckpoint).Save(nr.storage)
+
+	request := func(path string) (w *httptest.ResponseRecorder) {
+		w = httptest.NewRecorder()
+		nr.handleAPITransaction(w, httptest.NewRequest("GET", APIVersionPrefix+path, nil))
+		return
+	}
+
+	w := request(GetTransactionsPattern + tx.GetHash() + "/" + GetTransactionResultSubPattern)
+	var tr TransactionResult
+	json.Unmarshal(w.Body.Bytes(), &tr)
+	if w.Code != http.StatusOK || tr.Hash != tx.GetHash() || tr.Result != sebakerror.ResultTransactionBadCheckpoint {
+		t.Errorf("wrong result: %d %v", w.Code, tr)
+		return
+	}
+
+	if w = request(GetTransactionsPattern + "unknown/" + GetTransactionResultSubPattern); w.Code != http.StatusNotFound {
+		t.Errorf("unknown transaction must be not found: %d", w.Code)
+		return
+	}
+}
@@ -0,0 +1,285 @@
+package sebak
+
+import (
+	"sync"
+
+	"boscoin.io/sebak/lib/common"
+	"boscoin.io/sebak/lib/error"
+	"boscoin.io/sebak/lib/storage"
+)
+
+// TransactionState is the state of transaction in it's lifecycle,
+//  * `submitted`: received by the API, but not yet in the transaction pool
+//  * `queued`: held in the future transaction queue until it's checkpoint is
+//  reached
+//  * `pending`: in the transaction pool or in consensus
+//  * `included`: included in the block
+//  * `finalized`: the block is not above the finality marker, so it can not
+//  be reverted
+//  * `rejected`: refused by the checks of node or by consensus; the reason
+//  is kept with the result code
+type TransactionState string
+
+const (
+	TransactionStateSubmitted TransactionState = "submitted"
+	TransactionStateQueued    TransactionState = "queued"
+	TransactionStatePending   TransactionState = "pending"
+	TransactionStateIncluded  TransactionState = "included"
+	TransactionStateFinalized TransactionState = "finalized"
+	TransactionStateRejected  TransactionState = "rejected"
+)
+
+// MaxTransactionStatuses is the maximum number of the tracked transactions;
+// the oldest one is forgotten first. The included transactions are found in
+// storage after they are forgotten.
+const MaxTransactionStatuses int = 10000
+
+type TransactionStatus struct {
+	Hash        string                `json:"hash"`
+	State       TransactionState      `json:"state"`
+	BlockHash   string                `json:"block_hash,omitempty"`
+	BlockHeight uint64                `json:"block_height,omitempty"`
+	Result      sebakerror.ResultCode `json:"result,omitempty"`
+	Reason      string                `json:"reason,omitempty"`
+	Updated     string                `json:"updated"`
+
+	// Operations are the results of every operation, when the operations of
+	// transaction failed.
+	Operations []sebakerror.OperationResult `json:"operations,omitempty"`
+
+	refused error // the reason, why this node voted NO in consensus
+}
+
+// TransactionStatusTracker keeps the states of the transactions, which this
+// node has seen, only in memory; after restart, the pending transactions
+// are found in the transaction pool and the included ones in storage. With
+// `SetStorage()`, the rejected transactions are also kept in storage as
+// `TransactionResult`.
+type TransactionStatusTracker struct {
+	sync.RWMutex
+
+	statuses map[ /* Transaction.GetHash() */ string]TransactionStatus
+	order    []string // by the first time of tracking
+	maxSize  int
+	storage  *sebakstorage.LevelDBBackend
+}
+
+func NewTransactionStatusTracker(maxSize int) *TransactionStatusTracker {
+	return &TransactionStatusTracker{
+		statuses: map[string]TransactionStatus{},
+		maxSize:  maxSize,
+	}
+}
+
+// SetStorage keeps the results of the rejected transactions in storage from
+// now.
+func (t *TransactionStatusTracker) SetStorage(st *sebakstorage.LevelDBBackend) {
+	t.Lock()
+	defer t.Unlock()
+
+	t.storage = st
+}
+
+func (t *TransactionStatusTracker) Get(hash string) (status TransactionStatus, found bool) {
+	t.RLock()
+	defer t.RUnlock()
+
+	status, found = t.statuses[hash]
+	return
+}
+
+func (t *TransactionStatusTracker) Len() int {
+	t.RLock()
+	defer t.RUnlock()
+
+	return len(t.statuses)
+}
+
+func (t *TransactionStatusTracker) Submitted(hash string) {
+	t.update(hash, func(s *TransactionStatus) bool {
+		if s.State != "" && s.State != TransactionStateRejected && s.State != TransactionStateQueued {
+			return false
+		}
+		*s = TransactionStatus{Hash: hash, State: TransactionStateSubmitted}
+		return true
+	})
+}
+
+func (t *TransactionStatusTracker) Queued(hash string) {
+	t.update(hash, func(s *TransactionStatus) bool {
+		if s.State != "" && s.State != TransactionStateRejected {
+			return false
+		}
+		*s = TransactionStatus{Hash: hash, State: TransactionStateQueued}
+		return true
+	})
+}
+
+func (t *TransactionStatusTracker) Pending(hash string) {
+	t.update(hash, func(s *TransactionStatus) bool {
+		if s.State == TransactionStateIncluded {
+			return false
+		}
+		*s = TransactionStatus{Hash: hash, State: TransactionStatePending}
+		return true
+	})
+}
+
+func (t *TransactionStatusTracker) Included(hash string, block Block) {
+	t.update(hash, func(s *TransactionStatus) bool {
+		*s = TransactionStatus{
+			Hash:        hash,
+			State:       TransactionStateIncluded,
+			BlockHash:   block.Hash,
+			BlockHeight: block.Height,
+		}
+		return true
+	})
+}
+
+// Refused keeps the reason, why this node voted NO for the transaction; it
+// becomes the reason of rejection, when the consensus also rejects it.
+func (t *TransactionStatusTracker) Refused(hash string, reason error) {
+	t.update(hash, func(s *TransactionStatus) bool {
+		if s.State == TransactionStateIncluded {
+			return false
+		}
+		if s.State == "" {
+			s.Hash, s.State = hash, TransactionStatePending
+		}
+		s.refused = reason
+		return true
+	})
+}
+
+// Rejected marks the transaction as rejected by `reason`; if `reason` is nil,
+// it is rejected by consensus and the reason of `Refused()` is used.
+func (t *TransactionStatusTracker) Rejected(hash string, reason error) {
+	var rejected bool
+	t.update(hash, func(s *TransactionStatus) bool {
+		if s.State == TransactionStateIncluded {
+			return false
+		}
+		if reason == nil {
+			reason = s.refused
+		}
+		if reason == nil {
+			reason = sebakerror.ErrorTransactionRejected
+		}
+
+		*s = TransactionStatus{
+			Hash:   hash,
+			State:  TransactionStateRejected,
+			Result: sebakerror.ResultOf(reason),
+			Reason: reason.Error(),
+		}
+		if e, ok := reason.(*sebakerror.OperationsError); ok {
+			s.Operations = e.Operations
+		}
+		rejected = true
+		return true
+	})
+	if !rejected {
+		return
+	}
+
+	t.RLock()
+	st := t.storage
+	t.RUnlock()
+	if st == nil {
+		return
+	}
+	if err := NewRejectedTransactionResult(hash, reason).Save(st); err != nil {
+		log.Error("failed to save the result of rejected transaction", "transaction", hash, "error", err)
+	}
+}
+
+func (t *TransactionStatusTracker) update(hash string, f func(*TransactionStatus) bool) {
+	t.Lock()
+	defer t.Unlock()
+
+	status, found := t.statuses[hash]
+	if !f(&status) {
+		return
+	}
+	status.Updated = sebakcommon.NowISO8601()
+	t.statuses[hash] = status
+
+	if found {
+		return
+	}
+	t.order = append(t.order, hash)
+	if t.maxSize > 0 && len(t.order) > t.maxSize {
+		delete(t.statuses, t.order[0])
+		t.order = t.order[1:]
+	}
+}
+
+// GetTransactionStatus returns the status of transaction. The included
+// transaction and the rejected one are found in storage, even if they are not
+// tracked; the included one is `finalized`, when it's block is not higher
+// than the finality marker. In ISAAC, every committed block is final, so the
+// transaction, whose block is not known, is `finalized` with the marker.
+func GetTransactionStatus(st *sebakstorage.LevelDBBackend, pool *TransactionPool, tracker *TransactionStatusTracker, hash string) (status TransactionStatus, found bool, err error) {
+	status, found = tracker.Get(hash)
+
+	var included bool
+	if included, err = ExistBlockTransaction(st, hash); err != nil {
+		return
+	}
+	if included {
+		if !found || status.State != TransactionStateIncluded {
+			var bt BlockTransaction
+			if bt, err = GetBlockTransaction(st, hash); err != nil {
+				return
+			}
+			status = TransactionStatus{Hash: hash, State: TransactionStateIncluded, Updated: bt.Confirmed}
+
+			var tr TransactionResult
+			var hasResult bool
+			if tr, hasResult, err = GetTransactionResult(st, hash); err != nil {
+				return
+			} else if hasResult && tr.IsIncluded() {
+				status.BlockHash, status.BlockHeight = tr.BlockHash, tr.BlockHeight
+			}
+		}
+		found = true
+
+		var f Finality
+		if f, err = GetFinality(st); err != nil {
+			return
+		}
+		if !f.IsEmpty() && (status.BlockHeight < 1 || status.BlockHeight <= f.Height) {
+			status.State = TransactionStateFinalized
+		}
+
+		return
+	}
+
+	if found {
+		return
+	}
+	if pool.Has(hash) {
+		status = TransactionStatus{Hash: hash, State: TransactionStatePending}
+		found = true
+		return
+	}
+
+	// the rejected transaction, which is forgotten, is found in storage
+	var tr TransactionResult
+	if tr, found, err = GetTransactionResult(st, hash); err != nil || !found {
+		return
+	}
+	status = TransactionStatus{
+		Hash:    hash,
+		State:   TransactionStateRejected,
+		Result:  tr.Result,
+		Reason:  tr.Reason,
+		Updated: tr.Confirmed,
+	}
+	if len(tr.Operations) > 0 {
+		status.Operations = tr.Operations
+	}
+
+	return
+}
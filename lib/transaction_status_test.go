@@ -0,0 +1,151 @@
+package sebak
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"boscoin.io/sebak/lib/error"
+	"boscoin.io/sebak/lib/network"
+	"boscoin.io/sebak/lib/storage"
+)
+
+func TestTransactionStatusTracker(t *testing.T) {
+	tracker := NewTransactionStatusTracker(2)
+
+	tracker.Submitted("a")
+	tracker.Pending("a")
+	tracker.Submitted("a")
+	if status, _ := tracker.Get("a"); status.State != TransactionStatePending {
+		t.Errorf("pending transaction must not go back to submitted: %v", status)
+		return
+	}
+
+	// the reason of the vote of this node is the reason of rejection
+	tracker.Refused("a", sebakerror.ErrorTransactionInvalidCheckpoint)
+	tracker.Rejected("a", nil)
+	if status, _ := tracker.Get("a"); status.State != TransactionStateRejected || status.Result != sebakerror.ResultTransactionBadCheckpoint {
+		t.Errorf("wrong rejected status: %v", status)
+		return
+	}
+
+	tracker.Pending("b")
+	tracker.Rejected("b", nil)
+	if status, _ := tracker.Get("b"); status.Result != sebakerror.ResultTransactionRejected || len(status.Reason) < 1 {
+		t.Errorf("wrong rejected status: %v", status)
+		return
+	}
+
+	block := NewBlock(Block{}, "", "b")
+	tracker.Included("b", block)
+	tracker.Rejected("b", sebakerror.ErrorTransactionDoubleSpend)
+	if status, _ := tracker.Get("b"); status.State != TransactionStateIncluded || status.BlockHeight != block.Height || status.BlockHash != block.Hash {
+		t.Errorf("included transaction must not be rejected: %v", status)
+		return
+	}
+
+	// the oldest is forgotten
+	tracker.Submitted("c")
+	if _, found := tracker.Get("a"); found || tracker.Len() != 2 {
+		t.Error("oldest status must be forgotten")
+		return
+	}
+}
+
+func TestGetTransactionStatus(t *testing.T) {
+	st, _ := sebakstorage.NewTestMemoryLevelDBBackend()
+	defer st.Close()
+
+	pool := NewTransactionPool()
+	tracker := NewTransactionStatusTracker(MaxTransactionStatuses)
+	pool.SetEvictedHook(func(hash string) {
+		tracker.Rejected(hash, sebakerror.ErrorTransactionEvicted)
+	})
+
+	// not tracked, but in pool
+	low := testMakeTransactionWithFee(BaseFee)
+	pool.Add(low)
+	if status, found, _ := GetTransactionStatus(st, pool, tracker, low.GetHash()); !found || status.State != TransactionStatePending {
+		t.Errorf("transaction in pool must be pending: %v", status)
+		return
+	}
+
+	pool.SetLimits(1, 0)
+	pool.Add(testMakeTransactionWithFee(BaseFee * 2))
+	status, found, _ := GetTransactionStatus(st, pool, tracker, low.GetHash())
+	if !found || status.State != TransactionStateRejected || status.Result != sebakerror.ResultTransactionEvicted {
+		t.Errorf("evicted transaction must be rejected: %v", status)
+		return
+	}
+
+	// included, but not yet below the finality marker
+	tx := testMakeTransactionWithFee(BaseFee)
+	bt := NewBlockTransactionFromTransaction(tx, tx.B.MakeHash())
+	bt.Save(st)
+	first := NewBlock(Block{}, "", tx.GetHash())
+	first.Save(st)
+	second := NewBlock(first, "")
+	second.Save(st)
+	tracker.Included(tx.GetHash(), second)
+
+	if status, _, _ = GetTransactionStatus(st, pool, tracker, tx.GetHash()); status.State != TransactionStateIncluded {
+		t.Errorf("wrong status: %v", status)
+		return
+	}
+
+	NewFinality(first).Save(st)
+	if status, _, _ = GetTransactionStatus(st, pool, tracker, tx.GetHash()); status.State != TransactionStateIncluded {
+		t.Errorf("block above the marker must not be finalized: %v", status)
+		return
+	}
+
+	NewFinality(second).Save(st)
+	if status, _, _ = GetTransactionStatus(st, pool, tracker, tx.GetHash()); status.State != TransactionStateFinalized || status.BlockHeight != second.Height {
+		t.Errorf("wrong status: %v", status)
+		return
+	}
+
+	// not tracked, but in block
+	if status, _, _ = GetTransactionStatus(st, pool, NewTransactionStatusTracker(0), tx.GetHash()); status.State != TransactionStateFinalized {
+		t.Errorf("wrong status: %v", status)
+		return
+	}
+
+	if _, found, _ = GetTransactionStatus(st, pool, tracker, "unknown"); found {
+		t.Error("unknown transaction must not be found")
+		return
+	}
+}
+
+func TestNodeRunnerAPITransactionStatus(t *testing.T) {
+	defer sebaknetwork.CleanUpMemoryNetwork()
+
+	nr := createNodeRunners(1)[0]
+
+	tx := testMakeTransactionWithFee(BaseFee)
+	nr.TransactionStatuses().Submitted(tx.GetHash())
+
+	request := func(path string) (w *httptest.ResponseRecorder) {
+		w = httptest.NewRecorder()
+		nr.handleAPITransaction(w, httptest.NewRequest("GET", APIVersionPrefix+path, nil))
+		return
+	}
+
+	w := request(GetTransactionsPattern + tx.GetHash() + "/" + GetTransactionStatusSubPattern)
+	var status TransactionStatus
+	json.Unmarshal(w.Body.Bytes(), &status)
+	if w.Code != http.StatusOK || status.Hash != tx.GetHash() || status.State != TransactionStateSubmitted {
+		t.Errorf("wrong status: %d %v", w.Code, status)
+		return
+	}
+
+	if w = request(GetTransactionsPattern + "unknown/" + GetTransactionStatusSubPattern); w.Code != http.StatusNotFound {
+		t.Errorf("unknown transaction must be not found: %d", w.Code)
+		return
+	}
+	if w = request(GetTransactionsPattern + tx.GetHash()); w.Code != http.StatusNotFound {
+		t.Errorf("path without status must be not found: %d", w.Code)
+		return
+	}
+}
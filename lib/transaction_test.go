@@ -1,9 +1,14 @@
 package sebak
 
 import (
+	"encoding/json"
 	"testing"
+	"time"
 
 	"github.com/btcsuite/btcutil/base58"
+	"github.com/google/uuid"
+	"boscoin.io/sebak/lib/common"
+	"boscoin.io/sebak/lib/error"
 	"boscoin.io/sebak/lib/storage"
 	"github.com/stellar/go/keypair"
 )
@@ -100,3 +105,223 @@ func TestIsWellFormedTransactionWithInvalidSignature(t *testing.T) {
 		t.Errorf("transaction must be failed for signature verification")
 	}
 }
+
+func TestValidateTransactionState(t *testing.T) {
+	st, _ := sebakstorage.NewTestMemoryLevelDBBackend()
+	tp := NewTransactionPool()
+
+	kp, _ := keypair.Random()
+	target, _ := keypair.Random()
+
+	tx0 := makeTransactionPayment(kp, target.Address(), Amount(BaseFee))
+	if err := ValidateTransactionState(st, tp, tx0); err != sebakerror.ErrorBlockAccountDoesNotExists {
+		t.Errorf("unknown source must be refused: %v", err)
+		return
+	}
+
+	// the balance covers one transaction with fee
+	NewBlockAccount(kp.Address(), tx0.TotalAmount(true), tx0.B.Checkpoint).Save(st)
+	if err := ValidateTransactionState(st, tp, tx0); err != nil {
+		t.Error(err)
+		return
+	}
+
+	op := Operation{
+		H: OperationHeader{Type: OperationPayment},
+		B: NewOperationBodyPayment(target.Address(), Amount(1)),
+	}
+	invalid, _ := NewTransaction(kp.Address(), "unknown-checkpoint", op)
+	invalid.Sign(kp, networkID)
+	if err := ValidateTransactionState(st, tp, invalid); err != sebakerror.ErrorTransactionInvalidCheckpoint {
+		t.Errorf("unknown checkpoint must be refused: %v", err)
+		return
+	}
+
+	// the transaction chained from the transaction in pool is valid, but the
+	// balance is already spent by it
+	tp.Add(tx0)
+	chained, _ := NewTransaction(kp.Address(), tx0.NextCheckpoint(), op)
+	chained.Sign(kp, networkID)
+	if err := ValidateTransactionState(st, tp, chained); err != sebakerror.ErrorAccountBalanceUnderZero {
+		t.Errorf("balance spent by the transaction in pool must be counted: %v", err)
+		return
+	}
+}
+
+func TestTransactionBatchPayments(t *testing.T) {
+	st, _ := sebakstorage.NewTestMemoryLevelDBBackend()
+
+	kp, _ := keypair.Random()
+	if err := TestMakeTransactionWithKeypair(networkID, MaxTransactionPayments, kp).IsWellFormed(networkID); err != nil {
+		t.Error(err)
+		return
+	}
+	if err := TestMakeTransactionWithKeypair(networkID, MaxTransactionPayments+1, kp).IsWellFormed(networkID); err != sebakerror.ErrorTransactionTooManyPayments {
+		t.Errorf("too many payments must be refused: %v", err)
+		return
+	}
+
+	a := testMakeBlockAccount()
+	a.Save(st)
+	b := testMakeBlockAccount()
+	b.Save(st)
+	unknown, _ := keypair.Random()
+
+	payment := func(target string) Operation {
+		return Operation{H: OperationHeader{Type: OperationPayment}, B: NewOperationBodyPayment(target, Amount(1))}
+	}
+
+	tx, _ := NewTransaction(kp.Address(), uuid.New().String(), payment(a.Address), payment(b.Address))
+	if err := ValidateTransactionOperations(st, tx); err != nil {
+		t.Error(err)
+		return
+	}
+
+	tx, _ = NewTransaction(kp.Address(), uuid.New().String(), payment(a.Address), payment(unknown.Address()), payment(b.Address))
+	err := ValidateTransactionOperations(st, tx)
+	e, ok := err.(*sebakerror.OperationsError)
+	if !ok || sebakerror.ResultOf(err) != sebakerror.ResultTransactionOperationsFailed {
+		t.Errorf("failed operation must be reported: %v", err)
+		return
+	}
+	if failed := e.Failed(); len(e.Operations) != 3 || len(failed) != 1 || failed[0].Index != 1 || failed[0].Result != sebakerror.ResultTransactionNoAccount {
+		t.Errorf("wrong results of operations: %v", e.Operations)
+		return
+	}
+
+	// the failed batch is not applied at all
+	tx.Sign(kp, networkID)
+	NewBlockAccount(kp.Address(), BaseFee*100, tx.B.Checkpoint).Save(st)
+	raw, _ := tx.Serialize()
+	if err := finishTransaction(st, networkID, raw, tx, sebakcommon.NowISO8601(), nil, Block{}, false); err == nil {
+		t.Error("batch with the unknown target must not be applied")
+		return
+	}
+	if ba, _ := GetBlockAccount(st, a.Address); ba.GetBalance() != a.GetBalance() {
+		t.Errorf("payment of the failed batch must be reverted: %v", ba.GetBalance())
+		return
+	}
+}
+
+func TestTransactionTimeBounds(t *testing.T) {
+	kp, _ := keypair.Random()
+	tx := TestMakeTransactionWithKeypair(networkID, 1, kp)
+	tx.H.Version = sebakcommon.EncodingVersionRLP
+	tx.Sign(kp, networkID)
+
+	// without time bounds, the hash is same as before
+	legacy := sebakcommon.MustMakeObjectHash(struct {
+		Source     string
+		Fee        Amount
+		Checkpoint string
+		Operations []Operation
+	}{tx.B.Source, tx.B.Fee, tx.B.Checkpoint, tx.B.Operations})
+	if tx.GetHash() != base58.Encode(legacy) {
+		t.Error("hash of transaction without time bounds must not be changed")
+		return
+	}
+
+	now := time.Now()
+	bounded := tx
+	bounded.B.ValidAfter = now.Add(-time.Minute).Format(time.RFC3339)
+	bounded.B.ValidUntil = now.Add(time.Minute).Format(time.RFC3339)
+	bounded.Sign(kp, networkID)
+	if bounded.GetHash() == tx.GetHash() {
+		t.Error("time bounds must be signed")
+		return
+	}
+	if err := bounded.IsWellFormed(networkID); err != nil {
+		t.Error(err)
+		return
+	}
+
+	encoded, _ := bounded.Serialize()
+	decoded, err := NewTransactionFromJSON(encoded)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if decoded.MakeHashString() != bounded.GetHash() {
+		t.Errorf("wrong transaction: %v", decoded)
+		return
+	}
+
+	if err := bounded.CheckTimeBounds(now); err != nil {
+		t.Error(err)
+		return
+	}
+	if err := bounded.CheckTimeBounds(now.Add(-time.Hour)); err != sebakerror.ErrorTransactionNotValidYet {
+		t.Errorf("transaction before valid_after must be refused: %v", err)
+		return
+	}
+	if err := bounded.CheckTimeBounds(now.Add(time.Hour)); err != sebakerror.ErrorTransactionExpired {
+		t.Errorf("transaction after valid_until must be refused: %v", err)
+		return
+	}
+
+	for _, bounds := range [][2]string{
+		{"yesterday", ""},
+		{bounded.B.ValidUntil, bounded.B.ValidAfter},
+	} {
+		invalid := tx
+		invalid.B.ValidAfter, invalid.B.ValidUntil = bounds[0], bounds[1]
+		invalid.Sign(kp, networkID)
+		if err := invalid.IsWellFormed(networkID); err != sebakerror.ErrorTransactionInvalidTimeBounds {
+			t.Errorf("invalid time bounds must be refused: %v", err)
+			return
+		}
+	}
+}
+
+func TestTransactionCanonicalEncoding(t *testing.T) {
+	kp, _ := keypair.Random()
+	tx := TestMakeTransactionWithKeypair(networkID, 2, kp)
+	if tx.H.Version != sebakcommon.EncodingVersionCanonical {
+		t.Errorf("new transaction must be encoded canonically: '%s'", tx.H.Version)
+		return
+	}
+	if err := tx.IsWellFormed(networkID); err != nil {
+		t.Error(err)
+		return
+	}
+	if tx.GetHash() == tx.B.MakeHashString() {
+		t.Error("canonical hash must be different from the legacy one")
+		return
+	}
+
+	// the order of fields and the whitespaces of JSON do not change the hash
+	var raw map[string]interface{}
+	encoded, _ := tx.Serialize()
+	json.Unmarshal(encoded, &raw)
+	reformatted, _ := json.MarshalIndent(raw, "", "    ")
+	decoded, err := NewTransactionFromJSON(reformatted)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if decoded.MakeHashString() != tx.GetHash() {
+		t.Errorf("wrong hash of decoded transaction: %s", decoded.MakeHashString())
+		return
+	}
+
+	// the legacy transaction is still valid
+	legacy := tx
+	legacy.H.Version = sebakcommon.EncodingVersionRLP
+	legacy.Sign(kp, networkID)
+	if err := legacy.IsWellFormed(networkID); err != nil {
+		t.Error(err)
+		return
+	}
+	if legacy.GetHash() != tx.B.MakeHashString() {
+		t.Error("legacy transaction must be hashed by RLP")
+		return
+	}
+
+	unknown := tx
+	unknown.H.Version = "2"
+	unknown.Sign(kp, networkID)
+	if err := unknown.IsWellFormed(networkID); err != sebakerror.ErrorUnknownEncodingVersion {
+		t.Errorf("unknown encoding version must be refused: %v", err)
+		return
+	}
+}
@@ -0,0 +1,227 @@
+package sebak
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+
+	"boscoin.io/sebak/lib/storage"
+)
+
+// Upgrade is the protocol feature, which the validators activate together
+// without the out-of-band schedule. The validator signals that it is ready
+// for the feature by `BallotBody.Signals` of it's ballots, and the block keeps
+// the signals of the validator, which proposed it, `Block.Signals`. Only the
+// blocks are counted, so every node locks in at the same height,
+//  * the blocks are counted in the windows of `UpgradeWindow` blocks from the
+//  height 1, like 1 to 1000, 1001 to 2000 and so on
+//  * when `UpgradeThreshold` percent of the blocks of the window are
+//  signaled for the feature, it is locked in at the height of the block
+//  * the feature activates at the height of lock in plus `UpgradeDelay`
+// Once locked in, the feature activates whatever the signals are after; the
+// delay is the time for the nodes, which are syncing the blocks to lock in
+// too. The upgrades are stored by,
+//  * 'ug-<Upgrade.Feature>': `Upgrade`
+
+const UpgradePrefix string = "ug-"
+
+// MaxUpgradeSignals is the maximum number of features in one ballot.
+const MaxUpgradeSignals int = 8
+
+var upgradeFeaturePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]{0,31}$`)
+
+// IsValidUpgradeFeature checks the name of feature, like 'fee-model.v2'.
+func IsValidUpgradeFeature(feature string) bool {
+	return upgradeFeaturePattern.MatchString(feature)
+}
+
+type Upgrade struct {
+	Feature     string `json:"feature"`
+	Window      uint64 `json:"window"`       // the first height of the window, which is counted
+	Signaled    uint64 `json:"signaled"`     // the signaled blocks in the window
+	LockedIn    uint64 `json:"locked_in"`    // the height of lock in; 0 is not locked in yet
+	ActivatesAt uint64 `json:"activates_at"` // the height, which the feature is active from
+}
+
+func (u Upgrade) IsLockedIn() bool {
+	return u.LockedIn > 0
+}
+
+func (u Upgrade) IsActive(height uint64) bool {
+	return u.IsLockedIn() && height >= u.ActivatesAt
+}
+
+func GetUpgradeKey(feature string) string {
+	return fmt.Sprintf("%s%s", UpgradePrefix, feature)
+}
+
+func (u Upgrade) Save(st *sebakstorage.LevelDBBackend) (err error) {
+	key := GetUpgradeKey(u.Feature)
+
+	var exists bool
+	if exists, err = st.Has(key); err != nil {
+		return
+	}
+
+	if exists {
+		err = st.Set(key, u)
+	} else {
+		err = st.New(key, u)
+	}
+
+	return
+}
+
+func GetUpgrade(st *sebakstorage.LevelDBBackend, feature string) (u Upgrade, found bool, err error) {
+	key := GetUpgradeKey(feature)
+	if found, err = st.Has(key); err != nil || !found {
+		return
+	}
+
+	err = st.Get(key, &u)
+
+	return
+}
+
+// GetUpgrades returns the upgrades in the order of feature.
+func GetUpgrades(st *sebakstorage.LevelDBBackend) (upgrades []Upgrade, err error) {
+	iterFunc, closeFunc := st.GetIterator(UpgradePrefix, false)
+	defer closeFunc()
+
+	for {
+		item, hasNext := iterFunc()
+		if !hasNext {
+			break
+		}
+
+		var u Upgrade
+		if err = json.Unmarshal(item.Value, &u); err != nil {
+			return
+		}
+		upgrades = append(upgrades, u)
+	}
+
+	return
+}
+
+// ApplyUpgradeSignals updates the upgrades by `Block.Signals` of the block,
+// which is just committed. It returns the upgrades, which are locked in by
+// the block.
+func ApplyUpgradeSignals(st *sebakstorage.LevelDBBackend, p NetworkParameters, block Block) (locked []Upgrade, err error) {
+	threshold, window, delay := p.UpgradePolicy()
+
+	var upgrades []Upgrade
+	if upgrades, err = GetUpgrades(st); err != nil {
+		return
+	}
+
+	known := map[string]bool{}
+	for _, u := range upgrades {
+		known[u.Feature] = true
+	}
+	signaled := map[string]bool{}
+	var features []string
+	for _, feature := range block.Signals {
+		if !known[feature] && !signaled[feature] {
+			features = append(features, feature)
+		}
+		signaled[feature] = true
+	}
+	sort.Strings(features)
+	for _, feature := range features {
+		upgrades = append(upgrades, Upgrade{Feature: feature})
+	}
+
+	start := (block.Height-1)/window*window + 1
+	for _, u := range upgrades {
+		if u.IsLockedIn() {
+			continue
+		}
+
+		changed := false
+		if u.Window != start {
+			u.Window, u.Signaled = start, 0
+			changed = true
+		}
+		if signaled[u.Feature] {
+			u.Signaled++
+			changed = true
+		}
+		if u.Signaled*100 >= uint64(threshold)*window {
+			u.LockedIn = block.Height
+			u.ActivatesAt = block.Height + delay
+			locked = append(locked, u)
+		} else if !changed {
+			continue
+		}
+
+		if err = u.Save(st); err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+// upgradeSignalSigner puts the signals of the node to it's ballots before
+// they are signed.
+type upgradeSignalSigner struct {
+	NodeSigner
+	signals []string
+}
+
+func (s upgradeSignalSigner) SignBallot(ballot *Ballot) error {
+	ballot.B.Signals = s.signals
+	return s.NodeSigner.SignBallot(ballot)
+}
+
+// SetUpgradeSignals sets the features, which the node is ready for; it must
+// be called before the node starts.
+func (is *ISAAC) SetUpgradeSignals(features []string) (err error) {
+	if len(features) > MaxUpgradeSignals {
+		err = fmt.Errorf("too many features; the maximum is %d", MaxUpgradeSignals)
+		return
+	}
+	for _, feature := range features {
+		if !IsValidUpgradeFeature(feature) {
+			err = fmt.Errorf("invalid feature: '%s'", feature)
+			return
+		}
+	}
+
+	is.signals = features
+
+	return
+}
+
+func (is *ISAAC) UpgradeSignals() []string {
+	return is.signals
+}
+
+// updateUpgrades counts the signals of the committed block.
+func (nr *NodeRunner) updateUpgrades(block Block) {
+	locked, err := ApplyUpgradeSignals(nr.storage, nr.networkParameters, block)
+	if err != nil {
+		nr.log.Error("failed to update upgrades", "height", block.Height, "error", err)
+		return
+	}
+	for _, u := range locked {
+		nr.log.Info("upgrade locked in", "feature", u.Feature, "height", u.LockedIn, "activates-at", u.ActivatesAt)
+	}
+}
+
+// IsUpgradeActive checks whether the feature is active at the latest block.
+func (nr *NodeRunner) IsUpgradeActive(feature string) bool {
+	u, found, err := GetUpgrade(nr.storage, feature)
+	if err != nil || !found || !u.IsLockedIn() {
+		return false
+	}
+
+	latest, err := GetLatestBlock(nr.storage)
+	if err != nil {
+		return false
+	}
+
+	return u.IsActive(latest.Height)
+}
@@ -0,0 +1,113 @@
+package sebak
+
+import (
+	"testing"
+
+	"github.com/stellar/go/keypair"
+
+	"boscoin.io/sebak/lib/common"
+	"boscoin.io/sebak/lib/error"
+	"boscoin.io/sebak/lib/storage"
+)
+
+func TestApplyUpgradeSignals(t *testing.T) {
+	st, _ := sebakstorage.NewTestMemoryLevelDBBackend()
+	defer st.Close()
+
+	p := NewDefaultNetworkParameters()
+	p.UpgradeThreshold = 75
+	p.UpgradeWindow = 4
+	p.UpgradeDelay = 10
+
+	apply := func(height uint64, signaled bool) []Upgrade {
+		block := Block{Height: height}
+		if signaled {
+			block.Signals = []string{"fee-model.v2"}
+		}
+		locked, err := ApplyUpgradeSignals(st, p, block)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return locked
+	}
+
+	// 2 of 4 blocks of the window are not the supermajority
+	apply(1, true)
+	apply(2, true)
+	apply(3, false)
+	apply(4, false)
+	if u, _, _ := GetUpgrade(st, "fee-model.v2"); u.Signaled != 2 || u.IsLockedIn() {
+		t.Errorf("upgrade must not be locked in: %v", u)
+		return
+	}
+
+	// the next window counts again
+	apply(5, true)
+	if u, _, _ := GetUpgrade(st, "fee-model.v2"); u.Window != 5 || u.Signaled != 1 {
+		t.Errorf("new window must count again: %v", u)
+		return
+	}
+
+	apply(6, true)
+	locked := apply(7, true)
+	if len(locked) != 1 || locked[0].LockedIn != 7 || locked[0].ActivatesAt != 17 {
+		t.Errorf("upgrade must be locked in by the threshold of window: %v", locked)
+		return
+	}
+
+	// once locked in, the upgrade is not changed by the signals
+	apply(8, false)
+	apply(9, false)
+	u, _, _ := GetUpgrade(st, "fee-model.v2")
+	if !u.IsLockedIn() || u.ActivatesAt != 17 {
+		t.Errorf("locked in upgrade must not be changed: %v", u)
+		return
+	}
+	if u.IsActive(16) || !u.IsActive(17) {
+		t.Error("upgrade must be active from `ActivatesAt`")
+		return
+	}
+}
+
+func TestUpgradeSignalsOfBallot(t *testing.T) {
+	kp, _ := keypair.Random()
+	node, _ := sebakcommon.NewValidator(kp.Address(), nil, "")
+	node.SetKeypair(kp)
+
+	p, _ := NewDefaultVotingThresholdPolicy(100, 30, 30)
+	is, _ := NewISAAC(networkID, node, p)
+	if err := is.SetUpgradeSignals([]string{"Invalid Feature"}); err == nil {
+		t.Error("invalid feature must be refused")
+		return
+	}
+	if err := is.SetUpgradeSignals([]string{"fee-model.v2"}); err != nil {
+		t.Error(err)
+		return
+	}
+
+	_, tx := TestMakeTransaction(networkID, 1)
+	ballot, err := is.ReceiveMessage(tx)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if len(ballot.B.Signals) != 1 || ballot.B.Signals[0] != "fee-model.v2" {
+		t.Errorf("ballot must have the signals of node: %v", ballot.B.Signals)
+		return
+	}
+	if len(ballot.B.ProposerSignals) != 1 || ballot.B.ProposerSignals[0] != "fee-model.v2" {
+		t.Errorf("first ballot must have the signals of proposer: %v", ballot.B.ProposerSignals)
+		return
+	}
+	if err = ballot.IsWellFormed(networkID); err != nil {
+		t.Error(err)
+		return
+	}
+
+	ballot.B.Signals = []string{"Invalid Feature"}
+	ballot.Sign(kp, networkID)
+	if err = ballot.IsWellFormed(networkID); err != sebakerror.ErrorBallotInvalidUpgradeSignals {
+		t.Errorf("ballot of invalid signals must be refused: %v", err)
+		return
+	}
+}
@@ -0,0 +1,246 @@
+package sebak
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/btcsuite/btcutil/base58"
+	"github.com/stellar/go/keypair"
+
+	"boscoin.io/sebak/lib/common"
+	"boscoin.io/sebak/lib/error"
+)
+
+// ViewChange is the vote of validator to move to the next view for the block
+// height, because the expected proposer of the current view did not propose
+// in time. When the quorum of validators vote for the same view, the next
+// proposer of `ProposerSchedule` becomes the proposer of the height.
+type ViewChange struct {
+	T string
+	H ViewChangeHeader
+	B ViewChangeBody
+}
+
+type ViewChangeHeader struct {
+	Hash        string                 `json:"hash"`
+	Signature   string                 `json:"signature"`
+	Certificate *SessionKeyCertificate `json:"certificate,omitempty"`
+}
+
+type ViewChangeBody struct {
+	NodeKey string `json:"node_key"`
+	Height  uint64 `json:"height"`
+	View    uint64 `json:"view"`
+	Created string `json:"created"`
+}
+
+func (vb ViewChangeBody) MakeHash() []byte {
+	return sebakcommon.MustMakeObjectHash(vb)
+}
+
+func (vb ViewChangeBody) MakeHashString() string {
+	return base58.Encode(vb.MakeHash())
+}
+
+func NewViewChange(nodeKey string, height, view uint64) ViewChange {
+	body := ViewChangeBody{
+		NodeKey: nodeKey,
+		Height:  height,
+		View:    view,
+		Created: sebakcommon.NowISO8601(),
+	}
+
+	return ViewChange{
+		T: "view-change",
+		H: ViewChangeHeader{Hash: body.MakeHashString()},
+		B: body,
+	}
+}
+
+func NewViewChangeFromJSON(b []byte) (vc ViewChange, err error) {
+	err = json.Unmarshal(b, &vc)
+	return
+}
+
+func (vc *ViewChange) Sign(kp keypair.KP, networkID []byte) {
+	vc.H.Hash = vc.B.MakeHashString()
+	signature, _ := kp.Sign(append(networkID, []byte(vc.H.Hash)...))
+
+	vc.H.Signature = base58.Encode(signature)
+}
+
+func (vc ViewChange) IsWellFormed(networkID []byte) (err error) {
+	if vc.H.Hash != vc.B.MakeHashString() {
+		err = sebakerror.ErrorHashDoesNotMatch
+		return
+	}
+
+	return VerifyNodeSignature(vc.B.NodeKey, vc.H.Certificate, networkID, vc.H.Hash, vc.H.Signature)
+}
+
+func (vc ViewChange) GetType() string {
+	return vc.T
+}
+
+func (vc ViewChange) GetHash() string {
+	return vc.H.Hash
+}
+
+func (vc ViewChange) Equal(m sebakcommon.Message) bool {
+	return vc.H.Hash == m.GetHash()
+}
+
+func (vc ViewChange) Serialize() (encoded []byte, err error) {
+	encoded, err = json.Marshal(vc)
+	return
+}
+
+func (vc ViewChange) String() string {
+	encoded, _ := json.MarshalIndent(vc, "", "  ")
+	return string(encoded)
+}
+
+// ViewChangeState keeps the current view of the next block height and the
+// view change votes from the validators. The votes for the higher height are
+// also kept, because the other validators can commit the block earlier.
+type ViewChangeState struct {
+	sync.RWMutex
+
+	height    uint64 // the next block height
+	view      uint64
+	requested uint64 // the view, which this node already voted for
+	updated   time.Time
+	now       func() time.Time
+
+	votes map[ /* height */ uint64]map[ /* view */ uint64]map[ /* node key */ string]bool
+}
+
+func NewViewChangeState() *ViewChangeState {
+	return &ViewChangeState{
+		updated: time.Now(),
+		now:     time.Now,
+		votes:   map[uint64]map[uint64]map[string]bool{},
+	}
+}
+
+// SetClock replaces the clock, by which the view is expired; the test moves
+// the clock to change the view without waiting the proposer timeout.
+func (s *ViewChangeState) SetClock(now func() time.Time) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.now = now
+	s.updated = now()
+}
+
+// Current returns the next block height and it's view.
+func (s *ViewChangeState) Current() (height, view uint64) {
+	s.RLock()
+	defer s.RUnlock()
+
+	return s.height, s.view
+}
+
+// SetHeight resets the view, when the next block height is changed. If the
+// votes for the new height already reach `required`, the view is changed by
+// them.
+func (s *ViewChangeState) SetHeight(height uint64, required int) {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.height >= height {
+		return
+	}
+
+	s.height = height
+	s.view = 0
+	s.requested = 0
+	s.updated = s.now()
+	for h := range s.votes {
+		if h < height {
+			delete(s.votes, h)
+		}
+	}
+
+	s.apply(required)
+}
+
+// IsExpired returns `true` if the current view is kept longer than `timeout`.
+func (s *ViewChangeState) IsExpired(timeout time.Duration) bool {
+	s.RLock()
+	defer s.RUnlock()
+
+	return s.now().Sub(s.updated) > timeout
+}
+
+// Touch extends the current view, because it is still making progress.
+func (s *ViewChangeState) Touch() {
+	s.Lock()
+	defer s.Unlock()
+
+	s.updated = s.now()
+}
+
+// Request returns the next view to vote for; if this node already voted for
+// it, `false` is returned.
+func (s *ViewChangeState) Request() (height, view uint64, ok bool) {
+	s.Lock()
+	defer s.Unlock()
+
+	height, view = s.height, s.view+1
+	if s.requested >= view {
+		return
+	}
+	s.requested = view
+	ok = true
+
+	return
+}
+
+// Vote adds the vote of validator; when the votes for the view of the current
+// height reach `required`, the current view is changed and `true` is
+// returned.
+func (s *ViewChangeState) Vote(vc ViewChange, required int) (changed bool) {
+	s.Lock()
+	defer s.Unlock()
+
+	if vc.B.Height < s.height || (vc.B.Height == s.height && vc.B.View <= s.view) {
+		return
+	}
+
+	if _, found := s.votes[vc.B.Height]; !found {
+		s.votes[vc.B.Height] = map[uint64]map[string]bool{}
+	}
+	if _, found := s.votes[vc.B.Height][vc.B.View]; !found {
+		s.votes[vc.B.Height][vc.B.View] = map[string]bool{}
+	}
+	s.votes[vc.B.Height][vc.B.View][vc.B.NodeKey] = true
+
+	changed = s.apply(required)
+
+	return
+}
+
+// apply moves to the highest view of the current height, which has enough
+// votes.
+func (s *ViewChangeState) apply(required int) (changed bool) {
+	for view, nodes := range s.votes[s.height] {
+		if view <= s.view || len(nodes) < required {
+			continue
+		}
+		s.view = view
+		changed = true
+	}
+
+	if changed {
+		s.updated = s.now()
+		for view := range s.votes[s.height] {
+			if view <= s.view {
+				delete(s.votes[s.height], view)
+			}
+		}
+	}
+
+	return
+}
@@ -0,0 +1,96 @@
+package sebak
+
+import (
+	"testing"
+
+	"github.com/stellar/go/keypair"
+)
+
+func TestViewChangeSign(t *testing.T) {
+	kp, _ := keypair.Random()
+
+	vc := NewViewChange(kp.Address(), 3, 1)
+	vc.Sign(kp, networkID)
+	if err := vc.IsWellFormed(networkID); err != nil {
+		t.Error(err)
+		return
+	}
+
+	b, _ := vc.Serialize()
+	unserialized, err := NewViewChangeFromJSON(b)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if err = unserialized.IsWellFormed(networkID); err != nil {
+		t.Error(err)
+		return
+	}
+
+	unserialized.B.View = 2
+	if err = unserialized.IsWellFormed(networkID); err == nil {
+		t.Error("modified view change must be refused")
+		return
+	}
+}
+
+func TestViewChangeStateVote(t *testing.T) {
+	s := NewViewChangeState()
+	s.SetHeight(3, 2)
+
+	newVote := func(height, view uint64) ViewChange {
+		kp, _ := keypair.Random()
+		return NewViewChange(kp.Address(), height, view)
+	}
+
+	if height, view, ok := s.Request(); !ok || height != 3 || view != 1 {
+		t.Error("failed to request view change")
+		return
+	}
+	if _, _, ok := s.Request(); ok {
+		t.Error("same view must not be requested again")
+		return
+	}
+
+	if s.Vote(newVote(3, 1), 2) {
+		t.Error("view must not be changed without quorum")
+		return
+	}
+	if !s.Vote(newVote(3, 1), 2) {
+		t.Error("view must be changed by quorum")
+		return
+	}
+	if _, view := s.Current(); view != 1 {
+		t.Errorf("wrong view: %d", view)
+		return
+	}
+
+	// old view is ignored
+	if s.Vote(newVote(3, 1), 2) {
+		t.Error("old view must be ignored")
+		return
+	}
+
+	// the votes for the next height are kept until the height is changed
+	s.Vote(newVote(4, 2), 2)
+	s.Vote(newVote(4, 2), 2)
+	s.SetHeight(4, 2)
+	if height, view := s.Current(); height != 4 || view != 2 {
+		t.Errorf("wrong view after height changed: %d, %d", height, view)
+		return
+	}
+}
+
+func TestProposerScheduleOfView(t *testing.T) {
+	nodeRunners := createNodeRunners(3)
+	schedule := NewProposerSchedule(nodeRunners[0].Node())
+
+	if schedule.ProposerOfView(1, 0).Address != schedule.Proposer(1).Address {
+		t.Error("the proposer of view 0 must be the scheduled proposer")
+		return
+	}
+	if schedule.ProposerOfView(1, 1).Address != schedule.Proposer(2).Address {
+		t.Error("view change must pass the proposer to the next validator")
+		return
+	}
+}
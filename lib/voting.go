@@ -93,6 +93,8 @@ type VotingResult struct {
 
 	ID          string                  // ID is unique and sequenital
 	MessageHash string                  // MessageHash is `Message.Hash`
+	Proposed    string                  // Proposed is `BallotBody.Proposed` of the first ballot
+	Signals     []string                // Signals is `BallotBody.ProposerSignals` of the first ballot
 	State       sebakcommon.BallotState // Latest `BallotState`
 	Ballots     map[sebakcommon.BallotState]VotingResultBallots
 	Staging     []VotingStateStaging // state changing histories
@@ -112,6 +114,8 @@ func NewVotingResult(ballot Ballot) (vr *VotingResult, err error) {
 	vr = &VotingResult{
 		ID:          sebakcommon.GetUniqueIDFromUUID(),
 		MessageHash: ballot.MessageHash(),
+		Proposed:    ballot.B.Proposed,
+		Signals:     ballot.B.ProposerSignals,
 		State:       ballot.State(),
 		Ballots:     ballots,
 	}
@@ -168,6 +172,8 @@ func (vr *VotingResult) VotedCount(state sebakcommon.BallotState) int {
 
 var VotingResultCheckerFuns = []sebakcommon.CheckerFunc{
 	checkBallotResultValidHash,
+	checkBallotResultSameProposed,
+	checkBallotResultSameProposerSignals,
 }
 
 func (vr *VotingResult) Add(ballot Ballot) (err error) {
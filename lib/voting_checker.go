@@ -1,6 +1,8 @@
 package sebak
 
 import (
+	"strings"
+
 	"boscoin.io/sebak/lib/common"
 	"boscoin.io/sebak/lib/error"
 )
@@ -21,3 +23,28 @@ func checkBallotResultValidHash(c sebakcommon.Checker, args ...interface{}) (err
 
 	return
 }
+
+// checkBallotResultSameProposed refuses the ballot, which has the other
+// proposed time; the block of message must have the same time in every node.
+func checkBallotResultSameProposed(c sebakcommon.Checker, args ...interface{}) (err error) {
+	checker := c.(*VotingResultChecker)
+	if checker.Ballot.B.Proposed != checker.VotingResult.Proposed {
+		err = sebakerror.ErrorBallotInvalidProposedTime
+		return
+	}
+
+	return
+}
+
+// checkBallotResultSameProposerSignals refuses the ballot, which has the other
+// signals of proposer; the block of message must have the same signals in
+// every node.
+func checkBallotResultSameProposerSignals(c sebakcommon.Checker, args ...interface{}) (err error) {
+	checker := c.(*VotingResultChecker)
+	if strings.Join(checker.Ballot.B.ProposerSignals, ",") != strings.Join(checker.VotingResult.Signals, ",") {
+		err = sebakerror.ErrorBallotInvalidUpgradeSignals
+		return
+	}
+
+	return
+}